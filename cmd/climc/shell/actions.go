@@ -16,6 +16,8 @@ package shell
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"yunion.io/x/jsonutils"
 
@@ -117,3 +119,36 @@ func init() {
 		return doActionList(s, &nargs)
 	})
 }
+
+func init() {
+	type ActionExportOptions struct {
+		Since  string `help:"Export logs since specific date" metavar:"DATETIME"`
+		Until  string `help:"Export logs until specific date" metavar:"DATETIME"`
+		Output string `help:"Output file, default to stdout"`
+	}
+	R(&ActionExportOptions{}, "action-export", "Export operation action logs as json lines", func(s *mcclient.ClientSession, args *ActionExportOptions) error {
+		params := jsonutils.NewDict()
+		if len(args.Since) > 0 {
+			params.Add(jsonutils.NewString(args.Since), "since")
+		}
+		if len(args.Until) > 0 {
+			params.Add(jsonutils.NewString(args.Until), "until")
+		}
+		body, err := modules.ExportActions(s, params)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		var w io.Writer = os.Stdout
+		if len(args.Output) > 0 {
+			f, err := os.Create(args.Output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		_, err = io.Copy(w, body)
+		return err
+	})
+}
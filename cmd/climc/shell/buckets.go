@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	R(&options.BucketListOptions{}, "bucket-list", "List object storage buckets", func(s *mcclient.ClientSession, opts *options.BucketListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.Buckets.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.Buckets.GetColumns(s))
+		return nil
+	})
+	R(&options.BucketCreateOptions{}, "bucket-create", "Create object storage bucket", func(s *mcclient.ClientSession, opts *options.BucketCreateOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.Buckets.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.BucketIdOptions{}, "bucket-show", "Show bucket", func(s *mcclient.ClientSession, opts *options.BucketIdOptions) error {
+		result, err := modules.Buckets.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.BucketIdOptions{}, "bucket-delete", "Delete bucket", func(s *mcclient.ClientSession, opts *options.BucketIdOptions) error {
+		result, err := modules.Buckets.Delete(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.BucketIdOptions{}, "bucket-purge", "Purge bucket of a disabled cloud provider", func(s *mcclient.ClientSession, opts *options.BucketIdOptions) error {
+		result, err := modules.Buckets.PerformAction(s, opts.ID, "purge", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.BucketIdOptions{}, "bucket-sync-stats", "Refresh size and object count of bucket", func(s *mcclient.ClientSession, opts *options.BucketIdOptions) error {
+		result, err := modules.Buckets.PerformAction(s, opts.ID, "sync-stats", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.BucketSetAclOptions{}, "bucket-set-acl", "Set canned acl of bucket", func(s *mcclient.ClientSession, opts *options.BucketSetAclOptions) error {
+		params := jsonutils.NewDict()
+		params.Set("acl", jsonutils.NewString(opts.ACL))
+		result, err := modules.Buckets.PerformAction(s, opts.ID, "set-acl", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.BucketIdOptions{}, "bucket-lifecycle", "Show lifecycle rules of bucket", func(s *mcclient.ClientSession, opts *options.BucketIdOptions) error {
+		result, err := modules.Buckets.GetSpecific(s, opts.ID, "lifecycle", nil)
+		if err != nil {
+			return err
+		}
+		rules, _ := result.GetArray("rules")
+		printList(modules.JSON2ListResult(jsonutils.NewArray(rules...)), nil)
+		return nil
+	})
+	R(&options.BucketSetLifecycleOptions{}, "bucket-set-lifecycle", "Replace lifecycle rules of bucket", func(s *mcclient.ClientSession, opts *options.BucketSetLifecycleOptions) error {
+		rules := jsonutils.NewArray()
+		for i, r := range opts.Rule {
+			pos := strings.LastIndexByte(r, ':')
+			if pos < 0 {
+				return fmt.Errorf("invalid rule %s, want <prefix>:<expiration_days>", r)
+			}
+			days, err := strconv.Atoi(r[pos+1:])
+			if err != nil {
+				return fmt.Errorf("invalid expiration days of rule %s: %s", r, err)
+			}
+			rule := jsonutils.NewDict()
+			rule.Set("id", jsonutils.NewString(fmt.Sprintf("rule-%d", i)))
+			rule.Set("prefix", jsonutils.NewString(r[:pos]))
+			rule.Set("enabled", jsonutils.JSONTrue)
+			rule.Set("expiration_days", jsonutils.NewInt(int64(days)))
+			rules.Add(rule)
+		}
+		params := jsonutils.NewDict()
+		params.Set("rules", rules)
+		result, err := modules.Buckets.PerformAction(s, opts.ID, "set-lifecycle", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.BucketTempUrlOptions{}, "bucket-temp-url", "Get presigned url of an object in bucket", func(s *mcclient.ClientSession, opts *options.BucketTempUrlOptions) error {
+		params := jsonutils.NewDict()
+		params.Set("key", jsonutils.NewString(opts.KEY))
+		params.Set("method", jsonutils.NewString(opts.Method))
+		params.Set("expire_seconds", jsonutils.NewInt(int64(opts.ExpireSeconds)))
+		result, err := modules.Buckets.GetSpecific(s, opts.ID, "temp-url", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	R(&options.CloudcostListOptions{}, "cloudcost-list", "List daily costs of resources", func(s *mcclient.ClientSession, opts *options.CloudcostListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.Cloudcosts.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.Cloudcosts.GetColumns(s))
+		return nil
+	})
+	R(&options.CloudcostSummaryOptions{}, "cloudcost-project-summary", "Show total costs of projects", func(s *mcclient.ClientSession, opts *options.CloudcostSummaryOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.Cloudcosts.Get(s, "project-summary", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.CloudcostSummaryOptions{}, "cloudcost-resource-summary", "Show total costs of resources", func(s *mcclient.ClientSession, opts *options.CloudcostSummaryOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.Cloudcosts.Get(s, "resource-summary", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.CloudcostCollectOptions{}, "cloudcost-collect", "Collect costs of a day again", func(s *mcclient.ClientSession, opts *options.CloudcostCollectOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.Cloudcosts.PerformClassAction(s, "collect", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&options.CostRateListOptions{}, "costrate-list", "List on-premise cost rates", func(s *mcclient.ClientSession, opts *options.CostRateListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.CostRates.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.CostRates.GetColumns(s))
+		return nil
+	})
+	R(&options.CostRateCreateOptions{}, "costrate-create", "Create on-premise cost rate", func(s *mcclient.ClientSession, opts *options.CostRateCreateOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.CostRates.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.CostRateUpdateOptions{}, "costrate-update", "Update on-premise cost rate", func(s *mcclient.ClientSession, opts *options.CostRateUpdateOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.CostRates.Update(s, opts.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.CostRateIdOptions{}, "costrate-show", "Show on-premise cost rate", func(s *mcclient.ClientSession, opts *options.CostRateIdOptions) error {
+		result, err := modules.CostRates.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.CostRateIdOptions{}, "costrate-delete", "Delete on-premise cost rate", func(s *mcclient.ClientSession, opts *options.CostRateIdOptions) error {
+		result, err := modules.CostRates.Delete(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	R(&options.DBInstanceListOptions{}, "dbinstance-list", "List managed database instances", func(s *mcclient.ClientSession, opts *options.DBInstanceListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.DBInstances.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.DBInstances.GetColumns(s))
+		return nil
+	})
+	R(&options.DBInstanceIdOptions{}, "dbinstance-show", "Show dbinstance", func(s *mcclient.ClientSession, opts *options.DBInstanceIdOptions) error {
+		result, err := modules.DBInstances.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.DBInstanceIdOptions{}, "dbinstance-start", "Start dbinstance", func(s *mcclient.ClientSession, opts *options.DBInstanceIdOptions) error {
+		result, err := modules.DBInstances.PerformAction(s, opts.ID, "start", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.DBInstanceIdOptions{}, "dbinstance-stop", "Stop dbinstance", func(s *mcclient.ClientSession, opts *options.DBInstanceIdOptions) error {
+		result, err := modules.DBInstances.PerformAction(s, opts.ID, "stop", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.DBInstanceIdOptions{}, "dbinstance-reboot", "Reboot dbinstance", func(s *mcclient.ClientSession, opts *options.DBInstanceIdOptions) error {
+		result, err := modules.DBInstances.PerformAction(s, opts.ID, "reboot", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.DBInstanceIdOptions{}, "dbinstance-purge", "Purge dbinstance of a disabled cloud provider", func(s *mcclient.ClientSession, opts *options.DBInstanceIdOptions) error {
+		result, err := modules.DBInstances.PerformAction(s, opts.ID, "purge", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.DBInstanceChangeConfigOptions{}, "dbinstance-change-config", "Change instance type or enlarge storage of dbinstance", func(s *mcclient.ClientSession, opts *options.DBInstanceChangeConfigOptions) error {
+		params := jsonutils.NewDict()
+		if len(opts.InstanceType) > 0 {
+			params.Set("instance_type", jsonutils.NewString(opts.InstanceType))
+		}
+		if opts.StorageSizeGb > 0 {
+			params.Set("storage_size_gb", jsonutils.NewInt(int64(opts.StorageSizeGb)))
+		}
+		result, err := modules.DBInstances.PerformAction(s, opts.ID, "change-config", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.DBInstanceIdOptions{}, "dbinstance-backups", "List backups of dbinstance", func(s *mcclient.ClientSession, opts *options.DBInstanceIdOptions) error {
+		result, err := modules.DBInstances.GetSpecific(s, opts.ID, "backups", nil)
+		if err != nil {
+			return err
+		}
+		backups, _ := result.GetArray("backups")
+		printList(modules.JSON2ListResult(jsonutils.NewArray(backups...)), nil)
+		return nil
+	})
+	R(&options.DBInstanceIdOptions{}, "dbinstance-accounts", "List accounts of dbinstance", func(s *mcclient.ClientSession, opts *options.DBInstanceIdOptions) error {
+		result, err := modules.DBInstances.GetSpecific(s, opts.ID, "accounts", nil)
+		if err != nil {
+			return err
+		}
+		accounts, _ := result.GetArray("accounts")
+		printList(modules.JSON2ListResult(jsonutils.NewArray(accounts...)), nil)
+		return nil
+	})
+}
@@ -140,4 +140,34 @@ func init() {
 		return nil
 	})
 
+	R(&options.DnsZoneListOptions{}, "dns-zone-list", "List cloud dns zones", func(s *mcclient.ClientSession, opts *options.DnsZoneListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.DnsZones.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.DnsZones.GetColumns(s))
+		return nil
+	})
+
+	R(&options.DnsZoneIdOptions{}, "dns-zone-show", "Show details of a cloud dns zone", func(s *mcclient.ClientSession, opts *options.DnsZoneIdOptions) error {
+		result, err := modules.DnsZones.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&options.DnsZoneIdOptions{}, "dns-zone-purge", "Purge dns zone of a disabled cloud provider", func(s *mcclient.ClientSession, opts *options.DnsZoneIdOptions) error {
+		result, err := modules.DnsZones.PerformAction(s, opts.ID, "purge", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
 }
@@ -414,6 +414,48 @@ func init() {
 		return nil
 	})
 
+	type HostDrainOptions struct {
+		ID          string `help:"ID or Name of host"`
+		Concurrency int    `help:"Maximal concurrent live migrations"`
+		Force       bool   `help:"Drain even if some guests can not be moved"`
+		DryRun      bool   `help:"Only show the planned migrations"`
+	}
+	R(&HostDrainOptions{}, "host-drain", "Disable a host and live migrate its guests to other hosts", func(s *mcclient.ClientSession, args *HostDrainOptions) error {
+		params := jsonutils.NewDict()
+		if args.Concurrency > 0 {
+			params.Add(jsonutils.NewInt(int64(args.Concurrency)), "concurrency")
+		}
+		if args.Force {
+			params.Add(jsonutils.JSONTrue, "force")
+		}
+		if args.DryRun {
+			params.Add(jsonutils.JSONTrue, "dry_run")
+		}
+		result, err := modules.Hosts.PerformAction(s, args.ID, "drain", params)
+		if err != nil {
+			return err
+		}
+		fmt.Println(result.YAMLString())
+		return nil
+	})
+
+	type HostFailoverOptions struct {
+		ID    string `help:"ID or Name of host"`
+		Force bool   `help:"Restart HA guests even if the host can not be powered off through IPMI"`
+	}
+	R(&HostFailoverOptions{}, "host-failover", "Fence an offline host and restart its HA guests on other hosts", func(s *mcclient.ClientSession, args *HostFailoverOptions) error {
+		params := jsonutils.NewDict()
+		if args.Force {
+			params.Add(jsonutils.JSONTrue, "force")
+		}
+		result, err := modules.Hosts.PerformAction(s, args.ID, "failover", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
 	R(&HostDetailOptions{}, "host-remove-all-netifs", "Remvoe all netifs expect admin&ipmi netifs", func(s *mcclient.ClientSession, args *HostDetailOptions) error {
 		result, err := modules.Hosts.PerformAction(s, args.ID, "remove-all-netifs", nil)
 		if err != nil {
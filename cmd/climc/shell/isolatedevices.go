@@ -30,6 +30,7 @@ func init() {
 		Host   string `help:"Host ID or Name"`
 		Region string `help:"Cloudregion ID or Name"`
 		Zone   string `help:"Zone ID or Name"`
+		Wire   string `help:"Wire ID or Name the SR-IOV virtual functions connect to"`
 	}
 	R(&DeviceListOptions{}, "isolated-device-list", "List isolated devices like GPU", func(s *mcclient.ClientSession, args *DeviceListOptions) error {
 		var params *jsonutils.JSONDict
@@ -56,6 +57,9 @@ func init() {
 		if args.Zone != "" {
 			params.Add(jsonutils.NewString(args.Zone), "zone")
 		}
+		if len(args.Wire) > 0 {
+			params.Add(jsonutils.NewString(args.Wire), "wire")
+		}
 		result, err := modules.IsolatedDevices.List(s, params)
 		if err != nil {
 			return err
@@ -0,0 +1,134 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	R(&options.NatGatewayListOptions{}, "natgateway-list", "List nat gateways", func(s *mcclient.ClientSession, opts *options.NatGatewayListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.NatGateways.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.NatGateways.GetColumns(s))
+		return nil
+	})
+	R(&options.NatGatewayIdOptions{}, "natgateway-show", "Show nat gateway", func(s *mcclient.ClientSession, opts *options.NatGatewayIdOptions) error {
+		result, err := modules.NatGateways.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.NatGatewayIdOptions{}, "natgateway-purge", "Purge nat gateway of a disabled cloud provider", func(s *mcclient.ClientSession, opts *options.NatGatewayIdOptions) error {
+		result, err := modules.NatGateways.PerformAction(s, opts.ID, "purge", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&options.NatSEntryListOptions{}, "natsentry-list", "List snat entries", func(s *mcclient.ClientSession, opts *options.NatSEntryListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.NatSTables.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.NatSTables.GetColumns(s))
+		return nil
+	})
+	R(&options.NatSEntryCreateOptions{}, "natsentry-create", "Create snat entry", func(s *mcclient.ClientSession, opts *options.NatSEntryCreateOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.NatSTables.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.NatEntryIdOptions{}, "natsentry-show", "Show snat entry", func(s *mcclient.ClientSession, opts *options.NatEntryIdOptions) error {
+		result, err := modules.NatSTables.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.NatEntryIdOptions{}, "natsentry-delete", "Delete snat entry", func(s *mcclient.ClientSession, opts *options.NatEntryIdOptions) error {
+		result, err := modules.NatSTables.Delete(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&options.NatDEntryListOptions{}, "natdentry-list", "List dnat entries", func(s *mcclient.ClientSession, opts *options.NatDEntryListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.NatDTables.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.NatDTables.GetColumns(s))
+		return nil
+	})
+	R(&options.NatDEntryCreateOptions{}, "natdentry-create", "Create dnat entry", func(s *mcclient.ClientSession, opts *options.NatDEntryCreateOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.NatDTables.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.NatEntryIdOptions{}, "natdentry-show", "Show dnat entry", func(s *mcclient.ClientSession, opts *options.NatEntryIdOptions) error {
+		result, err := modules.NatDTables.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.NatEntryIdOptions{}, "natdentry-delete", "Delete dnat entry", func(s *mcclient.ClientSession, opts *options.NatEntryIdOptions) error {
+		result, err := modules.NatDTables.Delete(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
@@ -242,11 +242,21 @@ func init() {
 		Role       []string `help:"Roles"`
 		Request    []string `help:"explain request, in format of key:is_admin:service:resource:action:extra"`
 		Name       string   `help:"policy name"`
+		Input      string   `help:"input in JSON to evaluate conditions of rules, e.g. {\"object\":{\"metadata\":{\"env\":\"prod\"}}}"`
 	}
 	R(&PolicyExplainOptions{}, "policy-explain", "Explain policy result", func(s *mcclient.ClientSession, args *PolicyExplainOptions) error {
 		auth.InitFromClientSession(s)
 		policy.EnableGlobalRbac(15*time.Second, 15*time.Second, false)
 
+		var input jsonutils.JSONObject
+		if len(args.Input) > 0 {
+			var err error
+			input, err = jsonutils.ParseString(args.Input)
+			if err != nil {
+				return fmt.Errorf("invalid input: %s", err)
+			}
+		}
+
 		req := jsonutils.NewDict()
 		for i := 0; i < len(args.Request); i += 1 {
 			parts := strings.Split(args.Request[i], ":")
@@ -265,6 +275,9 @@ func init() {
 			for i := 2; i < len(parts); i += 1 {
 				data = append(data, jsonutils.NewString(parts[i]))
 			}
+			if input != nil {
+				data = append(data, input)
+			}
 			req.Add(jsonutils.NewArray(data...), key)
 		}
 		fmt.Println("Request:", req.String())
@@ -295,3 +308,83 @@ func init() {
 		return nil
 	})
 }
+
+func init() {
+	type PolicySimulateOptions struct {
+		NAME         string   `help:"type of the policy to change"`
+		File         string   `help:"path to the candidate policy file, in yaml or json"`
+		Delete       bool     `help:"simulate deleting the policy"`
+		Subject      []string `help:"subject to simulate, in format of user:project:role1,role2"`
+		Request      []string `help:"request to simulate, in format of service:resource:action:extra, default to all covered by the policies"`
+		Input        string   `help:"input in JSON to evaluate conditions of rules"`
+		ChangedOnly  bool     `help:"show changed results only"`
+		FailOnChange bool     `help:"exit with error if any result is changed, for checking policies in CI"`
+	}
+	R(&PolicySimulateOptions{}, "policy-simulate", "Simulate the effect of a policy change", func(s *mcclient.ClientSession, args *PolicySimulateOptions) error {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(args.NAME), "name")
+		if args.Delete {
+			params.Add(jsonutils.JSONTrue, "delete")
+		} else {
+			if len(args.File) == 0 {
+				return fmt.Errorf("either --file or --delete should be specified")
+			}
+			policyBytes, err := ioutil.ReadFile(args.File)
+			if err != nil {
+				return err
+			}
+			params.Add(jsonutils.NewString(string(policyBytes)), "policy")
+		}
+		if len(args.Subject) == 0 {
+			return fmt.Errorf("at least one --subject is required")
+		}
+		subjects := jsonutils.NewArray()
+		for _, sub := range args.Subject {
+			parts := strings.Split(sub, ":")
+			if len(parts) != 3 {
+				return fmt.Errorf("invalid subject %s, should be in the form of user:project:roles", sub)
+			}
+			subject := jsonutils.NewDict()
+			subject.Add(jsonutils.NewString(parts[0]), "user")
+			subject.Add(jsonutils.NewString(parts[1]), "project")
+			subject.Add(jsonutils.NewString(parts[2]), "roles")
+			subjects.Add(subject)
+		}
+		params.Add(subjects, "subjects")
+		if len(args.Request) > 0 {
+			requests := jsonutils.NewArray()
+			for _, req := range args.Request {
+				parts := strings.Split(req, ":")
+				if len(parts) < 3 {
+					return fmt.Errorf("invalid request %s, should be in the form of service:resource:action[:extra]", req)
+				}
+				requests.Add(jsonutils.NewStringArray(parts))
+			}
+			params.Add(requests, "requests")
+		}
+		if len(args.Input) > 0 {
+			input, err := jsonutils.ParseString(args.Input)
+			if err != nil {
+				return fmt.Errorf("invalid input: %s", err)
+			}
+			params.Add(input, "input")
+		}
+		if args.ChangedOnly {
+			params.Add(jsonutils.JSONTrue, "changed_only")
+		}
+		result, err := modules.Rbac.Simulate(s, params)
+		if err != nil {
+			return err
+		}
+		data, _ := result.GetArray("results")
+		total, _ := result.Int("total")
+		changed, _ := result.Int("changed")
+		printList(&modules.ListResult{Data: data, Total: len(data)},
+			[]string{"subject", "is_admin", "service", "resource", "action", "extra", "current", "candidate", "changed"})
+		fmt.Printf("%d of %d results changed\n", changed, total)
+		if args.FailOnChange && changed > 0 {
+			return fmt.Errorf("policy %s changes %d results", args.NAME, changed)
+		}
+		return nil
+	})
+}
@@ -57,6 +57,34 @@ func init() {
 			return nil
 		})
 
+	R(&options.SchedulerCapacityOptions{}, "scheduler-capacity", "Simulate how many guests of a spec mix still fit",
+		func(s *mcclient.ClientSession, args *options.SchedulerCapacityOptions) error {
+			params, err := args.Params(s)
+			if err != nil {
+				return err
+			}
+			result, err := modules.SchedManager.Capacity(s, params)
+			if err != nil {
+				return err
+			}
+			fmt.Println(result.YAMLString())
+			return nil
+		})
+
+	R(&options.SchedulerGangOptions{}, "scheduler-gang", "Place a group of guests together or none of them",
+		func(s *mcclient.ClientSession, args *options.SchedulerGangOptions) error {
+			params, err := args.Params(s)
+			if err != nil {
+				return err
+			}
+			result, err := modules.SchedManager.Gang(s, params)
+			if err != nil {
+				return err
+			}
+			fmt.Println(result.YAMLString())
+			return nil
+		})
+
 	type SchedulerCandidateListOptions struct {
 		Type   string `help:"Sched type filter" choices:"baremetal|host"`
 		Region string `help:"Cloud region ID"`
@@ -179,6 +179,16 @@ func init() {
 		return nil
 	})
 
+	R(&options.ServerMigrateToKvmOptions{}, "server-migrate-to-kvm", "Import a stopped ESXi server into a KVM server", func(s *mcclient.ClientSession, opts *options.ServerMigrateToKvmOptions) error {
+		params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+		res, err := modules.Servers.PerformAction(s, opts.ID, "migrate-to-kvm", params)
+		if err != nil {
+			return err
+		}
+		printObject(res)
+		return nil
+	})
+
 	R(&options.ServerLoginInfoOptions{}, "server-logininfo", "Get login info of a server", func(s *mcclient.ClientSession, opts *options.ServerLoginInfoOptions) error {
 		srvid, e := modules.Servers.GetId(s, opts.ID, nil)
 		if e != nil {
@@ -854,4 +864,17 @@ func init() {
 		printObject(ret)
 		return nil
 	})
+
+	R(&options.ServerScheduleExplainOptions{}, "server-schedule-explain", "Show why the server is placed on its host or failed to schedule", func(s *mcclient.ClientSession, opts *options.ServerScheduleExplainOptions) error {
+		params, err := options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		ret, err := modules.Servers.GetSpecific(s, opts.ID, "schedule-explain", params)
+		if err != nil {
+			return err
+		}
+		fmt.Println(ret.YAMLString())
+		return nil
+	})
 }
@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	R(&options.VpcPeeringListOptions{}, "vpc-peering-list", "List vpc peerings", func(s *mcclient.ClientSession, opts *options.VpcPeeringListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.VpcPeerings.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.VpcPeerings.GetColumns(s))
+		return nil
+	})
+	R(&options.VpcPeeringIdOptions{}, "vpc-peering-show", "Show vpc peering", func(s *mcclient.ClientSession, opts *options.VpcPeeringIdOptions) error {
+		result, err := modules.VpcPeerings.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.VpcPeeringIdOptions{}, "vpc-peering-purge", "Purge vpc peering of a disabled cloud provider", func(s *mcclient.ClientSession, opts *options.VpcPeeringIdOptions) error {
+		result, err := modules.VpcPeerings.PerformAction(s, opts.ID, "purge", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&options.VpnConnectionListOptions{}, "vpn-connection-list", "List vpn connections", func(s *mcclient.ClientSession, opts *options.VpnConnectionListOptions) error {
+		params, err := options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.VpnConnections.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.VpnConnections.GetColumns(s))
+		return nil
+	})
+	R(&options.VpnConnectionIdOptions{}, "vpn-connection-show", "Show vpn connection", func(s *mcclient.ClientSession, opts *options.VpnConnectionIdOptions) error {
+		result, err := modules.VpnConnections.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&options.VpnConnectionIdOptions{}, "vpn-connection-purge", "Purge vpn connection of a disabled cloud provider", func(s *mcclient.ClientSession, opts *options.VpnConnectionIdOptions) error {
+		result, err := modules.VpnConnections.PerformAction(s, opts.ID, "purge", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
@@ -93,6 +93,15 @@ func init() {
 		return nil
 	})
 
+	R(&VpcShowOptions{}, "vpc-overlay-fdb", "Show guest addresses and VTEPs on the overlay wires of a VPC", func(s *mcclient.ClientSession, args *VpcShowOptions) error {
+		results, err := modules.Vpcs.GetSpecific(s, args.ID, "overlay-fdb", nil)
+		if err != nil {
+			return err
+		}
+		printObject(results)
+		return nil
+	})
+
 	R(&VpcShowOptions{}, "vpc-delete", "Delete a VPC", func(s *mcclient.ClientSession, args *VpcShowOptions) error {
 		results, err := modules.Vpcs.Delete(s, args.ID, nil)
 		if err != nil {
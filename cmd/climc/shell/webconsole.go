@@ -16,7 +16,9 @@ package shell
 
 import (
 	"fmt"
+	"io"
 	"net/url"
+	"os"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/utils"
@@ -121,4 +123,54 @@ func init() {
 		handleResult(args.WebConsoleOptions, ret)
 		return nil
 	})
+
+	R(&o.WebConsoleServerOptions{}, "webconsole-server-serial", "Connect server serial console", func(s *mcclient.ClientSession, args *o.WebConsoleServerOptions) error {
+		ret, err := modules.WebConsole.DoServerSerialConnect(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		handleResult(args.WebConsoleOptions, ret)
+		return nil
+	})
+
+	R(&o.ConsoleSessionListOptions{}, "webconsole-session-list", "List console sessions for audit", func(s *mcclient.ClientSession, args *o.ConsoleSessionListOptions) error {
+		params, err := args.Params()
+		if err != nil {
+			return err
+		}
+		ret, err := modules.WebConsole.ListConsoleSessions(s, params)
+		if err != nil {
+			return err
+		}
+		printList(ret, []string{"Id", "Protocol", "Target", "User", "Project", "Instance", "Created_at", "Closed_at", "Has_recording"})
+		return nil
+	})
+
+	R(&o.ConsoleSessionIdOptions{}, "webconsole-session-show", "Show details of a console session", func(s *mcclient.ClientSession, args *o.ConsoleSessionIdOptions) error {
+		ret, err := modules.WebConsole.GetConsoleSession(s, args.ID)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	R(&o.ConsoleSessionRecordingOptions{}, "webconsole-session-recording", "Download recording of a console session", func(s *mcclient.ClientSession, args *o.ConsoleSessionRecordingOptions) error {
+		body, err := modules.WebConsole.GetConsoleSessionRecording(s, args.ID)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		var out io.Writer = os.Stdout
+		if len(args.Output) > 0 {
+			f, err := os.Create(args.Output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		_, err = io.Copy(out, body)
+		return err
+	})
 }
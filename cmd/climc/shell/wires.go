@@ -29,6 +29,8 @@ func init() {
 		Region string `help:"List hosts in region"`
 		Zone   string `help:"list wires in zone" json:"-"`
 		Vpc    string `help:"List wires in vpc"`
+
+		WireType string `help:"List wires of type" choices:"vlan|vxlan"`
 	}
 	R(&WireListOptions{}, "wire-list", "List wires", func(s *mcclient.ClientSession, opts *WireListOptions) error {
 		params, err := options.ListStructToParams(opts)
@@ -83,6 +85,9 @@ func init() {
 		NAME string `help:"Name of wire"`
 		BW   int64  `help:"Bandwidth in mbps"`
 		Desc string `metavar:"<DESCRIPTION>" help:"Description"`
+
+		WireType string `help:"Type of wire, vxlan for an overlay wire" choices:"vlan|vxlan"`
+		Vni      int64  `help:"VNI of the overlay wire, allocated automatically if not given"`
 	}
 	R(&WireCreateOptions{}, "wire-create", "Create a wire", func(s *mcclient.ClientSession, args *WireCreateOptions) error {
 		params := jsonutils.NewDict()
@@ -94,6 +99,12 @@ func init() {
 		if len(args.Desc) > 0 {
 			params.Add(jsonutils.NewString(args.Desc), "description")
 		}
+		if len(args.WireType) > 0 {
+			params.Add(jsonutils.NewString(args.WireType), "wire_type")
+		}
+		if args.Vni > 0 {
+			params.Add(jsonutils.NewInt(args.Vni), "vni")
+		}
 		result, err := modules.Wires.CreateInContext(s, params, &modules.Zones, args.ZONE)
 		if err != nil {
 			return err
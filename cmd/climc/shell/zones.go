@@ -15,6 +15,8 @@
 package shell
 
 import (
+	"fmt"
+
 	"yunion.io/x/jsonutils"
 
 	"yunion.io/x/onecloud/pkg/mcclient"
@@ -157,4 +159,36 @@ func init() {
 		return nil
 	})
 
+	type ZoneRebalanceOptions struct {
+		ID          string  `help:"ID or name of zone"`
+		Metric      string  `help:"What to balance across hosts" choices:"allocation|load"`
+		Threshold   float64 `help:"Maximal difference of the host usage ratio to tolerate"`
+		MaxMoves    int     `help:"Maximal guests to migrate"`
+		Concurrency int     `help:"Maximal concurrent live migrations"`
+		DryRun      bool    `help:"Only show the planned migrations"`
+	}
+	R(&ZoneRebalanceOptions{}, "zone-rebalance", "Live migrate guests to even out the hosts of a zone", func(s *mcclient.ClientSession, args *ZoneRebalanceOptions) error {
+		params := jsonutils.NewDict()
+		if len(args.Metric) > 0 {
+			params.Add(jsonutils.NewString(args.Metric), "metric")
+		}
+		if args.Threshold > 0 {
+			params.Add(jsonutils.NewFloat(args.Threshold), "threshold")
+		}
+		if args.MaxMoves > 0 {
+			params.Add(jsonutils.NewInt(int64(args.MaxMoves)), "max_moves")
+		}
+		if args.Concurrency > 0 {
+			params.Add(jsonutils.NewInt(int64(args.Concurrency)), "concurrency")
+		}
+		if args.DryRun {
+			params.Add(jsonutils.JSONTrue, "dry_run")
+		}
+		result, err := modules.Zones.PerformAction(s, args.ID, "rebalance", params)
+		if err != nil {
+			return err
+		}
+		fmt.Println(result.YAMLString())
+		return nil
+	})
 }
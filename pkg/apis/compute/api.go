@@ -27,6 +27,22 @@ type SchedtagConfig struct {
 	Strategy string `json:"strategy"`
 }
 
+const (
+	SPREAD_TOPOLOGY_HOST = "host"
+	SPREAD_TOPOLOGY_ZONE = "zone"
+)
+
+// SpreadConstraint limits how many guests of one schedule request may be
+// placed in the same topology domain
+type SpreadConstraint struct {
+	apis.Meta
+
+	// Topology is host, zone or the name prefix of the host schedtags
+	// telling the domain of a host, e.g. rack- for schedtags rack-a, rack-b
+	Topology     string `json:"topology"`
+	MaxPerDomain int    `json:"max_per_domain"`
+}
+
 type NetworkConfig struct {
 	apis.Meta
 
@@ -44,6 +60,9 @@ type NetworkConfig struct {
 	Reserved bool   `json:"reserved"`
 	NetType  string `json:"net_type"`
 
+	// allocate a SR-IOV virtual function instead of a tap device
+	Sriov bool `json:"sriov"`
+
 	RequireTeaming bool `json:"require_teaming"`
 	TryTeaming     bool `json:"try_teaming"`
 
@@ -120,6 +139,14 @@ type ServerConfigs struct {
 	Backup       bool   `json:"backup"`
 	Count        int    `json:"count"`
 
+	// Gang places either all Count guests or none of them
+	Gang    bool                `json:"gang"`
+	Spreads []*SpreadConstraint `json:"spreads"`
+
+	// PriorityClass is normal or preemptible, resources of preemptible
+	// guests are reclaimed when normal guests don't fit
+	PriorityClass string `json:"priority_class"`
+
 	Disks                []*DiskConfig           `json:"disks"`
 	Networks             []*NetworkConfig        `json:"nets"`
 	Schedtags            []*SchedtagConfig       `json:"schedtags"`
@@ -161,6 +188,7 @@ type ServerCreateInput struct {
 	AutoStart          bool            `json:"auto_start"`
 	DeployConfigs      []*DeployConfig `json:"deploy_configs"`
 	IsSystem           bool            `json:"is_system"`
+	HaEnabled          bool            `json:"ha_enabled"`
 	Duration           string          `json:"duration"`
 	AutoPrepaidRecycle bool            `json:"auto_prepaid_recycle,omitfalse"`
 	SecgroupId         string          `json:"secgrp_id"`
@@ -181,6 +209,8 @@ type ServerCreateInput struct {
 	ParentTaskId string `json:"__parent_task_id,omitempty"`
 	// default stroage type if host is given
 	DefaultStorageType string `json:"default_storage_type,omitempty"`
+	// Id of the guest whose disks are imported, set by migrate-to-kvm
+	V2VSource string `json:"__v2v_source__,omitempty"`
 }
 
 type ServerCloneInput struct {
@@ -196,6 +226,16 @@ type ServerCloneInput struct {
 	PreferHost string `json:"prefer_host_id"`
 }
 
+type ServerMigrateToKvmInput struct {
+	apis.Meta
+
+	// Name of the KVM guest, default to the name of the source guest with a -kvm suffix
+	Name      string `json:"name"`
+	AutoStart bool   `json:"auto_start"`
+
+	PreferHost string `json:"prefer_host_id"`
+}
+
 type ServerDeployInput struct {
 	apis.Meta
 
@@ -0,0 +1,47 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/util/choices"
+)
+
+const (
+	BUCKET_STATUS_AVAILABLE     = "available"
+	BUCKET_STATUS_CREATING      = "creating"
+	BUCKET_STATUS_CREATE_FAILED = "create_failed"
+	BUCKET_STATUS_DELETING      = "deleting"
+	BUCKET_STATUS_DELETE_FAILED = "delete_failed"
+	BUCKET_STATUS_UNKNOWN       = "unknown"
+)
+
+const (
+	BUCKET_ACL_PRIVATE            = "private"
+	BUCKET_ACL_PUBLIC_READ        = "public-read"
+	BUCKET_ACL_PUBLIC_READ_WRITE  = "public-read-write"
+	BUCKET_ACL_AUTHENTICATED_READ = "authenticated-read"
+)
+
+var BUCKET_ACLS = choices.NewChoices(
+	BUCKET_ACL_PRIVATE,
+	BUCKET_ACL_PUBLIC_READ,
+	BUCKET_ACL_PUBLIC_READ_WRITE,
+	BUCKET_ACL_AUTHENTICATED_READ,
+)
+
+const (
+	// max expire seconds of a presigned url
+	BUCKET_TEMP_URL_MAX_EXPIRE = 7 * 24 * 3600
+)
@@ -22,6 +22,7 @@ const (
 	CLOUD_PROVIDER_HUAWEI    = "Huawei"
 	CLOUD_PROVIDER_OPENSTACK = "OpenStack"
 	CLOUD_PROVIDER_UCLOUD    = "Ucloud"
+	CLOUD_PROVIDER_MOCK      = "Mock" // in-memory cloud for testing
 
 	CLOUD_PROVIDER_HEALTH_NORMAL       = "normal"       // 远端处于健康状态
 	CLOUD_PROVIDER_HEALTH_INSUFFICIENT = "insufficient" // 不足按需资源余额
@@ -43,5 +44,6 @@ var (
 		CLOUD_PROVIDER_HUAWEI,
 		CLOUD_PROVIDER_OPENSTACK,
 		CLOUD_PROVIDER_UCLOUD,
+		CLOUD_PROVIDER_MOCK,
 	}
 )
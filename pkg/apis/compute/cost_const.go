@@ -0,0 +1,42 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/util/choices"
+)
+
+const (
+	COST_SOURCE_PROVIDER  = "provider"
+	COST_SOURCE_ONPREMISE = "onpremise"
+
+	// price of an instance of the sku per day, the sku is the instance type
+	COST_RATE_RESOURCE_SERVER = "server"
+	// price of a vcpu per day, used when no server rate matches
+	COST_RATE_RESOURCE_CPU = "cpu"
+	// price of 1GB memory per day, used when no server rate matches
+	COST_RATE_RESOURCE_MEMORY = "memory"
+	// price of 1GB disk per day, the sku is the storage type
+	COST_RATE_RESOURCE_DISK = "disk"
+
+	COST_DEFAULT_CURRENCY = "CNY"
+)
+
+var COST_RATE_RESOURCE_TYPES = choices.NewChoices(
+	COST_RATE_RESOURCE_SERVER,
+	COST_RATE_RESOURCE_CPU,
+	COST_RATE_RESOURCE_MEMORY,
+	COST_RATE_RESOURCE_DISK,
+)
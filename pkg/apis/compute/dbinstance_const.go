@@ -0,0 +1,42 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	DBINSTANCE_STATUS_RUNNING              = "running"
+	DBINSTANCE_STATUS_DEPLOYING            = "deploying"
+	DBINSTANCE_STATUS_STARTING             = "starting"
+	DBINSTANCE_STATUS_START_FAILED         = "start_failed"
+	DBINSTANCE_STATUS_STOPPING             = "stopping"
+	DBINSTANCE_STATUS_STOP_FAILED          = "stop_failed"
+	DBINSTANCE_STATUS_STOPPED              = "stopped"
+	DBINSTANCE_STATUS_REBOOTING            = "rebooting"
+	DBINSTANCE_STATUS_REBOOT_FAILED        = "reboot_failed"
+	DBINSTANCE_STATUS_CHANGE_CONFIG        = "change_config"
+	DBINSTANCE_STATUS_CHANGE_CONFIG_FAILED = "change_config_failed"
+	// backing up, restoring, upgrading or migrating by the provider
+	DBINSTANCE_STATUS_MAINTENANCE = "maintenance"
+	DBINSTANCE_STATUS_DELETING    = "deleting"
+	DBINSTANCE_STATUS_FAILED      = "failed"
+	DBINSTANCE_STATUS_UNKNOWN     = "unknown"
+)
+
+const (
+	DBINSTANCE_ENGINE_MYSQL      = "MySQL"
+	DBINSTANCE_ENGINE_MARIADB    = "MariaDB"
+	DBINSTANCE_ENGINE_POSTGRESQL = "PostgreSQL"
+	DBINSTANCE_ENGINE_SQLSERVER  = "SQLServer"
+	DBINSTANCE_ENGINE_ORACLE     = "Oracle"
+)
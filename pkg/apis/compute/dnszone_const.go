@@ -0,0 +1,28 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	DNS_ZONE_STATUS_AVAILABLE = "available"
+	DNS_ZONE_STATUS_UNKNOWN   = "unknown"
+)
+
+const (
+	DNS_RECORD_STATUS_AVAILABLE   = "available"
+	DNS_RECORD_STATUS_SYNCING     = "syncing"
+	DNS_RECORD_STATUS_SYNC_FAILED = "sync_failed"
+	DNS_RECORD_STATUS_DELETING    = "deleting"
+	DNS_RECORD_STATUS_DELETE_FAIL = "delete_failed"
+)
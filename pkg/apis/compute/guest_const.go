@@ -124,6 +124,7 @@ const (
 	HYPERVISOR_HUAWEI    = "huawei"
 	HYPERVISOR_OPENSTACK = "openstack"
 	HYPERVISOR_UCLOUD    = "ucloud"
+	HYPERVISOR_MOCK      = "mock"
 
 	//	HYPERVISOR_DEFAULT = HYPERVISOR_KVM
 	HYPERVISOR_DEFAULT = HYPERVISOR_KVM
@@ -143,6 +144,7 @@ var HYPERVISORS = []string{HYPERVISOR_KVM,
 	HYPERVISOR_HUAWEI,
 	HYPERVISOR_OPENSTACK,
 	HYPERVISOR_UCLOUD,
+	HYPERVISOR_MOCK,
 }
 
 var PUBLIC_CLOUD_HYPERVISORS = []string{
@@ -153,6 +155,7 @@ var PUBLIC_CLOUD_HYPERVISORS = []string{
 	HYPERVISOR_HUAWEI,
 	HYPERVISOR_OPENSTACK,
 	HYPERVISOR_UCLOUD,
+	HYPERVISOR_MOCK,
 }
 
 // var HYPERVISORS = []string{HYPERVISOR_ALIYUN}
@@ -169,6 +172,7 @@ var HYPERVISOR_HOSTTYPE = map[string]string{
 	HYPERVISOR_HUAWEI:    HOST_TYPE_HUAWEI,
 	HYPERVISOR_OPENSTACK: HOST_TYPE_OPENSTACK,
 	HYPERVISOR_UCLOUD:    HOST_TYPE_UCLOUD,
+	HYPERVISOR_MOCK:      HOST_TYPE_MOCK,
 }
 
 var HOSTTYPE_HYPERVISOR = map[string]string{
@@ -183,4 +187,14 @@ var HOSTTYPE_HYPERVISOR = map[string]string{
 	HOST_TYPE_HUAWEI:     HYPERVISOR_HUAWEI,
 	HOST_TYPE_OPENSTACK:  HYPERVISOR_OPENSTACK,
 	HOST_TYPE_UCLOUD:     HYPERVISOR_UCLOUD,
+	HOST_TYPE_MOCK:       HYPERVISOR_MOCK,
 }
+
+const (
+	GUEST_PRIORITY_CLASS_NORMAL      = "normal"
+	GUEST_PRIORITY_CLASS_PREEMPTIBLE = "preemptible"
+
+	// what is done to the preemptible guests evicted for a normal guest
+	GUEST_PREEMPT_ACTION_STOP   = "stop"
+	GUEST_PREEMPT_ACTION_DELETE = "delete"
+)
@@ -30,6 +30,7 @@ const (
 	HOST_TYPE_HUAWEI    = "huawei"
 	HOST_TYPE_OPENSTACK = "openstack"
 	HOST_TYPE_UCLOUD    = "ucloud"
+	HOST_TYPE_MOCK      = "mock"
 
 	HOST_TYPE_DEFAULT = HOST_TYPE_HYPERVISOR
 
@@ -39,6 +40,13 @@ const (
 	HOST_OFFLINE  = "offline"
 	HOST_DISABLED = "offline"
 
+	// status of a KVM host being fenced after it stopped pinging the region
+	HOST_FENCING = "fencing"
+	// status of a failed KVM host whose HA guests are restarted elsewhere
+	HOST_DOWN = "down"
+	// status of a failed KVM host that could not be powered off through IPMI
+	HOST_FENCE_FAILED = "fence_failed"
+
 	NIC_TYPE_IPMI  = "ipmi"
 	NIC_TYPE_ADMIN = "admin"
 	// #NIC_TYPE_NORMAL = 'normal'
@@ -20,6 +20,8 @@ const (
 	GPU_VGA_TYPE    = "GPU-VGA" // # for display
 	USB_TYPE        = "USB"
 	NIC_TYPE        = "NIC"
+	NIC_VF_TYPE     = "NIC-VF" // # SR-IOV virtual function of a physical NIC
+	VGPU_TYPE       = "VGPU"   // # mediated device of a GPU
 
 	NVIDIA_VENDOR_ID = "10de"
 	AMD_VENDOR_ID    = "1002"
@@ -27,7 +29,7 @@ const (
 
 var VALID_GPU_TYPES = []string{GPU_HPC_TYPE, GPU_VGA_TYPE}
 
-var VALID_PASSTHROUGH_TYPES = []string{DIRECT_PCI_TYPE, USB_TYPE, NIC_TYPE, GPU_HPC_TYPE, GPU_VGA_TYPE}
+var VALID_PASSTHROUGH_TYPES = []string{DIRECT_PCI_TYPE, USB_TYPE, NIC_TYPE, GPU_HPC_TYPE, GPU_VGA_TYPE, VGPU_TYPE}
 
 var ID_VENDOR_MAP = map[string]string{
 	NVIDIA_VENDOR_ID: "NVIDIA",
@@ -0,0 +1,47 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/util/choices"
+)
+
+const (
+	NAT_STATUS_AVAILABLE     = "available"
+	NAT_STATUS_ALLOCATE      = "allocate"
+	NAT_STATUS_DEPLOYING     = "deploying"
+	NAT_STATUS_UNKNOWN       = "unknown"
+	NAT_STATUS_FAILED        = "failed"
+	NAT_STATUS_CREATE_FAILED = "create_failed"
+	NAT_STATUS_DELETING      = "deleting"
+	NAT_STATUS_DELETE_FAILED = "delete_failed"
+
+	NAT_SPEC_SMALL  = "small"
+	NAT_SPEC_MIDDLE = "middle"
+	NAT_SPEC_LARGE  = "large"
+	NAT_SPEC_XLARGE = "xlarge"
+)
+
+const (
+	NAT_PROTOCOL_TCP = "tcp"
+	NAT_PROTOCOL_UDP = "udp"
+	NAT_PROTOCOL_ANY = "any"
+)
+
+var NAT_PROTOCOLS = choices.NewChoices(
+	NAT_PROTOCOL_TCP,
+	NAT_PROTOCOL_UDP,
+	NAT_PROTOCOL_ANY,
+)
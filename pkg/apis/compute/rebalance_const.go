@@ -0,0 +1,47 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	// balance the allocated vcpu and memory ratio of hosts
+	REBALANCE_METRIC_ALLOCATION = "allocation"
+	// balance the cpu and memory usage of hosts measured by telegraf
+	REBALANCE_METRIC_LOAD = "load"
+)
+
+var REBALANCE_METRICS = []string{
+	REBALANCE_METRIC_ALLOCATION,
+	REBALANCE_METRIC_LOAD,
+}
+
+type SRebalanceMove struct {
+	GuestId   string `json:"guest_id"`
+	Guest     string `json:"guest"`
+	SrcHostId string `json:"src_host_id"`
+	SrcHost   string `json:"src_host"`
+	DstHostId string `json:"dst_host_id"`
+	DstHost   string `json:"dst_host"`
+}
+
+type SRebalancePlan struct {
+	Metric string `json:"metric"`
+	// difference between the most and the least loaded host
+	SpreadBefore float64 `json:"spread_before"`
+	SpreadAfter  float64 `json:"spread_after"`
+
+	Moves []SRebalanceMove `json:"moves"`
+	// guests of a draining host that can not be moved or fit on no other host
+	Unplaced []string `json:"unplaced"`
+}
@@ -0,0 +1,38 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	VPC_PEERING_STATUS_ACTIVE   = "active"
+	VPC_PEERING_STATUS_PENDING  = "pending"
+	VPC_PEERING_STATUS_INACTIVE = "inactive"
+	VPC_PEERING_STATUS_FAILED   = "failed"
+	VPC_PEERING_STATUS_DELETING = "deleting"
+	VPC_PEERING_STATUS_UNKNOWN  = "unknown"
+)
+
+const (
+	VPN_CONNECTION_STATUS_CONNECTED    = "connected"
+	VPN_CONNECTION_STATUS_DISCONNECTED = "disconnected"
+	VPN_CONNECTION_STATUS_PENDING      = "pending"
+	VPN_CONNECTION_STATUS_DELETING     = "deleting"
+	VPN_CONNECTION_STATUS_UNKNOWN      = "unknown"
+)
+
+const (
+	// keywords of the resources a route can be linked to through its next hop
+	ROUTE_NEXT_HOP_RESOURCE_VPC_PEERING    = "vpc_peering"
+	ROUTE_NEXT_HOP_RESOURCE_VPN_CONNECTION = "vpn_connection"
+)
@@ -0,0 +1,48 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	// classic wire backed by a physical bridge and VLAN on each host
+	WIRE_TYPE_VLAN = "vlan"
+	// overlay wire carried by VXLAN tunnels between the KVM hosts of a zone
+	WIRE_TYPE_VXLAN = "vxlan"
+
+	VXLAN_VNI_MIN = 1000
+	VXLAN_VNI_MAX = 16777215
+
+	VXLAN_DEFAULT_PORT = 4789
+)
+
+var (
+	WIRE_TYPES = []string{WIRE_TYPE_VLAN, WIRE_TYPE_VXLAN}
+)
+
+// SOverlayFdbEntry tells a host which VTEP a guest MAC/IP on an overlay wire
+// lives behind, used to populate the VXLAN forwarding and neighbour tables.
+type SOverlayFdbEntry struct {
+	Vni  int
+	Mac  string
+	Ip   string
+	Vtep string
+}
+
+// SOverlayNetwork is a network on an overlay wire, whose gateway every host
+// with guests in the vpc of the wire configures on its bridge of the VNI.
+type SOverlayNetwork struct {
+	Vni     int
+	Gateway string
+	Masklen int
+}
@@ -72,6 +72,44 @@ type ScheduleInput struct {
 	ServerConfig
 }
 
+// CapacitySpec is one guest spec of a capacity planning mix
+type CapacitySpec struct {
+	ScheduleInput
+
+	// Weight is the relative share of this spec in the mix, default 1
+	Weight int `json:"weight"`
+}
+
+// CapacityInput used by scheduler capacity api
+type CapacityInput struct {
+	apis.Meta
+
+	Specs []*CapacitySpec `json:"specs"`
+	// ExcludeHosts are hosts id or name simulated as under maintenance
+	ExcludeHosts []string `json:"exclude_hosts"`
+}
+
+// GangInput used by scheduler gang api, the guests of all members are placed
+// together or none of them
+type GangInput struct {
+	apis.Meta
+
+	SessionId string `json:"session_id"`
+	// Members are guest specs, Count guests of each are placed
+	Members []*ScheduleInput `json:"members"`
+	// Spreads are applied to the guests of all members
+	Spreads []*compute.SpreadConstraint `json:"spreads"`
+	// DryRun only plans without reserving the resources
+	DryRun bool `json:"dry_run"`
+}
+
+type GangOutput struct {
+	apis.Meta
+
+	SessionId string            `json:"session_id"`
+	Members   []*ScheduleOutput `json:"members"`
+}
+
 func (input ScheduleInput) ToConditionInput() *jsonutils.JSONDict {
 	ret := input.JSON(input)
 	// old condition compatible
@@ -94,6 +132,10 @@ type CandidateResource struct {
 	IsMaster        bool               `json:"is_master"`
 	IsSlave         bool               `json:"is_slave"`
 
+	// PreemptGuests are the preemptible guests on the host to evict
+	// before the guest is created
+	PreemptGuests []string `json:"preempt_guests"`
+
 	// Error means no candidate found, include reasons
 	Error string `json:"error"`
 }
@@ -102,4 +144,43 @@ type ScheduleOutput struct {
 	apis.Meta
 
 	Candidates []*CandidateResource `json:"candidates"`
+
+	// Explain tells why the candidates are chosen or the schedule failed
+	Explain *ScheduleExplain `json:"explain"`
+}
+
+type PredicateExplain struct {
+	Name    string   `json:"name"`
+	Passed  bool     `json:"passed"`
+	Reasons []string `json:"reasons"`
+}
+
+type PriorityScore struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+// CandidateExplain is how a candidate is filtered and scored
+type CandidateExplain struct {
+	HostId string `json:"host_id"`
+	Name   string `json:"name"`
+
+	// Predicates are executed in order, the ones after the first failure
+	// are skipped unless all predicates are always checked
+	Predicates []*PredicateExplain `json:"predicates"`
+	Scores     []*PriorityScore    `json:"scores"`
+	Score      string              `json:"score"`
+	Capacity   int64               `json:"capacity"`
+	// Selected is the count of guests placed on the candidate
+	Selected int64 `json:"selected"`
+}
+
+// ScheduleExplain is the decision of a schedule request
+type ScheduleExplain struct {
+	apis.Meta
+
+	SessionId  string              `json:"session_id"`
+	Request    *ScheduleInput      `json:"request"`
+	Candidates []*CandidateExplain `json:"candidates"`
+	Error      string              `json:"error"`
 }
@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	"yunion.io/x/jsonutils"
 )
 
@@ -63,3 +65,9 @@ func StatisticHandler(ctx context.Context, w http.ResponseWriter, r *http.Reques
 	result.Add(jsonutils.NewFloat(total.counter5XX.duration), "duration.5XX")
 	fmt.Fprintf(w, result.String())
 }
+
+// MetricsHandler exposes the metrics registered to the default prometheus registry,
+// it is not authenticated and only added by the services opted in
+func MetricsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	promhttp.Handler().ServeHTTP(w, r)
+}
@@ -718,7 +718,7 @@ func (dispatcher *DBModelDispatcher) Get(ctx context.Context, idStr string, quer
 	// log.Debugf("Get found %s", model)
 	var isAllow bool
 	if consts.IsRbacEnabled() {
-		isAllow = isObjectRbacAllowed(dispatcher.modelManager, model, userCred, policy.PolicyActionGet)
+		isAllow = isObjectRbacAllowed(dispatcher.modelManager, model, userCred, query, policy.PolicyActionGet)
 	} else {
 		isAllow = model.AllowGetDetails(ctx, userCred, query)
 	}
@@ -754,7 +754,7 @@ func (dispatcher *DBModelDispatcher) GetSpecific(ctx context.Context, idStr stri
 
 	var isAllow bool
 	if consts.IsRbacEnabled() {
-		isAllow = isObjectRbacAllowed(dispatcher.modelManager, model, userCred, policy.PolicyActionGet, spec)
+		isAllow = isObjectRbacAllowed(dispatcher.modelManager, model, userCred, query, policy.PolicyActionGet, spec)
 	} else {
 		funcName := fmt.Sprintf("AllowGetDetails%s", specCamel)
 
@@ -1207,7 +1207,7 @@ func objectPerformAction(dispatcher *DBModelDispatcher, model IModel, modelValue
 			ownerProjId, _ := fetchOwnerProjectId(ctx, dispatcher.modelManager, userCred, data)
 			isAllow = isClassActionRbacAllowed(dispatcher.modelManager, userCred, ownerProjId, policy.PolicyActionPerform, action)
 		} else {
-			isAllow = isObjectRbacAllowed(dispatcher.modelManager, model, userCred, policy.PolicyActionPerform, action)
+			isAllow = isObjectRbacAllowed(dispatcher.modelManager, model, userCred, data, policy.PolicyActionPerform, action)
 		}
 	} else {
 		allowFuncName := "Allow" + funcName
@@ -1315,7 +1315,7 @@ func (dispatcher *DBModelDispatcher) Update(ctx context.Context, idStr string, q
 
 	var isAllow bool
 	if consts.IsRbacEnabled() {
-		isAllow = isObjectRbacAllowed(dispatcher.modelManager, model, userCred, policy.PolicyActionUpdate)
+		isAllow = isObjectRbacAllowed(dispatcher.modelManager, model, userCred, data, policy.PolicyActionUpdate)
 	} else {
 		isAllow = model.AllowUpdateItem(ctx, userCred)
 	}
@@ -1348,7 +1348,7 @@ func deleteItem(manager IModelManager, model IModel, ctx context.Context, userCr
 
 	var isAllow bool
 	if consts.IsRbacEnabled() {
-		isAllow = isObjectRbacAllowed(manager, model, userCred, policy.PolicyActionDelete)
+		isAllow = isObjectRbacAllowed(manager, model, userCred, data, policy.PolicyActionDelete)
 	} else {
 		isAllow = model.AllowDeleteItem(ctx, userCred, query, data)
 	}
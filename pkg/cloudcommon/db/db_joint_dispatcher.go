@@ -157,7 +157,7 @@ func (dispatcher *DBJointModelDispatcher) Get(ctx context.Context, id1 string, i
 	}
 	var isAllow bool
 	if consts.IsRbacEnabled() {
-		isAllow = isJointObjectRbacAllowed(dispatcher.JointModelManager(), item, userCred, policy.PolicyActionGet)
+		isAllow = isJointObjectRbacAllowed(dispatcher.JointModelManager(), item, userCred, query, policy.PolicyActionGet)
 	} else {
 		isAllow = item.AllowGetJointDetails(ctx, userCred, query, item)
 	}
@@ -170,8 +170,8 @@ func (dispatcher *DBJointModelDispatcher) Get(ctx context.Context, id1 string, i
 func attachItems(dispatcher *DBJointModelDispatcher, master IStandaloneModel, slave IStandaloneModel, ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	var isAllow bool
 	if consts.IsRbacEnabled() {
-		isAllow = isObjectRbacAllowed(master.GetModelManager(), master, userCred, policy.PolicyActionPerform, "attach") &&
-			isObjectRbacAllowed(slave.GetModelManager(), slave, userCred, policy.PolicyActionPerform, "attach")
+		isAllow = isObjectRbacAllowed(master.GetModelManager(), master, userCred, data, policy.PolicyActionPerform, "attach") &&
+			isObjectRbacAllowed(slave.GetModelManager(), slave, userCred, data, policy.PolicyActionPerform, "attach")
 	} else {
 		isAllow = dispatcher.JointModelManager().AllowAttach(ctx, userCred, master, slave)
 	}
@@ -249,7 +249,7 @@ func (dispatcher *DBJointModelDispatcher) Update(ctx context.Context, id1 string
 
 	var isAllow bool
 	if consts.IsRbacEnabled() {
-		isAllow = isJointObjectRbacAllowed(dispatcher.JointModelManager(), item, userCred, policy.PolicyActionUpdate)
+		isAllow = isJointObjectRbacAllowed(dispatcher.JointModelManager(), item, userCred, data, policy.PolicyActionUpdate)
 	} else {
 		isAllow = item.AllowUpdateJointItem(ctx, userCred, item)
 	}
@@ -61,6 +61,9 @@ const (
 	ACT_STOP      = "stop"
 	ACT_STOP_FAIL = "stop_fail"
 
+	ACT_REBOOT      = "reboot"
+	ACT_REBOOT_FAIL = "reboot_fail"
+
 	ACT_RESIZING    = "resizing"
 	ACT_RESIZE      = "resize"
 	ACT_RESIZE_FAIL = "resize_fail"
@@ -69,6 +72,17 @@ const (
 	ACT_MIGRATE      = "migrate"
 	ACT_MIGRATE_FAIL = "migrate_fail"
 
+	ACT_REBALANCE      = "rebalance"
+	ACT_REBALANCE_FAIL = "rebalance_fail"
+
+	ACT_FENCE         = "fence"
+	ACT_FENCE_FAIL    = "fence_fail"
+	ACT_FAILOVER      = "failover"
+	ACT_FAILOVER_FAIL = "failover_fail"
+
+	ACT_PREEMPT      = "preempt"
+	ACT_PREEMPT_FAIL = "preempt_fail"
+
 	ACT_SPLIT = "net_split"
 	ACT_MERGE = "net_merge"
 
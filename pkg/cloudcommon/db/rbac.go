@@ -15,6 +15,11 @@
 package db
 
 import (
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
 	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
 	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
 	"yunion.io/x/onecloud/pkg/mcclient"
@@ -94,7 +99,44 @@ func isClassActionRbacAllowed(manager IModelManager, userCred mcclient.TokenCred
 	return result == rbacutils.AdminAllow
 }
 
-func isObjectRbacAllowed(manager IModelManager, model IModel, userCred mcclient.TokenCredential, action string, extra ...string) bool {
+// getObjectRbacInput collects the attributes to evaluate the conditions of policy rules,
+// "object" holds the fields of the target object together with its metadata (object.metadata)
+// and user tags without prefix (object.tags), "request" holds the query or body of the request
+func getObjectRbacInput(model IModel, userCred mcclient.TokenCredential, data jsonutils.JSONObject) jsonutils.JSONObject {
+	if !policy.PolicyManager.HasConditionalRules() {
+		return nil
+	}
+	object := jsonutils.NewDict()
+	if objJson, ok := jsonutils.Marshal(model).(*jsonutils.JSONDict); ok {
+		object = objJson
+	}
+	if _, ok := model.(IStandaloneModel); ok {
+		metadata, err := Metadata.GetAll(model, nil, userCred)
+		if err != nil {
+			log.Errorf("fetch metadata of %s %s fail %s", model.Keyword(), model.GetId(), err)
+		}
+		meta := jsonutils.NewDict()
+		tags := jsonutils.NewDict()
+		for k, v := range metadata {
+			meta.Add(jsonutils.NewString(v), k)
+			if strings.HasPrefix(k, USER_TAG_PREFIX) {
+				tags.Add(jsonutils.NewString(v), k[len(USER_TAG_PREFIX):])
+			}
+		}
+		object.Set("metadata", meta)
+		object.Set("tags", tags)
+	}
+	input := jsonutils.NewDict()
+	input.Add(object, "object")
+	if data != nil {
+		input.Add(data, "request")
+	} else {
+		input.Add(jsonutils.NewDict(), "request")
+	}
+	return input
+}
+
+func isObjectRbacAllowed(manager IModelManager, model IModel, userCred mcclient.TokenCredential, data jsonutils.JSONObject, action string, extra ...string) bool {
 	var requireAdmin bool
 	var isOwner bool
 
@@ -117,7 +159,9 @@ func isObjectRbacAllowed(manager IModelManager, model IModel, userCred mcclient.
 		requireAdmin = true
 	}
 
-	result := policy.PolicyManager.Allow(false, userCred, consts.GetServiceType(),
+	input := getObjectRbacInput(model, userCred, data)
+
+	result := policy.PolicyManager.AllowWithInput(false, userCred, input, consts.GetServiceType(),
 		manager.KeywordPlural(), action, extra...)
 	switch {
 	case result == rbacutils.GuestAllow:
@@ -128,13 +172,13 @@ func isObjectRbacAllowed(manager IModelManager, model IModel, userCred mcclient.
 		return true
 	}
 
-	result = policy.PolicyManager.Allow(true, userCred, consts.GetServiceType(),
+	result = policy.PolicyManager.AllowWithInput(true, userCred, input, consts.GetServiceType(),
 		manager.KeywordPlural(), action, extra...)
 	return result == rbacutils.AdminAllow
 }
 
-func isJointObjectRbacAllowed(manager IJointModelManager, item IJointModel, userCred mcclient.TokenCredential, action string, extra ...string) bool {
-	return isObjectRbacAllowed(manager, item.Master(), userCred, action, extra...)
+func isJointObjectRbacAllowed(manager IJointModelManager, item IJointModel, userCred mcclient.TokenCredential, data jsonutils.JSONObject, action string, extra ...string) bool {
+	return isObjectRbacAllowed(manager, item.Master(), userCred, data, action, extra...)
 }
 
 func IsAdminAllowList(userCred mcclient.TokenCredential, manager IModelManager) bool {
@@ -174,6 +174,21 @@ func (cli *SEtcdClient) put(ctx context.Context, key string, val string, session
 	}
 }
 
+// PutWithTTL stores the key with its own lease, so the key expires after ttl
+// seconds independent of the client's keepalive session
+func (cli *SEtcdClient) PutWithTTL(ctx context.Context, key string, val string, ttl int64) error {
+	nctx, cancel := context.WithTimeout(ctx, cli.requestTimeout)
+	defer cancel()
+
+	resp, err := cli.client.Grant(nctx, ttl)
+	if err != nil {
+		return err
+	}
+	key = cli.getKey(key)
+	_, err = cli.client.Put(nctx, key, val, clientv3.WithLease(resp.ID))
+	return err
+}
+
 func (cli *SEtcdClient) Get(ctx context.Context, key string) ([]byte, error) {
 	nctx, cancel := context.WithTimeout(ctx, cli.requestTimeout)
 	defer cancel()
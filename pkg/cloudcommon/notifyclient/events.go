@@ -24,4 +24,5 @@ const (
 	SERVER_DELETED_ADMIN = "SERVER_DELETED_ADMIN"
 	SERVER_REBUILD_ROOT  = "SERVER_REBUILD_ROOT"
 	SERVER_CHANGE_FLAVOR = "SERVER_CHANGE_FLAVOR"
+	SERVER_PREEMPTED     = "SERVER_PREEMPTED"
 )
@@ -62,6 +62,9 @@ type SPolicyManager struct {
 	defaultPolicy *rbacutils.SRbacPolicy
 	lastSync      time.Time
 
+	// whether any of the fetched policies contains conditional rules
+	hasConditions bool
+
 	failedRetryInterval time.Duration
 	refreshInterval     time.Duration
 
@@ -159,6 +162,7 @@ func (manager *SPolicyManager) SyncOnce() error {
 
 	manager.policies = policies
 	manager.adminPolicies = adminPolicies
+	manager.hasConditions = hasConditionalRules(policies) || hasConditionalRules(adminPolicies)
 
 	manager.lastSync = time.Now()
 	manager.cache.Invalidate()
@@ -166,6 +170,21 @@ func (manager *SPolicyManager) SyncOnce() error {
 	return nil
 }
 
+func hasConditionalRules(policies map[string]rbacutils.SRbacPolicy) bool {
+	for _, p := range policies {
+		if p.HasConditionalRules() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConditionalRules tells whether the attributes of the target object
+// need to be collected to evaluate the policies
+func (manager *SPolicyManager) HasConditionalRules() bool {
+	return manager.hasConditions
+}
+
 func (manager *SPolicyManager) sync() {
 	err := manager.SyncOnce()
 	var interval time.Duration
@@ -218,6 +237,17 @@ func (manager *SPolicyManager) Allow(isAdmin bool, userCred mcclient.TokenCreden
 	}
 }
 
+// AllowWithInput is similar to Allow, besides evaluating the conditions of the
+// rules against input, which consists of the attributes of the target object
+// and the request. The result is not cached if any condition is involved
+func (manager *SPolicyManager) AllowWithInput(isAdmin bool, userCred mcclient.TokenCredential, input jsonutils.JSONObject, service string, resource string, action string, extra ...string) rbacutils.TRbacResult {
+	if input == nil || !manager.hasConditions {
+		return manager.Allow(isAdmin, userCred, service, resource, action, extra...)
+	}
+	result, _ := manager.allowWithInput(isAdmin, userCred, input, service, resource, action, extra...)
+	return result
+}
+
 func (manager *SPolicyManager) findPolicyByName(isAdmin bool, name string) *rbacutils.SRbacPolicy {
 	var policies map[string]rbacutils.SRbacPolicy
 	if isAdmin {
@@ -235,29 +265,41 @@ func (manager *SPolicyManager) findPolicyByName(isAdmin bool, name string) *rbac
 }
 
 func (manager *SPolicyManager) allowWithoutCache(isAdmin bool, userCred mcclient.TokenCredential, service string, resource string, action string, extra ...string) rbacutils.TRbacResult {
+	result, _ := manager.allowWithInput(isAdmin, userCred, nil, service, resource, action, extra...)
+	return result
+}
+
+// allowWithInput returns the result and the condition of the rule that decides the result
+func (manager *SPolicyManager) allowWithInput(isAdmin bool, userCred mcclient.TokenCredential, input jsonutils.JSONObject, service string, resource string, action string, extra ...string) (rbacutils.TRbacResult, string) {
 	var policies map[string]rbacutils.SRbacPolicy
 	if isAdmin {
 		policies = manager.adminPolicies
 	} else {
 		policies = manager.policies
 	}
+	return manager.allowWithPolicies(policies, isAdmin, userCred, input, service, resource, action, extra...)
+}
+
+func (manager *SPolicyManager) allowWithPolicies(policies map[string]rbacutils.SRbacPolicy, isAdmin bool, userCred mcclient.TokenCredential, input jsonutils.JSONObject, service string, resource string, action string, extra ...string) (rbacutils.TRbacResult, string) {
 	if policies == nil {
 		log.Warningf("no policies fetched")
-		return rbacutils.Deny
+		return rbacutils.Deny, ""
 	}
 	findMatchRule := false
 	findMatchPolicy := false
 	currentPriv := rbacutils.Deny
+	condition := ""
 	for _, p := range policies {
 		if !p.Match(userCred) {
 			continue
 		}
 		findMatchPolicy = true
-		rule := p.GetMatchRule(service, resource, action, extra...)
+		rule := p.GetMatchRuleWithInput(input, service, resource, action, extra...)
 		if rule != nil {
 			findMatchRule = true
 			if currentPriv.StricterThan(rule.Result) {
 				currentPriv = rule.Result
+				condition = rule.Condition
 			}
 		}
 	}
@@ -271,17 +313,18 @@ func (manager *SPolicyManager) allowWithoutCache(isAdmin bool, userCred mcclient
 		}
 	}
 	if !isAdmin && manager.defaultPolicy != nil {
-		rule := manager.defaultPolicy.GetMatchRule(service, resource, action, extra...)
+		rule := manager.defaultPolicy.GetMatchRuleWithInput(input, service, resource, action, extra...)
 		if rule != nil {
 			if currentPriv.StricterThan(rule.Result) {
 				currentPriv = rule.Result
+				condition = rule.Condition
 			}
 		}
 	}
 	if consts.IsRbacDebug() {
-		log.Debugf("[RBAC: %v] %s %s %s %#v permission %s userCred: %s", isAdmin, service, resource, action, extra, currentPriv, userCred)
+		log.Debugf("[RBAC: %v] %s %s %s %#v permission %s condition %q userCred: %s", isAdmin, service, resource, action, extra, currentPriv, condition, userCred)
 	}
-	return unifyRbacResult(isAdmin, currentPriv)
+	return unifyRbacResult(isAdmin, currentPriv), condition
 }
 
 func unifyRbacResult(isAdmin bool, currentPriv rbacutils.TRbacResult) rbacutils.TRbacResult {
@@ -307,20 +350,31 @@ func exportRbacResult(currentPriv rbacutils.TRbacResult) rbacutils.TRbacResult {
 	return currentPriv
 }
 
-func (manager *SPolicyManager) explainPolicy(userCred mcclient.TokenCredential, policyReq jsonutils.JSONObject, name string) ([]string, rbacutils.TRbacResult, error) {
-	isAdmin, request, result, err := manager.explainPolicyInternal(userCred, policyReq, name)
+func (manager *SPolicyManager) explainPolicy(userCred mcclient.TokenCredential, policyReq jsonutils.JSONObject, name string) ([]string, rbacutils.TRbacResult, string, bool, error) {
+	isAdmin, request, result, condition, hasInput, err := manager.explainPolicyInternal(userCred, policyReq, name)
+	if err != nil {
+		return request, result, condition, hasInput, err
+	}
 	if !isAdmin && isAdminResource(request[0], request[1]) && result == rbacutils.OwnerAllow {
 		result = rbacutils.Deny
 	}
 	result = exportRbacResult(result)
-	return request, result, err
+	return request, result, condition, hasInput, nil
 }
 
-func (manager *SPolicyManager) explainPolicyInternal(userCred mcclient.TokenCredential, policyReq jsonutils.JSONObject, name string) (bool, []string, rbacutils.TRbacResult, error) {
+// explainPolicyInternal explains a request in the form of [is_admin, service, resource, action, extra...],
+// a trailing dict, if presents, is taken as the input to evaluate the conditions of rules
+func (manager *SPolicyManager) explainPolicyInternal(userCred mcclient.TokenCredential, policyReq jsonutils.JSONObject, name string) (bool, []string, rbacutils.TRbacResult, string, bool, error) {
 	policySeq, err := policyReq.GetArray()
-	if err != nil {
-		return false, nil, rbacutils.Deny, httperrors.NewInputParameterError("invalid format")
+	if err != nil || len(policySeq) == 0 {
+		return false, nil, rbacutils.Deny, "", false, httperrors.NewInputParameterError("invalid format")
 	}
+	var input jsonutils.JSONObject
+	if inputDict, ok := policySeq[len(policySeq)-1].(*jsonutils.JSONDict); ok {
+		input = inputDict
+		policySeq = policySeq[:len(policySeq)-1]
+	}
+	hasInput := input != nil
 	service := rbacutils.WILD_MATCH
 	resource := rbacutils.WILD_MATCH
 	action := rbacutils.WILD_MATCH
@@ -349,28 +403,34 @@ func (manager *SPolicyManager) explainPolicyInternal(userCred mcclient.TokenCred
 	isAdmin, _ := policySeq[0].Bool()
 	if !consts.IsRbacEnabled() {
 		if !isAdmin {
-			return isAdmin, reqStrs, rbacutils.OwnerAllow, nil
+			return isAdmin, reqStrs, rbacutils.OwnerAllow, "", hasInput, nil
 		} else if isAdmin && userCred.HasSystemAdminPrivilege() {
-			return isAdmin, reqStrs, rbacutils.AdminAllow, nil
+			return isAdmin, reqStrs, rbacutils.AdminAllow, "", hasInput, nil
 		} else {
-			return isAdmin, reqStrs, rbacutils.Deny, httperrors.NewForbiddenError("operation not allowed")
+			return isAdmin, reqStrs, rbacutils.Deny, "", hasInput, httperrors.NewForbiddenError("operation not allowed")
 		}
 	}
 	if len(name) == 0 {
-		return isAdmin, reqStrs, manager.allowWithoutCache(isAdmin, userCred, service, resource, action, extra...), nil
+		result, condition := manager.allowWithInput(isAdmin, userCred, input, service, resource, action, extra...)
+		return isAdmin, reqStrs, result, condition, hasInput, nil
 	}
 	policy := manager.findPolicyByName(isAdmin, name)
 	if policy == nil {
-		return isAdmin, reqStrs, rbacutils.Deny, httperrors.NewNotFoundError("policy %s not found", name)
+		return isAdmin, reqStrs, rbacutils.Deny, "", hasInput, httperrors.NewNotFoundError("policy %s not found", name)
 	}
-	rule := policy.GetMatchRule(service, resource, action, extra...)
+	rule := policy.GetMatchRuleWithInput(input, service, resource, action, extra...)
 	result := rbacutils.Deny
+	condition := ""
 	if rule != nil {
 		result = rule.Result
+		condition = rule.Condition
 	}
-	return isAdmin, reqStrs, unifyRbacResult(isAdmin, result), nil
+	return isAdmin, reqStrs, unifyRbacResult(isAdmin, result), condition, hasInput, nil
 }
 
+// ExplainRpc explains the requests in params, the result of each request is appended
+// to the request. For the requests carrying input for conditions, the condition of the
+// rule which decides the result is appended as well, empty if no condition involved
 func (manager *SPolicyManager) ExplainRpc(userCred mcclient.TokenCredential, params jsonutils.JSONObject, name string) (jsonutils.JSONObject, error) {
 	paramDict, err := params.GetMap()
 	if err != nil {
@@ -378,11 +438,14 @@ func (manager *SPolicyManager) ExplainRpc(userCred mcclient.TokenCredential, par
 	}
 	ret := jsonutils.NewDict()
 	for key, policyReq := range paramDict {
-		reqStrs, result, err := manager.explainPolicy(userCred, policyReq, name)
+		reqStrs, result, condition, hasInput, err := manager.explainPolicy(userCred, policyReq, name)
 		if err != nil {
 			return nil, err
 		}
 		reqStrs = append(reqStrs, string(result))
+		if hasInput {
+			reqStrs = append(reqStrs, condition)
+		}
 		ret.Add(jsonutils.NewStringArray(reqStrs), key)
 	}
 	return ret, nil
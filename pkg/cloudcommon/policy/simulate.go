@@ -0,0 +1,424 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+var (
+	simulateActions = []string{
+		PolicyActionList,
+		PolicyActionGet,
+		PolicyActionCreate,
+		PolicyActionUpdate,
+		PolicyActionDelete,
+		PolicyActionPerform,
+	}
+)
+
+type SPolicySubject struct {
+	User    string
+	Domain  string
+	Project string
+	Roles   []string
+}
+
+func (subject SPolicySubject) token() mcclient.TokenCredential {
+	return &mcclient.SSimpleToken{
+		Domain:  subject.Domain,
+		User:    subject.User,
+		Project: subject.Project,
+		Roles:   strings.Join(subject.Roles, ","),
+	}
+}
+
+func (subject SPolicySubject) String() string {
+	return fmt.Sprintf("%s@%s[%s]", subject.User, subject.Project, strings.Join(subject.Roles, ","))
+}
+
+// SPolicySimulateInput describes a candidate change of policy, the policy named Name
+// is replaced by Policy, or removed if Delete is true, then the requests are evaluated
+// for the subjects against both the current and the candidate policies
+type SPolicySimulateInput struct {
+	Name     string
+	Policy   jsonutils.JSONObject
+	Delete   bool
+	Subjects []SPolicySubject
+	Requests [][]string
+	// input to evaluate the conditions of rules
+	Input       jsonutils.JSONObject
+	ChangedOnly bool
+}
+
+type SPolicySimulateResult struct {
+	Subject   string
+	IsAdmin   bool
+	Service   string
+	Resource  string
+	Action    string
+	Extra     []string
+	Current   rbacutils.TRbacResult
+	Candidate rbacutils.TRbacResult
+	Changed   bool
+}
+
+type SPolicySimulateOutput struct {
+	Total   int
+	Changed int
+	Results []SPolicySimulateResult
+}
+
+func (manager *SPolicyManager) currentPolicies() (map[string]rbacutils.SRbacPolicy, map[string]rbacutils.SRbacPolicy, error) {
+	manager.lock.Lock()
+	policies, adminPolicies := manager.policies, manager.adminPolicies
+	manager.lock.Unlock()
+	if policies != nil || adminPolicies != nil {
+		return policies, adminPolicies, nil
+	}
+	// rbac is not enabled for this service, fetch once for simulation
+	return fetchPolicies()
+}
+
+func copyPolicies(policies map[string]rbacutils.SRbacPolicy) map[string]rbacutils.SRbacPolicy {
+	ret := make(map[string]rbacutils.SRbacPolicy)
+	for k, p := range policies {
+		ret[k] = p
+	}
+	return ret
+}
+
+// simulateRequests enumerates the requests covered by the rules of the policies,
+// with wildcard actions expanded to all of the standard actions
+func simulateRequests(policyMaps ...map[string]rbacutils.SRbacPolicy) [][]string {
+	requests := make(map[string][]string)
+	for _, policies := range policyMaps {
+		for _, p := range policies {
+			for _, rule := range p.Rules {
+				service := rule.Service
+				if len(service) == 0 {
+					service = rbacutils.WILD_MATCH
+				}
+				resource := rule.Resource
+				if len(resource) == 0 {
+					resource = rbacutils.WILD_MATCH
+				}
+				actions := []string{rule.Action}
+				if len(rule.Action) == 0 || rule.Action == rbacutils.WILD_MATCH {
+					actions = simulateActions
+				}
+				for _, action := range actions {
+					req := []string{service, resource, action}
+					req = append(req, rule.Extra...)
+					requests[strings.Join(req, ":")] = req
+				}
+			}
+		}
+	}
+	keys := make([]string, 0, len(requests))
+	for k := range requests {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make([][]string, len(keys))
+	for i, k := range keys {
+		ret[i] = requests[k]
+	}
+	return ret
+}
+
+func (manager *SPolicyManager) simulateResult(policies map[string]rbacutils.SRbacPolicy, isAdmin bool, userCred mcclient.TokenCredential, input jsonutils.JSONObject, req []string) rbacutils.TRbacResult {
+	service, resource, action := req[0], req[1], req[2]
+	result, _ := manager.allowWithPolicies(policies, isAdmin, userCred, input, service, resource, action, req[3:]...)
+	if !isAdmin && isAdminResource(service, resource) && result == rbacutils.OwnerAllow {
+		result = rbacutils.Deny
+	}
+	return exportRbacResult(result)
+}
+
+// Simulate evaluates the requests of the subjects against the current policies and
+// the policies with the candidate change applied, and reports the differences
+func (manager *SPolicyManager) Simulate(input SPolicySimulateInput) (*SPolicySimulateOutput, error) {
+	if len(input.Name) == 0 {
+		return nil, httperrors.NewMissingParameterError("name")
+	}
+	if len(input.Subjects) == 0 {
+		return nil, httperrors.NewMissingParameterError("subjects")
+	}
+	candidate := rbacutils.SRbacPolicy{}
+	if !input.Delete {
+		if input.Policy == nil {
+			return nil, httperrors.NewMissingParameterError("policy")
+		}
+		err := candidate.Decode(input.Policy)
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("invalid policy: %s", err)
+		}
+	}
+
+	policies, adminPolicies, err := manager.currentPolicies()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	candPolicies := copyPolicies(policies)
+	candAdminPolicies := copyPolicies(adminPolicies)
+	delete(candPolicies, input.Name)
+	delete(candAdminPolicies, input.Name)
+	if !input.Delete {
+		if candidate.IsAdmin {
+			candAdminPolicies[input.Name] = candidate
+		} else {
+			candPolicies[input.Name] = candidate
+		}
+	}
+
+	requests := input.Requests
+	if len(requests) == 0 {
+		requests = simulateRequests(policies, adminPolicies, candPolicies, candAdminPolicies)
+	}
+
+	output := &SPolicySimulateOutput{Results: make([]SPolicySimulateResult, 0)}
+	for _, subject := range input.Subjects {
+		userCred := subject.token()
+		for _, req := range requests {
+			if len(req) < 3 {
+				return nil, httperrors.NewInputParameterError("invalid request %s, should be service, resource and action", req)
+			}
+			for _, isAdmin := range []bool{false, true} {
+				var current, cand rbacutils.TRbacResult
+				if isAdmin {
+					current = manager.simulateResult(adminPolicies, true, userCred, input.Input, req)
+					cand = manager.simulateResult(candAdminPolicies, true, userCred, input.Input, req)
+				} else {
+					current = manager.simulateResult(policies, false, userCred, input.Input, req)
+					cand = manager.simulateResult(candPolicies, false, userCred, input.Input, req)
+				}
+				result := SPolicySimulateResult{
+					Subject:   subject.String(),
+					IsAdmin:   isAdmin,
+					Service:   req[0],
+					Resource:  req[1],
+					Action:    req[2],
+					Extra:     req[3:],
+					Current:   current,
+					Candidate: cand,
+					Changed:   current != cand,
+				}
+				output.Total += 1
+				if result.Changed {
+					output.Changed += 1
+				}
+				if result.Changed || !input.ChangedOnly {
+					output.Results = append(output.Results, result)
+				}
+			}
+		}
+	}
+	return output, nil
+}
+
+// AddPolicySimulateHandler registers the simulation API, the policies are shared
+// by all of the services, so it is only served by the region service, which
+// is the endpoint of the rbac client module
+func AddPolicySimulateHandler(prefix string, app *appsrv.Application) {
+	app.AddHandler2("POST", fmt.Sprintf("%s/rbac/simulate", prefix), auth.Authenticate(policySimulateHandler), nil, "rbac_simulate", nil)
+}
+
+func fetchSimulateStrings(obj jsonutils.JSONObject) ([]string, bool) {
+	arr, ok := obj.(*jsonutils.JSONArray)
+	if !ok {
+		return nil, false
+	}
+	ret := make([]string, arr.Size())
+	for i := range ret {
+		elem, _ := arr.GetAt(i)
+		str, ok := elem.(*jsonutils.JSONString)
+		if !ok {
+			return nil, false
+		}
+		ret[i], _ = str.GetString()
+	}
+	return ret, true
+}
+
+func fetchSimulateInput(body jsonutils.JSONObject) (SPolicySimulateInput, error) {
+	input := SPolicySimulateInput{}
+	input.Name, _ = body.GetString("name")
+	input.Delete = jsonutils.QueryBoolean(body, "delete", false)
+	input.ChangedOnly = jsonutils.QueryBoolean(body, "changed_only", false)
+	if body.Contains("input") {
+		input.Input, _ = body.Get("input")
+		if _, ok := input.Input.(*jsonutils.JSONDict); !ok {
+			return input, httperrors.NewInputParameterError("invalid input %s, should be a dict", input.Input)
+		}
+	}
+	if body.Contains("policy") {
+		blob, _ := body.Get("policy")
+		if blobStr, ok := blob.(*jsonutils.JSONString); ok {
+			yaml, _ := blobStr.GetString()
+			var err error
+			blob, err = jsonutils.ParseYAML(yaml)
+			if err != nil {
+				return input, httperrors.NewInputParameterError("invalid policy: %s", err)
+			}
+		}
+		if _, ok := blob.(*jsonutils.JSONDict); !ok {
+			return input, httperrors.NewInputParameterError("invalid policy, should be a dict")
+		}
+		input.Policy = blob
+	}
+	if body.Contains("subjects") {
+		obj, _ := body.Get("subjects")
+		arr, ok := obj.(*jsonutils.JSONArray)
+		if !ok {
+			return input, httperrors.NewInputParameterError("invalid subjects, should be an array")
+		}
+		subjects, _ := arr.GetArray()
+		for i := range subjects {
+			if _, ok := subjects[i].(*jsonutils.JSONDict); !ok {
+				return input, httperrors.NewInputParameterError("invalid subject %s, should be a dict", subjects[i])
+			}
+			subject := SPolicySubject{}
+			subject.User, _ = subjects[i].GetString("user")
+			subject.Domain, _ = subjects[i].GetString("domain")
+			subject.Project, _ = subjects[i].GetString("project")
+			if subjects[i].Contains("roles") {
+				roles, _ := subjects[i].Get("roles")
+				if roleStr, ok := roles.(*jsonutils.JSONString); ok {
+					str, _ := roleStr.GetString()
+					if len(str) > 0 {
+						subject.Roles = strings.Split(str, ",")
+					}
+				} else if subject.Roles, ok = fetchSimulateStrings(roles); !ok {
+					return input, httperrors.NewInputParameterError("invalid roles %s of subject, should be strings", roles)
+				}
+			}
+			if len(subject.User) == 0 && len(subject.Roles) == 0 {
+				return input, httperrors.NewInputParameterError("invalid subject %s, either user or roles is required", subjects[i])
+			}
+			if len(subject.Roles) == 0 && len(subject.Project) == 0 {
+				return input, httperrors.NewInputParameterError("invalid subject %s, project is required to resolve the roles of user", subjects[i])
+			}
+			input.Subjects = append(input.Subjects, subject)
+		}
+	}
+	if body.Contains("requests") {
+		obj, _ := body.Get("requests")
+		arr, ok := obj.(*jsonutils.JSONArray)
+		if !ok {
+			return input, httperrors.NewInputParameterError("invalid requests, should be an array")
+		}
+		requests, _ := arr.GetArray()
+		for i := range requests {
+			req, ok := fetchSimulateStrings(requests[i])
+			if !ok || len(req) < 3 {
+				return input, httperrors.NewInputParameterError("invalid request %s, should be service, resource and action", requests[i])
+			}
+			input.Requests = append(input.Requests, req)
+		}
+	}
+	return input, nil
+}
+
+// resolveSubjectRoles fills the roles of a subject given by user only with
+// the effective role assignments of the user in the project from keystone
+func resolveSubjectRoles(s *mcclient.ClientSession, subject *SPolicySubject) error {
+	if len(subject.Roles) > 0 {
+		return nil
+	}
+	query := jsonutils.NewDict()
+	if len(subject.Domain) > 0 {
+		domainId, err := modules.Domains.GetId(s, subject.Domain, nil)
+		if err != nil {
+			return err
+		}
+		query.Add(jsonutils.NewString(domainId), "domain_id")
+	}
+	userId, err := modules.UsersV3.GetId(s, subject.User, query)
+	if err != nil {
+		return err
+	}
+	projectId, err := modules.Projects.GetId(s, subject.Project, query)
+	if err != nil {
+		return err
+	}
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.JSONNull, "include_names")
+	params.Add(jsonutils.JSONNull, "effective")
+	params.Add(jsonutils.NewString(userId), "user", "id")
+	params.Add(jsonutils.NewString(projectId), "scope", "project", "id")
+	result, err := modules.RoleAssignments.List(s, params)
+	if err != nil {
+		return err
+	}
+	for _, assignment := range result.Data {
+		role, _ := assignment.GetString("role", "name")
+		if len(role) > 0 && !utils.IsInStringArray(role, subject.Roles) {
+			subject.Roles = append(subject.Roles, role)
+		}
+	}
+	if len(subject.Roles) == 0 {
+		return httperrors.NewInputParameterError("user %s has no role in project %s", subject.User, subject.Project)
+	}
+	return nil
+}
+
+func policySimulateHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userCred := auth.FetchUserCredential(ctx, FilterPolicyCredential)
+	if !userCred.IsAdminAllow("identity", "policies", PolicyActionPerform, "simulate") {
+		httperrors.ForbiddenError(w, "not allow to simulate policies")
+		return
+	}
+	body, err := appsrv.FetchJSON(r)
+	if err != nil {
+		httperrors.InputParameterError(w, "invalid request body: %s", err)
+		return
+	}
+	input, err := fetchSimulateInput(body)
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return
+	}
+	s := auth.GetAdminSession(ctx, consts.GetRegion(), "v1")
+	for i := range input.Subjects {
+		if err := resolveSubjectRoles(s, &input.Subjects[i]); err != nil {
+			httperrors.GeneralServerError(w, err)
+			return
+		}
+	}
+	output, err := PolicyManager.Simulate(input)
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return
+	}
+	log.Infof("%s simulates policy %s: %d/%d changed", userCred.GetUserName(), input.Name, output.Changed, output.Total)
+	appsrv.SendJSON(w, jsonutils.Marshal(output))
+}
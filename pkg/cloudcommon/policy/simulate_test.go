@@ -0,0 +1,99 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"sync"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+func TestSPolicyManager_Simulate(t *testing.T) {
+	decode := func(policyStr string) rbacutils.SRbacPolicy {
+		policyJson, err := jsonutils.ParseString(policyStr)
+		if err != nil {
+			t.Fatalf("parse policy %s", err)
+		}
+		policy := rbacutils.SRbacPolicy{}
+		err = policy.Decode(policyJson)
+		if err != nil {
+			t.Fatalf("decode policy %s", err)
+		}
+		return policy
+	}
+	manager := &SPolicyManager{
+		lock: &sync.Mutex{},
+		policies: map[string]rbacutils.SRbacPolicy{
+			"projectmember": decode(`{"roles": ["member"], "policy": {"compute": {"servers": "allow"}}}`),
+		},
+		adminPolicies: map[string]rbacutils.SRbacPolicy{},
+	}
+	candidate, _ := jsonutils.ParseString(`{"roles": ["member"], "policy": {"compute": {"servers": {"*": "allow", "delete": "deny"}}}}`)
+	output, err := manager.Simulate(SPolicySimulateInput{
+		Name:   "projectmember",
+		Policy: candidate,
+		Subjects: []SPolicySubject{
+			{User: "alice", Project: "demo", Roles: []string{"member"}},
+			{User: "bob", Project: "demo", Roles: []string{"guest"}},
+		},
+		ChangedOnly: true,
+	})
+	if err != nil {
+		t.Fatalf("simulate error %s", err)
+	}
+	if output.Changed != 1 || len(output.Results) != 1 {
+		t.Fatalf("expect 1 changed result, got %s", jsonutils.Marshal(output))
+	}
+	result := output.Results[0]
+	if result.Subject != "alice@demo[member]" || result.IsAdmin || result.Action != PolicyActionDelete ||
+		result.Current != rbacutils.Allow || result.Candidate != rbacutils.Deny {
+		t.Errorf("unexpected result %s", jsonutils.Marshal(result))
+	}
+}
+
+func TestFetchSimulateInput(t *testing.T) {
+	cases := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"name":"p","subjects":[{"user":"alice","roles":["member"]}],"requests":[["compute","servers","list"]]}`, true},
+		{`{"name":"p","subjects":[{"roles":"member,admin"}],"policy":"policy:\n  compute: allow"}`, true},
+		{`{"name":"p","subjects":["alice"]}`, false},
+		{`{"name":"p","subjects":[{"project":"demo"}]}`, false},
+		{`{"name":"p","subjects":[{"user":"alice","project":"demo"}]}`, true},
+		{`{"name":"p","subjects":[{"user":"alice"}]}`, false},
+		{`{"name":"p","subjects":[{"user":"alice","roles":[1]}]}`, false},
+		{`{"name":"p","subjects":{"user":"alice"}}`, false},
+		{`{"name":"p","requests":[["compute","servers"]]}`, false},
+		{`{"name":"p","requests":[["compute","servers",{}]]}`, false},
+		{`{"name":"p","input":"alice"}`, false},
+		{`{"name":"p","policy":"allow"}`, false},
+	}
+	for _, c := range cases {
+		body, err := jsonutils.ParseString(c.body)
+		if err != nil {
+			t.Fatalf("parse body %s: %s", c.body, err)
+		}
+		_, err = fetchSimulateInput(body)
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error %s", c.body, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("%s: expect error", c.body)
+		}
+	}
+}
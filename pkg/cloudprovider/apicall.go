@@ -0,0 +1,297 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"yunion.io/x/log"
+)
+
+const (
+	// the provider refused to serve the call for now, e.g. throttling,
+	// the call is retried with exponential backoff
+	API_ERROR_THROTTLED = "throttled"
+	// the provider failed to serve the call, counted by the circuit breaker
+	API_ERROR_SERVER = "server"
+	// the call itself is invalid, e.g. the resource does not exist
+	API_ERROR_CLIENT = "client"
+
+	API_RESULT_SUCCESS  = "success"
+	API_RESULT_REJECTED = "rejected"
+)
+
+// ErrApiCircuitOpen is returned without calling the provider while the
+// api of the account keeps failing
+var ErrApiCircuitOpen = errors.New("api circuit open")
+
+// FApiErrorClassifier returns one of API_ERROR_* for a failed call
+type FApiErrorClassifier func(err error) string
+
+// ClassifyApiError matches the error message against the error codes of
+// the provider, errors not matched are taken as client errors
+func ClassifyApiError(err error, throttleErrs []string, serverErrs []string) string {
+	switch {
+	case err == ErrNotFound || err == ErrNotImplemented || err == ErrNotSupported:
+		return API_ERROR_CLIENT
+	case IsError(err, throttleErrs):
+		return API_ERROR_THROTTLED
+	case IsError(err, serverErrs):
+		return API_ERROR_SERVER
+	}
+	return API_ERROR_CLIENT
+}
+
+type SApiCallConfig struct {
+	// token bucket of each api of an account
+	Qps   float64
+	Burst int
+
+	// retries of throttled calls, the interval doubles from BackoffBase up to BackoffMax
+	MaxRetries  int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	// the circuit of an api opens after BreakerFailures consecutive failures,
+	// and a trial call is let through after BreakerCooldown
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+var (
+	apiCallConfig = SApiCallConfig{
+		Qps:             10,
+		Burst:           20,
+		MaxRetries:      5,
+		BackoffBase:     time.Second,
+		BackoffMax:      30 * time.Second,
+		BreakerFailures: 10,
+		BreakerCooldown: time.Minute,
+	}
+	apiCallers     = map[string]*SApiCaller{}
+	apiCallersLock = &sync.Mutex{}
+
+	apiCallCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cloudprovider",
+			Name:      "api_calls_total",
+			Help:      "Number of cloud provider api calls by result",
+		},
+		[]string{"provider", "action", "result"},
+	)
+	apiRetryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cloudprovider",
+			Name:      "api_retries_total",
+			Help:      "Number of retries of throttled cloud provider api calls",
+		},
+		[]string{"provider", "action"},
+	)
+	apiCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "cloudprovider",
+			Name:      "api_call_duration_seconds",
+			Help:      "Latency of cloud provider api calls",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		},
+		[]string{"provider", "action"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(apiCallCounter, apiRetryCounter, apiCallDuration)
+}
+
+// SetApiCallConfig replaces the limits of all provider api calls,
+// the states of the existing callers are dropped
+func SetApiCallConfig(conf SApiCallConfig) {
+	apiCallersLock.Lock()
+	defer apiCallersLock.Unlock()
+
+	apiCallConfig = conf
+	apiCallers = map[string]*SApiCaller{}
+}
+
+// GetApiCaller returns the caller shared by all clients of the account
+func GetApiCaller(provider string, account string) *SApiCaller {
+	apiCallersLock.Lock()
+	defer apiCallersLock.Unlock()
+
+	key := provider + "/" + account
+	caller, ok := apiCallers[key]
+	if !ok {
+		caller = &SApiCaller{
+			provider: provider,
+			config:   apiCallConfig,
+			apis:     map[string]*sApiState{},
+		}
+		apiCallers[key] = caller
+	}
+	return caller
+}
+
+type sApiState struct {
+	limiter *rate.Limiter
+
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+type SApiCaller struct {
+	provider string
+	config   SApiCallConfig
+
+	lock sync.Mutex
+	apis map[string]*sApiState
+}
+
+func (self *SApiCaller) GetConfig() SApiCallConfig {
+	return self.config
+}
+
+func (self *SApiCaller) getState(action string) *sApiState {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	state, ok := self.apis[action]
+	if !ok {
+		limit := rate.Inf
+		if self.config.Qps > 0 {
+			limit = rate.Limit(self.config.Qps)
+		}
+		burst := self.config.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		state = &sApiState{limiter: rate.NewLimiter(limit, burst)}
+		self.apis[action] = state
+	}
+	return state
+}
+
+func (self *SApiCaller) allow(state *sApiState) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if state.openUntil.IsZero() {
+		return nil
+	}
+	if time.Now().Before(state.openUntil) || state.probing {
+		return ErrApiCircuitOpen
+	}
+	// half open, only a single trial call is let through
+	state.probing = true
+	return nil
+}
+
+// Begin checks the circuit and waits for a token of the api,
+// a successful Begin must be followed by End
+func (self *SApiCaller) Begin(action string) error {
+	state := self.getState(action)
+	err := self.allow(state)
+	if err != nil {
+		apiCallCounter.WithLabelValues(self.provider, action, API_RESULT_REJECTED).Inc()
+		return err
+	}
+	return state.limiter.Wait(context.Background())
+}
+
+// End records the result of the call started at start, errClass is empty on success
+func (self *SApiCaller) End(action string, start time.Time, errClass string) {
+	self.end(action, start, errClass, errClass == API_ERROR_SERVER || errClass == API_ERROR_THROTTLED)
+}
+
+func (self *SApiCaller) end(action string, start time.Time, errClass string, failed bool) {
+	result := API_RESULT_SUCCESS
+	if len(errClass) > 0 {
+		result = errClass
+	}
+	apiCallCounter.WithLabelValues(self.provider, action, result).Inc()
+	apiCallDuration.WithLabelValues(self.provider, action).Observe(time.Since(start).Seconds())
+
+	state := self.getState(action)
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if errClass == API_ERROR_THROTTLED && !failed {
+		// to be retried, let the retry probe the circuit again
+		state.probing = false
+		return
+	}
+	if !failed {
+		state.failures = 0
+		state.openUntil = time.Time{}
+		state.probing = false
+		return
+	}
+	state.failures += 1
+	if state.probing || (self.config.BreakerFailures > 0 && state.failures >= self.config.BreakerFailures) {
+		log.Warningf("%s api %s failed %d times, open circuit for %s", self.provider, action, state.failures, self.config.BreakerCooldown)
+		state.failures = 0
+		state.openUntil = time.Now().Add(self.config.BreakerCooldown)
+		state.probing = false
+	}
+}
+
+func (self *SApiCaller) backoff(tried int) time.Duration {
+	interval := self.config.BackoffBase
+	for i := 0; i < tried && interval < self.config.BackoffMax; i++ {
+		interval *= 2
+	}
+	if interval > self.config.BackoffMax {
+		interval = self.config.BackoffMax
+	}
+	if interval <= 0 {
+		return 0
+	}
+	// full jitter on the upper half to spread the retries of concurrent syncs
+	return interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+}
+
+// Call invokes the api through the limiter and the circuit breaker,
+// throttled calls are retried until MaxRetries is reached
+func (self *SApiCaller) Call(action string, classify FApiErrorClassifier, call func() error) error {
+	for tried := 0; ; tried++ {
+		err := self.Begin(action)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		err = call()
+		if err == nil {
+			self.end(action, start, "", false)
+			return nil
+		}
+		errClass := classify(err)
+		retry := errClass == API_ERROR_THROTTLED && tried < self.config.MaxRetries
+		self.end(action, start, errClass, !retry && errClass != API_ERROR_CLIENT)
+		if !retry {
+			return err
+		}
+		interval := self.backoff(tried)
+		log.Warningf("%s api %s throttled, retry after %s: %s", self.provider, action, interval, err)
+		apiRetryCounter.WithLabelValues(self.provider, action).Inc()
+		time.Sleep(interval)
+	}
+}
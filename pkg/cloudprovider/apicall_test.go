@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestApiCaller() *SApiCaller {
+	return &SApiCaller{
+		provider: "test",
+		config: SApiCallConfig{
+			Burst:           1,
+			MaxRetries:      2,
+			BackoffBase:     time.Millisecond,
+			BackoffMax:      2 * time.Millisecond,
+			BreakerFailures: 2,
+			BreakerCooldown: 50 * time.Millisecond,
+		},
+		apis: map[string]*sApiState{},
+	}
+}
+
+func classifyTestError(err error) string {
+	return ClassifyApiError(err, []string{"Throttling"}, []string{"InternalError"})
+}
+
+func TestApiCallerRetryThrottled(t *testing.T) {
+	caller := newTestApiCaller()
+	tried := 0
+	err := caller.Call("Describe", classifyTestError, func() error {
+		tried++
+		if tried < 3 {
+			return errors.New("Throttling")
+		}
+		return nil
+	})
+	if err != nil || tried != 3 {
+		t.Errorf("expect success after 3 tries, got %d: %v", tried, err)
+	}
+
+	tried = 0
+	err = caller.Call("Describe", classifyTestError, func() error {
+		tried++
+		return errors.New("InvalidParameter")
+	})
+	if err == nil || tried != 1 {
+		t.Errorf("expect client error without retry, got %d: %v", tried, err)
+	}
+}
+
+func TestApiCallerCircuitBreaker(t *testing.T) {
+	caller := newTestApiCaller()
+	fail := func() error { return errors.New("InternalError") }
+	for i := 0; i < 2; i++ {
+		caller.Call("Create", classifyTestError, fail)
+	}
+	called := false
+	err := caller.Call("Create", classifyTestError, func() error {
+		called = true
+		return nil
+	})
+	if err != ErrApiCircuitOpen || called {
+		t.Fatalf("expect circuit open, got %v", err)
+	}
+	if err := caller.Call("Delete", classifyTestError, func() error { return nil }); err != nil {
+		t.Errorf("other apis should not be affected: %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := caller.Call("Create", classifyTestError, func() error { return nil }); err != nil {
+		t.Fatalf("expect trial call after cooldown, got %v", err)
+	}
+	if err := caller.Call("Create", classifyTestError, func() error { return nil }); err != nil {
+		t.Errorf("expect circuit closed, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import (
+	"time"
+)
+
+const (
+	CLOUD_BILL_RESOURCE_SERVER       = "server"
+	CLOUD_BILL_RESOURCE_DISK         = "disk"
+	CLOUD_BILL_RESOURCE_EIP          = "eip"
+	CLOUD_BILL_RESOURCE_LOADBALANCER = "loadbalancer"
+	CLOUD_BILL_RESOURCE_NATGATEWAY   = "natgateway"
+	CLOUD_BILL_RESOURCE_BUCKET       = "bucket"
+	CLOUD_BILL_RESOURCE_SNAPSHOT     = "snapshot"
+)
+
+// SCloudBillQuery describes the daily bill to fetch
+type SCloudBillQuery struct {
+	// Day is the usage date, only the year, month and day are used
+	Day time.Time
+	// Bucket and Prefix locate the cost reports of the providers exporting
+	// them as csv objects instead of an API, e.g. the AWS cost and usage report
+	Bucket string
+	Prefix string
+}
+
+// SCloudBillItem is the cost of one resource, or one product if the cost
+// is not related to any resource, in a day
+type SCloudBillItem struct {
+	// ResourceType is one of CLOUD_BILL_RESOURCE_*, empty if unknown
+	ResourceType string
+	// ResourceId is the global id of the charged resource
+	ResourceId string
+	// ProjectId is the global id of the project of the resource if the
+	// provider reports it
+	ProjectId string
+	RegionId  string
+	// Product is the provider specific product code, e.g. ecs, AmazonEC2
+	Product   string
+	Amount    float64
+	Currency  string
+	UsageDate time.Time
+}
+
+// ICloudBillingProvider is optionally implemented by the ICloudProvider of
+// the providers able to report the daily cost line items
+type ICloudBillingProvider interface {
+	GetCloudBillItems(query SCloudBillQuery) ([]SCloudBillItem, error)
+}
+
+// BillDate truncates the time to the usage date of the bill
+func BillDate(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// MergeCloudBillItems sums up the items of the same resource and product
+// in the same day, providers report one line item per billing component
+func MergeCloudBillItems(items []SCloudBillItem) []SCloudBillItem {
+	ret := make([]SCloudBillItem, 0, len(items))
+	idx := make(map[string]int)
+	for _, item := range items {
+		key := item.ResourceId + "/" + item.Product + "/" + item.Currency + "/" + BillDate(item.UsageDate).Format("2006-01-02")
+		if i, ok := idx[key]; ok {
+			ret[i].Amount += item.Amount
+			continue
+		}
+		idx[key] = len(ret)
+		ret = append(ret, item)
+	}
+	return ret
+}
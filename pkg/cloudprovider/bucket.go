@@ -0,0 +1,39 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+const (
+	ACL_PRIVATE            = "private"
+	ACL_PUBLIC_READ        = "public-read"
+	ACL_PUBLIC_READ_WRITE  = "public-read-write"
+	ACL_AUTHENTICATED_READ = "authenticated-read"
+
+	ACL_UNKNOWN = "unknown"
+)
+
+type SBucketStats struct {
+	SizeBytes   int64
+	ObjectCount int
+}
+
+type SBucketLifecycleRule struct {
+	ID     string
+	Prefix string
+
+	Enabled bool
+
+	// objects matching the prefix are removed after ExpirationDays days
+	ExpirationDays int
+}
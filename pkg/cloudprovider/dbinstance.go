@@ -0,0 +1,55 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import "time"
+
+const (
+	DBINSTANCE_ENDPOINT_INTRANET = "intranet"
+	DBINSTANCE_ENDPOINT_INTERNET = "internet"
+
+	DBINSTANCE_BACKUP_AUTOMATED = "automated"
+	DBINSTANCE_BACKUP_MANUAL    = "manual"
+)
+
+type SDBInstanceEndpoint struct {
+	// DBINSTANCE_ENDPOINT_INTRANET or DBINSTANCE_ENDPOINT_INTERNET
+	Type    string
+	Address string
+	Port    int
+}
+
+type SDBInstanceBackup struct {
+	Id        string
+	Mode      string
+	Status    string
+	SizeMB    int
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+type SDBInstanceAccount struct {
+	Name   string
+	Status string
+	// databases the account is granted to
+	Databases []string
+}
+
+// SDBInstanceChangeConfig holds the new spec of a database instance,
+// the zero value of a field keeps it unchanged
+type SDBInstanceChangeConfig struct {
+	InstanceType  string
+	StorageSizeGB int
+}
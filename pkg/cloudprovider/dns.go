@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+const (
+	DNS_ZONE_TYPE_PUBLIC  = "public"
+	DNS_ZONE_TYPE_PRIVATE = "private"
+)
+
+// SDnsRecord is one value of a record set, the name is relative to the
+// zone with "@" for the apex, SRV values are in the zone file form
+// "priority weight port target"
+type SDnsRecord struct {
+	Name  string
+	Type  string
+	Value string
+	Ttl   int
+}
+
+type ICloudDnsRecord interface {
+	GetGlobalId() string
+
+	GetDnsName() string
+	GetDnsType() string
+	GetDnsValue() string
+	GetTtl() int
+}
+
+type ICloudDnsZone interface {
+	ICloudResource
+
+	// GetZoneType returns one of DNS_ZONE_TYPE_*
+	GetZoneType() string
+
+	GetIDnsRecords() ([]ICloudDnsRecord, error)
+	// AddDnsRecord returns the global id of the new record
+	AddDnsRecord(record SDnsRecord) (string, error)
+	RemoveDnsRecord(recordId string) error
+}
+
+// ICloudDnsProvider is optionally implemented by the ICloudProvider of
+// the providers hosting dns zones, the zones are global to the account
+type ICloudDnsProvider interface {
+	GetIDnsZones() ([]ICloudDnsZone, error)
+}
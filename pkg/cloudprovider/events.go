@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	CLOUD_EVENT_ACTION_CREATE = "create"
+	CLOUD_EVENT_ACTION_UPDATE = "update"
+	CLOUD_EVENT_ACTION_DELETE = "delete"
+
+	CLOUD_EVENT_RESOURCE_SERVER       = "server"
+	CLOUD_EVENT_RESOURCE_DISK         = "disk"
+	CLOUD_EVENT_RESOURCE_SNAPSHOT     = "snapshot"
+	CLOUD_EVENT_RESOURCE_EIP          = "eip"
+	CLOUD_EVENT_RESOURCE_VPC          = "vpc"
+	CLOUD_EVENT_RESOURCE_SECGROUP     = "secgroup"
+	CLOUD_EVENT_RESOURCE_LOADBALANCER = "loadbalancer"
+	CLOUD_EVENT_RESOURCE_BUCKET       = "bucket"
+)
+
+// ErrEventExpired is returned by GetICloudEvents when the change feed no
+// longer holds the events of the requested period, the caller should fall
+// back to a full sync
+var ErrEventExpired = errors.New("events expired")
+
+type SCloudEvent struct {
+	// ResourceType is one of CLOUD_EVENT_RESOURCE_*, resources of other types
+	// are reported with their provider specific type
+	ResourceType string
+	// ResourceId is the global id of the changed resource
+	ResourceId string
+	Action     string
+	CreatedAt  time.Time
+}
+
+// ICloudEventSource is optionally implemented by the ICloudRegion of the
+// providers exposing a change feed, so that sync is able to apply only the
+// changed resources instead of walking the whole region
+type ICloudEventSource interface {
+	GetICloudEvents(start time.Time, end time.Time) ([]SCloudEvent, error)
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudprovider
+
+import "context"
+
+// SDiskExportLayer is one file of a disk backing chain, a descriptor and
+// the extent holding the data
+type SDiskExportLayer struct {
+	DescriptorName string
+	DescriptorUrl  string
+	// ExtentName is the file name the descriptor references the extent by
+	ExtentName string
+	ExtentUrl  string
+}
+
+// SDiskExportInfo describes how the content of a cloud disk can be fetched
+// over HTTP(S), e.g. the datastore file urls of an ESXi virtual disk
+type SDiskExportInfo struct {
+	// Format of the downloaded files, e.g. vmdk
+	Format string
+	// Layers of the backing chain, the disk itself first and its base last
+	Layers []SDiskExportLayer
+}
+
+// SDiskExportTicket grants a single download of an export url without the
+// credentials of the cloud account
+type SDiskExportTicket struct {
+	// Url to download from, it may differ from the exported one, e.g. the
+	// url of the ESXi host holding the datastore
+	Url string
+	// Headers to send along with the download request
+	Headers map[string]string
+}
+
+// ICloudDiskExporter is optionally implemented by the ICloudDisk of the
+// providers whose disk content is downloadable, so that the disk is able to
+// be imported into a local storage
+type ICloudDiskExporter interface {
+	GetExportInfo(ctx context.Context) (*SDiskExportInfo, error)
+	// GetExportTicket issues a short-lived ticket for one of the urls
+	// returned by GetExportInfo
+	GetExportTicket(ctx context.Context, url string) (*SDiskExportTicket, error)
+}
@@ -133,3 +133,35 @@ func (region *SFakeOnPremiseRegion) CreateILoadBalancerAcl(acl *SLoadbalancerAcc
 func (region *SFakeOnPremiseRegion) GetSkus(zoneId string) ([]ICloudSku, error) {
 	return nil, ErrNotSupported
 }
+
+func (region *SFakeOnPremiseRegion) GetIBuckets() ([]ICloudBucket, error) {
+	return nil, ErrNotSupported
+}
+
+func (region *SFakeOnPremiseRegion) GetIBucketById(name string) (ICloudBucket, error) {
+	return nil, ErrNotSupported
+}
+
+func (region *SFakeOnPremiseRegion) CreateIBucket(name string, storageClass string, acl string) (ICloudBucket, error) {
+	return nil, ErrNotSupported
+}
+
+func (region *SFakeOnPremiseRegion) DeleteIBucket(name string) error {
+	return ErrNotSupported
+}
+
+func (region *SFakeOnPremiseRegion) GetIDBInstances() ([]ICloudDBInstance, error) {
+	return nil, ErrNotSupported
+}
+
+func (region *SFakeOnPremiseRegion) GetIDBInstanceById(id string) (ICloudDBInstance, error) {
+	return nil, ErrNotSupported
+}
+
+func (region *SFakeOnPremiseRegion) GetIVpcPeerings() ([]ICloudVpcPeering, error) {
+	return nil, ErrNotSupported
+}
+
+func (region *SFakeOnPremiseRegion) GetIVpnConnections() ([]ICloudVpnConnection, error) {
+	return nil, ErrNotSupported
+}
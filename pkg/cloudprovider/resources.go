@@ -94,6 +94,17 @@ type ICloudRegion interface {
 
 	GetSkus(zoneId string) ([]ICloudSku, error)
 
+	GetIBuckets() ([]ICloudBucket, error)
+	GetIBucketById(name string) (ICloudBucket, error)
+	CreateIBucket(name string, storageClass string, acl string) (ICloudBucket, error)
+	DeleteIBucket(name string) error
+
+	GetIDBInstances() ([]ICloudDBInstance, error)
+	GetIDBInstanceById(id string) (ICloudDBInstance, error)
+
+	GetIVpcPeerings() ([]ICloudVpcPeering, error)
+	GetIVpnConnections() ([]ICloudVpnConnection, error)
+
 	GetProvider() string
 }
 
@@ -324,6 +335,126 @@ type ICloudRoute interface {
 	GetNextHop() string
 }
 
+// ICloudVpcPeering is a peering connection between a vpc of the region
+// and a peer vpc, the peer may live in another region or account
+type ICloudVpcPeering interface {
+	ICloudResource
+
+	GetVpcId() string
+	GetPeerVpcId() string
+	GetPeerRegionId() string
+	GetPeerAccountId() string
+	// bandwidth limit in Mbps, 0 means unlimited
+	GetBandwidth() int
+}
+
+// ICloudVpnConnection is an ipsec tunnel between the vpn gateway of a vpc
+// and a customer gateway
+type ICloudVpnConnection interface {
+	ICloudResource
+
+	GetVpcId() string
+	GetVpnGatewayId() string
+	GetVpnGatewayAddress() string
+	GetCustomerGatewayId() string
+	GetCustomerGatewayAddress() string
+	GetLocalCidrs() []string
+	GetRemoteCidrs() []string
+}
+
+type ICloudNatGateway interface {
+	ICloudResource
+
+	GetNatSpec() string
+	GetDescription() string
+
+	GetINatSEntries() ([]ICloudNatSEntry, error)
+	GetINatDEntries() ([]ICloudNatDEntry, error)
+
+	CreateINatSEntry(rule SNatSRule) (ICloudNatSEntry, error)
+	CreateINatDEntry(rule SNatDRule) (ICloudNatDEntry, error)
+}
+
+// ICloudNatSEntry describes a SNAT entry which translates the source address
+// of the outgoing traffic from a network or a cidr to the public ip
+type ICloudNatSEntry interface {
+	ICloudResource
+
+	GetIP() string
+	GetSourceCIDR() string
+	GetNetworkId() string
+
+	Delete() error
+}
+
+// ICloudNatDEntry describes a DNAT entry which forwards the incoming traffic
+// of the public ip and port to the internal ip and port
+type ICloudNatDEntry interface {
+	ICloudResource
+
+	GetIpProtocol() string
+	GetExternalIp() string
+	GetExternalPort() int
+
+	GetInternalIp() string
+	GetInternalPort() int
+
+	Delete() error
+}
+
+// ICloudBucket describes an object storage bucket, the name of a bucket
+// is globally unique and is used as its external id
+type ICloudBucket interface {
+	ICloudResource
+
+	GetIRegion() ICloudRegion
+
+	GetLocation() string
+	GetStorageClass() string
+	GetCreateAt() time.Time
+	GetAccessUrl() string
+
+	GetAcl() string
+	SetAcl(acl string) error
+
+	// GetStats may list all the objects of the bucket, it is called on demand only
+	GetStats() (SBucketStats, error)
+
+	GetLifecycle() ([]SBucketLifecycleRule, error)
+	SetLifecycle(rules []SBucketLifecycleRule) error
+
+	// GetTempUrl returns a presigned url of the object key which is valid in expire
+	GetTempUrl(method string, key string, expire time.Duration) (string, error)
+}
+
+// ICloudDBInstance describes a managed relational database instance
+type ICloudDBInstance interface {
+	ICloudResource
+	IBillingResource
+	IVirtualResource
+
+	GetEngine() string
+	GetEngineVersion() string
+	GetInstanceType() string
+	GetVcpuCount() int
+	GetVmemSizeMB() int
+	GetStorageType() string
+	GetStorageSizeGB() int
+
+	GetZoneId() string
+	GetVpcId() string
+	GetCreatedAt() time.Time
+
+	GetEndpoints() []SDBInstanceEndpoint
+	GetBackups() ([]SDBInstanceBackup, error)
+	GetAccounts() ([]SDBInstanceAccount, error)
+
+	Start() error
+	Stop() error
+	Reboot() error
+	ChangeConfig(config *SDBInstanceChangeConfig) error
+}
+
 type ICloudDisk interface {
 	ICloudResource
 	IBillingResource
@@ -378,6 +509,7 @@ type ICloudVpc interface {
 	GetIWires() ([]ICloudWire, error)
 	GetISecurityGroups() ([]ICloudSecurityGroup, error)
 	GetIRouteTables() ([]ICloudRouteTable, error)
+	GetINatGateways() ([]ICloudNatGateway, error)
 
 	GetManagerId() string
 
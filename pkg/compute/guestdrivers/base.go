@@ -26,6 +26,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/billing"
 )
@@ -127,6 +128,10 @@ func (self *SBaseGuestDriver) GetDeployStatus() ([]string, error) {
 	return []string{}, fmt.Errorf("This Guest driver dose not implement GetDeployStatus")
 }
 
+func (self *SBaseGuestDriver) GetGuestSerialInfo(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost) (*jsonutils.JSONDict, error) {
+	return nil, httperrors.NewUnsupportOperationError("Serial console of %s guest is not supported", guest.Hypervisor)
+}
+
 func (self *SBaseGuestDriver) IsNeedRestartForResetLoginInfo() bool {
 	return true
 }
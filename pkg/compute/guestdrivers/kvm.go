@@ -30,6 +30,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/httputils"
 	"yunion.io/x/onecloud/pkg/util/logclient"
@@ -164,6 +165,29 @@ func (self *SKVMGuestDriver) GetGuestVncInfo(ctx context.Context, userCred mccli
 	return retval, nil
 }
 
+func (self *SKVMGuestDriver) GetGuestSerialInfo(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost) (*jsonutils.JSONDict, error) {
+	// the serial port is a unix socket on host, hostman forwards it for a
+	// single connection on a random port presenting the one-time token
+	url := fmt.Sprintf("/servers/%s/serial", guest.Id)
+	body := jsonutils.NewDict()
+	body.Add(jsonutils.NewString(host.AccessIp), "address")
+	ret, err := host.Request(ctx, userCred, "POST", url, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	port, err := ret.Int("port")
+	if err != nil || port <= 0 {
+		return nil, httperrors.NewInternalServerError("Invalid serial forward port: %s", ret)
+	}
+	token, _ := ret.GetString("token")
+	retval := jsonutils.NewDict()
+	retval.Add(jsonutils.NewString(host.AccessIp), "host")
+	retval.Add(jsonutils.NewString("serial"), "protocol")
+	retval.Add(jsonutils.NewInt(int64(port)), "port")
+	retval.Add(jsonutils.NewString(token), "token")
+	return retval, nil
+}
+
 func (self *SKVMGuestDriver) RequestStopOnHost(ctx context.Context, guest *models.SGuest, host *models.SHost, task taskman.ITask) error {
 	body := jsonutils.NewDict()
 	params := task.GetParams()
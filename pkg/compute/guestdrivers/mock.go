@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestdrivers
+
+import (
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type SMockGuestDriver struct {
+	SManagedVirtualizedGuestDriver
+}
+
+func init() {
+	driver := SMockGuestDriver{}
+	models.RegisterGuestDriver(&driver)
+}
+
+func (self *SMockGuestDriver) GetHypervisor() string {
+	return models.HYPERVISOR_MOCK
+}
+
+func (self *SMockGuestDriver) GetDefaultSysDiskBackend() string {
+	return models.STORAGE_MOCK_CLOUD
+}
+
+func (self *SMockGuestDriver) GetMinimalSysDiskSizeGb() int {
+	return 10
+}
+
+func (self *SMockGuestDriver) GetStorageTypes() []string {
+	return []string{models.STORAGE_MOCK_CLOUD}
+}
+
+func (self *SMockGuestDriver) ChooseHostStorage(host *models.SHost, backend string) *models.SStorage {
+	storages := host.GetAttachedStorages("")
+	for i := 0; i < len(storages); i++ {
+		if storages[i].StorageType == backend {
+			return &storages[i]
+		}
+	}
+	for _, stype := range self.GetStorageTypes() {
+		for i := 0; i < len(storages); i++ {
+			if storages[i].StorageType == stype {
+				return &storages[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (self *SMockGuestDriver) GetDetachDiskStatus() ([]string, error) {
+	return []string{models.VM_READY, models.VM_RUNNING}, nil
+}
+
+func (self *SMockGuestDriver) GetAttachDiskStatus() ([]string, error) {
+	return []string{models.VM_READY, models.VM_RUNNING}, nil
+}
+
+func (self *SMockGuestDriver) GetChangeConfigStatus() ([]string, error) {
+	return []string{models.VM_READY, models.VM_RUNNING}, nil
+}
+
+func (self *SMockGuestDriver) GetDeployStatus() ([]string, error) {
+	return []string{models.VM_READY, models.VM_RUNNING}, nil
+}
+
+func (self *SMockGuestDriver) IsNeedRestartForResetLoginInfo() bool {
+	return false
+}
+
+func (self *SMockGuestDriver) GetGuestInitialStateAfterCreate() string {
+	return models.VM_RUNNING
+}
@@ -0,0 +1,549 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SBucketManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var BucketManager *SBucketManager
+
+func init() {
+	BucketManager = &SBucketManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SBucket{},
+			"buckets_tbl",
+			"bucket",
+			"buckets",
+		),
+	}
+}
+
+type SBucket struct {
+	db.SVirtualResourceBase
+	SManagedResourceBase
+
+	CloudregionId string `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required"`
+
+	StorageClass string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	Location     string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+	Acl          string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	AccessUrl    string `width:"512" charset:"ascii" nullable:"true" list:"user"`
+
+	SizeBytes int64 `nullable:"false" default:"0" list:"user"`
+	ObjectCnt int   `nullable:"false" default:"0" list:"user"`
+}
+
+// bucket names are shared by all the users of a cloud, the common rule of
+// S3, OSS, COS and OBS is 3-63 characters of lowercase letters, digits and hyphens
+var bucketNameReg = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+
+func (manager *SBucketManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = managedResourceFilterByAccount(q, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	q = managedResourceFilterByCloudType(q, query, "", nil)
+
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	userProjId := userCred.GetProjectId()
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "cloudregion", ModelKeyword: "cloudregion", ProjectId: userProjId},
+		{Key: "manager", ModelKeyword: "cloudprovider", ProjectId: userProjId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (manager *SBucketManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	name, _ := data.GetString("name")
+	if !bucketNameReg.MatchString(name) {
+		return nil, httperrors.NewInputParameterError("invalid bucket name %s", name)
+	}
+
+	regionStr := jsonutils.GetAnyString(data, []string{"cloudregion", "cloudregion_id", "region", "region_id"})
+	if len(regionStr) == 0 {
+		return nil, httperrors.NewMissingParameterError("cloudregion_id")
+	}
+	region, err := CloudregionManager.FetchByIdOrName(nil, regionStr)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		return nil, httperrors.NewResourceNotFoundError("Region %s not found", regionStr)
+	}
+	data.Set("cloudregion_id", jsonutils.NewString(region.GetId()))
+
+	managerStr := jsonutils.GetAnyString(data, []string{"manager", "manager_id"})
+	if len(managerStr) == 0 {
+		return nil, httperrors.NewMissingParameterError("manager_id")
+	}
+	provider, err := CloudproviderManager.FetchByIdOrName(nil, managerStr)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		return nil, httperrors.NewResourceNotFoundError("Cloud provider %s not found", managerStr)
+	}
+	data.Set("manager_id", jsonutils.NewString(provider.GetId()))
+
+	acl, _ := data.GetString("acl")
+	if len(acl) == 0 {
+		acl = api.BUCKET_ACL_PRIVATE
+	}
+	if !api.BUCKET_ACLS.Has(acl) {
+		return nil, httperrors.NewInputParameterError("invalid acl %s, want %s", acl, api.BUCKET_ACLS)
+	}
+	data.Set("acl", jsonutils.NewString(acl))
+
+	return manager.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerProjId, query, data)
+}
+
+func (self *SBucket) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SVirtualResourceBase.PostCreate(ctx, userCred, ownerProjId, query, data)
+	self.SetStatus(userCred, api.BUCKET_STATUS_CREATING, "")
+	err := self.StartBucketCreateTask(ctx, userCred, "")
+	if err != nil {
+		self.SetStatus(userCred, api.BUCKET_STATUS_CREATE_FAILED, err.Error())
+	}
+}
+
+func (self *SBucket) StartBucketCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "BucketCreateTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SBucket) ValidateDeleteCondition(ctx context.Context) error {
+	if self.ObjectCnt > 0 {
+		return httperrors.NewNotEmptyError("bucket %s is not empty", self.Name)
+	}
+	return self.SVirtualResourceBase.ValidateDeleteCondition(ctx)
+}
+
+func (self *SBucket) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	log.Infof("bucket delete do nothing")
+	return nil
+}
+
+func (self *SBucket) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return self.StartBucketDeleteTask(ctx, userCred, "")
+}
+
+func (self *SBucket) StartBucketDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "BucketDeleteTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	self.SetStatus(userCred, api.BUCKET_STATUS_DELETING, "")
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SBucket) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SBucket) AllowPerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "purge")
+}
+
+func (self *SBucket) PerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	provider := self.GetCloudprovider()
+	if provider != nil && provider.Enabled {
+		return nil, httperrors.NewInvalidStatusError("Cannot purge bucket on enabled cloud provider")
+	}
+	err := self.RealDelete(ctx, userCred)
+	return nil, err
+}
+
+func (self *SBucket) GetRegion() *SCloudregion {
+	region, err := CloudregionManager.FetchById(self.CloudregionId)
+	if err != nil {
+		log.Errorf("failed to find region for bucket %s", self.Name)
+		return nil
+	}
+	return region.(*SCloudregion)
+}
+
+func (self *SBucket) GetIRegion() (cloudprovider.ICloudRegion, error) {
+	provider, err := self.GetDriver()
+	if err != nil {
+		return nil, err
+	}
+	region := self.GetRegion()
+	if region == nil {
+		return nil, fmt.Errorf("fail to find region for bucket")
+	}
+	return provider.GetIRegionById(region.GetExternalId())
+}
+
+func (self *SBucket) GetIBucket() (cloudprovider.ICloudBucket, error) {
+	iregion, err := self.GetIRegion()
+	if err != nil {
+		return nil, err
+	}
+	return iregion.GetIBucketById(self.ExternalId)
+}
+
+func (self *SBucket) AllowPerformSetAcl(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "set-acl")
+}
+
+func (self *SBucket) PerformSetAcl(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	acl, _ := data.GetString("acl")
+	if !api.BUCKET_ACLS.Has(acl) {
+		return nil, httperrors.NewInputParameterError("invalid acl %s, want %s", acl, api.BUCKET_ACLS)
+	}
+	if self.Status != api.BUCKET_STATUS_AVAILABLE {
+		return nil, httperrors.NewInvalidStatusError("cannot set acl in status %s", self.Status)
+	}
+	ibucket, err := self.GetIBucket()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	err = ibucket.SetAcl(acl)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	diff, err := db.Update(self, func() error {
+		self.Acl = acl
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, diff, userCred)
+	return nil, nil
+}
+
+func (self *SBucket) AllowPerformSyncStats(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "sync-stats")
+}
+
+// PerformSyncStats refreshes the size and object count of the bucket. Most
+// providers have no usage api and the whole bucket is listed, so it is not
+// done by the sync of the cloud provider.
+func (self *SBucket) PerformSyncStats(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.Status != api.BUCKET_STATUS_AVAILABLE {
+		return nil, httperrors.NewInvalidStatusError("cannot sync stats in status %s", self.Status)
+	}
+	ibucket, err := self.GetIBucket()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	stats, err := ibucket.GetStats()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	_, err = db.Update(self, func() error {
+		self.SizeBytes = stats.SizeBytes
+		self.ObjectCnt = stats.ObjectCount
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("size_bytes", jsonutils.NewInt(stats.SizeBytes))
+	ret.Set("object_cnt", jsonutils.NewInt(int64(stats.ObjectCount)))
+	return ret, nil
+}
+
+func (self *SBucket) AllowGetDetailsLifecycle(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowGetSpec(userCred, self, "lifecycle")
+}
+
+func (self *SBucket) GetDetailsLifecycle(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	ibucket, err := self.GetIBucket()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	rules, err := ibucket.GetLifecycle()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("rules", jsonutils.Marshal(rules))
+	return ret, nil
+}
+
+func (self *SBucket) AllowPerformSetLifecycle(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "set-lifecycle")
+}
+
+// PerformSetLifecycle replaces the lifecycle rules of the bucket, empty
+// rules clear the lifecycle configuration
+func (self *SBucket) PerformSetLifecycle(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	rules := make([]cloudprovider.SBucketLifecycleRule, 0)
+	if data.Contains("rules") {
+		err := data.Unmarshal(&rules, "rules")
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("invalid rules: %s", err)
+		}
+	}
+	for i := range rules {
+		if rules[i].ExpirationDays <= 0 {
+			return nil, httperrors.NewInputParameterError("expiration_days of rule %d should be positive", i)
+		}
+	}
+	if self.Status != api.BUCKET_STATUS_AVAILABLE {
+		return nil, httperrors.NewInvalidStatusError("cannot set lifecycle in status %s", self.Status)
+	}
+	ibucket, err := self.GetIBucket()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	err = ibucket.SetLifecycle(rules)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, jsonutils.Marshal(rules), userCred)
+	return nil, nil
+}
+
+func (self *SBucket) AllowGetDetailsTempUrl(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowGetSpec(userCred, self, "temp-url")
+}
+
+// GetDetailsTempUrl returns a presigned url of an object in the bucket,
+// the url is valid for expire_seconds, 1 hour by default
+func (self *SBucket) GetDetailsTempUrl(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	key, _ := query.GetString("key")
+	if len(key) == 0 {
+		return nil, httperrors.NewMissingParameterError("key")
+	}
+	method, _ := query.GetString("method")
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+	expire, _ := query.Int("expire_seconds")
+	if expire <= 0 {
+		expire = 3600
+	}
+	if expire > api.BUCKET_TEMP_URL_MAX_EXPIRE {
+		return nil, httperrors.NewInputParameterError("expire_seconds should not exceed %d", api.BUCKET_TEMP_URL_MAX_EXPIRE)
+	}
+	ibucket, err := self.GetIBucket()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	url, err := ibucket.GetTempUrl(method, key, time.Duration(expire)*time.Second)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("url", jsonutils.NewString(url))
+	ret.Set("method", jsonutils.NewString(method))
+	ret.Set("expire_seconds", jsonutils.NewInt(expire))
+	return ret, nil
+}
+
+func (self *SBucket) getCloudProviderInfo() SCloudProviderInfo {
+	region := self.GetRegion()
+	provider := self.GetCloudprovider()
+	return MakeCloudProviderInfo(region, nil, provider)
+}
+
+func (self *SBucket) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SVirtualResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	info := self.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra
+}
+
+func (self *SBucket) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*jsonutils.JSONDict, error) {
+	extra, err := self.SVirtualResourceBase.GetExtraDetails(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	info := self.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra, nil
+}
+
+func (self *SBucket) GetShortDesc(ctx context.Context) *jsonutils.JSONDict {
+	desc := self.SVirtualResourceBase.GetShortDesc(ctx)
+	desc.Add(jsonutils.NewString(self.StorageClass), "storage_class")
+	desc.Add(jsonutils.NewString(self.Acl), "acl")
+	info := self.getCloudProviderInfo()
+	desc.Update(jsonutils.Marshal(&info))
+	return desc
+}
+
+func (manager *SBucketManager) SyncBuckets(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, region *SCloudregion, buckets []cloudprovider.ICloudBucket) compare.SyncResult {
+	lockman.LockClass(ctx, manager, manager.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, manager, manager.GetOwnerId(userCred))
+
+	syncResult := compare.SyncResult{}
+
+	dbBuckets := make([]SBucket, 0)
+	q := manager.Query().Equals("cloudregion_id", region.Id).Equals("manager_id", provider.Id)
+	if err := db.FetchModelObjects(manager, q, &dbBuckets); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	// buckets being created have no external id yet
+	syncedBuckets := make([]SBucket, 0, len(dbBuckets))
+	for i := range dbBuckets {
+		if len(dbBuckets[i].ExternalId) > 0 {
+			syncedBuckets = append(syncedBuckets, dbBuckets[i])
+		}
+	}
+
+	removed := make([]SBucket, 0)
+	commondb := make([]SBucket, 0)
+	commonext := make([]cloudprovider.ICloudBucket, 0)
+	added := make([]cloudprovider.ICloudBucket, 0)
+	if err := compare.CompareSets(syncedBuckets, buckets, &removed, &commondb, &commonext, &added); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err := removed[i].syncRemoveCloudBucket(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	}
+
+	for i := 0; i < len(commondb); i += 1 {
+		err := commondb[i].SyncWithCloudBucket(ctx, userCred, commonext[i])
+		if err != nil {
+			syncResult.UpdateError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, &commondb[i], commonext[i])
+		syncResult.Update()
+	}
+
+	for i := 0; i < len(added); i += 1 {
+		bucket, err := manager.newFromCloudBucket(ctx, userCred, provider, region, added[i])
+		if err != nil {
+			syncResult.AddError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, bucket, added[i])
+		syncResult.Add()
+	}
+	return syncResult
+}
+
+func (self *SBucket) syncRemoveCloudBucket(ctx context.Context, userCred mcclient.TokenCredential) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	err := self.SVirtualResourceBase.ValidateDeleteCondition(ctx)
+	if err != nil {
+		self.SetStatus(userCred, api.BUCKET_STATUS_UNKNOWN, "sync to delete")
+		return err
+	}
+	return self.RealDelete(ctx, userCred)
+}
+
+func (self *SBucket) SyncWithCloudBucket(ctx context.Context, userCred mcclient.TokenCredential, extBucket cloudprovider.ICloudBucket) error {
+	diff, err := db.UpdateWithLock(ctx, self, func() error {
+		self.Status = extBucket.GetStatus()
+		self.StorageClass = extBucket.GetStorageClass()
+		self.Location = extBucket.GetLocation()
+		self.Acl = extBucket.GetAcl()
+		self.AccessUrl = extBucket.GetAccessUrl()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogSyncUpdate(self, diff, userCred)
+	return nil
+}
+
+func (manager *SBucketManager) newFromCloudBucket(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, region *SCloudregion, extBucket cloudprovider.ICloudBucket) (*SBucket, error) {
+	bucket := SBucket{}
+	bucket.SetModelManager(manager)
+
+	bucket.Name = db.GenerateName(manager, provider.ProjectId, extBucket.GetName())
+	bucket.ExternalId = extBucket.GetGlobalId()
+	bucket.CloudregionId = region.Id
+	bucket.ManagerId = provider.Id
+	bucket.Status = extBucket.GetStatus()
+	bucket.StorageClass = extBucket.GetStorageClass()
+	bucket.Location = extBucket.GetLocation()
+	bucket.Acl = extBucket.GetAcl()
+	bucket.AccessUrl = extBucket.GetAccessUrl()
+	bucket.ProjectId = provider.ProjectId
+	if len(bucket.ProjectId) == 0 {
+		bucket.ProjectId = userCred.GetProjectId()
+	}
+
+	err := manager.TableSpec().Insert(&bucket)
+	if err != nil {
+		log.Errorf("newFromCloudBucket fail %s", err)
+		return nil, err
+	}
+
+	db.OpsLog.LogEvent(&bucket, db.ACT_CREATE, bucket.GetShortDesc(ctx), userCred)
+	return &bucket, nil
+}
+
+func (manager *SBucketManager) purgeAll(ctx context.Context, userCred mcclient.TokenCredential, providerId string) error {
+	buckets := make([]SBucket, 0)
+	err := fetchByManagerId(manager, providerId, &buckets)
+	if err != nil {
+		return err
+	}
+	for i := range buckets {
+		err := buckets[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return fmt.Errorf("purge bucket %s fail %s", buckets[i].Id, err)
+		}
+	}
+	return nil
+}
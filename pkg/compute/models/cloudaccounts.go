@@ -1210,7 +1210,7 @@ func (account *SCloudaccount) SubmitSyncAccountTask(ctx context.Context, userCre
 		} else {
 			syncCnt := 0
 			if err == nil && autoSync && account.Enabled && account.EnableAutoSync {
-				syncRange := SSyncRange{FullSync: true}
+				syncRange := SSyncRange{FullSync: true, EventSync: true}
 				providers := account.GetEnabledCloudproviders()
 				for i := range providers {
 					providers[i].syncCloudproviderRegions(ctx, userCred, syncRange, nil, autoSync)
@@ -0,0 +1,705 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+const (
+	// cloudaccount options locating the exported cost reports
+	CLOUDACCOUNT_OPTION_BILL_BUCKET = "bill_bucket"
+	CLOUDACCOUNT_OPTION_BILL_PREFIX = "bill_prefix"
+
+	COST_DATE_FORMAT = "2006-01-02"
+)
+
+type SCloudcostManager struct {
+	db.SResourceBaseManager
+}
+
+var CloudcostManager *SCloudcostManager
+
+func init() {
+	CloudcostManager = &SCloudcostManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SCloudcost{},
+			"cloudcosts_tbl",
+			"cloudcost",
+			"cloudcosts",
+		),
+	}
+}
+
+// SCloudcost is the cost of a resource in a day, either pulled from the bill
+// of the cloud provider or accounted by the on-premise cost rates
+type SCloudcost struct {
+	db.SResourceBase
+
+	Id        int64     `primary:"true" auto_increment:"true" list:"admin"`
+	UsageDate time.Time `nullable:"false" index:"true" list:"admin"`
+	// provider or onpremise
+	Source    string `width:"16" charset:"ascii" nullable:"false" list:"admin"`
+	ManagerId string `width:"36" charset:"ascii" nullable:"true" list:"admin"`
+
+	ResourceType string `width:"32" charset:"ascii" nullable:"true" list:"admin"`
+	// local id of the resource, empty if the resource is not managed
+	ResourceId string `width:"36" charset:"ascii" nullable:"true" index:"true" list:"admin"`
+	ExternalId string `width:"256" charset:"utf8" nullable:"true" list:"admin"`
+	ProjectId  string `name:"tenant_id" width:"128" charset:"ascii" nullable:"true" index:"true" list:"admin"`
+	Product    string `width:"64" charset:"utf8" nullable:"true" list:"admin"`
+
+	Amount   float64 `nullable:"false" default:"0" list:"admin"`
+	Currency string  `width:"8" charset:"ascii" nullable:"false" list:"admin"`
+}
+
+func (manager *SCloudcostManager) AllowListItems(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowList(userCred, manager)
+}
+
+func (manager *SCloudcostManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (self *SCloudcost) AllowGetDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGet(userCred, self)
+}
+
+func (self *SCloudcost) AllowUpdateItem(ctx context.Context, userCred mcclient.TokenCredential) bool {
+	return false
+}
+
+func (self *SCloudcost) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (self *SCloudcost) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	extra.Add(jsonutils.NewString(self.UsageDate.Format(COST_DATE_FORMAT)), "usage_date")
+	if len(self.ProjectId) > 0 {
+		tenant, _ := db.TenantCacheManager.FetchTenantById(ctx, self.ProjectId)
+		if tenant != nil {
+			extra.Add(jsonutils.NewString(tenant.GetName()), "tenant")
+		}
+	}
+	return extra
+}
+
+// parseCostDateRange returns the usage date range of the query, default to
+// the month to date
+func parseCostDateRange(query jsonutils.JSONObject) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := cloudprovider.BillDate(now)
+	if str, _ := query.GetString("start_date"); len(str) > 0 {
+		date, err := time.Parse(COST_DATE_FORMAT, str)
+		if err != nil {
+			return start, end, httperrors.NewInputParameterError("invalid start_date %s", str)
+		}
+		start = date
+	}
+	if str, _ := query.GetString("end_date"); len(str) > 0 {
+		date, err := time.Parse(COST_DATE_FORMAT, str)
+		if err != nil {
+			return start, end, httperrors.NewInputParameterError("invalid end_date %s", str)
+		}
+		end = date
+	}
+	if end.Before(start) {
+		return start, end, httperrors.NewInputParameterError("end_date is before start_date")
+	}
+	return start, end, nil
+}
+
+func (manager *SCloudcostManager) filterByQuery(ctx context.Context, q *sqlchemy.SQuery, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	q, err := managedResourceFilterByAccount(q, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if str := jsonutils.GetAnyString(query, []string{"project", "tenant", "project_id", "tenant_id"}); len(str) > 0 {
+		tenant, err := db.TenantCacheManager.FetchTenantByIdOrName(ctx, str)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, httperrors.NewResourceNotFoundError2("project", str)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		q = q.Equals("tenant_id", tenant.GetId())
+	}
+	for _, key := range []string{"source", "resource_type", "resource_id", "external_id", "currency"} {
+		if str, _ := query.GetString(key); len(str) > 0 {
+			q = q.Equals(key, str)
+		}
+	}
+	start, end, err := parseCostDateRange(query)
+	if err != nil {
+		return nil, err
+	}
+	q = q.GE("usage_date", start).LE("usage_date", end)
+	return q, nil
+}
+
+func (manager *SCloudcostManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	q, err := manager.SResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	return manager.filterByQuery(ctx, q, query)
+}
+
+type sCostSummary struct {
+	TenantId     string
+	ResourceType string
+	ResourceId   string
+	ExternalId   string
+	Currency     string
+	Amount       float64
+}
+
+// getSummaryQuery applies the query of the summary, non admin users are
+// only allowed to see the costs of their own project
+func (manager *SCloudcostManager) getSummaryQuery(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	q := manager.Query()
+	if !db.IsAdminAllowList(userCred, manager) {
+		project := jsonutils.GetAnyString(query, []string{"project", "tenant", "project_id", "tenant_id"})
+		if len(project) > 0 && project != userCred.GetProjectId() && project != userCred.GetProjectName() {
+			return nil, httperrors.NewForbiddenError("not allow to query costs of project %s", project)
+		}
+		query.(*jsonutils.JSONDict).Set("project", jsonutils.NewString(userCred.GetProjectId()))
+	}
+	return manager.filterByQuery(ctx, q, query)
+}
+
+func (manager *SCloudcostManager) AllowGetPropertyProjectSummary(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return true
+}
+
+// GetPropertyProjectSummary returns the total costs of the projects in the
+// date range
+func (manager *SCloudcostManager) GetPropertyProjectSummary(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	q, err := manager.getSummaryQuery(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	sq := q.SubQuery()
+	summaryQ := sq.Query(sq.Field("tenant_id"), sq.Field("currency"), sqlchemy.SUM("amount", sq.Field("amount")))
+	summaryQ = summaryQ.GroupBy(sq.Field("tenant_id"), sq.Field("currency"))
+	return manager.fetchSummary(ctx, summaryQ, query)
+}
+
+func (manager *SCloudcostManager) AllowGetPropertyResourceSummary(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return true
+}
+
+// GetPropertyResourceSummary returns the total costs of the resources in the
+// date range, filter by project to get the chargeback details of a project
+func (manager *SCloudcostManager) GetPropertyResourceSummary(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	q, err := manager.getSummaryQuery(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	sq := q.SubQuery()
+	summaryQ := sq.Query(sq.Field("tenant_id"), sq.Field("resource_type"), sq.Field("resource_id"), sq.Field("external_id"),
+		sq.Field("currency"), sqlchemy.SUM("amount", sq.Field("amount")))
+	summaryQ = summaryQ.GroupBy(sq.Field("tenant_id"), sq.Field("resource_type"), sq.Field("resource_id"), sq.Field("external_id"), sq.Field("currency"))
+	return manager.fetchSummary(ctx, summaryQ, query)
+}
+
+func (manager *SCloudcostManager) fetchSummary(ctx context.Context, q *sqlchemy.SQuery, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	summaries := make([]sCostSummary, 0)
+	err := q.All(&summaries)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, httperrors.NewInternalServerError("query cost summary fail %s", err)
+	}
+	rows := make([]jsonutils.JSONObject, 0, len(summaries))
+	for i := range summaries {
+		row := jsonutils.Marshal(summaries[i]).(*jsonutils.JSONDict)
+		if len(summaries[i].TenantId) > 0 {
+			tenant, _ := db.TenantCacheManager.FetchTenantById(ctx, summaries[i].TenantId)
+			if tenant != nil {
+				row.Add(jsonutils.NewString(tenant.GetName()), "tenant")
+			}
+		}
+		rows = append(rows, row)
+	}
+	start, end, _ := parseCostDateRange(query)
+	ret := jsonutils.NewDict()
+	ret.Add(jsonutils.NewString(start.Format(COST_DATE_FORMAT)), "start_date")
+	ret.Add(jsonutils.NewString(end.Format(COST_DATE_FORMAT)), "end_date")
+	ret.Add(jsonutils.NewArray(rows...), "data")
+	return ret, nil
+}
+
+func (manager *SCloudcostManager) AllowPerformCollect(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowClassPerform(userCred, manager, "collect")
+}
+
+// PerformCollect collects the costs of the day again, e.g. after the cost
+// rates are changed or the provider amended the bill
+func (manager *SCloudcostManager) PerformCollect(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	dayStr, _ := data.GetString("day")
+	if len(dayStr) == 0 {
+		return nil, httperrors.NewMissingParameterError("day")
+	}
+	day, err := time.Parse(COST_DATE_FORMAT, dayStr)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("invalid day %s", dayStr)
+	}
+	if !day.Before(cloudprovider.BillDate(time.Now().UTC())) {
+		return nil, httperrors.NewInputParameterError("costs of %s are not settled", dayStr)
+	}
+	err = manager.collectCosts(ctx, userCred, day)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
+
+// CollectCosts is the cron job collecting the costs of yesterday
+func (manager *SCloudcostManager) CollectCosts(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	day := cloudprovider.BillDate(time.Now().UTC()).AddDate(0, 0, -1)
+	err := manager.collectCosts(ctx, userCred, day)
+	if err != nil {
+		log.Errorf("collect costs of %s fail %s", day.Format(COST_DATE_FORMAT), err)
+	}
+}
+
+func (manager *SCloudcostManager) collectCosts(ctx context.Context, userCred mcclient.TokenCredential, day time.Time) error {
+	lockman.LockClass(ctx, manager, "")
+	defer lockman.ReleaseClass(ctx, manager, "")
+
+	providers := make([]SCloudprovider, 0)
+	q := CloudproviderManager.Query().IsTrue("enabled")
+	err := db.FetchModelObjects(CloudproviderManager, q, &providers)
+	if err != nil {
+		return err
+	}
+	for i := range providers {
+		err := manager.collectProviderCosts(ctx, userCred, &providers[i], day)
+		if err != nil {
+			// keep collecting the other providers
+			log.Errorf("collect costs of cloudprovider %s fail %s", providers[i].Name, err)
+		}
+	}
+	return manager.collectOnPremiseCosts(ctx, userCred, day)
+}
+
+func (manager *SCloudcostManager) collectProviderCosts(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, day time.Time) error {
+	driver, err := provider.GetProvider()
+	if err != nil {
+		return err
+	}
+	billing, ok := driver.(cloudprovider.ICloudBillingProvider)
+	if !ok {
+		return nil
+	}
+	query := cloudprovider.SCloudBillQuery{Day: day}
+	account := provider.GetCloudaccount()
+	if account != nil && account.Options != nil {
+		query.Bucket, _ = account.Options.GetString(CLOUDACCOUNT_OPTION_BILL_BUCKET)
+		query.Prefix, _ = account.Options.GetString(CLOUDACCOUNT_OPTION_BILL_PREFIX)
+	}
+	items, err := billing.GetCloudBillItems(query)
+	if err != nil {
+		if err == cloudprovider.ErrNotSupported {
+			return nil
+		}
+		return err
+	}
+	costs := make([]SCloudcost, 0, len(items))
+	for _, item := range items {
+		cost := SCloudcost{
+			UsageDate:    day,
+			Source:       api.COST_SOURCE_PROVIDER,
+			ManagerId:    provider.Id,
+			ResourceType: item.ResourceType,
+			ExternalId:   item.ResourceId,
+			Product:      item.Product,
+			Amount:       item.Amount,
+			Currency:     item.Currency,
+		}
+		res := fetchCostResource(provider.Id, item.ResourceType, item.ResourceId)
+		if res != nil {
+			cost.ResourceType = res.ResourceType
+			cost.ResourceId = res.Id
+			cost.ProjectId = res.TenantId
+		}
+		if len(item.ProjectId) > 0 {
+			// the project reported by the bill takes precedence as the
+			// resource may have been moved or deleted since
+			extProject, err := ExternalProjectManager.GetProject(item.ProjectId, provider.Id)
+			if err == nil && len(extProject.ProjectId) > 0 {
+				cost.ProjectId = extProject.ProjectId
+			}
+		}
+		if len(cost.ProjectId) == 0 {
+			cost.ProjectId = provider.ProjectId
+		}
+		costs = append(costs, cost)
+	}
+	log.Infof("collect %d costs of cloudprovider %s on %s", len(costs), provider.Name, day.Format(COST_DATE_FORMAT))
+	return manager.syncCosts(day, api.COST_SOURCE_PROVIDER, provider.Id, costs)
+}
+
+type sCostResource struct {
+	Id           string
+	TenantId     string
+	ResourceType string
+}
+
+// fetchCostResource finds the local resource of the bill item by its
+// external id, all the managed resources are searched if the type is unknown
+func fetchCostResource(providerId string, resourceType string, externalId string) *sCostResource {
+	if len(externalId) == 0 {
+		return nil
+	}
+	types := []string{resourceType}
+	if len(resourceType) == 0 {
+		types = []string{
+			cloudprovider.CLOUD_BILL_RESOURCE_SERVER,
+			cloudprovider.CLOUD_BILL_RESOURCE_DISK,
+			cloudprovider.CLOUD_BILL_RESOURCE_EIP,
+			cloudprovider.CLOUD_BILL_RESOURCE_LOADBALANCER,
+			cloudprovider.CLOUD_BILL_RESOURCE_NATGATEWAY,
+			cloudprovider.CLOUD_BILL_RESOURCE_BUCKET,
+			cloudprovider.CLOUD_BILL_RESOURCE_SNAPSHOT,
+		}
+	}
+	for _, t := range types {
+		var q *sqlchemy.SQuery
+		switch t {
+		case cloudprovider.CLOUD_BILL_RESOURCE_SERVER:
+			guests := GuestManager.Query().SubQuery()
+			hosts := HostManager.Query().SubQuery()
+			q = guests.Query(guests.Field("id"), guests.Field("tenant_id"))
+			q = q.Join(hosts, sqlchemy.Equals(hosts.Field("id"), guests.Field("host_id")))
+			q = q.Filter(sqlchemy.Equals(hosts.Field("manager_id"), providerId))
+			q = q.Filter(sqlchemy.Equals(guests.Field("external_id"), externalId))
+		case cloudprovider.CLOUD_BILL_RESOURCE_DISK:
+			disks := DiskManager.Query().SubQuery()
+			storages := StorageManager.Query().SubQuery()
+			q = disks.Query(disks.Field("id"), disks.Field("tenant_id"))
+			q = q.Join(storages, sqlchemy.Equals(storages.Field("id"), disks.Field("storage_id")))
+			q = q.Filter(sqlchemy.Equals(storages.Field("manager_id"), providerId))
+			q = q.Filter(sqlchemy.Equals(disks.Field("external_id"), externalId))
+		default:
+			var manager db.IModelManager
+			switch t {
+			case cloudprovider.CLOUD_BILL_RESOURCE_EIP:
+				manager = ElasticipManager
+			case cloudprovider.CLOUD_BILL_RESOURCE_LOADBALANCER:
+				manager = LoadbalancerManager
+			case cloudprovider.CLOUD_BILL_RESOURCE_NATGATEWAY:
+				manager = NatGatewayManager
+			case cloudprovider.CLOUD_BILL_RESOURCE_BUCKET:
+				manager = BucketManager
+			case cloudprovider.CLOUD_BILL_RESOURCE_SNAPSHOT:
+				manager = SnapshotManager
+			default:
+				continue
+			}
+			q = manager.Query("id", "tenant_id").Equals("manager_id", providerId).Equals("external_id", externalId)
+		}
+		res := sCostResource{}
+		err := q.First(&res)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Errorf("fetch %s %s of cost fail %s", t, externalId, err)
+			}
+			continue
+		}
+		res.ResourceType = t
+		return &res
+	}
+	return nil
+}
+
+// costPeriod returns the part of [dayStart, dayEnd) the resource exists
+func costPeriod(createdAt time.Time, deleted bool, deletedAt time.Time, dayStart, dayEnd time.Time) (time.Time, time.Time) {
+	start, end := dayStart, dayEnd
+	if createdAt.After(start) {
+		start = createdAt
+	}
+	if deleted && deletedAt.Before(end) {
+		end = deletedAt
+	}
+	return start, end
+}
+
+// costRatio returns the ratio of the day covered by the period
+func costRatio(start, end time.Time, day time.Duration) float64 {
+	if !end.After(start) {
+		return 0
+	}
+	return float64(end.Sub(start)) / float64(day)
+}
+
+type sStatusChange struct {
+	time      time.Time
+	oldStatus string
+	newStatus string
+}
+
+// parseStatusNotes parses the notes of the status update logs,
+// which is in the form of old=>new or old=>new: reason
+func parseStatusNotes(notes string) (string, string, bool) {
+	pos := strings.Index(notes, "=>")
+	if pos < 0 {
+		return "", "", false
+	}
+	oldStatus, newStatus := notes[:pos], notes[pos+2:]
+	if pos := strings.Index(newStatus, ":"); pos >= 0 {
+		newStatus = newStatus[:pos]
+	}
+	return strings.TrimSpace(oldStatus), strings.TrimSpace(newStatus), true
+}
+
+// fetchStatusChanges loads the status changes of the objects since the time
+// from the operation logs, ordered by time
+func fetchStatusChanges(objType string, objIds []string, since time.Time) (map[string][]sStatusChange, error) {
+	ret := make(map[string][]sStatusChange)
+	if len(objIds) == 0 {
+		return ret, nil
+	}
+	logs := make([]db.SOpsLog, 0)
+	q := db.OpsLog.Query().Equals("obj_type", objType).In("obj_id", objIds)
+	q = q.Equals("action", db.ACT_UPDATE_STATUS).GE("ops_time", since).Asc("ops_time")
+	err := db.FetchModelObjects(db.OpsLog, q, &logs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range logs {
+		oldStatus, newStatus, ok := parseStatusNotes(logs[i].Notes)
+		if !ok {
+			continue
+		}
+		ret[logs[i].ObjId] = append(ret[logs[i].ObjId], sStatusChange{
+			time:      logs[i].OpsTime,
+			oldStatus: oldStatus,
+			newStatus: newStatus,
+		})
+	}
+	return ret, nil
+}
+
+// runningPeriod returns the duration the resource is running within
+// [start, end), the status before the first change since start is the
+// former status of the change, or the current status if not changed
+func runningPeriod(status string, changes []sStatusChange, start, end time.Time, isRunning func(string) bool) time.Duration {
+	if len(changes) > 0 {
+		status = changes[0].oldStatus
+	}
+	var ret time.Duration
+	from := start
+	for _, change := range changes {
+		if !change.time.Before(end) {
+			break
+		}
+		if change.time.After(from) {
+			if isRunning(status) {
+				ret += change.time.Sub(from)
+			}
+			from = change.time
+		}
+		status = change.newStatus
+	}
+	if end.After(from) && isRunning(status) {
+		ret += end.Sub(from)
+	}
+	return ret
+}
+
+func isGuestRunning(status string) bool {
+	return utils.IsInStringArray(status, api.VM_RUNNING_STATUS)
+}
+
+// collectOnPremiseCosts accounts the costs of the guests and disks on the
+// on-premise hosts and storages by the cost rates, including the resources
+// deleted during the day, guests are charged for the time they are running
+// and disks for the time they exist
+func (manager *SCloudcostManager) collectOnPremiseCosts(ctx context.Context, userCred mcclient.TokenCredential, day time.Time) error {
+	rates, err := CostRateManager.fetchRates()
+	if err != nil {
+		return err
+	}
+	if rates.isEmpty() {
+		return nil
+	}
+	onPremise := CloudproviderManager.GetProviderIdsQuery(tristate.None, tristate.True)
+	isOnPremise := func(field sqlchemy.IQueryField) sqlchemy.ICondition {
+		return sqlchemy.OR(sqlchemy.IsNullOrEmpty(field), sqlchemy.In(field, onPremise))
+	}
+	dayStart, dayEnd := day, day.AddDate(0, 0, 1)
+	dayLength := dayEnd.Sub(dayStart)
+	// resources created after the day or deleted before it are not charged
+	inDay := func(q *sqlchemy.SQuery) *sqlchemy.SQuery {
+		q = q.LT("created_at", dayEnd)
+		return q.Filter(sqlchemy.OR(sqlchemy.IsFalse(q.Field("deleted")), sqlchemy.GT(q.Field("deleted_at"), dayStart)))
+	}
+
+	costs := make([]SCloudcost, 0)
+
+	guests := make([]SGuest, 0)
+	hosts := HostManager.Query().SubQuery()
+	q := inDay(GuestManager.RawQuery())
+	q = q.Join(hosts, sqlchemy.Equals(hosts.Field("id"), q.Field("host_id")))
+	q = q.Filter(isOnPremise(hosts.Field("manager_id")))
+	err = db.FetchModelObjects(GuestManager, q, &guests)
+	if err != nil {
+		return err
+	}
+	guestIds := make([]string, len(guests))
+	for i := range guests {
+		guestIds[i] = guests[i].Id
+	}
+	changes, err := fetchStatusChanges(GuestManager.Keyword(), guestIds, dayStart)
+	if err != nil {
+		return err
+	}
+	for i := range guests {
+		start, end := costPeriod(guests[i].CreatedAt, guests[i].Deleted, guests[i].DeletedAt, dayStart, dayEnd)
+		running := runningPeriod(guests[i].Status, changes[guests[i].Id], start, end, isGuestRunning)
+		if running <= 0 {
+			continue
+		}
+		amount, currency, ok := rates.guestCost(&guests[i])
+		if !ok {
+			continue
+		}
+		costs = append(costs, SCloudcost{
+			ResourceType: cloudprovider.CLOUD_BILL_RESOURCE_SERVER,
+			ResourceId:   guests[i].Id,
+			ProjectId:    guests[i].ProjectId,
+			Product:      guests[i].Hypervisor,
+			Amount:       amount * costRatio(start, start.Add(running), dayLength),
+			Currency:     currency,
+		})
+	}
+
+	disks := make([]SDisk, 0)
+	storages := StorageManager.Query().SubQuery()
+	q = inDay(DiskManager.RawQuery())
+	q = q.Join(storages, sqlchemy.Equals(storages.Field("id"), q.Field("storage_id")))
+	q = q.Filter(isOnPremise(storages.Field("manager_id")))
+	err = db.FetchModelObjects(DiskManager, q, &disks)
+	if err != nil {
+		return err
+	}
+	for i := range disks {
+		storage := disks[i].GetStorage()
+		if storage == nil {
+			continue
+		}
+		start, end := costPeriod(disks[i].CreatedAt, disks[i].Deleted, disks[i].DeletedAt, dayStart, dayEnd)
+		ratio := costRatio(start, end, dayLength)
+		if ratio <= 0 {
+			continue
+		}
+		amount, currency, ok := rates.diskCost(&disks[i], storage.StorageType)
+		if !ok {
+			continue
+		}
+		costs = append(costs, SCloudcost{
+			ResourceType: cloudprovider.CLOUD_BILL_RESOURCE_DISK,
+			ResourceId:   disks[i].Id,
+			ProjectId:    disks[i].ProjectId,
+			Product:      storage.StorageType,
+			Amount:       amount * ratio,
+			Currency:     currency,
+		})
+	}
+
+	for i := range costs {
+		costs[i].UsageDate = day
+		costs[i].Source = api.COST_SOURCE_ONPREMISE
+		if len(costs[i].Currency) == 0 {
+			costs[i].Currency = api.COST_DEFAULT_CURRENCY
+		}
+	}
+	log.Infof("collect %d on-premise costs on %s", len(costs), day.Format(COST_DATE_FORMAT))
+	return manager.syncCosts(day, api.COST_SOURCE_ONPREMISE, "", costs)
+}
+
+func (self *SCloudcost) costKey() string {
+	return fmt.Sprintf("%s/%s/%s/%s", self.ResourceId, self.ExternalId, self.Product, self.Currency)
+}
+
+// syncCosts upserts the costs of the day collected from the source, the
+// stale records of the resources no longer charged are removed
+func (manager *SCloudcostManager) syncCosts(day time.Time, source string, managerId string, costs []SCloudcost) error {
+	q := manager.Query().Equals("usage_date", day).Equals("source", source)
+	if len(managerId) > 0 {
+		q = q.Equals("manager_id", managerId)
+	} else {
+		q = q.IsNullOrEmpty("manager_id")
+	}
+	dbCosts := make([]SCloudcost, 0)
+	err := db.FetchModelObjects(manager, q, &dbCosts)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*SCloudcost)
+	for i := range dbCosts {
+		existing[dbCosts[i].costKey()] = &dbCosts[i]
+	}
+	for i := range costs {
+		key := costs[i].costKey()
+		dbCost, ok := existing[key]
+		if !ok {
+			costs[i].ManagerId = managerId
+			err = manager.TableSpec().Insert(&costs[i])
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		delete(existing, key)
+		_, err = db.Update(dbCost, func() error {
+			dbCost.ResourceType = costs[i].ResourceType
+			dbCost.ProjectId = costs[i].ProjectId
+			dbCost.Amount = costs[i].Amount
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, dbCost := range existing {
+		_, err = db.Update(dbCost, func() error {
+			return dbCost.MarkDelete()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestParseStatusNotes(t *testing.T) {
+	cases := []struct {
+		notes     string
+		oldStatus string
+		newStatus string
+		ok        bool
+	}{
+		{"ready=>running", api.VM_READY, api.VM_RUNNING, true},
+		{"running=>ready: stop by user", api.VM_RUNNING, api.VM_READY, true},
+		{"running", "", "", false},
+	}
+	for _, c := range cases {
+		oldStatus, newStatus, ok := parseStatusNotes(c.notes)
+		if oldStatus != c.oldStatus || newStatus != c.newStatus || ok != c.ok {
+			t.Errorf("%s: want %s %s %v, got %s %s %v", c.notes, c.oldStatus, c.newStatus, c.ok, oldStatus, newStatus, ok)
+		}
+	}
+}
+
+func TestCostPeriod(t *testing.T) {
+	dayStart := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	at := func(hour int) time.Time {
+		return dayStart.Add(time.Duration(hour) * time.Hour)
+	}
+	cases := []struct {
+		name      string
+		createdAt time.Time
+		deleted   bool
+		deletedAt time.Time
+		ratio     float64
+	}{
+		{"whole day", at(-24), false, time.Time{}, 1},
+		{"created in the day", at(18), false, time.Time{}, 0.25},
+		{"deleted in the day", at(-24), true, at(6), 0.25},
+		{"created and deleted in the day", at(6), true, at(18), 0.5},
+		{"created after the day", at(30), false, time.Time{}, 0},
+	}
+	for _, c := range cases {
+		start, end := costPeriod(c.createdAt, c.deleted, c.deletedAt, dayStart, dayEnd)
+		ratio := costRatio(start, end, dayEnd.Sub(dayStart))
+		if ratio != c.ratio {
+			t.Errorf("%s: want ratio %f, got %f", c.name, c.ratio, ratio)
+		}
+	}
+}
+
+func TestRunningPeriod(t *testing.T) {
+	dayStart := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	at := func(hour int) time.Time {
+		return dayStart.Add(time.Duration(hour) * time.Hour)
+	}
+	change := func(hour int, oldStatus, newStatus string) sStatusChange {
+		return sStatusChange{time: at(hour), oldStatus: oldStatus, newStatus: newStatus}
+	}
+	cases := []struct {
+		name    string
+		status  string
+		changes []sStatusChange
+		start   time.Time
+		end     time.Time
+		want    time.Duration
+	}{
+		{"running all day", api.VM_RUNNING, nil, dayStart, dayEnd, 24 * time.Hour},
+		{"stopped all day", api.VM_READY, nil, dayStart, dayEnd, 0},
+		{
+			"stopped in the day", api.VM_READY,
+			[]sStatusChange{change(6, api.VM_RUNNING, api.VM_READY)},
+			dayStart, dayEnd, 6 * time.Hour,
+		},
+		{
+			"started in the day", api.VM_RUNNING,
+			[]sStatusChange{change(12, api.VM_READY, api.VM_STARTING), change(13, api.VM_STARTING, api.VM_RUNNING)},
+			dayStart, dayEnd, 12 * time.Hour,
+		},
+		{
+			"stopped after the day", api.VM_READY,
+			[]sStatusChange{change(30, api.VM_RUNNING, api.VM_READY)},
+			dayStart, dayEnd, 24 * time.Hour,
+		},
+		{
+			"deleted in the day", api.VM_READY,
+			[]sStatusChange{change(6, api.VM_RUNNING, api.VM_READY)},
+			dayStart, at(8), 6 * time.Hour,
+		},
+		{
+			"created in the day", api.VM_RUNNING,
+			[]sStatusChange{change(17, api.VM_READY, api.VM_RUNNING)},
+			at(16), dayEnd, 7 * time.Hour,
+		},
+	}
+	for _, c := range cases {
+		got := runningPeriod(c.status, c.changes, c.start, c.end, isGuestRunning)
+		if got != c.want {
+			t.Errorf("%s: want %s, got %s", c.name, c.want, got)
+		}
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 )
@@ -66,6 +67,9 @@ type SCloudproviderregion struct {
 	SyncResults jsonutils.JSONObject `list:"admin"`
 
 	LastDeepSyncAt time.Time `list:"admin"`
+
+	LastFullSyncAt  time.Time `list:"admin"`
+	LastEventSyncAt time.Time `list:"admin"`
 }
 
 func (joint *SCloudproviderregion) Master() db.IStandaloneModel {
@@ -279,6 +283,24 @@ func (self *SCloudproviderregion) DoSync(ctx context.Context, userCred mcclient.
 		return err
 	}
 
+	if localRegion.isManaged() && syncRange.EventSync && !self.needFullSync() {
+		remoteRegion, err := driver.GetIRegionById(localRegion.ExternalId)
+		if err != nil {
+			log.Errorf("GetIRegionById %s fail %s", localRegion.ExternalId, err)
+			return err
+		}
+		if source, ok := remoteRegion.(cloudprovider.ICloudEventSource); ok {
+			err = self.doEventSync(ctx, userCred, syncResults, provider, driver, localRegion, remoteRegion, source)
+			if err == nil {
+				log.Debugf("event sync result: %s", jsonutils.Marshal(syncResults))
+				return nil
+			}
+			log.Warningf("event sync of region %s fail %s, fallback to full sync", localRegion.Name, err)
+		}
+	}
+
+	syncStartAt := timeutils.UtcNow()
+
 	log.Debugf("need to do deep sync ... %v", syncRange.DeepSync)
 	if !syncRange.DeepSync {
 		intval := self.getSyncIntervalSeconds(nil)
@@ -299,6 +321,8 @@ func (self *SCloudproviderregion) DoSync(ctx context.Context, userCred mcclient.
 
 	if err != nil {
 		log.Errorf("dosync fail %s", err)
+	} else {
+		self.markFullSync(syncStartAt)
 	}
 
 	log.Debugf("dosync result: %s", jsonutils.Marshal(syncResults))
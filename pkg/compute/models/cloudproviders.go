@@ -301,6 +301,9 @@ type SSyncRange struct {
 	Force    bool
 	FullSync bool
 	DeepSync bool
+	// EventSync applies only the changes reported by the events of the region
+	// if its provider supports, a full sync is done when it is due
+	EventSync bool
 	// ProjectSync bool
 
 	Region []string
@@ -905,7 +908,38 @@ func (provider *SCloudprovider) syncCloudproviderRegions(ctx context.Context, us
 	}
 	if syncCnt == 0 {
 		provider.markEndSyncWithLock(ctx, userCred)
+		return
+	}
+	var waitChan chan bool = nil
+	if wg != nil {
+		wg.Add(1)
+		waitChan = make(chan bool)
+	}
+	provider.submitSyncDnsZonesTask(userCred, waitChan)
+	if wg != nil {
+		<-waitChan
+		wg.Done()
+	}
+}
+
+func (provider *SCloudprovider) submitSyncDnsZonesTask(userCred mcclient.TokenCredential, waitChan chan bool) {
+	RunSyncCloudproviderRegionTask(provider.Id, func() {
+		provider.doSyncDnsZones(context.Background(), userCred)
+		if waitChan != nil {
+			waitChan <- true
+		}
+	})
+}
+
+func (provider *SCloudprovider) doSyncDnsZones(ctx context.Context, userCred mcclient.TokenCredential) {
+	driver, err := provider.GetProvider()
+	if err != nil {
+		log.Errorf("fail to get driver of provider %s: %s", provider.Name, err)
+		return
 	}
+	syncResults := SSyncResultSet{}
+	syncDnsZones(ctx, userCred, syncResults, driver, provider)
+	log.Debugf("sync dns zones result: %s", jsonutils.Marshal(syncResults))
 }
 
 func (provider *SCloudprovider) SyncCallSyncCloudproviderRegions(ctx context.Context, userCred mcclient.TokenCredential, syncRange SSyncRange) {
@@ -948,8 +982,14 @@ func (self *SCloudprovider) RealDelete(ctx context.Context, userCred mcclient.To
 		LoadbalancerManager,
 		LoadbalancerAclManager,
 		LoadbalancerCertificateManager,
+		NatGatewayManager,
+		VpcPeeringManager,
+		VpnConnectionManager,
 		VpcManager,
 		ElasticipManager,
+		BucketManager,
+		DBInstanceManager,
+		DnsZoneManager,
 		CloudproviderRegionManager,
 		ExternalProjectManager,
 	} {
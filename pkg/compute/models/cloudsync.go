@@ -130,6 +130,50 @@ func syncProjects(ctx context.Context, userCred mcclient.TokenCredential, syncRe
 	// db.OpsLog.LogEvent(provider, db.ACT_SYNC_PROJECT_COMPLETE, msg, task.UserCred)
 }
 
+// syncDnsZones syncs the dns zones and their records, the zones are global
+// to the account and synced once for the provider instead of each region
+func syncDnsZones(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, driver cloudprovider.ICloudProvider, provider *SCloudprovider) {
+	dnsProvider, ok := driver.(cloudprovider.ICloudDnsProvider)
+	if !ok {
+		return
+	}
+	zones, err := dnsProvider.GetIDnsZones()
+	if err != nil {
+		log.Errorf("GetIDnsZones for provider %s failed %s", provider.GetName(), err)
+		return
+	}
+
+	localZones, remoteZones, result := DnsZoneManager.SyncDnsZones(ctx, userCred, provider, zones)
+
+	syncResults.Add(DnsZoneManager, result)
+
+	msg := result.Result()
+	log.Infof("SyncDnsZones for provider %s result: %s", provider.Name, msg)
+	if result.IsError() {
+		return
+	}
+
+	for i := 0; i < len(localZones); i++ {
+		func() {
+			lockman.LockObject(ctx, &localZones[i])
+			defer lockman.ReleaseObject(ctx, &localZones[i])
+
+			syncDnsZoneRecords(ctx, userCred, syncResults, provider, &localZones[i], remoteZones[i])
+		}()
+	}
+}
+
+func syncDnsZoneRecords(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localZone *SDnsZone, remoteZone cloudprovider.ICloudDnsZone) {
+	records, err := remoteZone.GetIDnsRecords()
+	if err != nil {
+		log.Errorf("GetIDnsRecords for dns zone %s failed %s", remoteZone.GetName(), err)
+		return
+	}
+	result := DnsRecordManager.SyncDnsRecords(ctx, userCred, provider, localZone, records)
+	syncResults.Add(DnsRecordManager, result)
+	log.Infof("SyncDnsRecords for dns zone %s result: %s", localZone.Name, result.Result())
+}
+
 func syncRegionEips(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, syncRange *SSyncRange) {
 	eips, err := remoteRegion.GetIEips()
 	if err != nil {
@@ -170,6 +214,11 @@ func syncRegionVPCs(ctx context.Context, userCred mcclient.TokenCredential, sync
 	}
 	db.OpsLog.LogEvent(provider, db.ACT_SYNC_HOST_COMPLETE, msg, userCred)
 	// logclient.AddActionLog(provider, getAction(task.Params), notes, task.UserCred, true)
+
+	// peerings and vpn connections go before the route tables whose next hops link to them
+	syncRegionVpcPeerings(ctx, userCred, syncResults, provider, localRegion, remoteRegion, syncRange)
+	syncRegionVpnConnections(ctx, userCred, syncResults, provider, localRegion, remoteRegion, syncRange)
+
 	for j := 0; j < len(localVpcs); j += 1 {
 		func() {
 			// lock vpc
@@ -179,11 +228,44 @@ func syncRegionVPCs(ctx context.Context, userCred mcclient.TokenCredential, sync
 			syncVpcWires(ctx, userCred, syncResults, provider, &localVpcs[j], remoteVpcs[j], syncRange)
 			syncVpcSecGroup(ctx, userCred, syncResults, provider, &localVpcs[j], remoteVpcs[j], syncRange)
 			syncVpcRouteTables(ctx, userCred, syncResults, provider, &localVpcs[j], remoteVpcs[j], syncRange)
+			syncVpcNatgateways(ctx, userCred, syncResults, provider, &localVpcs[j], remoteVpcs[j], syncRange)
 
 		}()
 	}
 }
 
+func syncRegionVpcPeerings(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, syncRange *SSyncRange) {
+	peerings, err := remoteRegion.GetIVpcPeerings()
+	if err != nil {
+		msg := fmt.Sprintf("GetIVpcPeerings for region %s failed %s", remoteRegion.GetName(), err)
+		log.Errorf(msg)
+		return
+	}
+
+	result := VpcPeeringManager.SyncVpcPeerings(ctx, userCred, provider, localRegion, peerings)
+
+	syncResults.Add(VpcPeeringManager, result)
+
+	msg := result.Result()
+	log.Infof("SyncVpcPeerings for region %s result: %s", localRegion.Name, msg)
+}
+
+func syncRegionVpnConnections(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, syncRange *SSyncRange) {
+	conns, err := remoteRegion.GetIVpnConnections()
+	if err != nil {
+		msg := fmt.Sprintf("GetIVpnConnections for region %s failed %s", remoteRegion.GetName(), err)
+		log.Errorf(msg)
+		return
+	}
+
+	result := VpnConnectionManager.SyncVpnConnections(ctx, userCred, provider, localRegion, conns)
+
+	syncResults.Add(VpnConnectionManager, result)
+
+	msg := result.Result()
+	log.Infof("SyncVpnConnections for region %s result: %s", localRegion.Name, msg)
+}
+
 func syncVpcSecGroup(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localVpc *SVpc, remoteVpc cloudprovider.ICloudVpc, syncRange *SSyncRange) {
 	secgroups, err := remoteVpc.GetISecurityGroups()
 	if err != nil {
@@ -215,6 +297,56 @@ func syncVpcRouteTables(ctx context.Context, userCred mcclient.TokenCredential,
 	}
 }
 
+func syncVpcNatgateways(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localVpc *SVpc, remoteVpc cloudprovider.ICloudVpc, syncRange *SSyncRange) {
+	natGateways, err := remoteVpc.GetINatGateways()
+	if err != nil {
+		msg := fmt.Sprintf("GetINatGateways for vpc %s failed %s", remoteVpc.GetId(), err)
+		log.Errorf(msg)
+		return
+	}
+	localNatGateways, remoteNatGateways, result := NatGatewayManager.SyncNatGateways(ctx, userCred, provider, localVpc, natGateways)
+
+	syncResults.Add(NatGatewayManager, result)
+
+	msg := result.Result()
+	log.Infof("SyncNatGateways for VPC %s result: %s", localVpc.Name, msg)
+	if result.IsError() {
+		return
+	}
+
+	for i := 0; i < len(localNatGateways); i++ {
+		func() {
+			lockman.LockObject(ctx, &localNatGateways[i])
+			defer lockman.ReleaseObject(ctx, &localNatGateways[i])
+
+			syncNatSEntries(ctx, userCred, syncResults, provider, &localNatGateways[i], remoteNatGateways[i])
+			syncNatDEntries(ctx, userCred, syncResults, provider, &localNatGateways[i], remoteNatGateways[i])
+		}()
+	}
+}
+
+func syncNatSEntries(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localNat *SNatGateway, remoteNat cloudprovider.ICloudNatGateway) {
+	sentries, err := remoteNat.GetINatSEntries()
+	if err != nil {
+		log.Errorf("GetINatSEntries for nat gateway %s failed %s", remoteNat.GetId(), err)
+		return
+	}
+	result := NatSEntryManager.SyncNatSEntries(ctx, userCred, provider, localNat, sentries)
+	syncResults.Add(NatSEntryManager, result)
+	log.Infof("SyncNatSEntries for nat gateway %s result: %s", localNat.Name, result.Result())
+}
+
+func syncNatDEntries(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localNat *SNatGateway, remoteNat cloudprovider.ICloudNatGateway) {
+	dentries, err := remoteNat.GetINatDEntries()
+	if err != nil {
+		log.Errorf("GetINatDEntries for nat gateway %s failed %s", remoteNat.GetId(), err)
+		return
+	}
+	result := NatDEntryManager.SyncNatDEntries(ctx, userCred, provider, localNat, dentries)
+	syncResults.Add(NatDEntryManager, result)
+	log.Infof("SyncNatDEntries for nat gateway %s result: %s", localNat.Name, result.Result())
+}
+
 func syncVpcWires(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localVpc *SVpc, remoteVpc cloudprovider.ICloudVpc, syncRange *SSyncRange) {
 	wires, err := remoteVpc.GetIWires()
 	if err != nil {
@@ -740,6 +872,38 @@ func syncRegionSnapshots(ctx context.Context, userCred mcclient.TokenCredential,
 	// db.OpsLog.LogEvent(provider, db.ACT_SYNC_HOST_COMPLETE, msg, userCred)
 }
 
+func syncRegionBuckets(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, syncRange *SSyncRange) {
+	buckets, err := remoteRegion.GetIBuckets()
+	if err != nil {
+		msg := fmt.Sprintf("GetIBuckets for region %s failed %s", remoteRegion.GetName(), err)
+		log.Errorf(msg)
+		return
+	}
+
+	result := BucketManager.SyncBuckets(ctx, userCred, provider, localRegion, buckets)
+
+	syncResults.Add(BucketManager, result)
+
+	msg := result.Result()
+	log.Infof("SyncBuckets for region %s result: %s", localRegion.Name, msg)
+}
+
+func syncRegionDBInstances(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, syncRange *SSyncRange) {
+	instances, err := remoteRegion.GetIDBInstances()
+	if err != nil {
+		msg := fmt.Sprintf("GetIDBInstances for region %s failed %s", remoteRegion.GetName(), err)
+		log.Errorf(msg)
+		return
+	}
+
+	result := DBInstanceManager.SyncDBInstances(ctx, userCred, provider, localRegion, instances)
+
+	syncResults.Add(DBInstanceManager, result)
+
+	msg := result.Result()
+	log.Infof("SyncDBInstances for region %s result: %s", localRegion.Name, msg)
+}
+
 func syncPublicCloudProviderInfo(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
@@ -801,6 +965,10 @@ func syncPublicCloudProviderInfo(
 	syncRegionLoadbalancerCertificates(ctx, userCred, syncResults, provider, localRegion, remoteRegion, syncRange)
 	syncRegionLoadbalancers(ctx, userCred, syncResults, provider, localRegion, remoteRegion, syncRange)
 
+	syncRegionBuckets(ctx, userCred, syncResults, provider, localRegion, remoteRegion, syncRange)
+
+	syncRegionDBInstances(ctx, userCred, syncResults, provider, localRegion, remoteRegion, syncRange)
+
 	log.Debugf("storageCachePairs count %d", len(storageCachePairs))
 	for i := range storageCachePairs {
 		if storageCachePairs[i].isNew || syncRange.DeepSync {
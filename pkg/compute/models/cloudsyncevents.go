@@ -0,0 +1,706 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/pkg/util/timeutils"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// needFullSync tells whether the region is due for a full sync even if its
+// provider reports the changes through events
+func (self *SCloudproviderregion) needFullSync() bool {
+	if self.LastFullSyncAt.IsZero() || self.LastEventSyncAt.IsZero() {
+		return true
+	}
+	return time.Now().Sub(self.LastFullSyncAt) > time.Duration(options.Options.EventSyncFullSyncIntervalSeconds)*time.Second
+}
+
+func (self *SCloudproviderregion) markFullSync(syncStartAt time.Time) error {
+	_, err := db.Update(self, func() error {
+		self.LastFullSyncAt = syncStartAt
+		// events happened during the full sync are applied by the next event sync
+		self.LastEventSyncAt = syncStartAt
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Fail to update last_full_sync_at %s", err)
+		return err
+	}
+	return nil
+}
+
+// doEventSync applies the changes reported by the event source of the region
+// since the last event sync
+func (self *SCloudproviderregion) doEventSync(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	syncResults SSyncResultSet,
+	provider *SCloudprovider,
+	driver cloudprovider.ICloudProvider,
+	localRegion *SCloudregion,
+	remoteRegion cloudprovider.ICloudRegion,
+	source cloudprovider.ICloudEventSource,
+) error {
+	end := timeutils.UtcNow()
+	// change feeds deliver events with delay, the overlapped events are
+	// applied again which is harmless as the remote resources are refetched
+	start := self.LastEventSyncAt.Add(-time.Duration(options.Options.EventSyncDelaySeconds) * time.Second)
+	events, err := source.GetICloudEvents(start, end)
+	if err != nil {
+		return err
+	}
+	log.Debugf("GetICloudEvents for region %s since %s: %d events", localRegion.Name, start, len(events))
+
+	err = syncRegionEvents(ctx, userCred, syncResults, provider, driver, localRegion, remoteRegion, events)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Update(self, func() error {
+		self.LastEventSyncAt = end
+		return nil
+	})
+	return err
+}
+
+func syncRegionEvents(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	syncResults SSyncResultSet,
+	provider *SCloudprovider,
+	driver cloudprovider.ICloudProvider,
+	localRegion *SCloudregion,
+	remoteRegion cloudprovider.ICloudRegion,
+	events []cloudprovider.SCloudEvent,
+) error {
+	// resources are synced one by one by the ids carried by the events
+	changed := make(map[string][]string)
+	for _, event := range events {
+		switch event.ResourceType {
+		case cloudprovider.CLOUD_EVENT_RESOURCE_SERVER,
+			cloudprovider.CLOUD_EVENT_RESOURCE_DISK,
+			cloudprovider.CLOUD_EVENT_RESOURCE_SNAPSHOT,
+			cloudprovider.CLOUD_EVENT_RESOURCE_EIP,
+			cloudprovider.CLOUD_EVENT_RESOURCE_VPC,
+			cloudprovider.CLOUD_EVENT_RESOURCE_SECGROUP,
+			cloudprovider.CLOUD_EVENT_RESOURCE_LOADBALANCER,
+			cloudprovider.CLOUD_EVENT_RESOURCE_BUCKET:
+			if !utils.IsInStringArray(event.ResourceId, changed[event.ResourceType]) {
+				changed[event.ResourceType] = append(changed[event.ResourceType], event.ResourceId)
+			}
+		default:
+			// left to the full sync
+			log.Debugf("ignore event of %s %s", event.ResourceType, event.ResourceId)
+		}
+	}
+
+	addResult := func(manager db.IModelManager, extId string, result compare.SyncResult) {
+		syncResults.Add(manager, result)
+		if result.IsError() {
+			log.Errorf("sync event %s %s result: %s", manager.Keyword(), extId, result.Result())
+		}
+	}
+
+	for _, extId := range changed[cloudprovider.CLOUD_EVENT_RESOURCE_EIP] {
+		addResult(ElasticipManager, extId, syncEventEip(ctx, userCred, provider, localRegion, remoteRegion, extId))
+	}
+	if len(changed[cloudprovider.CLOUD_EVENT_RESOURCE_VPC]) > 0 {
+		// peerings and vpn connections are only listed by region, they go
+		// before the route tables whose next hops link to them
+		syncRegionVpcPeerings(ctx, userCred, syncResults, provider, localRegion, remoteRegion, &SSyncRange{})
+		syncRegionVpnConnections(ctx, userCred, syncResults, provider, localRegion, remoteRegion, &SSyncRange{})
+	}
+	for _, extId := range changed[cloudprovider.CLOUD_EVENT_RESOURCE_VPC] {
+		addResult(VpcManager, extId, syncEventVpc(ctx, userCred, syncResults, provider, localRegion, remoteRegion, extId))
+	}
+	if len(changed[cloudprovider.CLOUD_EVENT_RESOURCE_SECGROUP]) > 0 {
+		// security groups are only listed by vpc
+		syncEventSecgroups(ctx, userCred, syncResults, provider, localRegion, remoteRegion, changed[cloudprovider.CLOUD_EVENT_RESOURCE_VPC])
+	}
+	for _, extId := range changed[cloudprovider.CLOUD_EVENT_RESOURCE_DISK] {
+		addResult(DiskManager, extId, syncEventDisk(ctx, userCred, provider, driver, localRegion, remoteRegion, extId))
+	}
+	for _, extId := range changed[cloudprovider.CLOUD_EVENT_RESOURCE_SERVER] {
+		addResult(GuestManager, extId, syncEventVM(ctx, userCred, provider, driver, localRegion, remoteRegion, extId))
+	}
+	for _, extId := range changed[cloudprovider.CLOUD_EVENT_RESOURCE_SNAPSHOT] {
+		addResult(SnapshotManager, extId, syncEventSnapshot(ctx, userCred, provider, localRegion, remoteRegion, extId))
+	}
+	for _, extId := range changed[cloudprovider.CLOUD_EVENT_RESOURCE_LOADBALANCER] {
+		addResult(LoadbalancerManager, extId, syncEventLoadbalancer(ctx, userCred, syncResults, provider, localRegion, remoteRegion, extId))
+	}
+	for _, extId := range changed[cloudprovider.CLOUD_EVENT_RESOURCE_BUCKET] {
+		addResult(BucketManager, extId, syncEventBucket(ctx, userCred, provider, localRegion, remoteRegion, extId))
+	}
+	return nil
+}
+
+// fetchRegionObjectByExternalId fetches the object of extId synced from the
+// provider in the region, nil is returned if it is not synced yet
+func fetchRegionObjectByExternalId(manager db.IModelManager, provider *SCloudprovider, localRegion *SCloudregion, extId string) (db.IModel, error) {
+	q := manager.Query().Equals("cloudregion_id", localRegion.Id).Equals("manager_id", provider.Id).Equals("external_id", extId)
+	obj, err := db.NewModelObject(manager)
+	if err != nil {
+		return nil, err
+	}
+	err = q.First(obj)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// fetchEventObject fetches both the local and the remote object of extId,
+// the remote object is nil if it is gone
+func fetchEventObject(
+	manager db.IModelManager,
+	provider *SCloudprovider,
+	localRegion *SCloudregion,
+	extId string,
+	fetchRemote func() (interface{}, error),
+) (db.IModel, interface{}, error) {
+	local, err := fetchRegionObjectByExternalId(manager, provider, localRegion, extId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if local != nil && taskman.TaskManager.IsInTask(local) {
+		return nil, nil, fmt.Errorf("%s %s(%s) in task", manager.Keyword(), local.GetName(), local.GetId())
+	}
+	remote, err := fetchRemote()
+	if err == cloudprovider.ErrNotFound {
+		return local, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return local, remote, nil
+}
+
+func syncEventEip(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, extId string) compare.SyncResult {
+	syncResult := compare.SyncResult{}
+
+	lockman.LockClass(ctx, ElasticipManager, provider.ProjectId)
+	defer lockman.ReleaseClass(ctx, ElasticipManager, provider.ProjectId)
+
+	local, remote, err := fetchEventObject(ElasticipManager, provider, localRegion, extId, func() (interface{}, error) {
+		return remoteRegion.GetIEipById(extId)
+	})
+	if err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+	switch {
+	case remote == nil && local != nil:
+		err = local.(*SElasticip).syncRemoveCloudEip(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	case remote != nil && local != nil:
+		err = local.(*SElasticip).SyncWithCloudEip(ctx, userCred, provider, remote.(cloudprovider.ICloudEIP), provider.ProjectId)
+		if err != nil {
+			syncResult.UpdateError(err)
+		} else {
+			syncMetadata(ctx, userCred, local.(*SElasticip), remote.(cloudprovider.ICloudEIP))
+			syncResult.Update()
+		}
+	case remote != nil:
+		eip, err := ElasticipManager.newFromCloudEip(ctx, userCred, remote.(cloudprovider.ICloudEIP), localRegion, provider.ProjectId)
+		if err != nil {
+			syncResult.AddError(err)
+		} else {
+			syncMetadata(ctx, userCred, eip, remote.(cloudprovider.ICloudEIP))
+			syncResult.Add()
+		}
+	}
+	return syncResult
+}
+
+func syncEventSnapshot(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, extId string) compare.SyncResult {
+	syncResult := compare.SyncResult{}
+
+	lockman.LockClass(ctx, SnapshotManager, provider.ProjectId)
+	defer lockman.ReleaseClass(ctx, SnapshotManager, provider.ProjectId)
+
+	local, remote, err := fetchEventObject(SnapshotManager, provider, localRegion, extId, func() (interface{}, error) {
+		return remoteRegion.GetISnapshotById(extId)
+	})
+	if err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+	switch {
+	case remote == nil && local != nil:
+		err = local.(*SSnapshot).syncRemoveCloudSnapshot(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	case remote != nil && local != nil:
+		err = local.(*SSnapshot).SyncWithCloudSnapshot(ctx, userCred, remote.(cloudprovider.ICloudSnapshot), provider.ProjectId, localRegion)
+		if err != nil {
+			syncResult.UpdateError(err)
+		} else {
+			syncMetadata(ctx, userCred, local.(*SSnapshot), remote.(cloudprovider.ICloudSnapshot))
+			syncResult.Update()
+		}
+	case remote != nil:
+		snapshot, err := SnapshotManager.newFromCloudSnapshot(ctx, userCred, remote.(cloudprovider.ICloudSnapshot), localRegion, provider.ProjectId, provider)
+		if err != nil {
+			syncResult.AddError(err)
+		} else {
+			syncMetadata(ctx, userCred, snapshot, remote.(cloudprovider.ICloudSnapshot))
+			syncResult.Add()
+		}
+	}
+	return syncResult
+}
+
+func syncEventBucket(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, extId string) compare.SyncResult {
+	syncResult := compare.SyncResult{}
+
+	lockman.LockClass(ctx, BucketManager, BucketManager.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, BucketManager, BucketManager.GetOwnerId(userCred))
+
+	local, remote, err := fetchEventObject(BucketManager, provider, localRegion, extId, func() (interface{}, error) {
+		return remoteRegion.GetIBucketById(extId)
+	})
+	if err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+	switch {
+	case remote == nil && local != nil:
+		err = local.(*SBucket).syncRemoveCloudBucket(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	case remote != nil && local != nil:
+		err = local.(*SBucket).SyncWithCloudBucket(ctx, userCred, remote.(cloudprovider.ICloudBucket))
+		if err != nil {
+			syncResult.UpdateError(err)
+		} else {
+			syncMetadata(ctx, userCred, local.(*SBucket), remote.(cloudprovider.ICloudBucket))
+			syncResult.Update()
+		}
+	case remote != nil:
+		bucket, err := BucketManager.newFromCloudBucket(ctx, userCred, provider, localRegion, remote.(cloudprovider.ICloudBucket))
+		if err != nil {
+			syncResult.AddError(err)
+		} else {
+			syncMetadata(ctx, userCred, bucket, remote.(cloudprovider.ICloudBucket))
+			syncResult.Add()
+		}
+	}
+	return syncResult
+}
+
+func syncEventLoadbalancer(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, extId string) compare.SyncResult {
+	syncResult := compare.SyncResult{}
+
+	lockman.LockClass(ctx, LoadbalancerManager, provider.ProjectId)
+	defer lockman.ReleaseClass(ctx, LoadbalancerManager, provider.ProjectId)
+
+	local, remote, err := fetchEventObject(LoadbalancerManager, provider, localRegion, extId, func() (interface{}, error) {
+		return remoteRegion.GetILoadBalancerById(extId)
+	})
+	if err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+	var lb *SLoadbalancer
+	switch {
+	case remote == nil && local != nil:
+		err = local.(*SLoadbalancer).syncRemoveCloudLoadbalancer(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+		return syncResult
+	case remote != nil && local != nil:
+		lb = local.(*SLoadbalancer)
+		err = lb.SyncWithCloudLoadbalancer(ctx, userCred, remote.(cloudprovider.ICloudLoadbalancer), provider.ProjectId)
+		if err != nil {
+			syncResult.UpdateError(err)
+			return syncResult
+		}
+		syncResult.Update()
+	case remote != nil:
+		lb, err = LoadbalancerManager.newFromCloudLoadbalancer(ctx, userCred, provider, remote.(cloudprovider.ICloudLoadbalancer), localRegion, provider.ProjectId)
+		if err != nil {
+			syncResult.AddError(err)
+			return syncResult
+		}
+		syncResult.Add()
+	default:
+		return syncResult
+	}
+
+	lockman.LockObject(ctx, lb)
+	defer lockman.ReleaseObject(ctx, lb)
+
+	syncMetadata(ctx, userCred, lb, remote.(cloudprovider.ICloudLoadbalancer))
+	syncLoadbalancerBackendgroups(ctx, userCred, syncResults, provider, lb, remote.(cloudprovider.ICloudLoadbalancer), &SSyncRange{})
+	syncLoadbalancerListeners(ctx, userCred, syncResults, provider, lb, remote.(cloudprovider.ICloudLoadbalancer), &SSyncRange{})
+	return syncResult
+}
+
+func syncEventVpc(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, extId string) compare.SyncResult {
+	syncResult := compare.SyncResult{}
+
+	lockman.LockClass(ctx, VpcManager, provider.ProjectId)
+	defer lockman.ReleaseClass(ctx, VpcManager, provider.ProjectId)
+
+	local, remote, err := fetchEventObject(VpcManager, provider, localRegion, extId, func() (interface{}, error) {
+		return remoteRegion.GetIVpcById(extId)
+	})
+	if err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+	var vpc *SVpc
+	switch {
+	case remote == nil && local != nil:
+		err = local.(*SVpc).syncRemoveCloudVpc(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+		return syncResult
+	case remote != nil && local != nil:
+		vpc = local.(*SVpc)
+		err = vpc.SyncWithCloudVpc(ctx, userCred, remote.(cloudprovider.ICloudVpc))
+		if err != nil {
+			syncResult.UpdateError(err)
+			return syncResult
+		}
+		syncResult.Update()
+	case remote != nil:
+		vpc, err = VpcManager.newFromCloudVpc(ctx, userCred, remote.(cloudprovider.ICloudVpc), localRegion)
+		if err != nil {
+			syncResult.AddError(err)
+			return syncResult
+		}
+		syncResult.Add()
+	default:
+		return syncResult
+	}
+
+	lockman.LockObject(ctx, vpc)
+	defer lockman.ReleaseObject(ctx, vpc)
+
+	remoteVpc := remote.(cloudprovider.ICloudVpc)
+	syncVpcWires(ctx, userCred, syncResults, provider, vpc, remoteVpc, &SSyncRange{})
+	syncVpcSecGroup(ctx, userCred, syncResults, provider, vpc, remoteVpc, &SSyncRange{})
+	syncVpcRouteTables(ctx, userCred, syncResults, provider, vpc, remoteVpc, &SSyncRange{})
+	syncVpcNatgateways(ctx, userCred, syncResults, provider, vpc, remoteVpc, &SSyncRange{})
+	return syncResult
+}
+
+// syncEventSecgroups syncs the security groups of the vpcs in the region as
+// they can not be fetched by id, the vpcs synced by events are skipped
+func syncEventSecgroups(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, syncedVpcIds []string) {
+	vpcs, err := VpcManager.getVpcsByRegion(localRegion, provider)
+	if err != nil {
+		log.Errorf("getVpcsByRegion for region %s failed %s", localRegion.Name, err)
+		return
+	}
+	for i := range vpcs {
+		if len(vpcs[i].ExternalId) == 0 || utils.IsInStringArray(vpcs[i].ExternalId, syncedVpcIds) {
+			continue
+		}
+		remoteVpc, err := remoteRegion.GetIVpcById(vpcs[i].ExternalId)
+		if err != nil {
+			log.Errorf("GetIVpcById %s failed %s", vpcs[i].ExternalId, err)
+			continue
+		}
+		func() {
+			lockman.LockObject(ctx, &vpcs[i])
+			defer lockman.ReleaseObject(ctx, &vpcs[i])
+
+			syncVpcSecGroup(ctx, userCred, syncResults, provider, &vpcs[i], remoteVpc, &SSyncRange{})
+		}()
+	}
+}
+
+// findEventDisk looks for the remote disk of extId on the storages of the
+// region, the storage of the local disk is tried first
+func findEventDisk(provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, disk *SDisk, extId string) (*SStorage, cloudprovider.ICloudDisk, error) {
+	zones := ZoneManager.Query("id").Equals("cloudregion_id", localRegion.Id).SubQuery()
+	q := StorageManager.Query().Equals("manager_id", provider.Id).In("zone_id", zones)
+	storages := make([]SStorage, 0)
+	err := db.FetchModelObjects(StorageManager, q, &storages)
+	if err != nil {
+		return nil, nil, err
+	}
+	if disk != nil {
+		for i := range storages {
+			if storages[i].Id == disk.StorageId && i > 0 {
+				storages[0], storages[i] = storages[i], storages[0]
+				break
+			}
+		}
+	}
+	for i := range storages {
+		if len(storages[i].ExternalId) == 0 {
+			continue
+		}
+		istorage, err := remoteRegion.GetIStorageById(storages[i].ExternalId)
+		if err == cloudprovider.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		idisk, err := istorage.GetIDiskById(extId)
+		if err == nil {
+			return &storages[i], idisk, nil
+		}
+		if err != cloudprovider.ErrNotFound {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, cloudprovider.ErrNotFound
+}
+
+func syncEventDisk(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, driver cloudprovider.ICloudProvider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, extId string) compare.SyncResult {
+	syncResult := compare.SyncResult{}
+
+	lockman.LockClass(ctx, DiskManager, provider.ProjectId)
+	defer lockman.ReleaseClass(ctx, DiskManager, provider.ProjectId)
+
+	zones := ZoneManager.Query("id").Equals("cloudregion_id", localRegion.Id).SubQuery()
+	storages := StorageManager.Query("id").Equals("manager_id", provider.Id).In("zone_id", zones).SubQuery()
+	q := DiskManager.Query().Equals("external_id", extId).In("storage_id", storages)
+	disk := &SDisk{}
+	disk.SetModelManager(DiskManager)
+	err := q.First(disk)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			syncResult.Error(err)
+			return syncResult
+		}
+		disk = nil
+	}
+	if disk != nil && taskman.TaskManager.IsInTask(disk) {
+		syncResult.Error(fmt.Errorf("disk %s(%s) in task", disk.Name, disk.Id))
+		return syncResult
+	}
+
+	storage, remoteDisk, err := findEventDisk(provider, localRegion, remoteRegion, disk, extId)
+	if err == cloudprovider.ErrNotFound {
+		if disk != nil {
+			err = disk.syncRemoveCloudDisk(ctx, userCred)
+			if err != nil {
+				syncResult.DeleteError(err)
+			} else {
+				syncResult.Delete()
+			}
+		}
+		return syncResult
+	}
+	if err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	if disk != nil {
+		err = disk.syncWithCloudDisk(ctx, userCred, driver, remoteDisk, -1, provider.ProjectId)
+		if err != nil {
+			syncResult.UpdateError(err)
+			return syncResult
+		}
+		syncMetadata(ctx, userCred, disk, remoteDisk)
+		syncResult.Update()
+	} else {
+		disk, err = DiskManager.newFromCloudDisk(ctx, userCred, driver, remoteDisk, storage, -1, provider.ProjectId)
+		if err != nil {
+			syncResult.AddError(err)
+			return syncResult
+		}
+		syncMetadata(ctx, userCred, disk, remoteDisk)
+		syncResult.Add()
+	}
+	return syncResult
+}
+
+func (manager *SHostManager) fetchRegionHostByExternalId(provider *SCloudprovider, localRegion *SCloudregion, extId string) (*SHost, error) {
+	zones := ZoneManager.Query("id").Equals("cloudregion_id", localRegion.Id).SubQuery()
+	q := manager.Query().Equals("manager_id", provider.Id).Equals("external_id", extId).In("zone_id", zones)
+	host := &SHost{}
+	host.SetModelManager(manager)
+	err := q.First(host)
+	if err != nil {
+		return nil, err
+	}
+	return host, nil
+}
+
+func (manager *SGuestManager) fetchRegionGuestByExternalId(provider *SCloudprovider, localRegion *SCloudregion, extId string) (*SGuest, error) {
+	zones := ZoneManager.Query("id").Equals("cloudregion_id", localRegion.Id).SubQuery()
+	hosts := HostManager.Query("id").Equals("manager_id", provider.Id).In("zone_id", zones).SubQuery()
+	q := manager.Query().Equals("external_id", extId).In("host_id", hosts)
+	guest := &SGuest{}
+	guest.SetModelManager(manager)
+	err := q.First(guest)
+	if err != nil {
+		return nil, err
+	}
+	return guest, nil
+}
+
+// findEventVM looks for the remote VM of extId on the hosts of the region,
+// the host of the local guest is tried first
+func findEventVM(remoteRegion cloudprovider.ICloudRegion, guest *SGuest, extId string) (cloudprovider.ICloudHost, cloudprovider.ICloudVM, error) {
+	ihosts, err := remoteRegion.GetIHosts()
+	if err != nil {
+		return nil, nil, err
+	}
+	if guest != nil {
+		if host := guest.GetHost(); host != nil {
+			for i := range ihosts {
+				if ihosts[i].GetGlobalId() == host.ExternalId && i > 0 {
+					ihosts[0], ihosts[i] = ihosts[i], ihosts[0]
+					break
+				}
+			}
+		}
+	}
+	for i := range ihosts {
+		ivm, err := ihosts[i].GetIVMById(extId)
+		if err == nil {
+			return ihosts[i], ivm, nil
+		}
+		if err != cloudprovider.ErrNotFound {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, cloudprovider.ErrNotFound
+}
+
+// syncEventVM syncs the single VM of extId reported by the event source
+func syncEventVM(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	provider *SCloudprovider,
+	driver cloudprovider.ICloudProvider,
+	localRegion *SCloudregion,
+	remoteRegion cloudprovider.ICloudRegion,
+	extId string,
+) compare.SyncResult {
+	syncResult := compare.SyncResult{}
+
+	lockman.LockClass(ctx, GuestManager, provider.ProjectId)
+	defer lockman.ReleaseClass(ctx, GuestManager, provider.ProjectId)
+
+	guest, err := GuestManager.fetchRegionGuestByExternalId(provider, localRegion, extId)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			syncResult.Error(err)
+			return syncResult
+		}
+		guest = nil
+	}
+	if guest != nil && taskman.TaskManager.IsInTask(guest) {
+		syncResult.Error(fmt.Errorf("server %s(%s)in task", guest.Name, guest.Id))
+		return syncResult
+	}
+
+	remoteHost, remoteVM, err := findEventVM(remoteRegion, guest, extId)
+	if err == cloudprovider.ErrNotFound {
+		if guest != nil {
+			err = guest.syncRemoveCloudVM(ctx, userCred)
+			if err != nil {
+				syncResult.DeleteError(err)
+			} else {
+				syncResult.Delete()
+			}
+		}
+		return syncResult
+	}
+	if err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	host, err := HostManager.fetchRegionHostByExternalId(provider, localRegion, remoteHost.GetGlobalId())
+	if err != nil {
+		// hosts are not created by events, wait for the full sync
+		syncResult.Error(fmt.Errorf("host %s of server %s not synced: %s", remoteHost.GetGlobalId(), extId, err))
+		return syncResult
+	}
+
+	if guest != nil {
+		err = guest.syncWithCloudVM(ctx, userCred, driver, host, remoteVM, provider.ProjectId)
+		if err != nil {
+			syncResult.UpdateError(err)
+			return syncResult
+		}
+		syncResult.Update()
+	} else {
+		if remoteVM.GetBillingType() == BILLING_TYPE_PREPAID {
+			vhost := HostManager.GetHostByRealExternalId(remoteVM.GetGlobalId())
+			if vhost != nil {
+				// this recycle vm is not build yet, skip synchronize
+				err = vhost.SyncWithRealPrepaidVM(ctx, userCred, remoteVM)
+				if err != nil {
+					syncResult.AddError(err)
+				}
+				return syncResult
+			}
+		}
+		guest, err = GuestManager.newCloudVM(ctx, userCred, driver, host, remoteVM, provider.ProjectId)
+		if err != nil {
+			syncResult.AddError(err)
+			return syncResult
+		}
+		syncResult.Add()
+	}
+
+	lockman.LockObject(ctx, guest)
+	defer lockman.ReleaseObject(ctx, guest)
+
+	syncMetadata(ctx, userCred, guest, remoteVM)
+	syncVMNics(ctx, userCred, provider, host, guest, remoteVM)
+	syncVMDisks(ctx, userCred, provider, driver, host, guest, remoteVM, &SSyncRange{})
+	syncVMEip(ctx, userCred, provider, guest, remoteVM)
+	syncVMSecgroups(ctx, userCred, provider, guest, remoteVM)
+
+	return syncResult
+}
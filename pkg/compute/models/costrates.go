@@ -0,0 +1,214 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SCostRateManager struct {
+	db.SStandaloneResourceBaseManager
+}
+
+var CostRateManager *SCostRateManager
+
+func init() {
+	CostRateManager = &SCostRateManager{
+		SStandaloneResourceBaseManager: db.NewStandaloneResourceBaseManager(
+			SCostRate{},
+			"costrates_tbl",
+			"costrate",
+			"costrates",
+		),
+	}
+}
+
+// SCostRate is the daily price of the on-premise resources, used to
+// account the costs of the resources not billed by any cloud provider
+type SCostRate struct {
+	db.SStandaloneResourceBase
+
+	// one of server, cpu, memory and disk
+	ResourceType string `width:"16" charset:"ascii" nullable:"false" list:"admin" create:"admin_required"`
+	// instance type for server, storage type for disk, empty matches all
+	Sku      string  `width:"64" charset:"ascii" nullable:"true" list:"admin" create:"admin_optional"`
+	Price    float64 `nullable:"false" default:"0" list:"admin" create:"admin_required" update:"admin"`
+	Currency string  `width:"8" charset:"ascii" nullable:"false" default:"CNY" list:"admin" create:"admin_optional" update:"admin"`
+}
+
+func (manager *SCostRateManager) AllowListItems(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowList(userCred, manager)
+}
+
+func (manager *SCostRateManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowCreate(userCred, manager)
+}
+
+func (self *SCostRate) AllowGetDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGet(userCred, self)
+}
+
+func (self *SCostRate) AllowUpdateItem(ctx context.Context, userCred mcclient.TokenCredential) bool {
+	return db.IsAdminAllowUpdate(userCred, self)
+}
+
+func (self *SCostRate) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowDelete(userCred, self)
+}
+
+func validateCostRatePrice(data *jsonutils.JSONDict) error {
+	if !data.Contains("price") {
+		return nil
+	}
+	price, err := data.Float("price")
+	if err != nil {
+		return httperrors.NewInputParameterError("invalid price: %s", err)
+	}
+	if price < 0 {
+		return httperrors.NewInputParameterError("price should not be negative")
+	}
+	return nil
+}
+
+func (manager *SCostRateManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	resourceTypeV := validators.NewStringChoicesValidator("resource_type", api.COST_RATE_RESOURCE_TYPES)
+	if err := resourceTypeV.Validate(data); err != nil {
+		return nil, err
+	}
+	if !data.Contains("price") {
+		return nil, httperrors.NewMissingParameterError("price")
+	}
+	if err := validateCostRatePrice(data); err != nil {
+		return nil, err
+	}
+	sku, _ := data.GetString("sku")
+	switch resourceTypeV.Value {
+	case api.COST_RATE_RESOURCE_CPU, api.COST_RATE_RESOURCE_MEMORY:
+		if len(sku) > 0 {
+			return nil, httperrors.NewInputParameterError("sku is not applicable to %s rate", resourceTypeV.Value)
+		}
+	}
+	if manager.Query().Equals("resource_type", resourceTypeV.Value).Equals("sku", sku).Count() > 0 {
+		return nil, httperrors.NewDuplicateResourceError("%s rate of sku %q already exists", resourceTypeV.Value, sku)
+	}
+	return manager.SStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerProjId, query, data)
+}
+
+func (self *SCostRate) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	if err := validateCostRatePrice(data); err != nil {
+		return nil, err
+	}
+	return self.SStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, data)
+}
+
+func (manager *SCostRateManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	if resourceType, _ := query.GetString("resource_type"); len(resourceType) > 0 {
+		q = q.Equals("resource_type", resourceType)
+	}
+	return q, nil
+}
+
+// sCostRates is the snapshot of all the rates, the rates of the sku take
+// precedence over the default rates of the resource type
+type sCostRates struct {
+	rates map[string]map[string]SCostRate
+}
+
+func (manager *SCostRateManager) fetchRates() (*sCostRates, error) {
+	rates := make([]SCostRate, 0)
+	err := db.FetchModelObjects(manager, manager.Query(), &rates)
+	if err != nil {
+		log.Errorf("fetch cost rates fail %s", err)
+		return nil, err
+	}
+	ret := &sCostRates{rates: make(map[string]map[string]SCostRate)}
+	for i := range rates {
+		if _, ok := ret.rates[rates[i].ResourceType]; !ok {
+			ret.rates[rates[i].ResourceType] = make(map[string]SCostRate)
+		}
+		ret.rates[rates[i].ResourceType][rates[i].Sku] = rates[i]
+	}
+	return ret, nil
+}
+
+func (self *sCostRates) isEmpty() bool {
+	return len(self.rates) == 0
+}
+
+func (self *sCostRates) get(resourceType string, sku string) (*SCostRate, bool) {
+	rates, ok := self.rates[resourceType]
+	if !ok {
+		return nil, false
+	}
+	if rate, ok := rates[sku]; ok && len(sku) > 0 {
+		return &rate, true
+	}
+	if rate, ok := rates[""]; ok {
+		return &rate, true
+	}
+	return nil, false
+}
+
+// guestCost returns the daily cost of the guest, the server rate of its
+// instance type is used if exists, otherwise the cpu and memory rates
+func (self *sCostRates) guestCost(guest *SGuest) (float64, string, bool) {
+	if len(guest.InstanceType) > 0 {
+		if rate, ok := self.rates[api.COST_RATE_RESOURCE_SERVER][guest.InstanceType]; ok {
+			return rate.Price, rate.Currency, true
+		}
+	}
+	amount := 0.0
+	currency := ""
+	found := false
+	if rate, ok := self.get(api.COST_RATE_RESOURCE_CPU, ""); ok {
+		amount += rate.Price * float64(guest.VcpuCount)
+		currency = rate.Currency
+		found = true
+	}
+	if rate, ok := self.get(api.COST_RATE_RESOURCE_MEMORY, ""); ok {
+		amount += rate.Price * float64(guest.VmemSize) / 1024
+		currency = rate.Currency
+		found = true
+	}
+	if !found {
+		if rate, ok := self.get(api.COST_RATE_RESOURCE_SERVER, ""); ok {
+			return rate.Price, rate.Currency, true
+		}
+	}
+	return amount, currency, found
+}
+
+// diskCost returns the daily cost of the disk by the rate of its storage type
+func (self *sCostRates) diskCost(disk *SDisk, storageType string) (float64, string, bool) {
+	rate, ok := self.get(api.COST_RATE_RESOURCE_DISK, storageType)
+	if !ok {
+		return 0, "", false
+	}
+	return rate.Price * float64(disk.DiskSize) / 1024, rate.Currency, true
+}
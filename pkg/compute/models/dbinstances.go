@@ -0,0 +1,514 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SDBInstanceManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var DBInstanceManager *SDBInstanceManager
+
+func init() {
+	DBInstanceManager = &SDBInstanceManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SDBInstance{},
+			"dbinstances_tbl",
+			"dbinstance",
+			"dbinstances",
+		),
+	}
+}
+
+type SDBInstance struct {
+	db.SVirtualResourceBase
+	SManagedResourceBase
+	SBillingResourceBase
+
+	CloudregionId string `width:"36" charset:"ascii" nullable:"false" list:"user"`
+	ZoneId        string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+	VpcId         string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+
+	Engine        string `width:"16" charset:"ascii" nullable:"true" list:"user"`
+	EngineVersion string `width:"32" charset:"ascii" nullable:"true" list:"user"`
+	InstanceType  string `width:"64" charset:"ascii" nullable:"true" list:"user"`
+
+	VcpuCount     int    `nullable:"false" default:"0" list:"user"`
+	VmemSizeMb    int    `nullable:"false" default:"0" list:"user"`
+	StorageType   string `width:"32" charset:"ascii" nullable:"true" list:"user"`
+	StorageSizeGb int    `nullable:"false" default:"0" list:"user"`
+
+	// address of the intranet endpoint, the one used by the guests in the vpc
+	InternalConnectionStr string `width:"256" charset:"ascii" nullable:"true" list:"user"`
+	ConnectionStr         string `width:"256" charset:"ascii" nullable:"true" list:"user"`
+	Port                  int    `nullable:"false" default:"0" list:"user"`
+}
+
+func (manager *SDBInstanceManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = managedResourceFilterByAccount(q, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	q = managedResourceFilterByCloudType(q, query, "", nil)
+
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	userProjId := userCred.GetProjectId()
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "cloudregion", ModelKeyword: "cloudregion", ProjectId: userProjId},
+		{Key: "zone", ModelKeyword: "zone", ProjectId: userProjId},
+		{Key: "vpc", ModelKeyword: "vpc", ProjectId: userProjId},
+		{Key: "manager", ModelKeyword: "cloudprovider", ProjectId: userProjId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if engine, _ := query.GetString("engine"); len(engine) > 0 {
+		q = q.Equals("engine", engine)
+	}
+	return q, nil
+}
+
+func (manager *SDBInstanceManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (manager *SDBInstanceManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	return nil, httperrors.NewUnsupportOperationError("dbinstance can only be synchronized from cloud")
+}
+
+func (self *SDBInstance) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (self *SDBInstance) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SDBInstance) AllowPerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "purge")
+}
+
+func (self *SDBInstance) PerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	provider := self.GetCloudprovider()
+	if provider != nil && provider.Enabled {
+		return nil, httperrors.NewInvalidStatusError("Cannot purge dbinstance on enabled cloud provider")
+	}
+	err := self.RealDelete(ctx, userCred)
+	return nil, err
+}
+
+func (self *SDBInstance) GetRegion() *SCloudregion {
+	region, err := CloudregionManager.FetchById(self.CloudregionId)
+	if err != nil {
+		log.Errorf("failed to find region for dbinstance %s", self.Name)
+		return nil
+	}
+	return region.(*SCloudregion)
+}
+
+func (self *SDBInstance) GetZone() *SZone {
+	if len(self.ZoneId) == 0 {
+		return nil
+	}
+	zone, err := ZoneManager.FetchById(self.ZoneId)
+	if err != nil {
+		log.Errorf("failed to find zone for dbinstance %s", self.Name)
+		return nil
+	}
+	return zone.(*SZone)
+}
+
+func (self *SDBInstance) GetIRegion() (cloudprovider.ICloudRegion, error) {
+	provider, err := self.GetDriver()
+	if err != nil {
+		return nil, err
+	}
+	region := self.GetRegion()
+	if region == nil {
+		return nil, fmt.Errorf("fail to find region for dbinstance")
+	}
+	return provider.GetIRegionById(region.GetExternalId())
+}
+
+func (self *SDBInstance) GetIDBInstance() (cloudprovider.ICloudDBInstance, error) {
+	iregion, err := self.GetIRegion()
+	if err != nil {
+		return nil, err
+	}
+	return iregion.GetIDBInstanceById(self.ExternalId)
+}
+
+func (self *SDBInstance) AllowGetDetailsBackups(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowGetSpec(userCred, self, "backups")
+}
+
+// GetDetailsBackups lists the backups of the instance, they are fetched
+// from the cloud on every call and not stored locally
+func (self *SDBInstance) GetDetailsBackups(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	iinstance, err := self.GetIDBInstance()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	backups, err := iinstance.GetBackups()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("backups", jsonutils.Marshal(backups))
+	return ret, nil
+}
+
+func (self *SDBInstance) AllowGetDetailsAccounts(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowGetSpec(userCred, self, "accounts")
+}
+
+func (self *SDBInstance) GetDetailsAccounts(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	iinstance, err := self.GetIDBInstance()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	accounts, err := iinstance.GetAccounts()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("accounts", jsonutils.Marshal(accounts))
+	return ret, nil
+}
+
+func (self *SDBInstance) StartDBInstanceTask(ctx context.Context, userCred mcclient.TokenCredential, taskName string, status string, params *jsonutils.JSONDict, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, taskName, self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	self.SetStatus(userCred, status, "")
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SDBInstance) AllowPerformStart(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "start")
+}
+
+func (self *SDBInstance) PerformStart(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.DBINSTANCE_STATUS_STOPPED, api.DBINSTANCE_STATUS_START_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("cannot start dbinstance in status %s", self.Status)
+	}
+	return nil, self.StartDBInstanceTask(ctx, userCred, "DBInstanceStartTask", api.DBINSTANCE_STATUS_STARTING, nil, "")
+}
+
+func (self *SDBInstance) AllowPerformStop(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "stop")
+}
+
+func (self *SDBInstance) PerformStop(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.DBINSTANCE_STATUS_RUNNING, api.DBINSTANCE_STATUS_STOP_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("cannot stop dbinstance in status %s", self.Status)
+	}
+	return nil, self.StartDBInstanceTask(ctx, userCred, "DBInstanceStopTask", api.DBINSTANCE_STATUS_STOPPING, nil, "")
+}
+
+func (self *SDBInstance) AllowPerformReboot(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "reboot")
+}
+
+func (self *SDBInstance) PerformReboot(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.DBINSTANCE_STATUS_RUNNING, api.DBINSTANCE_STATUS_REBOOT_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("cannot reboot dbinstance in status %s", self.Status)
+	}
+	return nil, self.StartDBInstanceTask(ctx, userCred, "DBInstanceRebootTask", api.DBINSTANCE_STATUS_REBOOTING, nil, "")
+}
+
+func (self *SDBInstance) AllowPerformChangeConfig(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "change-config")
+}
+
+// validateChangeConfig returns the changes requested by data, none of the
+// providers can shrink the storage
+func (self *SDBInstance) validateChangeConfig(data jsonutils.JSONObject) (*cloudprovider.SDBInstanceChangeConfig, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.DBINSTANCE_STATUS_RUNNING, api.DBINSTANCE_STATUS_CHANGE_CONFIG_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("cannot change config of dbinstance in status %s", self.Status)
+	}
+	config := &cloudprovider.SDBInstanceChangeConfig{}
+	config.InstanceType, _ = data.GetString("instance_type")
+	if config.InstanceType == self.InstanceType {
+		config.InstanceType = ""
+	}
+	storageSize, _ := data.Int("storage_size_gb")
+	if storageSize > 0 {
+		if int(storageSize) < self.StorageSizeGb {
+			return nil, httperrors.NewInputParameterError("cannot shrink storage from %dGB to %dGB", self.StorageSizeGb, storageSize)
+		}
+		if int(storageSize) > self.StorageSizeGb {
+			config.StorageSizeGB = int(storageSize)
+		}
+	}
+	if len(config.InstanceType) == 0 && config.StorageSizeGB == 0 {
+		return nil, httperrors.NewInputParameterError("nothing to change")
+	}
+	return config, nil
+}
+
+// PerformChangeConfig changes the instance type and/or enlarges the
+// storage of the instance
+func (self *SDBInstance) PerformChangeConfig(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	config, err := self.validateChangeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	params := jsonutils.Marshal(config).(*jsonutils.JSONDict)
+	return nil, self.StartDBInstanceTask(ctx, userCred, "DBInstanceChangeConfigTask", api.DBINSTANCE_STATUS_CHANGE_CONFIG, params, "")
+}
+
+func (self *SDBInstance) getCloudProviderInfo() SCloudProviderInfo {
+	region := self.GetRegion()
+	zone := self.GetZone()
+	provider := self.GetCloudprovider()
+	return MakeCloudProviderInfo(region, zone, provider)
+}
+
+func (self *SDBInstance) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SVirtualResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	info := self.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra
+}
+
+func (self *SDBInstance) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*jsonutils.JSONDict, error) {
+	extra, err := self.SVirtualResourceBase.GetExtraDetails(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	info := self.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra, nil
+}
+
+func (self *SDBInstance) GetShortDesc(ctx context.Context) *jsonutils.JSONDict {
+	desc := self.SVirtualResourceBase.GetShortDesc(ctx)
+	desc.Add(jsonutils.NewString(self.Engine), "engine")
+	desc.Add(jsonutils.NewString(self.EngineVersion), "engine_version")
+	desc.Add(jsonutils.NewString(self.InstanceType), "instance_type")
+	info := self.getCloudProviderInfo()
+	desc.Update(jsonutils.Marshal(&info))
+	return desc
+}
+
+func (manager *SDBInstanceManager) SyncDBInstances(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, region *SCloudregion, instances []cloudprovider.ICloudDBInstance) compare.SyncResult {
+	lockman.LockClass(ctx, manager, manager.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, manager, manager.GetOwnerId(userCred))
+
+	syncResult := compare.SyncResult{}
+
+	dbInstances := make([]SDBInstance, 0)
+	q := manager.Query().Equals("cloudregion_id", region.Id).Equals("manager_id", provider.Id)
+	if err := db.FetchModelObjects(manager, q, &dbInstances); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	removed := make([]SDBInstance, 0)
+	commondb := make([]SDBInstance, 0)
+	commonext := make([]cloudprovider.ICloudDBInstance, 0)
+	added := make([]cloudprovider.ICloudDBInstance, 0)
+	if err := compare.CompareSets(dbInstances, instances, &removed, &commondb, &commonext, &added); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err := removed[i].syncRemoveCloudDBInstance(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	}
+
+	for i := 0; i < len(commondb); i += 1 {
+		err := commondb[i].SyncWithCloudDBInstance(ctx, userCred, provider, commonext[i])
+		if err != nil {
+			syncResult.UpdateError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, &commondb[i], commonext[i])
+		syncResult.Update()
+	}
+
+	for i := 0; i < len(added); i += 1 {
+		instance, err := manager.newFromCloudDBInstance(ctx, userCred, provider, region, added[i])
+		if err != nil {
+			syncResult.AddError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, instance, added[i])
+		syncResult.Add()
+	}
+	return syncResult
+}
+
+func (self *SDBInstance) syncRemoveCloudDBInstance(ctx context.Context, userCred mcclient.TokenCredential) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	err := self.SVirtualResourceBase.ValidateDeleteCondition(ctx)
+	if err != nil {
+		self.SetStatus(userCred, api.DBINSTANCE_STATUS_UNKNOWN, "sync to delete")
+		return err
+	}
+	return self.RealDelete(ctx, userCred)
+}
+
+// setCloudDBInstanceAttrs copies the spec, placement and endpoints of the
+// cloud instance, zone and vpc are left empty if they are not synced yet
+func (self *SDBInstance) setCloudDBInstanceAttrs(provider *SCloudprovider, region *SCloudregion, extInstance cloudprovider.ICloudDBInstance) {
+	self.Status = extInstance.GetStatus()
+	self.Engine = extInstance.GetEngine()
+	self.EngineVersion = extInstance.GetEngineVersion()
+	self.InstanceType = extInstance.GetInstanceType()
+	self.VcpuCount = extInstance.GetVcpuCount()
+	self.VmemSizeMb = extInstance.GetVmemSizeMB()
+	self.StorageType = extInstance.GetStorageType()
+	self.StorageSizeGb = extInstance.GetStorageSizeGB()
+
+	if zoneId := extInstance.GetZoneId(); len(zoneId) > 0 {
+		zone, err := ZoneManager.FetchByExternalId(fmt.Sprintf("%s/%s", region.ExternalId, zoneId))
+		if err == nil {
+			self.ZoneId = zone.GetId()
+		} else {
+			log.Warningf("find zone %s for dbinstance %s fail %s", zoneId, extInstance.GetName(), err)
+		}
+	}
+	if vpcId := extInstance.GetVpcId(); len(vpcId) > 0 {
+		vpc, err := VpcManager.FetchByExternalId(vpcId)
+		if err == nil {
+			self.VpcId = vpc.GetId()
+		} else {
+			log.Warningf("find vpc %s for dbinstance %s fail %s", vpcId, extInstance.GetName(), err)
+		}
+	}
+
+	self.InternalConnectionStr = ""
+	self.ConnectionStr = ""
+	for _, endpoint := range extInstance.GetEndpoints() {
+		switch endpoint.Type {
+		case cloudprovider.DBINSTANCE_ENDPOINT_INTRANET:
+			if len(self.InternalConnectionStr) == 0 {
+				self.InternalConnectionStr = endpoint.Address
+				self.Port = endpoint.Port
+			}
+		case cloudprovider.DBINSTANCE_ENDPOINT_INTERNET:
+			if len(self.ConnectionStr) == 0 {
+				self.ConnectionStr = endpoint.Address
+				if self.Port == 0 {
+					self.Port = endpoint.Port
+				}
+			}
+		}
+	}
+
+	if factory, err := provider.GetProviderFactory(); err == nil && factory.IsSupportPrepaidResources() {
+		self.BillingType = extInstance.GetBillingType()
+		self.ExpiredAt = extInstance.GetExpiredAt()
+	}
+}
+
+func (self *SDBInstance) SyncWithCloudDBInstance(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, extInstance cloudprovider.ICloudDBInstance) error {
+	region := self.GetRegion()
+	if region == nil {
+		return fmt.Errorf("fail to find region for dbinstance %s", self.Name)
+	}
+	diff, err := db.UpdateWithLock(ctx, self, func() error {
+		self.setCloudDBInstanceAttrs(provider, region, extInstance)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogSyncUpdate(self, diff, userCred)
+
+	SyncCloudProject(userCred, self, provider.ProjectId, extInstance, provider.Id)
+	return nil
+}
+
+func (manager *SDBInstanceManager) newFromCloudDBInstance(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, region *SCloudregion, extInstance cloudprovider.ICloudDBInstance) (*SDBInstance, error) {
+	instance := SDBInstance{}
+	instance.SetModelManager(manager)
+
+	instance.Name = db.GenerateName(manager, provider.ProjectId, extInstance.GetName())
+	instance.ExternalId = extInstance.GetGlobalId()
+	instance.CloudregionId = region.Id
+	instance.ManagerId = provider.Id
+	instance.setCloudDBInstanceAttrs(provider, region, extInstance)
+
+	err := manager.TableSpec().Insert(&instance)
+	if err != nil {
+		log.Errorf("newFromCloudDBInstance fail %s", err)
+		return nil, err
+	}
+
+	SyncCloudProject(userCred, &instance, provider.ProjectId, extInstance, provider.Id)
+
+	db.OpsLog.LogEvent(&instance, db.ACT_CREATE, instance.GetShortDesc(ctx), userCred)
+	return &instance, nil
+}
+
+func (manager *SDBInstanceManager) purgeAll(ctx context.Context, userCred mcclient.TokenCredential, providerId string) error {
+	instances := make([]SDBInstance, 0)
+	err := fetchByManagerId(manager, providerId, &instances)
+	if err != nil {
+		return err
+	}
+	for i := range instances {
+		err := instances[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return fmt.Errorf("purge dbinstance %s fail %s", instances[i].Id, err)
+		}
+	}
+	return nil
+}
+
+// dbinstanceStatusWaitTimeout bounds the wait of a lifecycle action, a
+// change of spec can take tens of minutes on all the providers
+const dbinstanceStatusWaitTimeout = 60 * time.Minute
+
+func (self *SDBInstance) WaitCloudStatus(iinstance cloudprovider.ICloudDBInstance, status string) error {
+	return cloudprovider.WaitStatus(iinstance, status, 15*time.Second, dbinstanceStatusWaitTimeout)
+}
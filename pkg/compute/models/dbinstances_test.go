@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+func TestDBInstanceValidateChangeConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		status string
+		in     string
+		out    cloudprovider.SDBInstanceChangeConfig
+		isErr  bool
+	}{
+		{
+			name:   "instance type",
+			status: api.DBINSTANCE_STATUS_RUNNING,
+			in:     `{"instance_type": "rds.mysql.s2.large"}`,
+			out:    cloudprovider.SDBInstanceChangeConfig{InstanceType: "rds.mysql.s2.large"},
+		},
+		{
+			name:   "enlarge storage",
+			status: api.DBINSTANCE_STATUS_CHANGE_CONFIG_FAILED,
+			in:     `{"instance_type": "rds.mysql.s1.small", "storage_size_gb": 40}`,
+			out:    cloudprovider.SDBInstanceChangeConfig{StorageSizeGB: 40},
+		},
+		{
+			name:   "shrink storage",
+			status: api.DBINSTANCE_STATUS_RUNNING,
+			in:     `{"storage_size_gb": 10}`,
+			isErr:  true,
+		},
+		{
+			name:   "nothing to change",
+			status: api.DBINSTANCE_STATUS_RUNNING,
+			in:     `{"instance_type": "rds.mysql.s1.small", "storage_size_gb": 20}`,
+			isErr:  true,
+		},
+		{
+			name:   "invalid status",
+			status: api.DBINSTANCE_STATUS_CHANGE_CONFIG,
+			in:     `{"storage_size_gb": 40}`,
+			isErr:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			instance := &SDBInstance{InstanceType: "rds.mysql.s1.small", StorageSizeGb: 20}
+			instance.Status = c.status
+			data, err := jsonutils.ParseString(c.in)
+			if err != nil {
+				t.Fatalf("invalid json string: %s\n%s", err, c.in)
+			}
+			config, err := instance.validateChangeConfig(data)
+			if c.isErr {
+				if err == nil {
+					t.Fatalf("expect error, got %#v", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *config != c.out {
+				t.Errorf("want %#v, got %#v", c.out, *config)
+			}
+			// the task reads the config back from its params
+			params := jsonutils.Marshal(config)
+			back := cloudprovider.SDBInstanceChangeConfig{}
+			err = params.Unmarshal(&back)
+			if err != nil {
+				t.Fatalf("unmarshal params %s: %v", params, err)
+			}
+			if back != c.out {
+				t.Errorf("params %s unmarshal to %#v", params, back)
+			}
+		})
+	}
+}
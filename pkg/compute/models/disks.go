@@ -580,6 +580,25 @@ func (self *SDisk) StartAllocate(ctx context.Context, host *SHost, storage *SSto
 		self.SetMetadata(ctx, "merge_snapshot", jsonutils.JSONTrue, userCred)
 	} else if len(templateId) > 0 {
 		content.Add(jsonutils.NewString(templateId), "image_id")
+	} else {
+		source, err := self.getV2VImportSource()
+		if err != nil {
+			return err
+		}
+		if source != nil {
+			exporter, err := source.getExporter()
+			if err != nil {
+				return err
+			}
+			importInfo, err := exporter.GetExportInfo(ctx)
+			if err != nil {
+				return err
+			}
+			// the files are downloaded with the tickets issued by the
+			// export-ticket of the source disk, no credential is sent
+			content.Add(jsonutils.Marshal(importInfo), "import")
+			content.Add(jsonutils.NewString(source.Id), "import_disk_id")
+		}
 	}
 	if len(fsFormat) > 0 {
 		content.Add(jsonutils.NewString(fsFormat), "fs_format")
@@ -600,6 +619,69 @@ func (self *SDisk) StartAllocate(ctx context.Context, host *SHost, storage *SSto
 	}
 }
 
+// getV2VImportSource returns the disk the content of the disk is imported
+// from if its guest is migrated from another hypervisor, that is the disk at
+// the same index of the source guest
+func (self *SDisk) getV2VImportSource() (*SDisk, error) {
+	guests := self.GetGuests()
+	if len(guests) != 1 {
+		return nil, nil
+	}
+	guest := guests[0]
+	sourceId := guest.GetMetadata(VM_METADATA_V2V_SOURCE, nil)
+	if len(sourceId) == 0 {
+		return nil, nil
+	}
+	source := GuestManager.FetchGuestById(sourceId)
+	if source == nil {
+		return nil, fmt.Errorf("source guest %s not found", sourceId)
+	}
+	guestDisk := guest.GetGuestDisk(self.Id)
+	if guestDisk == nil {
+		return nil, fmt.Errorf("disk %s not attached to guest %s", self.Id, guest.Id)
+	}
+	for _, sourceDisk := range source.GetDisks() {
+		if sourceDisk.Index == guestDisk.Index {
+			return sourceDisk.GetDisk(), nil
+		}
+	}
+	return nil, nil
+}
+
+func (self *SDisk) getExporter() (cloudprovider.ICloudDiskExporter, error) {
+	iDisk, err := self.GetIDisk()
+	if err != nil {
+		return nil, err
+	}
+	exporter, ok := iDisk.(cloudprovider.ICloudDiskExporter)
+	if !ok {
+		return nil, fmt.Errorf("disk %s is not exportable", self.Name)
+	}
+	return exporter, nil
+}
+
+func (self *SDisk) AllowGetDetailsExportTicket(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGetSpec(userCred, self, "export-ticket")
+}
+
+// GetDetailsExportTicket issues a short-lived ticket for the host importing
+// the disk to download one of its export urls
+func (self *SDisk) GetDetailsExportTicket(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	url, _ := query.GetString("url")
+	if len(url) == 0 {
+		return nil, httperrors.NewMissingParameterError("url")
+	}
+	exporter, err := self.getExporter()
+	if err != nil {
+		return nil, httperrors.NewUnsupportOperationError("%s", err)
+	}
+	ticket, err := exporter.GetExportTicket(ctx, url)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return jsonutils.Marshal(ticket), nil
+}
+
 func (self *SDisk) AllowGetDetailsConvertSnapshot(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
 	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "convert-snapshot")
 }
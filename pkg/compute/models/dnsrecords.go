@@ -16,16 +16,24 @@ package models
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
 	"yunion.io/x/pkg/util/regutils"
+	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/logclient"
@@ -54,6 +62,10 @@ type SDnsRecord struct {
 	db.SAdminSharableVirtualResourceBase
 	Ttl     int  `nullable:"true" default:"1" create:"optional" list:"user" update:"user"`
 	Enabled bool `nullable:"false" default:"true" create:"optional" list:"user"`
+
+	// DnsZoneId is set for the records of a cloud dns zone, they are pushed
+	// to the provider and not answered by the region dns
+	DnsZoneId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
 }
 
 // GetRecordsSeparator implements IAdminSharableVirtualModelManager
@@ -326,13 +338,49 @@ func (man *SDnsRecordManager) ValidateCreateData(
 	if err != nil {
 		return nil, err
 	}
+	zoneStr := jsonutils.GetAnyString(data, []string{"dns_zone", "dns_zone_id"})
+	if len(zoneStr) > 0 {
+		zoneObj, err := DnsZoneManager.FetchByIdOrName(userCred, zoneStr)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, httperrors.NewResourceNotFoundError2(DnsZoneManager.Keyword(), zoneStr)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		zone := zoneObj.(*SDnsZone)
+		name, _ := data.GetString("name")
+		if name != zone.Name && !strings.HasSuffix(name, "."+zone.Name) {
+			return nil, httperrors.NewInputParameterError("record %s is not in dns zone %s", name, zone.Name)
+		}
+		cnt := man.Query().Equals("dns_zone_id", zone.Id).Equals("name", name).Count()
+		if cnt > 0 {
+			return nil, httperrors.NewDuplicateResourceError("record %s already exists in dns zone %s", name, zone.Name)
+		}
+		data.Set("dns_zone_id", jsonutils.NewString(zone.Id))
+	}
 	return man.SAdminSharableVirtualResourceBaseManager.ValidateCreateData(man, data)
 }
 
+func (man *SDnsRecordManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	q, err := man.SAdminSharableVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "dns_zone", ModelKeyword: "dnszone", ProjectId: userCred.GetProjectId()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
 func (man *SDnsRecordManager) QueryDns(projectId, name string) *SDnsRecord {
 	q := man.Query().
 		Equals("name", name).
-		IsTrue("enabled")
+		IsTrue("enabled").
+		IsNullOrEmpty("dns_zone_id")
 	if len(projectId) == 0 {
 		q = q.IsTrue("is_public")
 	} else {
@@ -378,6 +426,9 @@ func (rec *SDnsRecord) GetInfo() []string {
 }
 
 func (rec *SDnsRecord) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	if name, _ := data.GetString("name"); len(rec.DnsZoneId) > 0 && len(name) > 0 && name != rec.Name {
+		return nil, httperrors.NewUnsupportOperationError("cannot rename the record of a dns zone")
+	}
 	data.UpdateDefault(jsonutils.Marshal(rec))
 	data, err := DnsRecordManager.validateModelData(ctx, userCred, rec.GetOwnerProjectId(), query, data)
 	if err != nil {
@@ -413,7 +464,10 @@ func (rec *SDnsRecord) PerformAddRecords(ctx context.Context, userCred mcclient.
 		return nil, httperrors.NewNotAcceptableError("Cannot mix different types of records, %s != %s", oldType, newType)
 	}
 	err = rec.AddInfo(ctx, userCred, data)
-	return nil, err
+	if err != nil {
+		return nil, err
+	}
+	return nil, rec.startSyncIfInZone(ctx, userCred)
 }
 
 func (rec *SDnsRecord) AllowPerformRemoveRecords(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
@@ -422,7 +476,10 @@ func (rec *SDnsRecord) AllowPerformRemoveRecords(ctx context.Context, userCred m
 
 func (rec *SDnsRecord) PerformRemoveRecords(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	err := rec.SAdminSharableVirtualResourceBase.RemoveInfo(ctx, userCred, DnsRecordManager, rec, data, false)
-	return nil, err
+	if err != nil {
+		return nil, err
+	}
+	return nil, rec.startSyncIfInZone(ctx, userCred)
 }
 
 func (rec *SDnsRecord) AllowPerformEnable(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
@@ -441,6 +498,7 @@ func (rec *SDnsRecord) PerformEnable(ctx context.Context, userCred mcclient.Toke
 		}
 		db.OpsLog.LogEvent(rec, db.ACT_ENABLE, diff, userCred)
 		logclient.AddActionLogWithContext(ctx, rec, logclient.ACT_ENABLE, diff, userCred, true)
+		return nil, rec.startSyncIfInZone(ctx, userCred)
 	}
 	return nil, nil
 }
@@ -461,6 +519,276 @@ func (rec *SDnsRecord) PerformDisable(ctx context.Context, userCred mcclient.Tok
 		}
 		db.OpsLog.LogEvent(rec, db.ACT_DISABLE, diff, userCred)
 		logclient.AddActionLogWithContext(ctx, rec, logclient.ACT_DISABLE, diff, userCred, true)
+		return nil, rec.startSyncIfInZone(ctx, userCred)
 	}
 	return nil, nil
 }
+
+func (rec *SDnsRecord) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	rec.SAdminSharableVirtualResourceBase.PostCreate(ctx, userCred, ownerProjId, query, data)
+	err := rec.startSyncIfInZone(ctx, userCred)
+	if err != nil {
+		log.Errorf("start sync of dnsrecord %s fail %s", rec.Name, err)
+	}
+}
+
+func (rec *SDnsRecord) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	rec.SAdminSharableVirtualResourceBase.PostUpdate(ctx, userCred, query, data)
+	err := rec.startSyncIfInZone(ctx, userCred)
+	if err != nil {
+		log.Errorf("start sync of dnsrecord %s fail %s", rec.Name, err)
+	}
+}
+
+func (rec *SDnsRecord) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if len(rec.DnsZoneId) == 0 {
+		return rec.SAdminSharableVirtualResourceBase.CustomizeDelete(ctx, userCred, query, data)
+	}
+	return rec.StartDnsRecordTask(ctx, userCred, "DnsRecordDeleteTask", api.DNS_RECORD_STATUS_DELETING, "")
+}
+
+// Delete of the records of a dns zone is done by DnsRecordDeleteTask once
+// the record is removed from the cloud
+func (rec *SDnsRecord) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if len(rec.DnsZoneId) > 0 {
+		return nil
+	}
+	return rec.RealDelete(ctx, userCred)
+}
+
+func (rec *SDnsRecord) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return rec.SAdminSharableVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (rec *SDnsRecord) GetDnsZone() *SDnsZone {
+	if len(rec.DnsZoneId) == 0 {
+		return nil
+	}
+	zone, err := DnsZoneManager.FetchById(rec.DnsZoneId)
+	if err != nil {
+		log.Errorf("failed to find dns zone for dnsrecord %s", rec.Name)
+		return nil
+	}
+	return zone.(*SDnsZone)
+}
+
+func (rec *SDnsRecord) StartDnsRecordTask(ctx context.Context, userCred mcclient.TokenCredential, taskName string, status string, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, taskName, rec, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	rec.SetStatus(userCred, status, "")
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (rec *SDnsRecord) startSyncIfInZone(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if len(rec.DnsZoneId) == 0 {
+		return nil
+	}
+	return rec.StartDnsRecordTask(ctx, userCred, "DnsRecordSyncTask", api.DNS_RECORD_STATUS_SYNCING, "")
+}
+
+// dnsRecordToCloud returns the type and the value of the cloud record of an
+// entry of Records, e.g. SRV:host:port:weight:priority is converted to
+// SRV "priority weight port host"
+func dnsRecordToCloud(rec string) (string, string) {
+	parts := strings.SplitN(rec, ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	typ, val := parts[0], parts[1]
+	if typ == "SRV" {
+		srv := strings.Split(val, ":")
+		if len(srv) == 4 {
+			val = strings.Join([]string{srv[3], srv[2], srv[1], srv[0]}, " ")
+		}
+	}
+	return typ, val
+}
+
+// dnsRecordFromCloud returns the entry of Records of a cloud record, false
+// if the type has no local counterpart, e.g. MX and TXT
+func dnsRecordFromCloud(typ, val string) (string, bool) {
+	switch typ {
+	case "A", "AAAA":
+		return typ + ":" + val, true
+	case "CNAME", "PTR":
+		return typ + ":" + strings.TrimSuffix(val, "."), true
+	case "SRV":
+		srv := strings.Fields(val)
+		if len(srv) != 4 {
+			return "", false
+		}
+		return fmt.Sprintf("SRV:%s:%s:%s:%s", strings.TrimSuffix(srv[3], "."), srv[2], srv[1], srv[0]), true
+	}
+	return "", false
+}
+
+func (zone *SDnsZone) recordFqdn(name string) string {
+	if name == "@" || len(name) == 0 {
+		return zone.Name
+	}
+	return name + "." + zone.Name
+}
+
+func (zone *SDnsZone) recordRelativeName(fqdn string) string {
+	if fqdn == zone.Name {
+		return "@"
+	}
+	return strings.TrimSuffix(fqdn, "."+zone.Name)
+}
+
+// RemoteUpdate makes the cloud records of the name of the record the same
+// as its Records, they are all removed if the record is disabled or
+// removed, a ttl not greater than 1 leaves the ttl to the provider
+func (rec *SDnsRecord) RemoteUpdate(zone *SDnsZone, iZone cloudprovider.ICloudDnsZone, remove bool) error {
+	name := zone.recordRelativeName(rec.Name)
+	ttl := 0
+	if rec.Ttl > 1 {
+		ttl = rec.Ttl
+	}
+	desired := []string{}
+	if !remove && rec.Enabled {
+		for _, r := range rec.GetInfo() {
+			if len(r) > 0 {
+				desired = append(desired, r)
+			}
+		}
+	}
+	iRecords, err := iZone.GetIDnsRecords()
+	if err != nil {
+		return err
+	}
+	existing := []string{}
+	for _, iRecord := range iRecords {
+		if !strings.EqualFold(iRecord.GetDnsName(), name) {
+			continue
+		}
+		r, ok := dnsRecordFromCloud(iRecord.GetDnsType(), iRecord.GetDnsValue())
+		if !ok {
+			continue
+		}
+		if utils.IsInStringArray(r, desired) && !utils.IsInStringArray(r, existing) && (ttl == 0 || iRecord.GetTtl() == ttl) {
+			existing = append(existing, r)
+			continue
+		}
+		err := iZone.RemoveDnsRecord(iRecord.GetGlobalId())
+		if err != nil {
+			return fmt.Errorf("remove cloud record %s: %s", iRecord.GetGlobalId(), err)
+		}
+	}
+	for _, r := range desired {
+		if utils.IsInStringArray(r, existing) {
+			continue
+		}
+		typ, val := dnsRecordToCloud(r)
+		_, err := iZone.AddDnsRecord(cloudprovider.SDnsRecord{Name: name, Type: typ, Value: val, Ttl: ttl})
+		if err != nil {
+			return fmt.Errorf("add cloud record %s: %s", r, err)
+		}
+	}
+	return nil
+}
+
+type sDnsRecordSet struct {
+	records []string
+	ttl     int
+}
+
+// SyncDnsRecords syncs the records of a cloud dns zone, the cloud records
+// of the same name are merged into one record, the records being pushed
+// or failed to push are left alone
+func (man *SDnsRecordManager) SyncDnsRecords(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, zone *SDnsZone, iRecords []cloudprovider.ICloudDnsRecord) compare.SyncResult {
+	lockman.LockClass(ctx, man, man.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, man, man.GetOwnerId(userCred))
+
+	syncResult := compare.SyncResult{}
+
+	names := []string{}
+	sets := map[string]*sDnsRecordSet{}
+	for _, iRecord := range iRecords {
+		r, ok := dnsRecordFromCloud(iRecord.GetDnsType(), iRecord.GetDnsValue())
+		if !ok {
+			continue
+		}
+		name := zone.recordFqdn(iRecord.GetDnsName())
+		set, ok := sets[name]
+		if !ok {
+			set = &sDnsRecordSet{ttl: iRecord.GetTtl()}
+			sets[name] = set
+			names = append(names, name)
+		}
+		// records of different kinds cannot share a name locally
+		if len(set.records) > 0 && man.getRecordsType(set.records) != man.getRecordsType([]string{r}) {
+			log.Warningf("skip %s record of %s in dns zone %s", iRecord.GetDnsType(), name, zone.Name)
+			continue
+		}
+		if !utils.IsInStringArray(r, set.records) {
+			set.records = append(set.records, r)
+		}
+	}
+
+	dbRecords, err := zone.GetDnsRecords()
+	if err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+	synced := map[string]bool{}
+	for i := range dbRecords {
+		rec := &dbRecords[i]
+		synced[rec.Name] = true
+		if rec.Status != api.DNS_RECORD_STATUS_AVAILABLE || !rec.Enabled {
+			continue
+		}
+		set, ok := sets[rec.Name]
+		if !ok {
+			err := rec.RealDelete(ctx, userCred)
+			if err != nil {
+				syncResult.DeleteError(err)
+			} else {
+				syncResult.Delete()
+			}
+			continue
+		}
+		diff, err := db.UpdateWithLock(ctx, rec, func() error {
+			rec.Records = strings.Join(set.records, DNS_RECORDS_SEPARATOR)
+			rec.Ttl = set.ttl
+			return nil
+		})
+		if err != nil {
+			syncResult.UpdateError(err)
+			continue
+		}
+		db.OpsLog.LogSyncUpdate(rec, diff, userCred)
+		syncResult.Update()
+	}
+
+	for _, name := range names {
+		if synced[name] {
+			continue
+		}
+		rec := SDnsRecord{}
+		rec.SetModelManager(man)
+		rec.Name = name
+		rec.Records = strings.Join(sets[name].records, DNS_RECORDS_SEPARATOR)
+		rec.Ttl = sets[name].ttl
+		rec.Enabled = true
+		rec.Status = api.DNS_RECORD_STATUS_AVAILABLE
+		rec.DnsZoneId = zone.Id
+		rec.ProjectId = zone.ProjectId
+		err := man.TableSpec().Insert(&rec)
+		if err != nil {
+			syncResult.AddError(err)
+			continue
+		}
+		db.OpsLog.LogEvent(&rec, db.ACT_CREATE, rec.GetShortDesc(ctx), userCred)
+		syncResult.Add()
+	}
+
+	err = zone.updateRecordCount(len(names))
+	if err != nil {
+		log.Errorf("update record count of dns zone %s fail %s", zone.Name, err)
+	}
+	return syncResult
+}
@@ -126,3 +126,34 @@ func TestDnsRecordsParseInputInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestDnsRecordsCloudConversion(t *testing.T) {
+	cases := []struct {
+		rec   string
+		typ   string
+		val   string
+		cloud string
+	}{
+		{rec: "A:1.2.3.4", typ: "A", val: "1.2.3.4"},
+		{rec: "AAAA:::1", typ: "AAAA", val: "::1"},
+		{rec: "CNAME:x.a.com", typ: "CNAME", val: "x.a.com", cloud: "x.a.com."},
+		{rec: "SRV:etcd0.a.com:2379:10:0", typ: "SRV", val: "0 10 2379 etcd0.a.com", cloud: "0 10 2379 etcd0.a.com."},
+	}
+	for _, c := range cases {
+		typ, val := dnsRecordToCloud(c.rec)
+		if typ != c.typ || val != c.val {
+			t.Errorf("%s: want %s %q, got %s %q", c.rec, c.typ, c.val, typ, val)
+		}
+		cloud := c.cloud
+		if len(cloud) == 0 {
+			cloud = c.val
+		}
+		rec, ok := dnsRecordFromCloud(c.typ, cloud)
+		if !ok || rec != c.rec {
+			t.Errorf("%s %q: want %s, got %s", c.typ, cloud, c.rec, rec)
+		}
+	}
+	if _, ok := dnsRecordFromCloud("TXT", "v=spf1 -all"); ok {
+		t.Errorf("TXT record should not be converted")
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SDnsZoneManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var DnsZoneManager *SDnsZoneManager
+
+func init() {
+	DnsZoneManager = &SDnsZoneManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SDnsZone{},
+			"dnszones_tbl",
+			"dnszone",
+			"dnszones",
+		),
+	}
+}
+
+// SDnsZone is a dns zone hosted by a cloud provider, its records are the
+// dnsrecords with the dns_zone_id of the zone
+type SDnsZone struct {
+	db.SVirtualResourceBase
+	SManagedResourceBase
+
+	// public or private
+	ZoneType    string `width:"16" charset:"ascii" nullable:"false" list:"user"`
+	RecordCount int    `nullable:"false" default:"0" list:"user"`
+}
+
+func (manager *SDnsZoneManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = managedResourceFilterByAccount(q, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	q = managedResourceFilterByCloudType(q, query, "", nil)
+
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	userProjId := userCred.GetProjectId()
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "manager", ModelKeyword: "cloudprovider", ProjectId: userProjId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if zoneType, _ := query.GetString("zone_type"); len(zoneType) > 0 {
+		q = q.Equals("zone_type", zoneType)
+	}
+	return q, nil
+}
+
+func (manager *SDnsZoneManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (manager *SDnsZoneManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	return nil, httperrors.NewUnsupportOperationError("dns zone can only be synchronized from cloud")
+}
+
+func (self *SDnsZone) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+// RealDelete removes the zone and its records locally, nothing is removed
+// from the cloud
+func (self *SDnsZone) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	records, err := self.GetDnsRecords()
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		err := records[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return fmt.Errorf("delete dnsrecord %s of zone %s fail %s", records[i].Id, self.Name, err)
+		}
+	}
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SDnsZone) AllowPerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "purge")
+}
+
+func (self *SDnsZone) PerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	provider := self.GetCloudprovider()
+	if provider != nil && provider.Enabled {
+		return nil, httperrors.NewInvalidStatusError("Cannot purge dns zone on enabled cloud provider")
+	}
+	err := self.RealDelete(ctx, userCred)
+	return nil, err
+}
+
+func (self *SDnsZone) GetDnsRecords() ([]SDnsRecord, error) {
+	records := make([]SDnsRecord, 0)
+	q := DnsRecordManager.Query().Equals("dns_zone_id", self.Id)
+	err := db.FetchModelObjects(DnsRecordManager, q, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (self *SDnsZone) GetICloudDnsZone() (cloudprovider.ICloudDnsZone, error) {
+	driver, err := self.GetDriver()
+	if err != nil {
+		return nil, err
+	}
+	dnsProvider, ok := driver.(cloudprovider.ICloudDnsProvider)
+	if !ok {
+		return nil, cloudprovider.ErrNotSupported
+	}
+	zones, err := dnsProvider.GetIDnsZones()
+	if err != nil {
+		return nil, err
+	}
+	for i := range zones {
+		if zones[i].GetGlobalId() == self.ExternalId {
+			return zones[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SDnsZone) getMoreDetails(extra *jsonutils.JSONDict) *jsonutils.JSONDict {
+	info := MakeCloudProviderInfo(nil, nil, self.GetCloudprovider())
+	extra.Update(jsonutils.Marshal(&info))
+	return extra
+}
+
+func (self *SDnsZone) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SVirtualResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	return self.getMoreDetails(extra)
+}
+
+func (self *SDnsZone) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*jsonutils.JSONDict, error) {
+	extra, err := self.SVirtualResourceBase.GetExtraDetails(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	return self.getMoreDetails(extra), nil
+}
+
+func (manager *SDnsZoneManager) SyncDnsZones(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, zones []cloudprovider.ICloudDnsZone) ([]SDnsZone, []cloudprovider.ICloudDnsZone, compare.SyncResult) {
+	lockman.LockClass(ctx, manager, manager.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, manager, manager.GetOwnerId(userCred))
+
+	localZones := make([]SDnsZone, 0)
+	remoteZones := make([]cloudprovider.ICloudDnsZone, 0)
+	syncResult := compare.SyncResult{}
+
+	dbZones := make([]SDnsZone, 0)
+	q := manager.Query().Equals("manager_id", provider.Id)
+	if err := db.FetchModelObjects(manager, q, &dbZones); err != nil {
+		syncResult.Error(err)
+		return nil, nil, syncResult
+	}
+
+	removed := make([]SDnsZone, 0)
+	commondb := make([]SDnsZone, 0)
+	commonext := make([]cloudprovider.ICloudDnsZone, 0)
+	added := make([]cloudprovider.ICloudDnsZone, 0)
+	if err := compare.CompareSets(dbZones, zones, &removed, &commondb, &commonext, &added); err != nil {
+		syncResult.Error(err)
+		return nil, nil, syncResult
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err := removed[i].syncRemoveCloudDnsZone(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	}
+
+	for i := 0; i < len(commondb); i += 1 {
+		err := commondb[i].SyncWithCloudDnsZone(ctx, userCred, commonext[i])
+		if err != nil {
+			syncResult.UpdateError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, &commondb[i], commonext[i])
+		localZones = append(localZones, commondb[i])
+		remoteZones = append(remoteZones, commonext[i])
+		syncResult.Update()
+	}
+
+	for i := 0; i < len(added); i += 1 {
+		zone, err := manager.newFromCloudDnsZone(ctx, userCred, provider, added[i])
+		if err != nil {
+			syncResult.AddError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, zone, added[i])
+		localZones = append(localZones, *zone)
+		remoteZones = append(remoteZones, added[i])
+		syncResult.Add()
+	}
+	return localZones, remoteZones, syncResult
+}
+
+func (self *SDnsZone) syncRemoveCloudDnsZone(ctx context.Context, userCred mcclient.TokenCredential) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	err := self.SVirtualResourceBase.ValidateDeleteCondition(ctx)
+	if err != nil {
+		self.SetStatus(userCred, api.DNS_ZONE_STATUS_UNKNOWN, "sync to delete")
+		return err
+	}
+	return self.RealDelete(ctx, userCred)
+}
+
+func (self *SDnsZone) SyncWithCloudDnsZone(ctx context.Context, userCred mcclient.TokenCredential, extZone cloudprovider.ICloudDnsZone) error {
+	diff, err := db.UpdateWithLock(ctx, self, func() error {
+		self.Status = extZone.GetStatus()
+		self.ZoneType = extZone.GetZoneType()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogSyncUpdate(self, diff, userCred)
+	return nil
+}
+
+func (manager *SDnsZoneManager) newFromCloudDnsZone(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, extZone cloudprovider.ICloudDnsZone) (*SDnsZone, error) {
+	zone := SDnsZone{}
+	zone.SetModelManager(manager)
+
+	// the name is the domain, it is kept as is to derive the names of the
+	// records
+	zone.Name = extZone.GetName()
+	zone.ExternalId = extZone.GetGlobalId()
+	zone.Status = extZone.GetStatus()
+	zone.ZoneType = extZone.GetZoneType()
+	zone.ManagerId = provider.Id
+	zone.ProjectId = provider.ProjectId
+	if len(zone.ProjectId) == 0 {
+		zone.ProjectId = userCred.GetProjectId()
+	}
+
+	err := manager.TableSpec().Insert(&zone)
+	if err != nil {
+		log.Errorf("newFromCloudDnsZone fail %s", err)
+		return nil, err
+	}
+
+	db.OpsLog.LogEvent(&zone, db.ACT_CREATE, zone.GetShortDesc(ctx), userCred)
+	return &zone, nil
+}
+
+func (self *SDnsZone) updateRecordCount(count int) error {
+	_, err := db.Update(self, func() error {
+		self.RecordCount = count
+		return nil
+	})
+	return err
+}
+
+func (manager *SDnsZoneManager) purgeAll(ctx context.Context, userCred mcclient.TokenCredential, providerId string) error {
+	zones := make([]SDnsZone, 0)
+	err := fetchByManagerId(manager, providerId, &zones)
+	if err != nil {
+		return err
+	}
+	for i := range zones {
+		err := zones[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return fmt.Errorf("purge dns zone %s fail %s", zones[i].Id, err)
+		}
+	}
+	return nil
+}
@@ -74,6 +74,26 @@ func (self *SGuest) GetDetailsVnc(ctx context.Context, userCred mcclient.TokenCr
 	}
 }
 
+func (self *SGuest) AllowGetDetailsSerial(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowGetSpec(userCred, self, "serial")
+}
+
+func (self *SGuest) GetDetailsSerial(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{VM_RUNNING, VM_BLOCK_STREAM}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot connect serial console in status %s", self.Status)
+	}
+	host := self.GetHost()
+	if host == nil {
+		return nil, httperrors.NewInternalServerError("Host missing")
+	}
+	retval, err := self.GetDriver().GetGuestSerialInfo(ctx, userCred, self, host)
+	if err != nil {
+		return nil, err
+	}
+	retval.Add(jsonutils.NewString(self.Id), "id")
+	return retval, nil
+}
+
 func (self *SGuest) AllowPerformMonitor(ctx context.Context,
 	userCred mcclient.TokenCredential,
 	query jsonutils.JSONObject,
@@ -269,16 +289,8 @@ func (self *SGuest) PerformLiveMigrate(ctx context.Context, userCred mcclient.To
 		return nil, httperrors.NewBadRequestError("Guest have backup, can't migrate")
 	}
 	if utils.IsInStringArray(self.Status, []string{VM_RUNNING, VM_SUSPEND}) {
-		cdrom := self.getCdrom(false)
-		if cdrom != nil && len(cdrom.ImageId) > 0 {
-			return nil, httperrors.NewBadRequestError("Cannot migrate with cdrom")
-		}
-		devices := self.GetIsolatedDevices()
-		if devices != nil && len(devices) > 0 {
-			return nil, httperrors.NewBadRequestError("Cannot migrate with isolated devices")
-		}
-		if !self.CheckQemuVersion(self.GetQemuVersion(userCred), "1.1.2") {
-			return nil, httperrors.NewBadRequestError("Cannot do live migrate, too low qemu version")
+		if err := self.checkLiveMigrate(userCred); err != nil {
+			return nil, err
 		}
 		var preferHostId string
 		preferHost, _ := data.GetString("prefer_host")
@@ -299,6 +311,22 @@ func (self *SGuest) PerformLiveMigrate(ctx context.Context, userCred mcclient.To
 	return nil, httperrors.NewBadRequestError("Cannot live migrate in status %s", self.Status)
 }
 
+// checkLiveMigrate tells why a running guest can not be live migrated
+func (self *SGuest) checkLiveMigrate(userCred mcclient.TokenCredential) error {
+	cdrom := self.getCdrom(false)
+	if cdrom != nil && len(cdrom.ImageId) > 0 {
+		return httperrors.NewBadRequestError("Cannot migrate with cdrom")
+	}
+	devices := self.GetIsolatedDevices()
+	if devices != nil && len(devices) > 0 {
+		return httperrors.NewBadRequestError("Cannot migrate with isolated devices")
+	}
+	if !self.CheckQemuVersion(self.GetQemuVersion(userCred), "1.1.2") {
+		return httperrors.NewBadRequestError("Cannot do live migrate, too low qemu version")
+	}
+	return nil
+}
+
 func (self *SGuest) StartGuestLiveMigrateTask(ctx context.Context, userCred mcclient.TokenCredential, guestStatus, preferHostId, parentTaskId string) error {
 	self.SetStatus(userCred, VM_START_MIGRATE, "")
 	data := jsonutils.NewDict()
@@ -382,6 +410,148 @@ func (self *SGuest) PerformClone(ctx context.Context, userCred mcclient.TokenCre
 	return nil, nil
 }
 
+func (self *SGuest) AllowPerformMigrateToKvm(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "migrate-to-kvm")
+}
+
+// PerformMigrateToKvm creates a KVM guest equivalent to a powered off ESXi
+// guest, the disks of the new guest are imported from the datastore files of
+// the source disks and the nics keep their mac and ip addresses if the
+// networks are reachable from KVM hosts. Windows guests are rejected since
+// no virtio driver is injected into the imported disks
+func (self *SGuest) PerformMigrateToKvm(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != HYPERVISOR_ESXI {
+		return nil, httperrors.NewBadRequestError("Cannot migrate %s guest to %s", self.Hypervisor, HYPERVISOR_KVM)
+	}
+	if self.Status != VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Cannot migrate VM in status %s, stop it first", self.Status)
+	}
+	if self.IsWindows() {
+		return nil, httperrors.NewUnsupportOperationError("Cannot migrate Windows guest to %s without virtio drivers", HYPERVISOR_KVM)
+	}
+	if targetId := self.GetMetadata(VM_METADATA_V2V_TARGET, nil); len(targetId) > 0 {
+		if target := GuestManager.FetchGuestById(targetId); target != nil {
+			return nil, httperrors.NewBadRequestError("Guest has been migrated to %s", target.Name)
+		}
+	}
+	input := api.ServerMigrateToKvmInput{}
+	if err := data.Unmarshal(&input); err != nil {
+		return nil, httperrors.NewInputParameterError("Unmarshal input error %s", err)
+	}
+	if len(input.Name) == 0 {
+		input.Name = fmt.Sprintf("%s-kvm", self.Name)
+	}
+	if err := db.NewNameValidator(GuestManager, self.ProjectId, input.Name); err != nil {
+		return nil, err
+	}
+	if len(input.PreferHost) > 0 {
+		iHost, _ := HostManager.FetchByIdOrName(userCred, input.PreferHost)
+		if iHost == nil {
+			return nil, httperrors.NewBadRequestError("Host %s not found", input.PreferHost)
+		}
+		host := iHost.(*SHost)
+		if host.HostType != HOST_TYPE_HYPERVISOR {
+			return nil, httperrors.NewBadRequestError("Host %s is not a %s host", host.Name, HYPERVISOR_KVM)
+		}
+		input.PreferHost = host.Id
+	}
+
+	guestDisks := self.GetDisks()
+	if len(guestDisks) == 0 {
+		return nil, httperrors.NewBadRequestError("Guest has no disk to import")
+	}
+	createInput := &api.ServerCreateInput{ServerConfigs: new(api.ServerConfigs)}
+	createInput.Name = input.Name
+	createInput.VmemSize = self.VmemSize
+	createInput.VcpuCount = int(self.VcpuCount)
+	createInput.Bios = self.Bios
+	createInput.Description = self.Description
+	createInput.OsType = self.OsType
+	createInput.AutoStart = input.AutoStart
+	createInput.ResetPassword = new(bool)
+	createInput.V2VSource = self.Id
+	createInput.Hypervisor = HYPERVISOR_KVM
+	createInput.PreferHost = input.PreferHost
+	createInput.Project = self.ProjectId
+	createInput.Count = 1
+	for i, guestDisk := range guestDisks {
+		disk := guestDisk.GetDisk()
+		diskConf := &api.DiskConfig{
+			Index:    i,
+			SizeMb:   disk.DiskSize,
+			Format:   "qcow2",
+			Driver:   "virtio",
+			DiskType: DISK_TYPE_DATA,
+		}
+		if i == 0 {
+			diskConf.DiskType = DISK_TYPE_SYS
+		}
+		createInput.Disks = append(createInput.Disks, diskConf)
+	}
+
+	guestNetworks, err := self.GetNetworks("")
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	keptNetworks := make([]SGuestnetwork, 0)
+	for i, gn := range guestNetworks {
+		netConf := &api.NetworkConfig{Index: i, Driver: "virtio"}
+		if network := gn.GetNetwork(); network != nil && network.isReachableFromHostType(HOST_TYPE_HYPERVISOR) {
+			netConf.Network = network.Id
+			netConf.Address = gn.IpAddr
+			netConf.Mac = gn.MacAddr
+			netConf.Reserved = true
+			keptNetworks = append(keptNetworks, gn)
+		}
+		createInput.Networks = append(createInput.Networks, netConf)
+	}
+
+	dataDict := createInput.JSON(createInput)
+	model, err := db.DoCreate(GuestManager, ctx, userCred, query, dataDict, createInput.Project)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+
+	func() {
+		lockman.LockObject(ctx, model)
+		defer lockman.ReleaseObject(ctx, model)
+
+		model.PostCreate(ctx, userCred, createInput.Project, query, dataDict)
+	}()
+
+	// the addresses are reserved for the KVM guest to take them over when
+	// its networks are allocated, the source guest keeps its nics if the
+	// creation fails
+	err = GuestnetworkManager.DeleteGuestNics(ctx, userCred, keptNetworks, true)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+
+	self.SetMetadata(ctx, VM_METADATA_V2V_TARGET, model.GetId(), userCred)
+	db.OpsLog.LogEvent(self, db.ACT_MIGRATING, fmt.Sprintf("migrate to %s guest %s", HYPERVISOR_KVM, model.GetId()), userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_MIGRATE, model.GetId(), userCred, true)
+	db.OpsLog.LogEvent(model, db.ACT_CREATE, model.GetShortDesc(ctx), userCred)
+	logclient.AddActionLogWithContext(ctx, model, logclient.ACT_CREATE, "", userCred, true)
+
+	GuestManager.OnCreateComplete(ctx, []db.IModel{model}, userCred, query, dataDict)
+	return nil, nil
+}
+
+// isValidV2VNetworkInfo accepts the reserved addresses still used by the
+// source guest of an import, they are handed over once the guest is created
+func isValidV2VNetworkInfo(userCred mcclient.TokenCredential, sourceId string, netConfig *api.NetworkConfig) error {
+	if netConfig.Reserved && len(netConfig.Address) > 0 {
+		gn, _ := GuestnetworkManager.getGuestNicByIP(netConfig.Address, netConfig.Network)
+		if gn != nil && gn.GuestId == sourceId {
+			if netConfig.BwLimit > MAX_BANDWIDTH {
+				return httperrors.NewInputParameterError("Bandwidth limit cannot exceed %dMbps", MAX_BANDWIDTH)
+			}
+			return nil
+		}
+	}
+	return isValidNetworkInfo(userCred, netConfig)
+}
+
 func (self *SGuest) AllowGetDetailsCreateParams(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
 	return self.IsOwner(userCred) || db.IsAdminAllowGetSpec(userCred, self, "create-params")
 }
@@ -1374,6 +1544,11 @@ func (self *SGuest) PerformDetachIsolatedDevice(ctx context.Context, userCred mc
 		return nil, httperrors.NewBadRequestError(msg)
 	}
 	dev := iDev.(*SIsolatedDevice)
+	if dev.isSriovVF() {
+		msg := "SR-IOV virtual function is managed along with guest networks"
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_GUEST_DETACH_ISOLATED_DEVICE, msg, userCred, false)
+		return nil, httperrors.NewBadRequestError(msg)
+	}
 	host := self.GetHost()
 	lockman.LockObject(ctx, host)
 	defer lockman.ReleaseObject(ctx, host)
@@ -1424,6 +1599,11 @@ func (self *SGuest) PerformAttachIsolatedDevice(ctx context.Context, userCred mc
 		return nil, httperrors.NewBadRequestError(msg)
 	}
 	dev := iDev.(*SIsolatedDevice)
+	if dev.isSriovVF() {
+		msg := "SR-IOV virtual function is managed along with guest networks"
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_GUEST_ATTACH_ISOLATED_DEVICE, msg, userCred, false)
+		return nil, httperrors.NewBadRequestError(msg)
+	}
 	host := self.GetHost()
 	lockman.LockObject(ctx, host)
 	defer lockman.ReleaseObject(ctx, host)
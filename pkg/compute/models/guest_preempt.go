@@ -0,0 +1,101 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/notify"
+)
+
+func (self *SGuest) IsPreemptible() bool {
+	return self.PriorityClass == api.GUEST_PRIORITY_CLASS_PREEMPTIBLE
+}
+
+const (
+	// the guest is gone, nothing to wait for
+	GUEST_PREEMPT_DONE = "done"
+	// the eviction is started, the task is notified when the guest is gone
+	GUEST_PREEMPT_STARTED = "started"
+	// another guest placed on the host is evicting the guest, poll it
+	GUEST_PREEMPT_EVICTING = "evicting"
+)
+
+// PreemptGuest evicts a preemptible guest on the host chosen by the scheduler
+// to make room for preemptor, it is stopped or deleted as the evict action
+// option. All the guests placed on the host by one schedule share the same
+// victims, the first of them evicts a victim and the others wait for it.
+func (manager *SGuestManager) PreemptGuest(ctx context.Context, userCred mcclient.TokenCredential, preemptor *SGuest, guestId string, parentTaskId string) (string, error) {
+	guest := manager.FetchGuestById(guestId)
+	if guest == nil {
+		log.Warningf("preempt guest %s not found", guestId)
+		return GUEST_PREEMPT_DONE, nil
+	}
+	lockman.LockObject(ctx, guest)
+	defer lockman.ReleaseObject(ctx, guest)
+
+	if !guest.IsPreemptible() || guest.HostId != preemptor.HostId {
+		return GUEST_PREEMPT_DONE, fmt.Errorf("guest %s is not a preemptible guest of host %s", guest.Name, preemptor.HostId)
+	}
+	if guest.isEvicting() {
+		return GUEST_PREEMPT_EVICTING, nil
+	}
+	action := options.Options.PreemptibleGuestEvictAction
+	started, err := guest.preempt(ctx, userCred, action, parentTaskId)
+	notes := jsonutils.NewDict()
+	notes.Add(jsonutils.NewString(preemptor.Id), "preemptor_id")
+	notes.Add(jsonutils.NewString(preemptor.Name), "preemptor")
+	notes.Add(jsonutils.NewString(action), "action")
+	if err != nil {
+		notes.Add(jsonutils.NewString(err.Error()), "reason")
+		db.OpsLog.LogEvent(guest, db.ACT_PREEMPT_FAIL, notes, userCred)
+		return GUEST_PREEMPT_DONE, err
+	}
+	if !started {
+		return GUEST_PREEMPT_DONE, nil
+	}
+	db.OpsLog.LogEvent(guest, db.ACT_PREEMPT, notes, userCred)
+	guest.NotifyAdminServerEvent(ctx, notifyclient.SERVER_PREEMPTED, notify.NotifyPriorityImportant)
+	return GUEST_PREEMPT_STARTED, nil
+}
+
+// isEvicting tells whether the guest is being stopped or deleted
+func (self *SGuest) isEvicting() bool {
+	return utils.IsInStringArray(self.Status, []string{VM_START_STOP, VM_STOPPING, VM_START_DELETE, VM_DELETING})
+}
+
+func (self *SGuest) preempt(ctx context.Context, userCred mcclient.TokenCredential, action string, parentTaskId string) (bool, error) {
+	switch action {
+	case api.GUEST_PREEMPT_ACTION_STOP:
+		if self.Status == VM_READY {
+			return false, nil
+		}
+		return true, self.StartGuestStopTask(ctx, userCred, true, parentTaskId)
+	case api.GUEST_PREEMPT_ACTION_DELETE:
+		return true, self.StartDeleteGuestTask(ctx, userCred, parentTaskId, false, true)
+	}
+	return false, fmt.Errorf("Unsupported preempt action %q", action)
+}
@@ -0,0 +1,788 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/influxdb"
+)
+
+const (
+	// minimal improvement of the host score for a move to be worth it
+	rebalanceEpsilon = 1e-6
+	// window of the telegraf metrics averaged as the measured load
+	rebalanceLoadWindow = "10m"
+)
+
+type sRebalanceGuest struct {
+	Id   string
+	Name string
+
+	Cpu float64
+	Mem float64
+	// estimated share of the measured host load, in cores and MB
+	CpuLoad float64
+	MemLoad float64
+
+	// groups whose guests should not share a host
+	Groups []string
+
+	// shared storages of the disks, which the destination must attach too
+	Storages []string
+	// whether some disk is on a local storage copied during the migration
+	LocalStorage bool
+}
+
+type sRebalanceHost struct {
+	Id   string
+	Name string
+
+	// allocatable vcpu and memory with overcommit
+	CpuTotal float64
+	MemTotal float64
+	CpuAlloc float64
+	MemAlloc float64
+
+	// physical capacity and measured usage, in cores and MB
+	CpuCount float64
+	MemSize  float64
+	CpuLoad  float64
+	MemLoad  float64
+
+	RequireTags []string
+	ExcludeTags []string
+
+	// guests run with the host cpu model, so they can only be live migrated
+	// between hosts of the same cpu
+	CpuDesc      string
+	Storages     []string
+	LocalStorage bool
+
+	Draining bool
+	// guests that can be live migrated
+	Guests []*sRebalanceGuest
+	// running guests that can not be live migrated
+	Pinned []string
+	// groups of all guests on the host, including those that can not move
+	Groups map[string]int
+}
+
+func rebalanceRatio(used, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return used / total
+}
+
+func (h *sRebalanceHost) scoreWith(g *sRebalanceGuest, sign float64, metric string) float64 {
+	if metric == api.REBALANCE_METRIC_LOAD {
+		var cpu, mem float64
+		if g != nil {
+			cpu, mem = g.CpuLoad, g.MemLoad
+		}
+		return math.Max(rebalanceRatio(h.CpuLoad+sign*cpu, h.CpuCount), rebalanceRatio(h.MemLoad+sign*mem, h.MemSize))
+	}
+	var cpu, mem float64
+	if g != nil {
+		cpu, mem = g.Cpu, g.Mem
+	}
+	return math.Max(rebalanceRatio(h.CpuAlloc+sign*cpu, h.CpuTotal), rebalanceRatio(h.MemAlloc+sign*mem, h.MemTotal))
+}
+
+func (h *sRebalanceHost) score(metric string) float64 {
+	return h.scoreWith(nil, 0, metric)
+}
+
+func (h *sRebalanceHost) fits(g *sRebalanceGuest) bool {
+	return h.CpuAlloc+g.Cpu <= h.CpuTotal && h.MemAlloc+g.Mem <= h.MemTotal
+}
+
+// acceptTagsOf tells whether the schedtag constraints satisfied by src are
+// still satisfied on the host
+func (h *sRebalanceHost) acceptTagsOf(src *sRebalanceHost) bool {
+	for _, tag := range src.RequireTags {
+		if !utils.IsInStringArray(tag, h.RequireTags) {
+			return false
+		}
+	}
+	for _, tag := range h.ExcludeTags {
+		if !utils.IsInStringArray(tag, src.ExcludeTags) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *sRebalanceHost) hasGroups(groups []string) bool {
+	for _, group := range groups {
+		if h.Groups[group] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compatibleWith tells whether the guest of src can be live migrated to the
+// host, like the scheduler checks for a live migration
+func (h *sRebalanceHost) compatibleWith(src *sRebalanceHost, g *sRebalanceGuest) bool {
+	if h.CpuDesc != src.CpuDesc {
+		return false
+	}
+	if g.LocalStorage && !h.LocalStorage {
+		return false
+	}
+	for _, storageId := range g.Storages {
+		if !utils.IsInStringArray(storageId, h.Storages) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *sRebalanceHost) canAccept(src *sRebalanceHost, g *sRebalanceGuest) bool {
+	return h != src && !h.Draining && h.fits(g) && h.compatibleWith(src, g) &&
+		h.acceptTagsOf(src) && !h.hasGroups(g.Groups)
+}
+
+// reserveGuest accounts the resources of a guest that is not movable on the
+// host, e.g. one being migrated to it
+func (h *sRebalanceHost) reserveGuest(g *sRebalanceGuest) {
+	h.CpuAlloc += g.Cpu
+	h.MemAlloc += g.Mem
+	h.CpuLoad += g.CpuLoad
+	h.MemLoad += g.MemLoad
+	for _, group := range g.Groups {
+		h.Groups[group] += 1
+	}
+}
+
+func (h *sRebalanceHost) addGuest(g *sRebalanceGuest) {
+	h.reserveGuest(g)
+	h.Guests = append(h.Guests, g)
+}
+
+func (h *sRebalanceHost) removeGuest(g *sRebalanceGuest) {
+	h.CpuAlloc -= g.Cpu
+	h.MemAlloc -= g.Mem
+	h.CpuLoad -= g.CpuLoad
+	h.MemLoad -= g.MemLoad
+	for _, group := range g.Groups {
+		h.Groups[group] -= 1
+	}
+	for i := range h.Guests {
+		if h.Guests[i] == g {
+			h.Guests = append(h.Guests[:i], h.Guests[i+1:]...)
+			break
+		}
+	}
+}
+
+func rebalanceSpread(hosts []*sRebalanceHost, metric string) float64 {
+	min, max := math.MaxFloat64, 0.0
+	for _, h := range hosts {
+		if h.Draining {
+			continue
+		}
+		score := h.score(metric)
+		min = math.Min(min, score)
+		max = math.Max(max, score)
+	}
+	if max < min {
+		return 0
+	}
+	return max - min
+}
+
+// rebalanceTarget returns the host that would be the least loaded after
+// accepting guest g from src
+func rebalanceTarget(hosts []*sRebalanceHost, src *sRebalanceHost, g *sRebalanceGuest, metric string) (*sRebalanceHost, float64) {
+	var target *sRebalanceHost
+	targetScore := math.MaxFloat64
+	for _, h := range hosts {
+		if !h.canAccept(src, g) {
+			continue
+		}
+		score := h.scoreWith(g, 1, metric)
+		if score < targetScore {
+			target, targetScore = h, score
+		}
+	}
+	return target, targetScore
+}
+
+func addRebalanceMove(plan *api.SRebalancePlan, src, dst *sRebalanceHost, g *sRebalanceGuest) {
+	src.removeGuest(g)
+	dst.addGuest(g)
+	plan.Moves = append(plan.Moves, api.SRebalanceMove{
+		GuestId:   g.Id,
+		Guest:     g.Name,
+		SrcHostId: src.Id,
+		SrcHost:   src.Name,
+		DstHostId: dst.Id,
+		DstHost:   dst.Name,
+	})
+}
+
+// planRebalance moves every guest off the draining hosts, then greedily moves
+// guests from the most loaded hosts until the spread of host scores is within
+// threshold or maxMoves balancing moves are planned. A balancing move is only
+// taken when both of its hosts end up below the score the source had.
+func planRebalance(hosts []*sRebalanceHost, metric string, threshold float64, maxMoves int) *api.SRebalancePlan {
+	plan := &api.SRebalancePlan{
+		Metric:       metric,
+		SpreadBefore: rebalanceSpread(hosts, metric),
+		Moves:        []api.SRebalanceMove{},
+		Unplaced:     []string{},
+	}
+
+	for _, src := range hosts {
+		if !src.Draining {
+			continue
+		}
+		plan.Unplaced = append(plan.Unplaced, src.Pinned...)
+		guests := make([]*sRebalanceGuest, len(src.Guests))
+		copy(guests, src.Guests)
+		// place the largest guests first while there is most room left
+		sort.SliceStable(guests, func(i, j int) bool {
+			return guests[i].Mem+guests[i].Cpu > guests[j].Mem+guests[j].Cpu
+		})
+		for _, g := range guests {
+			dst, _ := rebalanceTarget(hosts, src, g, metric)
+			if dst == nil {
+				plan.Unplaced = append(plan.Unplaced, g.Name)
+				continue
+			}
+			addRebalanceMove(plan, src, dst, g)
+		}
+	}
+
+	for moves := 0; moves < maxMoves; moves++ {
+		if rebalanceSpread(hosts, metric) <= threshold {
+			break
+		}
+		srcs := make([]*sRebalanceHost, 0, len(hosts))
+		for _, h := range hosts {
+			if !h.Draining {
+				srcs = append(srcs, h)
+			}
+		}
+		sort.SliceStable(srcs, func(i, j int) bool {
+			return srcs[i].score(metric) > srcs[j].score(metric)
+		})
+		moved := false
+		for _, src := range srcs {
+			srcScore := src.score(metric)
+			var (
+				bestGuest *sRebalanceGuest
+				bestDst   *sRebalanceHost
+				bestScore = srcScore - rebalanceEpsilon
+			)
+			for _, g := range src.Guests {
+				dst, dstScore := rebalanceTarget(hosts, src, g, metric)
+				if dst == nil {
+					continue
+				}
+				score := math.Max(src.scoreWith(g, -1, metric), dstScore)
+				if score < bestScore {
+					bestGuest, bestDst, bestScore = g, dst, score
+				}
+			}
+			if bestGuest != nil {
+				addRebalanceMove(plan, src, bestDst, bestGuest)
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+
+	plan.SpreadAfter = rebalanceSpread(hosts, metric)
+	return plan
+}
+
+// isRebalanceMovable tells whether the guest can be live migrated without
+// the intervention of its owner
+func (self *SGuest) isRebalanceMovable(userCred mcclient.TokenCredential) bool {
+	if self.Hypervisor != HYPERVISOR_KVM || self.Status != VM_RUNNING {
+		return false
+	}
+	if len(self.BackupHostId) > 0 {
+		return false
+	}
+	return self.checkLiveMigrate(userCred) == nil
+}
+
+// fetchMigrateTargetHostId returns the destination of the migration the guest
+// is going through, or empty when it is not known yet
+func (self *SGuest) fetchMigrateTargetHostId() string {
+	tasks, err := taskman.TaskManager.FetchIncompleteTasksOfObject(self)
+	if err != nil {
+		return ""
+	}
+	for i := range tasks {
+		if !utils.IsInStringArray(tasks[i].TaskName, []string{"GuestLiveMigrateTask", "GuestMigrateTask"}) || tasks[i].Params == nil {
+			continue
+		}
+		for _, key := range []string{"target_host_id", "prefer_host_id"} {
+			if hostId, _ := tasks[i].Params.GetString(key); len(hostId) > 0 {
+				return hostId
+			}
+		}
+	}
+	return ""
+}
+
+// fillRebalanceStorages records the storages of the disks of the guest
+func (self *SGuest) fillRebalanceStorages(g *sRebalanceGuest) {
+	for _, guestdisk := range self.GetDisks() {
+		disk := guestdisk.GetDisk()
+		if disk == nil {
+			continue
+		}
+		storage := disk.GetStorage()
+		if storage == nil {
+			continue
+		}
+		if utils.IsInStringArray(storage.StorageType, STORAGE_LOCAL_TYPES) {
+			g.LocalStorage = true
+		} else if !utils.IsInStringArray(storage.Id, g.Storages) {
+			g.Storages = append(g.Storages, storage.Id)
+		}
+	}
+}
+
+// fetchRebalanceGroups returns the anti-affinity groups of each guest and the
+// guests pinned together by a require group
+func fetchRebalanceGroups(guestIds []string) (map[string][]string, map[string]bool, error) {
+	groups := map[string][]string{}
+	pinned := map[string]bool{}
+	if len(guestIds) == 0 {
+		return groups, pinned, nil
+	}
+	groupQ := GroupManager.Query().SubQuery()
+	groupguests := GroupguestManager.Query().SubQuery()
+	q := groupguests.Query(groupguests.Field("guest_id"), groupguests.Field("group_id"), groupQ.Field("sched_strategy"))
+	q = q.Join(groupQ, sqlchemy.Equals(groupguests.Field("group_id"), groupQ.Field("id")))
+	q = q.Filter(sqlchemy.In(groupguests.Field("guest_id"), guestIds))
+	q = q.Filter(sqlchemy.In(groupQ.Field("sched_strategy"), []string{STRATEGY_EXCLUDE, STRATEGY_AVOID, STRATEGY_REQUIRE}))
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var guestId, groupId, strategy string
+		err := rows.Scan(&guestId, &groupId, &strategy)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch strategy {
+		case STRATEGY_EXCLUDE, STRATEGY_AVOID:
+			groups[guestId] = append(groups[guestId], groupId)
+		case STRATEGY_REQUIRE:
+			pinned[guestId] = true
+		}
+	}
+	return groups, pinned, nil
+}
+
+// fetchHostMeasuredLoads returns the recent cpu cores and memory MB in use of
+// each host reported by telegraf
+func fetchHostMeasuredLoads() (map[string][2]float64, error) {
+	urls, err := auth.GetServiceURLs("influxdb", options.Options.Region, "", "internal")
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no influxdb endpoint")
+	}
+	tsdb := influxdb.NewInfluxdb(urls[0])
+	err = tsdb.SetDatabase("telegraf")
+	if err != nil {
+		return nil, err
+	}
+	results, err := tsdb.Query(fmt.Sprintf(
+		`SELECT mean("usage_active") FROM "telegraf".."cpu" WHERE "cpu" = 'cpu-total' AND time > now() - %s GROUP BY "host_id"; `+
+			`SELECT mean("used") FROM "telegraf".."mem" WHERE time > now() - %s GROUP BY "host_id"`,
+		rebalanceLoadWindow, rebalanceLoadWindow))
+	if err != nil {
+		return nil, err
+	}
+	loads := map[string][2]float64{}
+	for i := range results {
+		if i > 1 {
+			break
+		}
+		for _, series := range results[i] {
+			hostId := series.Tags["host_id"]
+			if len(hostId) == 0 || len(series.Values) == 0 || len(series.Values[0]) < 2 {
+				continue
+			}
+			val, err := series.Values[0][1].Float()
+			if err != nil {
+				continue
+			}
+			load := loads[hostId]
+			if i == 0 {
+				// percent of all cores
+				load[0] = val
+			} else {
+				// bytes
+				load[1] = val / 1024 / 1024
+			}
+			loads[hostId] = load
+		}
+	}
+	return loads, nil
+}
+
+// fetchRebalanceHosts collects the enabled online KVM hosts of the zone and
+// the draining host with their guests
+func (zone *SZone) fetchRebalanceHosts(userCred mcclient.TokenCredential, metric string, drainHostId string) ([]*sRebalanceHost, error) {
+	q := HostManager.Query().Equals("zone_id", zone.Id).Equals("host_type", HOST_TYPE_HYPERVISOR)
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.AND(sqlchemy.IsTrue(q.Field("enabled")), sqlchemy.Equals(q.Field("host_status"), HOST_ONLINE)),
+		sqlchemy.Equals(q.Field("id"), drainHostId),
+	))
+	dbHosts := make([]SHost, 0)
+	err := db.FetchModelObjects(HostManager, q, &dbHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	var loads map[string][2]float64
+	if metric == api.REBALANCE_METRIC_LOAD {
+		loads, err = fetchHostMeasuredLoads()
+		if err != nil {
+			return nil, httperrors.NewInternalServerError("fetch measured host load: %v", err)
+		}
+	}
+
+	hosts := make([]*sRebalanceHost, 0, len(dbHosts))
+	// guests being migrated, by destination host
+	incomings := map[string][]*sRebalanceGuest{}
+	for i := range dbHosts {
+		dbHost := &dbHosts[i]
+		host := &sRebalanceHost{
+			Id:       dbHost.Id,
+			Name:     dbHost.Name,
+			CpuTotal: float64(dbHost.GetVirtualCPUCount()),
+			MemTotal: float64(dbHost.GetVirtualMemorySize()),
+			CpuCount: float64(dbHost.GetCpuCount()),
+			MemSize:  float64(dbHost.GetMemSize()),
+			CpuDesc:  dbHost.CpuDesc,
+			Draining: dbHost.Id == drainHostId,
+			Guests:   []*sRebalanceGuest{},
+			Groups:   map[string]int{},
+		}
+		if metric == api.REBALANCE_METRIC_LOAD {
+			load, ok := loads[dbHost.Id]
+			if !ok && !host.Draining {
+				// a host without metrics can not be compared with the others
+				log.Warningf("no measured load of host %s, skip it", dbHost.Name)
+				continue
+			}
+			host.CpuLoad = load[0] / 100 * host.CpuCount
+			host.MemLoad = load[1]
+		}
+		for _, tag := range dbHost.GetSchedtags() {
+			switch tag.DefaultStrategy {
+			case STRATEGY_REQUIRE:
+				host.RequireTags = append(host.RequireTags, tag.Id)
+			case STRATEGY_EXCLUDE:
+				host.ExcludeTags = append(host.ExcludeTags, tag.Id)
+			}
+		}
+
+		for _, hoststorage := range dbHost.GetHoststorages() {
+			storage := hoststorage.GetStorage()
+			if storage == nil {
+				continue
+			}
+			if utils.IsInStringArray(storage.StorageType, STORAGE_LOCAL_TYPES) {
+				host.LocalStorage = true
+			} else {
+				host.Storages = append(host.Storages, storage.Id)
+			}
+		}
+
+		guests := dbHost.GetGuests()
+		guestIds := make([]string, len(guests))
+		for j := range guests {
+			guestIds[j] = guests[j].Id
+		}
+		groups, pinned, err := fetchRebalanceGroups(guestIds)
+		if err != nil {
+			return nil, err
+		}
+		movables := make([]*sRebalanceGuest, 0)
+		migratings := make([]*sRebalanceGuest, 0)
+		for j := range guests {
+			guest := &guests[j]
+			g := &sRebalanceGuest{
+				Id:     guest.Id,
+				Name:   guest.Name,
+				Cpu:    float64(guest.VcpuCount),
+				Mem:    float64(guest.VmemSize),
+				Groups: groups[guest.Id],
+			}
+			host.CpuAlloc += g.Cpu
+			host.MemAlloc += g.Mem
+			for _, group := range g.Groups {
+				host.Groups[group] += 1
+			}
+			if utils.IsInStringArray(guest.Status, []string{VM_START_MIGRATE, VM_MIGRATING}) {
+				if dstHostId := guest.fetchMigrateTargetHostId(); len(dstHostId) > 0 && dstHostId != host.Id {
+					incomings[dstHostId] = append(incomings[dstHostId], g)
+					migratings = append(migratings, g)
+				}
+				continue
+			}
+			if !pinned[guest.Id] && guest.isRebalanceMovable(userCred) {
+				guest.fillRebalanceStorages(g)
+				movables = append(movables, g)
+			} else if guest.Status == VM_RUNNING {
+				host.Pinned = append(host.Pinned, guest.Name)
+			}
+		}
+		// attribute the measured load to guests by their allocation
+		for _, g := range append(movables, migratings...) {
+			g.CpuLoad = host.CpuLoad * rebalanceRatio(g.Cpu, host.CpuAlloc)
+			g.MemLoad = host.MemLoad * rebalanceRatio(g.Mem, host.MemAlloc)
+		}
+		host.Guests = movables
+		hosts = append(hosts, host)
+	}
+	// the destinations of the ongoing migrations already hold their guests
+	for _, host := range hosts {
+		for _, g := range incomings[host.Id] {
+			host.reserveGuest(g)
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+	return hosts, nil
+}
+
+func (zone *SZone) planGuestRebalance(userCred mcclient.TokenCredential, metric string, threshold float64, maxMoves int, drainHostId string) (*api.SRebalancePlan, error) {
+	hosts, err := zone.fetchRebalanceHosts(userCred, metric, drainHostId)
+	if err != nil {
+		return nil, err
+	}
+	return planRebalance(hosts, metric, threshold, maxMoves), nil
+}
+
+func fetchRebalanceConcurrency(data jsonutils.JSONObject) int {
+	concurrency := options.Options.GuestRebalanceConcurrency
+	if data != nil {
+		if val, err := data.Int("concurrency"); err == nil && val > 0 {
+			concurrency = int(val)
+		}
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return concurrency
+}
+
+func startGuestRebalanceTask(ctx context.Context, userCred mcclient.TokenCredential, obj db.IStandaloneModel, plan *api.SRebalancePlan, concurrency int) error {
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.Marshal(plan.Moves), "moves")
+	params.Add(jsonutils.NewInt(int64(concurrency)), "concurrency")
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestRebalanceTask", obj, userCred, params, "", "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// isRebalancing tells whether a rebalance task of the object is still moving
+// guests
+func isRebalancing(obj db.IStandaloneModel) bool {
+	tasks, err := taskman.TaskManager.FetchIncompleteTasksOfObject(obj)
+	if err != nil {
+		log.Errorf("fetch tasks of %s %s: %v", obj.Keyword(), obj.GetName(), err)
+		return true
+	}
+	for i := range tasks {
+		if tasks[i].TaskName == "GuestRebalanceTask" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPendingRebalance tells whether the zone or one of its hosts is still
+// being rebalanced or drained
+func (zone *SZone) hasPendingRebalance() bool {
+	if isRebalancing(zone) {
+		return true
+	}
+	hosts := make([]SHost, 0)
+	q := HostManager.Query().Equals("zone_id", zone.Id).Equals("host_type", HOST_TYPE_HYPERVISOR)
+	err := db.FetchModelObjects(HostManager, q, &hosts)
+	if err != nil {
+		log.Errorf("fetch hosts of zone %s: %v", zone.Name, err)
+		return true
+	}
+	for i := range hosts {
+		if isRebalancing(&hosts[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (zone *SZone) AllowPerformRebalance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, zone, "rebalance")
+}
+
+// PerformRebalance plans live migrations evening out the hosts of the zone and
+// starts them unless dry_run is set
+func (zone *SZone) PerformRebalance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	metric, _ := data.GetString("metric")
+	if len(metric) == 0 {
+		metric = options.Options.GuestRebalanceMetric
+	}
+	if !utils.IsInStringArray(metric, api.REBALANCE_METRICS) {
+		return nil, httperrors.NewInputParameterError("invalid metric %s, must be one of %s", metric, api.REBALANCE_METRICS)
+	}
+	threshold := float64(options.Options.GuestRebalanceThreshold)
+	if data.Contains("threshold") {
+		str, _ := data.GetString("threshold")
+		val, err := strconv.ParseFloat(str, 64)
+		if err != nil || val < 0 || val > 1 {
+			return nil, httperrors.NewInputParameterError("threshold must be a ratio between 0 and 1")
+		}
+		threshold = val
+	}
+	maxMoves := options.Options.GuestRebalanceMaxMoves
+	if data.Contains("max_moves") {
+		val, err := data.Int("max_moves")
+		if err != nil || val <= 0 {
+			return nil, httperrors.NewInputParameterError("max_moves must be a positive integer")
+		}
+		maxMoves = int(val)
+	}
+	plan, err := zone.planGuestRebalance(userCred, metric, threshold, maxMoves, "")
+	if err != nil {
+		return nil, err
+	}
+	if !jsonutils.QueryBoolean(data, "dry_run", false) && len(plan.Moves) > 0 {
+		if zone.hasPendingRebalance() {
+			return nil, httperrors.NewConflictError("zone %s is being rebalanced", zone.Name)
+		}
+		err = startGuestRebalanceTask(ctx, userCred, zone, plan, fetchRebalanceConcurrency(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jsonutils.Marshal(plan), nil
+}
+
+func (self *SHost) AllowPerformDrain(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "drain")
+}
+
+// PerformDrain disables the host and live migrates all of its guests to the
+// other hosts of the zone, e.g. before a maintenance
+func (self *SHost) PerformDrain(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.HostType != HOST_TYPE_HYPERVISOR {
+		return nil, httperrors.NewUnsupportOperationError("Drain is not supported for host type %s", self.HostType)
+	}
+	zone := self.GetZone()
+	if zone == nil {
+		return nil, httperrors.NewInternalServerError("host %s has no zone", self.Name)
+	}
+	plan, err := zone.planGuestRebalance(userCred, api.REBALANCE_METRIC_ALLOCATION, 0, 0, self.Id)
+	if err != nil {
+		return nil, err
+	}
+	if jsonutils.QueryBoolean(data, "dry_run", false) {
+		return jsonutils.Marshal(plan), nil
+	}
+	if zone.hasPendingRebalance() {
+		return nil, httperrors.NewConflictError("zone %s is being rebalanced", zone.Name)
+	}
+	if len(plan.Unplaced) > 0 && !jsonutils.QueryBoolean(data, "force", false) {
+		return nil, httperrors.NewInsufficientResourceError("guests %s can not be moved off the host, use force to drain the others", plan.Unplaced)
+	}
+	_, err = self.PerformDisable(ctx, userCred, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Moves) > 0 {
+		err = startGuestRebalanceTask(ctx, userCred, self, plan, fetchRebalanceConcurrency(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jsonutils.Marshal(plan), nil
+}
+
+// AutoRebalanceGuests rebalances the zones with on premise KVM hosts by the
+// configured metric
+func (manager *SZoneManager) AutoRebalanceGuests(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	hosts := HostManager.Query().SubQuery()
+	q := manager.Query()
+	q = q.Filter(sqlchemy.In(q.Field("id"), hosts.Query(hosts.Field("zone_id")).
+		Equals("host_type", HOST_TYPE_HYPERVISOR).SubQuery()))
+	zones := make([]SZone, 0)
+	err := db.FetchModelObjects(manager, q, &zones)
+	if err != nil {
+		log.Errorf("fetch zones for rebalance: %v", err)
+		return
+	}
+	for i := range zones {
+		zone := &zones[i]
+		if zone.hasPendingRebalance() {
+			log.Infof("zone %s is being rebalanced, skip it", zone.Name)
+			continue
+		}
+		plan, err := zone.planGuestRebalance(userCred, options.Options.GuestRebalanceMetric,
+			float64(options.Options.GuestRebalanceThreshold), options.Options.GuestRebalanceMaxMoves, "")
+		if err != nil {
+			log.Errorf("plan rebalance of zone %s: %v", zone.Name, err)
+			continue
+		}
+		if len(plan.Moves) == 0 {
+			continue
+		}
+		log.Infof("rebalance zone %s with %d moves, spread %.3f -> %.3f", zone.Name, len(plan.Moves), plan.SpreadBefore, plan.SpreadAfter)
+		err = startGuestRebalanceTask(ctx, userCred, zone, plan, options.Options.GuestRebalanceConcurrency)
+		if err != nil {
+			log.Errorf("start rebalance task of zone %s: %v", zone.Name, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func newTestRebalanceHost(name string, cpu, mem float64, guests ...*sRebalanceGuest) *sRebalanceHost {
+	h := &sRebalanceHost{
+		Id:       name,
+		Name:     name,
+		CpuTotal: cpu,
+		MemTotal: mem,
+		Guests:   []*sRebalanceGuest{},
+		Groups:   map[string]int{},
+	}
+	for _, g := range guests {
+		h.addGuest(g)
+	}
+	return h
+}
+
+func newTestRebalanceGuest(name string, cpu, mem float64, groups ...string) *sRebalanceGuest {
+	return &sRebalanceGuest{Id: name, Name: name, Cpu: cpu, Mem: mem, Groups: groups}
+}
+
+func TestPlanRebalance(t *testing.T) {
+	metric := api.REBALANCE_METRIC_ALLOCATION
+	t.Run("even out", func(t *testing.T) {
+		hosts := []*sRebalanceHost{
+			newTestRebalanceHost("h1", 16, 16384,
+				newTestRebalanceGuest("g1", 4, 4096),
+				newTestRebalanceGuest("g2", 4, 4096),
+				newTestRebalanceGuest("g3", 4, 4096),
+				newTestRebalanceGuest("g4", 4, 4096),
+			),
+			newTestRebalanceHost("h2", 16, 16384),
+		}
+		plan := planRebalance(hosts, metric, 0.1, 10)
+		if len(plan.Moves) != 2 {
+			t.Fatalf("want 2 moves, got %#v", plan.Moves)
+		}
+		for _, move := range plan.Moves {
+			if move.SrcHost != "h1" || move.DstHost != "h2" {
+				t.Errorf("unexpected move %#v", move)
+			}
+		}
+		if plan.SpreadBefore != 1 || plan.SpreadAfter != 0 {
+			t.Errorf("spread %f -> %f", plan.SpreadBefore, plan.SpreadAfter)
+		}
+	})
+	t.Run("max moves", func(t *testing.T) {
+		hosts := []*sRebalanceHost{
+			newTestRebalanceHost("h1", 16, 16384,
+				newTestRebalanceGuest("g1", 4, 4096),
+				newTestRebalanceGuest("g2", 4, 4096),
+				newTestRebalanceGuest("g3", 4, 4096),
+				newTestRebalanceGuest("g4", 4, 4096),
+			),
+			newTestRebalanceHost("h2", 16, 16384),
+		}
+		plan := planRebalance(hosts, metric, 0.1, 1)
+		if len(plan.Moves) != 1 {
+			t.Fatalf("want 1 move, got %#v", plan.Moves)
+		}
+	})
+	t.Run("anti affinity", func(t *testing.T) {
+		hosts := []*sRebalanceHost{
+			newTestRebalanceHost("h1", 16, 16384,
+				newTestRebalanceGuest("g1", 4, 4096, "grp"),
+				newTestRebalanceGuest("g2", 4, 4096),
+			),
+			newTestRebalanceHost("h2", 16, 16384,
+				newTestRebalanceGuest("g3", 2, 2048, "grp"),
+			),
+		}
+		plan := planRebalance(hosts, metric, 0, 10)
+		for _, move := range plan.Moves {
+			if move.Guest == "g1" {
+				t.Errorf("g1 moved next to a guest of its group")
+			}
+		}
+	})
+	t.Run("schedtag", func(t *testing.T) {
+		src := newTestRebalanceHost("h1", 16, 16384,
+			newTestRebalanceGuest("g1", 4, 4096),
+			newTestRebalanceGuest("g2", 4, 4096),
+		)
+		src.RequireTags = []string{"ssd"}
+		plan := planRebalance([]*sRebalanceHost{src, newTestRebalanceHost("h2", 16, 16384)}, metric, 0, 10)
+		if len(plan.Moves) != 0 {
+			t.Errorf("moved to a host without the required tag: %#v", plan.Moves)
+		}
+	})
+	t.Run("drain", func(t *testing.T) {
+		drain := newTestRebalanceHost("h1", 16, 16384,
+			newTestRebalanceGuest("g1", 8, 8192),
+			newTestRebalanceGuest("g2", 4, 4096),
+			newTestRebalanceGuest("g3", 4, 4096),
+		)
+		drain.Draining = true
+		drain.Pinned = []string{"gpu"}
+		hosts := []*sRebalanceHost{
+			drain,
+			newTestRebalanceHost("h2", 16, 16384, newTestRebalanceGuest("g4", 8, 8192)),
+			newTestRebalanceHost("h3", 16, 16384, newTestRebalanceGuest("g5", 10, 10240)),
+		}
+		plan := planRebalance(hosts, metric, 0, 0)
+		if len(plan.Moves) != 2 {
+			t.Fatalf("want 2 moves, got %#v", plan.Moves)
+		}
+		if len(plan.Unplaced) != 2 || plan.Unplaced[0] != "gpu" || plan.Unplaced[1] != "g3" {
+			t.Errorf("unexpected unplaced %v", plan.Unplaced)
+		}
+		for _, h := range hosts[1:] {
+			if h.CpuAlloc > h.CpuTotal || h.MemAlloc > h.MemTotal {
+				t.Errorf("host %s overcommitted", h.Name)
+			}
+		}
+	})
+	t.Run("compatibility", func(t *testing.T) {
+		local := newTestRebalanceGuest("g1", 4, 4096)
+		local.LocalStorage = true
+		shared := newTestRebalanceGuest("g2", 4, 4096)
+		shared.Storages = []string{"s1"}
+		src := newTestRebalanceHost("h1", 16, 16384, local, shared,
+			newTestRebalanceGuest("g3", 4, 4096),
+			newTestRebalanceGuest("g4", 4, 4096),
+		)
+		src.CpuDesc = "Xeon"
+		src.LocalStorage = true
+		src.Storages = []string{"s1"}
+		otherCpu := newTestRebalanceHost("h2", 16, 16384)
+		otherCpu.CpuDesc = "EPYC"
+		otherCpu.LocalStorage = true
+		otherCpu.Storages = []string{"s1"}
+		noStorage := newTestRebalanceHost("h3", 16, 16384)
+		noStorage.CpuDesc = "Xeon"
+		hosts := []*sRebalanceHost{src, otherCpu, noStorage}
+		plan := planRebalance(hosts, metric, 0.1, 10)
+		if len(plan.Moves) != 2 {
+			t.Fatalf("want 2 moves, got %#v", plan.Moves)
+		}
+		for _, move := range plan.Moves {
+			if move.DstHost != "h3" || move.Guest == "g1" || move.Guest == "g2" {
+				t.Errorf("moved to an incompatible host: %#v", move)
+			}
+		}
+	})
+	t.Run("incoming migration", func(t *testing.T) {
+		hosts := []*sRebalanceHost{
+			newTestRebalanceHost("h1", 16, 16384,
+				newTestRebalanceGuest("g1", 4, 4096),
+				newTestRebalanceGuest("g2", 4, 4096),
+				newTestRebalanceGuest("g3", 4, 4096),
+				newTestRebalanceGuest("g4", 4, 4096),
+			),
+			newTestRebalanceHost("h2", 16, 16384),
+		}
+		hosts[1].reserveGuest(newTestRebalanceGuest("g5", 8, 8192))
+		plan := planRebalance(hosts, metric, 0.1, 10)
+		if len(plan.Moves) != 1 {
+			t.Fatalf("want 1 move, got %#v", plan.Moves)
+		}
+		if len(hosts[1].Guests) != 1 {
+			t.Errorf("migrating guest should not be movable: %#v", hosts[1].Guests)
+		}
+	})
+}
@@ -114,6 +114,7 @@ type IGuestDriver interface {
 	CheckDiskTemplateOnStorage(ctx context.Context, userCred mcclient.TokenCredential, imageId string, format string, storageId string, task taskman.ITask) error
 
 	GetGuestVncInfo(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, host *SHost) (*jsonutils.JSONDict, error)
+	GetGuestSerialInfo(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, host *SHost) (*jsonutils.JSONDict, error)
 
 	RequestAttachDisk(ctx context.Context, guest *SGuest, task taskman.ITask) error
 	RequestDetachDisk(ctx context.Context, guest *SGuest, task taskman.ITask) error
@@ -78,6 +78,9 @@ type SGuestnetwork struct {
 	Ifname    string `width:"16" charset:"ascii" nullable:"true" list:"user" update:"user"` // Column(VARCHAR(16, charset='ascii'), nullable=True)
 
 	TeamWith string `width:"32" charset:"ascii" nullable:"false" list:"user"`
+
+	// SR-IOV virtual function passed through to the guest instead of a tap device
+	IsolatedDeviceId string `width:"36" charset:"ascii" nullable:"true" list:"user"`
 }
 
 func (joint *SGuestnetwork) Master() db.IStandaloneModel {
@@ -263,6 +266,18 @@ func (self *SGuestnetwork) GetTeamGuestnetwork() (*SGuestnetwork, error) {
 	return nil, nil
 }
 
+func (self *SGuestnetwork) getSriovDevice() *SIsolatedDevice {
+	if len(self.IsolatedDeviceId) == 0 {
+		return nil
+	}
+	devObj, err := IsolatedDeviceManager.FetchById(self.IsolatedDeviceId)
+	if err != nil {
+		log.Errorf("fetch SR-IOV virtual function %s: %s", self.IsolatedDeviceId, err)
+		return nil
+	}
+	return devObj.(*SIsolatedDevice)
+}
+
 func (self *SGuestnetwork) getJsonDescAtBaremetal(host *SHost) jsonutils.JSONObject {
 	network := self.GetNetwork()
 	hostwire := host.getHostwireOfIdAndMac(network.WireId, self.MacAddr)
@@ -325,6 +340,17 @@ func (self *SGuestnetwork) getGeneralJsonDesc(host *SHost, network *SNetwork, ho
 	desc.Add(jsonutils.NewString(hostwire.WireId), "wire_id")
 	desc.Add(jsonutils.NewInt(int64(network.VlanId)), "vlan")
 	desc.Add(jsonutils.NewString(hostwire.Interface), "interface")
+	if wire := network.GetWire(); wire != nil && wire.IsOverlay() {
+		desc.Add(jsonutils.NewInt(int64(wire.Vni)), "vni")
+		desc.Add(jsonutils.NewString(wire.VpcId), "vpc_id")
+		if vpc := wire.getVpc(); vpc != nil {
+			desc.Add(jsonutils.NewInt(int64(vpc.Vni)), "vpc_vni")
+		}
+		desc.Add(jsonutils.NewString(host.AccessIp), "vtep")
+	}
+	if dev := self.getSriovDevice(); dev != nil {
+		desc.Add(dev.getDesc(), "sriov_device")
+	}
 	desc.Add(jsonutils.NewInt(int64(self.getBandwidth())), "bw")
 	desc.Add(jsonutils.NewInt(int64(self.Index)), "index")
 	vips := self.GetVirtualIPs()
@@ -406,6 +432,12 @@ func (manager *SGuestnetworkManager) DeleteGuestNics(ctx context.Context, userCr
 			log.Errorf("%s", err)
 		}
 		gn.LogDetachEvent(ctx, userCred, guest, net)
+		if len(gn.IsolatedDeviceId) > 0 {
+			err := IsolatedDeviceManager.releaseSriovVF(ctx, userCred, guest, gn.IsolatedDeviceId)
+			if err != nil {
+				log.Errorf("release SR-IOV virtual function %s: %s", gn.IsolatedDeviceId, err)
+			}
+		}
 		if reserve && regutils.MatchIP4Addr(gn.IpAddr) {
 			ReservedipManager.ReserveIP(userCred, net, gn.IpAddr, "Delete to reserve")
 		}
@@ -165,6 +165,7 @@ const (
 	HYPERVISOR_HUAWEI    = api.HYPERVISOR_HUAWEI
 	HYPERVISOR_OPENSTACK = api.HYPERVISOR_OPENSTACK
 	HYPERVISOR_UCLOUD    = api.HYPERVISOR_UCLOUD
+	HYPERVISOR_MOCK      = api.HYPERVISOR_MOCK
 
 	//	HYPERVISOR_DEFAULT = HYPERVISOR_KVM
 	HYPERVISOR_DEFAULT = HYPERVISOR_KVM
@@ -175,6 +176,9 @@ const (
 
 	VM_METADATA_APP_TAGS      = "app_tags"
 	VM_METADATA_CREATE_PARAMS = "create_params"
+	VM_METADATA_V2V_SOURCE    = "__v2v_source"
+	VM_METADATA_V2V_TARGET    = "__v2v_target"
+	VM_METADATA_HA_RUNNING    = "__ha_running"
 )
 
 var VM_RUNNING_STATUS = api.VM_RUNNING_STATUS
@@ -221,6 +225,11 @@ type SGuest struct {
 	DisableDelete    tristate.TriState `nullable:"false" default:"true" list:"user" update:"user" create:"optional"`           // Column(Boolean, nullable=False, default=True)
 	ShutdownBehavior string            `width:"16" charset:"ascii" default:"stop" list:"user" update:"user" create:"optional"` // Column(VARCHAR(16, charset='ascii'), default=SHUTDOWN_STOP)
 
+	// restart the guest on another host sharing its storage when its host fails
+	HaEnabled bool `nullable:"false" default:"false" list:"user" update:"user" create:"optional"`
+	// preemptible guests are evicted when normal guests don't fit
+	PriorityClass string `width:"16" charset:"ascii" nullable:"false" default:"normal" list:"user" create:"optional"`
+
 	KeypairId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"` // Column(VARCHAR(36, charset='ascii'), nullable=True)
 
 	HostId       string `width:"36" charset:"ascii" nullable:"true" list:"admin" get:"admin" index:"true"` // Column(VARCHAR(36, charset='ascii'), nullable=True)
@@ -773,6 +782,10 @@ func (self *SGuest) ValidateUpdateData(ctx context.Context, userCred mcclient.To
 		}
 	}
 
+	if jsonutils.QueryBoolean(data, "ha_enabled", false) && self.GetHypervisor() != HYPERVISOR_KVM {
+		return nil, httperrors.NewInputParameterError("HA is not supported for hypervisor %s", self.GetHypervisor())
+	}
+
 	if vmemSize > 0 || vcpuCount > 0 {
 		if !utils.IsInStringArray(self.Status, []string{VM_READY}) && self.GetHypervisor() != HYPERVISOR_CONTAINER {
 			return nil, httperrors.NewInvalidStatusError("Cannot modify Memory and CPU in status %s", self.Status)
@@ -844,7 +857,14 @@ func (manager *SGuestManager) ValidateCreateData(ctx context.Context, userCred m
 			return nil, httperrors.NewBadRequestError("Snapshot error: disk index 0 but disk type is %s", diskConfig.DiskType)
 		}
 
-		if len(diskConfig.ImageId) == 0 && len(diskConfig.SnapshotId) == 0 && !data.Contains("cdrom") {
+		if len(input.V2VSource) > 0 {
+			if !db.IsAdminAllowCreate(userCred, manager) {
+				return nil, httperrors.NewForbiddenError("Only system admin allowed to import guest")
+			}
+			if source := manager.FetchGuestById(input.V2VSource); source == nil {
+				return nil, httperrors.NewResourceNotFoundError("Guest %s not found", input.V2VSource)
+			}
+		} else if len(diskConfig.ImageId) == 0 && len(diskConfig.SnapshotId) == 0 && !data.Contains("cdrom") {
 			return nil, httperrors.NewBadRequestError("Miss operating system???")
 		}
 
@@ -869,7 +889,11 @@ func (manager *SGuestManager) ValidateCreateData(ctx context.Context, userCred m
 		}
 
 		if len(imgProperties) == 0 {
-			imgProperties = map[string]string{"os_type": "Linux"}
+			if len(input.OsType) > 0 {
+				imgProperties = map[string]string{"os_type": input.OsType}
+			} else {
+				imgProperties = map[string]string{"os_type": "Linux"}
+			}
 		}
 
 		osType := input.OsType
@@ -895,6 +919,23 @@ func (manager *SGuestManager) ValidateCreateData(ctx context.Context, userCred m
 	}
 
 	hypervisor = input.Hypervisor
+	if input.HaEnabled && hypervisor != HYPERVISOR_KVM {
+		return nil, httperrors.NewInputParameterError("HA is not supported for hypervisor %s", hypervisor)
+	}
+	switch input.PriorityClass {
+	case "":
+		input.PriorityClass = api.GUEST_PRIORITY_CLASS_NORMAL
+	case api.GUEST_PRIORITY_CLASS_NORMAL:
+	case api.GUEST_PRIORITY_CLASS_PREEMPTIBLE:
+		if !utils.IsInStringArray(hypervisor, []string{HYPERVISOR_KVM, HYPERVISOR_ESXI}) {
+			return nil, httperrors.NewInputParameterError("preemptible is not supported for hypervisor %s", hypervisor)
+		}
+		if input.HaEnabled {
+			return nil, httperrors.NewInputParameterError("preemptible server can't be HA enabled")
+		}
+	default:
+		return nil, httperrors.NewInputParameterError("invalid priority_class %s", input.PriorityClass)
+	}
 	if hypervisor != HYPERVISOR_CONTAINER {
 		// support sku here
 		var sku *SServerSku
@@ -1015,7 +1056,11 @@ func (manager *SGuestManager) ValidateCreateData(ctx context.Context, userCred m
 		if err != nil {
 			return nil, httperrors.NewInputParameterError("parse network description error %s", err)
 		}
-		err = isValidNetworkInfo(userCred, netConfig)
+		if len(input.V2VSource) > 0 {
+			err = isValidV2VNetworkInfo(userCred, input.V2VSource, netConfig)
+		} else {
+			err = isValidNetworkInfo(userCred, netConfig)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -1238,6 +1283,11 @@ func (guest *SGuest) PostCreate(ctx context.Context, userCred mcclient.TokenCred
 		}
 	}
 	guest.setApptags(ctx, appTags, userCred)
+	if v2vSource, _ := data.GetString("__v2v_source__"); len(v2vSource) > 0 {
+		guest.SetMetadata(ctx, VM_METADATA_V2V_SOURCE, v2vSource, userCred)
+		// the disks are imported only once, clones start from scratch
+		data.(*jsonutils.JSONDict).Remove("__v2v_source__")
+	}
 	guest.SetCreateParams(ctx, userCred, data)
 	osProfileJson, _ := data.Get("__os_profile__")
 	if osProfileJson != nil {
@@ -2386,6 +2436,10 @@ type SGuestCountStat struct {
 	TotalBackupCpuCount   int
 	TotalBackupMemSize    int
 	TotalBackupDiskSize   int
+
+	PreemptibleGuestCount int
+	PreemptibleCpuCount   int
+	PreemptibleMemSize    int
 }
 
 func totalGuestResourceCount(
@@ -2430,6 +2484,11 @@ func totalGuestResourceCount(
 		"vcpu_count",
 		"vmem_size",
 	).IsNotEmpty("backup_host_id").SubQuery()
+	guestPreemptibleSubQuery := GuestManager.Query(
+		"id",
+		"vcpu_count",
+		"vmem_size",
+	).Equals("priority_class", api.GUEST_PRIORITY_CLASS_PREEMPTIBLE).SubQuery()
 
 	q := guests.Query(sqlchemy.COUNT("total_guest_count"),
 		sqlchemy.SUM("total_cpu_count", guests.Field("vcpu_count")),
@@ -2440,9 +2499,13 @@ func totalGuestResourceCount(
 		sqlchemy.SUM("total_backup_cpu_count", guestBackupSubQuery.Field("vcpu_count")),
 		sqlchemy.SUM("total_backup_mem_size", guestBackupSubQuery.Field("vmem_size")),
 		sqlchemy.COUNT("total_backup_guest_count", guestBackupSubQuery.Field("id")),
+		sqlchemy.SUM("preemptible_cpu_count", guestPreemptibleSubQuery.Field("vcpu_count")),
+		sqlchemy.SUM("preemptible_mem_size", guestPreemptibleSubQuery.Field("vmem_size")),
+		sqlchemy.COUNT("preemptible_guest_count", guestPreemptibleSubQuery.Field("id")),
 	)
 
 	q = q.LeftJoin(guestBackupSubQuery, sqlchemy.Equals(guestBackupSubQuery.Field("id"), guests.Field("id")))
+	q = q.LeftJoin(guestPreemptibleSubQuery, sqlchemy.Equals(guestPreemptibleSubQuery.Field("id"), guests.Field("id")))
 
 	q = q.LeftJoin(diskSubQuery, sqlchemy.Equals(diskSubQuery.Field("guest_id"), guests.Field("id")))
 	q = q.LeftJoin(diskBackupSubQuery, sqlchemy.Equals(diskBackupSubQuery.Field("guest_id"), guests.Field("id")))
@@ -2513,12 +2576,12 @@ func (self *SGuest) attach2NetworkDesc(ctx context.Context, userCred mcclient.To
 	if len(netConfig.Network) > 0 {
 		gns, err1 = self.attach2NamedNetworkDesc(ctx, userCred, host, netConfig, pendingUsage)
 		if err1 == nil {
-			return gns, nil
+			return self.attachSriovVFs(ctx, userCred, host, netConfig, gns)
 		}
 	}
 	gns, err2 = self.attach2RandomNetwork(ctx, userCred, host, netConfig, pendingUsage)
 	if err2 == nil {
-		return gns, nil
+		return self.attachSriovVFs(ctx, userCred, host, netConfig, gns)
 	}
 	if err1 != nil {
 		return nil, fmt.Errorf("%s/%s", err1, err2)
@@ -2527,6 +2590,52 @@ func (self *SGuest) attach2NetworkDesc(ctx context.Context, userCred mcclient.To
 	}
 }
 
+func (self *SGuest) attachSriovVFs(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, netConfig *api.NetworkConfig, gns []SGuestnetwork) ([]SGuestnetwork, error) {
+	if !netConfig.Sriov {
+		return gns, nil
+	}
+	for i := range gns {
+		err := self.attachSriovVF(ctx, userCred, host, &gns[i])
+		if err != nil {
+			GuestnetworkManager.DeleteGuestNics(ctx, userCred, gns, false)
+			return nil, err
+		}
+	}
+	return gns, nil
+}
+
+func (self *SGuest) attachSriovVF(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, gn *SGuestnetwork) error {
+	if self.Hypervisor != HYPERVISOR_KVM {
+		return fmt.Errorf("SR-IOV nic is not supported by hypervisor %s", self.Hypervisor)
+	}
+	network := gn.GetNetwork()
+	if wire := network.GetWire(); wire != nil && wire.IsOverlay() {
+		return fmt.Errorf("SR-IOV nic is not supported on overlay wire %s", wire.Name)
+	}
+
+	lockman.LockObject(ctx, host)
+	defer lockman.ReleaseObject(ctx, host)
+
+	devs, err := IsolatedDeviceManager.findHostUnusedSriovVFs(host.Id, network.WireId)
+	if err != nil {
+		return err
+	}
+	if len(devs) == 0 {
+		return fmt.Errorf("No free SR-IOV virtual function on host %s for wire %s", host.Name, network.WireId)
+	}
+	dev := &devs[0]
+	err = self.attachIsolatedDevice(ctx, userCred, dev)
+	if err != nil {
+		return err
+	}
+	_, err = db.Update(gn, func() error {
+		gn.IsolatedDeviceId = dev.Id
+		gn.Driver = "vfio-pci"
+		return nil
+	})
+	return err
+}
+
 func (self *SGuest) attach2NamedNetworkDesc(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, netConfig *api.NetworkConfig, pendingUsage quotas.IQuota) ([]SGuestnetwork, error) {
 	driver := self.GetDriver()
 	net, nicConfs, allocDir := driver.GetNamedNetworkConfiguration(self, userCred, host, netConfig)
@@ -2921,6 +3030,10 @@ func (self *SGuest) GetDeployConfigOnHost(ctx context.Context, userCred mcclient
 
 	config.Add(jsonutils.NewString(onFinish), "on_finish")
 
+	if deployAction == "create" && len(self.GetMetadata(VM_METADATA_V2V_SOURCE, nil)) > 0 && !self.IsWindows() {
+		config.Add(jsonutils.JSONTrue, "inject_virtio_drivers")
+	}
+
 	if deployAction == "create" && !utils.IsInStringArray(self.Hypervisor, []string{HYPERVISOR_KVM, HYPERVISOR_BAREMETAL, HYPERVISOR_CONTAINER, HYPERVISOR_ESXI, HYPERVISOR_XEN}) {
 		nets, err := self.GetNetworks("")
 		if err != nil || len(nets) == 0 {
@@ -2931,7 +3044,7 @@ func (self *SGuest) GetDeployConfigOnHost(ctx context.Context, userCred mcclient
 		registerVpcId := vpc.ExternalId
 		externalVpcId := vpc.ExternalId
 		switch self.Hypervisor {
-		case HYPERVISOR_ALIYUN, HYPERVISOR_HUAWEI:
+		case HYPERVISOR_ALIYUN, HYPERVISOR_HUAWEI, HYPERVISOR_MOCK:
 			break
 		case HYPERVISOR_AWS:
 			loginUser := cloudinit.NewUser(VM_AWS_DEFAULT_LOGIN_USER)
@@ -3974,6 +4087,7 @@ func (self *SGuest) ToSchedDesc() *schedapi.ScheduleInput {
 		config.HostId = self.HostId
 	}
 	config.Project = self.ProjectId
+	config.PriorityClass = self.PriorityClass
 	/*tags := self.GetApptags()
 	for i := 0; i < len(tags); i++ {
 		desc.Set(tags[i], jsonutils.JSONTrue)
@@ -4119,6 +4233,7 @@ func (self *SGuest) ToCreateInput(userCred mcclient.TokenCredential) *api.Server
 	userInput.DisableDelete = genInput.DisableDelete
 	userInput.ShutdownBehavior = genInput.ShutdownBehavior
 	userInput.IsSystem = genInput.IsSystem
+	userInput.HaEnabled = genInput.HaEnabled
 	userInput.SecgroupId = genInput.SecgroupId
 	userInput.KeypairId = genInput.KeypairId
 	userInput.Project = genInput.Project
@@ -4151,10 +4266,12 @@ func (self *SGuest) toCreateInput() *api.ServerCreateInput {
 	r.ShutdownBehavior = self.ShutdownBehavior
 	// ignore r.DeployConfigs
 	r.IsSystem = self.IsSystem
+	r.HaEnabled = self.HaEnabled
 	r.SecgroupId = self.SecgrpId
 
 	r.ServerConfigs = new(api.ServerConfigs)
 	r.Hypervisor = self.Hypervisor
+	r.PriorityClass = self.PriorityClass
 	r.InstanceType = self.InstanceType
 	r.Project = self.ProjectId
 	r.Count = 1
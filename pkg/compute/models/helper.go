@@ -125,6 +125,12 @@ func ValidateScheduleCreateData(ctx context.Context, userCred mcclient.TokenCred
 			input.Schedtags = tags
 		}
 
+		for _, spread := range input.Spreads {
+			if len(spread.Topology) == 0 || spread.MaxPerDomain <= 0 {
+				return nil, httperrors.NewInputParameterError("invalid spread %s:%d", spread.Topology, spread.MaxPerDomain)
+			}
+		}
+
 		if input.PreferWire != "" {
 			wireStr := input.PreferWire
 			wireObj, err := WireManager.FetchById(wireStr)
@@ -0,0 +1,216 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/baremetal/utils/ipmitool"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+const (
+	// seconds to wait for the chassis of a fenced host to report power off
+	HOST_FENCE_TIMEOUT_SECONDS = 60
+	// seconds between two checks of the chassis power of a fenced host
+	HOST_FENCE_POLL_SECONDS = 5
+)
+
+var HOST_FAILOVER_STATUS = []string{api.HOST_FENCING, api.HOST_DOWN, api.HOST_FENCE_FAILED}
+
+// FailoverDetectionTask starts a failover for every KVM host that has stopped
+// pinging the region for longer than the failover delay
+func (manager *SHostManager) FailoverDetectionTask(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	deadline := time.Now().Add(-1 * time.Duration(options.Options.HostFailoverDelaySeconds) * time.Second)
+
+	q := manager.Query().Equals("host_status", HOST_OFFLINE).
+		Equals("host_type", HOST_TYPE_HYPERVISOR).
+		NotIn("status", HOST_FAILOVER_STATUS)
+	q = q.Filter(sqlchemy.OR(sqlchemy.IsNull(q.Field("last_ping_at")),
+		sqlchemy.LT(q.Field("last_ping_at"), deadline)))
+
+	hosts := make([]SHost, 0)
+	err := db.FetchModelObjects(manager, q, &hosts)
+	if err != nil {
+		log.Errorf("FailoverDetectionTask fetch hosts: %v", err)
+		return
+	}
+	for i := range hosts {
+		err := hosts[i].StartHostFailoverTask(ctx, userCred, false, "")
+		if err != nil {
+			log.Errorf("start failover of host %s: %v", hosts[i].Name, err)
+		}
+	}
+}
+
+func (self *SHost) StartHostFailoverTask(ctx context.Context, userCred mcclient.TokenCredential, force bool, parentTaskId string) error {
+	self.SetStatus(userCred, api.HOST_FENCING, "")
+	params := jsonutils.NewDict()
+	if force {
+		params.Set("force", jsonutils.JSONTrue)
+	}
+	task, err := taskman.TaskManager.NewTask(ctx, "HostFailoverTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SHost) getIpmiExecutor() (ipmitool.IPMIExecutor, error) {
+	info, ok := self.IpmiInfo.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, fmt.Errorf("no ipmi information")
+	}
+	ipAddr, _ := info.GetString("ip_addr")
+	username, _ := info.GetString("username")
+	password, _ := info.GetString("password")
+	if len(ipAddr) == 0 || len(username) == 0 || len(password) == 0 {
+		return nil, fmt.Errorf("incomplete ipmi information")
+	}
+	password, err := utils.DescryptAESBase64(self.Id, password)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt ipmi password: %v", err)
+	}
+	return ipmitool.NewLanPlusIPMI(ipAddr, username, password), nil
+}
+
+// PowerOffByIpmi asks the chassis of the host to power off through IPMI, so
+// that its guests can not write to shared storage any longer. It tells whether
+// the chassis is off already.
+func (self *SHost) PowerOffByIpmi() (bool, error) {
+	ipmi, err := self.getIpmiExecutor()
+	if err != nil {
+		return false, err
+	}
+	status, err := ipmitool.GetChassisPowerStatus(ipmi)
+	if err != nil {
+		return false, fmt.Errorf("get chassis power status: %v", err)
+	}
+	if status == "off" {
+		return true, nil
+	}
+	err = ipmitool.DoHardShutdown(ipmi)
+	if err != nil {
+		return false, fmt.Errorf("power off: %v", err)
+	}
+	return false, nil
+}
+
+// IsPowerOffByIpmi tells whether the chassis of the host reports power off
+func (self *SHost) IsPowerOffByIpmi() (bool, error) {
+	ipmi, err := self.getIpmiExecutor()
+	if err != nil {
+		return false, err
+	}
+	status, err := ipmitool.GetChassisPowerStatus(ipmi)
+	if err != nil {
+		return false, fmt.Errorf("get chassis power status: %v", err)
+	}
+	return status == "off", nil
+}
+
+// isHaRestartable tells whether the guest was running with HA enabled when its
+// host went offline and can be started on another host sharing its storage
+func (self *SGuest) isHaRestartable() error {
+	haRunning := utils.ToBool(self.GetMetadata(VM_METADATA_HA_RUNNING, nil))
+	storageTypes := make(map[string]string)
+	for _, guestDisk := range self.GetDisks() {
+		storageType := ""
+		if storage := guestDisk.GetDisk().GetStorage(); storage != nil {
+			storageType = storage.StorageType
+		}
+		storageTypes[guestDisk.DiskId] = storageType
+	}
+	return self.checkHaRestartable(haRunning, len(self.GetIsolatedDevices()), storageTypes)
+}
+
+// checkHaRestartable checks the guest with its storage type of each disk id
+func (self *SGuest) checkHaRestartable(haRunning bool, isolatedDevices int, storageTypes map[string]string) error {
+	if !self.HaEnabled {
+		return fmt.Errorf("ha is not enabled")
+	}
+	if self.GetHypervisor() != HYPERVISOR_KVM {
+		return fmt.Errorf("hypervisor %s", self.GetHypervisor())
+	}
+	// a guest failed to restart is restarted again by the next failover
+	if !utils.IsInStringArray(self.Status, []string{VM_UNKNOWN, VM_MIGRATE_FAILED}) {
+		return fmt.Errorf("status %s", self.Status)
+	}
+	if !haRunning {
+		return fmt.Errorf("not running when the host went offline")
+	}
+	if len(self.BackupHostId) > 0 {
+		return fmt.Errorf("guest has backup")
+	}
+	if isolatedDevices > 0 {
+		return fmt.Errorf("guest has isolated devices")
+	}
+	for diskId, storageType := range storageTypes {
+		if len(storageType) == 0 || utils.IsInStringArray(storageType, STORAGE_LOCAL_TYPES) {
+			return fmt.Errorf("disk %s is not on shared storage", diskId)
+		}
+	}
+	return nil
+}
+
+// GetHaRestartGuests returns the guests of a failed host to be restarted on
+// other hosts
+func (self *SHost) GetHaRestartGuests() []SGuest {
+	guests := make([]SGuest, 0)
+	for _, guest := range self.GetGuests() {
+		err := guest.isHaRestartable()
+		if err != nil {
+			if guest.HaEnabled {
+				log.Warningf("failover of host %s skip guest %s: %v", self.Name, guest.Name, err)
+			}
+			continue
+		}
+		guests = append(guests, guest)
+	}
+	return guests
+}
+
+func (self *SHost) AllowPerformFailover(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "failover")
+}
+
+// PerformFailover fences an offline KVM host and restarts its HA guests on
+// other hosts, force restarts them even if the host can not be fenced
+func (self *SHost) PerformFailover(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.HostType != HOST_TYPE_HYPERVISOR {
+		return nil, httperrors.NewUnsupportOperationError("Failover is not supported for host type %s", self.HostType)
+	}
+	if self.HostStatus != HOST_OFFLINE {
+		return nil, httperrors.NewInvalidStatusError("Cannot failover host in host status %s", self.HostStatus)
+	}
+	if self.Status == api.HOST_FENCING {
+		return nil, httperrors.NewInvalidStatusError("Host %s is being fenced", self.Name)
+	}
+	force := jsonutils.QueryBoolean(data, "force", false)
+	return nil, self.StartHostFailoverTask(ctx, userCred, force, "")
+}
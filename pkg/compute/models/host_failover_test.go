@@ -0,0 +1,130 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSGuest_checkHaRestartable(t *testing.T) {
+	newGuest := func() *SGuest {
+		guest := &SGuest{HaEnabled: true}
+		guest.Hypervisor = HYPERVISOR_KVM
+		guest.Status = VM_UNKNOWN
+		return guest
+	}
+	shared := map[string]string{"d1": STORAGE_RBD}
+	tests := []struct {
+		name            string
+		guest           func() *SGuest
+		haRunning       bool
+		isolatedDevices int
+		storageTypes    map[string]string
+		wantErr         string
+	}{
+		{
+			name:         "restartable",
+			guest:        newGuest,
+			haRunning:    true,
+			storageTypes: shared,
+		},
+		{
+			name: "failed restart is restarted again",
+			guest: func() *SGuest {
+				guest := newGuest()
+				guest.Status = VM_MIGRATE_FAILED
+				return guest
+			},
+			haRunning:    true,
+			storageTypes: shared,
+		},
+		{
+			name: "ha disabled",
+			guest: func() *SGuest {
+				guest := newGuest()
+				guest.HaEnabled = false
+				return guest
+			},
+			haRunning:    true,
+			storageTypes: shared,
+			wantErr:      "ha is not enabled",
+		},
+		{
+			name: "restarted already",
+			guest: func() *SGuest {
+				guest := newGuest()
+				guest.Status = VM_READY
+				return guest
+			},
+			haRunning:    true,
+			storageTypes: shared,
+			wantErr:      "status",
+		},
+		{
+			name:         "not running when the host went offline",
+			guest:        newGuest,
+			storageTypes: shared,
+			wantErr:      "not running",
+		},
+		{
+			name: "backup guest",
+			guest: func() *SGuest {
+				guest := newGuest()
+				guest.BackupHostId = "backup"
+				return guest
+			},
+			haRunning:    true,
+			storageTypes: shared,
+			wantErr:      "backup",
+		},
+		{
+			name:            "isolated devices",
+			guest:           newGuest,
+			haRunning:       true,
+			isolatedDevices: 1,
+			storageTypes:    shared,
+			wantErr:         "isolated devices",
+		},
+		{
+			name:         "local disk",
+			guest:        newGuest,
+			haRunning:    true,
+			storageTypes: map[string]string{"d1": STORAGE_RBD, "d2": STORAGE_LOCAL},
+			wantErr:      "disk d2",
+		},
+		{
+			name:         "disk without storage",
+			guest:        newGuest,
+			haRunning:    true,
+			storageTypes: map[string]string{"d1": ""},
+			wantErr:      "disk d1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.guest().checkHaRestartable(tt.haRunning, tt.isolatedDevices, tt.storageTypes)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v doesn't contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -67,6 +67,7 @@ const (
 	HOST_TYPE_HUAWEI    = api.HOST_TYPE_HUAWEI
 	HOST_TYPE_OPENSTACK = api.HOST_TYPE_OPENSTACK
 	HOST_TYPE_UCLOUD    = api.HOST_TYPE_UCLOUD
+	HOST_TYPE_MOCK      = api.HOST_TYPE_MOCK
 
 	HOST_TYPE_DEFAULT = HOST_TYPE_HYPERVISOR
 
@@ -1751,6 +1752,50 @@ func (self *SHost) Attach2Wire(ctx context.Context, userCred mcclient.TokenCrede
 	return nil
 }
 
+// attachOverlayWire attaches an overlay wire through the master interface
+// of the host, whose address is the local VTEP of the VXLAN tunnels
+func (self *SHost) attachOverlayWire(ctx context.Context, userCred mcclient.TokenCredential, wire *SWire) error {
+	master := self.GetMasterHostwire()
+	if master == nil {
+		return fmt.Errorf("host %s has no master wire", self.Name)
+	}
+	if hw, _ := HostwireManager.FetchByIdsAndMac(self.Id, wire.Id, master.MacAddr); hw != nil {
+		return nil
+	}
+	hw := &SHostwire{}
+	hw.SetModelManager(HostwireManager)
+	hw.Bridge = master.Bridge
+	hw.Interface = master.Interface
+	hw.HostId = self.Id
+	hw.WireId = wire.Id
+	hw.MacAddr = master.MacAddr
+	err := HostwireManager.TableSpec().Insert(hw)
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogAttachEvent(ctx, self, wire, userCred, nil)
+	return nil
+}
+
+func (self *SHost) syncOverlayWires(ctx context.Context, userCred mcclient.TokenCredential) {
+	if self.HostType != HOST_TYPE_HYPERVISOR {
+		return
+	}
+	wires := make([]SWire, 0)
+	q := WireManager.Query().Equals("zone_id", self.ZoneId).Equals("wire_type", api.WIRE_TYPE_VXLAN)
+	err := db.FetchModelObjects(WireManager, q, &wires)
+	if err != nil {
+		log.Errorf("fetch overlay wires of zone %s fail %s", self.ZoneId, err)
+		return
+	}
+	for i := range wires {
+		err := self.attachOverlayWire(ctx, userCred, &wires[i])
+		if err != nil {
+			log.Errorf("attach overlay wire %s to host %s fail %s", wires[i].Name, self.Name, err)
+		}
+	}
+}
+
 func (self *SHost) newCloudHostWire(ctx context.Context, userCred mcclient.TokenCredential, extWire cloudprovider.ICloudWire) error {
 	wireObj, err := WireManager.FetchByExternalId(extWire.GetGlobalId())
 	if err != nil {
@@ -3053,6 +3098,9 @@ func (self *SHost) addNetif(ctx context.Context, userCred mcclient.TokenCredenti
 					return nil
 				})
 			}
+			if isMaster {
+				self.syncOverlayWires(ctx, userCred)
+			}
 		}
 	}
 	if len(ipAddr) > 0 {
@@ -3782,9 +3830,13 @@ func (self *SHost) GetShortDesc(ctx context.Context) *jsonutils.JSONDict {
 	return desc
 }
 
-func (self *SHost) MarkGuestUnknown(userCred mcclient.TokenCredential) {
+func (self *SHost) MarkGuestUnknown(ctx context.Context, userCred mcclient.TokenCredential) {
 	log.Errorln(self.GetGuests())
 	for _, guest := range self.GetGuests() {
+		if guest.HaEnabled {
+			// remember which guests to restart if the host turns out failed
+			guest.SetMetadata(ctx, VM_METADATA_HA_RUNNING, jsonutils.NewBool(guest.Status == VM_RUNNING), userCred)
+		}
 		guest.SetStatus(userCred, VM_UNKNOWN, "host offline")
 	}
 }
@@ -3809,7 +3861,7 @@ func (manager *SHostManager) PingDetectionTask(ctx context.Context, userCred mcc
 		q.Row2Struct(rows, &host)
 		host.SetModelManager(manager)
 		host.PerformOffline(ctx, userCred, nil, nil)
-		host.MarkGuestUnknown(userCred)
+		host.MarkGuestUnknown(ctx, userCred)
 	}
 }
 
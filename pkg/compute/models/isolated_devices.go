@@ -38,6 +38,8 @@ const (
 	GPU_VGA_TYPE    = api.GPU_VGA_TYPE // # for display
 	USB_TYPE        = api.USB_TYPE
 	NIC_TYPE        = api.NIC_TYPE
+	NIC_VF_TYPE     = api.NIC_VF_TYPE
+	VGPU_TYPE       = api.VGPU_TYPE
 
 	NVIDIA_VENDOR_ID = api.NVIDIA_VENDOR_ID
 	AMD_VENDOR_ID    = api.AMD_VENDOR_ID
@@ -87,6 +89,9 @@ type SIsolatedDevice struct {
 	Addr string `width:"16" charset:"ascii" nullable:"true" list:"admin" update:"admin" create:"admin_optional"` // Column(VARCHAR(16, charset='ascii'), nullable=True)
 
 	VendorDeviceId string `width:"16" charset:"ascii" nullable:"true" list:"admin" create:"admin_optional"` // Column(VARCHAR(16, charset='ascii'), nullable=True)
+
+	// # wire the physical function of a SR-IOV virtual function is connected to
+	WireId string `width:"36" charset:"ascii" nullable:"true" index:"true" list:"admin" create:"admin_optional" update:"admin"`
 }
 
 func (manager *SIsolatedDeviceManager) AllowListItems(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
@@ -148,6 +153,17 @@ func (manager *SIsolatedDeviceManager) ListItemFilter(ctx context.Context, q *sq
 	if jsonutils.QueryBoolean(query, "unused", false) {
 		q = q.IsEmpty("guest_id")
 	}
+	wireStr := jsonutils.GetAnyString(query, []string{"wire", "wire_id"})
+	if len(wireStr) > 0 {
+		wire, err := WireManager.FetchByIdOrName(userCred, wireStr)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, httperrors.NewResourceNotFoundError2(WireManager.Keyword(), wireStr)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		q = q.Equals("wire_id", wire.GetId())
+	}
 	regionStr := jsonutils.GetAnyString(query, []string{"region", "region_id"})
 	if len(regionStr) > 0 {
 		region, err := CloudregionManager.FetchByIdOrName(nil, regionStr)
@@ -236,6 +252,14 @@ func (self *SIsolatedDevice) isGpu() bool {
 	return strings.HasPrefix(self.DevType, "GPU")
 }
 
+func (self *SIsolatedDevice) isSriovVF() bool {
+	return self.DevType == NIC_VF_TYPE
+}
+
+func (self *SIsolatedDevice) isVGpu() bool {
+	return self.DevType == VGPU_TYPE
+}
+
 func (manager *SIsolatedDeviceManager) parseDeviceInfo(userCred mcclient.TokenCredential, devConfig *api.IsolatedDeviceConfig) (*api.IsolatedDeviceConfig, error) {
 	var devId, devType, devVendor string
 	var matchDev *SIsolatedDevice
@@ -277,6 +301,9 @@ func (manager *SIsolatedDeviceManager) parseDeviceInfo(userCred mcclient.TokenCr
 				return nil, fmt.Errorf("%s not valid for GPU device", devType)
 			}
 		}
+		if len(devType) > 0 && dev.isVGpu() != (devType == VGPU_TYPE) {
+			return nil, fmt.Errorf("%s not valid for %s device", devType, dev.DevType)
+		}
 	}
 	if len(devType) > 0 {
 		devConfig.DevType = devType
@@ -326,8 +353,14 @@ func (manager *SIsolatedDeviceManager) attachHostDeviceToGuestByModel(ctx contex
 	if err != nil || len(devs) == 0 {
 		return fmt.Errorf("Can't found %s model on host", host.Id)
 	}
-	selectedDev := devs[0]
-	return guest.attachIsolatedDevice(ctx, userCred, &selectedDev)
+	// vGPUs are only for vGPU requests, they can't be used as other types
+	isVGpu := devConfig.DevType == VGPU_TYPE
+	for i := range devs {
+		if devs[i].isVGpu() == isVGpu {
+			return guest.attachIsolatedDevice(ctx, userCred, &devs[i])
+		}
+	}
+	return fmt.Errorf("Can't found %s model of type %s on host %s", devConfig.Model, devConfig.DevType, host.Id)
 }
 
 func (manager *SIsolatedDeviceManager) findUnusedQuery() *sqlchemy.SQuery {
@@ -378,6 +411,41 @@ func (manager *SIsolatedDeviceManager) findHostUnusedByModel(model string, hostI
 	return devs, nil
 }
 
+func (manager *SIsolatedDeviceManager) findHostUnusedSriovVFs(hostId string, wireId string) ([]SIsolatedDevice, error) {
+	devs := make([]SIsolatedDevice, 0)
+	q := manager.findUnusedQuery()
+	q = q.Equals("dev_type", NIC_VF_TYPE).Equals("host_id", hostId).Equals("wire_id", wireId)
+	err := db.FetchModelObjects(manager, q, &devs)
+	if err != nil {
+		return nil, err
+	}
+	return devs, nil
+}
+
+func (manager *SIsolatedDeviceManager) releaseSriovVF(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, devId string) error {
+	devObj, err := manager.FetchById(devId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	dev := devObj.(*SIsolatedDevice)
+	if dev.GuestId != guest.Id {
+		return nil
+	}
+	_, err = db.Update(dev, func() error {
+		dev.GuestId = ""
+		return nil
+	})
+	if err != nil {
+		db.OpsLog.LogEvent(guest, db.ACT_GUEST_DETACH_ISOLATED_DEVICE_FAIL, dev.GetShortDesc(ctx), userCred)
+		return err
+	}
+	db.OpsLog.LogEvent(guest, db.ACT_GUEST_DETACH_ISOLATED_DEVICE, dev.GetShortDesc(ctx), userCred)
+	return nil
+}
+
 func (manager *SIsolatedDeviceManager) ReleaseDevicesOfGuest(ctx context.Context, guest *SGuest, userCred mcclient.TokenCredential) error {
 	devs := manager.findAttachedDevicesOfGuest(guest)
 	if devs == nil {
@@ -436,6 +504,9 @@ func (self *SIsolatedDevice) getDesc() *jsonutils.JSONDict {
 	desc.Add(jsonutils.NewString(self.Addr), "addr")
 	desc.Add(jsonutils.NewString(self.VendorDeviceId), "vendor_device_id")
 	desc.Add(jsonutils.NewString(self.getVendor()), "vendor")
+	if len(self.WireId) > 0 {
+		desc.Add(jsonutils.NewString(self.WireId), "wire_id")
+	}
 	return desc
 }
 
@@ -484,6 +555,10 @@ func (manager *SIsolatedDeviceManager) generateJsonDescForGuest(guest *SGuest) [
 	devs := manager.findAttachedDevicesOfGuest(guest)
 	if devs != nil && len(devs) > 0 {
 		for _, dev := range devs {
+			if dev.isSriovVF() {
+				// virtual functions are described along with guest nics
+				continue
+			}
 			ret = append(ret, dev.getDesc())
 		}
 	}
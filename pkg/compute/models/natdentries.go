@@ -0,0 +1,341 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SNatDEntryManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var NatDEntryManager *SNatDEntryManager
+
+func init() {
+	NatDEntryManager = &SNatDEntryManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SNatDEntry{},
+			"natdtables_tbl",
+			"natdentry",
+			"natdentries",
+		),
+	}
+}
+
+type SNatDEntry struct {
+	db.SVirtualResourceBase
+	SManagedResourceBase
+
+	NatgatewayId string `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required"`
+
+	ExternalIP   string `width:"17" charset:"ascii" list:"user" create:"required"`
+	ExternalPort int    `list:"user" create:"required"`
+
+	InternalIP   string `width:"17" charset:"ascii" list:"user" create:"required"`
+	InternalPort int    `list:"user" create:"required"`
+	IpProtocol   string `width:"8" charset:"ascii" list:"user" create:"required"`
+}
+
+func (man *SNatDEntryManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	q, err := man.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	userProjId := userCred.GetProjectId()
+	data := query.(*jsonutils.JSONDict)
+	return validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "natgateway", ModelKeyword: "natgateway", ProjectId: userProjId},
+	})
+}
+
+func (man *SNatDEntryManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	natV := validators.NewModelIdOrNameValidator("natgateway", "natgateway", ownerProjId)
+	keyV := map[string]validators.IValidator{
+		"external_ip":   validators.NewIPv4AddrValidator("external_ip"),
+		"external_port": validators.NewPortValidator("external_port"),
+		"internal_ip":   validators.NewIPv4AddrValidator("internal_ip"),
+		"internal_port": validators.NewPortValidator("internal_port"),
+		"ip_protocol":   validators.NewStringChoicesValidator("ip_protocol", api.NAT_PROTOCOLS),
+	}
+	if err := natV.Validate(data); err != nil {
+		return nil, err
+	}
+	for _, v := range keyV {
+		if err := v.Validate(data); err != nil {
+			return nil, err
+		}
+	}
+	nat := natV.Model.(*SNatGateway)
+	if !nat.IsManaged() {
+		return nil, httperrors.NewUnsupportOperationError("nat gateway %s is not managed by any cloud provider", nat.Name)
+	}
+	externalIp := keyV["external_ip"].(*validators.ValidatorIPv4Addr).IP.String()
+	if _, err := nat.getElasticipByIp(externalIp); err != nil {
+		return nil, err
+	}
+	externalPort := keyV["external_port"].(*validators.ValidatorRange).Value
+	ipProtocol := keyV["ip_protocol"].(*validators.ValidatorStringChoices).Value
+	q := man.Query().Equals("natgateway_id", nat.Id).Equals("external_ip", externalIp).
+		Equals("external_port", externalPort).Equals("ip_protocol", ipProtocol)
+	if q.Count() > 0 {
+		return nil, httperrors.NewDuplicateResourceError("%s %s:%d is already forwarded", ipProtocol, externalIp, externalPort)
+	}
+
+	data.Set("manager_id", jsonutils.NewString(nat.ManagerId))
+	return man.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerProjId, query, data)
+}
+
+func (self *SNatDEntry) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SVirtualResourceBase.PostCreate(ctx, userCred, ownerProjId, query, data)
+	self.SetStatus(userCred, api.NAT_STATUS_ALLOCATE, "")
+	err := self.StartNatDEntryCreateTask(ctx, userCred, "")
+	if err != nil {
+		self.SetStatus(userCred, api.NAT_STATUS_CREATE_FAILED, err.Error())
+	}
+}
+
+func (self *SNatDEntry) StartNatDEntryCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "NatDEntryCreateTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SNatDEntry) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	log.Infof("natdentry delete do nothing")
+	return nil
+}
+
+func (self *SNatDEntry) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return self.StartNatDEntryDeleteTask(ctx, userCred, "")
+}
+
+func (self *SNatDEntry) StartNatDEntryDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "NatDEntryDeleteTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	self.SetStatus(userCred, api.NAT_STATUS_DELETING, "")
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SNatDEntry) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SNatDEntry) GetNatgateway() (*SNatGateway, error) {
+	nat, err := NatGatewayManager.FetchById(self.NatgatewayId)
+	if err != nil {
+		return nil, err
+	}
+	return nat.(*SNatGateway), nil
+}
+
+// GetINatDEntry finds the cloud dnat entry by the external id
+func (self *SNatDEntry) GetINatDEntry() (cloudprovider.ICloudNatDEntry, error) {
+	nat, err := self.GetNatgateway()
+	if err != nil {
+		return nil, err
+	}
+	inat, err := nat.GetINatGateway()
+	if err != nil {
+		return nil, err
+	}
+	ientries, err := inat.GetINatDEntries()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ientries {
+		if ientries[i].GetGlobalId() == self.ExternalId {
+			return ientries[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+// GetDNatRule builds the rule which is used to create the cloud dnat entry
+func (self *SNatDEntry) GetDNatRule() (*cloudprovider.SNatDRule, error) {
+	nat, err := self.GetNatgateway()
+	if err != nil {
+		return nil, err
+	}
+	eip, err := nat.getElasticipByIp(self.ExternalIP)
+	if err != nil {
+		return nil, err
+	}
+	return self.newDNatRule(eip.ExternalId), nil
+}
+
+func (self *SNatDEntry) newDNatRule(eipExtId string) *cloudprovider.SNatDRule {
+	return &cloudprovider.SNatDRule{
+		Name:         self.Name,
+		Protocol:     self.IpProtocol,
+		ExternalIP:   self.ExternalIP,
+		ExternalIPID: eipExtId,
+		ExternalPort: self.ExternalPort,
+		InternalIP:   self.InternalIP,
+		InternalPort: self.InternalPort,
+	}
+}
+
+func (self *SNatDEntry) getMoreDetails(extra *jsonutils.JSONDict) *jsonutils.JSONDict {
+	nat, err := self.GetNatgateway()
+	if err != nil {
+		log.Errorf("natdentry %s(%s): fetch natgateway (%s) error: %s", self.Name, self.Id, self.NatgatewayId, err)
+		return extra
+	}
+	extra.Set("natgateway", jsonutils.NewString(nat.Name))
+	info := nat.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra
+}
+
+func (self *SNatDEntry) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SVirtualResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	return self.getMoreDetails(extra)
+}
+
+func (self *SNatDEntry) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*jsonutils.JSONDict, error) {
+	extra, err := self.SVirtualResourceBase.GetExtraDetails(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	return self.getMoreDetails(extra), nil
+}
+
+func (man *SNatDEntryManager) SyncNatDEntries(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, nat *SNatGateway, extEntries []cloudprovider.ICloudNatDEntry) compare.SyncResult {
+	lockman.LockClass(ctx, man, man.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, man, man.GetOwnerId(userCred))
+
+	syncResult := compare.SyncResult{}
+
+	entries := make([]SNatDEntry, 0)
+	q := man.Query().Equals("natgateway_id", nat.Id)
+	if err := db.FetchModelObjects(man, q, &entries); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+	dbEntries := make([]SNatDEntry, 0, len(entries))
+	for i := range entries {
+		// entries being created have no external id yet and should not be removed
+		if len(entries[i].ExternalId) > 0 {
+			dbEntries = append(dbEntries, entries[i])
+		} else if entries[i].Status != api.NAT_STATUS_ALLOCATE {
+			log.Warningf("dnat entry %s(%s) of nat gateway %s has no external id, status %s, skip syncing", entries[i].Name, entries[i].Id, nat.Name, entries[i].Status)
+		}
+	}
+
+	removed := make([]SNatDEntry, 0)
+	commondb := make([]SNatDEntry, 0)
+	commonext := make([]cloudprovider.ICloudNatDEntry, 0)
+	added := make([]cloudprovider.ICloudNatDEntry, 0)
+	if err := compare.CompareSets(dbEntries, extEntries, &removed, &commondb, &commonext, &added); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err := removed[i].RealDelete(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	}
+
+	for i := 0; i < len(commondb); i += 1 {
+		err := commondb[i].SyncWithCloudNatDEntry(ctx, userCred, commonext[i])
+		if err != nil {
+			syncResult.UpdateError(err)
+		} else {
+			syncMetadata(ctx, userCred, &commondb[i], commonext[i])
+			syncResult.Update()
+		}
+	}
+
+	for i := 0; i < len(added); i += 1 {
+		entry, err := man.newFromCloudNatDEntry(ctx, userCred, provider, nat, added[i])
+		if err != nil {
+			syncResult.AddError(err)
+		} else {
+			syncMetadata(ctx, userCred, entry, added[i])
+			syncResult.Add()
+		}
+	}
+	return syncResult
+}
+
+func (self *SNatDEntry) SyncWithCloudNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, extEntry cloudprovider.ICloudNatDEntry) error {
+	diff, err := db.UpdateWithLock(ctx, self, func() error {
+		self.Status = extEntry.GetStatus()
+		self.ExternalIP = extEntry.GetExternalIp()
+		self.ExternalPort = extEntry.GetExternalPort()
+		self.InternalIP = extEntry.GetInternalIp()
+		self.InternalPort = extEntry.GetInternalPort()
+		self.IpProtocol = strings.ToLower(extEntry.GetIpProtocol())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogSyncUpdate(self, diff, userCred)
+	return nil
+}
+
+func (man *SNatDEntryManager) newFromCloudNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, nat *SNatGateway, extEntry cloudprovider.ICloudNatDEntry) (*SNatDEntry, error) {
+	entry := SNatDEntry{}
+	entry.SetModelManager(man)
+
+	entry.Name = db.GenerateName(man, nat.ProjectId, extEntry.GetName())
+	entry.Status = extEntry.GetStatus()
+	entry.ExternalId = extEntry.GetGlobalId()
+	entry.IsEmulated = extEntry.IsEmulated()
+	entry.ManagerId = provider.Id
+	entry.ProjectId = nat.ProjectId
+	entry.NatgatewayId = nat.Id
+	entry.ExternalIP = extEntry.GetExternalIp()
+	entry.ExternalPort = extEntry.GetExternalPort()
+	entry.InternalIP = extEntry.GetInternalIp()
+	entry.InternalPort = extEntry.GetInternalPort()
+	entry.IpProtocol = strings.ToLower(extEntry.GetIpProtocol())
+
+	err := man.TableSpec().Insert(&entry)
+	if err != nil {
+		return nil, fmt.Errorf("newFromCloudNatDEntry fail %s", err)
+	}
+
+	db.OpsLog.LogEvent(&entry, db.ACT_CREATE, entry.GetShortDesc(ctx), userCred)
+	return &entry, nil
+}
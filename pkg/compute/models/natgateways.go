@@ -0,0 +1,377 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SNatGatewayManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var NatGatewayManager *SNatGatewayManager
+
+func init() {
+	NatGatewayManager = &SNatGatewayManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SNatGateway{},
+			"natgateways_tbl",
+			"natgateway",
+			"natgateways",
+		),
+	}
+}
+
+type SNatGateway struct {
+	db.SVirtualResourceBase
+	SManagedResourceBase
+
+	VpcId         string `width:"36" charset:"ascii" nullable:"false" list:"user"`
+	CloudregionId string `width:"36" charset:"ascii" nullable:"false" list:"user"`
+	NatSpec       string `list:"user" create:"optional"` // NAT规格
+}
+
+func (man *SNatGatewayManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = managedResourceFilterByAccount(q, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	q = managedResourceFilterByCloudType(q, query, "", nil)
+
+	q, err = man.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	userProjId := userCred.GetProjectId()
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "vpc", ModelKeyword: "vpc", ProjectId: userProjId},
+		{Key: "cloudregion", ModelKeyword: "cloudregion", ProjectId: userProjId},
+		{Key: "manager", ModelKeyword: "cloudprovider", ProjectId: userProjId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (man *SNatGatewayManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (man *SNatGatewayManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	return nil, httperrors.NewUnsupportOperationError("nat gateway can only be synchronized from cloud")
+}
+
+func (self *SNatGateway) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (self *SNatGateway) AllowPerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "purge")
+}
+
+func (self *SNatGateway) PerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	provider := self.GetCloudprovider()
+	if provider != nil {
+		if provider.Enabled {
+			return nil, httperrors.NewInvalidStatusError("Cannot purge nat gateway on enabled cloud provider")
+		}
+	}
+	err := self.RealDelete(ctx, userCred)
+	return nil, err
+}
+
+func (self *SNatGateway) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	sentries, err := self.GetNatSEntries()
+	if err != nil {
+		return err
+	}
+	for i := range sentries {
+		err = sentries[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return err
+		}
+	}
+	dentries, err := self.GetNatDEntries()
+	if err != nil {
+		return err
+	}
+	for i := range dentries {
+		err = dentries[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return err
+		}
+	}
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SNatGateway) GetVpc() (*SVpc, error) {
+	vpc, err := VpcManager.FetchById(self.VpcId)
+	if err != nil {
+		return nil, err
+	}
+	return vpc.(*SVpc), nil
+}
+
+func (self *SNatGateway) GetRegion() (*SCloudregion, error) {
+	region, err := CloudregionManager.FetchById(self.CloudregionId)
+	if err != nil {
+		return nil, err
+	}
+	return region.(*SCloudregion), nil
+}
+
+func (self *SNatGateway) GetNatSEntries() ([]SNatSEntry, error) {
+	entries := make([]SNatSEntry, 0)
+	q := NatSEntryManager.Query().Equals("natgateway_id", self.Id)
+	err := db.FetchModelObjects(NatSEntryManager, q, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (self *SNatGateway) GetNatDEntries() ([]SNatDEntry, error) {
+	entries := make([]SNatDEntry, 0)
+	q := NatDEntryManager.Query().Equals("natgateway_id", self.Id)
+	err := db.FetchModelObjects(NatDEntryManager, q, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (self *SNatGateway) GetINatGateway() (cloudprovider.ICloudNatGateway, error) {
+	vpc, err := self.GetVpc()
+	if err != nil {
+		return nil, err
+	}
+	ivpc, err := vpc.GetIVpc()
+	if err != nil {
+		return nil, err
+	}
+	inats, err := ivpc.GetINatGateways()
+	if err != nil {
+		return nil, err
+	}
+	for i := range inats {
+		if inats[i].GetGlobalId() == self.ExternalId {
+			return inats[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+// getElasticipByIp finds the eip in the same region, cloud provider and project of the nat gateway
+func (self *SNatGateway) getElasticipByIp(ip string) (*SElasticip, error) {
+	q := ElasticipManager.Query().Equals("ip_addr", ip).Equals("cloudregion_id", self.CloudregionId).Equals("tenant_id", self.ProjectId)
+	if len(self.ManagerId) > 0 {
+		q = q.Equals("manager_id", self.ManagerId)
+	}
+	eips := make([]SElasticip, 0)
+	err := db.FetchModelObjects(ElasticipManager, q, &eips)
+	if err != nil {
+		return nil, err
+	}
+	if len(eips) != 1 {
+		return nil, httperrors.NewResourceNotFoundError("eip %s not found in region of nat gateway %s", ip, self.Name)
+	}
+	return &eips[0], nil
+}
+
+func (self *SNatGateway) getCloudProviderInfo() SCloudProviderInfo {
+	region, _ := self.GetRegion()
+	provider := self.GetCloudprovider()
+	return MakeCloudProviderInfo(region, nil, provider)
+}
+
+func (self *SNatGateway) getMoreDetails(extra *jsonutils.JSONDict) *jsonutils.JSONDict {
+	vpc, err := self.GetVpc()
+	if err != nil {
+		log.Errorf("nat gateway %s(%s): fetch vpc (%s) error: %s", self.Name, self.Id, self.VpcId, err)
+	} else {
+		extra.Set("vpc", jsonutils.NewString(vpc.Name))
+	}
+	extra.Set("snat_entry_count", jsonutils.NewInt(int64(NatSEntryManager.Query().Equals("natgateway_id", self.Id).Count())))
+	extra.Set("dnat_entry_count", jsonutils.NewInt(int64(NatDEntryManager.Query().Equals("natgateway_id", self.Id).Count())))
+	info := self.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra
+}
+
+func (self *SNatGateway) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SVirtualResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	return self.getMoreDetails(extra)
+}
+
+func (self *SNatGateway) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*jsonutils.JSONDict, error) {
+	extra, err := self.SVirtualResourceBase.GetExtraDetails(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	return self.getMoreDetails(extra), nil
+}
+
+func (man *SNatGatewayManager) SyncNatGateways(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, vpc *SVpc, cloudNatGateways []cloudprovider.ICloudNatGateway) ([]SNatGateway, []cloudprovider.ICloudNatGateway, compare.SyncResult) {
+	lockman.LockClass(ctx, man, man.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, man, man.GetOwnerId(userCred))
+
+	localNatGateways := make([]SNatGateway, 0)
+	remoteNatGateways := make([]cloudprovider.ICloudNatGateway, 0)
+	syncResult := compare.SyncResult{}
+
+	dbNatGateways := make([]SNatGateway, 0)
+	q := man.Query().Equals("vpc_id", vpc.Id).Equals("manager_id", provider.Id)
+	if err := db.FetchModelObjects(man, q, &dbNatGateways); err != nil {
+		syncResult.Error(err)
+		return nil, nil, syncResult
+	}
+
+	removed := make([]SNatGateway, 0)
+	commondb := make([]SNatGateway, 0)
+	commonext := make([]cloudprovider.ICloudNatGateway, 0)
+	added := make([]cloudprovider.ICloudNatGateway, 0)
+	if err := compare.CompareSets(dbNatGateways, cloudNatGateways, &removed, &commondb, &commonext, &added); err != nil {
+		syncResult.Error(err)
+		return nil, nil, syncResult
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err := removed[i].syncRemoveCloudNatGateway(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	}
+
+	for i := 0; i < len(commondb); i += 1 {
+		err := commondb[i].SyncWithCloudNatGateway(ctx, userCred, commonext[i])
+		if err != nil {
+			syncResult.UpdateError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, &commondb[i], commonext[i])
+		localNatGateways = append(localNatGateways, commondb[i])
+		remoteNatGateways = append(remoteNatGateways, commonext[i])
+		syncResult.Update()
+	}
+
+	for i := 0; i < len(added); i += 1 {
+		natNew, err := man.newFromCloudNatGateway(ctx, userCred, provider, vpc, added[i])
+		if err != nil {
+			syncResult.AddError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, natNew, added[i])
+		localNatGateways = append(localNatGateways, *natNew)
+		remoteNatGateways = append(remoteNatGateways, added[i])
+		syncResult.Add()
+	}
+	return localNatGateways, remoteNatGateways, syncResult
+}
+
+func (self *SNatGateway) syncRemoveCloudNatGateway(ctx context.Context, userCred mcclient.TokenCredential) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	err := self.ValidateDeleteCondition(ctx)
+	if err != nil {
+		self.SetStatus(userCred, api.NAT_STATUS_UNKNOWN, "sync to delete")
+		return err
+	}
+	return self.RealDelete(ctx, userCred)
+}
+
+func (self *SNatGateway) SyncWithCloudNatGateway(ctx context.Context, userCred mcclient.TokenCredential, extNat cloudprovider.ICloudNatGateway) error {
+	diff, err := db.UpdateWithLock(ctx, self, func() error {
+		self.Status = extNat.GetStatus()
+		self.NatSpec = extNat.GetNatSpec()
+		self.Description = extNat.GetDescription()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogSyncUpdate(self, diff, userCred)
+	return nil
+}
+
+func (man *SNatGatewayManager) newFromCloudNatGateway(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, vpc *SVpc, extNat cloudprovider.ICloudNatGateway) (*SNatGateway, error) {
+	nat := SNatGateway{}
+	nat.SetModelManager(man)
+
+	nat.Name = db.GenerateName(man, provider.ProjectId, extNat.GetName())
+	nat.VpcId = vpc.Id
+	nat.CloudregionId = vpc.CloudregionId
+	nat.Status = extNat.GetStatus()
+	nat.NatSpec = extNat.GetNatSpec()
+	nat.Description = extNat.GetDescription()
+	nat.ExternalId = extNat.GetGlobalId()
+	nat.ManagerId = provider.Id
+	nat.ProjectId = provider.ProjectId
+	if len(nat.ProjectId) == 0 {
+		nat.ProjectId = userCred.GetProjectId()
+	}
+
+	err := man.TableSpec().Insert(&nat)
+	if err != nil {
+		log.Errorf("newFromCloudNatGateway fail %s", err)
+		return nil, err
+	}
+
+	db.OpsLog.LogEvent(&nat, db.ACT_CREATE, nat.GetShortDesc(ctx), userCred)
+	return &nat, nil
+}
+
+func (self *SNatGateway) GetShortDesc(ctx context.Context) *jsonutils.JSONDict {
+	desc := self.SVirtualResourceBase.GetShortDesc(ctx)
+	desc.Add(jsonutils.NewString(self.NatSpec), "nat_spec")
+	info := self.getCloudProviderInfo()
+	desc.Update(jsonutils.Marshal(&info))
+	return desc
+}
+
+func (manager *SNatGatewayManager) purgeAll(ctx context.Context, userCred mcclient.TokenCredential, providerId string) error {
+	nats := make([]SNatGateway, 0)
+	err := fetchByManagerId(manager, providerId, &nats)
+	if err != nil {
+		return err
+	}
+	for i := range nats {
+		err := nats[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return fmt.Errorf("purge nat gateway %s fail %s", nats[i].Id, err)
+		}
+	}
+	return nil
+}
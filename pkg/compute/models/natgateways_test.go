@@ -0,0 +1,87 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+func TestSNatSEntry_newSNatRule(t *testing.T) {
+	entry := &SNatSEntry{
+		IP:         "1.2.3.4",
+		SourceCIDR: "192.168.0.0/24",
+	}
+	entry.Name = "snat"
+	cases := []struct {
+		name         string
+		networkExtId string
+		want         *cloudprovider.SNatSRule
+	}{
+		{
+			name: "source cidr",
+			want: &cloudprovider.SNatSRule{
+				Name:         "snat",
+				ExternalIP:   "1.2.3.4",
+				ExternalIPID: "eip-1",
+				SourceCIDR:   "192.168.0.0/24",
+			},
+		},
+		{
+			name:         "network",
+			networkExtId: "vsw-1",
+			want: &cloudprovider.SNatSRule{
+				Name:         "snat",
+				ExternalIP:   "1.2.3.4",
+				ExternalIPID: "eip-1",
+				NetworkID:    "vsw-1",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := entry.newSNatRule("eip-1", c.networkExtId)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want %#v got %#v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestSNatDEntry_newDNatRule(t *testing.T) {
+	entry := &SNatDEntry{
+		ExternalIP:   "1.2.3.4",
+		ExternalPort: 8080,
+		InternalIP:   "192.168.0.10",
+		InternalPort: 80,
+		IpProtocol:   "tcp",
+	}
+	entry.Name = "dnat"
+	want := &cloudprovider.SNatDRule{
+		Name:         "dnat",
+		Protocol:     "tcp",
+		ExternalIP:   "1.2.3.4",
+		ExternalIPID: "eip-1",
+		ExternalPort: 8080,
+		InternalIP:   "192.168.0.10",
+		InternalPort: 80,
+	}
+	got := entry.newDNatRule("eip-1")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %#v got %#v", want, got)
+	}
+}
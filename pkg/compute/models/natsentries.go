@@ -0,0 +1,378 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/pkg/util/regutils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SNatSEntryManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var NatSEntryManager *SNatSEntryManager
+
+func init() {
+	NatSEntryManager = &SNatSEntryManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SNatSEntry{},
+			"natstables_tbl",
+			"natsentry",
+			"natsentries",
+		),
+	}
+}
+
+type SNatSEntry struct {
+	db.SVirtualResourceBase
+	SManagedResourceBase
+
+	NatgatewayId string `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required"`
+	IP           string `width:"17" charset:"ascii" list:"user" create:"required"`
+	SourceCIDR   string `width:"22" charset:"ascii" list:"user" create:"optional"`
+	NetworkId    string `width:"36" charset:"ascii" list:"user" create:"optional"`
+}
+
+func (man *SNatSEntryManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	q, err := man.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	userProjId := userCred.GetProjectId()
+	data := query.(*jsonutils.JSONDict)
+	return validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "natgateway", ModelKeyword: "natgateway", ProjectId: userProjId},
+		{Key: "network", ModelKeyword: "network", ProjectId: userProjId},
+	})
+}
+
+func (man *SNatSEntryManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	natV := validators.NewModelIdOrNameValidator("natgateway", "natgateway", ownerProjId)
+	ipV := validators.NewIPv4AddrValidator("ip")
+	if err := natV.Validate(data); err != nil {
+		return nil, err
+	}
+	if err := ipV.Validate(data); err != nil {
+		return nil, err
+	}
+	nat := natV.Model.(*SNatGateway)
+	if !nat.IsManaged() {
+		return nil, httperrors.NewUnsupportOperationError("nat gateway %s is not managed by any cloud provider", nat.Name)
+	}
+	if _, err := nat.getElasticipByIp(ipV.IP.String()); err != nil {
+		return nil, err
+	}
+
+	networkStr, _ := data.GetString("network")
+	sourceCidr, _ := data.GetString("source_cidr")
+	if len(networkStr) > 0 {
+		netObj, err := NetworkManager.FetchByIdOrName(userCred, networkStr)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(NetworkManager.Keyword(), networkStr)
+		}
+		network := netObj.(*SNetwork)
+		vpc := network.GetVpc()
+		if vpc == nil || vpc.Id != nat.VpcId {
+			return nil, httperrors.NewInputParameterError("network %s is not in the vpc of nat gateway %s", network.Name, nat.Name)
+		}
+		data.Set("network_id", jsonutils.NewString(network.Id))
+		data.Remove("source_cidr")
+	} else if len(sourceCidr) > 0 {
+		if !regutils.MatchCIDR(sourceCidr) {
+			return nil, httperrors.NewInputParameterError("invalid source_cidr %s", sourceCidr)
+		}
+	} else {
+		return nil, httperrors.NewMissingParameterError("network or source_cidr")
+	}
+
+	data.Set("manager_id", jsonutils.NewString(nat.ManagerId))
+	return man.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerProjId, query, data)
+}
+
+func (self *SNatSEntry) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SVirtualResourceBase.PostCreate(ctx, userCred, ownerProjId, query, data)
+	self.SetStatus(userCred, api.NAT_STATUS_ALLOCATE, "")
+	err := self.StartNatSEntryCreateTask(ctx, userCred, "")
+	if err != nil {
+		self.SetStatus(userCred, api.NAT_STATUS_CREATE_FAILED, err.Error())
+	}
+}
+
+func (self *SNatSEntry) StartNatSEntryCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "NatSEntryCreateTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SNatSEntry) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	log.Infof("natsentry delete do nothing")
+	return nil
+}
+
+func (self *SNatSEntry) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return self.StartNatSEntryDeleteTask(ctx, userCred, "")
+}
+
+func (self *SNatSEntry) StartNatSEntryDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "NatSEntryDeleteTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	self.SetStatus(userCred, api.NAT_STATUS_DELETING, "")
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SNatSEntry) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SNatSEntry) GetNatgateway() (*SNatGateway, error) {
+	nat, err := NatGatewayManager.FetchById(self.NatgatewayId)
+	if err != nil {
+		return nil, err
+	}
+	return nat.(*SNatGateway), nil
+}
+
+// GetINatSEntry finds the cloud snat entry by the external id
+func (self *SNatSEntry) GetINatSEntry() (cloudprovider.ICloudNatSEntry, error) {
+	nat, err := self.GetNatgateway()
+	if err != nil {
+		return nil, err
+	}
+	inat, err := nat.GetINatGateway()
+	if err != nil {
+		return nil, err
+	}
+	ientries, err := inat.GetINatSEntries()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ientries {
+		if ientries[i].GetGlobalId() == self.ExternalId {
+			return ientries[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+// GetSNatRule builds the rule which is used to create the cloud snat entry
+func (self *SNatSEntry) GetSNatRule() (*cloudprovider.SNatSRule, error) {
+	nat, err := self.GetNatgateway()
+	if err != nil {
+		return nil, err
+	}
+	eip, err := nat.getElasticipByIp(self.IP)
+	if err != nil {
+		return nil, err
+	}
+	networkExtId := ""
+	if len(self.NetworkId) > 0 {
+		netObj, err := NetworkManager.FetchById(self.NetworkId)
+		if err != nil {
+			return nil, err
+		}
+		networkExtId = netObj.(*SNetwork).ExternalId
+	}
+	return self.newSNatRule(eip.ExternalId, networkExtId), nil
+}
+
+// newSNatRule translates the entry with the external ids of its eip and
+// network, the source cidr is left to the cloud if the network is given
+func (self *SNatSEntry) newSNatRule(eipExtId string, networkExtId string) *cloudprovider.SNatSRule {
+	rule := &cloudprovider.SNatSRule{
+		Name:         self.Name,
+		ExternalIP:   self.IP,
+		ExternalIPID: eipExtId,
+	}
+	if len(networkExtId) > 0 {
+		rule.NetworkID = networkExtId
+	} else {
+		rule.SourceCIDR = self.SourceCIDR
+	}
+	return rule
+}
+
+func (self *SNatSEntry) getMoreDetails(extra *jsonutils.JSONDict) *jsonutils.JSONDict {
+	nat, err := self.GetNatgateway()
+	if err != nil {
+		log.Errorf("natsentry %s(%s): fetch natgateway (%s) error: %s", self.Name, self.Id, self.NatgatewayId, err)
+		return extra
+	}
+	extra.Set("natgateway", jsonutils.NewString(nat.Name))
+	if len(self.NetworkId) > 0 {
+		netObj, err := NetworkManager.FetchById(self.NetworkId)
+		if err == nil {
+			extra.Set("network", jsonutils.NewString(netObj.GetName()))
+		}
+	}
+	info := nat.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra
+}
+
+func (self *SNatSEntry) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SVirtualResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	return self.getMoreDetails(extra)
+}
+
+func (self *SNatSEntry) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*jsonutils.JSONDict, error) {
+	extra, err := self.SVirtualResourceBase.GetExtraDetails(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	return self.getMoreDetails(extra), nil
+}
+
+func (man *SNatSEntryManager) SyncNatSEntries(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, nat *SNatGateway, extEntries []cloudprovider.ICloudNatSEntry) compare.SyncResult {
+	lockman.LockClass(ctx, man, man.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, man, man.GetOwnerId(userCred))
+
+	syncResult := compare.SyncResult{}
+
+	entries := make([]SNatSEntry, 0)
+	q := man.Query().Equals("natgateway_id", nat.Id)
+	if err := db.FetchModelObjects(man, q, &entries); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+	dbEntries := make([]SNatSEntry, 0, len(entries))
+	for i := range entries {
+		// entries being created have no external id yet and should not be removed
+		if len(entries[i].ExternalId) > 0 {
+			dbEntries = append(dbEntries, entries[i])
+		} else if entries[i].Status != api.NAT_STATUS_ALLOCATE {
+			log.Warningf("snat entry %s(%s) of nat gateway %s has no external id, status %s, skip syncing", entries[i].Name, entries[i].Id, nat.Name, entries[i].Status)
+		}
+	}
+
+	removed := make([]SNatSEntry, 0)
+	commondb := make([]SNatSEntry, 0)
+	commonext := make([]cloudprovider.ICloudNatSEntry, 0)
+	added := make([]cloudprovider.ICloudNatSEntry, 0)
+	if err := compare.CompareSets(dbEntries, extEntries, &removed, &commondb, &commonext, &added); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err := removed[i].RealDelete(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	}
+
+	for i := 0; i < len(commondb); i += 1 {
+		err := commondb[i].SyncWithCloudNatSEntry(ctx, userCred, nat, commonext[i])
+		if err != nil {
+			syncResult.UpdateError(err)
+		} else {
+			syncMetadata(ctx, userCred, &commondb[i], commonext[i])
+			syncResult.Update()
+		}
+	}
+
+	for i := 0; i < len(added); i += 1 {
+		entry, err := man.newFromCloudNatSEntry(ctx, userCred, provider, nat, added[i])
+		if err != nil {
+			syncResult.AddError(err)
+		} else {
+			syncMetadata(ctx, userCred, entry, added[i])
+			syncResult.Add()
+		}
+	}
+	return syncResult
+}
+
+func (self *SNatSEntry) syncNetworkId(nat *SNatGateway, extEntry cloudprovider.ICloudNatSEntry) {
+	self.NetworkId = ""
+	extNetworkId := extEntry.GetNetworkId()
+	if len(extNetworkId) == 0 {
+		return
+	}
+	vpc, err := nat.GetVpc()
+	if err != nil {
+		return
+	}
+	networks := make([]SNetwork, 0)
+	q := vpc.getNetworkQuery().Equals("external_id", extNetworkId)
+	err = db.FetchModelObjects(NetworkManager, q, &networks)
+	if err != nil || len(networks) != 1 {
+		log.Warningf("network %s of snat entry %s not found", extNetworkId, extEntry.GetGlobalId())
+		return
+	}
+	self.NetworkId = networks[0].Id
+}
+
+func (self *SNatSEntry) SyncWithCloudNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, nat *SNatGateway, extEntry cloudprovider.ICloudNatSEntry) error {
+	diff, err := db.UpdateWithLock(ctx, self, func() error {
+		self.Status = extEntry.GetStatus()
+		self.IP = extEntry.GetIP()
+		self.SourceCIDR = extEntry.GetSourceCIDR()
+		self.syncNetworkId(nat, extEntry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogSyncUpdate(self, diff, userCred)
+	return nil
+}
+
+func (man *SNatSEntryManager) newFromCloudNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, nat *SNatGateway, extEntry cloudprovider.ICloudNatSEntry) (*SNatSEntry, error) {
+	entry := SNatSEntry{}
+	entry.SetModelManager(man)
+
+	entry.Name = db.GenerateName(man, nat.ProjectId, extEntry.GetName())
+	entry.Status = extEntry.GetStatus()
+	entry.ExternalId = extEntry.GetGlobalId()
+	entry.IsEmulated = extEntry.IsEmulated()
+	entry.ManagerId = provider.Id
+	entry.ProjectId = nat.ProjectId
+	entry.NatgatewayId = nat.Id
+	entry.IP = extEntry.GetIP()
+	entry.SourceCIDR = extEntry.GetSourceCIDR()
+	entry.syncNetworkId(nat, extEntry)
+
+	err := man.TableSpec().Insert(&entry)
+	if err != nil {
+		return nil, fmt.Errorf("newFromCloudNatSEntry fail %s", err)
+	}
+
+	db.OpsLog.LogEvent(&entry, db.ACT_CREATE, entry.GetShortDesc(ctx), userCred)
+	return &entry, nil
+}
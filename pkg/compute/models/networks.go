@@ -742,6 +742,19 @@ func parseNetworkInfo(userCred mcclient.TokenCredential, info *api.NetworkConfig
 	return info, nil
 }
 
+func (self *SNetwork) isReachableFromHostType(hostType string) bool {
+	wire := self.GetWire()
+	if wire == nil {
+		return false
+	}
+	for _, host := range wire.getEnabledHosts() {
+		if host.HostType == hostType {
+			return true
+		}
+	}
+	return false
+}
+
 func (self *SNetwork) getFreeAddressCount() int {
 	return self.getIPRange().AddressCount() - self.GetTotalNicCount()
 }
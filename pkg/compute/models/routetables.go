@@ -26,6 +26,7 @@ import (
 	"yunion.io/x/pkg/util/compare"
 	"yunion.io/x/sqlchemy"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
@@ -39,6 +40,11 @@ type SRoute struct {
 	Cidr        string
 	NextHopType string
 	NextHopId   string
+
+	// the synced resource the next hop refers to, i.e. a vpc peering or
+	// a vpn connection, empty for other types of next hop
+	NextHopResource   string
+	NextHopResourceId string
 }
 
 func (route *SRoute) Validate(data *jsonutils.JSONDict) error {
@@ -58,6 +64,74 @@ func (route *SRoute) Validate(data *jsonutils.JSONDict) error {
 	return nil
 }
 
+// sNextHopResources indexes the vpc peerings and vpn connections of a cloud
+// provider by the ids the next hops of its routes refer to
+type sNextHopResources struct {
+	peerings map[string]string
+	conns    map[string]string
+}
+
+func newNextHopResources(peerings []SVpcPeering, conns []SVpnConnection) *sNextHopResources {
+	res := &sNextHopResources{
+		peerings: map[string]string{},
+		conns:    map[string]string{},
+	}
+	for i := range peerings {
+		if len(peerings[i].ExternalId) > 0 {
+			res.peerings[peerings[i].ExternalId] = peerings[i].Id
+		}
+	}
+	// a vpn route may refer to the connection or to the vpn gateway,
+	// depending on the cloud, the connection ids take precedence
+	for i := range conns {
+		gatewayId := conns[i].VpnGatewayId
+		if _, ok := res.conns[gatewayId]; len(gatewayId) > 0 && !ok {
+			res.conns[gatewayId] = conns[i].Id
+		}
+	}
+	for i := range conns {
+		if len(conns[i].ExternalId) > 0 {
+			res.conns[conns[i].ExternalId] = conns[i].Id
+		}
+	}
+	return res
+}
+
+// fetchNextHopResources loads the vpc peerings and vpn connections of the
+// cloud provider at once for the routes of a route table
+func fetchNextHopResources(managerId string) (*sNextHopResources, error) {
+	peerings := make([]SVpcPeering, 0)
+	q := VpcPeeringManager.Query().Equals("manager_id", managerId)
+	if err := db.FetchModelObjects(VpcPeeringManager, q, &peerings); err != nil {
+		return nil, err
+	}
+	conns := make([]SVpnConnection, 0)
+	q = VpnConnectionManager.Query().Equals("manager_id", managerId)
+	if err := db.FetchModelObjects(VpnConnectionManager, q, &conns); err != nil {
+		return nil, err
+	}
+	return newNextHopResources(peerings, conns), nil
+}
+
+// linkNextHop links the route to the vpc peering or vpn connection of the
+// same cloud provider its next hop refers to
+func (route *SRoute) linkNextHop(res *sNextHopResources) {
+	route.NextHopResource = ""
+	route.NextHopResourceId = ""
+	if len(route.NextHopId) == 0 {
+		return
+	}
+	if id, ok := res.peerings[route.NextHopId]; ok {
+		route.NextHopResource = api.ROUTE_NEXT_HOP_RESOURCE_VPC_PEERING
+		route.NextHopResourceId = id
+		return
+	}
+	if id, ok := res.conns[route.NextHopId]; ok {
+		route.NextHopResource = api.ROUTE_NEXT_HOP_RESOURCE_VPN_CONNECTION
+		route.NextHopResourceId = id
+	}
+}
+
 type SRoutes []*SRoute
 
 func (routes *SRoutes) String() string {
@@ -363,7 +437,8 @@ func (man *SRouteTableManager) SyncRouteTables(ctx context.Context, userCred mcc
 	syncResult := compare.SyncResult{}
 
 	dbRouteTables := []SRouteTable{}
-	if err := db.FetchModelObjects(man, man.Query(), &dbRouteTables); err != nil {
+	q := man.Query().Equals("vpc_id", vpc.Id)
+	if err := db.FetchModelObjects(man, q, &dbRouteTables); err != nil {
 		syncResult.Error(err)
 		return nil, nil, syncResult
 	}
@@ -418,6 +493,10 @@ func (man *SRouteTableManager) newRouteTableFromCloud(userCred mcclient.TokenCre
 		if err != nil {
 			return nil, err
 		}
+		nextHops, err := fetchNextHopResources(vpc.ManagerId)
+		if err != nil {
+			return nil, err
+		}
 		for _, cloudRoute := range cloudRoutes {
 			route := &SRoute{
 				Type:        cloudRoute.GetType(),
@@ -425,6 +504,7 @@ func (man *SRouteTableManager) newRouteTableFromCloud(userCred mcclient.TokenCre
 				NextHopType: cloudRoute.GetNextHopType(),
 				NextHopId:   cloudRoute.GetNextHop(),
 			}
+			route.linkNextHop(nextHops)
 			routes = append(routes, route)
 		}
 	}
@@ -0,0 +1,92 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestSRoute_linkNextHop(t *testing.T) {
+	peerings := []SVpcPeering{{}, {}}
+	peerings[0].Id, peerings[0].ExternalId = "peering-0", "pcx-0"
+	// not synced from the cloud
+	peerings[1].Id = "peering-1"
+	conns := []SVpnConnection{
+		{VpnGatewayId: "vgw-0"},
+		{VpnGatewayId: "vgw-0"},
+		{VpnGatewayId: "vpn-1"},
+		{},
+	}
+	conns[0].Id, conns[0].ExternalId = "conn-0", "vpn-0"
+	conns[1].Id, conns[1].ExternalId = "conn-1", "vpn-1"
+	// the gateway id of a connection may equal the id of another one
+	conns[2].Id, conns[2].ExternalId = "conn-2", "vpn-2"
+	conns[3].Id, conns[3].ExternalId = "conn-3", "pcx-0"
+	res := newNextHopResources(peerings, conns)
+
+	cases := []struct {
+		name       string
+		nextHopId  string
+		resource   string
+		resourceId string
+	}{
+		{
+			name:       "peering",
+			nextHopId:  "pcx-0",
+			resource:   api.ROUTE_NEXT_HOP_RESOURCE_VPC_PEERING,
+			resourceId: "peering-0",
+		},
+		{
+			name:       "vpn connection",
+			nextHopId:  "vpn-0",
+			resource:   api.ROUTE_NEXT_HOP_RESOURCE_VPN_CONNECTION,
+			resourceId: "conn-0",
+		},
+		{
+			name:       "vpn gateway",
+			nextHopId:  "vgw-0",
+			resource:   api.ROUTE_NEXT_HOP_RESOURCE_VPN_CONNECTION,
+			resourceId: "conn-0",
+		},
+		{
+			name:       "connection before gateway",
+			nextHopId:  "vpn-1",
+			resource:   api.ROUTE_NEXT_HOP_RESOURCE_VPN_CONNECTION,
+			resourceId: "conn-1",
+		},
+		{
+			name:      "unknown",
+			nextHopId: "igw-0",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			route := &SRoute{
+				NextHopId:         c.nextHopId,
+				NextHopResource:   "stale",
+				NextHopResourceId: "stale",
+			}
+			route.linkNextHop(res)
+			if route.NextHopResource != c.resource || route.NextHopResourceId != c.resourceId {
+				t.Errorf("want %q %q got %q %q", c.resource, c.resourceId, route.NextHopResource, route.NextHopResourceId)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/sqlchemy"
+
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SScheduleDecisionManager struct {
+	db.SResourceBaseManager
+}
+
+var ScheduleDecisionManager *SScheduleDecisionManager
+
+type SScheduleExplainManager struct {
+	db.SResourceBaseManager
+}
+
+var ScheduleExplainManager *SScheduleExplainManager
+
+func init() {
+	ScheduleDecisionManager = &SScheduleDecisionManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SScheduleDecision{},
+			"schedule_decisions_tbl",
+			"schedule_decision",
+			"schedule_decisions",
+		),
+	}
+	ScheduleExplainManager = &SScheduleExplainManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SScheduleExplain{},
+			"schedule_explains_tbl",
+			"schedule_explain",
+			"schedule_explains",
+		),
+	}
+}
+
+// SScheduleDecision is how the scheduler placed a guest or disk, or why it
+// failed to
+type SScheduleDecision struct {
+	db.SResourceBase
+
+	Id        int64  `primary:"true" auto_increment:"true" list:"admin"`
+	ObjType   string `width:"32" charset:"ascii" nullable:"false" list:"admin"`
+	ObjId     string `width:"36" charset:"ascii" nullable:"false" index:"true" list:"admin"`
+	SessionId string `width:"64" charset:"ascii" nullable:"true" list:"admin"`
+	TaskName  string `width:"64" charset:"ascii" nullable:"true" list:"admin"`
+
+	// the chosen host, empty if the schedule failed
+	HostId string `width:"36" charset:"ascii" nullable:"true" list:"admin"`
+	Error  string `charset:"utf8" nullable:"true" list:"admin"`
+}
+
+// SScheduleExplain is how the candidates are filtered and scored by a
+// schedule session, shared by the decisions of all the objects scheduled
+// together
+type SScheduleExplain struct {
+	db.SResourceBase
+
+	SessionId string               `width:"64" charset:"ascii" primary:"true" list:"admin"`
+	Explain   jsonutils.JSONObject `length:"medium" charset:"utf8" nullable:"true" get:"admin"`
+}
+
+func (manager *SScheduleExplainManager) AllowListItems(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowList(userCred, manager)
+}
+
+func (manager *SScheduleExplainManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (self *SScheduleExplain) AllowGetDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGet(userCred, self)
+}
+
+func (self *SScheduleExplain) AllowUpdateItem(ctx context.Context, userCred mcclient.TokenCredential) bool {
+	return false
+}
+
+func (self *SScheduleExplain) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+// Record saves the explain of a schedule session, a session scheduled again
+// keeps the latest explain
+func (manager *SScheduleExplainManager) Record(explain *schedapi.ScheduleExplain) {
+	if explain == nil || len(explain.SessionId) == 0 {
+		return
+	}
+	obj, err := db.NewModelObject(manager)
+	if err != nil {
+		log.Errorf("new schedule explain fail %s", err)
+		return
+	}
+	q := manager.Query().Equals("session_id", explain.SessionId)
+	err = q.First(obj)
+	if err == nil {
+		rec := obj.(*SScheduleExplain)
+		_, err = db.Update(rec, func() error {
+			rec.Explain = jsonutils.Marshal(explain)
+			return nil
+		})
+	} else if err == sql.ErrNoRows {
+		rec := &SScheduleExplain{
+			SessionId: explain.SessionId,
+			Explain:   jsonutils.Marshal(explain),
+		}
+		rec.SetModelManager(manager)
+		err = manager.TableSpec().Insert(rec)
+	}
+	if err != nil {
+		log.Errorf("record schedule explain of session %s fail %s", explain.SessionId, err)
+	}
+}
+
+// FetchExplains returns the explains of sessions by session id
+func (manager *SScheduleExplainManager) FetchExplains(sessionIds []string) (map[string]jsonutils.JSONObject, error) {
+	ret := make(map[string]jsonutils.JSONObject)
+	if len(sessionIds) == 0 {
+		return ret, nil
+	}
+	q := manager.Query().In("session_id", sessionIds)
+	explains := make([]SScheduleExplain, 0)
+	err := db.FetchModelObjects(manager, q, &explains)
+	if err != nil {
+		return nil, err
+	}
+	for i := range explains {
+		ret[explains[i].SessionId] = explains[i].Explain
+	}
+	return ret, nil
+}
+
+func (manager *SScheduleDecisionManager) AllowListItems(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowList(userCred, manager)
+}
+
+func (manager *SScheduleDecisionManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (self *SScheduleDecision) AllowGetDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGet(userCred, self)
+}
+
+func (self *SScheduleDecision) AllowUpdateItem(ctx context.Context, userCred mcclient.TokenCredential) bool {
+	return false
+}
+
+func (self *SScheduleDecision) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+// Record saves the decision of obj, candidate is nil if the schedule request
+// itself failed. The explain of the session is saved by
+// ScheduleExplainManager once for all the objects scheduled together
+func (manager *SScheduleDecisionManager) Record(
+	ctx context.Context,
+	obj db.IStandaloneModel,
+	taskName string,
+	sessionId string,
+	candidate *schedapi.CandidateResource,
+	reason string,
+) {
+	decision := &SScheduleDecision{
+		ObjType:   obj.Keyword(),
+		ObjId:     obj.GetId(),
+		SessionId: sessionId,
+		TaskName:  taskName,
+		Error:     reason,
+	}
+	decision.SetModelManager(manager)
+	if candidate != nil {
+		decision.HostId = candidate.HostId
+		if len(candidate.Error) > 0 {
+			decision.Error = candidate.Error
+		}
+	}
+	err := manager.TableSpec().Insert(decision)
+	if err != nil {
+		log.Errorf("record schedule decision of %s %s fail %s", obj.Keyword(), obj.GetId(), err)
+	}
+}
+
+// FetchDecisions returns the latest decisions of an object
+func (manager *SScheduleDecisionManager) FetchDecisions(objType, objId string, limit int) ([]SScheduleDecision, error) {
+	q := manager.Query().Equals("obj_type", objType).Equals("obj_id", objId).Desc("id")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	decisions := make([]SScheduleDecision, 0)
+	err := db.FetchModelObjects(manager, q, &decisions)
+	if err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// CleanExpiredDecisions deletes the decisions and explains older than
+// ScheduleDecisionKeepDays, in batches to keep every statement short
+func (manager *SScheduleDecisionManager) CleanExpiredDecisions(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	expired := time.Now().UTC().AddDate(0, 0, -options.Options.ScheduleDecisionKeepDays)
+	for _, tbl := range []string{manager.TableSpec().Name(), ScheduleExplainManager.TableSpec().Name()} {
+		err := deleteExpiredRows(tbl, expired, 1000)
+		if err != nil {
+			log.Errorf("delete expired rows of %s fail %s", tbl, err)
+		}
+	}
+}
+
+func deleteExpiredRows(tbl string, expired time.Time, batch int) error {
+	stmt := fmt.Sprintf("DELETE FROM `%s` WHERE `created_at` < ? LIMIT %d", tbl, batch)
+	for {
+		result, err := sqlchemy.GetDB().Exec(stmt, expired)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows < int64(batch) {
+			return nil
+		}
+	}
+}
+
+func (self *SGuest) AllowGetDetailsScheduleExplain(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGetSpec(userCred, self, "schedule-explain")
+}
+
+// GetDetailsScheduleExplain tells why the guest is placed on its host or
+// failed to schedule, the latest decision first
+func (self *SGuest) GetDetailsScheduleExplain(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	limit, _ := query.Int("limit")
+	if limit <= 0 {
+		limit = 1
+	}
+	decisions, err := ScheduleDecisionManager.FetchDecisions(self.Keyword(), self.Id, int(limit))
+	if err != nil {
+		return nil, err
+	}
+	sessionIds := make([]string, 0, len(decisions))
+	for i := range decisions {
+		if len(decisions[i].SessionId) > 0 {
+			sessionIds = append(sessionIds, decisions[i].SessionId)
+		}
+	}
+	explains, err := ScheduleExplainManager.FetchExplains(sessionIds)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]jsonutils.JSONObject, 0, len(decisions))
+	for i := range decisions {
+		item := jsonutils.Marshal(&decisions[i]).(*jsonutils.JSONDict)
+		if explain, ok := explains[decisions[i].SessionId]; ok {
+			item.Add(explain, "explain")
+		}
+		items = append(items, item)
+	}
+	ret := jsonutils.NewDict()
+	ret.Add(jsonutils.NewArray(items...), "decisions")
+	return ret, nil
+}
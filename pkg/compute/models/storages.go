@@ -84,6 +84,8 @@ const (
 	STORAGE_UCLOUD_LOCAL_NORMAL         = api.STORAGE_UCLOUD_LOCAL_NORMAL
 	STORAGE_UCLOUD_LOCAL_SSD            = api.STORAGE_UCLOUD_LOCAL_SSD
 	STORAGE_UCLOUD_EXCLUSIVE_LOCAL_DISK = api.STORAGE_UCLOUD_EXCLUSIVE_LOCAL_DISK
+
+	STORAGE_MOCK_CLOUD = api.STORAGE_MOCK_CLOUD
 )
 
 const (
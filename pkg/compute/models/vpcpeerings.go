@@ -0,0 +1,305 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SVpcPeeringManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var VpcPeeringManager *SVpcPeeringManager
+
+func init() {
+	VpcPeeringManager = &SVpcPeeringManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SVpcPeering{},
+			"vpc_peerings_tbl",
+			"vpc_peering",
+			"vpc_peerings",
+		),
+	}
+}
+
+type SVpcPeering struct {
+	db.SVirtualResourceBase
+	SManagedResourceBase
+
+	CloudregionId string `width:"36" charset:"ascii" nullable:"false" list:"user"`
+	VpcId         string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+
+	// local id of the peer vpc, empty if the peer vpc is not synced,
+	// e.g. it belongs to an account unknown to the region
+	PeerVpcId         string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+	PeerExternalVpcId string `width:"256" charset:"utf8" nullable:"true" list:"user"`
+	PeerRegion        string `width:"64" charset:"ascii" nullable:"true" list:"user"`
+	PeerAccount       string `width:"128" charset:"ascii" nullable:"true" list:"user"`
+
+	// bandwidth limit in Mbps, 0 means unlimited
+	Bandwidth int `nullable:"false" default:"0" list:"user"`
+}
+
+func (manager *SVpcPeeringManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = managedResourceFilterByAccount(q, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	q = managedResourceFilterByCloudType(q, query, "", nil)
+
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	userProjId := userCred.GetProjectId()
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "cloudregion", ModelKeyword: "cloudregion", ProjectId: userProjId},
+		{Key: "vpc", ModelKeyword: "vpc", ProjectId: userProjId},
+		{Key: "manager", ModelKeyword: "cloudprovider", ProjectId: userProjId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (manager *SVpcPeeringManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (manager *SVpcPeeringManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	return nil, httperrors.NewUnsupportOperationError("vpc peering can only be synchronized from cloud")
+}
+
+func (self *SVpcPeering) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (self *SVpcPeering) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SVpcPeering) AllowPerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "purge")
+}
+
+func (self *SVpcPeering) PerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	provider := self.GetCloudprovider()
+	if provider != nil && provider.Enabled {
+		return nil, httperrors.NewInvalidStatusError("Cannot purge vpc peering on enabled cloud provider")
+	}
+	err := self.RealDelete(ctx, userCred)
+	return nil, err
+}
+
+func (self *SVpcPeering) GetRegion() *SCloudregion {
+	region, err := CloudregionManager.FetchById(self.CloudregionId)
+	if err != nil {
+		log.Errorf("failed to find region for vpc peering %s", self.Name)
+		return nil
+	}
+	return region.(*SCloudregion)
+}
+
+func (self *SVpcPeering) getCloudProviderInfo() SCloudProviderInfo {
+	region := self.GetRegion()
+	provider := self.GetCloudprovider()
+	return MakeCloudProviderInfo(region, nil, provider)
+}
+
+func (self *SVpcPeering) getMoreDetails(extra *jsonutils.JSONDict) *jsonutils.JSONDict {
+	if len(self.VpcId) > 0 {
+		if vpc, err := VpcManager.FetchById(self.VpcId); err == nil {
+			extra.Set("vpc", jsonutils.NewString(vpc.GetName()))
+		}
+	}
+	if len(self.PeerVpcId) > 0 {
+		if vpc, err := VpcManager.FetchById(self.PeerVpcId); err == nil {
+			extra.Set("peer_vpc", jsonutils.NewString(vpc.GetName()))
+		}
+	}
+	info := self.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra
+}
+
+func (self *SVpcPeering) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SVirtualResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	return self.getMoreDetails(extra)
+}
+
+func (self *SVpcPeering) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*jsonutils.JSONDict, error) {
+	extra, err := self.SVirtualResourceBase.GetExtraDetails(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	return self.getMoreDetails(extra), nil
+}
+
+func (manager *SVpcPeeringManager) SyncVpcPeerings(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, region *SCloudregion, peerings []cloudprovider.ICloudVpcPeering) compare.SyncResult {
+	lockman.LockClass(ctx, manager, manager.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, manager, manager.GetOwnerId(userCred))
+
+	syncResult := compare.SyncResult{}
+
+	dbPeerings := make([]SVpcPeering, 0)
+	q := manager.Query().Equals("cloudregion_id", region.Id).Equals("manager_id", provider.Id)
+	if err := db.FetchModelObjects(manager, q, &dbPeerings); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	removed := make([]SVpcPeering, 0)
+	commondb := make([]SVpcPeering, 0)
+	commonext := make([]cloudprovider.ICloudVpcPeering, 0)
+	added := make([]cloudprovider.ICloudVpcPeering, 0)
+	if err := compare.CompareSets(dbPeerings, peerings, &removed, &commondb, &commonext, &added); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err := removed[i].syncRemoveCloudVpcPeering(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	}
+
+	for i := 0; i < len(commondb); i += 1 {
+		err := commondb[i].SyncWithCloudVpcPeering(ctx, userCred, commonext[i])
+		if err != nil {
+			syncResult.UpdateError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, &commondb[i], commonext[i])
+		syncResult.Update()
+	}
+
+	for i := 0; i < len(added); i += 1 {
+		peering, err := manager.newFromCloudVpcPeering(ctx, userCred, provider, region, added[i])
+		if err != nil {
+			syncResult.AddError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, peering, added[i])
+		syncResult.Add()
+	}
+	return syncResult
+}
+
+func (self *SVpcPeering) syncRemoveCloudVpcPeering(ctx context.Context, userCred mcclient.TokenCredential) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	err := self.SVirtualResourceBase.ValidateDeleteCondition(ctx)
+	if err != nil {
+		self.SetStatus(userCred, api.VPC_PEERING_STATUS_UNKNOWN, "sync to delete")
+		return err
+	}
+	return self.RealDelete(ctx, userCred)
+}
+
+func (self *SVpcPeering) setCloudVpcPeeringAttrs(extPeering cloudprovider.ICloudVpcPeering) {
+	self.Status = extPeering.GetStatus()
+	self.VpcId = ""
+	if vpcId := extPeering.GetVpcId(); len(vpcId) > 0 {
+		vpc, err := fetchVpcByExternalId(self.ManagerId, vpcId)
+		if err == nil {
+			self.VpcId = vpc.Id
+		} else {
+			log.Warningf("find vpc %s for vpc peering %s fail %s", vpcId, extPeering.GetName(), err)
+		}
+	}
+	self.PeerExternalVpcId = extPeering.GetPeerVpcId()
+	self.PeerVpcId = ""
+	if len(self.PeerExternalVpcId) > 0 {
+		if vpc, err := fetchVpcByExternalId("", self.PeerExternalVpcId); err == nil {
+			self.PeerVpcId = vpc.Id
+		}
+	}
+	self.PeerRegion = extPeering.GetPeerRegionId()
+	self.PeerAccount = extPeering.GetPeerAccountId()
+	self.Bandwidth = extPeering.GetBandwidth()
+}
+
+func (self *SVpcPeering) SyncWithCloudVpcPeering(ctx context.Context, userCred mcclient.TokenCredential, extPeering cloudprovider.ICloudVpcPeering) error {
+	diff, err := db.UpdateWithLock(ctx, self, func() error {
+		self.setCloudVpcPeeringAttrs(extPeering)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogSyncUpdate(self, diff, userCred)
+	return nil
+}
+
+func (manager *SVpcPeeringManager) newFromCloudVpcPeering(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, region *SCloudregion, extPeering cloudprovider.ICloudVpcPeering) (*SVpcPeering, error) {
+	peering := SVpcPeering{}
+	peering.SetModelManager(manager)
+
+	peering.Name = db.GenerateName(manager, provider.ProjectId, extPeering.GetName())
+	peering.ExternalId = extPeering.GetGlobalId()
+	peering.CloudregionId = region.Id
+	peering.ManagerId = provider.Id
+	peering.setCloudVpcPeeringAttrs(extPeering)
+	peering.ProjectId = provider.ProjectId
+	if len(peering.ProjectId) == 0 {
+		peering.ProjectId = userCred.GetProjectId()
+	}
+
+	err := manager.TableSpec().Insert(&peering)
+	if err != nil {
+		log.Errorf("newFromCloudVpcPeering fail %s", err)
+		return nil, err
+	}
+
+	db.OpsLog.LogEvent(&peering, db.ACT_CREATE, peering.GetShortDesc(ctx), userCred)
+	return &peering, nil
+}
+
+func (manager *SVpcPeeringManager) purgeAll(ctx context.Context, userCred mcclient.TokenCredential, providerId string) error {
+	peerings := make([]SVpcPeering, 0)
+	err := fetchByManagerId(manager, providerId, &peerings)
+	if err != nil {
+		return err
+	}
+	for i := range peerings {
+		err := peerings[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return fmt.Errorf("purge vpc peering %s fail %s", peerings[i].Id, err)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 	"yunion.io/x/pkg/util/netutils"
 	"yunion.io/x/sqlchemy"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
@@ -75,6 +76,9 @@ type SVpc struct {
 	CidrBlock string `width:"64" charset:"ascii" nullable:"true" list:"admin" create:"admin_required"`
 
 	CloudregionId string `width:"36" charset:"ascii" nullable:"false" list:"admin" create:"admin_required"`
+
+	// VNI of the VRF routing between the overlay wires of the vpc
+	Vni int `nullable:"true" list:"admin"`
 }
 
 func (manager *SVpcManager) GetContextManager() []db.IModelManager {
@@ -181,6 +185,13 @@ func (self *SVpc) GetRouteTableCount() int {
 	return self.GetRouteTableQuery().Count()
 }
 
+func (self *SVpc) GetNatGateways() []SNatGateway {
+	nats := make([]SNatGateway, 0)
+	q := NatGatewayManager.Query().Equals("vpc_id", self.Id)
+	db.FetchModelObjects(NatGatewayManager, q, &nats)
+	return nats
+}
+
 func (self *SVpc) getMoreDetails(extra *jsonutils.JSONDict) *jsonutils.JSONDict {
 	extra.Add(jsonutils.NewInt(int64(self.GetWireCount())), "wire_count")
 	extra.Add(jsonutils.NewInt(int64(self.GetNetworkCount())), "network_count")
@@ -201,6 +212,95 @@ func (self *SVpc) getMoreDetails(extra *jsonutils.JSONDict) *jsonutils.JSONDict
 	return extra
 }
 
+func (self *SVpc) AllowGetDetailsOverlayFdb(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGetSpec(userCred, self, "overlay-fdb")
+}
+
+// GetDetailsOverlayFdb lists the guest MAC/IPs on the overlay wires of the
+// vpc together with the VTEP of their hosts, which the hosts use to populate
+// the forwarding and neighbour tables of their VXLAN devices
+func (self *SVpc) GetDetailsOverlayFdb(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	entries, err := self.getOverlayFdbEntries()
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("query overlay fdb fail %s", err)
+	}
+	networks, err := self.getOverlayNetworks()
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("query overlay networks fail %s", err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Add(jsonutils.NewInt(int64(self.Vni)), "vni")
+	ret.Add(jsonutils.Marshal(networks), "networks")
+	ret.Add(jsonutils.Marshal(entries), "entries")
+	return ret, nil
+}
+
+func (self *SVpc) getOverlayNetworks() ([]api.SOverlayNetwork, error) {
+	wires := self.getWireQuery().Equals("wire_type", api.WIRE_TYPE_VXLAN).SubQuery()
+	networks := NetworkManager.Query().SubQuery()
+
+	q := networks.Query(wires.Field("vni"), networks.Field("guest_gateway"), networks.Field("guest_ip_mask"))
+	q = q.Join(wires, sqlchemy.Equals(wires.Field("id"), networks.Field("wire_id")))
+
+	rows := make([]struct {
+		Vni          int
+		GuestGateway string
+		GuestIpMask  int8
+	}, 0)
+	err := q.All(&rows)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	ret := make([]api.SOverlayNetwork, 0, len(rows))
+	for _, row := range rows {
+		ret = append(ret, api.SOverlayNetwork{
+			Vni:     row.Vni,
+			Gateway: row.GuestGateway,
+			Masklen: int(row.GuestIpMask),
+		})
+	}
+	return ret, nil
+}
+
+func (self *SVpc) getOverlayFdbEntries() ([]api.SOverlayFdbEntry, error) {
+	wires := self.getWireQuery().Equals("wire_type", api.WIRE_TYPE_VXLAN).SubQuery()
+	networks := NetworkManager.Query().SubQuery()
+	guestnetworks := GuestnetworkManager.Query().SubQuery()
+	guests := GuestManager.Query().SubQuery()
+	hosts := HostManager.Query().SubQuery()
+
+	q := guestnetworks.Query(guestnetworks.Field("mac_addr"), guestnetworks.Field("ip_addr"),
+		wires.Field("vni"), hosts.Field("access_ip"))
+	q = q.Join(networks, sqlchemy.Equals(networks.Field("id"), guestnetworks.Field("network_id")))
+	q = q.Join(wires, sqlchemy.Equals(wires.Field("id"), networks.Field("wire_id")))
+	q = q.Join(guests, sqlchemy.Equals(guests.Field("id"), guestnetworks.Field("guest_id")))
+	q = q.Join(hosts, sqlchemy.Equals(hosts.Field("id"), guests.Field("host_id")))
+
+	rows := make([]struct {
+		MacAddr  string
+		IpAddr   string
+		Vni      int
+		AccessIp string
+	}, 0)
+	err := q.All(&rows)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	entries := make([]api.SOverlayFdbEntry, 0, len(rows))
+	for _, row := range rows {
+		if len(row.AccessIp) == 0 {
+			continue
+		}
+		entries = append(entries, api.SOverlayFdbEntry{
+			Vni:  row.Vni,
+			Mac:  row.MacAddr,
+			Ip:   row.IpAddr,
+			Vtep: row.AccessIp,
+		})
+	}
+	return entries, nil
+}
+
 func (self *SVpc) getCloudProviderInfo() SCloudProviderInfo {
 	region, _ := self.GetRegion()
 	provider := self.GetCloudprovider()
@@ -215,6 +315,25 @@ func (self *SVpc) GetRegion() (*SCloudregion, error) {
 	return region.(*SCloudregion), nil
 }
 
+// fetchVpcByExternalId finds a synced vpc by its cloud id, an empty managerId
+// matches the vpcs of all the cloud providers, e.g. for the peer vpc of
+// another account
+func fetchVpcByExternalId(managerId string, extId string) (*SVpc, error) {
+	q := VpcManager.Query().Equals("external_id", extId)
+	if len(managerId) > 0 {
+		q = q.Equals("manager_id", managerId)
+	}
+	vpcs := make([]SVpc, 0)
+	err := db.FetchModelObjects(VpcManager, q, &vpcs)
+	if err != nil {
+		return nil, err
+	}
+	if len(vpcs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &vpcs[0], nil
+}
+
 func (self *SVpc) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
 	extra := self.SEnabledStatusStandaloneResourceBase.GetCustomizeColumns(ctx, userCred, query)
 	return self.getMoreDetails(extra)
@@ -532,6 +651,13 @@ func (self *SVpc) RealDelete(ctx context.Context, userCred mcclient.TokenCredent
 	for i := 0; i < len(routes); i++ {
 		routes[i].RealDelete(ctx, userCred)
 	}
+	nats := self.GetNatGateways()
+	for i := 0; i < len(nats); i++ {
+		err := nats[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return err
+		}
+	}
 	return self.SEnabledStatusStandaloneResourceBase.Delete(ctx, userCred)
 }
 
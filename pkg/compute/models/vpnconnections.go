@@ -0,0 +1,297 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SVpnConnectionManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var VpnConnectionManager *SVpnConnectionManager
+
+func init() {
+	VpnConnectionManager = &SVpnConnectionManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SVpnConnection{},
+			"vpn_connections_tbl",
+			"vpn_connection",
+			"vpn_connections",
+		),
+	}
+}
+
+type SVpnConnection struct {
+	db.SVirtualResourceBase
+	SManagedResourceBase
+
+	CloudregionId string `width:"36" charset:"ascii" nullable:"false" list:"user"`
+	VpcId         string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+
+	// cloud ids of the gateways, gateways are not synced as resources
+	VpnGatewayId           string `width:"256" charset:"utf8" nullable:"true" list:"user"`
+	VpnGatewayAddress      string `width:"64" charset:"ascii" nullable:"true" list:"user"`
+	CustomerGatewayId      string `width:"256" charset:"utf8" nullable:"true" list:"user"`
+	CustomerGatewayAddress string `width:"64" charset:"ascii" nullable:"true" list:"user"`
+
+	// comma separated cidrs at the two ends of the tunnel
+	LocalCidrs  string `width:"1024" charset:"ascii" nullable:"true" list:"user"`
+	RemoteCidrs string `width:"1024" charset:"ascii" nullable:"true" list:"user"`
+}
+
+func (manager *SVpnConnectionManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = managedResourceFilterByAccount(q, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	q = managedResourceFilterByCloudType(q, query, "", nil)
+
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	userProjId := userCred.GetProjectId()
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(q, data, []*validators.ModelFilterOptions{
+		{Key: "cloudregion", ModelKeyword: "cloudregion", ProjectId: userProjId},
+		{Key: "vpc", ModelKeyword: "vpc", ProjectId: userProjId},
+		{Key: "manager", ModelKeyword: "cloudprovider", ProjectId: userProjId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (manager *SVpnConnectionManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (manager *SVpnConnectionManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	return nil, httperrors.NewUnsupportOperationError("vpn connection can only be synchronized from cloud")
+}
+
+func (self *SVpnConnection) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (self *SVpnConnection) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SVpnConnection) AllowPerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "purge")
+}
+
+func (self *SVpnConnection) PerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	provider := self.GetCloudprovider()
+	if provider != nil && provider.Enabled {
+		return nil, httperrors.NewInvalidStatusError("Cannot purge vpn connection on enabled cloud provider")
+	}
+	err := self.RealDelete(ctx, userCred)
+	return nil, err
+}
+
+func (self *SVpnConnection) GetRegion() *SCloudregion {
+	region, err := CloudregionManager.FetchById(self.CloudregionId)
+	if err != nil {
+		log.Errorf("failed to find region for vpn connection %s", self.Name)
+		return nil
+	}
+	return region.(*SCloudregion)
+}
+
+func (self *SVpnConnection) getCloudProviderInfo() SCloudProviderInfo {
+	region := self.GetRegion()
+	provider := self.GetCloudprovider()
+	return MakeCloudProviderInfo(region, nil, provider)
+}
+
+func (self *SVpnConnection) getMoreDetails(extra *jsonutils.JSONDict) *jsonutils.JSONDict {
+	if len(self.VpcId) > 0 {
+		if vpc, err := VpcManager.FetchById(self.VpcId); err == nil {
+			extra.Set("vpc", jsonutils.NewString(vpc.GetName()))
+		}
+	}
+	info := self.getCloudProviderInfo()
+	extra.Update(jsonutils.Marshal(&info))
+	return extra
+}
+
+func (self *SVpnConnection) GetCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) *jsonutils.JSONDict {
+	extra := self.SVirtualResourceBase.GetCustomizeColumns(ctx, userCred, query)
+	return self.getMoreDetails(extra)
+}
+
+func (self *SVpnConnection) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*jsonutils.JSONDict, error) {
+	extra, err := self.SVirtualResourceBase.GetExtraDetails(ctx, userCred, query)
+	if err != nil {
+		return nil, err
+	}
+	return self.getMoreDetails(extra), nil
+}
+
+func (manager *SVpnConnectionManager) SyncVpnConnections(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, region *SCloudregion, conns []cloudprovider.ICloudVpnConnection) compare.SyncResult {
+	lockman.LockClass(ctx, manager, manager.GetOwnerId(userCred))
+	defer lockman.ReleaseClass(ctx, manager, manager.GetOwnerId(userCred))
+
+	syncResult := compare.SyncResult{}
+
+	dbConns := make([]SVpnConnection, 0)
+	q := manager.Query().Equals("cloudregion_id", region.Id).Equals("manager_id", provider.Id)
+	if err := db.FetchModelObjects(manager, q, &dbConns); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	removed := make([]SVpnConnection, 0)
+	commondb := make([]SVpnConnection, 0)
+	commonext := make([]cloudprovider.ICloudVpnConnection, 0)
+	added := make([]cloudprovider.ICloudVpnConnection, 0)
+	if err := compare.CompareSets(dbConns, conns, &removed, &commondb, &commonext, &added); err != nil {
+		syncResult.Error(err)
+		return syncResult
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err := removed[i].syncRemoveCloudVpnConnection(ctx, userCred)
+		if err != nil {
+			syncResult.DeleteError(err)
+		} else {
+			syncResult.Delete()
+		}
+	}
+
+	for i := 0; i < len(commondb); i += 1 {
+		err := commondb[i].SyncWithCloudVpnConnection(ctx, userCred, commonext[i])
+		if err != nil {
+			syncResult.UpdateError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, &commondb[i], commonext[i])
+		syncResult.Update()
+	}
+
+	for i := 0; i < len(added); i += 1 {
+		conn, err := manager.newFromCloudVpnConnection(ctx, userCred, provider, region, added[i])
+		if err != nil {
+			syncResult.AddError(err)
+			continue
+		}
+		syncMetadata(ctx, userCred, conn, added[i])
+		syncResult.Add()
+	}
+	return syncResult
+}
+
+func (self *SVpnConnection) syncRemoveCloudVpnConnection(ctx context.Context, userCred mcclient.TokenCredential) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	err := self.SVirtualResourceBase.ValidateDeleteCondition(ctx)
+	if err != nil {
+		self.SetStatus(userCred, api.VPN_CONNECTION_STATUS_UNKNOWN, "sync to delete")
+		return err
+	}
+	return self.RealDelete(ctx, userCred)
+}
+
+func (self *SVpnConnection) setCloudVpnConnectionAttrs(extConn cloudprovider.ICloudVpnConnection) {
+	self.Status = extConn.GetStatus()
+	self.VpcId = ""
+	if vpcId := extConn.GetVpcId(); len(vpcId) > 0 {
+		vpc, err := fetchVpcByExternalId(self.ManagerId, vpcId)
+		if err == nil {
+			self.VpcId = vpc.Id
+		} else {
+			log.Warningf("find vpc %s for vpn connection %s fail %s", vpcId, extConn.GetName(), err)
+		}
+	}
+	self.VpnGatewayId = extConn.GetVpnGatewayId()
+	self.VpnGatewayAddress = extConn.GetVpnGatewayAddress()
+	self.CustomerGatewayId = extConn.GetCustomerGatewayId()
+	self.CustomerGatewayAddress = extConn.GetCustomerGatewayAddress()
+	self.LocalCidrs = strings.Join(extConn.GetLocalCidrs(), ",")
+	self.RemoteCidrs = strings.Join(extConn.GetRemoteCidrs(), ",")
+}
+
+func (self *SVpnConnection) SyncWithCloudVpnConnection(ctx context.Context, userCred mcclient.TokenCredential, extConn cloudprovider.ICloudVpnConnection) error {
+	diff, err := db.UpdateWithLock(ctx, self, func() error {
+		self.setCloudVpnConnectionAttrs(extConn)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogSyncUpdate(self, diff, userCred)
+	return nil
+}
+
+func (manager *SVpnConnectionManager) newFromCloudVpnConnection(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, region *SCloudregion, extConn cloudprovider.ICloudVpnConnection) (*SVpnConnection, error) {
+	conn := SVpnConnection{}
+	conn.SetModelManager(manager)
+
+	conn.Name = db.GenerateName(manager, provider.ProjectId, extConn.GetName())
+	conn.ExternalId = extConn.GetGlobalId()
+	conn.CloudregionId = region.Id
+	conn.ManagerId = provider.Id
+	conn.setCloudVpnConnectionAttrs(extConn)
+	conn.ProjectId = provider.ProjectId
+	if len(conn.ProjectId) == 0 {
+		conn.ProjectId = userCred.GetProjectId()
+	}
+
+	err := manager.TableSpec().Insert(&conn)
+	if err != nil {
+		log.Errorf("newFromCloudVpnConnection fail %s", err)
+		return nil, err
+	}
+
+	db.OpsLog.LogEvent(&conn, db.ACT_CREATE, conn.GetShortDesc(ctx), userCred)
+	return &conn, nil
+}
+
+func (manager *SVpnConnectionManager) purgeAll(ctx context.Context, userCred mcclient.TokenCredential, providerId string) error {
+	conns := make([]SVpnConnection, 0)
+	err := fetchByManagerId(manager, providerId, &conns)
+	if err != nil {
+		return err
+	}
+	for i := range conns {
+		err := conns[i].RealDelete(ctx, userCred)
+		if err != nil {
+			return fmt.Errorf("purge vpn connection %s fail %s", conns[i].Id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type fakeVpnConnection struct {
+	cloudprovider.ICloudVpnConnection
+}
+
+func (conn *fakeVpnConnection) GetStatus() string                 { return "available" }
+func (conn *fakeVpnConnection) GetVpcId() string                  { return "" }
+func (conn *fakeVpnConnection) GetVpnGatewayId() string           { return "vgw-0" }
+func (conn *fakeVpnConnection) GetVpnGatewayAddress() string      { return "1.2.3.4" }
+func (conn *fakeVpnConnection) GetCustomerGatewayId() string      { return "cgw-0" }
+func (conn *fakeVpnConnection) GetCustomerGatewayAddress() string { return "5.6.7.8" }
+func (conn *fakeVpnConnection) GetLocalCidrs() []string {
+	return []string{"10.0.0.0/16", "10.1.0.0/16"}
+}
+func (conn *fakeVpnConnection) GetRemoteCidrs() []string { return []string{"192.168.0.0/24"} }
+
+func TestSVpnConnection_setCloudVpnConnectionAttrs(t *testing.T) {
+	conn := &SVpnConnection{VpcId: "stale"}
+	conn.setCloudVpnConnectionAttrs(&fakeVpnConnection{})
+	want := SVpnConnection{
+		VpnGatewayId:           "vgw-0",
+		VpnGatewayAddress:      "1.2.3.4",
+		CustomerGatewayId:      "cgw-0",
+		CustomerGatewayAddress: "5.6.7.8",
+		LocalCidrs:             "10.0.0.0/16,10.1.0.0/16",
+		RemoteCidrs:            "192.168.0.0/24",
+	}
+	want.Status = "available"
+	if conn.Status != want.Status || conn.VpcId != "" ||
+		conn.VpnGatewayId != want.VpnGatewayId || conn.VpnGatewayAddress != want.VpnGatewayAddress ||
+		conn.CustomerGatewayId != want.CustomerGatewayId || conn.CustomerGatewayAddress != want.CustomerGatewayAddress ||
+		conn.LocalCidrs != want.LocalCidrs || conn.RemoteCidrs != want.RemoteCidrs {
+		t.Errorf("want %#v got %#v", want, *conn)
+	}
+}
@@ -18,14 +18,17 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/tristate"
 	"yunion.io/x/pkg/util/compare"
 	"yunion.io/x/pkg/util/netutils"
+	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
@@ -60,6 +63,10 @@ type SWire struct {
 	ScheduleRank int    `list:"admin" update:"admin"`                                                     // = Column(Integer, default=0, nullable=True)
 	ZoneId       string `width:"36" charset:"ascii" nullable:"true" list:"admin" create:"admin_required"` // = Column(VARCHAR(36, charset='ascii'), nullable=False)
 	VpcId        string `wdith:"36" charset:"ascii" nullable:"false" list:"admin" create:"admin_required"`
+
+	WireType string `width:"16" charset:"ascii" nullable:"false" default:"vlan" list:"admin" create:"admin_optional"`
+	// VXLAN network identifier of an overlay wire
+	Vni int `nullable:"true" list:"admin" create:"admin_optional"`
 }
 
 func (manager *SWireManager) GetContextManager() []db.IModelManager {
@@ -107,11 +114,198 @@ func (manager *SWireManager) ValidateCreateData(ctx context.Context, userCred mc
 			}
 		}
 		data.Add(jsonutils.NewString(vpcObj.GetId()), "vpc_id")
+
+		wireType, _ := data.GetString("wire_type")
+		if len(wireType) == 0 {
+			wireType = api.WIRE_TYPE_VLAN
+		}
+		if !utils.IsInStringArray(wireType, api.WIRE_TYPES) {
+			return nil, httperrors.NewInputParameterError("invalid wire_type %s", wireType)
+		}
+		data.Set("wire_type", jsonutils.NewString(wireType))
+		if wireType == api.WIRE_TYPE_VXLAN {
+			if len(vpcObj.(*SVpc).ManagerId) > 0 {
+				return nil, httperrors.NewInputParameterError("overlay wire is only supported in on-premise vpc")
+			}
+			manager.lockVni(ctx)
+			defer manager.releaseVni(ctx)
+			vni, _ := data.Int("vni")
+			if vni > 0 {
+				if vni < api.VXLAN_VNI_MIN || vni > api.VXLAN_VNI_MAX {
+					return nil, httperrors.NewInputParameterError("vni out of range [%d, %d]", api.VXLAN_VNI_MIN, api.VXLAN_VNI_MAX)
+				}
+				if manager.isVniUsed(int(vni)) {
+					return nil, httperrors.NewConflictError("vni %d is in use", vni)
+				}
+			} else {
+				newVni, err := manager.allocVni()
+				if err != nil {
+					return nil, httperrors.NewInternalServerError("alloc vni fail %s", err)
+				}
+				vni = int64(newVni)
+			}
+			manager.reserveVni(int(vni))
+			data.Set("vni", jsonutils.NewInt(vni))
+		} else {
+			data.Remove("vni")
+		}
 	}
 
 	return manager.SStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerProjId, query, data)
 }
 
+// VNIs handed out by ValidateCreateData stay reserved until the wire is
+// saved, so concurrent creations do not get the same one. A reservation of
+// a failed creation expires after wireVniReserveTimeout.
+const wireVniReserveTimeout = time.Minute
+
+var wireVniReserved = make(map[int]time.Time)
+
+// lockVni serializes the allocation of VNIs, wireVniReserved is only
+// accessed with it held
+func (manager *SWireManager) lockVni(ctx context.Context) {
+	lockman.LockRawObject(ctx, manager.Keyword(), "vni")
+}
+
+func (manager *SWireManager) releaseVni(ctx context.Context) {
+	lockman.ReleaseRawObject(ctx, manager.Keyword(), "vni")
+}
+
+func (manager *SWireManager) reserveVni(vni int) {
+	wireVniReserved[vni] = time.Now().Add(wireVniReserveTimeout)
+}
+
+func (manager *SWireManager) reservedVnis() map[int]bool {
+	reserved := make(map[int]bool)
+	now := time.Now()
+	for vni, expire := range wireVniReserved {
+		if now.After(expire) {
+			delete(wireVniReserved, vni)
+			continue
+		}
+		reserved[vni] = true
+	}
+	return reserved
+}
+
+// usedVnis returns the VNIs saved to overlay wires and vpcs
+func (manager *SWireManager) usedVnis() (map[int]bool, error) {
+	used := make(map[int]bool)
+	wires := make([]SWire, 0)
+	err := db.FetchModelObjects(manager, manager.Query().GT("vni", 0), &wires)
+	if err != nil {
+		return nil, err
+	}
+	for i := range wires {
+		used[wires[i].Vni] = true
+	}
+	vpcs := make([]SVpc, 0)
+	err = db.FetchModelObjects(VpcManager, VpcManager.Query().GT("vni", 0), &vpcs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range vpcs {
+		used[vpcs[i].Vni] = true
+	}
+	return used, nil
+}
+
+func (manager *SWireManager) isVniUsed(vni int) bool {
+	used, err := manager.usedVnis()
+	if err != nil {
+		log.Errorf("fetch used vnis fail %s", err)
+		return true
+	}
+	return used[vni] || manager.reservedVnis()[vni]
+}
+
+// allocVni returns the smallest VNI neither taken by any overlay wire or
+// vpc nor reserved, the numbers in exclude are allocated but not saved yet
+func (manager *SWireManager) allocVni(exclude ...int) (int, error) {
+	used, err := manager.usedVnis()
+	if err != nil {
+		return 0, err
+	}
+	for vni := range manager.reservedVnis() {
+		used[vni] = true
+	}
+	for _, vni := range exclude {
+		used[vni] = true
+	}
+	for vni := api.VXLAN_VNI_MIN; vni <= api.VXLAN_VNI_MAX; vni++ {
+		if !used[vni] {
+			return vni, nil
+		}
+	}
+	return 0, fmt.Errorf("no vni available")
+}
+
+func (wire *SWire) IsOverlay() bool {
+	return wire.WireType == api.WIRE_TYPE_VXLAN
+}
+
+func (wire *SWire) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if wire.IsOverlay() {
+		WireManager.lockVni(ctx)
+		defer WireManager.releaseVni(ctx)
+		// the VNI is checked again when saving, a vpc may have taken it since
+		used, err := WireManager.usedVnis()
+		if err != nil {
+			return httperrors.NewInternalServerError("fetch used vnis fail %s", err)
+		}
+		if used[wire.Vni] {
+			return httperrors.NewConflictError("vni %d is in use", wire.Vni)
+		}
+		// the vpc routes between its overlay wires in a VRF identified by a VNI of its own
+		vpc := wire.getVpc()
+		if vpc == nil {
+			return httperrors.NewResourceNotFoundError("vpc %s not found", wire.VpcId)
+		}
+		if vpc.Vni == 0 {
+			vni, err := WireManager.allocVni(wire.Vni)
+			if err != nil {
+				return httperrors.NewInternalServerError("alloc vni fail %s", err)
+			}
+			_, err = db.Update(vpc, func() error {
+				vpc.Vni = vni
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return wire.SStandaloneResourceBase.CustomizeCreate(ctx, userCred, ownerProjId, query, data)
+}
+
+func (wire *SWire) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId string, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	wire.SStandaloneResourceBase.PostCreate(ctx, userCred, ownerProjId, query, data)
+	if wire.IsOverlay() {
+		WireManager.lockVni(ctx)
+		delete(wireVniReserved, wire.Vni)
+		WireManager.releaseVni(ctx)
+		wire.attachOverlayHosts(ctx, userCred)
+	}
+}
+
+// attachOverlayHosts attaches an overlay wire to all KVM hosts of its zone
+// through their master interface, which terminates the VXLAN tunnels
+func (wire *SWire) attachOverlayHosts(ctx context.Context, userCred mcclient.TokenCredential) {
+	hosts := make([]SHost, 0)
+	q := HostManager.Query().Equals("zone_id", wire.ZoneId).Equals("host_type", HOST_TYPE_HYPERVISOR)
+	err := db.FetchModelObjects(HostManager, q, &hosts)
+	if err != nil {
+		log.Errorf("fetch hosts of zone %s fail %s", wire.ZoneId, err)
+		return
+	}
+	for i := range hosts {
+		err := hosts[i].attachOverlayWire(ctx, userCred, wire)
+		if err != nil {
+			log.Errorf("attach overlay wire %s to host %s fail %s", wire.Name, hosts[i].Name, err)
+		}
+	}
+}
+
 func (wire *SWire) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
 	bandwidth, err := data.Int("bandwidth")
 	if err == nil && bandwidth <= 0 {
@@ -121,12 +315,30 @@ func (wire *SWire) ValidateUpdateData(ctx context.Context, userCred mcclient.Tok
 }
 
 func (wire *SWire) ValidateDeleteCondition(ctx context.Context) error {
-	if wire.HostCount() > 0 || wire.NetworkCount() > 0 {
+	// hosts are attached to an overlay wire automatically and detached on delete
+	if (!wire.IsOverlay() && wire.HostCount() > 0) || wire.NetworkCount() > 0 {
 		return httperrors.NewNotEmptyError("not an empty wire")
 	}
 	return wire.SStandaloneResourceBase.ValidateDeleteCondition(ctx)
 }
 
+func (wire *SWire) PreDelete(ctx context.Context, userCred mcclient.TokenCredential) {
+	wire.SStandaloneResourceBase.PreDelete(ctx, userCred)
+	if wire.IsOverlay() {
+		hostwires, err := wire.GetHostwires()
+		if err != nil {
+			log.Errorf("fetch hostwires of %s fail %s", wire.Name, err)
+			return
+		}
+		for i := range hostwires {
+			hostwires[i].SetModelManager(HostwireManager)
+			if err := hostwires[i].Detach(ctx, userCred); err != nil {
+				log.Errorf("detach hostwire fail %s", err)
+			}
+		}
+	}
+}
+
 func (wire *SWire) getHostwireQuery() *sqlchemy.SQuery {
 	return HostwireManager.Query().Equals("wire_id", wire.Id)
 }
@@ -683,6 +895,10 @@ func (manager *SWireManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQu
 		q = q.Equals("vpc_id", vpc.GetId())
 	}
 
+	if wireType, _ := query.GetString("wire_type"); len(wireType) > 0 {
+		q = q.Equals("wire_type", wireType)
+	}
+
 	regionStr := jsonutils.GetAnyString(query, []string{"region_id", "region", "cloudregion_id", "cloudregion"})
 	if len(regionStr) > 0 {
 		region, err := CloudregionManager.FetchByIdOrName(userCred, regionStr)
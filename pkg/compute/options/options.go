@@ -77,6 +77,19 @@ type ComputeOptions struct {
 	SyncSkusDay  int `default:"1" help:"Days auto sync skus data, default 1 day"`
 	SyncSkusHour int `default:"3" help:"What hour start sync skus, default 03:00"`
 
+	// cost collection
+	CollectCostsHour int `default:"6" help:"What hour start collect costs of yesterday, default 06:00"`
+
+	// cloud provider api calls
+	CloudApiQps                    int `default:"10" help:"Maximal calls per second of each api of a cloud account, default 10"`
+	CloudApiBurst                  int `default:"20" help:"Maximal burst calls of each api of a cloud account, default 20"`
+	CloudApiMaxRetries             int `default:"5" help:"Maximal retries of a throttled cloud api call, default 5"`
+	CloudApiBackoffMaxSeconds      int `default:"30" help:"Maximal interval between retries of a throttled cloud api call, default 30 seconds"`
+	CloudApiCircuitBreakerFailures int `default:"10" help:"Consecutive failures of a cloud api before further calls are rejected, default 10"`
+	CloudApiCircuitBreakerSeconds  int `default:"60" help:"Seconds to reject calls of a failing cloud api before trying again, default 60 seconds"`
+
+	CloudApiMetricsEnabled bool `default:"false" help:"Expose the metrics of cloud api calls at /metrics without authentication, default false"`
+
 	// aws instance type file
 	DefaultAwsInstanceTypeFile string `default:"/etc/yunion/aws_instance_types.json" help:"aws instance type json file"`
 
@@ -91,6 +104,14 @@ type ComputeOptions struct {
 	HostOfflineMaxSeconds        int `help:"Maximal seconds interval that a host considered offline during which it did not ping region, default is 3 minues" default:"180"`
 	HostOfflineDetectionInterval int `help:"Interval to check offline hosts, defualt is half a minute" default:"30"`
 
+	HostFailoverEnabled        bool `help:"Fence failed KVM hosts and restart their HA guests on other hosts" default:"false"`
+	HostFailoverDelaySeconds   int  `help:"Seconds a KVM host must have missed pings before it is considered failed, default 5 minutes" default:"300"`
+	HostFailoverWithoutFencing bool `help:"Restart HA guests of a failed host that can not be powered off through IPMI, at the risk of running a guest twice" default:"false"`
+
+	PreemptibleGuestEvictAction string `help:"How preemptible guests are evicted for higher priority guests" choices:"stop|delete" default:"stop"`
+
+	ScheduleDecisionKeepDays int `help:"Days to keep the scheduler decisions of guests and disks" default:"30"`
+
 	MinimalIpAddrReusedIntervalSeconds int `help:"Minimal seconds when a release IP address can be reallocate" default:"30"`
 
 	CloudSyncWorkerCount         int `help:"how many current synchronization threads" default:"2"`
@@ -103,6 +124,15 @@ type ComputeOptions struct {
 
 	DisconnectedCloudAccountRetryProbeIntervalHours int `help:"interval to wait to probe status of a disconnected cloud account" default:"24"`
 
+	EventSyncFullSyncIntervalSeconds int `help:"interval of full synchronization of the regions whose changes are synchronized through events, default 6 hours" default:"21600"`
+	EventSyncDelaySeconds            int `help:"events of this many seconds before the last event synchronization are fetched again to tolerate the delay of change feeds" default:"600"`
+
+	GuestRebalanceIntervalMinutes int     `help:"Interval to rebalance guests of each zone by live migration, 0 to disable" default:"0"`
+	GuestRebalanceMetric          string  `help:"What to balance across hosts, allocated vcpu and memory or measured load" choices:"allocation|load" default:"allocation"`
+	GuestRebalanceThreshold       float32 `help:"Maximal difference of the host usage ratio tolerated before rebalancing, default 0.2" default:"0.2"`
+	GuestRebalanceMaxMoves        int     `help:"Maximal guests to migrate of each rebalance, default 10" default:"10"`
+	GuestRebalanceConcurrency     int     `help:"Maximal concurrent live migrations of each rebalance, default 2" default:"2"`
+
 	SCapabilityOptions
 	common_options.CommonOptions
 	common_options.DBOptions
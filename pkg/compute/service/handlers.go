@@ -20,6 +20,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
 	"yunion.io/x/onecloud/pkg/compute/capabilities"
 	"yunion.io/x/onecloud/pkg/compute/misc"
 	"yunion.io/x/onecloud/pkg/compute/models"
@@ -38,6 +39,7 @@ func InitHandlers(app *appsrv.Application) {
 	sshkeys.AddSshKeysHandler("", app)
 	taskman.AddTaskHandler("", app)
 	misc.AddMiscHandler("", app)
+	policy.AddPolicySimulateHandler("", app)
 
 	for _, manager := range []db.IModelManager{
 		taskman.TaskManager,
@@ -81,6 +83,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.SecurityGroupRuleManager,
 		// models.VCenterManager,
 		models.DnsRecordManager,
+		models.DnsZoneManager,
 		models.ElasticipManager,
 		models.SnapshotManager,
 		models.BaremetalagentManager,
@@ -93,12 +96,25 @@ func InitHandlers(app *appsrv.Application) {
 		models.LoadbalancerAclManager,
 		models.LoadbalancerAgentManager,
 		models.RouteTableManager,
+		models.NatGatewayManager,
+		models.NatSEntryManager,
+		models.NatDEntryManager,
+		models.VpcPeeringManager,
+		models.VpnConnectionManager,
+		models.BucketManager,
+		models.DBInstanceManager,
 
 		models.SchedpolicyManager,
 		models.DynamicschedtagManager,
 
 		models.ServerSkuManager,
 		models.ExternalProjectManager,
+
+		models.CloudcostManager,
+		models.CostRateManager,
+
+		models.ScheduleDecisionManager,
+		models.ScheduleExplainManager,
 	} {
 		db.RegisterModelManager(manager)
 		handler := db.NewModelHandler(manager)
@@ -22,11 +22,13 @@ import (
 
 	"yunion.io/x/log"
 
+	"yunion.io/x/onecloud/pkg/appsrv"
 	"yunion.io/x/onecloud/pkg/cloudcommon"
 	app_common "yunion.io/x/onecloud/pkg/cloudcommon/app"
 	"yunion.io/x/onecloud/pkg/cloudcommon/cronman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	_ "yunion.io/x/onecloud/pkg/compute/guestdrivers"
 	_ "yunion.io/x/onecloud/pkg/compute/hostdrivers"
 	"yunion.io/x/onecloud/pkg/compute/models"
@@ -67,6 +69,9 @@ func StartService() {
 
 	app := app_common.InitApp(commonOpts, true)
 	cloudcommon.AppDBInit(app)
+	if opts.CloudApiMetricsEnabled {
+		app.AddDefaultHandler("GET", "/metrics", appsrv.MetricsHandler, "metrics")
+	}
 	InitHandlers(app)
 
 	if !db.CheckSync(opts.AutoSyncTable) {
@@ -83,6 +88,15 @@ func StartService() {
 		log.Errorf("setInfluxdbRetentionPolicy fail: %s", err)
 	}
 
+	cloudprovider.SetApiCallConfig(cloudprovider.SApiCallConfig{
+		Qps:             float64(opts.CloudApiQps),
+		Burst:           opts.CloudApiBurst,
+		MaxRetries:      opts.CloudApiMaxRetries,
+		BackoffBase:     time.Second,
+		BackoffMax:      time.Duration(opts.CloudApiBackoffMaxSeconds) * time.Second,
+		BreakerFailures: opts.CloudApiCircuitBreakerFailures,
+		BreakerCooldown: time.Duration(opts.CloudApiCircuitBreakerSeconds) * time.Second,
+	})
 	models.InitSyncWorkers(options.Options.CloudSyncWorkerCount)
 
 	cron := cronman.GetCronJobManager(true)
@@ -92,12 +106,21 @@ func StartService() {
 	if opts.PrepaidExpireCheck {
 		cron.AddJob1("CleanExpiredPrepaidServers", time.Duration(opts.PrepaidExpireCheckSeconds)*time.Second, models.GuestManager.DeleteExpiredPrepaidServers)
 	}
+	cron.AddJob1("CleanExpiredScheduleDecisions", time.Hour, models.ScheduleDecisionManager.CleanExpiredDecisions)
 	cron.AddJob1("StartHostPingDetectionTask", time.Duration(opts.HostOfflineDetectionInterval)*time.Second, models.HostManager.PingDetectionTask)
+	if opts.HostFailoverEnabled {
+		cron.AddJob1("StartHostFailoverDetectionTask", time.Duration(opts.HostOfflineDetectionInterval)*time.Second, models.HostManager.FailoverDetectionTask)
+	}
+
+	if opts.GuestRebalanceIntervalMinutes > 0 {
+		cron.AddJob1("AutoRebalanceGuests", time.Duration(opts.GuestRebalanceIntervalMinutes)*time.Minute, models.ZoneManager.AutoRebalanceGuests)
+	}
 
 	cron.AddJob1WithStartRun("AutoSyncCloudaccountTask", time.Duration(opts.CloudAutoSyncIntervalSeconds)*time.Second, models.CloudaccountManager.AutoSyncCloudaccountTask, true)
 
 	cron.AddJob2("AutoDiskSnapshot", opts.AutoSnapshotDay, opts.AutoSnapshotHour, 0, 0, models.DiskManager.AutoDiskSnapshot, false)
 	cron.AddJob2("SyncSkus", opts.SyncSkusDay, opts.SyncSkusHour, 0, 0, models.SyncSkus, true)
+	cron.AddJob2("CollectCosts", 1, opts.CollectCostsHour, 0, 0, models.CloudcostManager.CollectCosts, false)
 
 	cron.Start()
 	defer cron.Stop()
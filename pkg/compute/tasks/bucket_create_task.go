@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type BucketCreateTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(BucketCreateTask{})
+}
+
+func (self *BucketCreateTask) TaskFailed(ctx context.Context, bucket *models.SBucket, err error) {
+	bucket.SetStatus(self.UserCred, api.BUCKET_STATUS_CREATE_FAILED, err.Error())
+	db.OpsLog.LogEvent(bucket, db.ACT_ALLOCATE_FAIL, err.Error(), self.UserCred)
+	self.SetStageFailed(ctx, err.Error())
+}
+
+func (self *BucketCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	bucket := obj.(*models.SBucket)
+	iregion, err := bucket.GetIRegion()
+	if err != nil {
+		self.TaskFailed(ctx, bucket, fmt.Errorf("fetch cloud region: %s", err))
+		return
+	}
+	ibucket, err := iregion.CreateIBucket(bucket.Name, bucket.StorageClass, bucket.Acl)
+	if err != nil {
+		self.TaskFailed(ctx, bucket, fmt.Errorf("create cloud bucket: %s", err))
+		return
+	}
+	err = bucket.SyncWithCloudBucket(ctx, self.UserCred, ibucket)
+	if err != nil {
+		self.TaskFailed(ctx, bucket, err)
+		return
+	}
+	_, err = db.Update(bucket, func() error {
+		bucket.ExternalId = ibucket.GetGlobalId()
+		return nil
+	})
+	if err != nil {
+		self.TaskFailed(ctx, bucket, err)
+		return
+	}
+	db.OpsLog.LogEvent(bucket, db.ACT_ALLOCATE, bucket.GetShortDesc(ctx), self.UserCred)
+	self.SetStageComplete(ctx, nil)
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type BucketDeleteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(BucketDeleteTask{})
+}
+
+func (self *BucketDeleteTask) TaskFailed(ctx context.Context, bucket *models.SBucket, err error) {
+	bucket.SetStatus(self.UserCred, api.BUCKET_STATUS_DELETE_FAILED, err.Error())
+	db.OpsLog.LogEvent(bucket, db.ACT_DELOCATE_FAIL, err.Error(), self.UserCred)
+	self.SetStageFailed(ctx, err.Error())
+}
+
+func (self *BucketDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	bucket := obj.(*models.SBucket)
+	if len(bucket.ExternalId) > 0 {
+		iregion, err := bucket.GetIRegion()
+		if err != nil {
+			self.TaskFailed(ctx, bucket, fmt.Errorf("fetch cloud region: %s", err))
+			return
+		}
+		err = iregion.DeleteIBucket(bucket.ExternalId)
+		if err != nil {
+			self.TaskFailed(ctx, bucket, fmt.Errorf("delete cloud bucket: %s", err))
+			return
+		}
+	}
+	db.OpsLog.LogEvent(bucket, db.ACT_DELOCATE, bucket.GetShortDesc(ctx), self.UserCred)
+	bucket.RealDelete(ctx, self.UserCred)
+	self.SetStageComplete(ctx, nil)
+}
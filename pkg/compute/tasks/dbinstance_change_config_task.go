@@ -0,0 +1,75 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type DBInstanceChangeConfigTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DBInstanceChangeConfigTask{})
+}
+
+func (self *DBInstanceChangeConfigTask) TaskFailed(ctx context.Context, instance *models.SDBInstance, err error) {
+	instance.SetStatus(self.UserCred, api.DBINSTANCE_STATUS_CHANGE_CONFIG_FAILED, err.Error())
+	db.OpsLog.LogEvent(instance, db.ACT_CHANGE_FLAVOR_FAIL, err.Error(), self.UserCred)
+	self.SetStageFailed(ctx, err.Error())
+}
+
+func (self *DBInstanceChangeConfigTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	instance := obj.(*models.SDBInstance)
+	iinstance, err := instance.GetIDBInstance()
+	if err != nil {
+		self.TaskFailed(ctx, instance, fmt.Errorf("fetch cloud dbinstance: %s", err))
+		return
+	}
+	config := cloudprovider.SDBInstanceChangeConfig{}
+	err = self.GetParams().Unmarshal(&config)
+	if err != nil {
+		self.TaskFailed(ctx, instance, fmt.Errorf("invalid config: %s", err))
+		return
+	}
+	err = iinstance.ChangeConfig(&config)
+	if err != nil {
+		self.TaskFailed(ctx, instance, fmt.Errorf("change config of cloud dbinstance: %s", err))
+		return
+	}
+	err = instance.WaitCloudStatus(iinstance, api.DBINSTANCE_STATUS_RUNNING)
+	if err != nil {
+		self.TaskFailed(ctx, instance, err)
+		return
+	}
+	provider := instance.GetCloudprovider()
+	err = instance.SyncWithCloudDBInstance(ctx, self.UserCred, provider, iinstance)
+	if err != nil {
+		self.TaskFailed(ctx, instance, fmt.Errorf("sync cloud dbinstance: %s", err))
+		return
+	}
+	db.OpsLog.LogEvent(instance, db.ACT_CHANGE_FLAVOR, instance.GetShortDesc(ctx), self.UserCred)
+	self.SetStageComplete(ctx, nil)
+}
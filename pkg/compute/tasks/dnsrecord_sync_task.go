@@ -0,0 +1,65 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+// DnsRecordSyncTask pushes the records of a dnsrecord of a cloud dns zone
+// to the provider
+type DnsRecordSyncTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DnsRecordSyncTask{})
+}
+
+func (self *DnsRecordSyncTask) TaskFailed(ctx context.Context, record *models.SDnsRecord, err error) {
+	record.SetStatus(self.UserCred, api.DNS_RECORD_STATUS_SYNC_FAILED, err.Error())
+	db.OpsLog.LogEvent(record, db.ACT_SYNC_CONF_FAIL, err.Error(), self.UserCred)
+	self.SetStageFailed(ctx, err.Error())
+}
+
+func (self *DnsRecordSyncTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	record := obj.(*models.SDnsRecord)
+	zone := record.GetDnsZone()
+	if zone == nil {
+		self.TaskFailed(ctx, record, fmt.Errorf("fail to find dns zone of dnsrecord %s", record.Name))
+		return
+	}
+	iZone, err := zone.GetICloudDnsZone()
+	if err != nil {
+		self.TaskFailed(ctx, record, fmt.Errorf("fetch cloud dns zone: %s", err))
+		return
+	}
+	err = record.RemoteUpdate(zone, iZone, false)
+	if err != nil {
+		self.TaskFailed(ctx, record, err)
+		return
+	}
+	record.SetStatus(self.UserCred, api.DNS_RECORD_STATUS_AVAILABLE, "")
+	db.OpsLog.LogEvent(record, db.ACT_SYNC_CONF, record.GetShortDesc(ctx), self.UserCred)
+	self.SetStageComplete(ctx, nil)
+}
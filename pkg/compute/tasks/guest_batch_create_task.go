@@ -144,7 +144,12 @@ func (self *GuestBatchCreateTask) allocateGuestOnHost(ctx context.Context, guest
 		return nil
 	}
 
-	err = guest.StartGuestCreateTask(ctx, self.UserCred, input, nil, self.GetId())
+	params := input.JSON(input)
+	if len(candidate.PreemptGuests) > 0 {
+		// the guest is created after the eviction of the preemptible guests
+		params.Add(jsonutils.NewStringArray(candidate.PreemptGuests), "preempt_guests")
+	}
+	err = guest.GetDriver().StartGuestCreateTask(guest, ctx, self.UserCred, params, nil, self.GetId())
 	if err != nil {
 		log.Errorf("start guest create task fail %s", err)
 		guest.SetStatus(self.UserCred, models.VM_CREATE_FAILED, err.Error())
@@ -39,10 +39,58 @@ func init() {
 }
 
 func (self *GuestCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	if self.Params.Contains("preempt_guests") {
+		self.OnGuestPreempted(ctx, obj, nil)
+		return
+	}
+	self.startCreate(ctx, obj.(*models.SGuest))
+}
+
+// OnGuestPreempted evicts the preemptible guests chosen by the scheduler one
+// by one, the guest is created on host after all of them are gone. A victim
+// evicted by another guest placed on the host is polled until it is gone.
+func (self *GuestCreateTask) OnGuestPreempted(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	victims, _ := self.Params.GetArray("preempt_guests")
+	guestIds := jsonutils.JSONArray2StringArray(victims)
+	idx, _ := self.Params.Int("preempt_index")
+	for ; int(idx) < len(guestIds); idx++ {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewInt(idx+1), "preempt_index")
+		self.SetStage("OnGuestPreempted", params)
+		state, err := models.GuestManager.PreemptGuest(ctx, self.UserCred, guest, guestIds[idx], self.GetTaskId())
+		if err != nil {
+			self.OnGuestPreemptedFailed(ctx, guest, jsonutils.NewString(err.Error()))
+			return
+		}
+		switch state {
+		case models.GUEST_PREEMPT_STARTED:
+			return
+		case models.GUEST_PREEMPT_EVICTING:
+			params.Set("preempt_index", jsonutils.NewInt(idx))
+			self.SetStage("OnGuestPreempted", params)
+			time.Sleep(time.Second * 2)
+			self.ScheduleRun(nil)
+			return
+		}
+	}
+	self.startCreate(ctx, guest)
+}
+
+func (self *GuestCreateTask) OnGuestPreemptedFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
 	guest := obj.(*models.SGuest)
+	reason := fmt.Sprintf("preempt guests fail: %s", data)
+	guest.SetStatus(self.UserCred, models.VM_CREATE_FAILED, reason)
+	db.OpsLog.LogEvent(guest, db.ACT_ALLOCATE_FAIL, reason, self.UserCred)
+	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_ALLOCATE, reason, self.UserCred, false)
+	notifyclient.NotifySystemError(guest.Id, guest.Name, models.VM_CREATE_FAILED, reason)
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *GuestCreateTask) startCreate(ctx context.Context, guest *models.SGuest) {
 	guest.SetStatus(self.UserCred, models.VM_CREATE_NETWORK, "")
 	self.SetStage("on_wait_guest_networks_ready", nil)
-	self.OnWaitGuestNetworksReady(ctx, obj, nil)
+	self.OnWaitGuestNetworksReady(ctx, guest, nil)
 }
 
 func (self *GuestCreateTask) OnWaitGuestNetworksReady(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
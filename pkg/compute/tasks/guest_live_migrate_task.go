@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 	"yunion.io/x/pkg/utils"
 
 	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
@@ -203,10 +204,22 @@ func (self *GuestMigrateTask) OnUndeployOldHostSucc(ctx context.Context, guest *
 	self.TaskComplete(ctx, guest)
 }
 
+func (self *GuestMigrateTask) OnUndeployOldHostSuccFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	if jsonutils.QueryBoolean(self.Params, "is_rescue_mode", false) {
+		// the old host of a rescued guest is usually unreachable, it cleans
+		// up the guest by itself when it comes back
+		log.Warningf("undeploy guest %s from old host: %s", guest.Name, data)
+		self.TaskComplete(ctx, guest)
+		return
+	}
+	self.TaskFailed(ctx, guest, data.String())
+}
+
 func (self *GuestMigrateTask) sharedStorageMigrateConf(ctx context.Context, guest *models.SGuest, targetHost *models.SHost) (*jsonutils.JSONDict, bool) {
 	body := jsonutils.NewDict()
 	body.Set("is_local_storage", jsonutils.JSONFalse)
 	body.Set("qemu_version", jsonutils.NewString(guest.GetQemuVersion(self.UserCred)))
+	body.Set("serial_console", jsonutils.NewBool(guest.GetMetadata("__serial_console", self.UserCred) == "true"))
 	targetDesc := guest.GetJsonDescAtHypervisor(ctx, targetHost)
 	body.Set("desc", targetDesc)
 	return body, false
@@ -239,6 +252,7 @@ func (self *GuestMigrateTask) localStorageMigrateConf(ctx context.Context,
 	body.Set("disks_uri", jsonutils.NewString(disksUri))
 	body.Set("server_url", jsonutils.NewString(serverUrl))
 	body.Set("qemu_version", jsonutils.NewString(guest.GetQemuVersion(self.UserCred)))
+	body.Set("serial_console", jsonutils.NewBool(guest.GetMetadata("__serial_console", self.UserCred) == "true"))
 	targetDesc := guest.GetJsonDescAtHypervisor(ctx, targetHost)
 	jsonDisks, _ := targetDesc.Get("disks")
 	if jsonDisks == nil {
@@ -392,6 +406,10 @@ func (self *GuestLiveMigrateTask) OnGuestSyncStatus(ctx context.Context, guest *
 }
 
 func (self *GuestMigrateTask) TaskComplete(ctx context.Context, guest *models.SGuest) {
+	if jsonutils.QueryBoolean(self.Params, "is_rescue_mode", false) {
+		// the guest is restarted, the outage it was recorded for is over
+		guest.SetMetadata(ctx, models.VM_METADATA_HA_RUNNING, "", self.UserCred)
+	}
 	self.SetStageComplete(ctx, nil)
 	db.OpsLog.LogEvent(guest, db.ACT_MIGRATE, "Migrate success", self.UserCred)
 	logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_MIGRATE, "", self.UserCred, true)
@@ -0,0 +1,143 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+// GuestRebalanceTask live migrates the planned moves of a zone rebalance or a
+// host drain, at most concurrency guests at a time
+type GuestRebalanceTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(GuestRebalanceTask{})
+}
+
+func (self *GuestRebalanceTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.startNextBatch(ctx, obj)
+}
+
+func (self *GuestRebalanceTask) startNextBatch(ctx context.Context, obj db.IStandaloneModel) {
+	moves := make([]api.SRebalanceMove, 0)
+	err := self.Params.Unmarshal(&moves, "moves")
+	if err != nil {
+		self.taskFailed(ctx, obj, fmt.Sprintf("unmarshal moves: %v", err))
+		return
+	}
+	concurrency, _ := self.Params.Int("concurrency")
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	next, _ := self.Params.Int("next")
+
+	for int(next) < len(moves) {
+		end := int(next + concurrency)
+		if end > len(moves) {
+			end = len(moves)
+		}
+		guests := make([]*models.SGuest, 0)
+		dstHostIds := make([]string, 0)
+		for _, move := range moves[next:end] {
+			guest, err := self.checkMove(move)
+			if err != nil {
+				log.Warningf("rebalance skip guest %s: %v", move.Guest, err)
+				continue
+			}
+			guests = append(guests, guest)
+			dstHostIds = append(dstHostIds, move.DstHostId)
+		}
+		next = int64(end)
+
+		// subtasks are bound to the stage of the parent when they are created
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewInt(next), "next")
+		self.SetStage("OnMigrateBatchComplete", params)
+
+		started := 0
+		for i, guest := range guests {
+			err := self.startMove(ctx, guest, dstHostIds[i])
+			if err != nil {
+				log.Errorf("rebalance migrate guest %s: %v", guest.Name, err)
+				continue
+			}
+			started += 1
+		}
+		if started > 0 {
+			return
+		}
+	}
+	// every batch runs in the same stage, so its subtasks cover all the moves
+	migrated := taskman.SubTaskManager.GetTotalSubtasks(self.Id, self.Stage, taskman.SUBTASK_SUCC)
+	self.taskComplete(ctx, obj, len(migrated), len(moves))
+}
+
+// checkMove tells whether the guest is still where the plan found it
+func (self *GuestRebalanceTask) checkMove(move api.SRebalanceMove) (*models.SGuest, error) {
+	guest := models.GuestManager.FetchGuestById(move.GuestId)
+	if guest == nil {
+		return nil, fmt.Errorf("guest not found")
+	}
+	if guest.HostId != move.SrcHostId {
+		return nil, fmt.Errorf("guest is no longer on host %s", move.SrcHost)
+	}
+	if guest.Status != models.VM_RUNNING {
+		return nil, fmt.Errorf("guest status %s", guest.Status)
+	}
+	return guest, nil
+}
+
+func (self *GuestRebalanceTask) startMove(ctx context.Context, guest *models.SGuest, dstHostId string) error {
+	lockman.LockObject(ctx, guest)
+	defer lockman.ReleaseObject(ctx, guest)
+
+	return guest.StartGuestLiveMigrateTask(ctx, self.UserCred, guest.Status, dstHostId, self.GetTaskId())
+}
+
+func (self *GuestRebalanceTask) OnMigrateBatchComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.startNextBatch(ctx, obj)
+}
+
+func (self *GuestRebalanceTask) OnMigrateBatchCompleteFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	// a failed migration leaves its guest on the source host, go on with the others
+	self.startNextBatch(ctx, obj)
+}
+
+func (self *GuestRebalanceTask) taskComplete(ctx context.Context, obj db.IStandaloneModel, migrated, total int) {
+	notes := fmt.Sprintf("%d of %d guests migrated", migrated, total)
+	if migrated < total {
+		db.OpsLog.LogEvent(obj, db.ACT_REBALANCE_FAIL, notes, self.UserCred)
+	} else {
+		db.OpsLog.LogEvent(obj, db.ACT_REBALANCE, notes, self.UserCred)
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestRebalanceTask) taskFailed(ctx context.Context, obj db.IStandaloneModel, reason string) {
+	db.OpsLog.LogEvent(obj, db.ACT_REBALANCE_FAIL, reason, self.UserCred)
+	self.SetStageFailed(ctx, reason)
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// HostFailoverTask fences a failed KVM host through IPMI, marks it down and
+// restarts its HA guests on other hosts sharing their storage
+type HostFailoverTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(HostFailoverTask{})
+}
+
+func (self *HostFailoverTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	host := obj.(*models.SHost)
+
+	self.SetStage("OnFencePowerOff", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		off, err := host.PowerOffByIpmi()
+		if err != nil {
+			return nil, err
+		}
+		return fencePowerStatus(off), nil
+	})
+}
+
+func fencePowerStatus(off bool) jsonutils.JSONObject {
+	ret := jsonutils.NewDict()
+	ret.Set("power_off", jsonutils.NewBool(off))
+	return ret
+}
+
+// OnFencePowerOff checks the chassis power again after a while until it is
+// off or the fence times out, no worker waits meanwhile
+func (self *HostFailoverTask) OnFencePowerOff(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	host := obj.(*models.SHost)
+	if jsonutils.QueryBoolean(data, "power_off", false) {
+		db.OpsLog.LogEvent(host, db.ACT_FENCE, "", self.UserCred)
+		self.restartGuests(ctx, host)
+		return
+	}
+
+	deadline, err := self.Params.GetTime("fence_deadline")
+	if err != nil {
+		params := jsonutils.NewDict()
+		params.Set("fence_deadline", jsonutils.NewTimeString(time.Now().Add(models.HOST_FENCE_TIMEOUT_SECONDS*time.Second)))
+		self.SetStage("OnFencePowerOff", params)
+	} else if time.Now().After(deadline) {
+		self.onFenceFailed(ctx, host, "chassis power is still on")
+		return
+	}
+	time.AfterFunc(models.HOST_FENCE_POLL_SECONDS*time.Second, func() {
+		taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+			off, err := host.IsPowerOffByIpmi()
+			if err != nil {
+				// the BMC may not answer while the chassis powers off
+				log.Warningf("fence host %s: %v", host.Name, err)
+			}
+			return fencePowerStatus(off), nil
+		})
+	})
+}
+
+func (self *HostFailoverTask) OnFencePowerOffFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.onFenceFailed(ctx, obj.(*models.SHost), data.String())
+}
+
+func (self *HostFailoverTask) onFenceFailed(ctx context.Context, host *models.SHost, reason string) {
+	db.OpsLog.LogEvent(host, db.ACT_FENCE_FAIL, reason, self.UserCred)
+	if !jsonutils.QueryBoolean(self.Params, "force", false) && !options.Options.HostFailoverWithoutFencing {
+		host.SetStatus(self.UserCred, api.HOST_FENCE_FAILED, reason)
+		self.taskFailed(ctx, host, fmt.Sprintf("fence host: %s", reason))
+		return
+	}
+	log.Warningf("failover host %s without fencing: %s", host.Name, reason)
+	self.restartGuests(ctx, host)
+}
+
+func (self *HostFailoverTask) restartGuests(ctx context.Context, host *models.SHost) {
+	host.SetStatus(self.UserCred, api.HOST_DOWN, "")
+
+	guests := host.GetHaRestartGuests()
+	// subtasks are bound to the stage of the parent when they are created
+	params := jsonutils.NewDict()
+	params.Set("total", jsonutils.NewInt(int64(len(guests))))
+	self.SetStage("OnGuestsRestartComplete", params)
+	started := 0
+	for i := range guests {
+		err := self.restartGuest(ctx, &guests[i])
+		if err != nil {
+			log.Errorf("failover host %s restart guest %s: %v", host.Name, guests[i].Name, err)
+			continue
+		}
+		started += 1
+	}
+	if started == 0 {
+		self.taskComplete(ctx, host, 0, len(guests))
+	}
+}
+
+// restartGuest starts the guest on another host in rescue mode, the guest is
+// marked not running by the migration once it is restarted, so that a failed
+// restart is tried again by the next failover
+func (self *HostFailoverTask) restartGuest(ctx context.Context, guest *models.SGuest) error {
+	lockman.LockObject(ctx, guest)
+	defer lockman.ReleaseObject(ctx, guest)
+
+	// rescue mode starts the guest on the new host from its shared disks
+	return guest.StartMigrateTask(ctx, self.UserCred, true, models.VM_READY, "", self.GetTaskId())
+}
+
+func (self *HostFailoverTask) OnGuestsRestartComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.onGuestsRestarted(ctx, obj.(*models.SHost))
+}
+
+func (self *HostFailoverTask) OnGuestsRestartCompleteFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.onGuestsRestarted(ctx, obj.(*models.SHost))
+}
+
+func (self *HostFailoverTask) onGuestsRestarted(ctx context.Context, host *models.SHost) {
+	succ := taskman.SubTaskManager.GetTotalSubtasks(self.Id, self.Stage, taskman.SUBTASK_SUCC)
+	total, _ := self.Params.Int("total")
+	self.taskComplete(ctx, host, len(succ), int(total))
+}
+
+func (self *HostFailoverTask) taskComplete(ctx context.Context, host *models.SHost, restarted, total int) {
+	notes := fmt.Sprintf("%d of %d guests restarted", restarted, total)
+	if restarted < total {
+		db.OpsLog.LogEvent(host, db.ACT_FAILOVER_FAIL, notes, self.UserCred)
+		logclient.AddActionLogWithContext(ctx, host, logclient.ACT_HOST_FAILOVER, notes, self.UserCred, false)
+	} else {
+		db.OpsLog.LogEvent(host, db.ACT_FAILOVER, notes, self.UserCred)
+		logclient.AddActionLogWithContext(ctx, host, logclient.ACT_HOST_FAILOVER, notes, self.UserCred, true)
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *HostFailoverTask) taskFailed(ctx context.Context, host *models.SHost, reason string) {
+	db.OpsLog.LogEvent(host, db.ACT_FAILOVER_FAIL, reason, self.UserCred)
+	logclient.AddActionLogWithContext(ctx, host, logclient.ACT_HOST_FAILOVER, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, reason)
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type NatSEntryCreateTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(NatSEntryCreateTask{})
+}
+
+func (self *NatSEntryCreateTask) TaskFailed(ctx context.Context, entry *models.SNatSEntry, err error) {
+	entry.SetStatus(self.UserCred, api.NAT_STATUS_CREATE_FAILED, err.Error())
+	db.OpsLog.LogEvent(entry, db.ACT_ALLOCATE_FAIL, err.Error(), self.UserCred)
+	self.SetStageFailed(ctx, err.Error())
+}
+
+func (self *NatSEntryCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	entry := obj.(*models.SNatSEntry)
+	nat, err := entry.GetNatgateway()
+	if err != nil {
+		self.TaskFailed(ctx, entry, fmt.Errorf("fetch natgateway: %s", err))
+		return
+	}
+	inat, err := nat.GetINatGateway()
+	if err != nil {
+		self.TaskFailed(ctx, entry, fmt.Errorf("fetch cloud natgateway: %s", err))
+		return
+	}
+	rule, err := entry.GetSNatRule()
+	if err != nil {
+		self.TaskFailed(ctx, entry, err)
+		return
+	}
+	ientry, err := inat.CreateINatSEntry(*rule)
+	if err != nil {
+		self.TaskFailed(ctx, entry, fmt.Errorf("create cloud snat entry: %s", err))
+		return
+	}
+	_, err = db.Update(entry, func() error {
+		entry.ExternalId = ientry.GetGlobalId()
+		return nil
+	})
+	if err != nil {
+		self.TaskFailed(ctx, entry, err)
+		return
+	}
+	entry.SetStatus(self.UserCred, api.NAT_STATUS_AVAILABLE, "")
+	db.OpsLog.LogEvent(entry, db.ACT_ALLOCATE, entry.GetShortDesc(ctx), self.UserCred)
+	self.SetStageComplete(ctx, nil)
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type NatSEntryDeleteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(NatSEntryDeleteTask{})
+}
+
+func (self *NatSEntryDeleteTask) TaskFailed(ctx context.Context, entry *models.SNatSEntry, err error) {
+	entry.SetStatus(self.UserCred, api.NAT_STATUS_DELETE_FAILED, err.Error())
+	db.OpsLog.LogEvent(entry, db.ACT_DELOCATE_FAIL, err.Error(), self.UserCred)
+	self.SetStageFailed(ctx, err.Error())
+}
+
+func (self *NatSEntryDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	entry := obj.(*models.SNatSEntry)
+	if len(entry.ExternalId) > 0 {
+		ientry, err := entry.GetINatSEntry()
+		if err != nil && err != cloudprovider.ErrNotFound {
+			self.TaskFailed(ctx, entry, fmt.Errorf("fetch cloud snat entry: %s", err))
+			return
+		}
+		if err == nil {
+			err = ientry.Delete()
+			if err != nil {
+				self.TaskFailed(ctx, entry, fmt.Errorf("delete cloud snat entry: %s", err))
+				return
+			}
+		}
+	}
+	db.OpsLog.LogEvent(entry, db.ACT_DELOCATE, entry.GetShortDesc(ctx), self.UserCred)
+	entry.RealDelete(ctx, self.UserCred)
+	self.SetStageComplete(ctx, nil)
+}
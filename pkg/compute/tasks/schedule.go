@@ -20,6 +20,7 @@ import (
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/stringutils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
@@ -49,6 +50,7 @@ type IScheduleModel interface {
 }
 
 type IScheduleTask interface {
+	GetName() string
 	GetUserCred() mcclient.TokenCredential
 	GetSchedParams() (*schedapi.ScheduleInput, error)
 	GetPendingUsage(quota quotas.IQuota) error
@@ -149,6 +151,10 @@ func doScheduleObjects(
 		return
 	}
 	schedInput = models.ApplySchedPolicies(schedInput)
+	if len(schedInput.SessionId) == 0 {
+		// the decisions are bound to the explain of the session
+		schedInput.SessionId = stringutils.UUID4()
+	}
 
 	params := jsonutils.Marshal(schedInput).(*jsonutils.JSONDict)
 	task.SetStage("OnScheduleComplete", params)
@@ -156,9 +162,26 @@ func doScheduleObjects(
 	s := auth.GetAdminSession(ctx, options.Options.Region, "")
 	output, err := modules.SchedManager.DoSchedule(s, schedInput, len(objs))
 	if err != nil {
-		onSchedulerRequestFail(ctx, task, objs, fmt.Sprintf("Scheduler fail: %s", err))
+		reason := fmt.Sprintf("Scheduler fail: %s", err)
+		explain, explainErr := modules.SchedManager.GetExplain(s, schedInput.SessionId)
+		if explainErr != nil {
+			log.Warningf("get explain of schedule session %s fail %s", schedInput.SessionId, explainErr)
+		}
+		models.ScheduleExplainManager.Record(explain)
+		for _, obj := range objs {
+			models.ScheduleDecisionManager.Record(ctx, obj, task.GetName(), schedInput.SessionId, nil, reason)
+		}
+		onSchedulerRequestFail(ctx, task, objs, reason)
 		return
 	}
+	models.ScheduleExplainManager.Record(output.Explain)
+	for idx, obj := range objs {
+		var candidate *schedapi.CandidateResource
+		if idx < len(output.Candidates) {
+			candidate = output.Candidates[idx]
+		}
+		models.ScheduleDecisionManager.Record(ctx, obj, task.GetName(), schedInput.SessionId, candidate, "")
+	}
 	onSchedulerResults(ctx, task, objs, output.Candidates)
 }
 
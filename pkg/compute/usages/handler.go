@@ -543,6 +543,11 @@ func guestHypervisorsUsage(
 	count[fmt.Sprintf("%s.ha.memory", prefix)] = guest.TotalBackupMemSize
 	count[fmt.Sprintf("%s.ha.disk", prefix)] = guest.TotalBackupDiskSize
 
+	// reclaimable by normal guests
+	count[fmt.Sprintf("%s.preemptible", prefix)] = guest.PreemptibleGuestCount
+	count[fmt.Sprintf("%s.preemptible.cpu", prefix)] = guest.PreemptibleCpuCount
+	count[fmt.Sprintf("%s.preemptible.memory", prefix)] = guest.PreemptibleMemSize
+
 	return count
 }
 
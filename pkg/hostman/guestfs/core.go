@@ -34,6 +34,7 @@ type SDeployInfo struct {
 	enableTty               bool
 	defaultRootUser         bool
 	windowsDefaultAdminUser bool
+	injectVirtioDrivers     bool
 }
 
 func NewDeployInfo(
@@ -44,6 +45,7 @@ func NewDeployInfo(
 	enableTty bool,
 	defaultRootUser bool,
 	windowsDefaultAdminUser bool,
+	injectVirtioDrivers bool,
 ) *SDeployInfo {
 	return &SDeployInfo{
 		publicKey:               publicKey,
@@ -53,6 +55,7 @@ func NewDeployInfo(
 		enableTty:               enableTty,
 		defaultRootUser:         defaultRootUser,
 		windowsDefaultAdminUser: windowsDefaultAdminUser,
+		injectVirtioDrivers:     injectVirtioDrivers,
 	}
 }
 
@@ -161,6 +164,11 @@ func DeployGuestFs(
 			return nil, fmt.Errorf("DeployFstabScripts: %v", err)
 		}
 	}
+	if deployInfo.injectVirtioDrivers {
+		if err = rootfs.DeployVirtioDrivers(partition); err != nil {
+			return nil, fmt.Errorf("DeployVirtioDrivers: %v", err)
+		}
+	}
 	if len(deployInfo.password) > 0 {
 		if account := rootfs.GetLoginAccount(partition,
 			deployInfo.defaultRootUser, deployInfo.windowsDefaultAdminUser); len(account) > 0 {
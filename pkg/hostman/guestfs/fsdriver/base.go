@@ -97,6 +97,10 @@ func (d *sGuestRootFsDriver) DeployFstabScripts(_ IDiskPartition, _ []jsonutils.
 	return nil
 }
 
+func (d *sGuestRootFsDriver) DeployVirtioDrivers(_ IDiskPartition) error {
+	return nil
+}
+
 func (d *sGuestRootFsDriver) EnableSerialConsole(rootfs IDiskPartition, sysInfo *jsonutils.JSONDict) error {
 	return nil
 }
@@ -46,6 +46,8 @@ const (
 	YUNIONROOT_USER = "cloudroot"
 )
 
+var virtioModules = []string{"virtio", "virtio_ring", "virtio_pci", "virtio_blk", "virtio_scsi", "virtio_net"}
+
 type sLinuxRootFs struct {
 	*sGuestRootFsDriver
 }
@@ -303,6 +305,43 @@ func (l *sLinuxRootFs) GetArch(rootFs IDiskPartition) string {
 	}
 }
 
+// DeployVirtioDrivers makes the initramfs carry the virtio modules, so that
+// a guest imported from another hypervisor is able to boot from virtio disks
+func (l *sLinuxRootFs) DeployVirtioDrivers(rootFs IDiskPartition) error {
+	var cmd []string
+	switch {
+	case rootFs.Exists("/etc/dracut.conf.d", false):
+		conf := fmt.Sprintf("add_drivers+=\" %s \"\n", strings.Join(virtioModules, " "))
+		if err := rootFs.FilePutContents("/etc/dracut.conf.d/virtio.conf", conf, false, false); err != nil {
+			return err
+		}
+		cmd = []string{"dracut", "-f", "--regenerate-all"}
+	case rootFs.Exists("/etc/initramfs-tools/modules", false):
+		cont, _ := rootFs.FileGetContents("/etc/initramfs-tools/modules", false)
+		modules := strings.Split(string(cont), "\n")
+		var conf string
+		for _, mod := range virtioModules {
+			if !utils.IsInStringArray(mod, modules) {
+				conf += mod + "\n"
+			}
+		}
+		if len(conf) > 0 {
+			if err := rootFs.FilePutContents("/etc/initramfs-tools/modules", conf, true, false); err != nil {
+				return err
+			}
+		}
+		cmd = []string{"update-initramfs", "-u", "-k", "all"}
+	default:
+		log.Warningf("no dracut or initramfs-tools found, virtio modules not deployed")
+		return nil
+	}
+	args := append([]string{rootFs.GetMountPath()}, cmd...)
+	if output, err := procutils.NewCommand("chroot", args...).Run(); err != nil {
+		return fmt.Errorf("%s: %s %s", strings.Join(cmd, " "), err, output)
+	}
+	return nil
+}
+
 func (l *sLinuxRootFs) PrepareFsForTemplate(rootFs IDiskPartition) error {
 	// clean /etc/fstab
 	if rootFs.Exists("/etc/fstab", false) {
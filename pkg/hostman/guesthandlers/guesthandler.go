@@ -44,6 +44,7 @@ var (
 		"start":   guestStart,
 		"stop":    guestStop,
 		"monitor": guestMonitor,
+		"serial":  guestOpenSerial,
 		"sync":    guestSync,
 		"suspend": guestSuspend,
 
@@ -219,6 +220,24 @@ func guestMonitor(ctx context.Context, sid string, body jsonutils.JSONObject) (i
 	}
 }
 
+func guestOpenSerial(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	if !guestman.GetGuestManager().IsGuestExist(sid) {
+		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
+	}
+	address, err := body.GetString("address")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("address")
+	}
+	port, token, err := guestman.GetGuestManager().OpenSerialForward(sid, address)
+	if err != nil {
+		return nil, err
+	}
+	ret := jsonutils.NewDict()
+	ret.Add(jsonutils.NewInt(int64(port)), "port")
+	ret.Add(jsonutils.NewString(token), "token")
+	return ret, nil
+}
+
 func guestSync(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	if !guestman.GetGuestManager().IsGuestExist(sid) {
 		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
@@ -267,6 +286,7 @@ func guestDestPrepareMigrate(ctx context.Context, sid string, body jsonutils.JSO
 	params.Desc = desc
 	params.QemuVersion = qemuVersion
 	params.LiveMigrate = liveMigrate
+	params.SerialConsole = jsonutils.QueryBoolean(body, "serial_console", false)
 	if isLocal {
 		serverUrl, err := body.GetString("server_url")
 		if err != nil {
@@ -66,6 +66,7 @@ func NewGuestManager(host hostutils.IHost, serversPath string) *SGuestManager {
 	manager.CandidateServers = make(map[string]*SKVMGuestInstance, 0)
 	manager.ServersLock = &sync.Mutex{}
 	manager.StartCpusetBalancer()
+	manager.StartOverlayFdbSync()
 	manager.LoadExistingGuests()
 	return manager
 }
@@ -295,6 +296,14 @@ func (m *SGuestManager) Monitor(sid, cmd string, callback func(string)) error {
 	}
 }
 
+func (m *SGuestManager) OpenSerialForward(sid, address string) (int, string, error) {
+	if guest, ok := m.Servers[sid]; ok {
+		return guest.OpenSerialForward(address)
+	} else {
+		return -1, "", httperrors.NewNotFoundError("Not found")
+	}
+}
+
 // Delay process
 func (m *SGuestManager) GuestDeploy(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
 	deployParams, ok := params.(*SGuestDeploy)
@@ -315,13 +324,15 @@ func (m *SGuestManager) GuestDeploy(ctx context.Context, params interface{}) (js
 		deploys, _ := deployParams.Body.GetArray("deploys")
 		password, _ := deployParams.Body.GetString("password")
 		resetPassword := jsonutils.QueryBoolean(deployParams.Body, "reset_password", false)
+		injectVirtioDrivers := jsonutils.QueryBoolean(deployParams.Body, "inject_virtio_drivers", false)
 		if resetPassword && len(password) == 0 {
 			password = seclib.RandomPassword(12)
 		}
 
 		guestInfo, err := guest.DeployFs(guestfs.NewDeployInfo(
 			publicKey, deploys, password, deployParams.IsInit, false,
-			options.HostOptions.LinuxDefaultRootUser, options.HostOptions.WindowsDefaultAdminUser))
+			options.HostOptions.LinuxDefaultRootUser, options.HostOptions.WindowsDefaultAdminUser,
+			injectVirtioDrivers))
 		if err != nil {
 			log.Errorf("Deploy guest fs error: %s", err)
 			return nil, err
@@ -530,6 +541,7 @@ func (m *SGuestManager) DestPrepareMigrate(ctx context.Context, params interface
 		startParams := jsonutils.NewDict()
 		startParams.Set("qemu_version", jsonutils.NewString(migParams.QemuVersion))
 		startParams.Set("need_migrate", jsonutils.JSONTrue)
+		startParams.Set("serial_console", jsonutils.NewBool(migParams.SerialConsole))
 		hostutils.DelayTaskWithoutReqctx(ctx, guest.asyncScriptStart, startParams)
 	}
 
@@ -0,0 +1,132 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo/hostbridge"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+func (m *SGuestManager) StartOverlayFdbSync() {
+	if options.HostOptions.OverlayFdbSyncIntervalSeconds <= 0 {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(time.Second * time.Duration(options.HostOptions.OverlayFdbSyncIntervalSeconds))
+			m.syncOverlayFdbSafe()
+		}
+	}()
+}
+
+// syncOverlayFdbSafe keeps the sync loop running if a round panics
+func (m *SGuestManager) syncOverlayFdbSafe() {
+	defer func() {
+		if r := recover(); r != nil {
+			debug.PrintStack()
+			log.Errorf("Overlay fdb sync failed %s", r)
+		}
+	}()
+	m.syncOverlayFdb()
+}
+
+// getOverlayVnis returns the VNIs of the overlay nics of the guests on this
+// host, grouped by vpc
+func (m *SGuestManager) getOverlayVnis() map[string]map[int]bool {
+	m.ServersLock.Lock()
+	guests := make([]*SKVMGuestInstance, 0, len(m.Servers))
+	for _, guest := range m.Servers {
+		guests = append(guests, guest)
+	}
+	m.ServersLock.Unlock()
+
+	vpcVnis := make(map[string]map[int]bool)
+	for _, guest := range guests {
+		if guest.Desc == nil {
+			continue
+		}
+		nics, _ := guest.Desc.GetArray("nics")
+		for _, nic := range nics {
+			vni, _ := nic.Int("vni")
+			vpcId, _ := nic.GetString("vpc_id")
+			if vni <= 0 || len(vpcId) == 0 {
+				continue
+			}
+			if _, ok := vpcVnis[vpcId]; !ok {
+				vpcVnis[vpcId] = make(map[int]bool)
+			}
+			vpcVnis[vpcId][int(vni)] = true
+		}
+	}
+	return vpcVnis
+}
+
+// syncOverlayFdb sets up the VXLAN bridges of every overlay wire in the
+// vpcs this host has guests in, populates their forwarding tables from the
+// region and removes the bridges no longer needed
+func (m *SGuestManager) syncOverlayFdb() {
+	vtep := m.host.GetMasterIp()
+	keep := make(map[int]bool)
+	session := hostutils.GetComputeSession(context.Background())
+	for vpcId, localVnis := range m.getOverlayVnis() {
+		for vni := range localVnis {
+			keep[vni] = true
+		}
+		ret, err := modules.Vpcs.GetSpecific(session, vpcId, "overlay-fdb", nil)
+		if err != nil {
+			log.Errorf("fetch overlay fdb of vpc %s fail %s", vpcId, err)
+			continue
+		}
+		vpcVni, _ := ret.Int("vni")
+		networks := make([]api.SOverlayNetwork, 0)
+		ret.Unmarshal(&networks, "networks")
+		entries := make([]api.SOverlayFdbEntry, 0)
+		ret.Unmarshal(&entries, "entries")
+
+		vnis := make(map[int]bool)
+		for vni := range localVnis {
+			vnis[vni] = true
+		}
+		for _, n := range networks {
+			vnis[n.Vni] = true
+		}
+		for vni := range vnis {
+			keep[vni] = true
+			if err := hostbridge.EnsureVxlanDevice(vni, int(vpcVni), vtep, networks); err != nil {
+				log.Errorln(err)
+				continue
+			}
+			hostbridge.SyncVxlanFdb(vni, vtep, entries)
+			if err := m.host.EnsureOverlayDHCPServer(hostbridge.VxlanBridgeName(vni)); err != nil {
+				log.Errorf("start dhcp server of vni %d fail %s", vni, err)
+			}
+		}
+	}
+	for _, vni := range hostbridge.GetVxlanVnis() {
+		if !keep[vni] {
+			log.Infof("remove vxlan device of vni %d", vni)
+			hostbridge.CleanVxlanDevice(vni)
+		}
+	}
+}
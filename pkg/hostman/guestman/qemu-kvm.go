@@ -15,10 +15,16 @@
 package guestman
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
 	"strconv"
@@ -39,6 +45,7 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/monitor"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
 	"yunion.io/x/onecloud/pkg/util/cgrouputils"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
@@ -52,6 +59,8 @@ const (
 	STATE_FILE_PREFIX             = "STATEFILE"
 	MONITOR_PORT_BASE             = 55900
 	LIVE_MIGRATE_PORT_BASE        = 4396
+	SERIAL_FORWARD_TIMEOUT        = 30 * time.Second
+	SERIAL_FORWARD_TOKEN_TIMEOUT  = 5 * time.Second
 	BUILT_IN_NBD_SERVER_PORT_BASE = 7777
 	MAX_TRY                       = 3
 )
@@ -558,6 +567,90 @@ func (s *SKVMGuestInstance) GetQmpMonitorPort(vncPort int) int {
 	}
 }
 
+func (s *SKVMGuestInstance) GetSerialSocketPath() string {
+	return path.Join(s.HomeDir(), "serial.sock")
+}
+
+// OpenSerialForward listens on a random port of address for a single
+// connection of webconsole and pipes it to the serial port of the guest,
+// which is only exposed as a unix socket. The connection must present the
+// returned one-time token in its first line, the others are dropped, and the
+// listener is closed once connected or after SERIAL_FORWARD_TIMEOUT
+func (s *SKVMGuestInstance) OpenSerialForward(address string) (int, string, error) {
+	if !s.IsRunning() || !fileutils2.Exists(s.GetSerialSocketPath()) {
+		return -1, "", httperrors.NewUnsupportOperationError("Serial console not enabled, restart server %s on host with serial console enabled", s.GetName())
+	}
+	tokenBytes := make([]byte, 16)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return -1, "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	listener, err := net.Listen("tcp", net.JoinHostPort(address, "0"))
+	if err != nil {
+		return -1, "", err
+	}
+	go func() {
+		defer listener.Close()
+		listener.(*net.TCPListener).SetDeadline(time.Now().Add(SERIAL_FORWARD_TIMEOUT))
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				log.Warningf("Serial forward of %s not connected: %s", s.GetName(), err)
+				return
+			}
+			reader, ok := checkSerialForwardToken(conn, token)
+			if !ok {
+				log.Warningf("Serial forward of %s: drop connection from %s with invalid token", s.GetName(), conn.RemoteAddr())
+				conn.Close()
+				continue
+			}
+			// the token is used up, stop accepting
+			listener.Close()
+			s.pipeSerial(conn, reader)
+			return
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, token, nil
+}
+
+// checkSerialForwardToken reads the token line from the connection, the
+// reader returned holds the data following the token
+func checkSerialForwardToken(conn net.Conn, token string) (io.Reader, bool) {
+	conn.SetReadDeadline(time.Now().Add(SERIAL_FORWARD_TOKEN_TIMEOUT))
+	reader := bufio.NewReaderSize(conn, 256)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, false
+	}
+	conn.SetReadDeadline(time.Time{})
+	line = strings.TrimRight(line, "\r\n")
+	if subtle.ConstantTimeCompare([]byte(line), []byte(token)) != 1 {
+		return nil, false
+	}
+	return reader, true
+}
+
+func (s *SKVMGuestInstance) pipeSerial(conn net.Conn, reader io.Reader) {
+	defer conn.Close()
+	serial, err := net.Dial("unix", s.GetSerialSocketPath())
+	if err != nil {
+		log.Errorf("Connect serial of %s: %s", s.GetName(), err)
+		return
+	}
+	defer serial.Close()
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(serial, reader)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, serial)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
 func (s *SKVMGuestInstance) GetVncPort() int {
 	if s.IsRunning() {
 		vncPort, err := ioutil.ReadFile(s.GetVncFilePath())
@@ -782,6 +875,9 @@ func (s *SKVMGuestInstance) GetNicDescMatch(mac, ip, port, bridge string) jsonut
 		nicIp, _ := nic.GetString("ip")
 		nicPort, _ := nic.GetString("ifname")
 		nicBridge, _ := nic.GetString("bridge")
+		if vni, _ := nic.Int("vni"); vni > 0 {
+			nicBridge = hostbridge.VxlanBridgeName(int(vni))
+		}
 		if (len(mac) == 0 || netutils2.MacEqual(nicMac, mac)) &&
 			(len(ip) == 0 || nicIp == ip) &&
 			(len(port) == 0 || nicPort == port) &&
@@ -1090,6 +1186,8 @@ func (s *SKVMGuestInstance) SyncMetadataInfo() {
 	if len(s.VncPassword) > 0 {
 		meta.Set("__vnc_password", jsonutils.NewString(s.VncPassword))
 	}
+	// the destination of a live migration must create the same devices
+	meta.Set("__serial_console", jsonutils.NewBool(fileutils2.Exists(s.GetSerialSocketPath())))
 
 	s.SyncMetadata(meta)
 }
@@ -15,6 +15,8 @@
 package guestman
 
 import (
+	"io/ioutil"
+	"net"
 	"testing"
 )
 
@@ -23,3 +25,33 @@ func TestSKVMGuestInstance_getPid(t *testing.T) {
 	s := NewKVMGuestInstance("05b787e9-b78e-4ebc-8128-04f55d37306f", manager)
 	t.Logf("Guest is ->> %d", s.GetPid())
 }
+
+func TestCheckSerialForwardToken(t *testing.T) {
+	cases := []struct {
+		input string
+		ok    bool
+		rest  string
+	}{
+		{"0a1b\nls\r", true, "ls\r"},
+		{"0a1b\r\n", true, ""},
+		{"0a1c\nls\r", false, ""},
+		{"ls\r", false, ""},
+	}
+	for _, c := range cases {
+		server, client := net.Pipe()
+		go func() {
+			client.Write([]byte(c.input))
+			client.Close()
+		}()
+		reader, ok := checkSerialForwardToken(server, "0a1b")
+		if ok != c.ok {
+			t.Errorf("%q: want %v got %v", c.input, c.ok, ok)
+		} else if ok {
+			rest, _ := ioutil.ReadAll(reader)
+			if string(rest) != c.rest {
+				t.Errorf("%q: want rest %q got %q", c.input, c.rest, rest)
+			}
+		}
+		server.Close()
+	}
+}
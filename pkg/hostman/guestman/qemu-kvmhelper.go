@@ -24,6 +24,7 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/utils"
 
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo/hostbridge"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
 	"yunion.io/x/onecloud/pkg/util/ethernet"
@@ -67,6 +68,23 @@ func (s *SKVMGuestInstance) getMonitorDesc(idstr string, port int, mode string)
 	return cmd
 }
 
+func (s *SKVMGuestInstance) getSerialDesc() string {
+	var cmd = ""
+	cmd += fmt.Sprintf(" -chardev socket,id=serial0dev,path=%s,server,nowait", s.GetSerialSocketPath())
+	cmd += " -device isa-serial,chardev=serial0dev,id=serial0"
+	return cmd
+}
+
+// isSerialConsoleEnabled tells whether to add the serial port, the
+// destination of a live migration must follow the source guest, which has
+// no serial port if started before serial console enabled
+func (s *SKVMGuestInstance) isSerialConsoleEnabled(data *jsonutils.JSONDict) bool {
+	if jsonutils.QueryBoolean(data, "need_migrate", false) {
+		return jsonutils.QueryBoolean(data, "serial_console", false)
+	}
+	return options.HostOptions.EnableSerialConsole
+}
+
 func (s *SKVMGuestInstance) getOsname() string {
 	if s.Desc.Contains("metadata") {
 		metadata, _ := s.Desc.Get("metadata")
@@ -192,6 +210,9 @@ func (s *SKVMGuestInstance) getNicDownScriptPath(nic jsonutils.JSONObject) strin
 }
 
 func (s *SKVMGuestInstance) generateNicScripts(nic jsonutils.JSONObject) error {
+	if vni, _ := nic.Int("vni"); vni > 0 {
+		return s.generateOverlayNicScripts(nic)
+	}
 	bridge, _ := nic.GetString("bridge")
 	dev := guestManger.GetHost().GetBridgeDev(bridge)
 	if dev == nil {
@@ -208,6 +229,23 @@ func (s *SKVMGuestInstance) generateNicScripts(nic jsonutils.JSONObject) error {
 	return nil
 }
 
+func (s *SKVMGuestInstance) generateOverlayNicScripts(nic jsonutils.JSONObject) error {
+	if err := hostbridge.GenerateVxlanIfupScripts(s.getNicUpScriptPath(nic), nic); err != nil {
+		log.Errorln(err)
+		return err
+	}
+	if err := hostbridge.GenerateVxlanIfdownScripts(s.getNicDownScriptPath(nic), nic); err != nil {
+		log.Errorln(err)
+		return err
+	}
+	vni, _ := nic.Int("vni")
+	if err := guestManger.GetHost().EnsureOverlayDHCPServer(hostbridge.VxlanBridgeName(int(vni))); err != nil {
+		log.Errorln(err)
+		return err
+	}
+	return nil
+}
+
 func (s *SKVMGuestInstance) getNetdevDesc(nic jsonutils.JSONObject) (string, error) {
 	ifname, _ := nic.GetString("ifname")
 	driver, _ := nic.GetString("driver")
@@ -269,6 +307,31 @@ func (s *SKVMGuestInstance) getVnicDesc(nic jsonutils.JSONObject) string {
 	return cmd
 }
 
+func (s *SKVMGuestInstance) getSriovNicSetupScript(nic jsonutils.JSONObject) (string, error) {
+	addr, _ := nic.GetString("sriov_device", "addr")
+	mac, _ := nic.GetString("mac")
+	vlan, _ := nic.Int("vlan")
+	return s.manager.GetHost().GetIsolatedDeviceManager().GetSriovVFSetupScript(addr, mac, int(vlan))
+}
+
+// getSriovVnicDesc passes the SR-IOV virtual function through at the pci slot
+// a tap nic of the same index would take
+func (s *SKVMGuestInstance) getSriovVnicDesc(nic jsonutils.JSONObject) string {
+	addr, _ := nic.GetString("sriov_device", "addr")
+	index, _ := nic.Int("index")
+	return fmt.Sprintf(" -device vfio-pci,host=%s,addr=0x%x", addr, s.getNicAddr(int(index)))
+}
+
+func (s *SKVMGuestInstance) getIsolatedDeviceAddrs() []string {
+	var devAddrs = []string{}
+	isolatedParams, _ := s.Desc.GetArray("isolated_devices")
+	for _, params := range isolatedParams {
+		devAddr, _ := params.GetString("addr")
+		devAddrs = append(devAddrs, devAddr)
+	}
+	return devAddrs
+}
+
 func (s *SKVMGuestInstance) getQgaDesc() string {
 	cmd := " -chardev socket,path="
 	cmd += path.Join(s.HomeDir(), "qga.sock")
@@ -304,15 +367,19 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 		qemuVersion = ""
 	}
 
-	var devAddrs = []string{}
-	isolatedParams, _ := s.Desc.GetArray("isolated_devices")
-	for _, params := range isolatedParams {
-		devAddr, _ := params.GetString("addr")
-		devAddrs = append(devAddrs, devAddr)
-	}
+	devAddrs := s.getIsolatedDeviceAddrs()
 	isolatedDevsParams := s.manager.GetHost().GetIsolatedDeviceManager().GetQemuParams(devAddrs)
+	cmd += s.manager.GetHost().GetIsolatedDeviceManager().GetMdevSetupScript(devAddrs)
 
 	for _, nic := range nics {
+		if nic.Contains("sriov_device") {
+			setupScript, err := s.getSriovNicSetupScript(nic)
+			if err != nil {
+				return "", err
+			}
+			cmd += setupScript
+			continue
+		}
 		downscript := s.getNicDownScriptPath(nic)
 		ifname, _ := nic.GetString("ifnam")
 		cmd += fmt.Sprintf("%s %s\n", downscript, ifname)
@@ -407,6 +474,9 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	if options.HostOptions.EnableQmpMonitor {
 		cmd += s.getMonitorDesc("qmqmon", s.GetQmpMonitorPort(int(vncPort)), MODE_CONTROL)
 	}
+	if s.isSerialConsoleEnabled(data) {
+		cmd += s.getSerialDesc()
+	}
 
 	cmd += " -rtc base=utc,clock=host,driftfix=none"
 	cmd += " -daemonize"
@@ -544,6 +614,10 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 		if osname == OS_NAME_VMWARE {
 			nics[i].(*jsonutils.JSONDict).Set("driver", jsonutils.NewString("vmxnet3"))
 		}
+		if nics[i].Contains("sriov_device") {
+			cmd += s.getSriovVnicDesc(nics[i])
+			continue
+		}
 		nicCmd, err := s.getNetdevDesc(nics[i])
 		if err != nil {
 			return "", err
@@ -633,10 +707,14 @@ func (s *SKVMGuestInstance) generateStopScript(data *jsonutils.JSONDict) string
 		cmd += "fi\n"
 	}
 	for _, nic := range nics {
+		if nic.Contains("sriov_device") {
+			continue
+		}
 		ifname, _ := nic.GetString("ifname")
 		downscript := s.getNicDownScriptPath(nic)
 		cmd += fmt.Sprintf("%s %s\n", downscript, ifname)
 	}
+	cmd += s.manager.GetHost().GetIsolatedDeviceManager().GetMdevCleanupScript(s.getIsolatedDeviceAddrs())
 	return cmd
 }
 
@@ -34,6 +34,11 @@ import (
 	"yunion.io/x/onecloud/pkg/util/procutils"
 )
 
+const (
+	DRIVER_OPENVSWITCH  = "openvswitch"
+	DRIVER_LINUX_BRIDGE = "linuxbridge"
+)
+
 type IBridgeDriver interface {
 	ConfirmToConfig(bool, []string) (bool, error)
 	Setup() error
@@ -207,6 +212,81 @@ func (d *SBaseBridgeDriver) SetupRoutes(routes [][]string) error {
 	return nil
 }
 
+// iBridgeSetup is implemented by concrete drivers so that the common
+// bring-up sequence in setup can create the bridge and attach the uplink.
+type iBridgeSetup interface {
+	Exists() bool
+	Interfaces() []string
+	SetupBridgeDev() error
+	SetupInterface() error
+}
+
+func (d *SBaseBridgeDriver) setup(drv iBridgeSetup) error {
+	var routes [][]string
+	var slaveAddrs [][]string
+	if d.inter != nil && len(d.inter.Addr) > 0 {
+		routes = d.inter.GetRoutes(true)
+		slaveAddrs = d.inter.GetSlaveAddresses()
+	}
+	if !drv.Exists() {
+		if err := drv.SetupBridgeDev(); err != nil {
+			return err
+		}
+	}
+
+	if d.inter != nil && !utils.IsInStringArray(d.inter.String(), drv.Interfaces()) {
+		if err := drv.SetupInterface(); err != nil {
+			return err
+		}
+	}
+	if len(d.bridge.Addr) == 0 {
+		if len(d.ip) > 0 {
+			if err := d.SetupAddresses(d.inter.Mask); err != nil {
+				return err
+			}
+			if len(slaveAddrs) > 0 {
+				if err := d.SetupSlaveAddresses(slaveAddrs); err != nil {
+					return err
+				}
+			}
+			if len(routes) > 0 {
+				if err := d.SetupRoutes(routes); err != nil {
+					return err
+				}
+			}
+		} else {
+			if err := d.SetupAddresses(nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return d.BringupInterface()
+}
+
+func (d *SBaseBridgeDriver) saveFileExecutable(scriptPath, script string) error {
+	if err := fileutils2.FilePutContents(scriptPath, script, false); err != nil {
+		return err
+	}
+	return os.Chmod(scriptPath, syscall.S_IRUSR|syscall.S_IWUSR|syscall.S_IXUSR)
+}
+
+func (d *SBaseBridgeDriver) getDownloadLimitScripts() string {
+	s := "if [ $LIMIT_DOWNLOAD != \"0mbit\" ]; then\n"
+	s += "    tc qdisc del dev $IF root 2>/dev/null\n"
+	s += "    tc qdisc add dev $IF root handle 1: htb default 10\n"
+	s += "    tc class add dev $IF parent 1: classid 1:1 htb " +
+		"rate $LIMIT_DOWNLOAD ceil $LIMIT_DOWNLOAD\n"
+	s += "    tc class add dev $IF parent 1:1 classid 1:10 htb " +
+		"rate $LIMIT_DOWNLOAD ceil $LIMIT_DOWNLOAD\n"
+	s += "fi\n"
+	return s
+}
+
+func (d *SBaseBridgeDriver) GetMetadataServerPort() int {
+	return options.HostOptions.Port + 1000
+}
+
 type SOVSBridgeDriver struct {
 	SBaseBridgeDriver
 }
@@ -247,46 +327,7 @@ func (o *SOVSBridgeDriver) Interfaces() []string {
 }
 
 func (o *SOVSBridgeDriver) Setup() error {
-	var routes [][]string
-	var slaveAddrs [][]string
-	if o.inter != nil && len(o.inter.Addr) > 0 {
-		routes = o.inter.GetRoutes(true)
-		slaveAddrs = o.inter.GetSlaveAddresses()
-	}
-	if !o.Exists() {
-		if err := o.SetupBridgeDev(); err != nil {
-			return err
-		}
-	}
-
-	if o.inter != nil && !utils.IsInStringArray(o.inter.String(), o.Interfaces()) {
-		if err := o.SetupInterface(); err != nil {
-			return err
-		}
-	}
-	if len(o.bridge.Addr) == 0 {
-		if len(o.ip) > 0 {
-			if err := o.SetupAddresses(o.inter.Mask); err != nil {
-				return err
-			}
-			if len(slaveAddrs) > 0 {
-				if err := o.SetupSlaveAddresses(slaveAddrs); err != nil {
-					return err
-				}
-			}
-			if len(routes) > 0 {
-				if err := o.SetupRoutes(routes); err != nil {
-					return err
-				}
-			}
-		} else {
-			if err := o.SetupAddresses(nil); err != nil {
-				return err
-			}
-		}
-	}
-
-	return o.BringupInterface()
+	return o.setup(o)
 }
 
 func (o *SOVSBridgeDriver) SetupInterface() error {
@@ -326,13 +367,6 @@ func (o *SOVSBridgeDriver) GenerateIfupScripts(scriptPath string, nic jsonutils.
 	return o.saveFileExecutable(scriptPath, script)
 }
 
-func (o *SOVSBridgeDriver) saveFileExecutable(scriptPath, script string) error {
-	if err := fileutils2.FilePutContents(scriptPath, script, false); err != nil {
-		return err
-	}
-	return os.Chmod(scriptPath, syscall.S_IRUSR|syscall.S_IWUSR|syscall.S_IXUSR)
-}
-
 func (o *SOVSBridgeDriver) getUpScripts(nic jsonutils.JSONObject) (string, error) {
 	var (
 		bridge, _ = nic.GetString("bridge")
@@ -382,14 +416,7 @@ func (o *SOVSBridgeDriver) getUpScripts(nic jsonutils.JSONObject) (string, error
 		s += "    " + o.AddFlow(r.cond, r.priority, r.actions)
 	}
 	s += "fi\n"
-	s += "if [ $LIMIT_DOWNLOAD != \"0mbit\" ]; then\n"
-	s += "    tc qdisc del dev $IF root 2>/dev/null\n"
-	s += "    tc qdisc add dev $IF root handle 1: htb default 10\n"
-	s += "    tc class add dev $IF parent 1: classid 1:1 htb " +
-		"rate $LIMIT_DOWNLOAD ceil $LIMIT_DOWNLOAD\n"
-	s += "    tc class add dev $IF parent 1:1 classid 1:10 htb " +
-		"rate $LIMIT_DOWNLOAD ceil $LIMIT_DOWNLOAD\n"
-	s += "fi\n"
+	s += o.getDownloadLimitScripts()
 	return s, nil
 }
 
@@ -466,10 +493,6 @@ func (o *SOVSBridgeDriver) GetOfRules(nic jsonutils.JSONObject) []SRule {
 	return rules
 }
 
-func (o *SOVSBridgeDriver) GetMetadataServerPort() int {
-	return options.HostOptions.Port + 1000
-}
-
 func (o *SOVSBridgeDriver) RegisterHostlocalServer(mac, ip string) error {
 	if !options.HostOptions.EnableOpenflowController {
 		metadataPort := o.GetMetadataServerPort()
@@ -567,23 +590,32 @@ func NewOVSBridgeDriver(bridge, inter, ip string) (*SOVSBridgeDriver, error) {
 }
 
 func NewDriver(bridgeDriver, bridge, inter, ip string) (IBridgeDriver, error) {
-	if bridgeDriver == "openvswitch" {
+	switch bridgeDriver {
+	case DRIVER_OPENVSWITCH:
 		return NewOVSBridgeDriver(bridge, inter, ip)
-	} else {
-		return nil, fmt.Errorf("Not Implentment")
+	case DRIVER_LINUX_BRIDGE:
+		return NewLinuxBridgeDriver(bridge, inter, ip)
+	default:
+		return nil, fmt.Errorf("Unsupported bridge driver %q", bridgeDriver)
 	}
 }
 
 func Prepare(bridgeDriver string) error {
-	if bridgeDriver == "openvswitch" {
+	switch bridgeDriver {
+	case DRIVER_OPENVSWITCH:
 		return OVSPrepare()
-	} else {
-		return fmt.Errorf("Not Implentment")
+	case DRIVER_LINUX_BRIDGE:
+		return LinuxBridgePrepare()
+	default:
+		return fmt.Errorf("Unsupported bridge driver %q", bridgeDriver)
 	}
 }
 
 func CleanDeletedPorts() {
-	if options.HostOptions.BridgeDriver == "openvswitch" {
+	switch options.HostOptions.BridgeDriver {
+	case DRIVER_OPENVSWITCH:
 		CleanOvsBridge()
+	case DRIVER_LINUX_BRIDGE:
+		CleanLinuxBridge()
 	}
 }
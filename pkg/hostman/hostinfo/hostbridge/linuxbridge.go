@@ -0,0 +1,413 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostbridge
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/bwutils"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	LINUX_BRIDGE_GUEST_CHAIN_PREFIX = "GUEST-"
+	LINUX_BRIDGE_HOSTLOCAL_CHAIN    = "HOSTLOCAL"
+
+	METADATA_SERVER_IP = "169.254.169.254"
+
+	// maximal length of a network interface name, IFNAMSIZ - 1
+	maxIfnameLength = 15
+)
+
+// SLinuxBridgeDriver attaches guest taps to native linux bridges. Tagged
+// networks are carried by a per-VLAN bridge on top of a VLAN subinterface of
+// the uplink, and the openflow rules of the OVS driver are replaced by
+// ebtables chains for anti-spoofing and ebtables/iptables NAT rules for
+// redirecting the metadata server.
+type SLinuxBridgeDriver struct {
+	SBaseBridgeDriver
+}
+
+func NewLinuxBridgeDriver(bridge, inter, ip string) (*SLinuxBridgeDriver, error) {
+	base, err := NewBaseBridgeDriver(bridge, inter, ip)
+	if err != nil {
+		return nil, err
+	}
+	return &SLinuxBridgeDriver{*base}, nil
+}
+
+func (l *SLinuxBridgeDriver) CleanupConfig() {
+	CleanLinuxBridge()
+}
+
+func (l *SLinuxBridgeDriver) Exists() bool {
+	_, err := os.Stat(path.Join("/sys/class/net", l.bridge.String(), "bridge"))
+	return err == nil
+}
+
+func (l *SLinuxBridgeDriver) Interfaces() []string {
+	files, err := ioutil.ReadDir(path.Join("/sys/class/net", l.bridge.String(), "brif"))
+	if err != nil {
+		log.Errorln(err)
+		return nil
+	}
+	var infs = make([]string, 0)
+	for _, f := range files {
+		infs = append(infs, f.Name())
+	}
+	return infs
+}
+
+func (l *SLinuxBridgeDriver) Setup() error {
+	return l.setup(l)
+}
+
+func (l *SLinuxBridgeDriver) SetupInterface() error {
+	if l.inter != nil && !utils.IsInStringArray(l.inter.String(), l.Interfaces()) {
+		output, err := procutils.NewCommand("ip", "link", "set", "dev",
+			l.inter.String(), "master", l.bridge.String()).Run()
+		if err != nil {
+			return fmt.Errorf("Failed to add interface %s", output)
+		}
+	}
+	return nil
+}
+
+func (l *SLinuxBridgeDriver) SetupBridgeDev() error {
+	if !l.Exists() {
+		_, err := procutils.NewCommand("ip", "link", "add", "name", l.bridge.String(), "type", "bridge").Run()
+		return err
+	}
+	return nil
+}
+
+func (l *SLinuxBridgeDriver) WarmupConfig() error {
+	_, err := procutils.NewCommand("ip", "link", "set", "dev", l.bridge.String(),
+		"type", "bridge", "stp_state", "0", "forward_delay", "0").Run()
+	return err
+}
+
+// RegisterHostlocalServer redirects guest requests to the metadata server
+// address to the host. ebtables rewrites the destination MAC so the frame is
+// delivered locally instead of being forwarded to the gateway, and iptables
+// DNATs it to the metadata port; conntrack restores the source of replies.
+func (l *SLinuxBridgeDriver) RegisterHostlocalServer(mac, ip string) error {
+	if err := ebtablesEnsureChain("nat", LINUX_BRIDGE_HOSTLOCAL_CHAIN, "PREROUTING"); err != nil {
+		log.Errorln(err)
+		return err
+	}
+	err := ebtablesAppend("nat", LINUX_BRIDGE_HOSTLOCAL_CHAIN,
+		"-p", "IPv4", "--ip-dst", METADATA_SERVER_IP, "--ip-proto", "tcp", "--ip-dport", "80",
+		"-j", "redirect", "--redirect-target", "ACCEPT")
+	if err != nil {
+		log.Errorln(err)
+		return err
+	}
+	metadataPort := l.GetMetadataServerPort()
+	err = iptablesEnsureRule("nat", "PREROUTING",
+		"-d", METADATA_SERVER_IP+"/32", "-p", "tcp", "--dport", "80",
+		"-j", "DNAT", "--to-destination", fmt.Sprintf("%s:%d", ip, metadataPort))
+	if err != nil {
+		log.Errorln(err)
+		return err
+	}
+	log.Infof("Linux bridge: metadata server %s:%d", ip, metadataPort)
+
+	k8sCidr := options.HostOptions.K8sClusterCidr
+	if len(k8sCidr) > 0 {
+		addr, mask, err := netutils2.PrefixSplit(k8sCidr)
+		if err != nil {
+			log.Errorln(err)
+			return err
+		}
+		k8sCidr = fmt.Sprintf("%s/%d", addr, mask)
+		log.Infof("Linux bridge: Kubernetes cluster IP range: %s", k8sCidr)
+		err = ebtablesAppend("nat", LINUX_BRIDGE_HOSTLOCAL_CHAIN,
+			"-p", "IPv4", "--ip-dst", k8sCidr, "-j", "redirect", "--redirect-target", "ACCEPT")
+		if err != nil {
+			log.Errorln(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *SLinuxBridgeDriver) GenerateIfdownScripts(scriptPath string, nic jsonutils.JSONObject) error {
+	script, err := l.getDownScripts(nic)
+	if err != nil {
+		log.Errorln(err)
+		return err
+	}
+	return l.saveFileExecutable(scriptPath, script)
+}
+
+func (l *SLinuxBridgeDriver) GenerateIfupScripts(scriptPath string, nic jsonutils.JSONObject) error {
+	script, err := l.getUpScripts(nic)
+	if err != nil {
+		log.Errorln(err)
+		return err
+	}
+	return l.saveFileExecutable(scriptPath, script)
+}
+
+func (l *SLinuxBridgeDriver) getUpScripts(nic jsonutils.JSONObject) (string, error) {
+	var (
+		bridge, _ = nic.GetString("bridge")
+		ifname, _ = nic.GetString("ifname")
+		ip, _     = nic.GetString("ip")
+		mac, _    = nic.GetString("mac")
+		vlan, _   = nic.Int("vlan")
+	)
+
+	s := "#!/bin/bash\n\n"
+	s += fmt.Sprintf("SWITCH='%s'\n", bridge)
+	s += fmt.Sprintf("IF='%s'\n", ifname)
+	s += fmt.Sprintf("IP='%s'\n", ip)
+	s += fmt.Sprintf("MAC='%s'\n", mac)
+	s += fmt.Sprintf("VLAN_ID=%d\n", vlan)
+	s += fmt.Sprintf("CHAIN='%s'\n", guestChainName(ifname))
+	if vlan != 1 {
+		if l.inter == nil {
+			return "", fmt.Errorf("Bridge %s has no uplink interface for vlan %d", bridge, vlan)
+		}
+		s += fmt.Sprintf("UPLINK='%s'\n", l.inter)
+		s += fmt.Sprintf("VLAN_IF='%s'\n", vlanInterfaceName(l.inter.String(), int(vlan)))
+		s += fmt.Sprintf("BRIDGE='%s'\n", vlanBridgeName(bridge, int(vlan)))
+	} else {
+		s += "BRIDGE=$SWITCH\n"
+	}
+	limit, burst, err := bwutils.GetOvsBwValues(nic)
+	if err != nil {
+		return "", err
+	}
+	s += fmt.Sprintf("LIMIT=%d\n", limit)
+	s += fmt.Sprintf("BURST=%d\n", burst)
+	bwDownload, err := bwutils.GetDownloadBwValue(nic, options.HostOptions.BwDownloadBandwidth)
+	if err != nil {
+		return "", err
+	}
+	s += fmt.Sprintf("LIMIT_DOWNLOAD='%dmbit'\n", bwDownload)
+	if options.HostOptions.TunnelPaddingBytes > 0 {
+		s += fmt.Sprintf("/sbin/ifconfig $IF mtu %d\n",
+			1500+options.HostOptions.TunnelPaddingBytes)
+	}
+	s += "/sbin/ifconfig $IF 0.0.0.0 up\n"
+	if vlan != 1 {
+		s += "if ! ip link show $VLAN_IF > /dev/null 2>&1; then\n"
+		s += "    ip link add link $UPLINK name $VLAN_IF type vlan id $VLAN_ID\n"
+		s += "fi\n"
+		s += "if ! ip link show $BRIDGE > /dev/null 2>&1; then\n"
+		s += "    ip link add name $BRIDGE type bridge stp_state 0 forward_delay 0\n"
+		s += "fi\n"
+		s += "ip link set dev $VLAN_IF master $BRIDGE\n"
+		s += "ip link set dev $VLAN_IF up\n"
+		s += "ip link set dev $BRIDGE up\n"
+	}
+	s += "ip link set dev $IF master $BRIDGE\n"
+	for _, r := range l.getDhcpRules() {
+		s += fmt.Sprintf("ebtables -t filter -D FORWARD %s > /dev/null 2>&1\n", r)
+		s += fmt.Sprintf("ebtables -t filter -A FORWARD %s\n", r)
+	}
+	s += "ebtables -t filter -N $CHAIN > /dev/null 2>&1\n"
+	s += "ebtables -t filter -F $CHAIN\n"
+	for _, r := range l.getChainJumpRules() {
+		s += fmt.Sprintf("ebtables -t filter -D %s > /dev/null 2>&1\n", r)
+		s += fmt.Sprintf("ebtables -t filter -A %s\n", r)
+	}
+	for _, r := range l.GetEbRules(nic) {
+		s += fmt.Sprintf("ebtables -t filter -A $CHAIN %s\n", r)
+	}
+	s += "tc qdisc del dev $IF ingress 2>/dev/null\n"
+	s += "tc qdisc add dev $IF handle ffff: ingress\n"
+	s += "tc filter add dev $IF parent ffff: protocol all u32 match u32 0 0 " +
+		"police rate ${LIMIT}kbit burst ${BURST}k drop flowid :1\n"
+	s += l.getDownloadLimitScripts()
+	return s, nil
+}
+
+func (l *SLinuxBridgeDriver) getDownScripts(nic jsonutils.JSONObject) (string, error) {
+	var (
+		bridge, _ = nic.GetString("bridge")
+		ifname, _ = nic.GetString("ifname")
+		ip, _     = nic.GetString("ip")
+		mac, _    = nic.GetString("mac")
+		vlan, _   = nic.Int("vlan")
+	)
+
+	s := "#!/bin/bash\n\n"
+	s += fmt.Sprintf("SWITCH='%s'\n", bridge)
+	s += fmt.Sprintf("IF='%s'\n", ifname)
+	s += fmt.Sprintf("IP='%s'\n", ip)
+	s += fmt.Sprintf("MAC='%s'\n", mac)
+	s += fmt.Sprintf("VLAN_ID=%d\n", vlan)
+	s += fmt.Sprintf("CHAIN='%s'\n", guestChainName(ifname))
+	s += l.getCleanupScripts()
+	s += "/sbin/ifconfig $IF 0.0.0.0 down\n"
+	s += "ip link set dev $IF nomaster > /dev/null 2>&1\n"
+	return s, nil
+}
+
+func (l *SLinuxBridgeDriver) getCleanupScripts() string {
+	s := ""
+	for _, r := range l.getDhcpRules() {
+		s += fmt.Sprintf("ebtables -t filter -D FORWARD %s > /dev/null 2>&1\n", r)
+	}
+	for _, r := range l.getChainJumpRules() {
+		s += fmt.Sprintf("ebtables -t filter -D %s > /dev/null 2>&1\n", r)
+	}
+	s += "ebtables -t filter -F $CHAIN > /dev/null 2>&1\n"
+	s += "ebtables -t filter -X $CHAIN > /dev/null 2>&1\n"
+	return s
+}
+
+// getDhcpRules keeps guest DHCP requests on the host, where the guest DHCP
+// server listens on the bridge, instead of flooding them to the uplink.
+func (l *SLinuxBridgeDriver) getDhcpRules() []string {
+	return []string{
+		"-i $IF -p IPv4 --ip-proto udp --ip-sport 68 --ip-dport 67 -j DROP",
+	}
+}
+
+func (l *SLinuxBridgeDriver) getChainJumpRules() []string {
+	return []string{
+		"INPUT -i $IF -j $CHAIN",
+		"FORWARD -i $IF -j $CHAIN",
+	}
+}
+
+// GetEbRules returns the anti-spoofing rules of the per-nic ebtables chain,
+// the counterpart of the OVS driver's GetOfRules.
+func (l *SLinuxBridgeDriver) GetEbRules(nic jsonutils.JSONObject) []string {
+	rules := []string{
+		"-s ! $MAC -j DROP",
+		"-p IPv6 -j DROP",
+		"-p ARP --arp-mac-src ! $MAC -j DROP",
+	}
+	if ip, _ := nic.GetString("ip"); len(ip) > 0 {
+		rules = append(rules,
+			"-p ARP --arp-ip-src 0.0.0.0 -j RETURN",
+			"-p ARP --arp-ip-src ! $IP -j DROP",
+			"-p IPv4 --ip-src 0.0.0.0 --ip-proto udp --ip-sport 68 --ip-dport 67 -j RETURN",
+			"-p IPv4 --ip-src ! $IP -j DROP",
+		)
+	}
+	return rules
+}
+
+func guestChainName(ifname string) string {
+	return LINUX_BRIDGE_GUEST_CHAIN_PREFIX + ifname
+}
+
+// vlanDeviceName names the device of a vlan on the parent device, a name
+// exceeding IFNAMSIZ is replaced by the prefix and a hash of the parent so
+// the same vlan on different parents does not collide
+func vlanDeviceName(prefix, parent string, vlan int) string {
+	name := fmt.Sprintf("%s.%d", parent, vlan)
+	if len(name) > maxIfnameLength {
+		name = fmt.Sprintf("%s%08x.%d", prefix, crc32.ChecksumIEEE([]byte(parent)), vlan)
+	}
+	return name
+}
+
+func vlanInterfaceName(inter string, vlan int) string {
+	return vlanDeviceName("v", inter, vlan)
+}
+
+func vlanBridgeName(bridge string, vlan int) string {
+	return vlanDeviceName("br", bridge, vlan)
+}
+
+func ebtablesEnsureChain(table, chain, parent string) error {
+	procutils.NewCommand("ebtables", "-t", table, "-N", chain).Run()
+	if output, err := procutils.NewCommand("ebtables", "-t", table, "-F", chain).Run(); err != nil {
+		return fmt.Errorf("Failed to flush ebtables chain %s: %s", chain, output)
+	}
+	procutils.NewCommand("ebtables", "-t", table, "-D", parent, "-j", chain).Run()
+	if output, err := procutils.NewCommand("ebtables", "-t", table, "-A", parent, "-j", chain).Run(); err != nil {
+		return fmt.Errorf("Failed to jump to ebtables chain %s: %s", chain, output)
+	}
+	return nil
+}
+
+func ebtablesAppend(table, chain string, rule ...string) error {
+	args := append([]string{"-t", table, "-A", chain}, rule...)
+	if output, err := procutils.NewCommand("ebtables", args...).Run(); err != nil {
+		return fmt.Errorf("Failed to add ebtables rule %s: %s", strings.Join(rule, " "), output)
+	}
+	return nil
+}
+
+func iptablesEnsureRule(table, chain string, rule ...string) error {
+	args := append([]string{"-t", table, "-C", chain}, rule...)
+	if _, err := procutils.NewCommand("iptables", args...).Run(); err == nil {
+		return nil
+	}
+	args = append([]string{"-t", table, "-A", chain}, rule...)
+	if output, err := procutils.NewCommand("iptables", args...).Run(); err != nil {
+		return fmt.Errorf("Failed to add iptables rule %s: %s", strings.Join(rule, " "), output)
+	}
+	return nil
+}
+
+func LinuxBridgePrepare() error {
+	for _, cmd := range []string{"ip", "ebtables", "iptables", "tc"} {
+		if _, err := exec.LookPath(cmd); err != nil {
+			return fmt.Errorf("Command %s not installed!", cmd)
+		}
+	}
+	for _, mod := range []string{"bridge", "8021q"} {
+		if output, err := procutils.NewCommand("modprobe", mod).Run(); err != nil {
+			return fmt.Errorf("Failed to load kernel module %s: %s", mod, output)
+		}
+	}
+	return nil
+}
+
+// CleanLinuxBridge removes the ebtables chains and rules left behind by guest
+// taps which disappeared without their ifdown script being run.
+func CleanLinuxBridge() {
+	output, err := procutils.NewCommand("ebtables", "-t", "filter", "-L").Run()
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	re := regexp.MustCompile(`^Bridge chain: ` + LINUX_BRIDGE_GUEST_CHAIN_PREFIX + `([a-zA-Z0-9._@-]+),`)
+	l := &SLinuxBridgeDriver{}
+	for _, line := range strings.Split(string(output), "\n") {
+		m := re.FindStringSubmatch(strings.TrimSpace(line))
+		if len(m) != 2 || netutils2.NewNetInterface(m[1]).Exist() {
+			continue
+		}
+		log.Infof("Linux bridge: remove rules of deleted port %s", m[1])
+		s := fmt.Sprintf("IF='%s'\nCHAIN='%s'\n", m[1], guestChainName(m[1]))
+		s += l.getCleanupScripts()
+		if _, err := procutils.NewCommand("bash", "-c", s).Run(); err != nil {
+			log.Errorln(err)
+		}
+	}
+}
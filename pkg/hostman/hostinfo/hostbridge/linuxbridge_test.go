@@ -0,0 +1,58 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostbridge
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestVlanNames(t *testing.T) {
+	cases := []struct {
+		name   string
+		vlan   int
+		inter  string
+		bridge string
+	}{
+		{"eth0", 100, "eth0.100", "eth0.100"},
+		{"enp175s0f10", 4094, "ve71ac093.4094", "bre71ac093.4094"},
+		{"enp175s0f11", 4094, "v901df005.4094", "br901df005.4094"},
+	}
+	for _, c := range cases {
+		if got := vlanInterfaceName(c.name, c.vlan); got != c.inter {
+			t.Errorf("vlanInterfaceName(%s, %d) = %s, want %s", c.name, c.vlan, got, c.inter)
+		}
+		if got := vlanBridgeName(c.name, c.vlan); got != c.bridge {
+			t.Errorf("vlanBridgeName(%s, %d) = %s, want %s", c.name, c.vlan, got, c.bridge)
+		}
+		if len(c.inter) > maxIfnameLength || len(c.bridge) > maxIfnameLength {
+			t.Errorf("names of %s vlan %d exceed IFNAMSIZ", c.name, c.vlan)
+		}
+	}
+}
+
+func TestGetEbRules(t *testing.T) {
+	l := &SLinuxBridgeDriver{}
+	nic := jsonutils.NewDict()
+	nic.Set("mac", jsonutils.NewString("00:22:33:44:55:66"))
+	if rules := l.GetEbRules(nic); len(rules) != 3 {
+		t.Errorf("rules without ip: %v", rules)
+	}
+	nic.Set("ip", jsonutils.NewString("10.0.0.2"))
+	if rules := l.GetEbRules(nic); len(rules) != 7 {
+		t.Errorf("rules with ip: %v", rules)
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostbridge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/bwutils"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	// every host answers for the gateway of an overlay network with the same
+	// MAC, so guests keep their gateway when they are migrated
+	VXLAN_GATEWAY_MAC = "02:00:00:00:10:01"
+
+	VXLAN_DEVICE_PREFIX = "vx"
+	VXLAN_BRIDGE_PREFIX = "brvx"
+	VXLAN_VRF_PREFIX    = "vrf"
+)
+
+// Overlay nics do not use the bridge of the host nic. Each VNI gets a linux
+// bridge holding a kernel vxlan device whose local address is the VTEP of
+// the host, and the bridges of a vpc are enslaved to a VRF which routes
+// between them with the gateway address of every network configured on all
+// hosts having guests in the vpc. Learning is disabled on the vxlan devices,
+// the forwarding and neighbour entries are populated by SyncVxlanFdb.
+
+func vxlanDeviceName(vni int) string {
+	return fmt.Sprintf("%s%d", VXLAN_DEVICE_PREFIX, vni)
+}
+
+// VxlanBridgeName is the bridge the overlay nics of a VNI are attached to
+func VxlanBridgeName(vni int) string {
+	return fmt.Sprintf("%s%d", VXLAN_BRIDGE_PREFIX, vni)
+}
+
+func vxlanVrfName(vni int) string {
+	return fmt.Sprintf("%s%d", VXLAN_VRF_PREFIX, vni)
+}
+
+func GenerateVxlanIfupScripts(scriptPath string, nic jsonutils.JSONObject) error {
+	script, err := getVxlanUpScripts(nic)
+	if err != nil {
+		log.Errorln(err)
+		return err
+	}
+	return new(SBaseBridgeDriver).saveFileExecutable(scriptPath, script)
+}
+
+func GenerateVxlanIfdownScripts(scriptPath string, nic jsonutils.JSONObject) error {
+	script, err := getVxlanDownScripts(nic)
+	if err != nil {
+		log.Errorln(err)
+		return err
+	}
+	return new(SBaseBridgeDriver).saveFileExecutable(scriptPath, script)
+}
+
+func getVxlanUpScripts(nic jsonutils.JSONObject) (string, error) {
+	var (
+		ifname, _  = nic.GetString("ifname")
+		ip, _      = nic.GetString("ip")
+		mac, _     = nic.GetString("mac")
+		gateway, _ = nic.GetString("gateway")
+		masklen, _ = nic.Int("masklen")
+		vni, _     = nic.Int("vni")
+		vpcVni, _  = nic.Int("vpc_vni")
+		vtep, _    = nic.GetString("vtep")
+	)
+	if vni <= 0 || len(vtep) == 0 {
+		return "", fmt.Errorf("Nic %s is not on an overlay wire", ifname)
+	}
+
+	l := &SLinuxBridgeDriver{}
+	s := "#!/bin/bash\n\n"
+	s += fmt.Sprintf("IF='%s'\n", ifname)
+	s += fmt.Sprintf("IP='%s'\n", ip)
+	s += fmt.Sprintf("MAC='%s'\n", mac)
+	s += fmt.Sprintf("VNI=%d\n", vni)
+	s += fmt.Sprintf("BRIDGE='%s'\n", VxlanBridgeName(int(vni)))
+	s += fmt.Sprintf("CHAIN='%s'\n", guestChainName(ifname))
+	limit, burst, err := bwutils.GetOvsBwValues(nic)
+	if err != nil {
+		return "", err
+	}
+	s += fmt.Sprintf("LIMIT=%d\n", limit)
+	s += fmt.Sprintf("BURST=%d\n", burst)
+	bwDownload, err := bwutils.GetDownloadBwValue(nic, options.HostOptions.BwDownloadBandwidth)
+	if err != nil {
+		return "", err
+	}
+	s += fmt.Sprintf("LIMIT_DOWNLOAD='%dmbit'\n", bwDownload)
+	if options.HostOptions.TunnelPaddingBytes > 0 {
+		s += fmt.Sprintf("/sbin/ifconfig $IF mtu %d\n",
+			1500+options.HostOptions.TunnelPaddingBytes)
+	}
+	s += "/sbin/ifconfig $IF 0.0.0.0 up\n"
+	var gateways []string
+	if len(gateway) > 0 {
+		gateways = append(gateways, fmt.Sprintf("%s/%d", gateway, masklen))
+	}
+	s += getVxlanDeviceScripts(int(vni), int(vpcVni), vtep, gateways)
+	s += "ip link set dev $IF master $BRIDGE\n"
+	s += "ebtables -t filter -N $CHAIN > /dev/null 2>&1\n"
+	s += "ebtables -t filter -F $CHAIN\n"
+	for _, r := range l.getChainJumpRules() {
+		s += fmt.Sprintf("ebtables -t filter -D %s > /dev/null 2>&1\n", r)
+		s += fmt.Sprintf("ebtables -t filter -A %s\n", r)
+	}
+	for _, r := range l.GetEbRules(nic) {
+		s += fmt.Sprintf("ebtables -t filter -A $CHAIN %s\n", r)
+	}
+	s += "tc qdisc del dev $IF ingress 2>/dev/null\n"
+	s += "tc qdisc add dev $IF handle ffff: ingress\n"
+	s += "tc filter add dev $IF parent ffff: protocol all u32 match u32 0 0 " +
+		"police rate ${LIMIT}kbit burst ${BURST}k drop flowid :1\n"
+	s += l.getDownloadLimitScripts()
+	return s, nil
+}
+
+// getVxlanDeviceScripts creates the vxlan device and bridge of a VNI if
+// missing, enslaves the bridge to the VRF of the vpc and configures the
+// gateway addresses of the networks on it.
+func getVxlanDeviceScripts(vni, vpcVni int, vtep string, gateways []string) string {
+	vxlanIf := vxlanDeviceName(vni)
+	bridge := VxlanBridgeName(vni)
+	s := fmt.Sprintf("if ! ip link show %s > /dev/null 2>&1; then\n", vxlanIf)
+	s += fmt.Sprintf("    ip link add %s type vxlan id %d local %s dstport %d nolearning proxy\n",
+		vxlanIf, vni, vtep, api.VXLAN_DEFAULT_PORT)
+	s += "fi\n"
+	s += fmt.Sprintf("if ! ip link show %s > /dev/null 2>&1; then\n", bridge)
+	s += fmt.Sprintf("    ip link add name %s type bridge stp_state 0 forward_delay 0\n", bridge)
+	s += "fi\n"
+	if vpcVni > 0 {
+		vrf := vxlanVrfName(vpcVni)
+		s += fmt.Sprintf("if ! ip link show %s > /dev/null 2>&1; then\n", vrf)
+		s += fmt.Sprintf("    ip link add %s type vrf table %d\n", vrf, vpcVni)
+		s += "fi\n"
+		s += fmt.Sprintf("ip link set dev %s up\n", vrf)
+		s += fmt.Sprintf("ip link set dev %s master %s\n", bridge, vrf)
+	}
+	s += fmt.Sprintf("ip link set dev %s master %s\n", vxlanIf, bridge)
+	s += fmt.Sprintf("bridge link set dev %s learning off neigh_suppress on\n", vxlanIf)
+	s += fmt.Sprintf("ip link set dev %s up\n", vxlanIf)
+	if len(gateways) > 0 {
+		s += fmt.Sprintf("ip link set dev %s address %s\n", bridge, VXLAN_GATEWAY_MAC)
+		for _, gw := range gateways {
+			s += fmt.Sprintf("ip address replace %s dev %s\n", gw, bridge)
+		}
+		// the host DHCP and metadata servers listen outside the VRF, let
+		// them accept the requests arriving on its bridges
+		s += "sysctl -q -w net.ipv4.tcp_l3mdev_accept=1 net.ipv4.udp_l3mdev_accept=1\n"
+		rule := vxlanMetadataRule(bridge)
+		s += fmt.Sprintf("iptables -t nat -C PREROUTING %s > /dev/null 2>&1 || iptables -t nat -I PREROUTING %s\n", rule, rule)
+	}
+	s += fmt.Sprintf("ip link set dev %s up\n", bridge)
+	return s
+}
+
+// vxlanMetadataRule redirects the metadata requests of the guests on an
+// overlay bridge to the gateway address there. The DNAT of the host bridges
+// to the host address cannot be used, it is not routable in the VRF.
+func vxlanMetadataRule(bridge string) string {
+	return fmt.Sprintf("-i %s -d %s/32 -p tcp --dport 80 -j REDIRECT --to-ports %d",
+		bridge, METADATA_SERVER_IP, new(SBaseBridgeDriver).GetMetadataServerPort())
+}
+
+// EnsureVxlanDevice sets up the bridge of a VNI of the vpc even if no guest
+// of this host is on it, so the VRF can route to the remote guests there.
+func EnsureVxlanDevice(vni, vpcVni int, vtep string, networks []api.SOverlayNetwork) error {
+	gateways := make([]string, 0)
+	for _, n := range networks {
+		if n.Vni == vni && len(n.Gateway) > 0 {
+			gateways = append(gateways, fmt.Sprintf("%s/%d", n.Gateway, n.Masklen))
+		}
+	}
+	script := getVxlanDeviceScripts(vni, vpcVni, vtep, gateways)
+	if output, err := procutils.NewCommand("bash", "-c", script).Run(); err != nil {
+		return fmt.Errorf("setup vxlan device of vni %d fail %s", vni, output)
+	}
+	return nil
+}
+
+func getVxlanDownScripts(nic jsonutils.JSONObject) (string, error) {
+	var (
+		ifname, _ = nic.GetString("ifname")
+		vni, _    = nic.Int("vni")
+	)
+
+	l := &SLinuxBridgeDriver{}
+	s := "#!/bin/bash\n\n"
+	s += fmt.Sprintf("IF='%s'\n", ifname)
+	s += fmt.Sprintf("VNI=%d\n", vni)
+	s += fmt.Sprintf("CHAIN='%s'\n", guestChainName(ifname))
+	s += l.getCleanupScripts()
+	s += "/sbin/ifconfig $IF 0.0.0.0 down\n"
+	s += "ip link set dev $IF nomaster > /dev/null 2>&1\n"
+	return s, nil
+}
+
+// GetVxlanVnis returns the VNIs which have a vxlan device on this host
+func GetVxlanVnis() []int {
+	files, err := ioutil.ReadDir("/sys/class/net")
+	if err != nil {
+		log.Errorln(err)
+		return nil
+	}
+	vnis := make([]int, 0)
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name(), VXLAN_DEVICE_PREFIX) {
+			continue
+		}
+		vni, err := strconv.Atoi(strings.TrimPrefix(f.Name(), VXLAN_DEVICE_PREFIX))
+		if err == nil && vni > 0 {
+			vnis = append(vnis, vni)
+		}
+	}
+	return vnis
+}
+
+// CleanVxlanDevice removes the vxlan device and bridge of a VNI no guest on
+// this host uses any more
+func CleanVxlanDevice(vni int) {
+	rule := strings.Split(vxlanMetadataRule(VxlanBridgeName(vni)), " ")
+	procutils.NewCommand("iptables", append([]string{"-t", "nat", "-D", "PREROUTING"}, rule...)...).Run()
+	for _, dev := range []string{vxlanDeviceName(vni), VxlanBridgeName(vni)} {
+		if _, err := procutils.NewCommand("ip", "link", "del", "dev", dev).Run(); err != nil {
+			log.Errorf("remove %s fail %s", dev, err)
+		}
+	}
+}
+
+type sVxlanFdb struct {
+	mac  string
+	vtep string
+}
+
+var (
+	vxlanFdbRegexp   = regexp.MustCompile(`^([0-9a-f:]{17}) dst ([0-9.]+)`)
+	vxlanNeighRegexp = regexp.MustCompile(`^([0-9.]+) lladdr ([0-9a-f:]{17})`)
+)
+
+// SyncVxlanFdb makes the forwarding table of the vxlan device of a VNI point
+// the guests of remote hosts at their VTEP, floods broadcast to all remote
+// VTEPs of the VNI, and installs permanent neighbours for the remote guests
+// on the bridge, which answers ARP for them locally.
+func SyncVxlanFdb(vni int, localVtep string, entries []api.SOverlayFdbEntry) {
+	vxlanIf := vxlanDeviceName(vni)
+	bridge := VxlanBridgeName(vni)
+
+	fdbs := make(map[sVxlanFdb]bool)
+	neighs := make(map[string]string)
+	for _, e := range entries {
+		if e.Vtep == localVtep {
+			continue
+		}
+		if e.Vni != vni {
+			continue
+		}
+		fdbs[sVxlanFdb{"00:00:00:00:00:00", e.Vtep}] = true
+		fdbs[sVxlanFdb{strings.ToLower(e.Mac), e.Vtep}] = true
+		if len(e.Ip) > 0 {
+			neighs[e.Ip] = strings.ToLower(e.Mac)
+		}
+	}
+
+	output, err := procutils.NewCommand("bridge", "fdb", "show", "dev", vxlanIf).Run()
+	if err != nil {
+		log.Errorf("show fdb of %s fail %s", vxlanIf, err)
+		return
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		m := vxlanFdbRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if len(m) != 3 {
+			continue
+		}
+		fdb := sVxlanFdb{m[1], m[2]}
+		if fdbs[fdb] {
+			delete(fdbs, fdb)
+			continue
+		}
+		procutils.NewCommand("bridge", "fdb", "del", fdb.mac, "dev", vxlanIf, "dst", fdb.vtep).Run()
+	}
+	for fdb := range fdbs {
+		if fdb.mac == "00:00:00:00:00:00" {
+			_, err = procutils.NewCommand("bridge", "fdb", "append", fdb.mac, "dev", vxlanIf, "dst", fdb.vtep).Run()
+		} else {
+			_, err = procutils.NewCommand("bridge", "fdb", "replace", fdb.mac, "dev", vxlanIf, "dst", fdb.vtep).Run()
+			if err == nil {
+				_, err = procutils.NewCommand("bridge", "fdb", "replace", fdb.mac, "dev", vxlanIf, "master", "static").Run()
+			}
+		}
+		if err != nil {
+			log.Errorf("add fdb %s dst %s to %s fail %s", fdb.mac, fdb.vtep, vxlanIf, err)
+		}
+	}
+
+	output, err = procutils.NewCommand("ip", "neigh", "show", "dev", bridge, "nud", "permanent").Run()
+	if err != nil {
+		log.Errorf("show neighbours of %s fail %s", bridge, err)
+		return
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		m := vxlanNeighRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if len(m) != 3 {
+			continue
+		}
+		if neighs[m[1]] == m[2] {
+			delete(neighs, m[1])
+			continue
+		}
+		procutils.NewCommand("ip", "neigh", "del", m[1], "dev", bridge).Run()
+	}
+	for ip, mac := range neighs {
+		_, err := procutils.NewCommand("ip", "neigh", "replace", ip, "lladdr", mac,
+			"dev", bridge, "nud", "permanent").Run()
+		if err != nil {
+			log.Errorf("add neighbour %s %s to %s fail %s", ip, mac, bridge, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostbridge
+
+import (
+	"strings"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestGetVxlanUpScripts(t *testing.T) {
+	nic := jsonutils.NewDict()
+	nic.Set("ifname", jsonutils.NewString("vnet1-12"))
+	nic.Set("ip", jsonutils.NewString("192.168.10.5"))
+	nic.Set("mac", jsonutils.NewString("00:22:33:44:55:66"))
+	nic.Set("gateway", jsonutils.NewString("192.168.10.1"))
+	nic.Set("masklen", jsonutils.NewInt(24))
+	if _, err := getVxlanUpScripts(nic); err == nil {
+		t.Errorf("nic without vni should fail")
+	}
+
+	nic.Set("vni", jsonutils.NewInt(1001))
+	nic.Set("vpc_vni", jsonutils.NewInt(1000))
+	nic.Set("vtep", jsonutils.NewString("10.0.0.2"))
+	script, err := getVxlanUpScripts(nic)
+	if err != nil {
+		t.Fatalf("getVxlanUpScripts: %s", err)
+	}
+	for _, want := range []string{
+		"ip link add vx1001 type vxlan id 1001 local 10.0.0.2 dstport 4789 nolearning proxy",
+		"ip link add vrf1000 type vrf table 1000",
+		"ip link set dev brvx1001 master vrf1000",
+		"ip address replace 192.168.10.1/24 dev brvx1001",
+		"ip link set dev $IF master $BRIDGE",
+		"iptables -t nat -I PREROUTING -i brvx1001 -d 169.254.169.254/32 -p tcp --dport 80 -j REDIRECT",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q", want)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -33,6 +34,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/sshkeys"
 	"yunion.io/x/onecloud/pkg/hostman/guestfs/fsdriver"
 	"yunion.io/x/onecloud/pkg/hostman/hostinfo/hostbridge"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo/hostdhcp"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
 	"yunion.io/x/onecloud/pkg/hostman/isolated_device"
 	"yunion.io/x/onecloud/pkg/hostman/options"
@@ -82,6 +84,9 @@ type SHostInfo struct {
 	MasterNic *netutils2.SNetInterface
 	Nics      []*SNIC
 
+	overlayDhcpLock    sync.Mutex
+	overlayDhcpServers map[string]*hostdhcp.SGuestDHCPServer
+
 	HostId         string
 	Zone           string
 	ZoneId         string
@@ -105,6 +110,35 @@ func (h *SHostInfo) GetBridgeDev(bridge string) hostbridge.IBridgeDriver {
 	return nil
 }
 
+// EnsureOverlayDHCPServer starts the guest DHCP server of an overlay bridge
+// once, the guests there do not reach the servers of the host bridges
+func (h *SHostInfo) EnsureOverlayDHCPServer(bridge string) error {
+	h.overlayDhcpLock.Lock()
+	defer h.overlayDhcpLock.Unlock()
+	if _, ok := h.overlayDhcpServers[bridge]; ok {
+		return nil
+	}
+	server, err := hostdhcp.NewGuestDHCPServer(bridge, nil)
+	if err != nil {
+		return err
+	}
+	server.Start()
+	if h.overlayDhcpServers == nil {
+		h.overlayDhcpServers = make(map[string]*hostdhcp.SGuestDHCPServer)
+	}
+	h.overlayDhcpServers[bridge] = server
+	return nil
+}
+
+func (h *SHostInfo) GetWireIdOfInterface(ifname string) string {
+	for _, n := range h.Nics {
+		if ifname == n.Inter {
+			return n.WireId
+		}
+	}
+	return ""
+}
+
 func (h *SHostInfo) GetHostId() string {
 	return h.HostId
 }
@@ -640,7 +674,10 @@ func (h *SHostInfo) detectiveSyssoftwareInfo() error {
 	if err := h.detectiveQemuVersion(); err != nil {
 		return err
 	}
-	h.detectiveOvsVersion()
+	h.sysinfo.BridgeDriver = options.HostOptions.BridgeDriver
+	if options.HostOptions.BridgeDriver == hostbridge.DRIVER_OPENVSWITCH {
+		h.detectiveOvsVersion()
+	}
 	return nil
 }
 
@@ -303,6 +303,7 @@ type SSysInfo struct {
 	KernelVersion  string `json:"kernel_version"`
 	QemuVersion    string `json:"qemu_version"`
 	OvsVersion     string `json:"ovs_version"`
+	BridgeDriver   string `json:"bridge_driver"`
 
 	StorageType string `json:"storage_type"`
 }
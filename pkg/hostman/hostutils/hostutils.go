@@ -47,6 +47,7 @@ type IHost interface {
 	PutHostOnline() error
 
 	GetBridgeDev(bridge string) hostbridge.IBridgeDriver
+	EnsureOverlayDHCPServer(bridge string) error
 	GetIsolatedDeviceManager() *isolated_device.IsolatedDeviceManager
 }
 
@@ -74,6 +74,7 @@ type CloudDeviceInfo struct {
 type IHost interface {
 	GetHostId() string
 	GetSession() *mcclient.ClientSession
+	GetWireIdOfInterface(ifname string) string
 }
 
 type IDevice interface {
@@ -110,7 +111,8 @@ func NewManager(host IHost) (*IsolatedDeviceManager, error) {
 }
 
 func (man *IsolatedDeviceManager) fillPCIDevices() error {
-	// only support gpu by now
+	man.fillSriovDevices()
+	man.fillMdevDevices()
 	gpus, err := getPassthroughGPUS()
 	if err != nil {
 		// ignore getPassthroughGPUS error on old machines without VGA devices
@@ -232,7 +234,10 @@ func (dev *sBaseDevice) SyncDeviceInfo(host IHost) error {
 	if len(dev.hostId) == 0 {
 		dev.hostId = host.GetHostId()
 	}
-	data := dev.GetApiResourceData()
+	return dev.syncDeviceInfo(host, dev.GetApiResourceData())
+}
+
+func (dev *sBaseDevice) syncDeviceInfo(host IHost, data jsonutils.JSONObject) error {
 	if len(dev.GetCloudId()) != 0 {
 		log.Infof("Update %s isolated_device: %s", dev.GetCloudId(), data.String())
 		_, err := modules.IsolatedDevices.Update(host.GetSession(), dev.GetCloudId(), data)
@@ -0,0 +1,306 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolated_device
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	o "yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	VGPU_TYPE = "VGPU"
+
+	MDEV_DEVICE_API_VFIO_PCI = "vfio-pci"
+)
+
+var (
+	sysfsMdevBusPath     = "/sys/class/mdev_bus"
+	sysfsMdevDevicesPath = "/sys/bus/mdev/devices"
+)
+
+// sMdevType is a mediated device type supported by a parent device, e.g.
+// nvidia-63 of a NVIDIA GRID card or i915-GVTg_V5_4 of an Intel GPU
+type sMdevType struct {
+	// parent pci address in `Domain:Bus:Device.Function` format
+	Parent string
+	Type   string
+	// Name is the human readable name like `GRID P40-2Q`
+	Name               string
+	DeviceApi          string
+	AvailableInstances int
+	// Instances are uuids of the mdevs of the type created on parent
+	Instances []string
+}
+
+func getMdevTypePath(parent, mdevType string) string {
+	return path.Join(sysfsMdevBusPath, parent, "mdev_supported_types", mdevType)
+}
+
+func getMdevPath(uuid string) string {
+	return path.Join(sysfsMdevDevicesPath, uuid)
+}
+
+func readSysfsString(p string) string {
+	content, err := fileutils2.FileGetContents(p)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(content)
+}
+
+// getMdevParents lists pci addresses of the devices mdevs can be created on
+func getMdevParents() ([]string, error) {
+	infos, err := ioutil.ReadDir(sysfsMdevBusPath)
+	if err != nil {
+		return nil, err
+	}
+	parents := make([]string, 0, len(infos))
+	for _, info := range infos {
+		parents = append(parents, info.Name())
+	}
+	sort.Strings(parents)
+	return parents, nil
+}
+
+func getMdevType(parent, mdevType string) (*sMdevType, error) {
+	typePath := getMdevTypePath(parent, mdevType)
+	available, err := readSysfsInt(path.Join(typePath, "available_instances"))
+	if err != nil {
+		return nil, fmt.Errorf("%s does not support mdev type %s: %v", parent, mdevType, err)
+	}
+	instances := []string{}
+	infos, err := ioutil.ReadDir(path.Join(typePath, "devices"))
+	if err == nil {
+		for _, info := range infos {
+			instances = append(instances, info.Name())
+		}
+	}
+	return &sMdevType{
+		Parent:             parent,
+		Type:               mdevType,
+		Name:               readSysfsString(path.Join(typePath, "name")),
+		DeviceApi:          readSysfsString(path.Join(typePath, "device_api")),
+		AvailableInstances: available,
+		Instances:          instances,
+	}, nil
+}
+
+// mdevUUID is the uuid of the index-th mdev of type on parent, it is derived
+// from them so that the same mdev is created again after host restarts
+func mdevUUID(parent, mdevType string, index int) string {
+	sum := md5.Sum([]byte(fmt.Sprintf("%s/%s/%d", parent, mdevType, index)))
+	// name based uuid of version 3
+	sum[6] = (sum[6] & 0x0f) | 0x30
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// slotUUIDs returns uuids of the mdevs the host can create of the type, the
+// ones already created by the host count but not those created by others
+func (t *sMdevType) slotUUIDs() []string {
+	created := make(map[string]bool)
+	for _, uuid := range t.Instances {
+		created[uuid] = true
+	}
+	uuids := []string{}
+	free := t.AvailableInstances
+	for i := 0; i < t.AvailableInstances+len(t.Instances); i++ {
+		uuid := mdevUUID(t.Parent, t.Type, i)
+		if created[uuid] {
+			uuids = append(uuids, uuid)
+		} else if free > 0 {
+			uuids = append(uuids, uuid)
+			free--
+		}
+	}
+	return uuids
+}
+
+func (man *IsolatedDeviceManager) fillMdevDevices() {
+	if len(o.HostOptions.VgpuTypes) == 0 {
+		return
+	}
+	if _, err := procutils.Run("modprobe", "vfio_mdev"); err != nil {
+		// built in the kernel or the vendor driver loads it
+		log.Warningf("modprobe vfio_mdev: %v", err)
+	}
+	devs, err := detectMdevDevices(o.HostOptions.VgpuTypes)
+	if err != nil {
+		log.Errorf("Detect mediated devices: %v", err)
+		return
+	}
+	for _, dev := range devs {
+		man.Devices = append(man.Devices, dev)
+		log.Infof("Add vGPU device: %s %s => %s", dev.parent, dev.mdevType, dev.uuid)
+	}
+}
+
+// detectMdevDevices returns a device for every mdev can be created on the
+// parents, a parent only creates mdevs of the first type of mdevTypes it
+// supports because vendors like NVIDIA don't mix types on one card
+func detectMdevDevices(mdevTypes []string) ([]*sMdevDevice, error) {
+	parents, err := getMdevParents()
+	if err != nil {
+		return nil, err
+	}
+	devs := []*sMdevDevice{}
+	for _, parent := range parents {
+		for _, mdevType := range mdevTypes {
+			t, err := getMdevType(parent, mdevType)
+			if err != nil {
+				continue
+			}
+			if t.DeviceApi != MDEV_DEVICE_API_VFIO_PCI {
+				log.Warningf("mdev type %s of %s has device api %q, skip it", mdevType, parent, t.DeviceApi)
+				continue
+			}
+			for idx, uuid := range t.slotUUIDs() {
+				devs = append(devs, newMdevDevice(t, idx, uuid))
+			}
+			break
+		}
+	}
+	return devs, nil
+}
+
+// GetMdevSetupScript creates the mdevs of the vGPUs of devAddrs not created yet
+func (man *IsolatedDeviceManager) GetMdevSetupScript(devAddrs []string) string {
+	cmd := ""
+	for _, addr := range devAddrs {
+		if dev, ok := man.GetDeviceByAddr(addr).(*sMdevDevice); ok {
+			cmd += dev.getCreateScript()
+		}
+	}
+	return cmd
+}
+
+// GetMdevCleanupScript removes the mdevs of the vGPUs of devAddrs, so their
+// capacity is available to other types again
+func (man *IsolatedDeviceManager) GetMdevCleanupScript(devAddrs []string) string {
+	cmd := ""
+	for _, addr := range devAddrs {
+		if dev, ok := man.GetDeviceByAddr(addr).(*sMdevDevice); ok {
+			cmd += dev.getRemoveScript()
+		}
+	}
+	return cmd
+}
+
+type sMdevDevice struct {
+	*sBaseDevice
+	parent   string
+	mdevType string
+	uuid     string
+}
+
+// newMdevDevice returns the idx-th vGPU of type t, its address is the
+// address of the parent with the index like `3b:00.0/1`
+func newMdevDevice(t *sMdevType, idx int, uuid string) *sMdevDevice {
+	parentPath := path.Join(sysfsMdevBusPath, t.Parent)
+	dev := &PCIDevice{
+		Addr:      fmt.Sprintf("%s/%d", strings.TrimPrefix(t.Parent, "0000:"), idx),
+		VendorId:  strings.TrimPrefix(readSysfsString(path.Join(parentPath, "vendor")), "0x"),
+		DeviceId:  strings.TrimPrefix(readSysfsString(path.Join(parentPath, "device")), "0x"),
+		ModelName: t.Name,
+	}
+	if len(dev.ModelName) == 0 {
+		dev.ModelName = t.Type
+	}
+	if len(dev.ModelName) > MAX_MODEL_LENGTH {
+		dev.ModelName = dev.ModelName[:MAX_MODEL_LENGTH]
+	}
+	return &sMdevDevice{
+		sBaseDevice: newBaseDevice(dev),
+		parent:      t.Parent,
+		mdevType:    t.Type,
+		uuid:        uuid,
+	}
+}
+
+func (dev *sMdevDevice) GetDeviceType() string {
+	return VGPU_TYPE
+}
+
+func (dev *sMdevDevice) GetCPUCmd() string {
+	return DEFAULT_CPU_CMD
+}
+
+func (dev *sMdevDevice) GetVGACmd() string {
+	return DEFAULT_VGA_CMD
+}
+
+func (dev *sMdevDevice) GetPassthroughCmd(index int) string {
+	return fmt.Sprintf(" -device vfio-pci,sysfsdev=%s,addr=%s", getMdevPath(dev.uuid), getGuestAddr(index))
+}
+
+func (dev *sMdevDevice) isDetected() bool {
+	if fileutils2.Exists(getMdevPath(dev.uuid)) {
+		return true
+	}
+	return fileutils2.Exists(getMdevTypePath(dev.parent, dev.mdevType))
+}
+
+func (dev *sMdevDevice) CustomProbe() error {
+	if !dev.isDetected() {
+		return fmt.Errorf("mdev type %s not found on %s", dev.mdevType, dev.parent)
+	}
+	return nil
+}
+
+// SyncDeviceInfo describes the vGPU without probing its address by lspci,
+// which is not a pci address
+func (dev *sMdevDevice) SyncDeviceInfo(host IHost) error {
+	if len(dev.hostId) == 0 {
+		dev.hostId = host.GetHostId()
+	}
+	data := jsonutils.NewDict()
+	data.Set("dev_type", jsonutils.NewString(dev.GetDeviceType()))
+	data.Set("addr", jsonutils.NewString(dev.GetAddr()))
+	data.Set("model", jsonutils.NewString(dev.dev.ModelName))
+	data.Set("vendor_device_id", jsonutils.NewString(dev.GetVendorDeviceId()))
+	data.Set("detected_on_host", jsonutils.NewBool(dev.isDetected()))
+	if len(dev.cloudId) != 0 {
+		data.Set("id", jsonutils.NewString(dev.cloudId))
+	}
+	if len(dev.hostId) != 0 {
+		data.Set("host_id", jsonutils.NewString(dev.hostId))
+	}
+	if len(dev.guestId) != 0 {
+		data.Set("guest_id", jsonutils.NewString(dev.guestId))
+	}
+	return dev.syncDeviceInfo(host, data)
+}
+
+func (dev *sMdevDevice) getCreateScript() string {
+	mdevPath := getMdevPath(dev.uuid)
+	createPath := path.Join(getMdevTypePath(dev.parent, dev.mdevType), "create")
+	return fmt.Sprintf("if [ ! -d %s ]; then\n  echo %s > %s\nfi\n", mdevPath, dev.uuid, createPath)
+}
+
+func (dev *sMdevDevice) getRemoveScript() string {
+	removePath := path.Join(getMdevPath(dev.uuid), "remove")
+	return fmt.Sprintf("if [ -f %s ]; then\n  echo 1 > %s\nfi\n", removePath, removePath)
+}
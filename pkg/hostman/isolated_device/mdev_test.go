@@ -0,0 +1,141 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolated_device
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// prepareFakeMdevSysfs lays out a NVIDIA card 0000:3b:00.0 supporting mdev
+// types nvidia-63 and nvidia-64, created are the uuids of nvidia-63 mdevs
+func prepareFakeMdevSysfs(t *testing.T, available string, created ...string) string {
+	root, err := ioutil.TempDir("", "mdev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysfsMdevBusPath = path.Join(root, "class", "mdev_bus")
+	sysfsMdevDevicesPath = path.Join(root, "bus", "mdev", "devices")
+	parentPath := path.Join(sysfsMdevBusPath, "0000:3b:00.0")
+	files := map[string]string{
+		path.Join(parentPath, "vendor"): "0x10de",
+		path.Join(parentPath, "device"): "0x1b38",
+	}
+	for mdevType, name := range map[string]string{"nvidia-63": "GRID P40-2Q", "nvidia-64": "GRID P40-3Q"} {
+		typePath := path.Join(parentPath, "mdev_supported_types", mdevType)
+		if err := os.MkdirAll(path.Join(typePath, "devices"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		files[path.Join(typePath, "name")] = name
+		files[path.Join(typePath, "device_api")] = MDEV_DEVICE_API_VFIO_PCI
+		files[path.Join(typePath, "available_instances")] = available
+	}
+	for _, uuid := range created {
+		if err := os.MkdirAll(getMdevPath(uuid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		link := path.Join(getMdevTypePath("0000:3b:00.0", "nvidia-63"), "devices", uuid)
+		if err := os.Symlink(getMdevPath(uuid), link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(name, []byte(content+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func Test_mdevUUID(t *testing.T) {
+	uuid := mdevUUID("0000:3b:00.0", "nvidia-63", 0)
+	if len(uuid) != 36 || uuid[14] != '3' {
+		t.Errorf("mdevUUID = %q, want a version 3 uuid", uuid)
+	}
+	if uuid != mdevUUID("0000:3b:00.0", "nvidia-63", 0) {
+		t.Errorf("mdevUUID should be stable")
+	}
+	if uuid == mdevUUID("0000:3b:00.0", "nvidia-63", 1) {
+		t.Errorf("mdevUUID should differ by index")
+	}
+}
+
+func Test_detectMdevDevices(t *testing.T) {
+	uuid0 := mdevUUID("0000:3b:00.0", "nvidia-63", 0)
+	foreign := "5b0b7b4e-7a32-4a0b-9c39-2c1e6a4f3d11"
+	root := prepareFakeMdevSysfs(t, "2", uuid0, foreign)
+	defer os.RemoveAll(root)
+
+	if devs, _ := detectMdevDevices([]string{"nvidia-11"}); len(devs) != 0 {
+		t.Errorf("detectMdevDevices of unsupported type = %d devices, want 0", len(devs))
+	}
+
+	devs, err := detectMdevDevices([]string{"nvidia-11", "nvidia-63", "nvidia-64"})
+	if err != nil {
+		t.Fatalf("detectMdevDevices: %v", err)
+	}
+	// 2 available and the one created by host, not the foreign one
+	want := []string{uuid0, mdevUUID("0000:3b:00.0", "nvidia-63", 1), mdevUUID("0000:3b:00.0", "nvidia-63", 2)}
+	got := []string{}
+	for idx, dev := range devs {
+		got = append(got, dev.uuid)
+		if dev.mdevType != "nvidia-63" {
+			t.Errorf("device %d type = %s, want nvidia-63", idx, dev.mdevType)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("detectMdevDevices uuids = %v, want %v", got, want)
+	}
+
+	dev := devs[1]
+	if dev.GetAddr() != "3b:00.0/1" || dev.GetVendorDeviceId() != "10de:1b38" || dev.dev.ModelName != "GRID P40-2Q" {
+		t.Errorf("device = %s %s %s", dev.GetAddr(), dev.GetVendorDeviceId(), dev.dev.ModelName)
+	}
+	if err := dev.CustomProbe(); err != nil {
+		t.Errorf("CustomProbe: %v", err)
+	}
+	wantCmd := " -device vfio-pci,sysfsdev=" + getMdevPath(dev.uuid) + ",addr=0x16"
+	if cmd := dev.GetPassthroughCmd(1); cmd != wantCmd {
+		t.Errorf("GetPassthroughCmd = %q, want %q", cmd, wantCmd)
+	}
+}
+
+func TestIsolatedDeviceManager_GetMdevScripts(t *testing.T) {
+	root := prepareFakeMdevSysfs(t, "1")
+	defer os.RemoveAll(root)
+
+	devs, err := detectMdevDevices([]string{"nvidia-64"})
+	if err != nil || len(devs) != 1 {
+		t.Fatalf("detectMdevDevices = %d devices, %v", len(devs), err)
+	}
+	dev := devs[0]
+	man := &IsolatedDeviceManager{Devices: []IDevice{dev}}
+	addrs := []string{"3b:00.0/0", "3b:00.0"}
+
+	mdevPath := getMdevPath(dev.uuid)
+	createPath := path.Join(getMdevTypePath("0000:3b:00.0", "nvidia-64"), "create")
+	want := "if [ ! -d " + mdevPath + " ]; then\n  echo " + dev.uuid + " > " + createPath + "\nfi\n"
+	if got := man.GetMdevSetupScript(addrs); got != want {
+		t.Errorf("GetMdevSetupScript = %q, want %q", got, want)
+	}
+	removePath := path.Join(mdevPath, "remove")
+	want = "if [ -f " + removePath + " ]; then\n  echo 1 > " + removePath + "\nfi\n"
+	if got := man.GetMdevCleanupScript(addrs); got != want {
+		t.Errorf("GetMdevCleanupScript = %q, want %q", got, want)
+	}
+}
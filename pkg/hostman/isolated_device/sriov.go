@@ -0,0 +1,256 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolated_device
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	o "yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	NIC_VF_TYPE = "NIC-VF"
+
+	// model column of isolated devices is 32 chars width
+	MAX_MODEL_LENGTH = 32
+)
+
+var (
+	sysfsNetPath        = "/sys/class/net"
+	sysfsPCIDevicesPath = "/sys/bus/pci/devices"
+)
+
+type sSriovVF struct {
+	// pci address in `Bus:Device.Function` format like lspci
+	Addr  string
+	Index int
+}
+
+// parseSriovNicConf parse SriovNics option of `<ifname>/<num_vfs>` format
+func parseSriovNicConf(conf string) (string, int, error) {
+	parts := strings.Split(conf, "/")
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return "", 0, fmt.Errorf("Invalid SR-IOV nic config %q, should be <ifname>/<num_vfs>", conf)
+	}
+	numVfs, err := strconv.Atoi(parts[1])
+	if err != nil || numVfs <= 0 {
+		return "", 0, fmt.Errorf("Invalid number of virtual functions in %q", conf)
+	}
+	return parts[0], numVfs, nil
+}
+
+func getSriovDevicePath(ifname string) string {
+	return path.Join(sysfsNetPath, ifname, "device")
+}
+
+func readSysfsInt(p string) (int, error) {
+	content, err := fileutils2.FileGetContents(p)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(content))
+}
+
+func getSriovTotalVfs(ifname string) (int, error) {
+	return readSysfsInt(path.Join(getSriovDevicePath(ifname), "sriov_totalvfs"))
+}
+
+// setSriovNumVfs creates numVfs virtual functions on physical nic ifname.
+// The kernel only changes a non-zero count by resetting it to zero, which
+// removes the virtual functions under the running guests, so existing
+// virtual functions are kept as they are.
+func setSriovNumVfs(ifname string, numVfs int) error {
+	totalVfs, err := getSriovTotalVfs(ifname)
+	if err != nil {
+		return fmt.Errorf("%s is not SR-IOV capable: %v", ifname, err)
+	}
+	if numVfs > totalVfs {
+		return fmt.Errorf("%s supports at most %d virtual functions, %d requested", ifname, totalVfs, numVfs)
+	}
+	numVfsPath := path.Join(getSriovDevicePath(ifname), "sriov_numvfs")
+	curVfs, err := readSysfsInt(numVfsPath)
+	if err != nil {
+		return err
+	}
+	if curVfs == numVfs {
+		return nil
+	}
+	if curVfs > 0 {
+		log.Warningf("%s has %d virtual functions, %d configured, keep the existing ones", ifname, curVfs, numVfs)
+		return nil
+	}
+	return fileutils2.FilePutContents(numVfsPath, strconv.Itoa(numVfs), false)
+}
+
+// getSriovVFs lists virtual functions of physical nic ifname by
+// resolving the device/virtfn<N> links
+func getSriovVFs(ifname string) ([]sSriovVF, error) {
+	devPath := getSriovDevicePath(ifname)
+	links, err := filepath.Glob(path.Join(devPath, "virtfn*"))
+	if err != nil {
+		return nil, err
+	}
+	vfs := []sSriovVF{}
+	for _, link := range links {
+		index, err := strconv.Atoi(strings.TrimPrefix(path.Base(link), "virtfn"))
+		if err != nil {
+			continue
+		}
+		target, err := os.Readlink(link)
+		if err != nil {
+			return nil, err
+		}
+		addr := path.Base(target)
+		// strip pci domain to be the same format as lspci
+		if parts := strings.SplitN(addr, ":", 2); len(parts) == 2 && len(addr) == 12 {
+			addr = parts[1]
+		}
+		vfs = append(vfs, sSriovVF{Addr: addr, Index: index})
+	}
+	sort.Slice(vfs, func(i, j int) bool { return vfs[i].Index < vfs[j].Index })
+	return vfs, nil
+}
+
+func (man *IsolatedDeviceManager) fillSriovDevices() {
+	if len(o.HostOptions.SriovNics) == 0 {
+		return
+	}
+	if _, err := procutils.Run("modprobe", "vfio-pci"); err != nil {
+		log.Errorf("modprobe vfio-pci: %v", err)
+		return
+	}
+	for _, conf := range o.HostOptions.SriovNics {
+		ifname, numVfs, err := parseSriovNicConf(conf)
+		if err != nil {
+			log.Errorln(err)
+			continue
+		}
+		if err := setSriovNumVfs(ifname, numVfs); err != nil {
+			log.Errorf("Create SR-IOV virtual functions on %s: %v", ifname, err)
+			continue
+		}
+		vfs, err := getSriovVFs(ifname)
+		if err != nil {
+			log.Errorf("Get SR-IOV virtual functions of %s: %v", ifname, err)
+			continue
+		}
+		for _, vf := range vfs {
+			dev, err := detectPCIDevByAddrWithoutIOMMUGroup(vf.Addr)
+			if err != nil {
+				log.Errorf("Detect SR-IOV virtual function %s: %v", vf.Addr, err)
+				continue
+			}
+			if !dev.IsVFIOPCIDriverUsed() {
+				if err := dev.bindVFIOPCIDriverOverride(); err != nil {
+					log.Errorf("Bind %s to vfio-pci: %v", vf.Addr, err)
+					continue
+				}
+			}
+			man.Devices = append(man.Devices, newSriovVFDevice(dev, ifname, vf.Index))
+			log.Infof("Add SR-IOV virtual function: %s vf %d => %s", ifname, vf.Index, vf.Addr)
+		}
+	}
+}
+
+// bindVFIOPCIDriverOverride binds only this device to vfio-pci, unlike
+// bindDriver which grabs every unbound device of the same vendor:device id
+func (d *PCIDevice) bindVFIOPCIDriverOverride() error {
+	if err := d.unbindDriver(); err != nil {
+		return err
+	}
+	addr := fmt.Sprintf("0000:%s", d.Addr)
+	overridePath := path.Join(sysfsPCIDevicesPath, addr, "driver_override")
+	if err := fileutils2.FilePutContents(overridePath, VFIO_PCI_KERNEL_DRIVER, false); err != nil {
+		return fmt.Errorf("write %s: %v", overridePath, err)
+	}
+	return fileutils2.FilePutContents("/sys/bus/pci/drivers_probe", addr, false)
+}
+
+func (man *IsolatedDeviceManager) GetSriovVFSetupScript(addr string, mac string, vlan int) (string, error) {
+	dev, ok := man.GetDeviceByAddr(addr).(*sSriovVFDevice)
+	if !ok {
+		return "", fmt.Errorf("SR-IOV virtual function %s not found", addr)
+	}
+	return dev.getSetupScript(mac, vlan), nil
+}
+
+type sSriovVFDevice struct {
+	*sBaseDevice
+	pfName  string
+	vfIndex int
+}
+
+func newSriovVFDevice(dev *PCIDevice, pfName string, vfIndex int) *sSriovVFDevice {
+	if len(dev.ModelName) == 0 {
+		dev.ModelName = dev.DeviceName
+		if len(dev.ModelName) > MAX_MODEL_LENGTH {
+			dev.ModelName = dev.ModelName[:MAX_MODEL_LENGTH]
+		}
+	}
+	return &sSriovVFDevice{
+		sBaseDevice: newBaseDevice(dev),
+		pfName:      pfName,
+		vfIndex:     vfIndex,
+	}
+}
+
+func (dev *sSriovVFDevice) GetDeviceType() string {
+	return NIC_VF_TYPE
+}
+
+func (dev *sSriovVFDevice) GetCPUCmd() string {
+	return DEFAULT_CPU_CMD
+}
+
+func (dev *sSriovVFDevice) GetVGACmd() string {
+	return DEFAULT_VGA_CMD
+}
+
+func (dev *sSriovVFDevice) CustomProbe() error {
+	if !dev.IsPassthroughAble() {
+		return fmt.Errorf("SR-IOV virtual function %s is not bound to %s", dev.GetAddr(), VFIO_PCI_KERNEL_DRIVER)
+	}
+	return nil
+}
+
+func (dev *sSriovVFDevice) SyncDeviceInfo(host IHost) error {
+	if len(dev.hostId) == 0 {
+		dev.hostId = host.GetHostId()
+	}
+	data := dev.GetApiResourceData().(*jsonutils.JSONDict)
+	data.Set("dev_type", jsonutils.NewString(dev.GetDeviceType()))
+	data.Set("wire_id", jsonutils.NewString(host.GetWireIdOfInterface(dev.pfName)))
+	return dev.syncDeviceInfo(host, data)
+}
+
+// getSetupScript program mac and vlan of the virtual function on its
+// physical function, vlan 1 means untagged like bridge drivers
+func (dev *sSriovVFDevice) getSetupScript(mac string, vlan int) string {
+	if vlan <= 1 {
+		vlan = 0
+	}
+	return fmt.Sprintf("ip link set dev %s vf %d mac %s vlan %d spoofchk on\n", dev.pfName, dev.vfIndex, mac, vlan)
+}
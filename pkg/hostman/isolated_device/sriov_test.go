@@ -0,0 +1,148 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolated_device
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// prepareFakeSriovSysfs lays out a physical nic eth0 with totalVfs capability
+func prepareFakeSriovSysfs(t *testing.T, totalVfs string) string {
+	root, err := ioutil.TempDir("", "sriov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysfsNetPath = path.Join(root, "class", "net")
+	sysfsPCIDevicesPath = path.Join(root, "bus", "pci", "devices")
+	pfPath := path.Join(sysfsPCIDevicesPath, "0000:3b:00.0")
+	for _, dir := range []string{path.Join(sysfsNetPath, "eth0"), pfPath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(pfPath, getSriovDevicePath("eth0")); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"sriov_totalvfs": totalVfs,
+		"sriov_numvfs":   "0",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(pfPath, name), []byte(content+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func Test_parseSriovNicConf(t *testing.T) {
+	tests := []struct {
+		conf    string
+		ifname  string
+		numVfs  int
+		wantErr bool
+	}{
+		{conf: "eth0/8", ifname: "eth0", numVfs: 8},
+		{conf: "eth0", wantErr: true},
+		{conf: "eth0/0", wantErr: true},
+		{conf: "/4", wantErr: true},
+	}
+	for _, tt := range tests {
+		ifname, numVfs, err := parseSriovNicConf(tt.conf)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSriovNicConf(%q) error = %v, wantErr %v", tt.conf, err, tt.wantErr)
+			continue
+		}
+		if ifname != tt.ifname || numVfs != tt.numVfs {
+			t.Errorf("parseSriovNicConf(%q) = %s, %d, want %s, %d", tt.conf, ifname, numVfs, tt.ifname, tt.numVfs)
+		}
+	}
+}
+
+func Test_setSriovNumVfs(t *testing.T) {
+	root := prepareFakeSriovSysfs(t, "4")
+	defer os.RemoveAll(root)
+
+	if err := setSriovNumVfs("eth0", 8); err == nil {
+		t.Errorf("setSriovNumVfs should fail when exceeding sriov_totalvfs")
+	}
+	if err := setSriovNumVfs("eth0", 2); err != nil {
+		t.Fatalf("setSriovNumVfs: %v", err)
+	}
+	if numVfs, _ := readSysfsInt(path.Join(getSriovDevicePath("eth0"), "sriov_numvfs")); numVfs != 2 {
+		t.Errorf("sriov_numvfs = %d, want 2", numVfs)
+	}
+	// existing virtual functions may be used by guests and are never reset
+	if err := setSriovNumVfs("eth0", 3); err != nil {
+		t.Fatalf("setSriovNumVfs: %v", err)
+	}
+	if numVfs, _ := readSysfsInt(path.Join(getSriovDevicePath("eth0"), "sriov_numvfs")); numVfs != 2 {
+		t.Errorf("sriov_numvfs = %d, want 2 kept", numVfs)
+	}
+	if err := setSriovNumVfs("eth1", 2); err == nil {
+		t.Errorf("setSriovNumVfs should fail on nic without SR-IOV capability")
+	}
+}
+
+func Test_getSriovVFs(t *testing.T) {
+	root := prepareFakeSriovSysfs(t, "4")
+	defer os.RemoveAll(root)
+
+	devPath := getSriovDevicePath("eth0")
+	for name, addr := range map[string]string{
+		"virtfn0":  "0000:3b:02.0",
+		"virtfn1":  "0000:3b:02.1",
+		"virtfn10": "0000:3b:03.2",
+	} {
+		if err := os.Symlink("../"+addr, path.Join(devPath, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vfs, err := getSriovVFs("eth0")
+	if err != nil {
+		t.Fatalf("getSriovVFs: %v", err)
+	}
+	want := []sSriovVF{
+		{Addr: "3b:02.0", Index: 0},
+		{Addr: "3b:02.1", Index: 1},
+		{Addr: "3b:03.2", Index: 10},
+	}
+	if !reflect.DeepEqual(vfs, want) {
+		t.Errorf("getSriovVFs = %#v, want %#v", vfs, want)
+	}
+}
+
+func Test_sSriovVFDevice_getSetupScript(t *testing.T) {
+	dev := newSriovVFDevice(&PCIDevice{Addr: "3b:02.1", DeviceName: "Ethernet Virtual Function 700 Series"}, "eth0", 1)
+	if dev.dev.ModelName != "Ethernet Virtual Function 700 Se" {
+		t.Errorf("model name = %q", dev.dev.ModelName)
+	}
+	tests := []struct {
+		vlan int
+		want string
+	}{
+		{vlan: 1, want: "ip link set dev eth0 vf 1 mac 00:22:33:44:55:66 vlan 0 spoofchk on\n"},
+		{vlan: 100, want: "ip link set dev eth0 vf 1 mac 00:22:33:44:55:66 vlan 100 spoofchk on\n"},
+	}
+	for _, tt := range tests {
+		if got := dev.getSetupScript("00:22:33:44:55:66", tt.vlan); got != tt.want {
+			t.Errorf("getSetupScript vlan %d = %q, want %q", tt.vlan, got, tt.want)
+		}
+	}
+}
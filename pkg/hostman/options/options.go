@@ -21,8 +21,10 @@ type SHostOptions struct {
 
 	HostType        string   `help:"Host server type, either hypervisor or kubelet" default:"hypervisor"`
 	ListenInterface string   `help:"Master address of host server"`
-	BridgeDriver    string   `help:"Bridge driver, bridge or openvswitch" default: "openvswitch"`
+	BridgeDriver    string   `help:"Bridge driver, linuxbridge or openvswitch" default:"openvswitch"`
 	Networks        []string `help:"Network interface information"`
+	SriovNics       []string `help:"Physical NICs to create SR-IOV virtual functions on, in the form of <ifname>/<num_vfs>"`
+	VgpuTypes       []string `help:"Mediated device types to create vGPUs of, e.g. nvidia-63 or i915-GVTg_V5_4, a card uses the first type it supports"`
 	Rack            string   `help:"Rack of host (optional)"`
 	Slots           string   `help:"Slots of host (optional)"`
 	Hostname        string   `help:"Customized host name"`
@@ -59,6 +61,8 @@ type SHostOptions struct {
 	HugepagesOption  string `help:"Hugepages option: disable|native|transparent" default:"transparent"`
 	EnableQmpMonitor bool   `help:"Enable qmp monitor" default:"true"`
 
+	EnableSerialConsole bool `help:"Add a serial port to guests for webconsole, only takes effect on guest restart" default:"false"`
+
 	PrivatePrefixes []string `help:"IPv4 private prefixes"`
 	LocalImagePath  []string `help:"Local image storage paths"`
 	SharedStorages  []string `help:"Path of shared storages"`
@@ -71,6 +75,8 @@ type SHostOptions struct {
 
 	TunnelPaddingBytes int64 `help:"Specify tunnel padding bytes" default:"0"`
 
+	OverlayFdbSyncIntervalSeconds int `help:"Interval in seconds to sync VXLAN forwarding tables of overlay wires" default:"30"`
+
 	CheckSystemServices bool `help:"Check system services (ntpd, telegraf) on startup" default:"true"`
 
 	DhcpServerPort int    `help:"Host dhcp server bind port" default:"67"`
@@ -22,6 +22,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/hostman/guestfs"
 )
 
@@ -45,6 +46,7 @@ type IDisk interface {
 	CreateFromUrl(context.Context, string) error
 	CreateFromTemplate(context.Context, string, string, int64) (jsonutils.JSONObject, error)
 	CreateFromImageFuse(context.Context, string) error
+	CreateFromImport(context.Context, string, *cloudprovider.SDiskExportInfo, int64) (jsonutils.JSONObject, error)
 	CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string,
 		encryption bool, diskId string, back string) (jsonutils.JSONObject, error)
 	PostCreateFromImageFuse()
@@ -86,6 +88,10 @@ func (d *SBaseDisk) CreateFromUrl(context.Context, string) error {
 	return fmt.Errorf("Not implemented")
 }
 
+func (d *SBaseDisk) CreateFromImport(context.Context, string, *cloudprovider.SDiskExportInfo, int64) (jsonutils.JSONObject, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
 func (d *SBaseDisk) CreateFromTemplate(context.Context, string, string, int64) (jsonutils.JSONObject, error) {
 	return nil, fmt.Errorf("Not implemented")
 }
@@ -17,8 +17,11 @@ package storageman
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path"
+	"strconv"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -26,14 +29,18 @@ import (
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman/remotefile"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
 	"yunion.io/x/onecloud/pkg/util/fuseutils"
+	"yunion.io/x/onecloud/pkg/util/httputils"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/vmdkutils"
 )
 
 var _ALTER_SUFFIX_ = ".alter"
@@ -249,6 +256,112 @@ func (d *SLocalDisk) CreateFromUrl(ctx context.Context, url string) error {
 	}
 }
 
+// CreateFromImport downloads the backing chain of a disk exported by a cloud
+// provider, e.g. the vmdk files of an ESXi virtual disk, and converts it into
+// a standalone qcow2 image. Every file is downloaded with a one-time ticket
+// issued by the region for the exported disk
+func (d *SLocalDisk) CreateFromImport(ctx context.Context, exportDiskId string, info *cloudprovider.SDiskExportInfo, sizeMB int64) (jsonutils.JSONObject, error) {
+	if len(info.Layers) == 0 {
+		return nil, fmt.Errorf("No disk file to import")
+	}
+
+	importDir := path.Join(d.Storage.GetPath(), _IMPORT_TMP_PATH_, d.Id)
+	if _, err := procutils.NewCommand("mkdir", "-p", importDir).Run(); err != nil {
+		return nil, fmt.Errorf("Fail to create %s: %s", importDir, err)
+	}
+	defer procutils.NewCommand("rm", "-rf", importDir).Run()
+
+	// every layer is fetched into its own directory, so that the extent
+	// names referenced by the descriptors stay untouched
+	var topPath string
+	for i := len(info.Layers) - 1; i >= 0; i-- {
+		layer := info.Layers[i]
+		layerDir := path.Join(importDir, strconv.Itoa(i))
+		if _, err := procutils.NewCommand("mkdir", "-p", layerDir).Run(); err != nil {
+			return nil, fmt.Errorf("Fail to create %s: %s", layerDir, err)
+		}
+		descPath := path.Join(layerDir, layer.DescriptorName)
+		files := [][2]string{{layer.DescriptorUrl, descPath}}
+		if len(layer.ExtentUrl) > 0 {
+			files = append(files, [2]string{layer.ExtentUrl, path.Join(layerDir, layer.ExtentName)})
+		}
+		for _, f := range files {
+			if err := fetchExportFile(ctx, exportDiskId, f[0], f[1]); err != nil {
+				return nil, fmt.Errorf("Fail to fetch %s: %s", f[0], err)
+			}
+		}
+		if i < len(info.Layers)-1 && info.Format == "vmdk" {
+			content, err := fileutils2.FileGetContents(descPath)
+			if err != nil {
+				return nil, err
+			}
+			parent := path.Join("..", strconv.Itoa(i+1), info.Layers[i+1].DescriptorName)
+			content = vmdkutils.SetParentFileNameHint(content, parent)
+			if err := fileutils2.FilePutContents(descPath, content, false); err != nil {
+				return nil, err
+			}
+		}
+		topPath = descPath
+	}
+
+	img, err := qemuimg.NewQemuImage(topPath)
+	if err != nil {
+		return nil, err
+	}
+	if fileutils2.Exists(d.GetPath()) {
+		os.Remove(d.GetPath())
+	}
+	if err := img.Convert2Qcow2To(d.GetPath(), false); err != nil {
+		return nil, fmt.Errorf("Fail to convert %s: %s", topPath, err)
+	}
+	if sizeMB > 0 {
+		newImg, err := qemuimg.NewQemuImage(d.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		if int64(newImg.GetSizeMB()) < sizeMB {
+			if err := newImg.Resize(int(sizeMB)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return d.GetDiskDesc(), nil
+}
+
+// fetchExportFile downloads an export url of a disk into localPath, the
+// ticket is single use so the download is not retried
+func fetchExportFile(ctx context.Context, exportDiskId, url, localPath string) error {
+	query := jsonutils.NewDict()
+	query.Set("url", jsonutils.NewString(url))
+	ret, err := modules.Disks.GetSpecific(hostutils.GetComputeSession(ctx), exportDiskId, "export-ticket", query)
+	if err != nil {
+		return fmt.Errorf("Fail to get export ticket: %s", err)
+	}
+	ticket := cloudprovider.SDiskExportTicket{}
+	if err := ret.Unmarshal(&ticket); err != nil {
+		return err
+	}
+	header := http.Header{}
+	for k, v := range ticket.Headers {
+		header.Set(k, v)
+	}
+	resp, err := httputils.Request(httputils.GetTimeoutClient(0), ctx, httputils.GET, ticket.Url, header, nil, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	fi, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+	_, err = io.Copy(fi, resp.Body)
+	return err
+}
+
 func (d *SLocalDisk) CreateRaw(ctx context.Context, sizeMB int, diskFormat, fsFormat string,
 	encryption bool, uuid string, back string) (jsonutils.JSONObject, error) {
 	if fileutils2.Exists(d.GetPath()) {
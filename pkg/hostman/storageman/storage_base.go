@@ -24,6 +24,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
 )
 
@@ -237,6 +238,9 @@ func (s *SBaseStorage) CreateDiskByDiskinfo(ctx context.Context, params interfac
 	case createParams.DiskInfo.Contains("snapshot"):
 		log.Infof("CreateDiskFromSnpashot %s", createParams)
 		return s.CreateDiskFromSnpashot(ctx, disk, createParams)
+	case createParams.DiskInfo.Contains("import"):
+		log.Infof("CreateDiskFromImport %s", createParams.DiskId)
+		return s.CreateDiskFromImport(ctx, disk, createParams)
 	case createParams.DiskInfo.Contains("image_id"):
 		log.Infof("CreateDiskFromTemplate %s", createParams)
 		return s.CreateDiskFromTemplate(ctx, disk, createParams)
@@ -267,6 +271,16 @@ func (s *SBaseStorage) CreateDiskFromTemplate(ctx context.Context, disk IDisk, c
 	return disk.CreateFromTemplate(ctx, imageId, format, size)
 }
 
+func (s *SBaseStorage) CreateDiskFromImport(ctx context.Context, disk IDisk, createParams *SDiskCreateByDiskinfo) (jsonutils.JSONObject, error) {
+	info := cloudprovider.SDiskExportInfo{}
+	if err := createParams.DiskInfo.Unmarshal(&info, "import"); err != nil {
+		return nil, fmt.Errorf("Unmarshal import info: %s", err)
+	}
+	exportDiskId, _ := createParams.DiskInfo.GetString("import_disk_id")
+	size, _ := createParams.DiskInfo.Int("size")
+	return disk.CreateFromImport(ctx, exportDiskId, &info, size)
+}
+
 func (s *SBaseStorage) CreateDiskFromSnpashot(ctx context.Context, disk IDisk, createParams *SDiskCreateByDiskinfo) (jsonutils.JSONObject, error) {
 	var (
 		// diskPath            = path.Join(s.Path, createParams.DiskId)
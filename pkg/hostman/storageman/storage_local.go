@@ -43,6 +43,7 @@ import (
 var (
 	_FUSE_MOUNT_PATH_   = "fusemnt"
 	_FUSE_TMP_PATH_     = "fusetmp"
+	_IMPORT_TMP_PATH_   = "importtmp"
 	_SNAPSHOT_PATH_     = "snapshots"
 	DELETEING_SNAPSHOTS = map[string]bool{}
 )
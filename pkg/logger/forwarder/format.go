@@ -0,0 +1,214 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwarder
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+)
+
+const (
+	FORMAT_SYSLOG = "syslog"
+	FORMAT_JSON   = "json"
+	FORMAT_CEF    = "cef"
+
+	// facility log audit, RFC5424 section 6.2.1
+	syslogFacilityAudit = 13
+
+	syslogSeverityWarning = 4
+	syslogSeverityNotice  = 5
+
+	// private enterprise number used for the SD-ID of structured data
+	syslogEnterpriseId = 32473
+
+	cefVendor  = "Yunion"
+	cefProduct = "OneCloud"
+)
+
+// SActionRecord is the actionlog to be forwarded
+type SActionRecord struct {
+	Id        int64
+	OpsTime   time.Time
+	StartTime time.Time
+	Service   string
+	ObjType   string
+	ObjId     string
+	ObjName   string
+	Action    string
+	Notes     string
+	Success   bool
+	ProjectId string
+	Project   string
+	UserId    string
+	User      string
+	DomainId  string
+	Domain    string
+	Roles     string
+}
+
+type sFormatOptions struct {
+	appName  string
+	hostname string
+	version  string
+}
+
+// formatRecord formats the record, CEF events are wrapped in syslog messages
+// unless sent over http
+func formatRecord(format string, rec *SActionRecord, opts *sFormatOptions, isHttp bool) ([]byte, error) {
+	switch format {
+	case FORMAT_SYSLOG:
+		return []byte(formatSyslog(rec, opts)), nil
+	case FORMAT_CEF:
+		if isHttp {
+			return []byte(formatCEF(rec, opts)), nil
+		}
+		return []byte(fmt.Sprintf("%s - %s", syslogHeader(rec, "cef", opts), formatCEF(rec, opts))), nil
+	case FORMAT_JSON:
+		return []byte(jsonutils.Marshal(rec).String()), nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+func syslogHeader(rec *SActionRecord, msgId string, opts *sFormatOptions) string {
+	severity := syslogSeverityNotice
+	if !rec.Success {
+		severity = syslogSeverityWarning
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s - %s",
+		syslogFacilityAudit*8+severity,
+		rec.OpsTime.UTC().Format(time.RFC3339Nano),
+		syslogHeaderField(opts.hostname),
+		syslogHeaderField(opts.appName),
+		syslogHeaderField(msgId),
+	)
+}
+
+// syslogHeaderField makes sure a header field is printable ascii without spaces
+func syslogHeaderField(val string) string {
+	if len(val) == 0 {
+		return "-"
+	}
+	buf := bytes.Buffer{}
+	for _, c := range val {
+		if c > 32 && c < 127 {
+			buf.WriteRune(c)
+		} else {
+			buf.WriteByte('_')
+		}
+	}
+	return buf.String()
+}
+
+func escapeSDParam(val string) string {
+	val = strings.Replace(val, `\`, `\\`, -1)
+	val = strings.Replace(val, `"`, `\"`, -1)
+	val = strings.Replace(val, `]`, `\]`, -1)
+	return val
+}
+
+// formatSyslog formats the record as a RFC5424 syslog message, with the attributes
+// of the actionlog in structured data
+func formatSyslog(rec *SActionRecord, opts *sFormatOptions) string {
+	params := [][2]string{
+		{"id", strconv.FormatInt(rec.Id, 10)},
+		{"service", rec.Service},
+		{"obj_type", rec.ObjType},
+		{"obj_id", rec.ObjId},
+		{"obj_name", rec.ObjName},
+		{"success", strconv.FormatBool(rec.Success)},
+		{"user_id", rec.UserId},
+		{"user", rec.User},
+		{"tenant_id", rec.ProjectId},
+		{"tenant", rec.Project},
+		{"domain_id", rec.DomainId},
+		{"domain", rec.Domain},
+		{"roles", rec.Roles},
+	}
+	sd := bytes.Buffer{}
+	sd.WriteString(fmt.Sprintf("[actionlog@%d", syslogEnterpriseId))
+	for _, p := range params {
+		sd.WriteString(fmt.Sprintf(` %s="%s"`, p[0], escapeSDParam(p[1])))
+	}
+	sd.WriteString("]")
+	msg := fmt.Sprintf("%s %s", syslogHeader(rec, rec.Action, opts), sd.String())
+	if len(rec.Notes) > 0 {
+		msg = fmt.Sprintf("%s %s", msg, rec.Notes)
+	}
+	return msg
+}
+
+func escapeCEFHeader(val string) string {
+	val = strings.Replace(val, `\`, `\\`, -1)
+	val = strings.Replace(val, `|`, `\|`, -1)
+	return val
+}
+
+func escapeCEFExtension(val string) string {
+	val = strings.Replace(val, `\`, `\\`, -1)
+	val = strings.Replace(val, `=`, `\=`, -1)
+	val = strings.Replace(val, "\r", `\r`, -1)
+	val = strings.Replace(val, "\n", `\n`, -1)
+	return val
+}
+
+// formatCEF formats the record in ArcSight Common Event Format
+func formatCEF(rec *SActionRecord, opts *sFormatOptions) string {
+	severity := 3
+	outcome := "success"
+	if !rec.Success {
+		severity = 7
+		outcome = "failure"
+	}
+	header := []string{
+		"CEF:0",
+		escapeCEFHeader(cefVendor),
+		escapeCEFHeader(cefProduct),
+		escapeCEFHeader(opts.version),
+		escapeCEFHeader(rec.Action),
+		escapeCEFHeader(fmt.Sprintf("%s %s", rec.Action, rec.ObjType)),
+		strconv.Itoa(severity),
+	}
+	exts := [][2]string{
+		{"rt", strconv.FormatInt(rec.OpsTime.UnixNano()/int64(time.Millisecond), 10)},
+		{"externalId", strconv.FormatInt(rec.Id, 10)},
+		{"act", rec.Action},
+		{"outcome", outcome},
+		{"suid", rec.UserId},
+		{"suser", rec.User},
+		{"cs1Label", "tenant"},
+		{"cs1", rec.Project},
+		{"cs2Label", "service"},
+		{"cs2", rec.Service},
+		{"cs3Label", "obj_type"},
+		{"cs3", rec.ObjType},
+		{"cs4Label", "obj_id"},
+		{"cs4", rec.ObjId},
+		{"duser", rec.ObjName},
+		{"msg", rec.Notes},
+	}
+	ext := make([]string, 0, len(exts))
+	for _, e := range exts {
+		if len(e[1]) > 0 {
+			ext = append(ext, fmt.Sprintf("%s=%s", e[0], escapeCEFExtension(e[1])))
+		}
+	}
+	return fmt.Sprintf("%s|%s", strings.Join(header, "|"), strings.Join(ext, " "))
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwarder
+
+import (
+	"crypto/tls"
+	"os"
+	"time"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+type SForwarderOptions struct {
+	// sink urls in the form of <format>+<transport>://<address>
+	Sinks []string
+	// number of actionlogs fetched and forwarded in a batch
+	BatchSize     int
+	RetryInterval time.Duration
+	TlsInsecure   bool
+	AppName       string
+	Version       string
+}
+
+// SWatermark is the position of the last actionlog processed, actionlogs are
+// ordered by ops_time and then id
+type SWatermark struct {
+	OpsTime time.Time
+	Id      int64
+}
+
+// Before tells whether the watermark is before the other one
+func (mark SWatermark) Before(other SWatermark) bool {
+	return mark.OpsTime.Before(other.OpsTime) || (mark.OpsTime.Equal(other.OpsTime) && mark.Id < other.Id)
+}
+
+// IRecordStore is the persistent store of the actionlogs and the watermarks
+type IRecordStore interface {
+	// FetchRecords returns at most limit actionlogs after the watermark and
+	// before until, in the order of the watermark
+	FetchRecords(since SWatermark, until time.Time, limit int) ([]*SActionRecord, error)
+	// GetWatermark returns nil if the watermark of name is not set yet
+	GetWatermark(name string) (*SWatermark, error)
+	SetWatermark(name string, mark SWatermark) error
+}
+
+var (
+	sinks []*sSink
+)
+
+// Start starts a forwarding worker for each of the sinks, the actionlogs are
+// read from the store after the watermark of the sink, which is persisted
+// after they are sent, so that nothing is lost while a sink is unavailable or
+// the service restarts
+func Start(opts SForwarderOptions, store IRecordStore) error {
+	hostname, _ := os.Hostname()
+	fmtOpts := &sFormatOptions{
+		appName:  opts.AppName,
+		hostname: hostname,
+		version:  opts.Version,
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 10 * time.Second
+	}
+	newSinks := make([]*sSink, 0, len(opts.Sinks))
+	for _, sinkUrl := range opts.Sinks {
+		sink, err := parseSink(sinkUrl)
+		if err != nil {
+			return err
+		}
+		sink.fmtOpts = fmtOpts
+		sink.store = store
+		sink.batchSize = opts.BatchSize
+		sink.retryInterval = opts.RetryInterval
+		sink.tlsConfig = &tls.Config{InsecureSkipVerify: opts.TlsInsecure}
+		sink.client = httputils.GetClient(opts.TlsInsecure)
+		sink.notify = make(chan struct{}, 1)
+		sink.done = make(chan struct{})
+		newSinks = append(newSinks, sink)
+	}
+	for _, sink := range newSinks {
+		log.Infof("forward actionlogs to %s", sink)
+		go sink.run()
+	}
+	sinks = newSinks
+	return nil
+}
+
+// WatermarkNames returns the names of the watermarks of the sinks, the
+// actionlogs after any of them are not forwarded yet
+func WatermarkNames() []string {
+	names := make([]string, 0, len(sinks))
+	for _, sink := range sinks {
+		names = append(names, sink.watermarkName())
+	}
+	return names
+}
+
+// Notify wakes up the sinks to forward the new actionlogs without blocking
+func Notify() {
+	for _, sink := range sinks {
+		sink.wakeup()
+	}
+}
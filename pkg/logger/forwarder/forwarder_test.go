@@ -0,0 +1,256 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwarder
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testRecord() *SActionRecord {
+	return &SActionRecord{
+		Id:      42,
+		OpsTime: time.Date(2019, 5, 1, 8, 0, 0, 0, time.UTC),
+		Service: "compute",
+		ObjType: "server",
+		ObjId:   "b1c2",
+		ObjName: `vm "1"`,
+		Action:  "stop",
+		Notes:   "stop vm=1|ok",
+		Success: false,
+		User:    "alice",
+		Project: "demo",
+	}
+}
+
+func TestFormat(t *testing.T) {
+	opts := &sFormatOptions{appName: "onecloud", hostname: "logger 1", version: "v2.10"}
+	rec := testRecord()
+
+	msg := formatSyslog(rec, opts)
+	wantPrefix := `<108>1 2019-05-01T08:00:00Z logger_1 onecloud - stop [actionlog@32473 id="42" service="compute" obj_type="server" obj_id="b1c2" obj_name="vm \"1\"" success="false"`
+	if !strings.HasPrefix(msg, wantPrefix) {
+		t.Errorf("syslog got %s", msg)
+	}
+	if !strings.HasSuffix(msg, "] stop vm=1|ok") {
+		t.Errorf("syslog msg got %s", msg)
+	}
+
+	cef := formatCEF(rec, opts)
+	wantCEF := `CEF:0|Yunion|OneCloud|v2.10|stop|stop server|7|rt=1556697600000 externalId=42 act=stop outcome=failure suser=alice cs1Label=tenant cs1=demo cs2Label=service cs2=compute cs3Label=obj_type cs3=server cs4Label=obj_id cs4=b1c2 duser=vm "1" msg=stop vm\=1|ok`
+	if cef != wantCEF {
+		t.Errorf("cef want %s got %s", wantCEF, cef)
+	}
+}
+
+func TestParseSink(t *testing.T) {
+	cases := []struct {
+		in      string
+		address string
+		wantErr bool
+	}{
+		{"syslog+tcp://10.0.0.1:514", "10.0.0.1:514", false},
+		{"cef+udp://[::1]:514", "[::1]:514", false},
+		{"json+https://siem.example.com/ingest", "https://siem.example.com/ingest", false},
+		{"syslog+tcp://10.0.0.1", "", true},
+		{"xml+tcp://10.0.0.1:514", "", true},
+		{"syslog://10.0.0.1:514", "", true},
+	}
+	for _, c := range cases {
+		sink, err := parseSink(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expect error", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.in, err)
+		} else if sink.address != c.address {
+			t.Errorf("%s: want address %s got %s", c.in, c.address, sink.address)
+		}
+	}
+}
+
+// fakeStore keeps the actionlogs and the watermarks in memory
+type fakeStore struct {
+	lock       sync.Mutex
+	records    []*SActionRecord
+	watermarks map[string]SWatermark
+}
+
+func newFakeStore(records ...*SActionRecord) *fakeStore {
+	return &fakeStore{records: records, watermarks: map[string]SWatermark{}}
+}
+
+func (store *fakeStore) FetchRecords(since SWatermark, until time.Time, limit int) ([]*SActionRecord, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	ret := make([]*SActionRecord, 0)
+	for _, rec := range store.records {
+		after := rec.OpsTime.After(since.OpsTime) || (rec.OpsTime.Equal(since.OpsTime) && rec.Id > since.Id)
+		if after && rec.OpsTime.Before(until) && len(ret) < limit {
+			ret = append(ret, rec)
+		}
+	}
+	return ret, nil
+}
+
+func (store *fakeStore) GetWatermark(name string) (*SWatermark, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	mark, ok := store.watermarks[name]
+	if !ok {
+		return nil, nil
+	}
+	return &mark, nil
+}
+
+func (store *fakeStore) SetWatermark(name string, mark SWatermark) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	store.watermarks[name] = mark
+	return nil
+}
+
+func newTestSink(t *testing.T, sinkUrl string, store IRecordStore) *sSink {
+	sink, err := parseSink(sinkUrl)
+	if err != nil {
+		t.Fatalf("parseSink %s", err)
+	}
+	sink.fmtOpts = &sFormatOptions{}
+	sink.store = store
+	sink.batchSize = 2
+	sink.retryInterval = 50 * time.Millisecond
+	sink.tlsConfig = &tls.Config{}
+	sink.notify = make(chan struct{}, 1)
+	sink.done = make(chan struct{})
+	return sink
+}
+
+func TestSinkRetry(t *testing.T) {
+	// reserve a port, then release it so that the sink fails at first
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen %s", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	rec := testRecord()
+	rec.OpsTime = time.Now().UTC().Add(-time.Minute)
+	store := newFakeStore(rec)
+	sink := newTestSink(t, "json+tcp://"+addr, store)
+	store.SetWatermark(sink.watermarkName(), SWatermark{OpsTime: rec.OpsTime.Add(-time.Second)})
+	go sink.run()
+	defer sink.stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("port %s taken: %s", addr, err)
+	}
+	defer listener.Close()
+	listener.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("sink not retried: %s", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read %s", err)
+	}
+	if !strings.Contains(line, `"action":"stop"`) {
+		t.Errorf("unexpected line %s", line)
+	}
+}
+
+func TestSinkWatermark(t *testing.T) {
+	var lock sync.Mutex
+	received := make([]string, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		received = append(received, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+	}))
+	defer srv.Close()
+
+	base := time.Now().UTC().Add(-time.Hour)
+	records := make([]*SActionRecord, 0)
+	for i := 1; i <= 5; i++ {
+		rec := testRecord()
+		rec.Id = int64(i)
+		// actionlogs of the same time are ordered by id
+		rec.OpsTime = base.Add(time.Duration(i/2) * time.Second)
+		records = append(records, rec)
+	}
+	// the actionlog being inserted is not forwarded until settled
+	unsettled := testRecord()
+	unsettled.Id = 6
+	unsettled.OpsTime = time.Now().UTC()
+	store := newFakeStore(append(records, unsettled)...)
+
+	sink := newTestSink(t, "json+"+srv.URL, store)
+	// a new sink starts from now on
+	err := sink.forward()
+	if err != nil {
+		t.Fatalf("forward %s", err)
+	}
+	if len(received) != 0 {
+		t.Fatalf("history forwarded to new sink: %v", received)
+	}
+
+	// forward from the persisted watermark, e.g. after restart
+	store.SetWatermark(sink.watermarkName(), SWatermark{OpsTime: records[1].OpsTime, Id: records[1].Id})
+	sink = newTestSink(t, "json+"+srv.URL, store)
+	err = sink.forward()
+	if err != nil {
+		t.Fatalf("forward %s", err)
+	}
+	if len(received) != 3 {
+		t.Fatalf("want 3 actionlogs forwarded, got %v", received)
+	}
+	for i, line := range received {
+		if !strings.Contains(line, fmt.Sprintf(`"id":%d`, i+3)) {
+			t.Errorf("unexpected actionlog %s", line)
+		}
+	}
+	mark, _ := store.GetWatermark(sink.watermarkName())
+	if mark == nil || mark.Id != 5 || !mark.OpsTime.Equal(records[4].OpsTime) {
+		t.Errorf("unexpected watermark %#v", mark)
+	}
+
+	// nothing is forwarded twice
+	err = sink.forward()
+	if err != nil {
+		t.Fatalf("forward %s", err)
+	}
+	if len(received) != 3 {
+		t.Errorf("actionlogs forwarded twice: %v", received)
+	}
+}
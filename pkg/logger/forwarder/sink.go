@@ -0,0 +1,304 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwarder
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	TRANSPORT_TCP   = "tcp"
+	TRANSPORT_UDP   = "udp"
+	TRANSPORT_TLS   = "tls"
+	TRANSPORT_HTTP  = "http"
+	TRANSPORT_HTTPS = "https"
+
+	sinkDialTimeout  = 10 * time.Second
+	sinkWriteTimeout = 10 * time.Second
+
+	// actionlogs younger than the delay are not forwarded yet, so that the
+	// ones being inserted are not skipped over by the watermark
+	sinkSettleDelay = 5 * time.Second
+
+	sinkWatermarkPrefix = "forward:"
+)
+
+type sSink struct {
+	format    string
+	transport string
+	address   string
+
+	fmtOpts       *sFormatOptions
+	store         IRecordStore
+	batchSize     int
+	retryInterval time.Duration
+	tlsConfig     *tls.Config
+	client        *http.Client
+
+	notify    chan struct{}
+	done      chan struct{}
+	conn      net.Conn
+	watermark *SWatermark
+}
+
+// parseSink parses the sink url in the form of <format>+<transport>://<address>,
+// e.g. syslog+tcp://10.0.0.1:514 or json+https://siem.example.com/ingest
+func parseSink(sinkUrl string) (*sSink, error) {
+	pos := strings.Index(sinkUrl, "://")
+	if pos <= 0 {
+		return nil, fmt.Errorf("invalid sink %s", sinkUrl)
+	}
+	scheme := sinkUrl[:pos]
+	parts := strings.SplitN(scheme, "+", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid sink scheme %s, should be <format>+<transport>", scheme)
+	}
+	sink := &sSink{
+		format:    parts[0],
+		transport: parts[1],
+	}
+	switch sink.format {
+	case FORMAT_SYSLOG, FORMAT_JSON, FORMAT_CEF:
+	default:
+		return nil, fmt.Errorf("unsupported sink format %s", sink.format)
+	}
+	switch sink.transport {
+	case TRANSPORT_TCP, TRANSPORT_UDP, TRANSPORT_TLS:
+		u, err := url.Parse(fmt.Sprintf("%s://%s", sink.transport, sinkUrl[pos+3:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sink address %s: %s", sinkUrl, err)
+		}
+		if len(u.Port()) == 0 {
+			return nil, fmt.Errorf("missing port of sink %s", sinkUrl)
+		}
+		sink.address = u.Host
+	case TRANSPORT_HTTP, TRANSPORT_HTTPS:
+		sink.address = fmt.Sprintf("%s://%s", sink.transport, sinkUrl[pos+3:])
+	default:
+		return nil, fmt.Errorf("unsupported sink transport %s", sink.transport)
+	}
+	return sink, nil
+}
+
+func (sink *sSink) String() string {
+	return fmt.Sprintf("%s+%s://%s", sink.format, sink.transport, strings.TrimPrefix(strings.TrimPrefix(sink.address, "http://"), "https://"))
+}
+
+func (sink *sSink) isHttp() bool {
+	return sink.transport == TRANSPORT_HTTP || sink.transport == TRANSPORT_HTTPS
+}
+
+func (sink *sSink) watermarkName() string {
+	return sinkWatermarkPrefix + sink.String()
+}
+
+func (sink *sSink) wakeup() {
+	select {
+	case sink.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (sink *sSink) stop() {
+	close(sink.done)
+}
+
+func (sink *sSink) run() {
+	for {
+		err := sink.forward()
+		if err != nil {
+			log.Errorf("forward actionlogs to sink %s fail %s, retry after %s", sink, err, sink.retryInterval)
+			sink.close()
+		}
+		// poll the store as well for the actionlogs not notified, e.g.
+		// those settling when notified
+		select {
+		case <-sink.done:
+			sink.close()
+			return
+		case <-sink.notify:
+			if err != nil {
+				time.Sleep(sink.retryInterval)
+			}
+		case <-time.After(sink.retryInterval):
+		}
+	}
+}
+
+func (sink *sSink) loadWatermark() error {
+	if sink.watermark != nil {
+		return nil
+	}
+	mark, err := sink.store.GetWatermark(sink.watermarkName())
+	if err != nil {
+		return err
+	}
+	if mark == nil {
+		// a new sink starts from now on rather than the whole history
+		mark = &SWatermark{OpsTime: time.Now().UTC().Add(-sinkSettleDelay)}
+		err = sink.store.SetWatermark(sink.watermarkName(), *mark)
+		if err != nil {
+			return err
+		}
+	}
+	sink.watermark = mark
+	return nil
+}
+
+// forward sends the settled actionlogs after the watermark, the watermark is
+// persisted after each batch, so that an actionlog is forwarded at least once
+func (sink *sSink) forward() error {
+	err := sink.loadWatermark()
+	if err != nil {
+		return err
+	}
+	for {
+		until := time.Now().UTC().Add(-sinkSettleDelay)
+		records, err := sink.store.FetchRecords(*sink.watermark, until, sink.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		sent, err := sink.sendRecords(records)
+		if sent > 0 {
+			last := records[sent-1]
+			mark := SWatermark{OpsTime: last.OpsTime, Id: last.Id}
+			if e := sink.store.SetWatermark(sink.watermarkName(), mark); e != nil {
+				return e
+			}
+			sink.watermark = &mark
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sendRecords returns the number of the leading records sent, the records are
+// posted in one batch to http sinks, and written one by one to the others
+func (sink *sSink) sendRecords(records []*SActionRecord) (int, error) {
+	if sink.isHttp() {
+		err := sink.send(sink.format2Bytes(records))
+		if err != nil {
+			return 0, err
+		}
+		return len(records), nil
+	}
+	for i := range records {
+		err := sink.send(sink.format2Bytes(records[i : i+1]))
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// format2Bytes frames the formatted records, the records failed to format
+// are skipped as they would never succeed
+func (sink *sSink) format2Bytes(records []*SActionRecord) []byte {
+	buf := bytes.Buffer{}
+	for _, rec := range records {
+		msg, err := formatRecord(sink.format, rec, sink.fmtOpts, sink.isHttp())
+		if err != nil {
+			log.Errorf("format actionlog %d for sink %s fail %s", rec.Id, sink, err)
+			continue
+		}
+		switch {
+		case sink.isHttp():
+			buf.Write(msg)
+			buf.WriteByte('\n')
+		case sink.transport == TRANSPORT_UDP:
+			buf.Write(msg)
+		case sink.format == FORMAT_JSON:
+			// json lines over stream
+			buf.Write(msg)
+			buf.WriteByte('\n')
+		default:
+			// octet counting framing, RFC6587 section 3.4.1
+			buf.WriteString(fmt.Sprintf("%d ", len(msg)))
+			buf.Write(msg)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (sink *sSink) send(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if sink.isHttp() {
+		return sink.post(data)
+	}
+	if sink.conn == nil {
+		conn, err := sink.dial()
+		if err != nil {
+			return err
+		}
+		sink.conn = conn
+	}
+	sink.conn.SetWriteDeadline(time.Now().Add(sinkWriteTimeout))
+	_, err := sink.conn.Write(data)
+	return err
+}
+
+func (sink *sSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: sinkDialTimeout}
+	switch sink.transport {
+	case TRANSPORT_TLS:
+		return tls.DialWithDialer(dialer, "tcp", sink.address, sink.tlsConfig)
+	default:
+		return dialer.Dial(sink.transport, sink.address)
+	}
+}
+
+func (sink *sSink) close() {
+	if sink.conn != nil {
+		sink.conn.Close()
+		sink.conn = nil
+	}
+}
+
+func (sink *sSink) post(data []byte) error {
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-ndjson")
+	ctx, cancel := context.WithTimeout(context.Background(), sinkWriteTimeout)
+	defer cancel()
+	resp, err := httputils.Request(sink.client, ctx, httputils.POST, sink.address, header, bytes.NewReader(data), false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
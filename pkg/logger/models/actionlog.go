@@ -22,6 +22,7 @@ import (
 	"yunion.io/x/log"
 
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/logger/forwarder"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
@@ -69,8 +70,31 @@ func (action *SActionlog) CustomizeCreate(ctx context.Context, userCred mcclient
 	return nil
 }
 
+func (action *SActionlog) toForwardRecord() *forwarder.SActionRecord {
+	return &forwarder.SActionRecord{
+		Id:        action.Id,
+		OpsTime:   action.OpsTime,
+		StartTime: action.StartTime,
+		Service:   action.Service,
+		ObjType:   action.ObjType,
+		ObjId:     action.ObjId,
+		ObjName:   action.ObjName,
+		Action:    action.Action,
+		Notes:     action.Notes,
+		Success:   action.Success,
+		ProjectId: action.ProjectId,
+		Project:   action.Project,
+		UserId:    action.UserId,
+		User:      action.User,
+		DomainId:  action.DomainId,
+		Domain:    action.Domain,
+		Roles:     action.Roles,
+	}
+}
+
 func (manager *SActionlogManager) OnCreateComplete(ctx context.Context, items []db.IModel, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	actionLog := items[0].(*SActionlog)
+	forwarder.Notify()
 	if IsInActionWhiteList(actionLog.Action) {
 		select {
 		case logQueue <- actionLog:
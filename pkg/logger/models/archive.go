@@ -0,0 +1,233 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/logger/forwarder"
+	"yunion.io/x/onecloud/pkg/logger/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+const (
+	archiveBatchSize = 10000
+
+	archiveWatermarkName = "archive"
+)
+
+func (manager *SActionlogManager) rangeQuery(since, until time.Time) *sqlchemy.SQuery {
+	q := manager.Query()
+	if !since.IsZero() {
+		q = q.GE("ops_time", since)
+	}
+	if !until.IsZero() {
+		q = q.LT("ops_time", until)
+	}
+	return q.Asc("id")
+}
+
+// Export writes the actionlogs within [since, until) to w as json lines, a zero time
+// means no limit at that end
+func (manager *SActionlogManager) Export(w io.Writer, since, until time.Time) (int, error) {
+	q := manager.rangeQuery(since, until)
+	rows, err := q.Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		action := SActionlog{}
+		err = q.Row2Struct(rows, &action)
+		if err != nil {
+			return count, err
+		}
+		_, err = fmt.Fprintln(w, jsonutils.Marshal(&action).String())
+		if err != nil {
+			return count, err
+		}
+		count += 1
+	}
+	return count, nil
+}
+
+// archiveActionlogs writes the batch of actionlogs to its own file named by
+// the first actionlog, the file is replaced atomically, so that archiving the
+// batch again after a failure leaves no duplicates
+func archiveActionlogs(archiveDir string, actions []SActionlog) error {
+	err := os.MkdirAll(archiveDir, 0755)
+	if err != nil {
+		return err
+	}
+	first := actions[0]
+	fn := filepath.Join(archiveDir, fmt.Sprintf("actionlog-%s-%d.json.gz", first.OpsTime.UTC().Format("20060102"), first.Id))
+	tmpFn := fn + ".tmp"
+	f, err := os.Create(tmpFn)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFn)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	bw := bufio.NewWriter(gz)
+	for i := range actions {
+		_, err = fmt.Fprintln(bw, jsonutils.Marshal(&actions[i]).String())
+		if err != nil {
+			return err
+		}
+	}
+	err = bw.Flush()
+	if err != nil {
+		return err
+	}
+	err = gz.Close()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFn, fn)
+}
+
+// forwardWatermark returns the earliest watermark of the forwarding sinks,
+// the actionlogs after it are kept until every sink has forwarded them
+func (manager *SActionlogManager) forwardWatermark() (*forwarder.SWatermark, error) {
+	var ret *forwarder.SWatermark
+	for _, name := range forwarder.WatermarkNames() {
+		mark, err := manager.GetWatermark(name)
+		if err != nil {
+			return nil, err
+		}
+		// a new sink starts from the time it is added
+		if mark == nil {
+			continue
+		}
+		if ret == nil || mark.Before(*ret) {
+			ret = mark
+		}
+	}
+	return ret, nil
+}
+
+// capExpiredActionlogs drops the actionlogs after the limit, it tells
+// whether any is dropped
+func capExpiredActionlogs(actions []SActionlog, limit *forwarder.SWatermark) ([]SActionlog, bool) {
+	if limit == nil {
+		return actions, false
+	}
+	for i := range actions {
+		if limit.Before(forwarder.SWatermark{OpsTime: actions[i].OpsTime, Id: actions[i].Id}) {
+			return actions[:i], true
+		}
+	}
+	return actions, false
+}
+
+// CleanExpiredActionlogs deletes the actionlogs older than ActionlogRetentionDays,
+// they are archived to ActionlogArchiveDir before deletion if specified. The
+// actionlogs not forwarded by all the sinks yet are kept. The archive
+// watermark is persisted after each batch, the actionlogs before it are left
+// only if the deletion failed and are deleted on the next run
+func (manager *SActionlogManager) CleanExpiredActionlogs(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	if options.Options.ActionlogRetentionDays <= 0 {
+		return
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -options.Options.ActionlogRetentionDays)
+	limit, err := manager.forwardWatermark()
+	if err != nil {
+		log.Errorf("fetch forward watermarks fail %s", err)
+		return
+	}
+	mark, err := manager.GetWatermark(archiveWatermarkName)
+	if err != nil {
+		log.Errorf("fetch archive watermark fail %s", err)
+		return
+	}
+	if mark != nil {
+		err = manager.deleteBefore(*mark)
+		if err != nil {
+			log.Errorf("delete archived actionlogs fail %s", err)
+			return
+		}
+	} else {
+		mark = &forwarder.SWatermark{}
+	}
+	total := 0
+	for {
+		actions := make([]SActionlog, 0)
+		q := manager.afterWatermark(*mark, cutoff).Limit(archiveBatchSize)
+		err := db.FetchModelObjects(manager, q, &actions)
+		if err != nil {
+			log.Errorf("fetch expired actionlogs fail %s", err)
+			return
+		}
+		batchSize := len(actions)
+		actions, capped := capExpiredActionlogs(actions, limit)
+		if capped {
+			log.Warningf("actionlogs after %s are not forwarded yet, keep them", limit.OpsTime)
+		}
+		if len(actions) == 0 {
+			break
+		}
+		if len(options.Options.ActionlogArchiveDir) > 0 {
+			err = archiveActionlogs(options.Options.ActionlogArchiveDir, actions)
+			if err != nil {
+				log.Errorf("archive actionlogs fail %s", err)
+				return
+			}
+		}
+		last := actions[len(actions)-1]
+		mark = &forwarder.SWatermark{OpsTime: last.OpsTime, Id: last.Id}
+		err = manager.SetWatermark(archiveWatermarkName, *mark)
+		if err != nil {
+			log.Errorf("save archive watermark fail %s", err)
+			return
+		}
+		err = manager.deleteBefore(*mark)
+		if err != nil {
+			log.Errorf("delete expired actionlogs fail %s", err)
+			return
+		}
+		total += len(actions)
+		if capped || batchSize < archiveBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		log.Infof("%d actionlogs before %s cleaned", total, cutoff)
+	}
+}
+
+// deleteBefore deletes the actionlogs up to the watermark
+func (manager *SActionlogManager) deleteBefore(mark forwarder.SWatermark) error {
+	sql := fmt.Sprintf("DELETE FROM `%s` WHERE `ops_time` < ? OR (`ops_time` = ? AND `id` <= ?)", manager.TableSpec().Name())
+	_, err := sqlchemy.GetDB().Exec(sql, mark.OpsTime, mark.OpsTime, mark.Id)
+	return err
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/logger/forwarder"
+)
+
+func TestArchiveActionlogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "actionlog-archive")
+	if err != nil {
+		t.Fatalf("TempDir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	opsTime := time.Date(2019, 5, 1, 8, 0, 0, 0, time.UTC)
+	actions := make([]SActionlog, 3)
+	for i := range actions {
+		actions[i].Id = int64(i + 10)
+		actions[i].OpsTime = opsTime
+		actions[i].Action = "stop"
+	}
+	// archiving the batch again, e.g. after the deletion failed, replaces
+	// the file rather than appending duplicates
+	for _, batch := range [][]SActionlog{actions[:2], actions} {
+		err = archiveActionlogs(dir, batch)
+		if err != nil {
+			t.Fatalf("archiveActionlogs %s", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != "actionlog-20190501-10.json.gz" {
+		t.Fatalf("unexpected archive files %v", files)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("open %s", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip %s", err)
+	}
+	lines := 0
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines += 1
+	}
+	if lines != len(actions) {
+		t.Errorf("want %d archived actionlogs, got %d", len(actions), lines)
+	}
+}
+
+func TestCapExpiredActionlogs(t *testing.T) {
+	opsTime := time.Date(2019, 5, 1, 8, 0, 0, 0, time.UTC)
+	actions := make([]SActionlog, 4)
+	for i := range actions {
+		actions[i].Id = int64(i + 10)
+		actions[i].OpsTime = opsTime
+	}
+	actions[3].OpsTime = opsTime.Add(time.Second)
+	cases := []struct {
+		limit  *forwarder.SWatermark
+		want   int
+		capped bool
+	}{
+		{nil, 4, false},
+		{&forwarder.SWatermark{OpsTime: opsTime.Add(time.Hour)}, 4, false},
+		{&forwarder.SWatermark{OpsTime: opsTime, Id: 11}, 2, true},
+		{&forwarder.SWatermark{OpsTime: opsTime.Add(-time.Second)}, 0, true},
+	}
+	for _, c := range cases {
+		got, capped := capExpiredActionlogs(actions, c.limit)
+		if len(got) != c.want || capped != c.capped {
+			t.Errorf("limit %v: want %d %v, got %d %v", c.limit, c.want, c.capped, len(got), capped)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/logger/forwarder"
+)
+
+// SActionlogWatermarkManager persists the position of the last actionlog
+// processed by the forwarding sinks and the archiving
+type SActionlogWatermarkManager struct {
+	db.SModelBaseManager
+}
+
+type SActionlogWatermark struct {
+	db.SModelBase
+
+	Name      string    `width:"256" charset:"ascii" primary:"true"`
+	OpsTime   time.Time `nullable:"false"`
+	LogId     int64     `nullable:"false" default:"0"`
+	UpdatedAt time.Time `nullable:"false" updated_at:"true"`
+}
+
+var ActionlogWatermarkManager *SActionlogWatermarkManager
+
+func init() {
+	ActionlogWatermarkManager = &SActionlogWatermarkManager{db.NewModelBaseManager(SActionlogWatermark{}, "actionlog_watermark_tbl", "actionlog_watermark", "actionlog_watermarks")}
+}
+
+func (manager *SActionlogWatermarkManager) fetchWatermark(name string) (*SActionlogWatermark, error) {
+	mark := SActionlogWatermark{}
+	err := manager.Query().Equals("name", name).First(&mark)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mark, nil
+}
+
+func (manager *SActionlogWatermarkManager) GetWatermark(name string) (*forwarder.SWatermark, error) {
+	mark, err := manager.fetchWatermark(name)
+	if err != nil || mark == nil {
+		return nil, err
+	}
+	return &forwarder.SWatermark{OpsTime: mark.OpsTime, Id: mark.LogId}, nil
+}
+
+func (manager *SActionlogWatermarkManager) SetWatermark(name string, wm forwarder.SWatermark) error {
+	mark, err := manager.fetchWatermark(name)
+	if err != nil {
+		return err
+	}
+	if mark == nil {
+		mark = &SActionlogWatermark{Name: name, OpsTime: wm.OpsTime, LogId: wm.Id}
+		return manager.TableSpec().Insert(mark)
+	}
+	_, err = manager.TableSpec().Update(mark, func() error {
+		mark.OpsTime = wm.OpsTime
+		mark.LogId = wm.Id
+		return nil
+	})
+	return err
+}
+
+// afterWatermark filters the actionlogs after the watermark and before until
+func (manager *SActionlogManager) afterWatermark(since forwarder.SWatermark, until time.Time) *sqlchemy.SQuery {
+	q := manager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.GT(q.Field("ops_time"), since.OpsTime),
+		sqlchemy.AND(
+			sqlchemy.Equals(q.Field("ops_time"), since.OpsTime),
+			sqlchemy.GT(q.Field("id"), since.Id),
+		),
+	))
+	if !until.IsZero() {
+		q = q.LT("ops_time", until)
+	}
+	return q.Asc("ops_time").Asc("id")
+}
+
+// FetchRecords implements forwarder.IRecordStore
+func (manager *SActionlogManager) FetchRecords(since forwarder.SWatermark, until time.Time, limit int) ([]*forwarder.SActionRecord, error) {
+	actions := make([]SActionlog, 0)
+	q := manager.afterWatermark(since, until).Limit(limit)
+	err := db.FetchModelObjects(manager, q, &actions)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*forwarder.SActionRecord, len(actions))
+	for i := range actions {
+		records[i] = actions[i].toForwardRecord()
+	}
+	return records, nil
+}
+
+func (manager *SActionlogManager) GetWatermark(name string) (*forwarder.SWatermark, error) {
+	return ActionlogWatermarkManager.GetWatermark(name)
+}
+
+func (manager *SActionlogManager) SetWatermark(name string, mark forwarder.SWatermark) error {
+	return ActionlogWatermarkManager.SetWatermark(name, mark)
+}
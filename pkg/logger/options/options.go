@@ -22,6 +22,15 @@ type SLoggerOptions struct {
 	common_options.CommonOptions
 
 	common_options.DBOptions
+
+	ActionlogForwardSinks        []string `help:"Forward actionlogs to sinks, in the form of <syslog|json|cef>+<tcp|udp|tls|http|https>://<address>, e.g. syslog+tls://10.0.0.1:6514"`
+	ActionlogForwardBatchSize    int      `help:"Number of actionlogs forwarded to each sink in a batch" default:"100"`
+	ActionlogForwardRetrySeconds int      `help:"Interval in seconds to retry an unavailable sink" default:"10"`
+	ActionlogForwardTlsInsecure  bool     `help:"Skip verifying the certificate of tls/https sinks"`
+	ActionlogForwardAppName      string   `help:"APP-NAME of forwarded syslog messages" default:"onecloud"`
+
+	ActionlogRetentionDays int    `help:"Delete actionlogs older than the specified days, 0 to keep forever" default:"0"`
+	ActionlogArchiveDir    string `help:"Directory to archive actionlogs before they are deleted for retention"`
 }
 
 var (
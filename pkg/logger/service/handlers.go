@@ -15,10 +15,19 @@
 package service
 
 import (
+	"context"
+	"net/http"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
 	"yunion.io/x/onecloud/pkg/appsrv"
 	"yunion.io/x/onecloud/pkg/appsrv/dispatcher"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/logger/models"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
 )
 
 func initHandlers(app *appsrv.Application) {
@@ -27,10 +36,13 @@ func initHandlers(app *appsrv.Application) {
 	for _, manager := range []db.IModelManager{
 		// db.UserCacheManager,
 		db.TenantCacheManager,
+		models.ActionlogWatermarkManager,
 	} {
 		db.RegisterModelManager(manager)
 	}
 
+	app.AddHandler2("GET", "/actions/export", auth.Authenticate(exportActionlogHandler), nil, "export_actionlogs", nil)
+
 	for _, manager := range []db.IModelManager{
 		models.ActonLog,
 	} {
@@ -39,3 +51,30 @@ func initHandlers(app *appsrv.Application) {
 		dispatcher.AddModelDispatcher("", app, handler)
 	}
 }
+
+// exportActionlogHandler streams the actionlogs within [since, until) as json lines
+func exportActionlogHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+	if !db.IsAdminAllowList(userCred, models.ActonLog) {
+		httperrors.ForbiddenError(w, "not allow to export actionlogs")
+		return
+	}
+	query, err := jsonutils.ParseQueryString(r.URL.RawQuery)
+	if err != nil {
+		httperrors.InputParameterError(w, "invalid query string: %s", err)
+		return
+	}
+	since, _ := query.GetTime("since")
+	until, _ := query.GetTime("until")
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		httperrors.InputParameterError(w, "since should be before until")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	count, err := models.ActonLog.Export(w, since, until)
+	if err != nil {
+		log.Errorf("export actionlogs fail after %d records: %s", count, err)
+		return
+	}
+	log.Infof("%s exported %d actionlogs", userCred.GetUserName(), count)
+}
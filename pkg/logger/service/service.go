@@ -16,16 +16,20 @@ package service
 
 import (
 	"os"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/version"
 
 	"yunion.io/x/onecloud/pkg/cloudcommon"
 	app_common "yunion.io/x/onecloud/pkg/cloudcommon/app"
 	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
+	"yunion.io/x/onecloud/pkg/cloudcommon/cronman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
+	"yunion.io/x/onecloud/pkg/logger/forwarder"
 	"yunion.io/x/onecloud/pkg/logger/models"
 	"yunion.io/x/onecloud/pkg/logger/options"
 )
@@ -60,5 +64,23 @@ func StartService() {
 		log.Fatalf("database schema not in sync!")
 	}
 
+	// the watermarks of the sinks are persisted in the database
+	err := forwarder.Start(forwarder.SForwarderOptions{
+		Sinks:         opts.ActionlogForwardSinks,
+		BatchSize:     opts.ActionlogForwardBatchSize,
+		RetryInterval: time.Duration(opts.ActionlogForwardRetrySeconds) * time.Second,
+		TlsInsecure:   opts.ActionlogForwardTlsInsecure,
+		AppName:       opts.ActionlogForwardAppName,
+		Version:       version.GetShortString(),
+	}, models.ActonLog)
+	if err != nil {
+		log.Fatalf("start actionlog forwarder fail: %s", err)
+	}
+
+	cron := cronman.GetCronJobManager(true)
+	cron.AddJob2("CleanExpiredActionlogs", 1, 3, 0, 0, models.ActonLog.CleanExpiredActionlogs, false)
+	cron.Start()
+	defer cron.Stop()
+
 	app_common.ServeForever(app, commonOpts)
 }
@@ -14,6 +14,15 @@
 
 package modules
 
+import (
+	"fmt"
+	"io"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
 var (
 	Actions ResourceManager
 )
@@ -24,3 +33,20 @@ func init() {
 		[]string{})
 	register(&Actions)
 }
+
+// ExportActions streams the actionlogs within the time range as json lines
+func ExportActions(s *mcclient.ClientSession, params jsonutils.JSONObject) (io.ReadCloser, error) {
+	path := fmt.Sprintf("/%s/export", Actions.URLPath())
+	if params != nil {
+		qs := params.QueryString()
+		if len(qs) > 0 {
+			path = fmt.Sprintf("%s?%s", path, qs)
+		}
+	}
+	resp, err := Actions.rawRequest(s, "GET", path, nil, nil)
+	if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.Body, nil
+	}
+	_, _, err = s.ParseJSONResponse(resp, err)
+	return nil, err
+}
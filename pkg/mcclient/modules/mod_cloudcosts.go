@@ -0,0 +1,56 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+var (
+	Cloudcosts ResourceManager
+	CostRates  ResourceManager
+)
+
+func init() {
+	Cloudcosts = NewComputeManager(
+		"cloudcost",
+		"cloudcosts",
+		[]string{
+			"id",
+			"usage_date",
+			"source",
+			"manager_id",
+			"resource_type",
+			"resource_id",
+			"external_id",
+			"tenant",
+			"product",
+			"amount",
+			"currency",
+		},
+		[]string{},
+	)
+	CostRates = NewComputeManager(
+		"costrate",
+		"costrates",
+		[]string{
+			"id",
+			"name",
+			"resource_type",
+			"sku",
+			"price",
+			"currency",
+		},
+		[]string{},
+	)
+	registerCompute(&Cloudcosts)
+	registerCompute(&CostRates)
+}
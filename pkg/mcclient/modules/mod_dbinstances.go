@@ -0,0 +1,48 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+var (
+	DBInstances ResourceManager
+)
+
+func init() {
+	DBInstances = NewComputeManager(
+		"dbinstance",
+		"dbinstances",
+		[]string{
+			"id",
+			"name",
+			"status",
+			"engine",
+			"engine_version",
+			"instance_type",
+			"vcpu_count",
+			"vmem_size_mb",
+			"storage_type",
+			"storage_size_gb",
+			"internal_connection_str",
+			"connection_str",
+			"port",
+			"billing_type",
+			"expired_at",
+			"region",
+			"zone",
+			"provider",
+		},
+		[]string{"tenant"},
+	)
+	registerCompute(&DBInstances)
+}
@@ -16,12 +16,18 @@ package modules
 
 var (
 	DNSRecords ResourceManager
+	DnsZones   ResourceManager
 )
 
 func init() {
 	DNSRecords = NewComputeManager("dnsrecord", "dnsrecords",
-		[]string{"ID", "Name", "Records", "TTL", "is_public"},
+		[]string{"ID", "Name", "Records", "TTL", "is_public", "status", "dns_zone_id"},
 		[]string{})
 
+	DnsZones = NewComputeManager("dnszone", "dnszones",
+		[]string{"id", "name", "status", "zone_type", "record_count", "provider"},
+		[]string{"tenant"})
+
 	registerCompute(&DNSRecords)
+	registerCompute(&DnsZones)
 }
@@ -22,7 +22,7 @@ func init() {
 	IsolatedDevices = NewComputeManager("isolated_device", "isolated_devices",
 		[]string{"ID", "Dev_type",
 			"Model", "Addr", "Vendor_device_id",
-			"Host_id", "Host",
+			"Host_id", "Host", "Wire_id",
 			"Guest_id", "Guest", "Guest_status"},
 		[]string{})
 	registerCompute(&IsolatedDevices)
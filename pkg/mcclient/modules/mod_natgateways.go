@@ -0,0 +1,80 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+var (
+	NatGateways NatGatewayManager
+	NatSTables  ResourceManager
+	NatDTables  ResourceManager
+)
+
+type NatGatewayManager struct {
+	ResourceManager
+}
+
+func init() {
+	NatGateways = NatGatewayManager{
+		NewComputeManager(
+			"natgateway",
+			"natgateways",
+			[]string{
+				"id",
+				"name",
+				"status",
+				"nat_spec",
+				"vpc",
+				"vpc_id",
+				"cloudregion_id",
+				"snat_entry_count",
+				"dnat_entry_count",
+				"provider",
+			},
+			[]string{"tenant"},
+		),
+	}
+	NatSTables = NewComputeManager(
+		"natsentry",
+		"natsentries",
+		[]string{
+			"id",
+			"name",
+			"status",
+			"natgateway",
+			"ip",
+			"source_cidr",
+			"network",
+		},
+		[]string{"tenant"},
+	)
+	NatDTables = NewComputeManager(
+		"natdentry",
+		"natdentries",
+		[]string{
+			"id",
+			"name",
+			"status",
+			"natgateway",
+			"ip_protocol",
+			"external_ip",
+			"external_port",
+			"internal_ip",
+			"internal_port",
+		},
+		[]string{"tenant"},
+	)
+	registerCompute(&NatGateways)
+	registerCompute(&NatSTables)
+	registerCompute(&NatDTables)
+}
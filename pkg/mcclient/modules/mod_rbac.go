@@ -0,0 +1,42 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type RbacManager struct {
+	ResourceManager
+}
+
+// Simulate evaluates a candidate policy change against the current policies
+func (this *RbacManager) Simulate(session *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return this._post(session, "/rbac/simulate", params, "")
+}
+
+var (
+	Rbac RbacManager
+)
+
+func init() {
+	Rbac = RbacManager{NewComputeManager("rbac", "rbac",
+		[]string{},
+		[]string{})}
+
+	registerCompute(&Rbac)
+}
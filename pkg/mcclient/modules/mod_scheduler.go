@@ -87,6 +87,24 @@ func (this *SchedulerManager) Test(s *mcclient.ClientSession, params *api.Schedu
 	return obj, err
 }
 
+func (this *SchedulerManager) Capacity(s *mcclient.ClientSession, params *api.CapacityInput) (jsonutils.JSONObject, error) {
+	url := newSchedURL("capacity")
+	_, obj, err := this.jsonRequest(s, "POST", url, nil, params.JSON(params))
+	if err != nil {
+		return nil, err
+	}
+	return obj, err
+}
+
+func (this *SchedulerManager) Gang(s *mcclient.ClientSession, params *api.GangInput) (jsonutils.JSONObject, error) {
+	url := newSchedURL("gang")
+	_, obj, err := this.jsonRequest(s, "POST", url, nil, params.JSON(params))
+	if err != nil {
+		return nil, err
+	}
+	return obj, err
+}
+
 func (this *SchedulerManager) DoForecast(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	url := newSchedURL("forecast")
 	_, obj, err := this.jsonRequest(s, "POST", url, nil, params)
@@ -178,6 +196,22 @@ func (this *SchedulerManager) HistoryShow(s *mcclient.ClientSession, id string,
 	return this._post(s, url, params, "history")
 }
 
+// GetExplain returns the explain of a schedule session kept in the history
+// of the scheduler
+func (this *SchedulerManager) GetExplain(s *mcclient.ClientSession, sessionId string) (*api.ScheduleExplain, error) {
+	url := newSchedIdentURL("explain", sessionId)
+	ret, err := this._post(s, url, nil, "explain")
+	if err != nil {
+		return nil, err
+	}
+	explain := new(api.ScheduleExplain)
+	err = ret.Unmarshal(explain)
+	if err != nil {
+		return nil, fmt.Errorf("Not a valid response: %v", err)
+	}
+	return explain, nil
+}
+
 func (this *SchedulerManager) CleanCache(s *mcclient.ClientSession, hostId string) error {
 	url := newSchedURL("clean-cache")
 	if len(hostId) > 0 {
@@ -0,0 +1,65 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+var (
+	VpcPeerings    ResourceManager
+	VpnConnections ResourceManager
+)
+
+func init() {
+	VpcPeerings = NewComputeManager(
+		"vpc_peering",
+		"vpc_peerings",
+		[]string{
+			"id",
+			"name",
+			"status",
+			"vpc",
+			"vpc_id",
+			"peer_vpc",
+			"peer_vpc_id",
+			"peer_external_vpc_id",
+			"peer_region",
+			"peer_account",
+			"bandwidth",
+			"region",
+			"provider",
+		},
+		[]string{"tenant"},
+	)
+	VpnConnections = NewComputeManager(
+		"vpn_connection",
+		"vpn_connections",
+		[]string{
+			"id",
+			"name",
+			"status",
+			"vpc",
+			"vpc_id",
+			"vpn_gateway_id",
+			"vpn_gateway_address",
+			"customer_gateway_id",
+			"customer_gateway_address",
+			"local_cidrs",
+			"remote_cidrs",
+			"region",
+			"provider",
+		},
+		[]string{"tenant"},
+	)
+	registerCompute(&VpcPeerings)
+	registerCompute(&VpnConnections)
+}
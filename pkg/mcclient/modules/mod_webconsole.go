@@ -16,6 +16,7 @@ package modules
 
 import (
 	"fmt"
+	"io"
 
 	"yunion.io/x/jsonutils"
 
@@ -78,3 +79,31 @@ func (m WebConsoleManager) DoSshConnect(s *mcclient.ClientSession, id string, pa
 func (m WebConsoleManager) DoServerConnect(s *mcclient.ClientSession, id string, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	return m.DoConnect(s, "server", id, "", nil)
 }
+
+func (m WebConsoleManager) DoServerSerialConnect(s *mcclient.ClientSession, id string, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return m.DoConnect(s, "server", id, "serial", nil)
+}
+
+func (m WebConsoleManager) ListConsoleSessions(s *mcclient.ClientSession, params jsonutils.JSONObject) (*ListResult, error) {
+	url := "/webconsole/consolesessions"
+	if params != nil {
+		if qs := params.QueryString(); len(qs) > 0 {
+			url = fmt.Sprintf("%s?%s", url, qs)
+		}
+	}
+	return m._list(s, url, "consolesessions")
+}
+
+func (m WebConsoleManager) GetConsoleSession(s *mcclient.ClientSession, id string) (jsonutils.JSONObject, error) {
+	return m._get(s, fmt.Sprintf("/webconsole/consolesessions/%s", id), "consolesession")
+}
+
+func (m WebConsoleManager) GetConsoleSessionRecording(s *mcclient.ClientSession, id string) (io.ReadCloser, error) {
+	resp, err := m.rawRequest(s, "GET", fmt.Sprintf("/webconsole/consolesessions/%s/recording", id), nil, nil)
+	if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.Body, nil
+	} else {
+		_, _, err = s.ParseJSONResponse(resp, err)
+		return nil, err
+	}
+}
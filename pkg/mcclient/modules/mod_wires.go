@@ -21,7 +21,7 @@ var (
 func init() {
 	Wires = NewComputeManager("wire", "wires",
 		[]string{"ID", "Name", "Bandwidth", "Zone_ID",
-			"Zone", "Networks", "VPC", "VPC_ID"},
+			"Zone", "Networks", "VPC", "VPC_ID", "Wire_Type", "Vni"},
 		[]string{})
 
 	registerCompute(&Wires)
@@ -0,0 +1,51 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+type BucketListOptions struct {
+	Cloudregion string `help:"cloudregion id or name"`
+
+	BaseListOptions
+}
+
+type BucketCreateOptions struct {
+	NAME         string `help:"name of the bucket"`
+	CLOUDREGION  string `help:"cloudregion id or name" json:"cloudregion"`
+	MANAGER      string `help:"cloud provider id or name" json:"manager"`
+	StorageClass string `help:"storage class of the bucket, e.g. STANDARD"`
+	Acl          string `help:"canned acl" choices:"private|public-read|public-read-write|authenticated-read"`
+	Desc         string `help:"description" json:"description"`
+}
+
+type BucketIdOptions struct {
+	ID string `help:"ID or name of the bucket"`
+}
+
+type BucketSetAclOptions struct {
+	BucketIdOptions
+	ACL string `help:"canned acl" choices:"private|public-read|public-read-write|authenticated-read"`
+}
+
+type BucketSetLifecycleOptions struct {
+	BucketIdOptions
+	Rule []string `help:"lifecycle rule in the format <prefix>:<expiration_days>, e.g. logs/:30, no rule clears the lifecycle"`
+}
+
+type BucketTempUrlOptions struct {
+	BucketIdOptions
+	KEY           string `help:"object key" json:"key"`
+	Method        string `help:"http method of the url" choices:"GET|PUT|HEAD|DELETE" default:"GET"`
+	ExpireSeconds int    `help:"seconds before the url expires" default:"3600"`
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+type CloudcostListOptions struct {
+	Project      string `help:"project id or name"`
+	Manager      string `help:"cloudprovider id or name"`
+	Account      string `help:"cloudaccount id or name"`
+	Source       string `help:"source of the costs" choices:"provider|onpremise"`
+	ResourceType string `help:"resource type, e.g. server, disk"`
+	ResourceId   string `help:"local id of the resource"`
+	ExternalId   string `help:"external id of the resource"`
+	StartDate    string `help:"first usage date, e.g. 2019-05-01, default to the first day of the month"`
+	EndDate      string `help:"last usage date, default to today"`
+
+	BaseListOptions
+}
+
+type CloudcostSummaryOptions struct {
+	Project      string `help:"project id or name"`
+	Manager      string `help:"cloudprovider id or name"`
+	Account      string `help:"cloudaccount id or name"`
+	Source       string `help:"source of the costs" choices:"provider|onpremise"`
+	ResourceType string `help:"resource type, e.g. server, disk"`
+	StartDate    string `help:"first usage date, e.g. 2019-05-01, default to the first day of the month"`
+	EndDate      string `help:"last usage date, default to today"`
+}
+
+type CloudcostCollectOptions struct {
+	DAY string `help:"usage date to collect costs, e.g. 2019-05-01"`
+}
+
+type CostRateListOptions struct {
+	ResourceType string `help:"resource type" choices:"server|cpu|memory|disk"`
+
+	BaseListOptions
+}
+
+type CostRateCreateOptions struct {
+	NAME         string  `help:"name of the cost rate"`
+	RESOURCETYPE string  `help:"resource type" choices:"server|cpu|memory|disk" json:"resource_type"`
+	PRICE        float64 `help:"daily price of an instance of server, a vcpu, 1GB memory or 1GB disk" json:"price"`
+	Sku          string  `help:"instance type of server or storage type of disk, empty matches all"`
+	Currency     string  `help:"currency of the price"`
+}
+
+type CostRateUpdateOptions struct {
+	ID       string   `help:"ID or name of the cost rate" json:"-"`
+	Price    *float64 `help:"daily price"`
+	Currency string   `help:"currency of the price"`
+}
+
+type CostRateIdOptions struct {
+	ID string `help:"ID or name of the cost rate"`
+}
@@ -61,6 +61,7 @@ type DNSCreateOptions struct {
 	TTL      int64  `help:"TTL in seconds" positional:"false"`
 	Desc     string `help:"Description" json:"description"`
 	IsPublic *bool  `help:"Make the newly created record public to all"`
+	DnsZone  string `help:"Cloud dns zone of the record, the record is pushed to the cloud provider"`
 
 	DNSRecordOptions
 }
@@ -111,8 +112,19 @@ type DNSListOptions struct {
 	BaseListOptions
 
 	IsPublic string `choices:"0|1"`
+	DnsZone  string `help:"List records of the cloud dns zone"`
 }
 
 type DNSGetOptions struct {
 	ID string `help:"ID of DNS record to show" json:"-"`
 }
+
+type DnsZoneListOptions struct {
+	ZoneType string `help:"Type of the zone" choices:"public|private"`
+
+	BaseListOptions
+}
+
+type DnsZoneIdOptions struct {
+	ID string `help:"ID or name of the dns zone"`
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+type NatGatewayListOptions struct {
+	Vpc         string `help:"vpc id or name"`
+	Cloudregion string `help:"cloudregion id or name"`
+
+	BaseListOptions
+}
+
+type NatGatewayIdOptions struct {
+	ID string `help:"ID or name of the nat gateway"`
+}
+
+type NatSEntryListOptions struct {
+	Natgateway string `help:"nat gateway id or name"`
+	Network    string `help:"network id or name"`
+
+	BaseListOptions
+}
+
+type NatSEntryCreateOptions struct {
+	NAME       string `help:"name of the snat entry"`
+	NATGATEWAY string `help:"nat gateway id or name" json:"natgateway"`
+	IP         string `help:"public ip of the nat gateway used to translate the source address" json:"ip"`
+	Network    string `help:"source network id or name"`
+	SourceCidr string `help:"source cidr, used when network is not specified"`
+}
+
+type NatDEntryListOptions struct {
+	Natgateway string `help:"nat gateway id or name"`
+
+	BaseListOptions
+}
+
+type NatDEntryCreateOptions struct {
+	NAME         string `help:"name of the dnat entry"`
+	NATGATEWAY   string `help:"nat gateway id or name" json:"natgateway"`
+	ExternalIp   string `help:"public ip of the nat gateway" required:"true"`
+	ExternalPort int    `help:"public port" required:"true"`
+	InternalIp   string `help:"internal ip" required:"true"`
+	InternalPort int    `help:"internal port" required:"true"`
+	IpProtocol   string `help:"protocol" choices:"tcp|udp|any" default:"tcp"`
+}
+
+type NatEntryIdOptions struct {
+	ID string `help:"ID of the nat entry"`
+}
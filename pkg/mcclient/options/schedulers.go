@@ -15,6 +15,10 @@
 package options
 
 import (
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
 	"yunion.io/x/onecloud/pkg/apis/scheduler"
 	"yunion.io/x/onecloud/pkg/mcclient"
 )
@@ -93,3 +97,70 @@ func (o SchedulerForecastOptions) Params(s *mcclient.ClientSession) (*scheduler.
 	input.ScheduleBaseConfig = *opts
 	return input, nil
 }
+
+type SchedulerCapacityOptions struct {
+	SchedulerTestBaseOptions
+	Weight      int      `help:"Relative share of this spec in the mix" default:"1"`
+	ExtraSpec   []string `help:"Additional spec of the mix in JSON, e.g. '{\"vcpu_count\":4,\"vmem_size\":8192,\"weight\":2}'"`
+	ExcludeHost []string `help:"Host id or name simulated as under maintenance, its guests are re-placed first"`
+}
+
+func (o SchedulerCapacityOptions) Params(s *mcclient.ClientSession) (*scheduler.CapacityInput, error) {
+	data, err := o.data(s)
+	if err != nil {
+		return nil, err
+	}
+	spec := new(scheduler.CapacitySpec)
+	spec.ServerConfig = *data
+	spec.Weight = o.Weight
+	input := new(scheduler.CapacityInput)
+	input.Specs = []*scheduler.CapacitySpec{spec}
+	for _, extra := range o.ExtraSpec {
+		obj, err := jsonutils.ParseString(extra)
+		if err != nil {
+			return nil, fmt.Errorf("parse extra spec %q: %v", extra, err)
+		}
+		extraSpec := new(scheduler.CapacitySpec)
+		if err := obj.Unmarshal(extraSpec); err != nil {
+			return nil, fmt.Errorf("unmarshal extra spec %q: %v", extra, err)
+		}
+		input.Specs = append(input.Specs, extraSpec)
+	}
+	input.ExcludeHosts = o.ExcludeHost
+	return input, nil
+}
+
+type SchedulerGangOptions struct {
+	SchedulerTestBaseOptions
+	ExtraMember []string `help:"Additional member of the gang in JSON, e.g. '{\"vcpu_count\":4,\"vmem_size\":8192,\"count\":2}'"`
+	Session     string   `help:"Session id of the reservation"`
+	DryRun      bool     `help:"Only plan the placement without reserving resources"`
+}
+
+func (o SchedulerGangOptions) Params(s *mcclient.ClientSession) (*scheduler.GangInput, error) {
+	data, err := o.data(s)
+	if err != nil {
+		return nil, err
+	}
+	member := new(scheduler.ScheduleInput)
+	member.ServerConfig = *data
+	input := new(scheduler.GangInput)
+	input.SessionId = o.Session
+	input.DryRun = o.DryRun
+	// spreads are applied to the whole gang
+	input.Spreads = data.Spreads
+	data.Spreads = nil
+	input.Members = []*scheduler.ScheduleInput{member}
+	for _, extra := range o.ExtraMember {
+		obj, err := jsonutils.ParseString(extra)
+		if err != nil {
+			return nil, fmt.Errorf("parse extra member %q: %v", extra, err)
+		}
+		extraMember := new(scheduler.ScheduleInput)
+		if err := obj.Unmarshal(extraMember); err != nil {
+			return nil, fmt.Errorf("unmarshal extra member %q: %v", extra, err)
+		}
+		input.Members = append(input.Members, extraMember)
+	}
+	return input, nil
+}
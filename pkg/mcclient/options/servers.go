@@ -59,6 +59,11 @@ type ServerIdOptions struct {
 	ID string `help:"ID or name of the server" json:"-"`
 }
 
+type ServerScheduleExplainOptions struct {
+	ServerIdOptions
+	Limit int `help:"Number of the latest schedule decisions to show" default:"1"`
+}
+
 type ServerLoginInfoOptions struct {
 	ID  string `help:"ID or name of the server" json:"-"`
 	Key string `help:"File name of private key, if password is encrypted by key"`
@@ -139,6 +144,9 @@ type ServerConfigs struct {
 	Project        string   `help:"'Owner project ID or Name" json:"tenant"`
 	User           string   `help:"Owner user ID or Name"`
 	Count          int      `help:"Create multiple simultaneously" default:"1"`
+	Gang           bool     `help:"Place all the servers together or none of them"`
+	Spread         []string `help:"Spread constraint of the servers, topology is host, zone or a schedtag name prefix, e.g. 'rack-:2'" metavar:"<TOPOLOGY:MAX_PER_DOMAIN>"`
+	PriorityClass  string   `help:"Priority class, preemptible servers are evicted for normal servers when resources run out" choices:"normal|preemptible"`
 }
 
 func (o ServerConfigs) Data() (*computeapi.ServerConfigs, error) {
@@ -153,6 +161,22 @@ func (o ServerConfigs) Data() (*computeapi.ServerConfigs, error) {
 		Project:          o.Project,
 		Backup:           o.Backup,
 		Count:            o.Count,
+		Gang:             o.Gang,
+		PriorityClass:    o.PriorityClass,
+	}
+	for _, sp := range o.Spread {
+		pos := strings.LastIndex(sp, ":")
+		if pos <= 0 {
+			return nil, fmt.Errorf("Invalid spread %q", sp)
+		}
+		max, err := strconv.Atoi(sp[pos+1:])
+		if err != nil || max <= 0 {
+			return nil, fmt.Errorf("Invalid spread max per domain %q", sp)
+		}
+		data.Spreads = append(data.Spreads, &computeapi.SpreadConstraint{
+			Topology:     sp[:pos],
+			MaxPerDomain: max,
+		})
 	}
 	for i, d := range o.Disk {
 		disk, err := cmdline.ParseDiskConfig(d, i)
@@ -218,6 +242,13 @@ type ServerCloneOptions struct {
 	Eip           string `help:"associate with an existing EIP when server is created" json:"eip,omitempty"`
 }
 
+type ServerMigrateToKvmOptions struct {
+	ID         string `help:"ID or name of the ESXi server to migrate" json:"-"`
+	Name       string `help:"Name of the KVM server, default to the source name with a -kvm suffix"`
+	AutoStart  bool   `help:"Auto start server after it is migrated"`
+	PreferHost string `help:"Host of the KVM server" json:"prefer_host_id"`
+}
+
 type ServerCreateOptions struct {
 	ServerConfigs
 
@@ -240,6 +271,7 @@ type ServerCreateOptions struct {
 	Deploy           []string `help:"Specify deploy files in virtual server file system" json:"-"`
 	Group            []string `help:"Group of virtual server"`
 	System           bool     `help:"Create a system VM, sysadmin ONLY option" json:"is_system"`
+	HaEnabled        bool     `help:"Restart server on another host when its host fails, all disks must be on shared storage"`
 	TaskNotify       *bool    `help:"Setup task notify" json:"-"`
 	DryRun           *bool    `help:"Dry run to test scheduler" json:"-"`
 	UserDataFile     string   `help:"user_data file path" json:"-"`
@@ -320,6 +352,7 @@ func (opts *ServerCreateOptions) Params() (*computeapi.ServerCreateInput, error)
 		ShutdownBehavior:   opts.ShutdownBehavior,
 		AutoStart:          opts.AutoStart,
 		IsSystem:           opts.System,
+		HaEnabled:          opts.HaEnabled,
 		Duration:           opts.Duration,
 		AutoPrepaidRecycle: opts.AutoPrepaidRecycle,
 		EipBw:              opts.EipBw,
@@ -400,6 +433,7 @@ type ServerUpdateOptions struct {
 	Boot             string   `help:"Boot device" choices:"disk|cdrom"`
 	Delete           string   `help:"Lock server to prevent from deleting" choices:"enable|disable" json:"-"`
 	ShutdownBehavior string   `help:"Behavior after VM server shutdown, stop or terminate server" choices:"stop|terminate"`
+	Ha               string   `help:"Restart server on another host when its host fails" choices:"enable|disable" json:"-"`
 }
 
 func (opts *ServerUpdateOptions) Params() (*jsonutils.JSONDict, error) {
@@ -422,6 +456,9 @@ func (opts *ServerUpdateOptions) Params() (*jsonutils.JSONDict, error) {
 			params.Set("disable_delete", jsonutils.JSONFalse)
 		}
 	}
+	if len(opts.Ha) > 0 {
+		params.Set("ha_enabled", jsonutils.NewBool(opts.Ha == "enable"))
+	}
 	return params, nil
 }
 
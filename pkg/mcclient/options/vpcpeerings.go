@@ -0,0 +1,37 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+type VpcPeeringListOptions struct {
+	Vpc         string `help:"vpc id or name"`
+	Cloudregion string `help:"cloudregion id or name"`
+
+	BaseListOptions
+}
+
+type VpcPeeringIdOptions struct {
+	ID string `help:"ID or name of the vpc peering"`
+}
+
+type VpnConnectionListOptions struct {
+	Vpc         string `help:"vpc id or name"`
+	Cloudregion string `help:"cloudregion id or name"`
+
+	BaseListOptions
+}
+
+type VpnConnectionIdOptions struct {
+	ID string `help:"ID or name of the vpn connection"`
+}
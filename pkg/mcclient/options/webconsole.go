@@ -78,3 +78,23 @@ type WebConsoleServerOptions struct {
 	WebConsoleOptions
 	ID string `help:"Server id or name"`
 }
+
+type ConsoleSessionListOptions struct {
+	User     string `help:"Filter by user id or name"`
+	Project  string `help:"Filter by project id or name"`
+	Target   string `help:"Filter by target server, host, ip or pod"`
+	Protocol string `help:"Filter by protocol" choices:"tty|vnc|spice|wmks"`
+}
+
+func (opt *ConsoleSessionListOptions) Params() (*jsonutils.JSONDict, error) {
+	return StructToParams(opt)
+}
+
+type ConsoleSessionIdOptions struct {
+	ID string `help:"Console session id"`
+}
+
+type ConsoleSessionRecordingOptions struct {
+	ConsoleSessionIdOptions
+	Output string `help:"File to save the recording, default to stdout" short-token:"o"`
+}
@@ -35,7 +35,7 @@ func (p *CPUPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.Pr
 	h := predicates.NewPredicateHelper(p, u, c)
 	d := u.SchedData()
 
-	freeCPUCount := h.GetInt64("FreeCPUCount", 0)
+	freeCPUCount := h.GetInt64("FreeCPUCount", 0) - h.GetReserved("FreeCPUCount")
 	reqCPUCount := int64(d.Ncpu)
 	if freeCPUCount < reqCPUCount {
 		totalCPUCount := h.GetInt64("CPUCount", 0)
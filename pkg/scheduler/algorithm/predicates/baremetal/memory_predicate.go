@@ -35,7 +35,7 @@ func (p *MemoryPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core
 	h := predicates.NewPredicateHelper(p, u, c)
 	d := u.SchedData()
 
-	freeMemSize := h.GetInt64("FreeMemSize", 0)
+	freeMemSize := h.GetInt64("FreeMemSize", 0) - h.GetReserved("FreeMemSize")
 	reqMemSize := int64(d.Memory)
 	if freeMemSize < reqMemSize {
 		totalMemSize := h.GetInt64("MemSize", 0)
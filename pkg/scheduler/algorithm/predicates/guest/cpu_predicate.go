@@ -56,7 +56,10 @@ func (f *CPUPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.Pr
 	}
 
 	useRsvd := h.UseReserved()
-	freeCPUCount := hc.GetFreeCPUCount(useRsvd)
+	freeCPUCount := hc.GetFreeCPUCount(useRsvd) - h.GetReserved("FreeCPUCount")
+	if h.Preempt() {
+		freeCPUCount += hc.PreemptibleCPUCount
+	}
 	reqCPUCount := int64(d.Ncpu)
 	if freeCPUCount < reqCPUCount {
 		totalCPUCount := hc.GetTotalCPUCount(useRsvd)
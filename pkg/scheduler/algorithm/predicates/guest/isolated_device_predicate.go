@@ -17,12 +17,17 @@ package guest
 import (
 	"fmt"
 
+	"yunion.io/x/pkg/util/sets"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
 	"yunion.io/x/onecloud/pkg/scheduler/core"
 )
 
 // IsolatedDevicePredicate check mode, and number of scheduled
-// device configurations and current resources.
+// device configurations and current resources, including the SR-IOV
+// virtual functions requested by guest networks.
 type IsolatedDevicePredicate struct {
 	predicates.BasePredicate
 }
@@ -37,12 +42,46 @@ func (f *IsolatedDevicePredicate) Clone() core.FitPredicate {
 
 func (f *IsolatedDevicePredicate) PreExecute(u *core.Unit, cs []core.Candidater) (bool, error) {
 	data := u.SchedData()
-	if len(data.IsolatedDevices) == 0 {
+	if len(data.IsolatedDevices) == 0 && len(getSriovNetworks(data.Networks)) == 0 {
 		return false, nil
 	}
 	return true, nil
 }
 
+type vendorModelRequest struct {
+	vendorModel string
+	isVGPU      bool
+}
+
+func getSriovNetworks(nets []*computeapi.NetworkConfig) []*computeapi.NetworkConfig {
+	ret := make([]*computeapi.NetworkConfig, 0)
+	for _, net := range nets {
+		if net.Sriov {
+			ret = append(ret, net)
+		}
+	}
+	return ret
+}
+
+// sriovWiresOfNetwork returns the wires of candidate networks a SR-IOV nic
+// request may be placed on.
+func sriovWiresOfNetwork(net *computeapi.NetworkConfig, networks []models.SNetwork) sets.String {
+	wires := sets.NewString()
+	for _, n := range networks {
+		if len(net.Network) > 0 {
+			if net.Network != n.Id && net.Network != n.Name {
+				continue
+			}
+		} else if len(net.Wire) > 0 {
+			if net.Wire != n.WireId && net.Wire != n.GetWire().GetName() {
+				continue
+			}
+		}
+		wires.Insert(n.WireId)
+	}
+	return wires
+}
+
 func (f *IsolatedDevicePredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
 	h := predicates.NewPredicateHelper(f, u, c)
 	reqIsoDevs := u.SchedData().IsolatedDevices
@@ -102,15 +141,26 @@ func (f *IsolatedDevicePredicate) Execute(u *core.Unit, c core.Candidater) (bool
 		}
 	}
 
-	// check host device by model
-	devVendorModelRequest := make(map[string]int, 0)
+	// check host device by model, vGPUs of a model are only counted for vGPU
+	// requests as region attaches them only to vGPU requests
+	devVendorModelRequest := make(map[vendorModelRequest]int, 0)
 	for _, dev := range reqIsoDevs {
 		if len(dev.Model) != 0 {
-			devVendorModelRequest[fmt.Sprintf("%s:%s", dev.Vendor, dev.Model)] += 1
+			req := vendorModelRequest{
+				vendorModel: fmt.Sprintf("%s:%s", dev.Vendor, dev.Model),
+				isVGPU:      dev.DevType == computeapi.VGPU_TYPE,
+			}
+			devVendorModelRequest[req] += 1
 		}
 	}
-	for vendorModel, reqCount := range devVendorModelRequest {
-		freeCount := len(hc.UnusedIsolatedDevicesByVendorModel(vendorModel))
+	for req, reqCount := range devVendorModelRequest {
+		vendorModel := req.vendorModel
+		freeCount := 0
+		for _, dev := range hc.UnusedIsolatedDevicesByVendorModel(vendorModel) {
+			if (dev.DevType == computeapi.VGPU_TYPE) == req.isVGPU {
+				freeCount++
+			}
+		}
 		if freeCount < reqCount {
 			h.Exclude(fmt.Sprintf("IsolatedDevice vendor:model %q not enough, request: %d, hostFree: %d", vendorModel, reqCount, freeCount))
 			return h.GetResult()
@@ -121,6 +171,36 @@ func (f *IsolatedDevicePredicate) Execute(u *core.Unit, c core.Candidater) (bool
 		}
 	}
 
+	// check free SR-IOV virtual functions on wires of requested networks
+	vfRequest := make(map[string]int, 0)
+	vfWires := make(map[string]sets.String, 0)
+	for _, net := range getSriovNetworks(u.SchedData().Networks) {
+		key := net.Network
+		if len(key) == 0 {
+			if len(net.Wire) > 0 {
+				key = "wire=" + net.Wire
+			} else {
+				key = "[random]"
+			}
+		}
+		vfRequest[key] += 1
+		vfWires[key] = sriovWiresOfNetwork(net, hc.Networks)
+	}
+	for key, reqCount := range vfRequest {
+		freeCount := 0
+		for _, wireId := range vfWires[key].List() {
+			freeCount += len(hc.UnusedSriovVFsOnWire(wireId))
+		}
+		if freeCount < reqCount {
+			h.Exclude(fmt.Sprintf("SR-IOV virtual function of network %q not enough, request: %d, hostFree: %d", key, reqCount, freeCount))
+			return h.GetResult()
+		}
+		cap := freeCount / reqCount
+		if int64(cap) < minCapacity {
+			minCapacity = int64(cap)
+		}
+	}
+
 	h.SetCapacity(minCapacity)
 	return h.GetResult()
 }
@@ -57,7 +57,10 @@ func (p *MemoryPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core
 	}
 
 	useRsvd := h.UseReserved()
-	freeMemSize := hc.GetFreeMemSize(useRsvd)
+	freeMemSize := hc.GetFreeMemSize(useRsvd) - h.GetReserved("FreeMemSize")
+	if h.Preempt() {
+		freeMemSize += hc.PreemptibleMemSize
+	}
 	reqMemSize := int64(d.Memory)
 	if freeMemSize < reqMemSize {
 		totalMemSize := hc.GetTotalMemSize(useRsvd)
@@ -21,6 +21,7 @@ import (
 	"yunion.io/x/pkg/tristate"
 	"yunion.io/x/pkg/utils"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
 	"yunion.io/x/onecloud/pkg/scheduler/core"
 )
@@ -77,6 +78,9 @@ func (p *StoragePredicate) Execute(u *core.Unit, c core.Candidater) (bool, []cor
 
 	getStorageCapacity := func(backend string, reqMaxSize int64, reqTotalSize int64, useRsvd bool) (int64, int64) {
 		totalFree := hc.GetFreeStorageSizeOfType(backend, useRsvd)
+		if backend == computeapi.STORAGE_LOCAL {
+			totalFree -= h.GetReserved("FreeLocalStorageSize")
+		}
 		capacity := totalFree / utils.Max(reqTotalSize, 1)
 
 		return capacity, totalFree
@@ -169,13 +169,28 @@ func (h *PredicateHelper) BaremetalCandidate() (*candidate.BaremetalDesc, error)
 	return algorithm.ToBaremetalCandidate(h.Candidate)
 }
 
+// IReservedResourceGetter is the scheduler manager keeping the resources
+// reserved on the candidates by the gang plans not carried out yet
+type IReservedResourceGetter interface {
+	GetReservedResource(candidateId string, key string) int64
+}
+
+// GetReserved returns the resource of key reserved on the candidate, the
+// capacity predicates take it as used
+func (h *PredicateHelper) GetReserved(key string) int64 {
+	getter, ok := h.Unit.SchedulerManager.(IReservedResourceGetter)
+	if !ok {
+		return 0
+	}
+	return getter.GetReservedResource(h.Candidate.IndexKey(), key)
+}
+
+// Preempt tells whether the resources of preemptible guests are taken as free
+func (h *PredicateHelper) Preempt() bool {
+	return h.Unit.SchedData().Preempt
+}
+
 // UseReserved check whether the unit can use guest reserved resource
 func (h *PredicateHelper) UseReserved() bool {
-	usable := false
-	data := h.Unit.SchedData()
-	isoDevs := data.IsolatedDevices
-	if len(isoDevs) > 0 {
-		usable = true
-	}
-	return usable
+	return h.Unit.SchedData().UseReserved()
 }
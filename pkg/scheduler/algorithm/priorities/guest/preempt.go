@@ -0,0 +1,55 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// PreemptPriority prefers the hosts evicting fewer preemptible guests when
+// the schedule preempts
+type PreemptPriority struct {
+	priorities.BasePriority
+}
+
+func (p *PreemptPriority) Name() string {
+	return "guest_preempt"
+}
+
+func (p *PreemptPriority) Clone() core.Priority {
+	return &PreemptPriority{}
+}
+
+func (p *PreemptPriority) PreExecute(u *core.Unit, cs []core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	return u.SchedData().Preempt, nil, nil
+}
+
+func (p *PreemptPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	hc, err := p.HostCandidate(c)
+	if err != nil {
+		return core.HostPriority{}, err
+	}
+
+	d := u.SchedData()
+	victims := hc.PreemptVictims(int64(d.Ncpu), int64(d.Memory), d.UseReserved())
+	if len(victims) > 0 {
+		h.SetFrontRawScore(-1 * len(victims))
+	}
+
+	return h.GetResult()
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/scheduler/cache/candidate"
+)
+
+func TestHostDescPreemptVictims(t *testing.T) {
+	newHost := func(freeCPU, freeMem int64, guests ...*candidate.PreemptibleGuestDesc) *candidate.HostDesc {
+		h := &candidate.HostDesc{
+			FreeCPUCount:              freeCPU,
+			FreeMemSize:               freeMem,
+			GuestReservedResource:     &candidate.ReservedResource{CPUCount: 2, MemorySize: 2048},
+			GuestReservedResourceUsed: &candidate.ReservedResource{},
+			PreemptibleGuests:         guests,
+		}
+		for _, g := range guests {
+			h.PreemptibleCPUCount += g.VcpuCount
+			h.PreemptibleMemSize += g.VmemSize
+		}
+		return h
+	}
+	newGuest := func(id string, cpu, mem int64) *candidate.PreemptibleGuestDesc {
+		return &candidate.PreemptibleGuestDesc{Id: id, Name: id, VcpuCount: cpu, VmemSize: mem}
+	}
+	cases := []struct {
+		name    string
+		host    *candidate.HostDesc
+		cpu     int64
+		mem     int64
+		useRsvd bool
+		want    []string
+	}{
+		{
+			name: "enough free resources",
+			host: newHost(4, 4096, newGuest("g1", 2, 2048)),
+			cpu:  2,
+			mem:  2048,
+			want: []string{},
+		},
+		{
+			name: "largest first",
+			host: newHost(1, 1024, newGuest("g1", 1, 1024), newGuest("g2", 4, 4096), newGuest("g3", 2, 2048)),
+			cpu:  4,
+			mem:  4096,
+			want: []string{"g2"},
+		},
+		{
+			name: "cpu short",
+			host: newHost(0, 8192, newGuest("g1", 2, 1024), newGuest("g2", 2, 1024)),
+			cpu:  4,
+			mem:  1024,
+			want: []string{"g1", "g2"},
+		},
+		{
+			name: "same memory by cpu",
+			host: newHost(0, 0, newGuest("g1", 1, 2048), newGuest("g2", 2, 2048)),
+			cpu:  2,
+			mem:  2048,
+			want: []string{"g2"},
+		},
+		{
+			name: "not enough even evicting all",
+			host: newHost(0, 0, newGuest("g1", 2, 2048)),
+			cpu:  4,
+			mem:  2048,
+			want: nil,
+		},
+		{
+			name:    "reserved resources",
+			host:    newHost(0, 0, newGuest("g1", 2, 2048)),
+			cpu:     4,
+			mem:     4096,
+			useRsvd: true,
+			want:    []string{"g1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			victims := c.host.PreemptVictims(c.cpu, c.mem, c.useRsvd)
+			var got []string
+			if victims != nil {
+				got = make([]string, len(victims))
+				for i := range victims {
+					got[i] = victims[i].Id
+				}
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("victims = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
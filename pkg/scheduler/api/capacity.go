@@ -0,0 +1,100 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/cmdline"
+)
+
+// CapacityArgs is parsed from scheduler capacity request
+type CapacityArgs struct {
+	Specs        []*SchedInfo
+	Weights      []int
+	ExcludeHosts []string
+}
+
+func FetchCapacityArgs(req *http.Request) (*CapacityArgs, error) {
+	body, err := appsrv.FetchJSON(req)
+	if err != nil {
+		return nil, err
+	}
+	specObjs, err := body.GetArray("specs")
+	if err != nil || len(specObjs) == 0 {
+		return nil, fmt.Errorf("Missing specs")
+	}
+	args := new(CapacityArgs)
+	for idx, obj := range specObjs {
+		input, err := cmdline.FetchScheduleInputByJSON(obj)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid spec %d: %v", idx, err)
+		}
+		weight, _ := obj.Int("weight")
+		if weight <= 0 {
+			weight = 1
+		}
+		input.Count = 1
+		info := NewSchedInfo(input)
+		info.IsSuggestion = true
+		info.ShowSuggestionDetails = true
+		info.SuggestionAll = true
+		info.SuggestionLimit = math.MaxInt32
+		args.Specs = append(args.Specs, info)
+		args.Weights = append(args.Weights, int(weight))
+	}
+	if body.Contains("exclude_hosts") {
+		if err := body.Unmarshal(&args.ExcludeHosts, "exclude_hosts"); err != nil {
+			return nil, fmt.Errorf("Invalid exclude_hosts: %v", err)
+		}
+	}
+	return args, nil
+}
+
+type CapacitySpecResult struct {
+	Index  int   `json:"index"`
+	Weight int   `json:"weight"`
+	Count  int64 `json:"count"`
+}
+
+type CapacityHost struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Counts are guests of each spec placed on this host
+	Counts []int64 `json:"counts"`
+	// Usage is the used fraction of each predicate capacity
+	Usage      map[string]float64 `json:"usage"`
+	Bottleneck string             `json:"bottleneck"`
+	// Evacuated are guests of the excluded hosts re-placed on this host
+	Evacuated int64 `json:"evacuated"`
+}
+
+type SchedCapacityResult struct {
+	MaxCount int64 `json:"max_count"`
+	// Sets is how many complete rounds of the weighted mix fit
+	Sets          int64                `json:"sets"`
+	Specs         []CapacitySpecResult `json:"specs"`
+	Hosts         []CapacityHost       `json:"hosts"`
+	Bottleneck    string               `json:"bottleneck"`
+	Bottlenecks   map[string]int64     `json:"bottlenecks"`
+	ExcludedHosts []string             `json:"excluded_hosts"`
+	// EvacuatedGuests of the excluded hosts are re-placed before the mix,
+	// UnplacedGuests of them can't be
+	EvacuatedGuests int64 `json:"evacuated_guests"`
+	UnplacedGuests  int64 `json:"unplaced_guests"`
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	api "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/cmdline"
+)
+
+// GangArgs is parsed from scheduler gang request or a sync schedule request
+// asking for gang placement
+type GangArgs struct {
+	SessionId string
+	Members   []*SchedInfo
+	Counts    []int64
+	Spreads   []*computeapi.SpreadConstraint
+	DryRun    bool
+}
+
+func FetchGangArgs(req *http.Request) (*GangArgs, error) {
+	body, err := appsrv.FetchJSON(req)
+	if err != nil {
+		return nil, err
+	}
+	memberObjs, err := body.GetArray("members")
+	if err != nil || len(memberObjs) == 0 {
+		return nil, fmt.Errorf("Missing members")
+	}
+	members := make([]*api.ScheduleInput, 0, len(memberObjs))
+	for idx, obj := range memberObjs {
+		input, err := cmdline.FetchScheduleInputByJSON(obj)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid member %d: %v", idx, err)
+		}
+		members = append(members, input)
+	}
+	spreads := make([]*computeapi.SpreadConstraint, 0)
+	if body.Contains("spreads") {
+		if err := body.Unmarshal(&spreads, "spreads"); err != nil {
+			return nil, fmt.Errorf("Invalid spreads: %v", err)
+		}
+	}
+	sessionId, _ := body.GetString("session_id")
+	dryRun, _ := body.Bool("dry_run")
+	return NewGangArgs(sessionId, members, spreads, dryRun)
+}
+
+func NewGangArgs(sessionId string, members []*api.ScheduleInput, spreads []*computeapi.SpreadConstraint, dryRun bool) (*GangArgs, error) {
+	for _, spread := range spreads {
+		if len(spread.Topology) == 0 || spread.MaxPerDomain <= 0 {
+			return nil, fmt.Errorf("Invalid spread %s:%d", spread.Topology, spread.MaxPerDomain)
+		}
+	}
+	args := &GangArgs{
+		SessionId: sessionId,
+		Spreads:   spreads,
+		DryRun:    dryRun,
+	}
+	for _, member := range members {
+		count := int64(member.Count)
+		if count <= 0 {
+			count = 1
+		}
+		// capacities of every candidate are asked for, leave the
+		// request itself untouched
+		input := *member
+		conf := *member.ServerConfigs
+		input.ServerConfigs = &conf
+		input.Count = 1
+		info := NewSchedInfo(&input)
+		info.IsSuggestion = true
+		info.ShowSuggestionDetails = true
+		info.SuggestionAll = true
+		info.SuggestionLimit = math.MaxInt32
+		args.Members = append(args.Members, info)
+		args.Counts = append(args.Counts, count)
+	}
+	return args, nil
+}
+
+// ReservedPoolName is the reserved pool of the candidates of the gang
+func (args *GangArgs) ReservedPoolName() string {
+	for _, member := range args.Members {
+		if member.Hypervisor == SchedTypeBaremetal {
+			return "baremetal"
+		}
+	}
+	return "host"
+}
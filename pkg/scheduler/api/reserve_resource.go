@@ -14,6 +14,12 @@
 
 package api
 
+import (
+	"net/http"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+)
+
 type ReservedResourcesArgs struct {
 	Name   string
 	Remove string
@@ -22,3 +28,19 @@ type ReservedResourcesArgs struct {
 type ReservedResourcesResult struct {
 	Resources interface{} `json:"resources"`
 }
+
+// FetchReservedResourcesArgs parses the reserved pool to show, and the session
+// to release first if any
+func FetchReservedResourcesArgs(req *http.Request) (*ReservedResourcesArgs, error) {
+	body, err := appsrv.FetchJSON(req)
+	if err != nil {
+		return nil, err
+	}
+	args := new(ReservedResourcesArgs)
+	args.Name, _ = body.GetString("name")
+	if len(args.Name) == 0 {
+		args.Name = "host"
+	}
+	args.Remove, _ = body.GetString("remove")
+	return args, nil
+}
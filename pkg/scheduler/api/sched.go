@@ -40,6 +40,9 @@ type SchedInfo struct {
 	IsSuggestion          bool            `json:"suggestion"`
 	ShowSuggestionDetails bool            `json:"suggestion_details"`
 	Raw                   string
+
+	// Preempt takes the resources of preemptible guests as free
+	Preempt bool `json:"preempt"`
 }
 
 func FetchSchedInfo(req *http.Request) (*SchedInfo, error) {
@@ -125,6 +128,24 @@ func (d *SchedInfo) SkipDirtyMarkHost() bool {
 	return skipByHypervisor || skipByBackup
 }
 
+// UseReserved tells whether the guests can use the reserved resource of
+// the hosts, which is kept for the guests with isolated devices
+func (d *SchedInfo) UseReserved() bool {
+	return len(d.IsolatedDevices) > 0
+}
+
+// CanPreempt tells whether preemptible guests can be evicted for the request
+func (d *SchedInfo) CanPreempt() bool {
+	// only the creation of guests waits for the eviction, not migration
+	if !o.GetOptions().EnablePreemption || d.IsSuggestion || d.Backup || len(d.HostId) > 0 {
+		return false
+	}
+	if d.PriorityClass == computeapi.GUEST_PRIORITY_CLASS_PREEMPTIBLE {
+		return false
+	}
+	return d.Hypervisor == HostHypervisorForKvm || d.Hypervisor == SchedTypeEsxi
+}
+
 func (d *SchedInfo) GetCandidateHostTypes() []string {
 	switch d.Hypervisor {
 	case SchedTypeContainer:
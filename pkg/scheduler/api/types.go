@@ -45,6 +45,7 @@ const (
 	GPU_VGA_TYPE    = "GPU-VGA"
 	USB_TYPE        = "USB"
 	NIC_TYPE        = "NIC"
+	VGPU_TYPE       = "VGPU"
 
 	// Hard code vendor const
 	NVIDIA           = "NVIDIA"
@@ -72,6 +73,7 @@ var (
 		DIRECT_PCI_TYPE,
 		USB_TYPE,
 		NIC_TYPE,
+		VGPU_TYPE,
 	).Union(ValidGpuTypes)
 
 	IsolatedVendorIDMap = map[string]string{
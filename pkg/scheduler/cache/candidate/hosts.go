@@ -17,6 +17,7 @@ package candidate
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	gosync "sync"
 	"sync/atomic"
@@ -78,6 +79,18 @@ type HostDesc struct {
 	IsMaintenance             bool                  `json:"is_maintenance"`
 	GuestReservedResource     *ReservedResource     `json:"guest_reserved_resource"`
 	GuestReservedResourceUsed *ReservedResource     `json:"guest_reserved_used"`
+
+	// running preemptible guests whose resources can be reclaimed
+	PreemptibleGuests   []*PreemptibleGuestDesc `json:"preemptible_guests"`
+	PreemptibleCPUCount int64                   `json:"preemptible_cpu_count"`
+	PreemptibleMemSize  int64                   `json:"preemptible_mem_size"`
+}
+
+type PreemptibleGuestDesc struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	VcpuCount int64  `json:"vcpu_count"`
+	VmemSize  int64  `json:"vmem_size"`
 }
 
 type ReservedResource struct {
@@ -465,6 +478,35 @@ func (h *HostDesc) IndexKey() string {
 	return h.Id
 }
 
+// PreemptVictims returns the preemptible guests to evict so that cpu and mem
+// more than free are available, the largest guests are evicted first to evict
+// as few as possible. It returns nil if evicting all of them is not enough.
+func (h *HostDesc) PreemptVictims(cpu, mem int64, useRsvd bool) []*PreemptibleGuestDesc {
+	needCPU := cpu - h.GetFreeCPUCount(useRsvd)
+	needMem := mem - h.GetFreeMemSize(useRsvd)
+	if needCPU > h.PreemptibleCPUCount || needMem > h.PreemptibleMemSize {
+		return nil
+	}
+	guests := make([]*PreemptibleGuestDesc, len(h.PreemptibleGuests))
+	copy(guests, h.PreemptibleGuests)
+	sort.SliceStable(guests, func(i, j int) bool {
+		if guests[i].VmemSize != guests[j].VmemSize {
+			return guests[i].VmemSize > guests[j].VmemSize
+		}
+		return guests[i].VcpuCount > guests[j].VcpuCount
+	})
+	victims := make([]*PreemptibleGuestDesc, 0)
+	for _, g := range guests {
+		if needCPU <= 0 && needMem <= 0 {
+			break
+		}
+		victims = append(victims, g)
+		needCPU -= g.VcpuCount
+		needMem -= g.VmemSize
+	}
+	return victims
+}
+
 func (h *HostDesc) UnusedIsolatedDevices() []*IsolatedDeviceDesc {
 	ret := make([]*IsolatedDeviceDesc, 0)
 	for _, dev := range h.IsolatedDevices {
@@ -506,6 +548,16 @@ func (h *HostDesc) UnusedIsolatedDevicesByModel(model string) []*IsolatedDeviceD
 	return ret
 }
 
+func (h *HostDesc) UnusedSriovVFsOnWire(wireID string) []*IsolatedDeviceDesc {
+	ret := make([]*IsolatedDeviceDesc, 0)
+	for _, dev := range h.UnusedIsolatedDevicesByType(api.NIC_VF_TYPE) {
+		if dev.WireID == wireID {
+			ret = append(ret, dev)
+		}
+	}
+	return ret
+}
+
 func (h *HostDesc) GetIsolatedDevice(devID string) *IsolatedDeviceDesc {
 	for _, dev := range h.IsolatedDevices {
 		if dev.ID == devID {
@@ -1045,12 +1097,25 @@ func (b *HostBuilder) fillGuestsResourceInfo(desc *HostDesc, host *computemodels
 		guestsOnHost = append(guestsOnHost, backupGuestsOnHost...)
 	}
 
+	desc.PreemptibleGuests = make([]*PreemptibleGuestDesc, 0)
+	desc.PreemptibleCPUCount = 0
+	desc.PreemptibleMemSize = 0
 	for _, gst := range guestsOnHost {
 		guest := gst.(computemodels.SGuest)
 		if IsGuestRunning(guest) {
 			runningCount++
 			memSize += int64(guest.VmemSize)
 			cpuCount += int64(guest.VcpuCount)
+			if guest.PriorityClass == api.GUEST_PRIORITY_CLASS_PREEMPTIBLE && len(guest.BackupHostId) == 0 {
+				desc.PreemptibleGuests = append(desc.PreemptibleGuests, &PreemptibleGuestDesc{
+					Id:        guest.Id,
+					Name:      guest.Name,
+					VcpuCount: int64(guest.VcpuCount),
+					VmemSize:  int64(guest.VmemSize),
+				})
+				desc.PreemptibleCPUCount += int64(guest.VcpuCount)
+				desc.PreemptibleMemSize += int64(guest.VmemSize)
+			}
 		} else if IsGuestCreating(guest) {
 			creatingGuestCount++
 			creatingMemSize += int64(guest.VmemSize)
@@ -1200,6 +1265,7 @@ type IsolatedDeviceDesc struct {
 	Model          string
 	Addr           string
 	VendorDeviceID string
+	WireID         string
 }
 
 func (i *IsolatedDeviceDesc) VendorID() string {
@@ -1323,6 +1389,7 @@ func (b *HostBuilder) fillIsolatedDevices(desc *HostDesc, host *computemodels.SH
 			Model:          devModel.Model,
 			Addr:           devModel.Addr,
 			VendorDeviceID: devModel.VendorDeviceID,
+			WireID:         devModel.WireID,
 		}
 		devs[index] = dev
 	}
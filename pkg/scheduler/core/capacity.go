@@ -0,0 +1,165 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sort"
+)
+
+const (
+	// guard against predicates reporting unlimited capacity
+	maxCapacityCount = 100000
+
+	capacityEpsilon = 1e-9
+)
+
+// CapacityCandidate simulates placements on one candidate. Each predicate capacity
+// of a spec is treated as a budget of 1, so a guest of the spec consumes
+// 1/capacity of it and guests of different specs share the same budget.
+type CapacityCandidate struct {
+	ID   string
+	Name string
+	// Capacities of each spec by predicate name, nil if not a candidate
+	Capacities []map[string]int64
+	Counts     []int64
+	Usage      map[string]float64
+}
+
+func NewCapacityCandidate(id, name string, specCnt int) *CapacityCandidate {
+	return &CapacityCandidate{
+		ID:         id,
+		Name:       name,
+		Capacities: make([]map[string]int64, specCnt),
+		Counts:     make([]int64, specCnt),
+		Usage:      make(map[string]float64),
+	}
+}
+
+func (h *CapacityCandidate) predicates(spec int) []string {
+	names := make([]string, 0, len(h.Capacities[spec]))
+	for name := range h.Capacities[spec] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tryPlace returns the highest predicate usage after placing one more guest
+// of spec, or the predicate rejecting it
+func (h *CapacityCandidate) tryPlace(spec int) (float64, string) {
+	maxUsage := 0.0
+	for _, name := range h.predicates(spec) {
+		capacity := h.Capacities[spec][name]
+		if capacity <= 0 {
+			return -1, name
+		}
+		usage := h.Usage[name] + 1/float64(capacity)
+		if usage > 1+capacityEpsilon {
+			return -1, name
+		}
+		if usage > maxUsage {
+			maxUsage = usage
+		}
+	}
+	return maxUsage, ""
+}
+
+func (h *CapacityCandidate) place(spec int) {
+	for name, capacity := range h.Capacities[spec] {
+		h.Usage[name] += 1 / float64(capacity)
+	}
+	h.Counts[spec]++
+}
+
+func (h *CapacityCandidate) Bottleneck() string {
+	for spec := range h.Capacities {
+		if h.Capacities[spec] == nil {
+			continue
+		}
+		if _, blocker := h.tryPlace(spec); len(blocker) > 0 {
+			return blocker
+		}
+	}
+	return ""
+}
+
+// leastUsed returns the candidate of spec least used after placing one more
+// guest, like the default spreading of scheduler, or nil if none is left
+func leastUsed(hosts []*CapacityCandidate, spec int) *CapacityCandidate {
+	var selected *CapacityCandidate
+	minUsage := 0.0
+	for _, h := range hosts {
+		if h.Capacities[spec] == nil {
+			continue
+		}
+		usage, blocker := h.tryPlace(spec)
+		if len(blocker) > 0 {
+			continue
+		}
+		if selected == nil || usage < minUsage {
+			selected = h
+			minUsage = usage
+		}
+	}
+	return selected
+}
+
+func addBottlenecks(hosts []*CapacityCandidate, spec int, bottlenecks map[string]int64) {
+	for _, h := range hosts {
+		if h.Capacities[spec] == nil {
+			continue
+		}
+		if _, blocker := h.tryPlace(spec); len(blocker) > 0 {
+			bottlenecks[blocker]++
+		}
+	}
+}
+
+// SimulateCapacity first re-places the guests of the excluded hosts, evacuations
+// are the guest counts of the specs following the specs of weights. Then it
+// places the weighted mix round by round on the least used host until one
+// guest can't be placed. It returns complete rounds, the rejecting predicates
+// and the guests of the excluded hosts that can't be re-placed.
+func SimulateCapacity(hosts []*CapacityCandidate, weights []int, evacuations []int64) (int64, map[string]int64, int64) {
+	var sets, total, unplaced int64
+	bottlenecks := make(map[string]int64)
+	for i, count := range evacuations {
+		spec := len(weights) + i
+		for j := int64(0); j < count; j++ {
+			selected := leastUsed(hosts, spec)
+			if selected == nil {
+				addBottlenecks(hosts, spec, bottlenecks)
+				unplaced += count - j
+				break
+			}
+			selected.place(spec)
+		}
+	}
+	for total < maxCapacityCount {
+		for spec, weight := range weights {
+			for i := 0; i < weight; i++ {
+				selected := leastUsed(hosts, spec)
+				if selected == nil {
+					addBottlenecks(hosts, spec, bottlenecks)
+					return sets, bottlenecks, unplaced
+				}
+				selected.place(spec)
+				total++
+			}
+		}
+		sets++
+	}
+	return sets, bottlenecks, unplaced
+}
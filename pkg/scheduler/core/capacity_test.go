@@ -0,0 +1,111 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+)
+
+func newTestCapacityHost(id string, capacities ...map[string]int64) *CapacityCandidate {
+	h := NewCapacityCandidate(id, id, len(capacities))
+	for spec, c := range capacities {
+		h.Capacities[spec] = c
+	}
+	return h
+}
+
+func TestCapacityCandidate_tryPlace(t *testing.T) {
+	h := newTestCapacityHost("h1",
+		map[string]int64{"host_cpu": 4, "host_memory": 2},
+		map[string]int64{"host_cpu": 0},
+	)
+
+	usage, blocker := h.tryPlace(0)
+	if blocker != "" || usage != 0.5 {
+		t.Fatalf("first guest: usage %v blocker %q, want 0.5", usage, blocker)
+	}
+	h.place(0)
+	usage, blocker = h.tryPlace(0)
+	if blocker != "" || usage != 1 {
+		t.Fatalf("second guest: usage %v blocker %q, want 1", usage, blocker)
+	}
+	h.place(0)
+	if _, blocker = h.tryPlace(0); blocker != "host_memory" {
+		t.Errorf("third guest blocked by %q, want host_memory", blocker)
+	}
+	if _, blocker = h.tryPlace(1); blocker != "host_cpu" {
+		t.Errorf("spec without capacity blocked by %q, want host_cpu", blocker)
+	}
+	if h.Counts[0] != 2 {
+		t.Errorf("counts %v, want 2 of spec 0", h.Counts)
+	}
+}
+
+func TestSimulateCapacity(t *testing.T) {
+	cpu := func(capacity int64) map[string]int64 {
+		return map[string]int64{"host_cpu": capacity}
+	}
+	t.Run("weighted mix", func(t *testing.T) {
+		hosts := []*CapacityCandidate{
+			newTestCapacityHost("h1", cpu(4), cpu(2)),
+			newTestCapacityHost("h2", cpu(4), cpu(2)),
+		}
+		// each set takes 1/4 + 1/2 of a host
+		sets, bottlenecks, unplaced := SimulateCapacity(hosts, []int{1, 1}, nil)
+		if sets != 2 {
+			t.Errorf("sets %d, want 2", sets)
+		}
+		if unplaced != 0 {
+			t.Errorf("unplaced %d, want 0", unplaced)
+		}
+		if bottlenecks["host_cpu"] != 2 {
+			t.Errorf("bottlenecks %v, want host_cpu of both hosts", bottlenecks)
+		}
+	})
+	t.Run("evacuated guests first", func(t *testing.T) {
+		hosts := []*CapacityCandidate{
+			newTestCapacityHost("h1", cpu(4), cpu(2)),
+			newTestCapacityHost("h2", cpu(4), cpu(2)),
+		}
+		// 2 guests of the evacuated spec take half of the hosts
+		sets, _, unplaced := SimulateCapacity(hosts, []int{1}, []int64{2})
+		if unplaced != 0 {
+			t.Errorf("unplaced %d, want 0", unplaced)
+		}
+		if sets != 4 {
+			t.Errorf("sets %d, want 4", sets)
+		}
+		for _, h := range hosts {
+			if h.Counts[1] != 1 {
+				t.Errorf("host %s evacuated %d, want 1", h.ID, h.Counts[1])
+			}
+		}
+	})
+	t.Run("evacuated guests not fit", func(t *testing.T) {
+		hosts := []*CapacityCandidate{
+			newTestCapacityHost("h1", cpu(4), cpu(1)),
+		}
+		sets, bottlenecks, unplaced := SimulateCapacity(hosts, []int{1}, []int64{3})
+		if unplaced != 2 {
+			t.Errorf("unplaced %d, want 2", unplaced)
+		}
+		if sets != 0 {
+			t.Errorf("sets %d, want 0", sets)
+		}
+		if bottlenecks["host_cpu"] == 0 {
+			t.Errorf("bottlenecks %v, want host_cpu", bottlenecks)
+		}
+	})
+}
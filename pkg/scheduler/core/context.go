@@ -22,6 +22,7 @@ import (
 
 	"yunion.io/x/log"
 
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
 	"yunion.io/x/onecloud/pkg/scheduler/api"
 	"yunion.io/x/onecloud/pkg/scheduler/core/score"
 )
@@ -380,6 +381,9 @@ type Unit struct {
 	LogManager *SchedLogManager
 
 	AllocatedResources map[string]*AllocatedResource
+
+	candidateExplains map[string]*schedapi.CandidateExplain
+	explainLock       sync.Mutex
 }
 
 func NewScheduleUnit(info *api.SchedInfo, schedManager interface{}) *Unit {
@@ -399,6 +403,7 @@ func NewScheduleUnit(info *api.SchedInfo, schedManager interface{}) *Unit {
 		LogManager:             NewSchedLogManager(),
 		SchedulerManager:       schedManager,
 		AllocatedResources:     make(map[string]*AllocatedResource),
+		candidateExplains:      make(map[string]*schedapi.CandidateExplain),
 	}
 	return unit
 }
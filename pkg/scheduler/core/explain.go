@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sort"
+
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+)
+
+func toPredicateExplain(fit bool, reasons []PredicateFailureReason, err error, name string) *schedapi.PredicateExplain {
+	explain := &schedapi.PredicateExplain{
+		Name:   name,
+		Passed: fit && err == nil,
+	}
+	if err != nil {
+		explain.Reasons = []string{err.Error()}
+		return explain
+	}
+	for _, reason := range reasons {
+		explain.Reasons = append(explain.Reasons, reason.GetReason())
+	}
+	return explain
+}
+
+func (u *Unit) setPredicateExplains(c Candidater, predicates []*schedapi.PredicateExplain) {
+	u.explainLock.Lock()
+	defer u.explainLock.Unlock()
+
+	u.candidateExplains[c.IndexKey()] = &schedapi.CandidateExplain{
+		HostId:     c.IndexKey(),
+		Name:       c.Getter().Name(),
+		Predicates: predicates,
+	}
+}
+
+// Explain tells how the candidates of the unit are filtered and scored, items
+// are the result of the schedule and err is why it failed
+func (u *Unit) Explain(items []*SchedResultItem, err error) *schedapi.ScheduleExplain {
+	explain := &schedapi.ScheduleExplain{
+		SessionId: u.SessionID(),
+		Request:   u.SchedInfo.ScheduleInput,
+	}
+	if err != nil {
+		explain.Error = err.Error()
+	}
+	selected := make(map[string]int64)
+	for _, item := range items {
+		if item.Count > 0 {
+			selected[item.ID] = item.Count
+		}
+	}
+
+	u.explainLock.Lock()
+	defer u.explainLock.Unlock()
+
+	for id, c := range u.candidateExplains {
+		ce := *c
+		if score, ok := u.ScoreMap[id]; ok {
+			for _, s := range score.GetScores() {
+				ce.Scores = append(ce.Scores, &schedapi.PriorityScore{Name: s.Name, Score: int(s.Score)})
+			}
+			ce.Score = score.DigitString()
+		}
+		ce.Capacity = u.GetCapacity(id)
+		ce.Selected = selected[id]
+		explain.Candidates = append(explain.Candidates, &ce)
+	}
+	// chosen candidates first, then the ones passed all predicates
+	passed := func(c *schedapi.CandidateExplain) bool {
+		for _, p := range c.Predicates {
+			if !p.Passed {
+				return false
+			}
+		}
+		return true
+	}
+	sort.Slice(explain.Candidates, func(i, j int) bool {
+		ci, cj := explain.Candidates[i], explain.Candidates[j]
+		if ci.Selected != cj.Selected {
+			return ci.Selected > cj.Selected
+		}
+		if pi, pj := passed(ci), passed(cj); pi != pj {
+			return pi
+		}
+		return ci.Name < cj.Name
+	})
+	return explain
+}
+
+// MergeExplain merges the explain of another executor of the same session
+// into dst, a candidate evaluated by both keeps the one from src
+func MergeExplain(dst, src *schedapi.ScheduleExplain) *schedapi.ScheduleExplain {
+	if len(src.Error) > 0 {
+		dst.Error = src.Error
+	}
+	index := make(map[string]int, len(dst.Candidates))
+	for i, c := range dst.Candidates {
+		index[c.HostId] = i
+	}
+	for _, c := range src.Candidates {
+		if i, ok := index[c.HostId]; ok {
+			dst.Candidates[i] = c
+		} else {
+			index[c.HostId] = len(dst.Candidates)
+			dst.Candidates = append(dst.Candidates, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+)
+
+func TestMergeExplain(t *testing.T) {
+	tests := []struct {
+		name      string
+		dst       *schedapi.ScheduleExplain
+		src       *schedapi.ScheduleExplain
+		wantHosts []string
+		wantNames []string
+		wantError string
+	}{
+		{
+			name: "disjoint candidates are appended",
+			dst: &schedapi.ScheduleExplain{
+				Candidates: []*schedapi.CandidateExplain{{HostId: "h1", Name: "a"}},
+			},
+			src: &schedapi.ScheduleExplain{
+				Candidates: []*schedapi.CandidateExplain{{HostId: "h2", Name: "b"}},
+			},
+			wantHosts: []string{"h1", "h2"},
+			wantNames: []string{"a", "b"},
+		},
+		{
+			name: "same candidate is not duplicated",
+			dst: &schedapi.ScheduleExplain{
+				Candidates: []*schedapi.CandidateExplain{
+					{HostId: "h1", Name: "old"},
+					{HostId: "h2", Name: "b"},
+				},
+			},
+			src: &schedapi.ScheduleExplain{
+				Candidates: []*schedapi.CandidateExplain{
+					{HostId: "h1", Name: "new"},
+					{HostId: "h3", Name: "c"},
+				},
+			},
+			wantHosts: []string{"h1", "h2", "h3"},
+			wantNames: []string{"new", "b", "c"},
+		},
+		{
+			name: "error of a later executor is kept",
+			dst: &schedapi.ScheduleExplain{
+				Candidates: []*schedapi.CandidateExplain{{HostId: "h1", Name: "a"}},
+			},
+			src: &schedapi.ScheduleExplain{
+				Candidates: []*schedapi.CandidateExplain{{HostId: "h1", Name: "a"}},
+				Error:      "no enough resource",
+			},
+			wantHosts: []string{"h1"},
+			wantNames: []string{"a"},
+			wantError: "no enough resource",
+		},
+		{
+			name: "error is not cleared by a successful executor",
+			dst: &schedapi.ScheduleExplain{
+				Error: "no enough resource",
+			},
+			src: &schedapi.ScheduleExplain{
+				Candidates: []*schedapi.CandidateExplain{{HostId: "h1", Name: "a"}},
+			},
+			wantHosts: []string{"h1"},
+			wantNames: []string{"a"},
+			wantError: "no enough resource",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeExplain(tt.dst, tt.src)
+			if len(got.Candidates) != len(tt.wantHosts) {
+				t.Fatalf("got %d candidates, want %d", len(got.Candidates), len(tt.wantHosts))
+			}
+			for i, c := range got.Candidates {
+				if c.HostId != tt.wantHosts[i] || c.Name != tt.wantNames[i] {
+					t.Errorf("candidate %d = %s/%s, want %s/%s", i, c.HostId, c.Name, tt.wantHosts[i], tt.wantNames[i])
+				}
+			}
+			if got.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", got.Error, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// GangCandidate is a capacity candidate with the topology domains it belongs to
+type GangCandidate struct {
+	*CapacityCandidate
+	Candidate Candidater
+	// Domains of the candidate by spread topology, empty if unknown
+	Domains map[string]string
+}
+
+func NewGangCandidate(c Candidater, specCnt int) *GangCandidate {
+	return &GangCandidate{
+		CapacityCandidate: NewCapacityCandidate(c.IndexKey(), c.Getter().Name(), specCnt),
+		Candidate:         c,
+		Domains:           make(map[string]string),
+	}
+}
+
+type GangSpread struct {
+	Topology     string
+	MaxPerDomain int64
+}
+
+type gangPlanner struct {
+	hosts   []*GangCandidate
+	spreads []GangSpread
+	// guest count of each domain by topology
+	domainCounts map[string]map[string]int64
+}
+
+// spreadLoad returns the guests already in the domains of the host, or false
+// if one more guest would break a spread constraint
+func (p *gangPlanner) spreadLoad(h *GangCandidate) (int64, bool) {
+	var load int64
+	for _, spread := range p.spreads {
+		domain := h.Domains[spread.Topology]
+		if len(domain) == 0 {
+			return 0, false
+		}
+		cnt := p.domainCounts[spread.Topology][domain]
+		if cnt >= spread.MaxPerDomain {
+			return 0, false
+		}
+		load += cnt
+	}
+	return load, true
+}
+
+func (p *gangPlanner) place(h *GangCandidate, spec int) {
+	h.place(spec)
+	for _, spread := range p.spreads {
+		p.domainCounts[spread.Topology][h.Domains[spread.Topology]]++
+	}
+}
+
+// reason tells why no candidate is left for one more guest of spec
+func (p *gangPlanner) reason(spec int) string {
+	blockers := make(map[string]int)
+	for _, h := range p.hosts {
+		if h.Capacities[spec] == nil {
+			continue
+		}
+		if _, blocker := h.tryPlace(spec); len(blocker) > 0 {
+			blockers[blocker]++
+		} else {
+			blockers["spread"]++
+		}
+	}
+	if len(blockers) == 0 {
+		return "no candidate"
+	}
+	names := make([]string, 0, len(blockers))
+	for name := range blockers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reasons := make([]string, 0, len(names))
+	for _, name := range names {
+		reasons = append(reasons, fmt.Sprintf("%s(-%d)", name, blockers[name]))
+	}
+	return fmt.Sprintf("%v", reasons)
+}
+
+// PlanGang places counts[spec] guests of every spec, the spec with the fewest
+// candidates first, each guest on the candidate with the least guests in its
+// domains and then the least used. It returns the candidate of every guest of
+// each spec, or an error if any guest can't be placed.
+func PlanGang(hosts []*GangCandidate, counts []int64, spreads []GangSpread) ([][]*GangCandidate, error) {
+	p := &gangPlanner{
+		hosts:        hosts,
+		spreads:      spreads,
+		domainCounts: make(map[string]map[string]int64),
+	}
+	for _, spread := range spreads {
+		p.domainCounts[spread.Topology] = make(map[string]int64)
+	}
+
+	specs := make([]int, len(counts))
+	eligible := make([]int, len(counts))
+	for spec := range counts {
+		specs[spec] = spec
+		for _, h := range hosts {
+			if h.Capacities[spec] != nil {
+				eligible[spec]++
+			}
+		}
+	}
+	sort.SliceStable(specs, func(i, j int) bool { return eligible[specs[i]] < eligible[specs[j]] })
+
+	plan := make([][]*GangCandidate, len(counts))
+	for _, spec := range specs {
+		for i := int64(0); i < counts[spec]; i++ {
+			var (
+				selected *GangCandidate
+				minLoad  int64
+				minUsage float64
+			)
+			for _, h := range hosts {
+				if h.Capacities[spec] == nil {
+					continue
+				}
+				usage, blocker := h.tryPlace(spec)
+				if len(blocker) > 0 {
+					continue
+				}
+				load, ok := p.spreadLoad(h)
+				if !ok {
+					continue
+				}
+				if selected == nil || load < minLoad || (load == minLoad && usage < minUsage) {
+					selected = h
+					minLoad = load
+					minUsage = usage
+				}
+			}
+			if selected == nil {
+				return nil, fmt.Errorf("guest %d of member %d can't be placed: %s", i+1, spec, p.reason(spec))
+			}
+			p.place(selected, spec)
+			plan[spec] = append(plan[spec], selected)
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestGangHost(id string, domains map[string]string, capacities ...map[string]int64) *GangCandidate {
+	h := &GangCandidate{
+		CapacityCandidate: NewCapacityCandidate(id, id, len(capacities)),
+		Domains:           domains,
+	}
+	for spec, c := range capacities {
+		h.Capacities[spec] = c
+	}
+	return h
+}
+
+func planHostIds(placed []*GangCandidate) []string {
+	ids := make([]string, 0, len(placed))
+	for _, h := range placed {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
+
+func TestPlanGang(t *testing.T) {
+	cpu := func(capacity int64) map[string]int64 {
+		return map[string]int64{"host_cpu": capacity}
+	}
+	rack := func(name string) map[string]string {
+		return map[string]string{"rack-": name}
+	}
+	tests := []struct {
+		name    string
+		hosts   []*GangCandidate
+		counts  []int64
+		spreads []GangSpread
+		want    [][]string
+		wantErr string
+	}{
+		{
+			name: "least used host first",
+			hosts: []*GangCandidate{
+				newTestGangHost("h1", nil, cpu(4)),
+				newTestGangHost("h2", nil, cpu(2)),
+			},
+			counts: []int64{3},
+			want:   [][]string{{"h1", "h1", "h2"}},
+		},
+		{
+			name: "all or nothing",
+			hosts: []*GangCandidate{
+				newTestGangHost("h1", nil, cpu(1)),
+				newTestGangHost("h2", nil, cpu(1)),
+			},
+			counts:  []int64{3},
+			wantErr: "host_cpu(-2)",
+		},
+		{
+			name: "spread over the least loaded domain",
+			hosts: []*GangCandidate{
+				newTestGangHost("h1", rack("rack-a"), cpu(10)),
+				newTestGangHost("h2", rack("rack-a"), cpu(10)),
+				newTestGangHost("h3", rack("rack-b"), cpu(10)),
+			},
+			counts:  []int64{4},
+			spreads: []GangSpread{{Topology: "rack-", MaxPerDomain: 2}},
+			want:    [][]string{{"h1", "h3", "h2", "h3"}},
+		},
+		{
+			name: "spread max per domain reached",
+			hosts: []*GangCandidate{
+				newTestGangHost("h1", rack("rack-a"), cpu(10)),
+				newTestGangHost("h2", rack("rack-b"), cpu(10)),
+			},
+			counts:  []int64{3},
+			spreads: []GangSpread{{Topology: "rack-", MaxPerDomain: 1}},
+			wantErr: "spread(-2)",
+		},
+		{
+			name: "host without domain is skipped by spread",
+			hosts: []*GangCandidate{
+				newTestGangHost("h1", rack("rack-a"), cpu(1)),
+				newTestGangHost("h2", map[string]string{}, cpu(10)),
+			},
+			counts:  []int64{2},
+			spreads: []GangSpread{{Topology: "rack-", MaxPerDomain: 2}},
+			wantErr: "guest 2 of member 0",
+		},
+		{
+			name: "member with fewest candidates first",
+			hosts: []*GangCandidate{
+				newTestGangHost("h1", nil, cpu(1), cpu(1)),
+				newTestGangHost("h2", nil, cpu(1), nil),
+			},
+			counts: []int64{1, 1},
+			want:   [][]string{{"h2"}, {"h1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanGang(tt.hosts, tt.counts, tt.spreads)
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("want error %q, got plan %v", tt.wantErr, plan)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %q doesn't contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			for spec := range tt.want {
+				got := planHostIds(plan[spec])
+				if strings.Join(got, ",") != strings.Join(tt.want[spec], ",") {
+					t.Errorf("member %d placed on %v, want %v", spec, got, tt.want[spec])
+				}
+			}
+		})
+	}
+}
@@ -499,6 +499,7 @@ func unitFitsOnCandidate(
 		err     error
 		fcs     []FailedCandidate
 		logs    []SchedLog
+		results []*schedapi.PredicateExplain
 	)
 
 	isFit := true
@@ -506,6 +507,7 @@ func unitFitsOnCandidate(
 		if len(logs) > 0 {
 			unit.LogManager.Appends(logs)
 		}
+		unit.setPredicateExplains(candidate, results)
 	}()
 
 	toLog := func(fit bool, reasons []PredicateFailureReason,
@@ -541,6 +543,7 @@ func unitFitsOnCandidate(
 	for _, predicate := range predicates {
 		fit, reasons, err = predicate.Execute(unit, candidate)
 		logs = append(logs, toLog(fit, reasons, err, predicate.Name()))
+		results = append(results, toPredicateExplain(fit, reasons, err, predicate.Name()))
 		if err != nil {
 			return false, nil, err
 		}
@@ -31,11 +31,11 @@ type ReservedPoolManager struct {
 
 func (pm *ReservedPoolManager) GetPool(name string) (*ReservedPool, error) {
 	pm.RLock()
+	defer pm.RUnlock()
 	pool, ok := pm.pools[name]
 	if !ok {
 		return nil, fmt.Errorf("reserved pool %v not found", name)
 	}
-	pm.RUnlock()
 	return pool, nil
 }
 
@@ -85,6 +85,19 @@ func (pm *ReservedPoolManager) RemoveSession(sessionId string) bool {
 	return false
 }
 
+// GetReservedItem returns the resources reserved on the candidate by all the
+// sessions, or nil if nothing is reserved
+func (pm *ReservedPoolManager) GetReservedItem(candidateId string) *ReservedItem {
+	pm.RLock()
+	defer pm.RUnlock()
+	for _, pool := range pm.pools {
+		if item := pool.GetReservedItem(candidateId); item != nil {
+			return item
+		}
+	}
+	return nil
+}
+
 func ReservedSubtract(key string, value value_t, reserved value_t) value_t {
 	var al ResAlgorithm = GetResAlgorithm(key)
 	if al != nil {
@@ -61,7 +61,7 @@ func (item *ReservedItem) Set(key string, value value_t) {
 
 func (item *ReservedItem) set(key string, value value_t) {
 	item.Lock()
-	item.Unlock()
+	defer item.Unlock()
 
 	item.data[key] = value
 }
@@ -169,6 +169,14 @@ func (ci *CandidateItem) set(sessionID string, reservedItem *ReservedItem) {
 	ci.dirty = true
 }
 
+func (ci *CandidateItem) remove(sessionID string) int {
+	ci.Lock()
+	defer ci.Unlock()
+	delete(ci.data, sessionID)
+	ci.dirty = true
+	return len(ci.data)
+}
+
 func NewCandidateItem(candidateID string) *CandidateItem {
 	return &CandidateItem{
 		candidateID: candidateID,
@@ -248,8 +256,9 @@ func (pool *ReservedPool) Add(sessionID string, candidateID string,
 }
 
 func (pool *ReservedPool) GetReservedItem(candidateID string) *ReservedItem {
-	pool.RLock()
-	defer pool.RUnlock()
+	// the result is cached on the candidate item
+	pool.Lock()
+	defer pool.Unlock()
 	candidateItem, ok := pool.candidateDict[candidateID]
 	if !ok {
 		return nil
@@ -286,8 +295,8 @@ func (pool *ReservedPool) removeSession(sessionID string) bool {
 		} else {
 			for _, candidateId := range sessionItem.AllCandidateIDs() {
 				if candidateItem, ok := pool.candidateDict[candidateId]; ok {
-					if _, ok := candidateItem.data[sessionID]; ok {
-						delete(candidateItem.data, sessionID)
+					if candidateItem.remove(sessionID) == 0 {
+						delete(pool.candidateDict, candidateId)
 					}
 				}
 			}
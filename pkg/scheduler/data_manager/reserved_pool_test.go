@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package data_manager
+
+import (
+	"testing"
+)
+
+func newTestReservedItem(candidateId string, cpu int64) *ReservedItem {
+	item := NewReservedItem(candidateId)
+	item.Set("FreeCPUCount", cpu)
+	return item
+}
+
+func TestReservedPool_GetReservedItem(t *testing.T) {
+	pool := NewReservedPool("host", nil)
+	pool.Add("s1", "h1", newTestReservedItem("h1", 2))
+	pool.Add("s2", "h1", newTestReservedItem("h1", 3))
+	pool.Add("s2", "h2", newTestReservedItem("h2", 1))
+
+	getCPU := func(candidateId string) int64 {
+		item := pool.GetReservedItem(candidateId)
+		if item == nil {
+			return 0
+		}
+		return item.Get("FreeCPUCount", int64(0)).(int64)
+	}
+
+	if got := getCPU("h1"); got != 5 {
+		t.Errorf("reserved cpu of h1 = %d, want 5", got)
+	}
+	if !pool.RemoveSession("s2") {
+		t.Fatalf("session s2 not removed")
+	}
+	if got := getCPU("h1"); got != 2 {
+		t.Errorf("reserved cpu of h1 after s2 removed = %d, want 2", got)
+	}
+	if pool.InSession("h2") {
+		t.Errorf("h2 still in session after s2 removed")
+	}
+	if pool.RemoveSession("s2") {
+		t.Errorf("session s2 removed twice")
+	}
+	pool.RemoveSession("s1")
+	if got := getCPU("h1"); got != 0 {
+		t.Errorf("reserved cpu of h1 after all sessions removed = %d, want 0", got)
+	}
+}
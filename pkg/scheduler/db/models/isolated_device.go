@@ -26,6 +26,7 @@ type IsolatedDevice struct {
 	GuestID        string `json:"guest_id" gorm:"column:guest_id"`
 	Addr           string `json:"addr" gorm:"column:addr"`
 	VendorDeviceID string `json:"vendor_device_id" gorm:"column:vendor_device_id"`
+	WireID         string `json:"wire_id" gorm:"column:wire_id"`
 }
 
 func (d IsolatedDevice) TableName() string {
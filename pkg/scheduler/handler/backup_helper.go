@@ -25,7 +25,7 @@ import (
 	schedman "yunion.io/x/onecloud/pkg/scheduler/manager"
 )
 
-func transToBackupSchedResult(result *core.SchedResultItemList, preferMasterHost, preferBackupHost string, count int64) interface{} {
+func transToBackupSchedResult(result *core.SchedResultItemList, preferMasterHost, preferBackupHost string, count int64) *schedapi.ScheduleOutput {
 	// clean each result sched result item's count
 	for _, item := range result.Data {
 		item.Count = 0
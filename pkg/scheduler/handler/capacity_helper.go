@@ -0,0 +1,199 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	gin "gopkg.in/gin-gonic/gin.v1"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/utils"
+
+	computedb "yunion.io/x/onecloud/pkg/cloudcommon/db"
+	computemodels "yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/cache/candidate"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	schedman "yunion.io/x/onecloud/pkg/scheduler/manager"
+)
+
+func isExcludedHost(excludes []string, id, name string) bool {
+	return utils.IsInStringArray(id, excludes) || utils.IsInStringArray(name, excludes)
+}
+
+func doSchedulerCapacity(c *gin.Context) {
+	if !schedman.IsReady() {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("Global scheduler not init"))
+		return
+	}
+
+	args, err := api.FetchCapacityArgs(c.Request)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	results := make([]*core.SchedResultItemList, 0, len(args.Specs))
+	excludedIds := make([]string, 0)
+	for _, schedInfo := range args.Specs {
+		result, err := schedman.Schedule(schedInfo)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		for _, item := range result.Data {
+			if isExcludedHost(args.ExcludeHosts, item.ID, item.Name) && !utils.IsInStringArray(item.ID, excludedIds) {
+				excludedIds = append(excludedIds, item.ID)
+			}
+		}
+		results = append(results, result)
+	}
+	// the guests of the excluded hosts take the capacity first
+	evacuations, counts, err := fetchEvacuationSpecs(excludedIds)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	specs := append(args.Specs, evacuations...)
+	for _, schedInfo := range evacuations {
+		result, err := schedman.Schedule(schedInfo)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		results = append(results, result)
+	}
+
+	hostMap := make(map[string]*core.CapacityCandidate)
+	hosts := make([]*core.CapacityCandidate, 0)
+	excluded := make([]string, 0)
+	for spec, schedInfo := range specs {
+		for _, item := range results[spec].Data {
+			if item.Candidater.Getter().HostType() != schedInfo.Hypervisor {
+				continue
+			}
+			if isExcludedHost(args.ExcludeHosts, item.ID, item.Name) {
+				if !utils.IsInStringArray(item.Name, excluded) {
+					excluded = append(excluded, item.Name)
+				}
+				continue
+			}
+			h, ok := hostMap[item.ID]
+			if !ok {
+				h = core.NewCapacityCandidate(item.ID, item.Name, len(specs))
+				hostMap[item.ID] = h
+				hosts = append(hosts, h)
+			}
+			capacities := item.CapacityDetails
+			if len(capacities) == 0 {
+				capacities = map[string]int64{"capacity": item.Capacity}
+			}
+			h.Capacities[spec] = capacities
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+
+	sets, bottlenecks, unplaced := core.SimulateCapacity(hosts, args.Weights, counts)
+	result := transToSchedCapacityResult(hosts, args.Weights, sets, bottlenecks, excluded)
+	for _, cnt := range counts {
+		result.EvacuatedGuests += cnt
+	}
+	result.EvacuatedGuests -= unplaced
+	result.UnplacedGuests = unplaced
+	c.JSON(http.StatusOK, result)
+}
+
+// fetchEvacuationSpecs returns the guests of the excluded hosts to re-place
+// as migrations, the guests asking for the same are one spec with its count
+func fetchEvacuationSpecs(hostIds []string) ([]*api.SchedInfo, []int64, error) {
+	if len(hostIds) == 0 {
+		return nil, nil, nil
+	}
+	guests := make([]computemodels.SGuest, 0)
+	q := computemodels.GuestManager.Query().In("host_id", hostIds)
+	err := computedb.FetchModelObjects(computemodels.GuestManager, q, &guests)
+	if err != nil {
+		return nil, nil, err
+	}
+	specs := make([]*api.SchedInfo, 0)
+	counts := make([]int64, 0)
+	index := make(map[string]int)
+	for i := range guests {
+		if candidate.IsGuestPendingDelete(guests[i]) {
+			continue
+		}
+		input := guests[i].ToSchedDesc()
+		input.Id = ""
+		input.Name = ""
+		key := jsonutils.Marshal(input).String()
+		if idx, ok := index[key]; ok {
+			counts[idx]++
+			continue
+		}
+		input.Count = 1
+		info := api.NewSchedInfo(input)
+		info.IsSuggestion = true
+		info.ShowSuggestionDetails = true
+		info.SuggestionAll = true
+		info.SuggestionLimit = math.MaxInt32
+		index[key] = len(specs)
+		specs = append(specs, info)
+		counts = append(counts, 1)
+	}
+	return specs, counts, nil
+}
+
+func transToSchedCapacityResult(hosts []*core.CapacityCandidate, weights []int, sets int64, bottlenecks map[string]int64, excluded []string) *api.SchedCapacityResult {
+	result := &api.SchedCapacityResult{
+		Sets:          sets,
+		Specs:         make([]api.CapacitySpecResult, len(weights)),
+		Hosts:         make([]api.CapacityHost, 0),
+		Bottlenecks:   bottlenecks,
+		ExcludedHosts: excluded,
+	}
+	for spec, weight := range weights {
+		result.Specs[spec] = api.CapacitySpecResult{Index: spec, Weight: weight}
+	}
+	for _, h := range hosts {
+		host := api.CapacityHost{
+			ID:         h.ID,
+			Name:       h.Name,
+			Counts:     h.Counts[:len(weights)],
+			Usage:      h.Usage,
+			Bottleneck: h.Bottleneck(),
+		}
+		for spec, cnt := range h.Counts {
+			if spec >= len(weights) {
+				host.Evacuated += cnt
+				continue
+			}
+			result.Specs[spec].Count += cnt
+			result.MaxCount += cnt
+		}
+		result.Hosts = append(result.Hosts, host)
+	}
+	var maxCnt int64
+	for name, cnt := range bottlenecks {
+		if cnt > maxCnt || (cnt == maxCnt && name < result.Bottleneck) {
+			result.Bottleneck = name
+			maxCnt = cnt
+		}
+	}
+	return result
+}
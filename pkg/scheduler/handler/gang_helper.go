@@ -0,0 +1,214 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	gin "gopkg.in/gin-gonic/gin.v1"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	schedman "yunion.io/x/onecloud/pkg/scheduler/manager"
+)
+
+// gangLock keeps gang plans from reserving the same candidates
+var gangLock sync.Mutex
+
+func doSchedulerGang(c *gin.Context) {
+	if !schedman.IsReady() {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("Global scheduler not init"))
+		return
+	}
+
+	args, err := api.FetchGangArgs(c.Request)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	output, err := scheduleGang(args, true)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// gangDomain returns the domain of candidate in topology, a schedtag topology
+// is the name prefix of the schedtags telling the domain
+func gangDomain(c core.Candidater, topology string) string {
+	getter := c.Getter()
+	switch topology {
+	case computeapi.SPREAD_TOPOLOGY_HOST:
+		return c.IndexKey()
+	case computeapi.SPREAD_TOPOLOGY_ZONE:
+		if zone := getter.Zone(); zone != nil {
+			return zone.GetId()
+		}
+		return ""
+	}
+	domains := make([]string, 0)
+	for _, tag := range getter.HostSchedtags() {
+		if strings.HasPrefix(tag.Name, topology) {
+			domains = append(domains, tag.Name)
+		}
+	}
+	if len(domains) == 0 {
+		return ""
+	}
+	sort.Strings(domains)
+	return domains[0]
+}
+
+// scheduleGang plans the gang, the plan is kept in the reserved pool if reserve,
+// otherwise the guests are created right away and the selected candidates are
+// dirty until they are reloaded with the guests
+func scheduleGang(args *api.GangArgs, reserve bool) (*schedapi.GangOutput, error) {
+	gangLock.Lock()
+	defer gangLock.Unlock()
+
+	if len(args.SessionId) == 0 {
+		args.SessionId = schedman.NewSessionID()
+	} else if !args.DryRun {
+		// the session is planned again or carried out, its former
+		// reservation is not taken as used by the new plan
+		schedman.ReleaseReservation(args.SessionId)
+	}
+
+	hostMap := make(map[string]*core.GangCandidate)
+	hosts := make([]*core.GangCandidate, 0)
+	items := make([]map[string]*core.SchedResultItem, len(args.Members))
+	for spec, schedInfo := range args.Members {
+		result, err := schedman.Schedule(schedInfo)
+		if err != nil {
+			return nil, fmt.Errorf("member %d: %v", spec, err)
+		}
+		items[spec] = make(map[string]*core.SchedResultItem)
+		for _, item := range result.Data {
+			if item.Capacity <= 0 {
+				continue
+			}
+			h, ok := hostMap[item.ID]
+			if !ok {
+				h = core.NewGangCandidate(item.Candidater, len(args.Members))
+				for _, spread := range args.Spreads {
+					h.Domains[spread.Topology] = gangDomain(item.Candidater, spread.Topology)
+				}
+				hostMap[item.ID] = h
+				hosts = append(hosts, h)
+			}
+			capacities := item.CapacityDetails
+			if len(capacities) == 0 {
+				capacities = map[string]int64{"capacity": item.Capacity}
+			}
+			h.Capacities[spec] = capacities
+			items[spec][item.ID] = item
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+
+	spreads := make([]core.GangSpread, 0, len(args.Spreads))
+	for _, spread := range args.Spreads {
+		spreads = append(spreads, core.GangSpread{Topology: spread.Topology, MaxPerDomain: int64(spread.MaxPerDomain)})
+	}
+	plan, err := core.PlanGang(hosts, args.Counts, spreads)
+	if err != nil {
+		return nil, fmt.Errorf("%v, session_id=%q", err, args.SessionId)
+	}
+
+	output := &schedapi.GangOutput{SessionId: args.SessionId}
+	selectedMap := make(map[string]*core.SelectedCandidate)
+	selected := make([]*core.SelectedCandidate, 0)
+	reserved := make(map[string]map[string]interface{})
+	for spec, placed := range plan {
+		schedInfo := args.Members[spec]
+		localSize := schedInfo.AllDiskBackendSize()[computeapi.STORAGE_LOCAL]
+		memberOutput := &schedapi.ScheduleOutput{}
+		for _, h := range placed {
+			memberOutput.Candidates = append(memberOutput.Candidates, items[spec][h.ID].ToCandidateResource())
+			sc, ok := selectedMap[h.ID]
+			if !ok {
+				sc = &core.SelectedCandidate{Candidate: h.Candidate}
+				selectedMap[h.ID] = sc
+				selected = append(selected, sc)
+				reserved[h.ID] = map[string]interface{}{
+					"FreeCPUCount":         int64(0),
+					"FreeMemSize":          int64(0),
+					"FreeLocalStorageSize": int64(0),
+				}
+			}
+			sc.Count++
+			res := reserved[h.ID]
+			res["FreeCPUCount"] = res["FreeCPUCount"].(int64) + int64(schedInfo.Ncpu)
+			res["FreeMemSize"] = res["FreeMemSize"].(int64) + int64(schedInfo.Memory)
+			res["FreeLocalStorageSize"] = res["FreeLocalStorageSize"].(int64) + localSize
+		}
+		output.Members = append(output.Members, memberOutput)
+	}
+
+	if args.DryRun {
+		return output, nil
+	}
+	if !reserve {
+		schedman.DirtyCandidates(selected)
+		return output, nil
+	}
+	err = schedman.ReserveCandidates(args.ReservedPoolName(), args.SessionId, selected, reserved)
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func doGangSyncSchedule(schedInfo *api.SchedInfo, count int64) *schedapi.ScheduleOutput {
+	args, err := api.NewGangArgs(schedInfo.SessionId, []*schedapi.ScheduleInput{schedInfo.ScheduleInput}, schedInfo.Spreads, false)
+	if err != nil {
+		return transToFailedSchedResult(err.Error(), count)
+	}
+	output, err := scheduleGang(args, false)
+	if err != nil {
+		return transToFailedSchedResult(err.Error(), count)
+	}
+	return output.Members[0]
+}
+
+func doReservedResources(c *gin.Context) {
+	if !schedman.IsReady() {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("Global scheduler not init"))
+		return
+	}
+
+	args, err := api.FetchReservedResourcesArgs(c.Request)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if len(args.Remove) > 0 {
+		schedman.CompletedNotify(&api.CompletedNotifyArgs{SessionID: args.Remove})
+	}
+	resources, err := schedman.GetReservedResources(args.Name)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, &api.ReservedResourcesResult{Resources: resources})
+}
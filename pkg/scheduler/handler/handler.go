@@ -28,6 +28,7 @@ import (
 	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
 	computemodels "yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/cache/candidate"
 	"yunion.io/x/onecloud/pkg/scheduler/core"
 	"yunion.io/x/onecloud/pkg/scheduler/db/models"
 	schedman "yunion.io/x/onecloud/pkg/scheduler/manager"
@@ -71,14 +72,18 @@ func schedulerActionHandler(c *gin.Context) {
 		doSchedulerForecast(c)
 	case "candidate-list":
 		doCandidateList(c)
+	case "capacity":
+		doSchedulerCapacity(c)
+	case "gang":
+		doSchedulerGang(c)
 	case "cleanup":
 		doCleanup(c)
 	case "history-list":
 		doHistoryList(c)
 	case "clean-cache":
 		doCleanAllHostCache(c)
-	//case "reserved-resources":
-	//doReservedResources(c)
+	case "reserved-resources":
+		doReservedResources(c)
 	default:
 		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("action: %s not support", act))
 	}
@@ -94,6 +99,8 @@ func schedulerActionIdentHandler(c *gin.Context) {
 		doCandidateDetail(c, id)
 	case "history-detail":
 		doHistoryDetail(c, id)
+	case "explain":
+		doExplain(c, id)
 	case "completed":
 		doCompleted(c, id)
 	default:
@@ -267,6 +274,15 @@ func doHistoryDetail(c *gin.Context, id string) {
 	c.JSON(http.StatusOK, result)
 }
 
+func doExplain(c *gin.Context, sessionId string) {
+	explain, err := schedman.GetExplain(sessionId)
+	if err != nil {
+		c.AbortWithError(http.StatusNotFound, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{"explain": explain})
+}
+
 func doSyncSchedule(c *gin.Context) {
 	if !schedman.IsReady() {
 		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("Global scheduler not init"))
@@ -278,23 +294,86 @@ func doSyncSchedule(c *gin.Context) {
 		return
 	}
 
-	result, err := schedman.Schedule(schedInfo)
+	count := int64(schedInfo.Count)
+	if !schedInfo.Backup && (schedInfo.Gang || len(schedInfo.Spreads) > 0) {
+		c.JSON(http.StatusOK, doGangSyncSchedule(schedInfo, count))
+		return
+	}
+
+	result, explain, err := schedman.ScheduleWithExplain(schedInfo)
+	if err != nil && schedInfo.CanPreempt() {
+		// retry taking the resources of preemptible guests as free
+		log.Infof("Schedule %s failed: %v, retry with preemption", schedInfo.SessionId, err)
+		schedInfo.Preempt = true
+		result, explain, err = schedman.ScheduleWithExplain(schedInfo)
+	}
 	if err != nil {
+		// the explain of the failure is fetched by session id
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
 
-	count := int64(schedInfo.Count)
-	var resp interface{}
+	var resp *schedapi.ScheduleOutput
 	if schedInfo.Backup {
 		resp = transToBackupSchedResult(result, schedInfo.HostId, schedInfo.PreferBackupHost, count)
 	} else {
+		var victims map[string][]string
+		if schedInfo.Preempt {
+			victims = preemptVictims(schedInfo, result.Data)
+		}
 		resp = transToRegionSchedResult(result.Data, count)
+		setPreemptGuests(resp, victims)
 	}
+	resp.Explain = explain
 
 	c.JSON(http.StatusOK, resp)
 }
 
+// transToFailedSchedResult fails every guest of a sync schedule request with
+// the reason
+func transToFailedSchedResult(reason string, count int64) *schedapi.ScheduleOutput {
+	apiResults := make([]*schedapi.CandidateResource, 0, count)
+	for i := int64(0); i < count; i++ {
+		apiResults = append(apiResults, &schedapi.CandidateResource{Error: reason})
+	}
+	return &schedapi.ScheduleOutput{
+		Candidates: apiResults,
+	}
+}
+
+// preemptVictims returns the preemptible guests to evict of each host the
+// guests are placed on
+func preemptVictims(schedInfo *api.SchedInfo, result []*core.SchedResultItem) map[string][]string {
+	useRsvd := schedInfo.UseReserved()
+	ret := make(map[string][]string)
+	for _, item := range result {
+		if item.Count <= 0 {
+			continue
+		}
+		host, ok := item.Candidater.(*candidate.HostDesc)
+		if !ok {
+			continue
+		}
+		cpu := item.Count * int64(schedInfo.Ncpu)
+		mem := item.Count * int64(schedInfo.Memory)
+		for _, guest := range host.PreemptVictims(cpu, mem, useRsvd) {
+			ret[item.ID] = append(ret[item.ID], guest.Id)
+		}
+	}
+	return ret
+}
+
+// setPreemptGuests lets every guest placed on a host wait for the eviction
+// of the victims of the host, the guests are created in parallel and none of
+// them fits before all the victims are gone
+func setPreemptGuests(resp *schedapi.ScheduleOutput, victims map[string][]string) {
+	for _, c := range resp.Candidates {
+		if guests, ok := victims[c.HostId]; ok {
+			c.PreemptGuests = guests
+		}
+	}
+}
+
 func transToRegionSchedResult(result []*core.SchedResultItem, count int64) *schedapi.ScheduleOutput {
 	apiResults := make([]*schedapi.CandidateResource, 0)
 	succCount := 0
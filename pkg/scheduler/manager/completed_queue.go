@@ -44,27 +44,30 @@ func (c *CompletedManager) Run() {
 	t := time.Tick(utils.ToDuration(o.GetOptions().CompletedQueueConsumptionPeriod))
 
 	removeSession := func() {
-		//completedNotifyArgs := <-c.completedChannel
-		//pool, err := schedManager.ReservedPoolManager.SearchReservedPoolBySessionID(completedNotifyArgs.SessionID)
-		//if err != nil {
-		//log.Errorln(err)
-		//return
-		//}
-
-		//sessionItem := pool.GetSessionItem(completedNotifyArgs.SessionID)
-		//if sessionItem == nil {
-		//log.Errorln(fmt.Errorf("session %v not found\n", completedNotifyArgs.SessionID))
-		//return
-		//}
-		//candidateIds := sessionItem.AllCandidateIDs()
-
-		// load candidates
-		//if len(candidateIds) > 0 {
-		//schedManager.CandidateManager.Reload(pool.Name, candidateIds)
-		//}
+		completedNotifyArgs := <-c.completedChannel
+		pool, err := schedManager.ReservedPoolManager.SearchReservedPoolBySessionID(completedNotifyArgs.SessionID)
+		if err != nil {
+			// most of the sessions reserve nothing
+			return
+		}
+
+		sessionItem := pool.GetSessionItem(completedNotifyArgs.SessionID)
+		if sessionItem == nil {
+			// the session expired meanwhile
+			return
+		}
+		candidateIds := sessionItem.AllCandidateIDs()
+
+		// load candidates with the guests of the session
+		if len(candidateIds) > 0 {
+			_, err := schedManager.CandidateManager.Reload(pool.Name, candidateIds)
+			if err != nil {
+				log.Errorln(err)
+			}
+		}
 
 		// remove session
-		//pool.RemoveSession(completedNotifyArgs.SessionID)
+		pool.RemoveSession(completedNotifyArgs.SessionID)
 	}
 
 	reloadAndRemoveSessions := func() {
@@ -25,6 +25,7 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/utils"
 
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
 	"yunion.io/x/onecloud/pkg/scheduler/api"
 	"yunion.io/x/onecloud/pkg/scheduler/cache/candidate"
 	candidatecache "yunion.io/x/onecloud/pkg/scheduler/cache/candidate"
@@ -44,9 +45,9 @@ type SchedulerManager struct {
 	HistoryManager   *HistoryManager
 	TaskManager      *TaskManager
 
-	DataManager      *data_manager.DataManager
-	CandidateManager *data_manager.CandidateManager
-	//ReservedPoolManager *data_manager.ReservedPoolManager
+	DataManager         *data_manager.DataManager
+	CandidateManager    *data_manager.CandidateManager
+	ReservedPoolManager *data_manager.ReservedPoolManager
 	//NetworkManager   *data_manager.NetworkManager
 	KubeClusterManager *k8s.SKubeClusterManager
 }
@@ -59,7 +60,7 @@ func NewSchedulerManager(stopCh <-chan struct{}) *SchedulerManager {
 	sm.CompletedManager = NewCompletedManager(stopCh)
 	sm.HistoryManager = NewHistoryManager(stopCh)
 	sm.TaskManager = NewTaskManager(stopCh)
-	//sm.ReservedPoolManager = data_manager.NewReservedPoolManager(stopCh)
+	sm.ReservedPoolManager = data_manager.NewReservedPoolManager(stopCh)
 	//sm.NetworkManager = data_manager.NewNetworkManager(sm.DataManager, sm.ReservedPoolManager)
 	sm.KubeClusterManager = k8s.NewKubeClusterManager(o.GetOptions().Region, 30*time.Second)
 
@@ -92,7 +93,6 @@ func (sm *SchedulerManager) start() {
 		sm.TaskManager.Run,
 		sm.DataManager.Run,
 		sm.CandidateManager.Run,
-		//sm.ReservedPoolManager.Run,
 		//sm.NetworkManager.Run,
 		sm.KubeClusterManager.Start,
 	}
@@ -102,19 +102,24 @@ func (sm *SchedulerManager) start() {
 }
 
 func (sm *SchedulerManager) schedule(info *api.SchedInfo) (*core.SchedResultItemList, error) {
+	results, _, err := sm.scheduleWithExplain(info)
+	return results, err
+}
+
+func (sm *SchedulerManager) scheduleWithExplain(info *api.SchedInfo) (*core.SchedResultItemList, *schedapi.ScheduleExplain, error) {
 	log.V(10).Infof("SchedulerManager do schedule, input: %#v", info)
 	task, err := sm.TaskManager.AddTask(sm, info)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	sm.HistoryManager.NewHistoryItem(task)
 	results, err := task.Wait()
 	if err != nil {
-		return nil, err
+		return nil, task.GetExplain(), err
 	}
 	log.V(10).Infof("SchedulerManager finish schedule, selected candidates: %#v", results)
-	return results, nil
+	return results, task.GetExplain(), nil
 }
 
 // NewSessionID returns the current timestamp of a string type with precision of
@@ -133,6 +138,15 @@ func Schedule(info *api.SchedInfo) (*core.SchedResultItemList, error) {
 	return schedManager.schedule(info)
 }
 
+// ScheduleWithExplain is Schedule also returning the explain of the decision,
+// which is available even if the schedule failed
+func ScheduleWithExplain(info *api.SchedInfo) (*core.SchedResultItemList, *schedapi.ScheduleExplain, error) {
+	if len(info.SessionId) == 0 {
+		info.SessionId = NewSessionID()
+	}
+	return schedManager.scheduleWithExplain(info)
+}
+
 func IsReady() bool {
 	return schedManager != nil
 }
@@ -151,6 +165,58 @@ func CompletedNotify(completedNotifyArgs *api.CompletedNotifyArgs) (*api.Complet
 	return &api.CompletedNotifyResult{}, nil
 }
 
+// ReserveCandidates keeps the reserved resources of the selected candidates in
+// the reserved pool of the session, the capacity predicates take them as used
+// until the session is completed or expires
+func ReserveCandidates(resType, sessionId string, selected []*core.SelectedCandidate, reserved map[string]map[string]interface{}) error {
+	pool, err := schedManager.ReservedPoolManager.GetPool(resType)
+	if err != nil {
+		return err
+	}
+	for _, sc := range selected {
+		id := sc.Candidate.IndexKey()
+		item := data_manager.NewReservedItem(id)
+		item.SetAll(reserved[id])
+		pool.Add(sessionId, id, item)
+	}
+	return nil
+}
+
+// ReleaseReservation drops the resources reserved by the session right away
+func ReleaseReservation(sessionId string) bool {
+	return schedManager.ReservedPoolManager.RemoveSession(sessionId)
+}
+
+// DirtyCandidates keeps the selected candidates out of other schedules until
+// they are expired and reloaded with the created guests
+func DirtyCandidates(selected []*core.SelectedCandidate) {
+	for _, sc := range selected {
+		schedManager.CandidateManager.SetCandidateDirty(sc)
+	}
+}
+
+// GetReservedResource returns the resource of key reserved on the candidate
+// by all the sessions
+func (sm *SchedulerManager) GetReservedResource(candidateId string, key string) int64 {
+	item := sm.ReservedPoolManager.GetReservedItem(candidateId)
+	if item == nil {
+		return 0
+	}
+	value, ok := item.Get(key, int64(0)).(int64)
+	if !ok {
+		return 0
+	}
+	return value
+}
+
+func GetReservedResources(resType string) (interface{}, error) {
+	pool, err := schedManager.ReservedPoolManager.GetPool(resType)
+	if err != nil {
+		return nil, err
+	}
+	return pool.ToDict(), nil
+}
+
 func getHostCandidatesList(args *api.CandidateListArgs) (*api.CandidateListResult, error) {
 	r := new(api.CandidateListResult)
 	r.Limit = args.Limit
@@ -417,6 +483,19 @@ func newHistoryItem(historyItem *HistoryItem) *api.HistoryItem {
 	}
 }
 
+// GetExplain returns the explain of a schedule session in the history
+func GetExplain(sessionId string) (*schedapi.ScheduleExplain, error) {
+	historyItem := schedManager.HistoryManager.GetHistory(sessionId)
+	if historyItem == nil {
+		return nil, fmt.Errorf("History '%v' not found", sessionId)
+	}
+	explain := historyItem.Task.GetExplain()
+	if explain == nil {
+		return nil, fmt.Errorf("No explain of session '%v'", sessionId)
+	}
+	return explain, nil
+}
+
 func GetHistoryDetail(historyDetailArgs *api.HistoryDetailArgs) (*api.HistoryDetailResult, error) {
 	historyItem := schedManager.HistoryManager.GetHistory(historyDetailArgs.ID)
 	if historyItem == nil {
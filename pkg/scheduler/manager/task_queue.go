@@ -22,6 +22,7 @@ import (
 
 	"yunion.io/x/log"
 
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
 	"yunion.io/x/onecloud/pkg/scheduler/api"
 	"yunion.io/x/onecloud/pkg/scheduler/core"
 )
@@ -271,6 +272,7 @@ type Task struct {
 	completedCount int
 	resultItems    *core.SchedResultItemList
 	resultError    error
+	explain        *schedapi.ScheduleExplain
 }
 
 func NewTask(manager *SchedulerManager, schedInfo *api.SchedInfo) *Task {
@@ -322,6 +324,7 @@ func (t *Task) onTaskCompleted(taskExecutor *TaskExecutor) {
 	defer t.lock.Unlock()
 
 	log.V(10).Infof("onTaskCompleted executor: %#v", taskExecutor)
+	t.addExplain(taskExecutor)
 	if taskExecutor.resultError != nil {
 		t.resultError = taskExecutor.resultError
 		t.onError()
@@ -339,6 +342,31 @@ func (t *Task) onTaskCompleted(taskExecutor *TaskExecutor) {
 	}()
 }
 
+// addExplain must be called before the result items are consumed
+func (t *Task) addExplain(taskExecutor *TaskExecutor) {
+	u := taskExecutor.unit
+	if u == nil {
+		return
+	}
+	var items []*core.SchedResultItem
+	if taskExecutor.resultItems != nil {
+		items = taskExecutor.resultItems.Data
+	}
+	explain := u.Explain(items, taskExecutor.resultError)
+	if t.explain == nil {
+		t.explain = explain
+	} else {
+		t.explain = core.MergeExplain(t.explain, explain)
+	}
+}
+
+func (t *Task) GetExplain() *schedapi.ScheduleExplain {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.explain
+}
+
 func (t *Task) readLog(taskExecutor *TaskExecutor) {
 	u := taskExecutor.unit
 	if u != nil {
@@ -40,6 +40,8 @@ type SchedOptions struct {
 	SchedulerHistoryLimit       int    `help:"Scheduler history items' limitations" default:"1000"`
 	SchedulerHistoryCleanPeriod string `help:"Scheduler history cleanup period" default:"60s"`
 
+	EnablePreemption bool `help:"Evict preemptible guests for normal guests that don't fit otherwise" default:"false"`
+
 	// per isolated device default reserverd resource
 	MemoryReservedPerIsolatedDevice  int64 `help:"Per isolated device default reserverd memory size in MB" default:"8192"`    // 8G
 	CpuReservedPerIsolatedDevice     int64 `help:"Per isolated device default reserverd CPU count" default:"8"`               // 8 core
@@ -17,7 +17,6 @@ package aliyun
 import (
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
@@ -40,9 +39,16 @@ const (
 	ALIYUN_API_VERSION_VPC = "2016-04-28"
 	ALIYUN_API_VERSION_LB  = "2014-05-15"
 
+	ALIYUN_API_VERSION_ACTIONTRAIL = "2017-12-04"
+
 	ALIYUN_BSS_API_VERSION = "2017-12-14"
 
 	ALIYUN_RAM_API_VERSION = "2015-05-01"
+
+	ALIYUN_RDS_API_VERSION = "2014-08-15"
+
+	ALIYUN_DNS_API_VERSION  = "2015-01-09"
+	ALIYUN_PVTZ_API_VERSION = "2018-01-01"
 )
 
 type SAliyunClient struct {
@@ -64,39 +70,49 @@ func NewAliyunClient(providerId string, providerName string, accessKey string, s
 	return &client, nil
 }
 
-func jsonRequest(client *sdk.Client, domain, apiVersion, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+var (
+	// errors that go away if called later, retried with backoff
+	aliyunThrottledErrors = []string{
+		"Throttling",
+		"SignatureNonceUsed",
+		"InvalidInstance.NotSupported",
+		"try later",
+		"BackendServer.configuring",
+	}
+	aliyunServerErrors = []string{
+		"InternalError",
+		"ServiceUnavailable",
+		"SDK.ServerUnreachable",
+		"EOF",
+	}
+)
+
+func classifyAliyunError(err error) string {
+	return cloudprovider.ClassifyApiError(err, aliyunThrottledErrors, aliyunServerErrors)
+}
+
+func jsonRequest(caller *cloudprovider.SApiCaller, client *sdk.Client, domain, apiVersion, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	if debug {
 		log.Debugf("request %s %s %s %s", domain, apiVersion, apiName, params)
 	}
-	for i := 1; i < 4; i++ {
-		resp, err := _jsonRequest(client, domain, apiVersion, apiName, params)
-		retry := false
-		if err != nil {
-			for _, code := range []string{"404 Not Found"} {
-				if strings.Contains(err.Error(), code) {
-					return nil, cloudprovider.ErrNotFound
-				}
-			}
-			for _, code := range []string{"SignatureNonceUsed", "InvalidInstance.NotSupported", "try later", "BackendServer.configuring"} {
-				if strings.Contains(err.Error(), code) {
-					retry = true
-					break
-				}
-			}
-		}
-		if retry {
-			if debug {
-				log.Debugf("Retry %d...", i)
-			}
-			time.Sleep(time.Second * time.Duration(i*10))
-			continue
-		}
-		if debug {
-			log.Debugf("Response: %s", resp)
+	// the apis of different products share the same names, e.g. DescribeRegions
+	action := strings.Split(domain, ".")[0] + "." + apiName
+	var resp jsonutils.JSONObject
+	err := caller.Call(action, classifyAliyunError, func() error {
+		var err error
+		resp, err = _jsonRequest(client, domain, apiVersion, apiName, params)
+		return err
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "404 Not Found") {
+			return nil, cloudprovider.ErrNotFound
 		}
-		return resp, err
+		return nil, err
 	}
-	return nil, fmt.Errorf("timeout for request %s params: %s", apiName, params)
+	if debug {
+		log.Debugf("Response: %s", resp)
+	}
+	return resp, nil
 }
 
 func _jsonRequest(client *sdk.Client, domain string, version string, apiName string, params map[string]string) (jsonutils.JSONObject, error) {
@@ -142,6 +158,10 @@ func (self *SAliyunClient) UpdateAccount(accessKey, secret string) error {
 	}
 }
 
+func (self *SAliyunClient) apiCaller() *cloudprovider.SApiCaller {
+	return cloudprovider.GetApiCaller(CLOUD_PROVIDER_ALIYUN, self.accessKey)
+}
+
 func (self *SAliyunClient) getDefaultClient() (*sdk.Client, error) {
 	return sdk.NewClientWithAccessKey(ALIYUN_DEFAULT_REGION, self.accessKey, self.secret)
 }
@@ -151,7 +171,7 @@ func (self *SAliyunClient) ecsRequest(apiName string, params map[string]string)
 	if err != nil {
 		return nil, err
 	}
-	return jsonRequest(cli, "ecs.aliyuncs.com", ALIYUN_API_VERSION, apiName, params, self.Debug)
+	return jsonRequest(self.apiCaller(), cli, "ecs.aliyuncs.com", ALIYUN_API_VERSION, apiName, params, self.Debug)
 }
 
 func (self *SAliyunClient) fetchRegions() error {
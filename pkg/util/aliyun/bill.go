@@ -0,0 +1,110 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aliyun
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SInstanceBillItem struct {
+	InstanceID       string
+	ProductCode      string
+	ProductType      string
+	SubscriptionType string
+	Region           string
+	ResourceGroup    string
+	BillingDate      string
+	PretaxAmount     float64
+	Currency         string
+}
+
+// aliyun product codes of the resources managed locally
+var billProductResourceTypes = map[string]string{
+	"ecs":      cloudprovider.CLOUD_BILL_RESOURCE_SERVER,
+	"yundisk":  cloudprovider.CLOUD_BILL_RESOURCE_DISK,
+	"eip":      cloudprovider.CLOUD_BILL_RESOURCE_EIP,
+	"slb":      cloudprovider.CLOUD_BILL_RESOURCE_LOADBALANCER,
+	"nat_gw":   cloudprovider.CLOUD_BILL_RESOURCE_NATGATEWAY,
+	"oss":      cloudprovider.CLOUD_BILL_RESOURCE_BUCKET,
+	"snapshot": cloudprovider.CLOUD_BILL_RESOURCE_SNAPSHOT,
+}
+
+func (item *SInstanceBillItem) getResourceType() string {
+	// disks billed together with the instance are reported by product ecs
+	if item.ProductCode == "ecs" && strings.HasPrefix(item.InstanceID, "d-") {
+		return cloudprovider.CLOUD_BILL_RESOURCE_DISK
+	}
+	return billProductResourceTypes[item.ProductCode]
+}
+
+func (self *SAliyunClient) QueryInstanceBill(day time.Time, pageNum int, pageSize int) ([]SInstanceBillItem, int, error) {
+	params := make(map[string]string)
+	params["BillingCycle"] = day.Format("2006-01")
+	params["BillingDate"] = day.Format("2006-01-02")
+	params["Granularity"] = "DAILY"
+	params["PageNum"] = fmt.Sprintf("%d", pageNum)
+	params["PageSize"] = fmt.Sprintf("%d", pageSize)
+	body, err := self.businessRequest("QueryInstanceBill", params)
+	if err != nil {
+		log.Errorf("QueryInstanceBill fail %s", err)
+		return nil, 0, err
+	}
+	items := make([]SInstanceBillItem, 0)
+	err = body.Unmarshal(&items, "Data", "Items", "Item")
+	if err != nil {
+		log.Errorf("Unmarshal fail %s", err)
+		return nil, 0, err
+	}
+	total, _ := body.Int("Data", "TotalCount")
+	return items, int(total), nil
+}
+
+func (self *SAliyunClient) GetCloudBillItems(query cloudprovider.SCloudBillQuery) ([]cloudprovider.SCloudBillItem, error) {
+	day := cloudprovider.BillDate(query.Day)
+	items := make([]cloudprovider.SCloudBillItem, 0)
+	pageNum := 1
+	for {
+		parts, total, err := self.QueryInstanceBill(day, pageNum, 300)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			if part.PretaxAmount == 0 {
+				continue
+			}
+			items = append(items, cloudprovider.SCloudBillItem{
+				ResourceType: part.getResourceType(),
+				ResourceId:   part.InstanceID,
+				ProjectId:    part.ResourceGroup,
+				RegionId:     part.Region,
+				Product:      part.ProductCode,
+				Amount:       part.PretaxAmount,
+				Currency:     part.Currency,
+				UsageDate:    day,
+			})
+		}
+		if len(parts) == 0 || pageNum*300 >= total {
+			break
+		}
+		pageNum += 1
+	}
+	return cloudprovider.MergeCloudBillItems(items), nil
+}
@@ -0,0 +1,305 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aliyun
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SBucket struct {
+	region *SRegion
+
+	Name         string
+	Location     string
+	CreationDate time.Time
+	StorageClass string
+}
+
+func isOssErrorCode(err error, code string) bool {
+	if serr, ok := err.(oss.ServiceError); ok {
+		return serr.Code == code
+	}
+	return false
+}
+
+// ossLocation is the location of the buckets in the region, e.g. oss-cn-hangzhou
+func (self *SRegion) ossLocation() string {
+	return fmt.Sprintf("oss-%s", self.RegionId)
+}
+
+func (self *SRegion) GetBuckets() ([]SBucket, error) {
+	cli, err := self.GetOssClient()
+	if err != nil {
+		return nil, err
+	}
+	buckets := make([]SBucket, 0)
+	marker := ""
+	for {
+		result, err := cli.ListBuckets(oss.Marker(marker), oss.MaxKeys(1000))
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range result.Buckets {
+			if b.Location != self.ossLocation() {
+				continue
+			}
+			buckets = append(buckets, SBucket{
+				region:       self,
+				Name:         b.Name,
+				Location:     b.Location,
+				CreationDate: b.CreationDate,
+				StorageClass: b.StorageClass,
+			})
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextMarker
+	}
+	return buckets, nil
+}
+
+func (self *SRegion) GetIBuckets() ([]cloudprovider.ICloudBucket, error) {
+	buckets, err := self.GetBuckets()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudBucket, 0, len(buckets))
+	for i := range buckets {
+		ret = append(ret, &buckets[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIBucketById(name string) (cloudprovider.ICloudBucket, error) {
+	cli, err := self.GetOssClient()
+	if err != nil {
+		return nil, err
+	}
+	result, err := cli.GetBucketInfo(name)
+	if err != nil {
+		if isOssErrorCode(err, "NoSuchBucket") {
+			return nil, cloudprovider.ErrNotFound
+		}
+		return nil, err
+	}
+	if result.BucketInfo.Location != self.ossLocation() {
+		return nil, cloudprovider.ErrNotFound
+	}
+	bucket := SBucket{
+		region:       self,
+		Name:         result.BucketInfo.Name,
+		Location:     result.BucketInfo.Location,
+		CreationDate: result.BucketInfo.CreationDate,
+		StorageClass: result.BucketInfo.StorageClass,
+	}
+	return &bucket, nil
+}
+
+func (self *SRegion) CreateIBucket(name string, storageClass string, acl string) (cloudprovider.ICloudBucket, error) {
+	cli, err := self.GetOssClient()
+	if err != nil {
+		return nil, err
+	}
+	opts := make([]oss.Option, 0)
+	if len(storageClass) > 0 {
+		opts = append(opts, oss.StorageClass(oss.StorageClassType(storageClass)))
+	}
+	if len(acl) > 0 {
+		opts = append(opts, oss.ACL(oss.ACLType(acl)))
+	}
+	err = cli.CreateBucket(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetIBucketById(name)
+}
+
+func (self *SRegion) DeleteIBucket(name string) error {
+	cli, err := self.GetOssClient()
+	if err != nil {
+		return err
+	}
+	err = cli.DeleteBucket(name)
+	if err != nil && !isOssErrorCode(err, "NoSuchBucket") {
+		return err
+	}
+	return nil
+}
+
+func (b *SBucket) GetId() string {
+	return b.Name
+}
+
+func (b *SBucket) GetName() string {
+	return b.Name
+}
+
+func (b *SBucket) GetGlobalId() string {
+	return b.Name
+}
+
+func (b *SBucket) GetStatus() string {
+	return "available"
+}
+
+func (b *SBucket) Refresh() error {
+	bucket, err := b.region.GetIBucketById(b.Name)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(b, bucket)
+}
+
+func (b *SBucket) IsEmulated() bool {
+	return false
+}
+
+func (b *SBucket) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (b *SBucket) GetIRegion() cloudprovider.ICloudRegion {
+	return b.region
+}
+
+func (b *SBucket) GetLocation() string {
+	return b.Location
+}
+
+func (b *SBucket) GetStorageClass() string {
+	return b.StorageClass
+}
+
+func (b *SBucket) GetCreateAt() time.Time {
+	return b.CreationDate
+}
+
+func (b *SBucket) GetAccessUrl() string {
+	return fmt.Sprintf("https://%s.%s", b.Name, b.region.GetOSSExternalDomain())
+}
+
+func (b *SBucket) GetAcl() string {
+	cli, err := b.region.GetOssClient()
+	if err != nil {
+		return cloudprovider.ACL_UNKNOWN
+	}
+	result, err := cli.GetBucketACL(b.Name)
+	if err != nil {
+		return cloudprovider.ACL_UNKNOWN
+	}
+	return result.ACL
+}
+
+func (b *SBucket) SetAcl(acl string) error {
+	cli, err := b.region.GetOssClient()
+	if err != nil {
+		return err
+	}
+	return cli.SetBucketACL(b.Name, oss.ACLType(acl))
+}
+
+// GetStats sums up the objects of the bucket, the usage of a bucket is
+// only available from the cloud monitor of OSS
+func (b *SBucket) GetStats() (cloudprovider.SBucketStats, error) {
+	stats := cloudprovider.SBucketStats{}
+	cli, err := b.region.GetOssClient()
+	if err != nil {
+		return stats, err
+	}
+	bucket, err := cli.Bucket(b.Name)
+	if err != nil {
+		return stats, err
+	}
+	marker := ""
+	for {
+		result, err := bucket.ListObjects(oss.Marker(marker), oss.MaxKeys(1000))
+		if err != nil {
+			return stats, err
+		}
+		for _, obj := range result.Objects {
+			stats.SizeBytes += obj.Size
+			stats.ObjectCount += 1
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextMarker
+	}
+	return stats, nil
+}
+
+func (b *SBucket) GetLifecycle() ([]cloudprovider.SBucketLifecycleRule, error) {
+	cli, err := b.region.GetOssClient()
+	if err != nil {
+		return nil, err
+	}
+	result, err := cli.GetBucketLifecycle(b.Name)
+	if err != nil {
+		if isOssErrorCode(err, "NoSuchLifecycle") {
+			return []cloudprovider.SBucketLifecycleRule{}, nil
+		}
+		return nil, err
+	}
+	rules := make([]cloudprovider.SBucketLifecycleRule, 0, len(result.Rules))
+	for _, r := range result.Rules {
+		rules = append(rules, cloudprovider.SBucketLifecycleRule{
+			ID:             r.ID,
+			Prefix:         r.Prefix,
+			Enabled:        r.Status == "Enabled",
+			ExpirationDays: r.Expiration.Days,
+		})
+	}
+	return rules, nil
+}
+
+func (b *SBucket) SetLifecycle(rules []cloudprovider.SBucketLifecycleRule) error {
+	cli, err := b.region.GetOssClient()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return cli.DeleteBucketLifecycle(b.Name)
+	}
+	ossRules := make([]oss.LifecycleRule, 0, len(rules))
+	for i, rule := range rules {
+		id := rule.ID
+		if len(id) == 0 {
+			id = fmt.Sprintf("rule-%d", i)
+		}
+		ossRules = append(ossRules, oss.BuildLifecycleRuleByDays(id, rule.Prefix, rule.Enabled, rule.ExpirationDays))
+	}
+	return cli.SetBucketLifecycle(b.Name, ossRules)
+}
+
+func (b *SBucket) GetTempUrl(method string, key string, expire time.Duration) (string, error) {
+	cli, err := b.region.GetOssClient()
+	if err != nil {
+		return "", err
+	}
+	bucket, err := cli.Bucket(b.Name)
+	if err != nil {
+		return "", err
+	}
+	return bucket.SignURL(key, oss.HTTPMethod(strings.ToUpper(method)), int64(expire/time.Second))
+}
@@ -26,7 +26,7 @@ func (self *SAliyunClient) businessRequest(apiName string, params map[string]str
 	if err != nil {
 		return nil, err
 	}
-	return jsonRequest(cli, "business.aliyuncs.com", ALIYUN_BSS_API_VERSION, apiName, params, self.Debug)
+	return jsonRequest(self.apiCaller(), cli, "business.aliyuncs.com", ALIYUN_BSS_API_VERSION, apiName, params, self.Debug)
 }
 
 type SAccountBalance struct {
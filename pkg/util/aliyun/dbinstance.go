@@ -0,0 +1,398 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aliyun
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type SDBInstanceNetInfo struct {
+	ConnectionString string
+	IPAddress        string
+	IPType           string
+	Port             string
+}
+
+type SDBInstance struct {
+	region *SRegion
+
+	DBInstanceId          string
+	DBInstanceDescription string
+	DBInstanceStatus      string
+	DBInstanceClass       string
+	Engine                string
+	EngineVersion         string
+	PayType               string
+	ExpireTime            time.Time
+	CreateTime            time.Time
+	// the create time returned by DescribeDBInstanceAttribute
+	CreationTime time.Time
+	ZoneId       string
+	VpcId        string
+	VSwitchId    string
+
+	// filled by DescribeDBInstanceAttribute
+	DBInstanceCPU         string
+	DBInstanceMemory      int
+	DBInstanceStorage     int
+	DBInstanceStorageType string
+
+	netInfos []SDBInstanceNetInfo
+}
+
+func (self *SRegion) GetDBInstances(offset int, limit int) ([]SDBInstance, int, error) {
+	if limit > 100 || limit <= 0 {
+		limit = 100
+	}
+	params := make(map[string]string)
+	params["RegionId"] = self.RegionId
+	params["PageSize"] = fmt.Sprintf("%d", limit)
+	params["PageNumber"] = fmt.Sprintf("%d", (offset/limit)+1)
+	body, err := self.rdsRequest("DescribeDBInstances", params)
+	if err != nil {
+		return nil, 0, err
+	}
+	instances := make([]SDBInstance, 0)
+	err = body.Unmarshal(&instances, "Items", "DBInstance")
+	if err != nil {
+		return nil, 0, err
+	}
+	total, _ := body.Int("TotalRecordCount")
+	for i := range instances {
+		instances[i].region = self
+	}
+	return instances, int(total), nil
+}
+
+func (self *SRegion) GetDBInstanceDetail(instanceId string) (*SDBInstance, error) {
+	params := map[string]string{"DBInstanceId": instanceId}
+	body, err := self.rdsRequest("DescribeDBInstanceAttribute", params)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]SDBInstance, 0)
+	err = body.Unmarshal(&instances, "Items", "DBInstanceAttribute")
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, cloudprovider.ErrNotFound
+	}
+	instance := &instances[0]
+	instance.region = self
+	instance.netInfos, err = self.GetDBInstanceNetInfos(instanceId)
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+func (self *SRegion) GetDBInstanceNetInfos(instanceId string) ([]SDBInstanceNetInfo, error) {
+	params := map[string]string{"DBInstanceId": instanceId}
+	body, err := self.rdsRequest("DescribeDBInstanceNetInfo", params)
+	if err != nil {
+		return nil, err
+	}
+	netInfos := make([]SDBInstanceNetInfo, 0)
+	err = body.Unmarshal(&netInfos, "DBInstanceNetInfos", "DBInstanceNetInfo")
+	if err != nil {
+		return nil, err
+	}
+	return netInfos, nil
+}
+
+func (self *SRegion) GetIDBInstances() ([]cloudprovider.ICloudDBInstance, error) {
+	instances := make([]SDBInstance, 0)
+	for {
+		part, total, err := self.GetDBInstances(len(instances), 100)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, part...)
+		if len(instances) >= total || len(part) == 0 {
+			break
+		}
+	}
+	ret := make([]cloudprovider.ICloudDBInstance, 0, len(instances))
+	for i := range instances {
+		// the spec and endpoints are only returned by the detail apis
+		instance, err := self.GetDBInstanceDetail(instances[i].DBInstanceId)
+		if err != nil {
+			log.Errorf("fetch detail of dbinstance %s fail %s", instances[i].DBInstanceId, err)
+			instance = &instances[i]
+		}
+		ret = append(ret, instance)
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIDBInstanceById(id string) (cloudprovider.ICloudDBInstance, error) {
+	return self.GetDBInstanceDetail(id)
+}
+
+func (self *SDBInstance) GetId() string {
+	return self.DBInstanceId
+}
+
+func (self *SDBInstance) GetName() string {
+	if len(self.DBInstanceDescription) > 0 {
+		return self.DBInstanceDescription
+	}
+	return self.DBInstanceId
+}
+
+func (self *SDBInstance) GetGlobalId() string {
+	return self.DBInstanceId
+}
+
+func (self *SDBInstance) GetStatus() string {
+	switch self.DBInstanceStatus {
+	case "Creating", "GuardDBInstanceCreating":
+		return api.DBINSTANCE_STATUS_DEPLOYING
+	case "Running":
+		return api.DBINSTANCE_STATUS_RUNNING
+	case "Rebooting":
+		return api.DBINSTANCE_STATUS_REBOOTING
+	case "DBInstanceClassChanging":
+		return api.DBINSTANCE_STATUS_CHANGE_CONFIG
+	case "Deleting":
+		return api.DBINSTANCE_STATUS_DELETING
+	case "Restoring", "Importing", "ImportingFromOthers", "TRANSING", "TransingToOthers",
+		"EngineVersionUpgrading", "DBInstanceNetTypeChanging", "GuardSwitching", "INS_CLONING":
+		return api.DBINSTANCE_STATUS_MAINTENANCE
+	}
+	return api.DBINSTANCE_STATUS_UNKNOWN
+}
+
+func (self *SDBInstance) Refresh() error {
+	instance, err := self.region.GetDBInstanceDetail(self.DBInstanceId)
+	if err != nil {
+		return err
+	}
+	err = jsonutils.Update(self, instance)
+	if err != nil {
+		return err
+	}
+	self.netInfos = instance.netInfos
+	return nil
+}
+
+func (self *SDBInstance) IsEmulated() bool {
+	return false
+}
+
+func (self *SDBInstance) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SDBInstance) GetProjectId() string {
+	return ""
+}
+
+func (self *SDBInstance) GetBillingType() string {
+	if strings.ToLower(self.PayType) == "prepaid" {
+		return models.BILLING_TYPE_PREPAID
+	}
+	return models.BILLING_TYPE_POSTPAID
+}
+
+func (self *SDBInstance) GetExpiredAt() time.Time {
+	return convertExpiredAt(self.ExpireTime)
+}
+
+func (self *SDBInstance) GetEngine() string {
+	return self.Engine
+}
+
+func (self *SDBInstance) GetEngineVersion() string {
+	return self.EngineVersion
+}
+
+func (self *SDBInstance) GetInstanceType() string {
+	return self.DBInstanceClass
+}
+
+func (self *SDBInstance) GetVcpuCount() int {
+	cpu, _ := strconv.Atoi(self.DBInstanceCPU)
+	return cpu
+}
+
+func (self *SDBInstance) GetVmemSizeMB() int {
+	return self.DBInstanceMemory
+}
+
+func (self *SDBInstance) GetStorageType() string {
+	return self.DBInstanceStorageType
+}
+
+func (self *SDBInstance) GetStorageSizeGB() int {
+	return self.DBInstanceStorage
+}
+
+func (self *SDBInstance) GetZoneId() string {
+	return self.ZoneId
+}
+
+func (self *SDBInstance) GetVpcId() string {
+	return self.VpcId
+}
+
+func (self *SDBInstance) GetCreatedAt() time.Time {
+	if self.CreateTime.IsZero() {
+		return self.CreationTime
+	}
+	return self.CreateTime
+}
+
+func (self *SDBInstance) GetEndpoints() []cloudprovider.SDBInstanceEndpoint {
+	endpoints := make([]cloudprovider.SDBInstanceEndpoint, 0, len(self.netInfos))
+	for _, netInfo := range self.netInfos {
+		endpoint := cloudprovider.SDBInstanceEndpoint{
+			Type:    cloudprovider.DBINSTANCE_ENDPOINT_INTRANET,
+			Address: netInfo.ConnectionString,
+		}
+		if netInfo.IPType == "Public" {
+			endpoint.Type = cloudprovider.DBINSTANCE_ENDPOINT_INTERNET
+		}
+		endpoint.Port, _ = strconv.Atoi(netInfo.Port)
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+type SDBInstanceBackup struct {
+	BackupId        string
+	BackupStatus    string
+	BackupMode      string
+	BackupSize      int64
+	BackupStartTime time.Time
+	BackupEndTime   time.Time
+}
+
+// GetBackups returns the backups of the last week, which is the default
+// retention period of rds backups
+func (self *SDBInstance) GetBackups() ([]cloudprovider.SDBInstanceBackup, error) {
+	now := time.Now().UTC()
+	params := map[string]string{
+		"DBInstanceId": self.DBInstanceId,
+		"StartTime":    now.AddDate(0, 0, -7).Format("2006-01-02T15:04Z"),
+		"EndTime":      now.Format("2006-01-02T15:04Z"),
+		"PageSize":     "100",
+	}
+	body, err := self.region.rdsRequest("DescribeBackups", params)
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]SDBInstanceBackup, 0)
+	err = body.Unmarshal(&backups, "Items", "Backup")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.SDBInstanceBackup, len(backups))
+	for i, backup := range backups {
+		ret[i] = cloudprovider.SDBInstanceBackup{
+			Id:        backup.BackupId,
+			Mode:      cloudprovider.DBINSTANCE_BACKUP_AUTOMATED,
+			Status:    backup.BackupStatus,
+			SizeMB:    int(backup.BackupSize / 1024 / 1024),
+			StartTime: backup.BackupStartTime,
+			EndTime:   backup.BackupEndTime,
+		}
+		if backup.BackupMode == "Manual" {
+			ret[i].Mode = cloudprovider.DBINSTANCE_BACKUP_MANUAL
+		}
+	}
+	return ret, nil
+}
+
+type SDatabasePrivilege struct {
+	DBName           string
+	AccountPrivilege string
+}
+
+type SDBInstanceAccount struct {
+	AccountName        string
+	AccountStatus      string
+	DatabasePrivileges struct {
+		DatabasePrivilege []SDatabasePrivilege
+	}
+}
+
+func (self *SDBInstance) GetAccounts() ([]cloudprovider.SDBInstanceAccount, error) {
+	params := map[string]string{"DBInstanceId": self.DBInstanceId}
+	body, err := self.region.rdsRequest("DescribeAccounts", params)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]SDBInstanceAccount, 0)
+	err = body.Unmarshal(&accounts, "Accounts", "DBInstanceAccount")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.SDBInstanceAccount, len(accounts))
+	for i, account := range accounts {
+		ret[i] = cloudprovider.SDBInstanceAccount{
+			Name:      account.AccountName,
+			Status:    account.AccountStatus,
+			Databases: []string{},
+		}
+		for _, privilege := range account.DatabasePrivileges.DatabasePrivilege {
+			ret[i].Databases = append(ret[i].Databases, privilege.DBName)
+		}
+	}
+	return ret, nil
+}
+
+// rds instances of aliyun are always running once created
+func (self *SDBInstance) Start() error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SDBInstance) Stop() error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SDBInstance) Reboot() error {
+	params := map[string]string{"DBInstanceId": self.DBInstanceId}
+	_, err := self.region.rdsRequest("RestartDBInstance", params)
+	return err
+}
+
+func (self *SDBInstance) ChangeConfig(config *cloudprovider.SDBInstanceChangeConfig) error {
+	params := map[string]string{
+		"DBInstanceId":      self.DBInstanceId,
+		"PayType":           self.PayType,
+		"DBInstanceClass":   self.DBInstanceClass,
+		"DBInstanceStorage": fmt.Sprintf("%d", self.DBInstanceStorage),
+	}
+	if len(config.InstanceType) > 0 {
+		params["DBInstanceClass"] = config.InstanceType
+	}
+	if config.StorageSizeGB > 0 {
+		params["DBInstanceStorage"] = fmt.Sprintf("%d", config.StorageSizeGB)
+	}
+	_, err := self.region.rdsRequest("ModifyDBInstanceSpec", params)
+	return err
+}
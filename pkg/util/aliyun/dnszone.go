@@ -0,0 +1,374 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aliyun
+
+import (
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// the public zones are domains of Alidns, the private zones are the zones
+// of PrivateZone, both are global to the account
+func (self *SAliyunClient) alidnsRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
+	cli, err := self.getDefaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return jsonRequest(self.apiCaller(), cli, "alidns.aliyuncs.com", ALIYUN_DNS_API_VERSION, apiName, params, self.Debug)
+}
+
+func (self *SAliyunClient) pvtzRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
+	cli, err := self.getDefaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return jsonRequest(self.apiCaller(), cli, "pvtz.aliyuncs.com", ALIYUN_PVTZ_API_VERSION, apiName, params, self.Debug)
+}
+
+type SDnsRecord struct {
+	RecordId string
+	Name     string
+	Type     string
+	Value    string
+	Ttl      int
+}
+
+func (self *SDnsRecord) GetGlobalId() string {
+	return self.RecordId
+}
+
+func (self *SDnsRecord) GetDnsName() string {
+	return self.Name
+}
+
+func (self *SDnsRecord) GetDnsType() string {
+	return self.Type
+}
+
+func (self *SDnsRecord) GetDnsValue() string {
+	return self.Value
+}
+
+func (self *SDnsRecord) GetTtl() int {
+	return self.Ttl
+}
+
+type SDnsZone struct {
+	client *SAliyunClient
+
+	ZoneId      string
+	ZoneName    string
+	RecordCount int
+	IsPrivate   bool
+}
+
+func (self *SDnsZone) GetId() string {
+	return self.ZoneId
+}
+
+func (self *SDnsZone) GetName() string {
+	return self.ZoneName
+}
+
+func (self *SDnsZone) GetGlobalId() string {
+	return self.ZoneId
+}
+
+func (self *SDnsZone) GetStatus() string {
+	return api.DNS_ZONE_STATUS_AVAILABLE
+}
+
+func (self *SDnsZone) Refresh() error {
+	return nil
+}
+
+func (self *SDnsZone) IsEmulated() bool {
+	return false
+}
+
+func (self *SDnsZone) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SDnsZone) GetZoneType() string {
+	if self.IsPrivate {
+		return cloudprovider.DNS_ZONE_TYPE_PRIVATE
+	}
+	return cloudprovider.DNS_ZONE_TYPE_PUBLIC
+}
+
+func (self *SDnsZone) GetIDnsRecords() ([]cloudprovider.ICloudDnsRecord, error) {
+	var records []SDnsRecord
+	var err error
+	if self.IsPrivate {
+		records, err = self.client.GetPrivateZoneRecords(self.ZoneId)
+	} else {
+		records, err = self.client.GetDomainRecords(self.ZoneName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudDnsRecord, len(records))
+	for i := range records {
+		ret[i] = &records[i]
+	}
+	return ret, nil
+}
+
+func (self *SDnsZone) AddDnsRecord(record cloudprovider.SDnsRecord) (string, error) {
+	params := make(map[string]string)
+	params["Type"] = record.Type
+	params["Value"] = record.Value
+	if self.IsPrivate {
+		params["ZoneId"] = self.ZoneId
+		params["Rr"] = record.Name
+		if record.Ttl > 0 {
+			params["Ttl"] = fmt.Sprintf("%d", record.Ttl)
+		}
+		body, err := self.client.pvtzRequest("AddZoneRecord", params)
+		if err != nil {
+			return "", err
+		}
+		recordId, _ := body.Int("RecordId")
+		return fmt.Sprintf("%d", recordId), nil
+	}
+	params["DomainName"] = self.ZoneName
+	params["RR"] = record.Name
+	if record.Ttl > 0 {
+		params["TTL"] = fmt.Sprintf("%d", record.Ttl)
+	}
+	body, err := self.client.alidnsRequest("AddDomainRecord", params)
+	if err != nil {
+		return "", err
+	}
+	return body.GetString("RecordId")
+}
+
+func (self *SDnsZone) RemoveDnsRecord(recordId string) error {
+	params := map[string]string{"RecordId": recordId}
+	var err error
+	if self.IsPrivate {
+		_, err = self.client.pvtzRequest("DeleteZoneRecord", params)
+	} else {
+		_, err = self.client.alidnsRequest("DeleteDomainRecord", params)
+	}
+	return err
+}
+
+type sAlidnsDomain struct {
+	DomainId    string
+	DomainName  string
+	RecordCount int
+}
+
+type sAlidnsRecord struct {
+	RecordId string
+	RR       string `json:"RR"`
+	Type     string
+	Value    string
+	TTL      int `json:"TTL"`
+	Status   string
+}
+
+type sPvtzZone struct {
+	ZoneId      string
+	ZoneName    string
+	RecordCount int
+}
+
+type sPvtzRecord struct {
+	RecordId int64
+	Rr       string
+	Type     string
+	Value    string
+	Ttl      int
+	Status   string
+}
+
+func (self *SAliyunClient) GetDomains(offset int, limit int) ([]SDnsZone, int, error) {
+	if limit > 100 || limit <= 0 {
+		limit = 100
+	}
+	params := make(map[string]string)
+	params["PageSize"] = fmt.Sprintf("%d", limit)
+	params["PageNumber"] = fmt.Sprintf("%d", (offset/limit)+1)
+
+	body, err := self.alidnsRequest("DescribeDomains", params)
+	if err != nil {
+		log.Errorf("GetDomains fail %s", err)
+		return nil, 0, err
+	}
+	domains := make([]sAlidnsDomain, 0)
+	err = body.Unmarshal(&domains, "Domains", "Domain")
+	if err != nil {
+		return nil, 0, err
+	}
+	zones := make([]SDnsZone, len(domains))
+	for i := range domains {
+		zones[i] = SDnsZone{
+			client:      self,
+			ZoneId:      domains[i].DomainId,
+			ZoneName:    domains[i].DomainName,
+			RecordCount: domains[i].RecordCount,
+		}
+	}
+	total, _ := body.Int("TotalCount")
+	return zones, int(total), nil
+}
+
+func (self *SAliyunClient) GetPrivateZones(offset int, limit int) ([]SDnsZone, int, error) {
+	if limit > 100 || limit <= 0 {
+		limit = 100
+	}
+	params := make(map[string]string)
+	params["PageSize"] = fmt.Sprintf("%d", limit)
+	params["PageNumber"] = fmt.Sprintf("%d", (offset/limit)+1)
+
+	body, err := self.pvtzRequest("DescribeZones", params)
+	if err != nil {
+		log.Errorf("GetPrivateZones fail %s", err)
+		return nil, 0, err
+	}
+	pzones := make([]sPvtzZone, 0)
+	err = body.Unmarshal(&pzones, "Zones", "Zone")
+	if err != nil {
+		return nil, 0, err
+	}
+	zones := make([]SDnsZone, len(pzones))
+	for i := range pzones {
+		zones[i] = SDnsZone{
+			client:      self,
+			ZoneId:      pzones[i].ZoneId,
+			ZoneName:    pzones[i].ZoneName,
+			RecordCount: pzones[i].RecordCount,
+			IsPrivate:   true,
+		}
+	}
+	total, _ := body.Int("TotalItems")
+	return zones, int(total), nil
+}
+
+// GetDomainRecords returns the enabled records of an Alidns domain
+func (self *SAliyunClient) GetDomainRecords(domainName string) ([]SDnsRecord, error) {
+	records := make([]SDnsRecord, 0)
+	params := make(map[string]string)
+	params["DomainName"] = domainName
+	params["PageSize"] = "500"
+	for page, fetched := 1, 0; ; page++ {
+		params["PageNumber"] = fmt.Sprintf("%d", page)
+		body, err := self.alidnsRequest("DescribeDomainRecords", params)
+		if err != nil {
+			log.Errorf("GetDomainRecords fail %s", err)
+			return nil, err
+		}
+		parts := make([]sAlidnsRecord, 0)
+		err = body.Unmarshal(&parts, "DomainRecords", "Record")
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range parts {
+			if rec.Status != "ENABLE" {
+				continue
+			}
+			records = append(records, SDnsRecord{
+				RecordId: rec.RecordId,
+				Name:     rec.RR,
+				Type:     rec.Type,
+				Value:    rec.Value,
+				Ttl:      rec.TTL,
+			})
+		}
+		fetched += len(parts)
+		total, _ := body.Int("TotalCount")
+		if fetched >= int(total) || len(parts) == 0 {
+			break
+		}
+	}
+	return records, nil
+}
+
+// GetPrivateZoneRecords returns the enabled records of a PrivateZone zone
+func (self *SAliyunClient) GetPrivateZoneRecords(zoneId string) ([]SDnsRecord, error) {
+	records := make([]SDnsRecord, 0)
+	params := make(map[string]string)
+	params["ZoneId"] = zoneId
+	params["PageSize"] = "100"
+	for page, fetched := 1, 0; ; page++ {
+		params["PageNumber"] = fmt.Sprintf("%d", page)
+		body, err := self.pvtzRequest("DescribeZoneRecords", params)
+		if err != nil {
+			log.Errorf("GetPrivateZoneRecords fail %s", err)
+			return nil, err
+		}
+		parts := make([]sPvtzRecord, 0)
+		err = body.Unmarshal(&parts, "Records", "Record")
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range parts {
+			if rec.Status != "ENABLE" {
+				continue
+			}
+			records = append(records, SDnsRecord{
+				RecordId: fmt.Sprintf("%d", rec.RecordId),
+				Name:     rec.Rr,
+				Type:     rec.Type,
+				Value:    rec.Value,
+				Ttl:      rec.Ttl,
+			})
+		}
+		fetched += len(parts)
+		total, _ := body.Int("TotalItems")
+		if fetched >= int(total) || len(parts) == 0 {
+			break
+		}
+	}
+	return records, nil
+}
+
+func (self *SAliyunClient) GetIDnsZones() ([]cloudprovider.ICloudDnsZone, error) {
+	zones := make([]SDnsZone, 0)
+	for {
+		parts, total, err := self.GetDomains(len(zones), 100)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, parts...)
+		if len(zones) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	for offset := 0; ; {
+		parts, total, err := self.GetPrivateZones(offset, 100)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, parts...)
+		offset += len(parts)
+		if offset >= total || len(parts) == 0 {
+			break
+		}
+	}
+	ret := make([]cloudprovider.ICloudDnsZone, len(zones))
+	for i := range zones {
+		ret[i] = &zones[i]
+	}
+	return ret, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aliyun
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	// ActionTrail keeps the events of the last 90 days
+	ACTIONTRAIL_EVENT_RETENTION = 90 * 24 * time.Hour
+)
+
+// actiontrailResourceTypes maps the resource types of ActionTrail to the event
+// resource types
+var actiontrailResourceTypes = map[string]string{
+	"ACS::ECS::Instance":      cloudprovider.CLOUD_EVENT_RESOURCE_SERVER,
+	"ACS::ECS::Disk":          cloudprovider.CLOUD_EVENT_RESOURCE_DISK,
+	"ACS::ECS::Snapshot":      cloudprovider.CLOUD_EVENT_RESOURCE_SNAPSHOT,
+	"ACS::ECS::SecurityGroup": cloudprovider.CLOUD_EVENT_RESOURCE_SECGROUP,
+	"ACS::VPC::VPC":           cloudprovider.CLOUD_EVENT_RESOURCE_VPC,
+	"ACS::VPC::EIP":           cloudprovider.CLOUD_EVENT_RESOURCE_EIP,
+	"ACS::SLB::LoadBalancer":  cloudprovider.CLOUD_EVENT_RESOURCE_LOADBALANCER,
+	"ACS::OSS::Bucket":        cloudprovider.CLOUD_EVENT_RESOURCE_BUCKET,
+}
+
+type SActiontrailEvent struct {
+	EventId      string
+	EventName    string
+	EventTime    time.Time
+	ServiceName  string
+	ResourceName string
+	ResourceType string
+}
+
+func (self *SRegion) actiontrailRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
+	client, err := self.getSdkClient()
+	if err != nil {
+		return nil, err
+	}
+	domain := fmt.Sprintf("actiontrail.%s.aliyuncs.com", self.RegionId)
+	return jsonRequest(self.client.apiCaller(), client, domain, ALIYUN_API_VERSION_ACTIONTRAIL, apiName, params, self.client.Debug)
+}
+
+func (self *SRegion) LookupEvents(start time.Time, end time.Time) ([]SActiontrailEvent, error) {
+	params := map[string]string{
+		"RegionId":   self.RegionId,
+		"StartTime":  start.UTC().Format("2006-01-02T15:04:05Z"),
+		"EndTime":    end.UTC().Format("2006-01-02T15:04:05Z"),
+		"EventRW":    "Write",
+		"MaxResults": "50",
+	}
+	events := []SActiontrailEvent{}
+	for {
+		body, err := self.actiontrailRequest("LookupEvents", params)
+		if err != nil {
+			return nil, err
+		}
+		part := []SActiontrailEvent{}
+		if err := body.Unmarshal(&part, "Events"); err != nil {
+			return nil, err
+		}
+		events = append(events, part...)
+		nextToken, _ := body.GetString("NextToken")
+		if len(nextToken) == 0 || len(part) == 0 {
+			break
+		}
+		params["NextToken"] = nextToken
+	}
+	return events, nil
+}
+
+func actiontrailEventAction(eventName string) string {
+	for _, prefix := range []string{"Create", "Allocate", "Run", "Copy", "Import"} {
+		if strings.HasPrefix(eventName, prefix) {
+			return cloudprovider.CLOUD_EVENT_ACTION_CREATE
+		}
+	}
+	for _, prefix := range []string{"Delete", "Release"} {
+		if strings.HasPrefix(eventName, prefix) {
+			return cloudprovider.CLOUD_EVENT_ACTION_DELETE
+		}
+	}
+	return cloudprovider.CLOUD_EVENT_ACTION_UPDATE
+}
+
+func (self *SRegion) GetICloudEvents(start time.Time, end time.Time) ([]cloudprovider.SCloudEvent, error) {
+	if time.Now().Sub(start) > ACTIONTRAIL_EVENT_RETENTION {
+		return nil, cloudprovider.ErrEventExpired
+	}
+	events, err := self.LookupEvents(start, end)
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.SCloudEvent{}
+	for _, event := range events {
+		resourceType, ok := actiontrailResourceTypes[event.ResourceType]
+		if !ok {
+			resourceType = event.ResourceType
+		}
+		// a single event may touch several resources, e.g. DeleteInstances
+		for _, resourceId := range strings.Split(event.ResourceName, ";") {
+			if len(resourceId) == 0 {
+				continue
+			}
+			ret = append(ret, cloudprovider.SCloudEvent{
+				ResourceType: resourceType,
+				ResourceId:   resourceId,
+				Action:       actiontrailEventAction(event.EventName),
+				CreatedAt:    event.EventTime,
+			})
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aliyun
+
+import (
+	"fmt"
+	"strconv"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SForwardTableEntry struct {
+	nat *SNatGetway
+
+	ForwardEntryId   string
+	ForwardEntryName string
+	ForwardTableId   string
+	ExternalIp       string
+	ExternalPort     string
+	InternalIp       string
+	InternalPort     string
+	IpProtocol       string
+	Status           string
+}
+
+func (self *SRegion) GetForwardTableEntries(tableId string, offset, limit int) ([]SForwardTableEntry, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["RegionId"] = self.RegionId
+	params["PageSize"] = fmt.Sprintf("%d", limit)
+	params["PageNumber"] = fmt.Sprintf("%d", (offset/limit)+1)
+	params["ForwardTableId"] = tableId
+
+	body, err := self.vpcRequest("DescribeForwardTableEntries", params)
+	if err != nil {
+		log.Errorf("DescribeForwardTableEntries fail %s", err)
+		return nil, 0, err
+	}
+
+	if self.client.Debug {
+		log.Debugf("%s", body.PrettyString())
+	}
+
+	entries := make([]SForwardTableEntry, 0)
+	err = body.Unmarshal(&entries, "ForwardTableEntries", "ForwardTableEntry")
+	if err != nil {
+		log.Errorf("Unmarshal entries fail %s", err)
+		return nil, 0, err
+	}
+	total, _ := body.Int("TotalCount")
+	return entries, int(total), nil
+}
+
+func (region *SRegion) CreateForwardEntry(tableId string, rule cloudprovider.SNatDRule) (string, error) {
+	params := make(map[string]string)
+	params["RegionId"] = region.RegionId
+	params["ForwardTableId"] = tableId
+	params["ExternalIp"] = rule.ExternalIP
+	params["ExternalPort"] = fmt.Sprintf("%d", rule.ExternalPort)
+	params["InternalIp"] = rule.InternalIP
+	params["InternalPort"] = fmt.Sprintf("%d", rule.InternalPort)
+	params["IpProtocol"] = rule.Protocol
+	if len(rule.Name) > 0 {
+		params["ForwardEntryName"] = rule.Name
+	}
+	body, err := region.vpcRequest("CreateForwardEntry", params)
+	if err != nil {
+		return "", err
+	}
+	return body.GetString("ForwardEntryId")
+}
+
+func (region *SRegion) DeleteForwardEntry(tableId string, entryId string) error {
+	params := make(map[string]string)
+	params["RegionId"] = region.RegionId
+	params["ForwardTableId"] = tableId
+	params["ForwardEntryId"] = entryId
+	_, err := region.vpcRequest("DeleteForwardEntry", params)
+	return err
+}
+
+func (nat *SNatGetway) getForwardEntriesForTable(tblId string) ([]SForwardTableEntry, error) {
+	entries := make([]SForwardTableEntry, 0)
+	entryTotal := -1
+	for entryTotal < 0 || len(entries) < entryTotal {
+		parts, total, err := nat.vpc.region.GetForwardTableEntries(tblId, len(entries), 50)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) == 0 {
+			break
+		}
+		entries = append(entries, parts...)
+		entryTotal = total
+	}
+	for i := range entries {
+		entries[i].nat = nat
+	}
+	return entries, nil
+}
+
+func (nat *SNatGetway) getForwardEntries() ([]SForwardTableEntry, error) {
+	entries := make([]SForwardTableEntry, 0)
+	for i := range nat.ForwardTableIds.ForwardTableId {
+		dentries, err := nat.getForwardEntriesForTable(nat.ForwardTableIds.ForwardTableId[i])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, dentries...)
+	}
+	return entries, nil
+}
+
+func (entry *SForwardTableEntry) GetId() string {
+	return entry.ForwardEntryId
+}
+
+func (entry *SForwardTableEntry) GetName() string {
+	if len(entry.ForwardEntryName) > 0 {
+		return entry.ForwardEntryName
+	}
+	return entry.ForwardEntryId
+}
+
+func (entry *SForwardTableEntry) GetGlobalId() string {
+	return entry.ForwardEntryId
+}
+
+func (entry *SForwardTableEntry) GetStatus() string {
+	return natEntryStatus(entry.Status)
+}
+
+func (entry *SForwardTableEntry) Refresh() error {
+	entries, err := entry.nat.getForwardEntriesForTable(entry.ForwardTableId)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].ForwardEntryId == entry.ForwardEntryId {
+			return jsonutils.Update(entry, entries[i])
+		}
+	}
+	return cloudprovider.ErrNotFound
+}
+
+func (entry *SForwardTableEntry) IsEmulated() bool {
+	return false
+}
+
+func (entry *SForwardTableEntry) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (entry *SForwardTableEntry) GetIpProtocol() string {
+	return entry.IpProtocol
+}
+
+func (entry *SForwardTableEntry) GetExternalIp() string {
+	return entry.ExternalIp
+}
+
+func (entry *SForwardTableEntry) GetExternalPort() int {
+	port, _ := strconv.Atoi(entry.ExternalPort)
+	return port
+}
+
+func (entry *SForwardTableEntry) GetInternalIp() string {
+	return entry.InternalIp
+}
+
+func (entry *SForwardTableEntry) GetInternalPort() int {
+	port, _ := strconv.Atoi(entry.InternalPort)
+	return port
+}
+
+func (entry *SForwardTableEntry) Delete() error {
+	return entry.nat.vpc.region.DeleteForwardEntry(entry.ForwardTableId, entry.ForwardEntryId)
+}
@@ -16,9 +16,14 @@ package aliyun
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 )
 
 type SBandwidthPackageIds struct {
@@ -87,7 +92,10 @@ func (self *SRegion) GetNatGateways(vpcId string, natGwId string, offset, limit
 }
 
 type SSNATTableEntry struct {
+	nat *SNatGetway
+
 	SnatEntryId     string
+	SnatEntryName   string
 	SnatIp          string
 	SnatTableId     string `json:"snat_table_id"`
 	SourceCIDR      string `json:"source_cidr"`
@@ -147,6 +155,9 @@ func (nat *SNatGetway) getSnatEntriesForTable(tblId string) ([]SSNATTableEntry,
 		}
 		entryTotal = total
 	}
+	for i := range entries {
+		entries[i].nat = nat
+	}
 	return entries, nil
 }
 
@@ -168,7 +179,7 @@ func (nat *SNatGetway) dissociateWithVswitch(vswitchId string) error {
 		return err
 	}
 	for i := range entries {
-		log.Debugf("%s", entries[i])
+		log.Debugf("%s", jsonutils.Marshal(&entries[i]))
 		if entries[i].SourceVSwitchId == vswitchId {
 			err := nat.vpc.region.DeleteSnatEntry(entries[i].SnatTableId, entries[i].SnatEntryId)
 			if err != nil {
@@ -178,3 +189,225 @@ func (nat *SNatGetway) dissociateWithVswitch(vswitchId string) error {
 	}
 	return nil
 }
+
+func (nat *SNatGetway) GetId() string {
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGetway) GetName() string {
+	if len(nat.Name) > 0 {
+		return nat.Name
+	}
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGetway) GetGlobalId() string {
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGetway) GetStatus() string {
+	switch nat.Status {
+	case "Creating":
+		return api.NAT_STATUS_ALLOCATE
+	case "Available":
+		return api.NAT_STATUS_AVAILABLE
+	case "Modifying", "Converting":
+		return api.NAT_STATUS_DEPLOYING
+	case "Deleting":
+		return api.NAT_STATUS_DELETING
+	default:
+		return api.NAT_STATUS_UNKNOWN
+	}
+}
+
+func (nat *SNatGetway) Refresh() error {
+	gateways, total, err := nat.vpc.region.GetNatGateways(nat.VpcId, nat.NatGatewayId, 0, 1)
+	if err != nil {
+		return err
+	}
+	if total != 1 || len(gateways) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(nat, gateways[0])
+}
+
+func (nat *SNatGetway) IsEmulated() bool {
+	return false
+}
+
+func (nat *SNatGetway) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (nat *SNatGetway) GetNatSpec() string {
+	switch nat.Spec {
+	case "Small":
+		return api.NAT_SPEC_SMALL
+	case "Middle":
+		return api.NAT_SPEC_MIDDLE
+	case "Large":
+		return api.NAT_SPEC_LARGE
+	case "XLarge.1":
+		return api.NAT_SPEC_XLARGE
+	}
+	return strings.ToLower(nat.Spec)
+}
+
+func (nat *SNatGetway) GetDescription() string {
+	return nat.Description
+}
+
+func (nat *SNatGetway) GetINatSEntries() ([]cloudprovider.ICloudNatSEntry, error) {
+	entries, err := nat.getSnatEntries()
+	if err != nil {
+		return nil, err
+	}
+	ientries := make([]cloudprovider.ICloudNatSEntry, len(entries))
+	for i := 0; i < len(entries); i++ {
+		ientries[i] = &entries[i]
+	}
+	return ientries, nil
+}
+
+func (nat *SNatGetway) GetINatDEntries() ([]cloudprovider.ICloudNatDEntry, error) {
+	entries, err := nat.getForwardEntries()
+	if err != nil {
+		return nil, err
+	}
+	ientries := make([]cloudprovider.ICloudNatDEntry, len(entries))
+	for i := 0; i < len(entries); i++ {
+		ientries[i] = &entries[i]
+	}
+	return ientries, nil
+}
+
+func (nat *SNatGetway) CreateINatSEntry(rule cloudprovider.SNatSRule) (cloudprovider.ICloudNatSEntry, error) {
+	if len(nat.SnatTableIds.SnatTableId) == 0 {
+		return nil, fmt.Errorf("no snat table found for nat gateway %s", nat.NatGatewayId)
+	}
+	tableId := nat.SnatTableIds.SnatTableId[0]
+	entryId, err := nat.vpc.region.CreateSnatEntry(tableId, rule)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := nat.getSnatEntriesForTable(tableId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].SnatEntryId == entryId {
+			return &entries[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (nat *SNatGetway) CreateINatDEntry(rule cloudprovider.SNatDRule) (cloudprovider.ICloudNatDEntry, error) {
+	if len(nat.ForwardTableIds.ForwardTableId) == 0 {
+		return nil, fmt.Errorf("no forward table found for nat gateway %s", nat.NatGatewayId)
+	}
+	tableId := nat.ForwardTableIds.ForwardTableId[0]
+	entryId, err := nat.vpc.region.CreateForwardEntry(tableId, rule)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := nat.getForwardEntriesForTable(tableId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ForwardEntryId == entryId {
+			return &entries[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (region *SRegion) CreateSnatEntry(tableId string, rule cloudprovider.SNatSRule) (string, error) {
+	params := make(map[string]string)
+	params["RegionId"] = region.RegionId
+	params["SnatTableId"] = tableId
+	params["SnatIp"] = rule.ExternalIP
+	if len(rule.NetworkID) > 0 {
+		params["SourceVSwitchId"] = rule.NetworkID
+	} else {
+		params["SourceCIDR"] = rule.SourceCIDR
+	}
+	if len(rule.Name) > 0 {
+		params["SnatEntryName"] = rule.Name
+	}
+	body, err := region.vpcRequest("CreateSnatEntry", params)
+	if err != nil {
+		return "", err
+	}
+	return body.GetString("SnatEntryId")
+}
+
+func (entry *SSNATTableEntry) GetId() string {
+	return entry.SnatEntryId
+}
+
+func (entry *SSNATTableEntry) GetName() string {
+	if len(entry.SnatEntryName) > 0 {
+		return entry.SnatEntryName
+	}
+	return entry.SnatEntryId
+}
+
+func (entry *SSNATTableEntry) GetGlobalId() string {
+	return entry.SnatEntryId
+}
+
+func (entry *SSNATTableEntry) GetStatus() string {
+	return natEntryStatus(entry.Status)
+}
+
+func (entry *SSNATTableEntry) Refresh() error {
+	entries, err := entry.nat.getSnatEntriesForTable(entry.SnatTableId)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].SnatEntryId == entry.SnatEntryId {
+			return jsonutils.Update(entry, entries[i])
+		}
+	}
+	return cloudprovider.ErrNotFound
+}
+
+func (entry *SSNATTableEntry) IsEmulated() bool {
+	return false
+}
+
+func (entry *SSNATTableEntry) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (entry *SSNATTableEntry) GetIP() string {
+	return entry.SnatIp
+}
+
+func (entry *SSNATTableEntry) GetSourceCIDR() string {
+	return entry.SourceCIDR
+}
+
+func (entry *SSNATTableEntry) GetNetworkId() string {
+	return entry.SourceVSwitchId
+}
+
+func (entry *SSNATTableEntry) Delete() error {
+	return entry.nat.vpc.region.DeleteSnatEntry(entry.SnatTableId, entry.SnatEntryId)
+}
+
+func natEntryStatus(status string) string {
+	switch status {
+	case "Pending":
+		return api.NAT_STATUS_DEPLOYING
+	case "Available":
+		return api.NAT_STATUS_AVAILABLE
+	case "Deleting":
+		return api.NAT_STATUS_DELETING
+	default:
+		return api.NAT_STATUS_UNKNOWN
+	}
+}
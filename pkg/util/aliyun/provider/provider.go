@@ -150,3 +150,11 @@ func (self *SAliyunProvider) GetBalance() (float64, string, error) {
 func (self *SAliyunProvider) GetIProjects() ([]cloudprovider.ICloudProject, error) {
 	return self.client.GetIProjects()
 }
+
+func (self *SAliyunProvider) GetCloudBillItems(query cloudprovider.SCloudBillQuery) ([]cloudprovider.SCloudBillItem, error) {
+	return self.client.GetCloudBillItems(query)
+}
+
+func (self *SAliyunProvider) GetIDnsZones() ([]cloudprovider.ICloudDnsZone, error) {
+	return self.client.GetIDnsZones()
+}
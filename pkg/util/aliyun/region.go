@@ -105,7 +105,7 @@ func (self *SRegion) ecsRequest(apiName string, params map[string]string) (jsonu
 	if err != nil {
 		return nil, err
 	}
-	return jsonRequest(client, "ecs.aliyuncs.com", ALIYUN_API_VERSION, apiName, params, self.client.Debug)
+	return jsonRequest(self.client.apiCaller(), client, "ecs.aliyuncs.com", ALIYUN_API_VERSION, apiName, params, self.client.Debug)
 }
 
 func (self *SRegion) vpcRequest(action string, params map[string]string) (jsonutils.JSONObject, error) {
@@ -113,7 +113,15 @@ func (self *SRegion) vpcRequest(action string, params map[string]string) (jsonut
 	if err != nil {
 		return nil, err
 	}
-	return jsonRequest(client, "vpc.aliyuncs.com", ALIYUN_API_VERSION_VPC, action, params, self.Debug)
+	return jsonRequest(self.client.apiCaller(), client, "vpc.aliyuncs.com", ALIYUN_API_VERSION_VPC, action, params, self.Debug)
+}
+
+func (self *SRegion) rdsRequest(action string, params map[string]string) (jsonutils.JSONObject, error) {
+	client, err := self.getSdkClient()
+	if err != nil {
+		return nil, err
+	}
+	return jsonRequest(self.client.apiCaller(), client, "rds.aliyuncs.com", ALIYUN_RDS_API_VERSION, action, params, self.Debug)
 }
 
 type LBRegion struct {
@@ -163,7 +171,7 @@ func (self *SRegion) lbRequest(apiName string, params map[string]string) (jsonut
 }
 
 func (self *SRegion) _lbRequest(client *sdk.Client, apiName string, domain string, params map[string]string) (jsonutils.JSONObject, error) {
-	return jsonRequest(client, domain, ALIYUN_API_VERSION_LB, apiName, params, self.Debug)
+	return jsonRequest(self.client.apiCaller(), client, domain, ALIYUN_API_VERSION_LB, apiName, params, self.Debug)
 }
 
 /////////////////////////////////////////////////////////////////////////////
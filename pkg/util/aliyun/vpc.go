@@ -228,6 +228,18 @@ func (self *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
 	return self.routeTables, nil
 }
 
+func (self *SVpc) GetINatGateways() ([]cloudprovider.ICloudNatGateway, error) {
+	nats, err := self.getNatGateways()
+	if err != nil {
+		return nil, err
+	}
+	inats := make([]cloudprovider.ICloudNatGateway, len(nats))
+	for i := 0; i < len(nats); i++ {
+		inats[i] = &nats[i]
+	}
+	return inats, nil
+}
+
 func (self *SVpc) GetManagerId() string {
 	return self.region.client.providerId
 }
@@ -0,0 +1,221 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aliyun
+
+import (
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// aliyun connects two vpcs by a pair of router interfaces on their vrouters,
+// a routing entry to the peer vpc has the local router interface as next hop
+type SRouterInterface struct {
+	region *SRegion
+
+	RouterInterfaceId        string
+	Name                     string
+	Description              string
+	Status                   string
+	BusinessStatus           string
+	Role                     string
+	Spec                     string
+	Bandwidth                int
+	RouterType               string
+	RouterId                 string
+	OppositeRouterType       string
+	OppositeRouterId         string
+	OppositeInterfaceId      string
+	OppositeRegionId         string
+	OppositeInterfaceOwnerId string
+	CreationTime             time.Time
+}
+
+// bandwidth in Mbps of the router interface specs
+var routerInterfaceSpecBandwidth = map[string]int{
+	"Mini.2":   2,
+	"Mini.5":   5,
+	"Small.1":  10,
+	"Small.2":  20,
+	"Small.5":  50,
+	"Middle.1": 100,
+	"Middle.2": 200,
+	"Middle.5": 500,
+	"Large.1":  1000,
+	"Large.2":  2000,
+	"Large.5":  5000,
+	"Xlarge.1": 10000,
+}
+
+func (self *SRouterInterface) GetId() string {
+	return self.RouterInterfaceId
+}
+
+func (self *SRouterInterface) GetName() string {
+	if len(self.Name) > 0 {
+		return self.Name
+	}
+	return self.RouterInterfaceId
+}
+
+func (self *SRouterInterface) GetGlobalId() string {
+	return self.RouterInterfaceId
+}
+
+func (self *SRouterInterface) GetStatus() string {
+	switch self.Status {
+	case "Active":
+		return api.VPC_PEERING_STATUS_ACTIVE
+	case "Idle", "Connecting", "Activating":
+		return api.VPC_PEERING_STATUS_PENDING
+	case "Inactive", "Deactivating":
+		return api.VPC_PEERING_STATUS_INACTIVE
+	case "Deleting":
+		return api.VPC_PEERING_STATUS_DELETING
+	}
+	return api.VPC_PEERING_STATUS_UNKNOWN
+}
+
+func (self *SRouterInterface) Refresh() error {
+	ri, err := self.region.getRouterInterface(self.RouterInterfaceId)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(self, ri)
+}
+
+func (self *SRouterInterface) IsEmulated() bool {
+	return false
+}
+
+func (self *SRouterInterface) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SRouterInterface) GetVpcId() string {
+	return self.region.getVpcIdByVRouterId(self.RouterId)
+}
+
+func (self *SRouterInterface) GetPeerVpcId() string {
+	region := self.region
+	if self.OppositeRegionId != self.region.RegionId {
+		region = self.region.client.GetRegion(self.OppositeRegionId)
+		if region == nil {
+			return ""
+		}
+	}
+	// the vrouter of another account is not visible
+	return region.getVpcIdByVRouterId(self.OppositeRouterId)
+}
+
+func (self *SRouterInterface) GetPeerRegionId() string {
+	return self.OppositeRegionId
+}
+
+func (self *SRouterInterface) GetPeerAccountId() string {
+	return self.OppositeInterfaceOwnerId
+}
+
+func (self *SRouterInterface) GetBandwidth() int {
+	if self.Bandwidth > 0 {
+		return self.Bandwidth
+	}
+	return routerInterfaceSpecBandwidth[self.Spec]
+}
+
+func (self *SRegion) getVpcIdByVRouterId(routerId string) string {
+	ivpcs, err := self.GetIVpcs()
+	if err != nil {
+		log.Errorf("GetIVpcs of region %s fail %s", self.RegionId, err)
+		return ""
+	}
+	for i := range ivpcs {
+		vpc := ivpcs[i].(*SVpc)
+		if vpc.VRouterId == routerId {
+			return vpc.VpcId
+		}
+	}
+	return ""
+}
+
+func (self *SRegion) GetRouterInterfaces(riId string, offset int, limit int) ([]SRouterInterface, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["RegionId"] = self.RegionId
+	params["PageSize"] = fmt.Sprintf("%d", limit)
+	params["PageNumber"] = fmt.Sprintf("%d", (offset/limit)+1)
+	if len(riId) > 0 {
+		params["Filter.1.Key"] = "RouterInterfaceId"
+		params["Filter.1.Value.1"] = riId
+	}
+
+	body, err := self.vpcRequest("DescribeRouterInterfaces", params)
+	if err != nil {
+		log.Errorf("GetRouterInterfaces fail %s", err)
+		return nil, 0, err
+	}
+
+	ris := make([]SRouterInterface, 0)
+	err = body.Unmarshal(&ris, "RouterInterfaceSet", "RouterInterfaceType")
+	if err != nil {
+		log.Errorf("Unmarshal router interfaces fail %s", err)
+		return nil, 0, err
+	}
+	total, _ := body.Int("TotalCount")
+	return ris, int(total), nil
+}
+
+func (self *SRegion) getRouterInterface(riId string) (*SRouterInterface, error) {
+	ris, _, err := self.GetRouterInterfaces(riId, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(ris) != 1 {
+		return nil, cloudprovider.ErrNotFound
+	}
+	ris[0].region = self
+	return &ris[0], nil
+}
+
+func (self *SRegion) GetIVpcPeerings() ([]cloudprovider.ICloudVpcPeering, error) {
+	ris := make([]SRouterInterface, 0)
+	for {
+		parts, total, err := self.GetRouterInterfaces("", len(ris), 50)
+		if err != nil {
+			return nil, err
+		}
+		ris = append(ris, parts...)
+		if len(ris) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	ret := make([]cloudprovider.ICloudVpcPeering, 0)
+	for i := range ris {
+		// interfaces to a border router are express connect to idc, not peering
+		if ris[i].RouterType != "VRouter" || ris[i].OppositeRouterType != "VRouter" {
+			continue
+		}
+		ris[i].region = self
+		ret = append(ret, &ris[i])
+	}
+	return ret, nil
+}
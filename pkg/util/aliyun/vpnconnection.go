@@ -0,0 +1,283 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aliyun
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SVpnGateway struct {
+	VpcId        string
+	VpnGatewayId string
+	Name         string
+	InternetIp   string
+	Status       string
+}
+
+type SCustomerGateway struct {
+	CustomerGatewayId string
+	Name              string
+	IpAddress         string
+}
+
+type SVpnConnection struct {
+	region *SRegion
+
+	vpnGateway      *SVpnGateway
+	customerGateway *SCustomerGateway
+
+	VpnConnectionId   string
+	Name              string
+	VpnGatewayId      string
+	CustomerGatewayId string
+	LocalSubnet       string
+	RemoteSubnet      string
+	Status            string
+}
+
+func (self *SVpnConnection) GetId() string {
+	return self.VpnConnectionId
+}
+
+func (self *SVpnConnection) GetName() string {
+	if len(self.Name) > 0 {
+		return self.Name
+	}
+	return self.VpnConnectionId
+}
+
+func (self *SVpnConnection) GetGlobalId() string {
+	return self.VpnConnectionId
+}
+
+func (self *SVpnConnection) GetStatus() string {
+	switch self.Status {
+	case "ipsec_sa_established":
+		return api.VPN_CONNECTION_STATUS_CONNECTED
+	case "ike_sa_not_established", "ike_sa_established", "ipsec_sa_not_established":
+		return api.VPN_CONNECTION_STATUS_DISCONNECTED
+	}
+	return api.VPN_CONNECTION_STATUS_UNKNOWN
+}
+
+func (self *SVpnConnection) Refresh() error {
+	conns, _, err := self.region.GetVpnConnections(self.VpnConnectionId, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(conns) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(self, conns[0])
+}
+
+func (self *SVpnConnection) IsEmulated() bool {
+	return false
+}
+
+func (self *SVpnConnection) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SVpnConnection) GetVpcId() string {
+	if self.vpnGateway != nil {
+		return self.vpnGateway.VpcId
+	}
+	return ""
+}
+
+func (self *SVpnConnection) GetVpnGatewayId() string {
+	return self.VpnGatewayId
+}
+
+func (self *SVpnConnection) GetVpnGatewayAddress() string {
+	if self.vpnGateway != nil {
+		return self.vpnGateway.InternetIp
+	}
+	return ""
+}
+
+func (self *SVpnConnection) GetCustomerGatewayId() string {
+	return self.CustomerGatewayId
+}
+
+func (self *SVpnConnection) GetCustomerGatewayAddress() string {
+	if self.customerGateway != nil {
+		return self.customerGateway.IpAddress
+	}
+	return ""
+}
+
+func splitSubnets(subnets string) []string {
+	ret := make([]string, 0)
+	for _, subnet := range strings.Split(subnets, ",") {
+		if subnet = strings.TrimSpace(subnet); len(subnet) > 0 {
+			ret = append(ret, subnet)
+		}
+	}
+	return ret
+}
+
+func (self *SVpnConnection) GetLocalCidrs() []string {
+	return splitSubnets(self.LocalSubnet)
+}
+
+func (self *SVpnConnection) GetRemoteCidrs() []string {
+	return splitSubnets(self.RemoteSubnet)
+}
+
+func (self *SRegion) GetVpnConnections(connId string, offset int, limit int) ([]SVpnConnection, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["RegionId"] = self.RegionId
+	params["PageSize"] = fmt.Sprintf("%d", limit)
+	params["PageNumber"] = fmt.Sprintf("%d", (offset/limit)+1)
+	if len(connId) > 0 {
+		params["VpnConnectionId"] = connId
+	}
+
+	body, err := self.vpcRequest("DescribeVpnConnections", params)
+	if err != nil {
+		log.Errorf("GetVpnConnections fail %s", err)
+		return nil, 0, err
+	}
+
+	conns := make([]SVpnConnection, 0)
+	err = body.Unmarshal(&conns, "VpnConnections", "VpnConnection")
+	if err != nil {
+		log.Errorf("Unmarshal vpn connections fail %s", err)
+		return nil, 0, err
+	}
+	total, _ := body.Int("TotalCount")
+	return conns, int(total), nil
+}
+
+func (self *SRegion) GetVpnGateways(offset int, limit int) ([]SVpnGateway, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["RegionId"] = self.RegionId
+	params["PageSize"] = fmt.Sprintf("%d", limit)
+	params["PageNumber"] = fmt.Sprintf("%d", (offset/limit)+1)
+
+	body, err := self.vpcRequest("DescribeVpnGateways", params)
+	if err != nil {
+		log.Errorf("GetVpnGateways fail %s", err)
+		return nil, 0, err
+	}
+
+	gateways := make([]SVpnGateway, 0)
+	err = body.Unmarshal(&gateways, "VpnGateways", "VpnGateway")
+	if err != nil {
+		log.Errorf("Unmarshal vpn gateways fail %s", err)
+		return nil, 0, err
+	}
+	total, _ := body.Int("TotalCount")
+	return gateways, int(total), nil
+}
+
+func (self *SRegion) GetCustomerGateways(offset int, limit int) ([]SCustomerGateway, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["RegionId"] = self.RegionId
+	params["PageSize"] = fmt.Sprintf("%d", limit)
+	params["PageNumber"] = fmt.Sprintf("%d", (offset/limit)+1)
+
+	body, err := self.vpcRequest("DescribeCustomerGateways", params)
+	if err != nil {
+		log.Errorf("GetCustomerGateways fail %s", err)
+		return nil, 0, err
+	}
+
+	gateways := make([]SCustomerGateway, 0)
+	err = body.Unmarshal(&gateways, "CustomerGateways", "CustomerGateway")
+	if err != nil {
+		log.Errorf("Unmarshal customer gateways fail %s", err)
+		return nil, 0, err
+	}
+	total, _ := body.Int("TotalCount")
+	return gateways, int(total), nil
+}
+
+func (self *SRegion) GetIVpnConnections() ([]cloudprovider.ICloudVpnConnection, error) {
+	conns := make([]SVpnConnection, 0)
+	for {
+		parts, total, err := self.GetVpnConnections("", len(conns), 50)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, parts...)
+		if len(conns) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	if len(conns) == 0 {
+		return []cloudprovider.ICloudVpnConnection{}, nil
+	}
+
+	vpnGateways := make([]SVpnGateway, 0)
+	for {
+		parts, total, err := self.GetVpnGateways(len(vpnGateways), 50)
+		if err != nil {
+			return nil, err
+		}
+		vpnGateways = append(vpnGateways, parts...)
+		if len(vpnGateways) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	customerGateways := make([]SCustomerGateway, 0)
+	for {
+		parts, total, err := self.GetCustomerGateways(len(customerGateways), 50)
+		if err != nil {
+			return nil, err
+		}
+		customerGateways = append(customerGateways, parts...)
+		if len(customerGateways) >= total || len(parts) == 0 {
+			break
+		}
+	}
+
+	ret := make([]cloudprovider.ICloudVpnConnection, len(conns))
+	for i := range conns {
+		conns[i].region = self
+		for j := range vpnGateways {
+			if vpnGateways[j].VpnGatewayId == conns[i].VpnGatewayId {
+				conns[i].vpnGateway = &vpnGateways[j]
+				break
+			}
+		}
+		for j := range customerGateways {
+			if customerGateways[j].CustomerGatewayId == conns[i].CustomerGatewayId {
+				conns[i].customerGateway = &customerGateways[j]
+				break
+			}
+		}
+		ret[i] = &conns[i]
+	}
+	return ret, nil
+}
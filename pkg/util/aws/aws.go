@@ -16,7 +16,9 @@ package aws
 
 import (
 	sdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
@@ -24,6 +26,7 @@ import (
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/objectstore"
 )
 
 const (
@@ -42,10 +45,14 @@ type SAwsClient struct {
 	accessKey    string
 	secret       string
 	iregions     []cloudprovider.ICloudRegion
+
+	// the buckets of all the regions are listed in every region
+	bucketLocations *objectstore.SBucketLocationCache
 }
 
 func NewAwsClient(providerId string, providerName string, accessUrl string, accessKey string, secret string) (*SAwsClient, error) {
 	client := SAwsClient{providerId: providerId, providerName: providerName, accessUrl: accessUrl, accessKey: accessKey, secret: secret}
+	client.bucketLocations = objectstore.NewBucketLocationCache()
 	err := client.fetchRegions()
 	if err != nil {
 		log.Debugf("NewAwsClient %s", err.Error())
@@ -67,11 +74,54 @@ func (self *SAwsClient) getDefaultRegionId() string {
 }
 
 func (self *SAwsClient) getDefaultSession() (*session.Session, error) {
-	defaultRegion := self.getDefaultRegionId()
-	return session.NewSession(&sdk.Config{
-		Region:      sdk.String(defaultRegion),
+	return self.newSession(self.getDefaultRegionId())
+}
+
+// newSession returns a session whose requests go through the api caller of
+// the account, throttled requests are retried by the sdk with backoff
+func (self *SAwsClient) newSession(regionId string) (*session.Session, error) {
+	caller := cloudprovider.GetApiCaller(CLOUD_PROVIDER_AWS, self.accessKey)
+	s, err := session.NewSession(&sdk.Config{
+		Region:      sdk.String(regionId),
 		Credentials: credentials.NewStaticCredentials(self.accessKey, self.secret, ""),
+		MaxRetries:  sdk.Int(caller.GetConfig().MaxRetries),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Handlers.Build.PushBack(func(r *request.Request) {
+		err := caller.Begin(awsApiAction(r))
+		if err != nil {
+			r.Error = err
+		}
+	})
+	s.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error == cloudprovider.ErrApiCircuitOpen {
+			return
+		}
+		caller.End(awsApiAction(r), r.AttemptTime, classifyAwsError(r.Error))
 	})
+	return s, nil
+}
+
+func awsApiAction(r *request.Request) string {
+	return r.ClientInfo.ServiceName + "." + r.Operation.Name
+}
+
+func classifyAwsError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if request.IsErrorThrottle(err) {
+		return cloudprovider.API_ERROR_THROTTLED
+	}
+	if e, ok := err.(awserr.RequestFailure); ok && e.StatusCode() >= 500 {
+		return cloudprovider.API_ERROR_SERVER
+	}
+	if request.IsErrorRetryable(err) {
+		return cloudprovider.API_ERROR_SERVER
+	}
+	return cloudprovider.API_ERROR_CLIENT
 }
 
 func (self *SAwsClient) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
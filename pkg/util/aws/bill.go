@@ -0,0 +1,212 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/objectstore"
+)
+
+const (
+	CUR_COLUMN_USAGE_START = "lineItem/UsageStartDate"
+	CUR_COLUMN_RESOURCE_ID = "lineItem/ResourceId"
+	CUR_COLUMN_PRODUCT     = "lineItem/ProductCode"
+	CUR_COLUMN_COST        = "lineItem/UnblendedCost"
+	CUR_COLUMN_CURRENCY    = "lineItem/CurrencyCode"
+	CUR_COLUMN_REGION      = "product/region"
+)
+
+func getBillResourceType(product string, resourceId string) string {
+	switch {
+	case strings.HasPrefix(resourceId, "i-"):
+		return cloudprovider.CLOUD_BILL_RESOURCE_SERVER
+	case strings.HasPrefix(resourceId, "vol-"):
+		return cloudprovider.CLOUD_BILL_RESOURCE_DISK
+	case strings.HasPrefix(resourceId, "snap-"):
+		return cloudprovider.CLOUD_BILL_RESOURCE_SNAPSHOT
+	case strings.HasPrefix(resourceId, "eipalloc-"):
+		return cloudprovider.CLOUD_BILL_RESOURCE_EIP
+	case strings.HasPrefix(resourceId, "nat-"):
+		return cloudprovider.CLOUD_BILL_RESOURCE_NATGATEWAY
+	}
+	switch product {
+	case "AmazonS3":
+		return cloudprovider.CLOUD_BILL_RESOURCE_BUCKET
+	case "AWSELB", "AmazonElasticLoadBalancing":
+		return cloudprovider.CLOUD_BILL_RESOURCE_LOADBALANCER
+	}
+	return ""
+}
+
+// parseCostReport parses the line items of the day from the csv cost and
+// usage report, the other days of the billing period are skipped
+func parseCostReport(reader io.Reader, day time.Time) ([]cloudprovider.SCloudBillItem, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read report header: %s", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{CUR_COLUMN_USAGE_START, CUR_COLUMN_RESOURCE_ID, CUR_COLUMN_COST} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("report column %s not found", name)
+		}
+	}
+	get := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	items := make([]cloudprovider.SCloudBillItem, 0)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read report: %s", err)
+		}
+		start, err := time.Parse(time.RFC3339, get(record, CUR_COLUMN_USAGE_START))
+		if err != nil || !cloudprovider.BillDate(start).Equal(day) {
+			continue
+		}
+		cost, err := strconv.ParseFloat(get(record, CUR_COLUMN_COST), 64)
+		if err != nil || cost == 0 {
+			continue
+		}
+		currency := get(record, CUR_COLUMN_CURRENCY)
+		if len(currency) == 0 {
+			currency = "USD"
+		}
+		product := get(record, CUR_COLUMN_PRODUCT)
+		resourceId := get(record, CUR_COLUMN_RESOURCE_ID)
+		items = append(items, cloudprovider.SCloudBillItem{
+			ResourceType: getBillResourceType(product, resourceId),
+			ResourceId:   resourceId,
+			RegionId:     get(record, CUR_COLUMN_REGION),
+			Product:      product,
+			Amount:       cost,
+			Currency:     currency,
+			UsageDate:    day,
+		})
+	}
+	return items, nil
+}
+
+// getCostReportKeys returns the csv report files of the billing period of
+// the day, the report is rewritten several times a day in a new assembly
+// directory and only the latest assembly is used
+func getCostReportKeys(objects []objectstore.SObjectInfo, day time.Time) []string {
+	next := day.AddDate(0, 1, 0)
+	period := fmt.Sprintf("%s01-%s01", day.Format("200601"), next.Format("200601"))
+	latestDir := ""
+	latest := time.Time{}
+	for _, obj := range objects {
+		if !strings.Contains(obj.Key, period) {
+			continue
+		}
+		if !strings.HasSuffix(obj.Key, ".csv") && !strings.HasSuffix(obj.Key, ".csv.gz") {
+			continue
+		}
+		if obj.LastModified.After(latest) {
+			latest = obj.LastModified
+			latestDir = path.Dir(obj.Key)
+		}
+	}
+	keys := make([]string, 0)
+	for _, obj := range objects {
+		if path.Dir(obj.Key) != latestDir || !strings.Contains(obj.Key, period) {
+			continue
+		}
+		if strings.HasSuffix(obj.Key, ".csv") || strings.HasSuffix(obj.Key, ".csv.gz") {
+			keys = append(keys, obj.Key)
+		}
+	}
+	return keys
+}
+
+func (self *SAwsClient) readCostReport(cli *objectstore.SObjectStoreClient, bucket string, key string, day time.Time) ([]cloudprovider.SCloudBillItem, error) {
+	body, err := cli.GetObject(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var reader io.Reader = body
+	if strings.HasSuffix(key, ".gz") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	return parseCostReport(reader, day)
+}
+
+// GetCloudBillItems reads the cost and usage report delivered to the bucket,
+// aws provides no API to query the daily bill of the resources
+func (self *SAwsClient) GetCloudBillItems(query cloudprovider.SCloudBillQuery) ([]cloudprovider.SCloudBillItem, error) {
+	if len(query.Bucket) == 0 {
+		return nil, cloudprovider.ErrNotSupported
+	}
+	day := cloudprovider.BillDate(query.Day)
+	cli, err := objectstore.NewObjectStoreClient("", self.getDefaultRegionId(), self.accessKey, self.secret, false)
+	if err != nil {
+		return nil, err
+	}
+	location, err := cli.GetBucketLocation(query.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if location != self.getDefaultRegionId() {
+		cli, err = objectstore.NewObjectStoreClient("", location, self.accessKey, self.secret, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	objects, err := cli.ListObjects(query.Bucket, query.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := getCostReportKeys(objects, day)
+	if len(keys) == 0 {
+		log.Warningf("no cost report of %s found in bucket %s", day.Format("2006-01-02"), query.Bucket)
+	}
+	items := make([]cloudprovider.SCloudBillItem, 0)
+	for _, key := range keys {
+		parts, err := self.readCostReport(cli, query.Bucket, key, day)
+		if err != nil {
+			return nil, fmt.Errorf("read cost report %s: %s", key, err)
+		}
+		items = append(items, parts...)
+	}
+	return cloudprovider.MergeCloudBillItems(items), nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/objectstore"
+)
+
+func TestParseCostReport(t *testing.T) {
+	report := `identity/LineItemId,lineItem/UsageStartDate,lineItem/ProductCode,lineItem/ResourceId,lineItem/UnblendedCost,lineItem/CurrencyCode,product/region
+1,2019-05-01T00:00:00Z,AmazonEC2,i-0123,0.5,USD,us-west-1
+2,2019-05-01T01:00:00Z,AmazonEC2,i-0123,0.5,USD,us-west-1
+3,2019-05-01T00:00:00Z,AmazonEC2,vol-0456,0.1,USD,us-west-1
+4,2019-05-02T00:00:00Z,AmazonEC2,i-0123,0.5,USD,us-west-1
+5,2019-05-01T00:00:00Z,AmazonS3,my-bucket,0,USD,us-west-1
+`
+	day := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	items, err := parseCostReport(strings.NewReader(report), day)
+	if err != nil {
+		t.Fatalf("parseCostReport: %s", err)
+	}
+	items = cloudprovider.MergeCloudBillItems(items)
+	if len(items) != 2 {
+		t.Fatalf("expect 2 items, got %d", len(items))
+	}
+	if items[0].ResourceId != "i-0123" || items[0].ResourceType != cloudprovider.CLOUD_BILL_RESOURCE_SERVER || items[0].Amount != 1.0 {
+		t.Errorf("unexpected server item %#v", items[0])
+	}
+	if items[1].ResourceId != "vol-0456" || items[1].ResourceType != cloudprovider.CLOUD_BILL_RESOURCE_DISK {
+		t.Errorf("unexpected disk item %#v", items[1])
+	}
+}
+
+func TestGetCostReportKeys(t *testing.T) {
+	now := time.Now()
+	objects := []objectstore.SObjectInfo{
+		{Key: "cur/report/20190501-20190601/old/report-1.csv.gz", LastModified: now.Add(-time.Hour)},
+		{Key: "cur/report/20190501-20190601/new/report-1.csv.gz", LastModified: now},
+		{Key: "cur/report/20190501-20190601/new/report-2.csv.gz", LastModified: now},
+		{Key: "cur/report/20190501-20190601/new/report-Manifest.json", LastModified: now},
+		{Key: "cur/report/20190401-20190501/x/report-1.csv.gz", LastModified: now.Add(time.Hour)},
+	}
+	keys := getCostReportKeys(objects, time.Date(2019, 5, 3, 0, 0, 0, 0, time.UTC))
+	if len(keys) != 2 || !strings.Contains(keys[0], "/new/") || !strings.Contains(keys[1], "/new/") {
+		t.Errorf("unexpected report keys %v", keys)
+	}
+}
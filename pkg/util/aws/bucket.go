@@ -0,0 +1,84 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/objectstore"
+)
+
+func (self *SRegion) getBucketClient() (*objectstore.SObjectStoreClient, error) {
+	if self.bucketClient == nil {
+		cli, err := objectstore.NewObjectStoreClient("", self.RegionId, self.client.accessKey, self.client.secret, false)
+		if err != nil {
+			return nil, err
+		}
+		cli.SetLocationCache(self.client.bucketLocations)
+		self.bucketClient = cli
+	}
+	return self.bucketClient, nil
+}
+
+// GetIBuckets returns the buckets located in the region, S3 lists the
+// buckets of all the regions
+func (self *SRegion) GetIBuckets() ([]cloudprovider.ICloudBucket, error) {
+	cli, err := self.getBucketClient()
+	if err != nil {
+		return nil, err
+	}
+	return objectstore.GetBuckets(cli, self, self.RegionId)
+}
+
+func (self *SRegion) GetIBucketById(name string) (cloudprovider.ICloudBucket, error) {
+	cli, err := self.getBucketClient()
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := objectstore.GetBucket(cli, self, name)
+	if err != nil {
+		return nil, err
+	}
+	if bucket.GetLocation() != self.RegionId {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return bucket, nil
+}
+
+// CreateIBucket creates a bucket in the region, S3 sets the storage class
+// per object and has none for a bucket
+func (self *SRegion) CreateIBucket(name string, storageClass string, acl string) (cloudprovider.ICloudBucket, error) {
+	if len(storageClass) > 0 && storageClass != objectstore.DEFAULT_STORAGE_CLASS {
+		return nil, fmt.Errorf("unsupported storage class %s, only %s is supported", storageClass, objectstore.DEFAULT_STORAGE_CLASS)
+	}
+	cli, err := self.getBucketClient()
+	if err != nil {
+		return nil, err
+	}
+	err = cli.CreateBucket(name, self.RegionId, acl)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetIBucketById(name)
+}
+
+func (self *SRegion) DeleteIBucket(name string) error {
+	cli, err := self.getBucketClient()
+	if err != nil {
+		return err
+	}
+	return cli.DeleteBucket(name)
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// the cloudtrail service of the sdk is not vendored either, it speaks the
+// json protocol which is not vendored, so the requests are encoded below
+const (
+	CLOUDTRAIL_SERVICE_NAME  = "cloudtrail"
+	CLOUDTRAIL_SERVICE_ID    = "CloudTrail"
+	CLOUDTRAIL_API_VERSION   = "2013-11-01"
+	CLOUDTRAIL_TARGET_PREFIX = "com.amazonaws.cloudtrail.v20131101.CloudTrail_20131101"
+)
+
+type SCloudTrailClient struct {
+	*client.Client
+}
+
+func newCloudTrailClient(s *session.Session) *SCloudTrailClient {
+	c := s.ClientConfig(CLOUDTRAIL_SERVICE_NAME)
+	cli := client.New(
+		*c.Config,
+		metadata.ClientInfo{
+			ServiceName:   CLOUDTRAIL_SERVICE_NAME,
+			ServiceID:     CLOUDTRAIL_SERVICE_ID,
+			SigningName:   c.SigningName,
+			SigningRegion: c.SigningRegion,
+			Endpoint:      c.Endpoint,
+			APIVersion:    CLOUDTRAIL_API_VERSION,
+		},
+		c.Handlers,
+	)
+	cli.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	cli.Handlers.Build.PushBackNamed(request.NamedHandler{Name: "cloudtrail.Build", Fn: cloudtrailBuild})
+	cli.Handlers.Unmarshal.PushBackNamed(request.NamedHandler{Name: "cloudtrail.Unmarshal", Fn: cloudtrailUnmarshal})
+	cli.Handlers.UnmarshalMeta.PushBackNamed(request.NamedHandler{Name: "cloudtrail.UnmarshalMeta", Fn: cloudtrailUnmarshalMeta})
+	cli.Handlers.UnmarshalError.PushBackNamed(request.NamedHandler{Name: "cloudtrail.UnmarshalError", Fn: cloudtrailUnmarshalError})
+	return &SCloudTrailClient{Client: cli}
+}
+
+func cloudtrailBuild(r *request.Request) {
+	body, err := json.Marshal(r.Params)
+	if err != nil {
+		r.Error = awserr.New(request.ErrCodeSerialization, "failed encoding cloudtrail request", err)
+		return
+	}
+	r.SetBufferBody(body)
+	r.HTTPRequest.Header.Set("X-Amz-Target", fmt.Sprintf("%s.%s", CLOUDTRAIL_TARGET_PREFIX, r.Operation.Name))
+	r.HTTPRequest.Header.Set("Content-Type", "application/x-amz-json-1.1")
+}
+
+func cloudtrailUnmarshal(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	if r.DataFilled() {
+		err := json.NewDecoder(r.HTTPResponse.Body).Decode(r.Data)
+		if err != nil {
+			r.Error = awserr.New(request.ErrCodeSerialization, "failed decoding cloudtrail response", err)
+		}
+	}
+}
+
+func cloudtrailUnmarshalMeta(r *request.Request) {
+	r.RequestID = r.HTTPResponse.Header.Get("X-Amzn-Requestid")
+}
+
+func cloudtrailUnmarshalError(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	body := bytes.Buffer{}
+	body.ReadFrom(r.HTTPResponse.Body)
+	ret := struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(body.Bytes(), &ret); err != nil || len(ret.Type) == 0 {
+		r.Error = awserr.NewRequestFailure(awserr.New(request.ErrCodeSerialization, body.String(), err), r.HTTPResponse.StatusCode, r.RequestID)
+		return
+	}
+	// the type is prefixed with the namespace, e.g. com.amazonaws...#InvalidNextTokenException
+	code := ret.Type
+	if idx := strings.LastIndex(code, "#"); idx >= 0 {
+		code = code[idx+1:]
+	}
+	r.Error = awserr.NewRequestFailure(awserr.New(code, ret.Message, nil), r.HTTPResponse.StatusCode, r.RequestID)
+}
+
+func (self *SCloudTrailClient) request(action string, input interface{}, output interface{}) error {
+	op := &request.Operation{
+		Name:       action,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	return self.NewRequest(op, input, output).Send()
+}
+
+type cloudtrailLookupAttribute struct {
+	AttributeKey   string
+	AttributeValue string
+}
+
+type cloudtrailLookupEventsInput struct {
+	LookupAttributes []cloudtrailLookupAttribute `json:",omitempty"`
+	// seconds since the epoch
+	StartTime  int64
+	EndTime    int64
+	MaxResults int64
+	NextToken  string `json:",omitempty"`
+}
+
+type cloudtrailResource struct {
+	ResourceType string
+	ResourceName string
+}
+
+type cloudtrailEvent struct {
+	EventId     string
+	EventName   string
+	EventSource string
+	// seconds since the epoch
+	EventTime float64
+	Resources []cloudtrailResource
+}
+
+type cloudtrailLookupEventsOutput struct {
+	Events    []cloudtrailEvent
+	NextToken string
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+func TestCloudtrailEventAction(t *testing.T) {
+	cases := []struct {
+		eventName string
+		want      string
+	}{
+		{"RunInstances", cloudprovider.CLOUD_EVENT_ACTION_CREATE},
+		{"AllocateAddress", cloudprovider.CLOUD_EVENT_ACTION_CREATE},
+		{"TerminateInstances", cloudprovider.CLOUD_EVENT_ACTION_DELETE},
+		{"ReleaseAddress", cloudprovider.CLOUD_EVENT_ACTION_DELETE},
+		{"ModifyInstanceAttribute", cloudprovider.CLOUD_EVENT_ACTION_UPDATE},
+	}
+	for _, c := range cases {
+		if got := cloudtrailEventAction(c.eventName); got != c.want {
+			t.Errorf("%s: want %s got %s", c.eventName, c.want, got)
+		}
+	}
+}
+
+func TestCloudtrailUnmarshal(t *testing.T) {
+	body := `{"Events":[{"EventName":"RunInstances","EventTime":1.5707808E9,"Resources":[{"ResourceType":"AWS::EC2::Instance","ResourceName":"i-0123"}]}],"NextToken":"abc"}`
+	output := &cloudtrailLookupEventsOutput{}
+	r := &request.Request{
+		HTTPResponse: &http.Response{Body: ioutil.NopCloser(bytes.NewBufferString(body))},
+		Data:         output,
+	}
+	cloudtrailUnmarshal(r)
+	if r.Error != nil {
+		t.Fatalf("unmarshal error %s", r.Error)
+	}
+	if output.NextToken != "abc" || len(output.Events) != 1 || output.Events[0].Resources[0].ResourceName != "i-0123" {
+		t.Errorf("unexpected output %#v", output)
+	}
+
+	r = &request.Request{
+		HTTPResponse: &http.Response{
+			StatusCode: 400,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"__type":"com.amazonaws.cloudtrail.v20131101#InvalidNextTokenException","message":"bad token"}`)),
+		},
+	}
+	cloudtrailUnmarshalError(r)
+	aerr, ok := r.Error.(awserr.RequestFailure)
+	if !ok {
+		t.Fatalf("unexpected error %#v", r.Error)
+	}
+	if aerr.Code() != "InvalidNextTokenException" || aerr.StatusCode() != 400 {
+		t.Errorf("unexpected error %s", aerr)
+	}
+}
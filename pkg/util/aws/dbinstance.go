@@ -0,0 +1,355 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"strings"
+	"time"
+
+	sdk "github.com/aws/aws-sdk-go/aws"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type SDBInstance struct {
+	region *SRegion
+
+	DBInstanceIdentifier string
+	DBInstanceClass      string
+	DBInstanceStatus     string
+	Engine               string
+	EngineVersion        string
+	AllocatedStorage     int
+	StorageType          string
+	AvailabilityZone     string
+	MasterUsername       string
+	InstanceCreateTime   time.Time
+	EndpointAddress      string
+	EndpointPort         int
+	VpcId                string
+}
+
+func (self *SRegion) getRdsClient() (*SRdsClient, error) {
+	if self.rdsClient == nil {
+		s, err := self.getAwsSession()
+		if err != nil {
+			return nil, err
+		}
+		self.rdsClient = newRdsClient(s)
+	}
+	return self.rdsClient, nil
+}
+
+func (self *SRegion) newDBInstance(instance *rdsDBInstance) SDBInstance {
+	ret := SDBInstance{
+		region:               self,
+		DBInstanceIdentifier: sdk.StringValue(instance.DBInstanceIdentifier),
+		DBInstanceClass:      sdk.StringValue(instance.DBInstanceClass),
+		DBInstanceStatus:     sdk.StringValue(instance.DBInstanceStatus),
+		Engine:               sdk.StringValue(instance.Engine),
+		EngineVersion:        sdk.StringValue(instance.EngineVersion),
+		AllocatedStorage:     int(sdk.Int64Value(instance.AllocatedStorage)),
+		StorageType:          sdk.StringValue(instance.StorageType),
+		AvailabilityZone:     sdk.StringValue(instance.AvailabilityZone),
+		MasterUsername:       sdk.StringValue(instance.MasterUsername),
+		InstanceCreateTime:   sdk.TimeValue(instance.InstanceCreateTime),
+	}
+	if instance.Endpoint != nil {
+		ret.EndpointAddress = sdk.StringValue(instance.Endpoint.Address)
+		ret.EndpointPort = int(sdk.Int64Value(instance.Endpoint.Port))
+	}
+	if instance.DBSubnetGroup != nil {
+		ret.VpcId = sdk.StringValue(instance.DBSubnetGroup.VpcId)
+	}
+	return ret
+}
+
+func (self *SRegion) GetDBInstances(instanceId string) ([]SDBInstance, error) {
+	client, err := self.getRdsClient()
+	if err != nil {
+		return nil, err
+	}
+	input := &rdsDescribeDBInstancesInput{MaxRecords: sdk.Int64(100)}
+	if len(instanceId) > 0 {
+		input.DBInstanceIdentifier = sdk.String(instanceId)
+	}
+	instances := make([]SDBInstance, 0)
+	for {
+		output := &rdsDescribeDBInstancesOutput{}
+		err := client.request("DescribeDBInstances", input, output)
+		if err != nil {
+			if strings.Contains(err.Error(), "DBInstanceNotFound") {
+				return nil, cloudprovider.ErrNotFound
+			}
+			return nil, err
+		}
+		for _, instance := range output.DBInstances {
+			instances = append(instances, self.newDBInstance(instance))
+		}
+		if len(sdk.StringValue(output.Marker)) == 0 {
+			break
+		}
+		input.Marker = output.Marker
+	}
+	return instances, nil
+}
+
+func (self *SRegion) GetIDBInstances() ([]cloudprovider.ICloudDBInstance, error) {
+	instances, err := self.GetDBInstances("")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudDBInstance, len(instances))
+	for i := range instances {
+		ret[i] = &instances[i]
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIDBInstanceById(id string) (cloudprovider.ICloudDBInstance, error) {
+	instances, err := self.GetDBInstances(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) != 1 {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return &instances[0], nil
+}
+
+func (self *SDBInstance) GetId() string {
+	return self.DBInstanceIdentifier
+}
+
+func (self *SDBInstance) GetName() string {
+	return self.DBInstanceIdentifier
+}
+
+func (self *SDBInstance) GetGlobalId() string {
+	return self.DBInstanceIdentifier
+}
+
+func (self *SDBInstance) GetStatus() string {
+	switch self.DBInstanceStatus {
+	case "available":
+		return api.DBINSTANCE_STATUS_RUNNING
+	case "creating":
+		return api.DBINSTANCE_STATUS_DEPLOYING
+	case "starting":
+		return api.DBINSTANCE_STATUS_STARTING
+	case "stopping":
+		return api.DBINSTANCE_STATUS_STOPPING
+	case "stopped":
+		return api.DBINSTANCE_STATUS_STOPPED
+	case "rebooting":
+		return api.DBINSTANCE_STATUS_REBOOTING
+	case "modifying", "storage-optimization":
+		return api.DBINSTANCE_STATUS_CHANGE_CONFIG
+	case "deleting":
+		return api.DBINSTANCE_STATUS_DELETING
+	case "backing-up", "maintenance", "upgrading", "renaming", "resetting-master-credentials", "configuring-enhanced-monitoring":
+		return api.DBINSTANCE_STATUS_MAINTENANCE
+	case "failed", "incompatible-network", "incompatible-option-group", "incompatible-parameters", "incompatible-restore", "storage-full":
+		return api.DBINSTANCE_STATUS_FAILED
+	}
+	return api.DBINSTANCE_STATUS_UNKNOWN
+}
+
+func (self *SDBInstance) Refresh() error {
+	instances, err := self.region.GetDBInstances(self.DBInstanceIdentifier)
+	if err != nil {
+		return err
+	}
+	if len(instances) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(self, instances[0])
+}
+
+func (self *SDBInstance) IsEmulated() bool {
+	return false
+}
+
+func (self *SDBInstance) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SDBInstance) GetProjectId() string {
+	return ""
+}
+
+// reserved instances of rds are billing discounts rather than instances
+func (self *SDBInstance) GetBillingType() string {
+	return models.BILLING_TYPE_POSTPAID
+}
+
+func (self *SDBInstance) GetExpiredAt() time.Time {
+	return time.Time{}
+}
+
+func (self *SDBInstance) GetEngine() string {
+	switch {
+	case self.Engine == "mysql":
+		return api.DBINSTANCE_ENGINE_MYSQL
+	case self.Engine == "mariadb":
+		return api.DBINSTANCE_ENGINE_MARIADB
+	case self.Engine == "postgres":
+		return api.DBINSTANCE_ENGINE_POSTGRESQL
+	case strings.HasPrefix(self.Engine, "sqlserver"):
+		return api.DBINSTANCE_ENGINE_SQLSERVER
+	case strings.HasPrefix(self.Engine, "oracle"):
+		return api.DBINSTANCE_ENGINE_ORACLE
+	}
+	return self.Engine
+}
+
+func (self *SDBInstance) GetEngineVersion() string {
+	return self.EngineVersion
+}
+
+func (self *SDBInstance) GetInstanceType() string {
+	return self.DBInstanceClass
+}
+
+// the cpu and memory of a db instance class are not returned by the apis
+func (self *SDBInstance) GetVcpuCount() int {
+	return 0
+}
+
+func (self *SDBInstance) GetVmemSizeMB() int {
+	return 0
+}
+
+func (self *SDBInstance) GetStorageType() string {
+	return self.StorageType
+}
+
+func (self *SDBInstance) GetStorageSizeGB() int {
+	return self.AllocatedStorage
+}
+
+func (self *SDBInstance) GetZoneId() string {
+	return self.AvailabilityZone
+}
+
+func (self *SDBInstance) GetVpcId() string {
+	return self.VpcId
+}
+
+func (self *SDBInstance) GetCreatedAt() time.Time {
+	return self.InstanceCreateTime
+}
+
+func (self *SDBInstance) GetEndpoints() []cloudprovider.SDBInstanceEndpoint {
+	if len(self.EndpointAddress) == 0 {
+		return []cloudprovider.SDBInstanceEndpoint{}
+	}
+	return []cloudprovider.SDBInstanceEndpoint{
+		{
+			Type:    cloudprovider.DBINSTANCE_ENDPOINT_INTRANET,
+			Address: self.EndpointAddress,
+			Port:    self.EndpointPort,
+		},
+	}
+}
+
+func (self *SDBInstance) GetBackups() ([]cloudprovider.SDBInstanceBackup, error) {
+	client, err := self.region.getRdsClient()
+	if err != nil {
+		return nil, err
+	}
+	input := &rdsDescribeDBSnapshotsInput{DBInstanceIdentifier: sdk.String(self.DBInstanceIdentifier)}
+	backups := make([]cloudprovider.SDBInstanceBackup, 0)
+	for {
+		output := &rdsDescribeDBSnapshotsOutput{}
+		err := client.request("DescribeDBSnapshots", input, output)
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range output.DBSnapshots {
+			backup := cloudprovider.SDBInstanceBackup{
+				Id:        sdk.StringValue(snapshot.DBSnapshotIdentifier),
+				Mode:      cloudprovider.DBINSTANCE_BACKUP_AUTOMATED,
+				Status:    sdk.StringValue(snapshot.Status),
+				SizeMB:    int(sdk.Int64Value(snapshot.AllocatedStorage)) * 1024,
+				StartTime: sdk.TimeValue(snapshot.SnapshotCreateTime),
+				EndTime:   sdk.TimeValue(snapshot.SnapshotCreateTime),
+			}
+			if sdk.StringValue(snapshot.SnapshotType) == "manual" {
+				backup.Mode = cloudprovider.DBINSTANCE_BACKUP_MANUAL
+			}
+			backups = append(backups, backup)
+		}
+		if len(sdk.StringValue(output.Marker)) == 0 {
+			break
+		}
+		input.Marker = output.Marker
+	}
+	return backups, nil
+}
+
+// only the master account is known to the apis of rds, the other accounts
+// are managed inside the database
+func (self *SDBInstance) GetAccounts() ([]cloudprovider.SDBInstanceAccount, error) {
+	if len(self.MasterUsername) == 0 {
+		return []cloudprovider.SDBInstanceAccount{}, nil
+	}
+	return []cloudprovider.SDBInstanceAccount{
+		{Name: self.MasterUsername, Status: "available", Databases: []string{}},
+	}, nil
+}
+
+func (self *SDBInstance) doAction(action string) error {
+	client, err := self.region.getRdsClient()
+	if err != nil {
+		return err
+	}
+	input := &rdsDBInstanceActionInput{DBInstanceIdentifier: sdk.String(self.DBInstanceIdentifier)}
+	return client.request(action, input, &rdsDBInstanceActionOutput{})
+}
+
+func (self *SDBInstance) Start() error {
+	return self.doAction("StartDBInstance")
+}
+
+func (self *SDBInstance) Stop() error {
+	return self.doAction("StopDBInstance")
+}
+
+func (self *SDBInstance) Reboot() error {
+	return self.doAction("RebootDBInstance")
+}
+
+func (self *SDBInstance) ChangeConfig(config *cloudprovider.SDBInstanceChangeConfig) error {
+	client, err := self.region.getRdsClient()
+	if err != nil {
+		return err
+	}
+	input := &rdsModifyDBInstanceInput{
+		DBInstanceIdentifier: sdk.String(self.DBInstanceIdentifier),
+		ApplyImmediately:     sdk.Bool(true),
+	}
+	if len(config.InstanceType) > 0 {
+		input.DBInstanceClass = sdk.String(config.InstanceType)
+	}
+	if config.StorageSizeGB > 0 {
+		input.AllocatedStorage = sdk.Int64(int64(config.StorageSizeGB))
+	}
+	return client.request("ModifyDBInstance", input, &rdsDBInstanceActionOutput{})
+}
@@ -0,0 +1,308 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	sdk "github.com/aws/aws-sdk-go/aws"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// route53 manages record sets instead of records, each value of a record
+// set is exposed as a record whose id is "<fqdn>|<type>|<value>"
+type SDnsRecord struct {
+	zone *SDnsZone
+
+	Fqdn  string
+	Type  string
+	Value string
+	Ttl   int
+}
+
+func (self *SDnsRecord) GetGlobalId() string {
+	return strings.Join([]string{self.Fqdn, self.Type, self.Value}, "|")
+}
+
+func (self *SDnsRecord) GetDnsName() string {
+	if self.Fqdn == self.zone.ZoneName {
+		return "@"
+	}
+	return strings.TrimSuffix(self.Fqdn, "."+self.zone.ZoneName)
+}
+
+func (self *SDnsRecord) GetDnsType() string {
+	return self.Type
+}
+
+func (self *SDnsRecord) GetDnsValue() string {
+	return self.Value
+}
+
+func (self *SDnsRecord) GetTtl() int {
+	return self.Ttl
+}
+
+type SDnsZone struct {
+	client *SAwsClient
+
+	ZoneId      string
+	ZoneName    string
+	RecordCount int
+	IsPrivate   bool
+}
+
+func (self *SDnsZone) GetId() string {
+	return self.ZoneId
+}
+
+func (self *SDnsZone) GetName() string {
+	return self.ZoneName
+}
+
+func (self *SDnsZone) GetGlobalId() string {
+	return self.ZoneId
+}
+
+func (self *SDnsZone) GetStatus() string {
+	return api.DNS_ZONE_STATUS_AVAILABLE
+}
+
+func (self *SDnsZone) Refresh() error {
+	return nil
+}
+
+func (self *SDnsZone) IsEmulated() bool {
+	return false
+}
+
+func (self *SDnsZone) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SDnsZone) GetZoneType() string {
+	if self.IsPrivate {
+		return cloudprovider.DNS_ZONE_TYPE_PRIVATE
+	}
+	return cloudprovider.DNS_ZONE_TYPE_PUBLIC
+}
+
+// route53 names are absolute with the trailing dot, the asterisk of the
+// wildcard names is escaped in octal
+func route53Name(name string) string {
+	return strings.Replace(strings.TrimSuffix(name, "."), "\\052", "*", -1)
+}
+
+func (self *SDnsZone) fqdn(name string) string {
+	if name == "@" || len(name) == 0 {
+		return self.ZoneName
+	}
+	return name + "." + self.ZoneName
+}
+
+func (self *SDnsZone) GetIDnsRecords() ([]cloudprovider.ICloudDnsRecord, error) {
+	client, err := self.client.getRoute53Client()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudDnsRecord, 0)
+	input := &route53ListResourceRecordSetsInput{HostedZoneId: sdk.String(self.ZoneId)}
+	for {
+		output := &route53ListResourceRecordSetsOutput{}
+		err := client.request("ListResourceRecordSets", "GET", "/hostedzone/{Id}/rrset", input, output)
+		if err != nil {
+			return nil, err
+		}
+		for _, rrset := range output.ResourceRecordSets {
+			// alias and routing policy record sets have no local counterpart
+			if rrset.AliasTarget != nil || rrset.SetIdentifier != nil {
+				continue
+			}
+			for _, rr := range rrset.ResourceRecords {
+				ret = append(ret, &SDnsRecord{
+					zone:  self,
+					Fqdn:  route53Name(sdk.StringValue(rrset.Name)),
+					Type:  sdk.StringValue(rrset.Type),
+					Value: sdk.StringValue(rr.Value),
+					Ttl:   int(sdk.Int64Value(rrset.TTL)),
+				})
+			}
+		}
+		if !sdk.BoolValue(output.IsTruncated) {
+			break
+		}
+		input.StartRecordName = output.NextRecordName
+		input.StartRecordType = output.NextRecordType
+	}
+	return ret, nil
+}
+
+func (self *SDnsZone) getRecordSet(fqdn string, typ string) (*route53ResourceRecordSet, error) {
+	client, err := self.client.getRoute53Client()
+	if err != nil {
+		return nil, err
+	}
+	input := &route53ListResourceRecordSetsInput{
+		HostedZoneId:    sdk.String(self.ZoneId),
+		StartRecordName: sdk.String(fqdn),
+		StartRecordType: sdk.String(typ),
+		MaxItems:        sdk.String("1"),
+	}
+	output := &route53ListResourceRecordSetsOutput{}
+	err = client.request("ListResourceRecordSets", "GET", "/hostedzone/{Id}/rrset", input, output)
+	if err != nil {
+		return nil, err
+	}
+	for _, rrset := range output.ResourceRecordSets {
+		if strings.EqualFold(route53Name(sdk.StringValue(rrset.Name)), fqdn) && sdk.StringValue(rrset.Type) == typ {
+			return rrset, nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SDnsZone) changeRecordSet(action string, rrset *route53ResourceRecordSet) error {
+	client, err := self.client.getRoute53Client()
+	if err != nil {
+		return err
+	}
+	input := &route53ChangeResourceRecordSetsInput{
+		HostedZoneId: sdk.String(self.ZoneId),
+		ChangeBatch: &route53ChangeBatch{
+			Changes: []*route53Change{
+				{Action: sdk.String(action), ResourceRecordSet: rrset},
+			},
+		},
+	}
+	return client.request("ChangeResourceRecordSets", "POST", "/hostedzone/{Id}/rrset/", input, &route53ChangeResourceRecordSetsOutput{})
+}
+
+func (self *SDnsZone) AddDnsRecord(record cloudprovider.SDnsRecord) (string, error) {
+	fqdn := self.fqdn(record.Name)
+	rrset, err := self.getRecordSet(fqdn, record.Type)
+	if err != nil {
+		if err != cloudprovider.ErrNotFound {
+			return "", err
+		}
+		rrset = &route53ResourceRecordSet{
+			Name: sdk.String(fqdn),
+			Type: sdk.String(record.Type),
+			TTL:  sdk.Int64(300),
+		}
+	}
+	if record.Ttl > 0 {
+		rrset.TTL = sdk.Int64(int64(record.Ttl))
+	}
+	exists := false
+	for _, rr := range rrset.ResourceRecords {
+		if sdk.StringValue(rr.Value) == record.Value {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		rrset.ResourceRecords = append(rrset.ResourceRecords, &route53ResourceRecord{Value: sdk.String(record.Value)})
+	}
+	err = self.changeRecordSet("UPSERT", rrset)
+	if err != nil {
+		return "", err
+	}
+	rec := SDnsRecord{zone: self, Fqdn: fqdn, Type: record.Type, Value: record.Value}
+	return rec.GetGlobalId(), nil
+}
+
+func (self *SDnsZone) RemoveDnsRecord(recordId string) error {
+	parts := strings.SplitN(recordId, "|", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid route53 record id %s", recordId)
+	}
+	rrset, err := self.getRecordSet(parts[0], parts[1])
+	if err != nil {
+		if err == cloudprovider.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	values := make([]*route53ResourceRecord, 0, len(rrset.ResourceRecords))
+	for _, rr := range rrset.ResourceRecords {
+		if sdk.StringValue(rr.Value) != parts[2] {
+			values = append(values, rr)
+		}
+	}
+	if len(values) == len(rrset.ResourceRecords) {
+		return nil
+	}
+	if len(values) == 0 {
+		// a record set is deleted with exactly its current values
+		return self.changeRecordSet("DELETE", rrset)
+	}
+	rrset.ResourceRecords = values
+	return self.changeRecordSet("UPSERT", rrset)
+}
+
+func (self *SAwsClient) getRoute53Client() (*SRoute53Client, error) {
+	s, err := self.getDefaultSession()
+	if err != nil {
+		return nil, err
+	}
+	return newRoute53Client(s), nil
+}
+
+func (self *SAwsClient) GetDnsZones() ([]SDnsZone, error) {
+	client, err := self.getRoute53Client()
+	if err != nil {
+		return nil, err
+	}
+	zones := make([]SDnsZone, 0)
+	input := &route53ListHostedZonesInput{MaxItems: sdk.String("100")}
+	for {
+		output := &route53ListHostedZonesOutput{}
+		err := client.request("ListHostedZones", "GET", "/hostedzone", input, output)
+		if err != nil {
+			return nil, err
+		}
+		for _, zone := range output.HostedZones {
+			zones = append(zones, SDnsZone{
+				client:      self,
+				ZoneId:      strings.TrimPrefix(sdk.StringValue(zone.Id), "/hostedzone/"),
+				ZoneName:    route53Name(sdk.StringValue(zone.Name)),
+				RecordCount: int(sdk.Int64Value(zone.ResourceRecordSetCount)),
+				IsPrivate:   zone.Config != nil && sdk.BoolValue(zone.Config.PrivateZone),
+			})
+		}
+		if !sdk.BoolValue(output.IsTruncated) {
+			break
+		}
+		input.Marker = output.NextMarker
+	}
+	return zones, nil
+}
+
+func (self *SAwsClient) GetIDnsZones() ([]cloudprovider.ICloudDnsZone, error) {
+	zones, err := self.GetDnsZones()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudDnsZone, len(zones))
+	for i := range zones {
+		ret[i] = &zones[i]
+	}
+	return ret, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"strings"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	// CloudTrail keeps the management events of the last 90 days
+	CLOUDTRAIL_EVENT_RETENTION = 90 * 24 * time.Hour
+)
+
+// cloudtrailResourceTypes maps the resource types of CloudTrail to the event
+// resource types
+var cloudtrailResourceTypes = map[string]string{
+	"AWS::EC2::Instance":      cloudprovider.CLOUD_EVENT_RESOURCE_SERVER,
+	"AWS::EC2::Volume":        cloudprovider.CLOUD_EVENT_RESOURCE_DISK,
+	"AWS::EC2::Snapshot":      cloudprovider.CLOUD_EVENT_RESOURCE_SNAPSHOT,
+	"AWS::EC2::SecurityGroup": cloudprovider.CLOUD_EVENT_RESOURCE_SECGROUP,
+	"AWS::EC2::VPC":           cloudprovider.CLOUD_EVENT_RESOURCE_VPC,
+	"AWS::EC2::EIP":           cloudprovider.CLOUD_EVENT_RESOURCE_EIP,
+	"AWS::S3::Bucket":         cloudprovider.CLOUD_EVENT_RESOURCE_BUCKET,
+}
+
+func (self *SRegion) getCloudTrailClient() (*SCloudTrailClient, error) {
+	if self.cloudtrailClient == nil {
+		s, err := self.getAwsSession()
+		if err != nil {
+			return nil, err
+		}
+		self.cloudtrailClient = newCloudTrailClient(s)
+	}
+	return self.cloudtrailClient, nil
+}
+
+func (self *SRegion) LookupEvents(start time.Time, end time.Time) ([]cloudtrailEvent, error) {
+	client, err := self.getCloudTrailClient()
+	if err != nil {
+		return nil, err
+	}
+	input := &cloudtrailLookupEventsInput{
+		LookupAttributes: []cloudtrailLookupAttribute{
+			{AttributeKey: "ReadOnly", AttributeValue: "false"},
+		},
+		StartTime:  start.Unix(),
+		EndTime:    end.Unix(),
+		MaxResults: 50,
+	}
+	events := []cloudtrailEvent{}
+	for {
+		output := &cloudtrailLookupEventsOutput{}
+		err := client.request("LookupEvents", input, output)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, output.Events...)
+		if len(output.NextToken) == 0 || len(output.Events) == 0 {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	return events, nil
+}
+
+func cloudtrailEventAction(eventName string) string {
+	for _, prefix := range []string{"Create", "Allocate", "Run", "Copy", "Import"} {
+		if strings.HasPrefix(eventName, prefix) {
+			return cloudprovider.CLOUD_EVENT_ACTION_CREATE
+		}
+	}
+	for _, prefix := range []string{"Delete", "Release", "Terminate"} {
+		if strings.HasPrefix(eventName, prefix) {
+			return cloudprovider.CLOUD_EVENT_ACTION_DELETE
+		}
+	}
+	return cloudprovider.CLOUD_EVENT_ACTION_UPDATE
+}
+
+func (self *SRegion) GetICloudEvents(start time.Time, end time.Time) ([]cloudprovider.SCloudEvent, error) {
+	if time.Now().Sub(start) > CLOUDTRAIL_EVENT_RETENTION {
+		return nil, cloudprovider.ErrEventExpired
+	}
+	events, err := self.LookupEvents(start, end)
+	if err != nil {
+		return nil, err
+	}
+	ret := []cloudprovider.SCloudEvent{}
+	for _, event := range events {
+		sec := int64(event.EventTime)
+		createdAt := time.Unix(sec, int64((event.EventTime-float64(sec))*float64(time.Second))).UTC()
+		// a single event may touch several resources, e.g. TerminateInstances
+		for _, resource := range event.Resources {
+			resourceType, ok := cloudtrailResourceTypes[resource.ResourceType]
+			if !ok {
+				resourceType = resource.ResourceType
+			}
+			// eips are also named by their public ips, only the allocation
+			// ids are their global ids
+			if resourceType == cloudprovider.CLOUD_EVENT_RESOURCE_EIP && !strings.HasPrefix(resource.ResourceName, "eipalloc-") {
+				continue
+			}
+			if len(resource.ResourceName) == 0 {
+				continue
+			}
+			ret = append(ret, cloudprovider.SCloudEvent{
+				ResourceType: resourceType,
+				ResourceId:   resource.ResourceName,
+				Action:       cloudtrailEventAction(event.EventName),
+				CreatedAt:    createdAt,
+			})
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SNatGatewayAddress struct {
+	AllocationId string
+	PublicIp     string
+	PrivateIp    string
+}
+
+// aws nat gateway works in a single subnet and translates the source address
+// of all the traffic routed to it, so the snat entries are derived from
+// the public addresses of the gateway and no dnat entry is available
+type SNatGateway struct {
+	vpc *SVpc
+
+	NatGatewayId string
+	SubnetId     string
+	State        string
+	Addresses    []SNatGatewayAddress
+	Tags         TagSpec
+}
+
+type SNatSEntry struct {
+	nat *SNatGateway
+
+	address SNatGatewayAddress
+}
+
+func (self *SRegion) GetNatGateways(vpcId string, natGatewayId string) ([]SNatGateway, error) {
+	params := &ec2.DescribeNatGatewaysInput{}
+	filters := make([]*ec2.Filter, 0)
+	if len(vpcId) > 0 {
+		filters = AppendSingleValueFilter(filters, "vpc-id", vpcId)
+	}
+	if len(natGatewayId) > 0 {
+		params.SetNatGatewayIds([]*string{&natGatewayId})
+	}
+	if len(filters) > 0 {
+		params.SetFilter(filters)
+	}
+
+	nats := make([]SNatGateway, 0)
+	err := self.ec2Client.DescribeNatGatewaysPages(params, func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, gw := range page.NatGateways {
+			tagspec := TagSpec{ResourceType: "natgateway"}
+			tagspec.LoadingEc2Tags(gw.Tags)
+			nat := SNatGateway{
+				NatGatewayId: StrVal(gw.NatGatewayId),
+				SubnetId:     StrVal(gw.SubnetId),
+				State:        StrVal(gw.State),
+				Tags:         tagspec,
+			}
+			for _, addr := range gw.NatGatewayAddresses {
+				nat.Addresses = append(nat.Addresses, SNatGatewayAddress{
+					AllocationId: StrVal(addr.AllocationId),
+					PublicIp:     StrVal(addr.PublicIp),
+					PrivateIp:    StrVal(addr.PrivateIp),
+				})
+			}
+			nats = append(nats, nat)
+		}
+		return true
+	})
+	err = parseNotFoundError(err)
+	if err != nil {
+		return nil, err
+	}
+	return nats, nil
+}
+
+func (nat *SNatGateway) GetId() string {
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGateway) GetName() string {
+	if name := nat.Tags.GetNameTag(); len(name) > 0 {
+		return name
+	}
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGateway) GetGlobalId() string {
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGateway) GetStatus() string {
+	switch nat.State {
+	case ec2.NatGatewayStatePending:
+		return api.NAT_STATUS_ALLOCATE
+	case ec2.NatGatewayStateAvailable:
+		return api.NAT_STATUS_AVAILABLE
+	case ec2.NatGatewayStateDeleting:
+		return api.NAT_STATUS_DELETING
+	case ec2.NatGatewayStateFailed:
+		return api.NAT_STATUS_FAILED
+	default:
+		return api.NAT_STATUS_UNKNOWN
+	}
+}
+
+func (nat *SNatGateway) Refresh() error {
+	nats, err := nat.vpc.region.GetNatGateways("", nat.NatGatewayId)
+	if err != nil {
+		return err
+	}
+	if len(nats) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(nat, nats[0])
+}
+
+func (nat *SNatGateway) IsEmulated() bool {
+	return false
+}
+
+func (nat *SNatGateway) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (nat *SNatGateway) GetNatSpec() string {
+	return ""
+}
+
+func (nat *SNatGateway) GetDescription() string {
+	return nat.Tags.GetDescTag()
+}
+
+func (nat *SNatGateway) GetINatSEntries() ([]cloudprovider.ICloudNatSEntry, error) {
+	ientries := make([]cloudprovider.ICloudNatSEntry, len(nat.Addresses))
+	for i := 0; i < len(nat.Addresses); i++ {
+		ientries[i] = &SNatSEntry{nat: nat, address: nat.Addresses[i]}
+	}
+	return ientries, nil
+}
+
+func (nat *SNatGateway) GetINatDEntries() ([]cloudprovider.ICloudNatDEntry, error) {
+	return []cloudprovider.ICloudNatDEntry{}, nil
+}
+
+func (nat *SNatGateway) CreateINatSEntry(rule cloudprovider.SNatSRule) (cloudprovider.ICloudNatSEntry, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (nat *SNatGateway) CreateINatDEntry(rule cloudprovider.SNatDRule) (cloudprovider.ICloudNatDEntry, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (entry *SNatSEntry) GetId() string {
+	return fmt.Sprintf("%s/%s", entry.nat.NatGatewayId, entry.address.AllocationId)
+}
+
+func (entry *SNatSEntry) GetName() string {
+	return entry.address.PublicIp
+}
+
+func (entry *SNatSEntry) GetGlobalId() string {
+	return entry.GetId()
+}
+
+func (entry *SNatSEntry) GetStatus() string {
+	return entry.nat.GetStatus()
+}
+
+func (entry *SNatSEntry) Refresh() error {
+	return nil
+}
+
+func (entry *SNatSEntry) IsEmulated() bool {
+	return true
+}
+
+func (entry *SNatSEntry) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (entry *SNatSEntry) GetIP() string {
+	return entry.address.PublicIp
+}
+
+func (entry *SNatSEntry) GetSourceCIDR() string {
+	return entry.nat.vpc.CidrBlock
+}
+
+func (entry *SNatSEntry) GetNetworkId() string {
+	return ""
+}
+
+func (entry *SNatSEntry) Delete() error {
+	return cloudprovider.ErrNotSupported
+}
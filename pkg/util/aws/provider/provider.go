@@ -144,3 +144,11 @@ func (self *SAwsProvider) GetBalance() (float64, string, error) {
 func (self *SAwsProvider) GetIProjects() ([]cloudprovider.ICloudProject, error) {
 	return self.client.GetIProjects()
 }
+
+func (self *SAwsProvider) GetCloudBillItems(query cloudprovider.SCloudBillQuery) ([]cloudprovider.SCloudBillItem, error) {
+	return self.client.GetCloudBillItems(query)
+}
+
+func (self *SAwsProvider) GetIDnsZones() ([]cloudprovider.ICloudDnsZone, error) {
+	return self.client.GetIDnsZones()
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+// the rds service of the sdk is not vendored, the few apis used are
+// described below and sent with the query protocol shared by ec2 and iam
+const (
+	RDS_SERVICE_NAME = "rds"
+	RDS_SERVICE_ID   = "RDS"
+	RDS_API_VERSION  = "2014-10-31"
+)
+
+type SRdsClient struct {
+	*client.Client
+}
+
+func newRdsClient(s *session.Session) *SRdsClient {
+	c := s.ClientConfig(RDS_SERVICE_NAME)
+	cli := client.New(
+		*c.Config,
+		metadata.ClientInfo{
+			ServiceName:   RDS_SERVICE_NAME,
+			ServiceID:     RDS_SERVICE_ID,
+			SigningName:   c.SigningName,
+			SigningRegion: c.SigningRegion,
+			Endpoint:      c.Endpoint,
+			APIVersion:    RDS_API_VERSION,
+		},
+		c.Handlers,
+	)
+	cli.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	cli.Handlers.Build.PushBackNamed(query.BuildHandler)
+	cli.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	cli.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	cli.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+	return &SRdsClient{Client: cli}
+}
+
+func (self *SRdsClient) request(action string, input interface{}, output interface{}) error {
+	op := &request.Operation{
+		Name:       action,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	return self.NewRequest(op, input, output).Send()
+}
+
+type rdsEndpoint struct {
+	_ struct{} `type:"structure"`
+
+	Address *string `type:"string"`
+	Port    *int64  `type:"integer"`
+}
+
+type rdsDBSubnetGroup struct {
+	_ struct{} `type:"structure"`
+
+	VpcId *string `type:"string"`
+}
+
+type rdsDBInstance struct {
+	_ struct{} `type:"structure"`
+
+	DBInstanceIdentifier *string           `type:"string"`
+	DBInstanceClass      *string           `type:"string"`
+	DBInstanceStatus     *string           `type:"string"`
+	Engine               *string           `type:"string"`
+	EngineVersion        *string           `type:"string"`
+	AllocatedStorage     *int64            `type:"integer"`
+	StorageType          *string           `type:"string"`
+	AvailabilityZone     *string           `type:"string"`
+	MasterUsername       *string           `type:"string"`
+	InstanceCreateTime   *time.Time        `type:"timestamp"`
+	Endpoint             *rdsEndpoint      `type:"structure"`
+	DBSubnetGroup        *rdsDBSubnetGroup `type:"structure"`
+}
+
+type rdsDescribeDBInstancesInput struct {
+	_ struct{} `type:"structure"`
+
+	DBInstanceIdentifier *string `type:"string"`
+	Marker               *string `type:"string"`
+	MaxRecords           *int64  `type:"integer"`
+}
+
+type rdsDescribeDBInstancesOutput struct {
+	_ struct{} `type:"structure"`
+
+	DBInstances []*rdsDBInstance `locationNameList:"DBInstance" type:"list"`
+	Marker      *string          `type:"string"`
+}
+
+type rdsDBSnapshot struct {
+	_ struct{} `type:"structure"`
+
+	DBSnapshotIdentifier *string    `type:"string"`
+	SnapshotType         *string    `type:"string"`
+	Status               *string    `type:"string"`
+	AllocatedStorage     *int64     `type:"integer"`
+	SnapshotCreateTime   *time.Time `type:"timestamp"`
+}
+
+type rdsDescribeDBSnapshotsInput struct {
+	_ struct{} `type:"structure"`
+
+	DBInstanceIdentifier *string `type:"string"`
+	Marker               *string `type:"string"`
+}
+
+type rdsDescribeDBSnapshotsOutput struct {
+	_ struct{} `type:"structure"`
+
+	DBSnapshots []*rdsDBSnapshot `locationNameList:"DBSnapshot" type:"list"`
+	Marker      *string          `type:"string"`
+}
+
+type rdsDBInstanceActionInput struct {
+	_ struct{} `type:"structure"`
+
+	DBInstanceIdentifier *string `type:"string"`
+}
+
+type rdsModifyDBInstanceInput struct {
+	_ struct{} `type:"structure"`
+
+	DBInstanceIdentifier *string `type:"string"`
+	DBInstanceClass      *string `type:"string"`
+	AllocatedStorage     *int64  `type:"integer"`
+	ApplyImmediately     *bool   `type:"boolean"`
+}
+
+type rdsDBInstanceActionOutput struct {
+	_ struct{} `type:"structure"`
+
+	DBInstance *rdsDBInstance `type:"structure"`
+}
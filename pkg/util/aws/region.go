@@ -17,8 +17,6 @@ package aws
 import (
 	"fmt"
 
-	sdk "github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
@@ -29,6 +27,7 @@ import (
 
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/objectstore"
 )
 
 var RegionLocations = map[string]string{
@@ -54,10 +53,14 @@ var RegionLocations = map[string]string{
 }
 
 type SRegion struct {
-	client    *SAwsClient
-	ec2Client *ec2.EC2
-	iamClient *iam.IAM
-	s3Client  *s3.S3
+	client           *SAwsClient
+	ec2Client        *ec2.EC2
+	iamClient        *iam.IAM
+	s3Client         *s3.S3
+	rdsClient        *SRdsClient
+	cloudtrailClient *SCloudTrailClient
+
+	bucketClient *objectstore.SObjectStoreClient
 
 	izones []cloudprovider.ICloudZone
 	ivpcs  []cloudprovider.ICloudVpc
@@ -76,10 +79,7 @@ func (self *SRegion) GetClient() *SAwsClient {
 }
 
 func (self *SRegion) getAwsSession() (*session.Session, error) {
-	return session.NewSession(&sdk.Config{
-		Region:      sdk.String(self.RegionId),
-		Credentials: credentials.NewStaticCredentials(self.client.accessKey, self.client.secret, ""),
-	})
+	return self.client.newSession(self.RegionId)
 }
 
 func (self *SRegion) getEc2Client() (*ec2.EC2, error) {
@@ -0,0 +1,172 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/restxml"
+)
+
+// the route53 service of the sdk is not vendored either, the apis used are
+// described below and sent with the rest-xml protocol shared with s3
+const (
+	ROUTE53_SERVICE_NAME = "route53"
+	ROUTE53_SERVICE_ID   = "Route 53"
+	ROUTE53_API_VERSION  = "2013-04-01"
+)
+
+type SRoute53Client struct {
+	*client.Client
+}
+
+func newRoute53Client(s *session.Session) *SRoute53Client {
+	c := s.ClientConfig(ROUTE53_SERVICE_NAME)
+	cli := client.New(
+		*c.Config,
+		metadata.ClientInfo{
+			ServiceName:   ROUTE53_SERVICE_NAME,
+			ServiceID:     ROUTE53_SERVICE_ID,
+			SigningName:   c.SigningName,
+			SigningRegion: c.SigningRegion,
+			Endpoint:      c.Endpoint,
+			APIVersion:    ROUTE53_API_VERSION,
+		},
+		c.Handlers,
+	)
+	cli.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	cli.Handlers.Build.PushBackNamed(restxml.BuildHandler)
+	cli.Handlers.Unmarshal.PushBackNamed(restxml.UnmarshalHandler)
+	cli.Handlers.UnmarshalMeta.PushBackNamed(restxml.UnmarshalMetaHandler)
+	cli.Handlers.UnmarshalError.PushBackNamed(restxml.UnmarshalErrorHandler)
+	return &SRoute53Client{Client: cli}
+}
+
+func (self *SRoute53Client) request(action string, method string, path string, input interface{}, output interface{}) error {
+	op := &request.Operation{
+		Name:       action,
+		HTTPMethod: method,
+		HTTPPath:   "/" + ROUTE53_API_VERSION + path,
+	}
+	return self.NewRequest(op, input, output).Send()
+}
+
+type route53HostedZoneConfig struct {
+	_ struct{} `type:"structure"`
+
+	Comment     *string `type:"string"`
+	PrivateZone *bool   `type:"boolean"`
+}
+
+type route53HostedZone struct {
+	_ struct{} `type:"structure"`
+
+	Id                     *string                  `type:"string"`
+	Name                   *string                  `type:"string"`
+	Config                 *route53HostedZoneConfig `type:"structure"`
+	ResourceRecordSetCount *int64                   `type:"long"`
+}
+
+type route53ListHostedZonesInput struct {
+	_ struct{} `locationName:"ListHostedZonesRequest" type:"structure"`
+
+	Marker   *string `location:"querystring" locationName:"marker" type:"string"`
+	MaxItems *string `location:"querystring" locationName:"maxitems" type:"string"`
+}
+
+type route53ListHostedZonesOutput struct {
+	_ struct{} `type:"structure"`
+
+	HostedZones []*route53HostedZone `locationNameList:"HostedZone" type:"list"`
+	IsTruncated *bool                `type:"boolean"`
+	NextMarker  *string              `type:"string"`
+}
+
+type route53ResourceRecord struct {
+	_ struct{} `type:"structure"`
+
+	Value *string `type:"string"`
+}
+
+type route53AliasTarget struct {
+	_ struct{} `type:"structure"`
+
+	DNSName *string `type:"string"`
+}
+
+type route53ResourceRecordSet struct {
+	_ struct{} `type:"structure"`
+
+	Name            *string                  `type:"string"`
+	Type            *string                  `type:"string"`
+	TTL             *int64                   `type:"long"`
+	SetIdentifier   *string                  `type:"string"`
+	AliasTarget     *route53AliasTarget      `type:"structure"`
+	ResourceRecords []*route53ResourceRecord `locationNameList:"ResourceRecord" type:"list"`
+}
+
+type route53ListResourceRecordSetsInput struct {
+	_ struct{} `locationName:"ListResourceRecordSetsRequest" type:"structure"`
+
+	HostedZoneId    *string `location:"uri" locationName:"Id" type:"string"`
+	MaxItems        *string `location:"querystring" locationName:"maxitems" type:"string"`
+	StartRecordName *string `location:"querystring" locationName:"name" type:"string"`
+	StartRecordType *string `location:"querystring" locationName:"type" type:"string"`
+}
+
+type route53ListResourceRecordSetsOutput struct {
+	_ struct{} `type:"structure"`
+
+	ResourceRecordSets []*route53ResourceRecordSet `locationNameList:"ResourceRecordSet" type:"list"`
+	IsTruncated        *bool                       `type:"boolean"`
+	NextRecordName     *string                     `type:"string"`
+	NextRecordType     *string                     `type:"string"`
+}
+
+type route53Change struct {
+	_ struct{} `type:"structure"`
+
+	Action            *string                   `type:"string"`
+	ResourceRecordSet *route53ResourceRecordSet `type:"structure"`
+}
+
+type route53ChangeBatch struct {
+	_ struct{} `type:"structure"`
+
+	Changes []*route53Change `locationNameList:"Change" type:"list"`
+}
+
+type route53ChangeResourceRecordSetsInput struct {
+	_ struct{} `locationName:"ChangeResourceRecordSetsRequest" type:"structure" xmlURI:"https://route53.amazonaws.com/doc/2013-04-01/"`
+
+	HostedZoneId *string             `location:"uri" locationName:"Id" type:"string"`
+	ChangeBatch  *route53ChangeBatch `type:"structure"`
+}
+
+type route53ChangeInfo struct {
+	_ struct{} `type:"structure"`
+
+	Id     *string `type:"string"`
+	Status *string `type:"string"`
+}
+
+type route53ChangeResourceRecordSetsOutput struct {
+	_ struct{} `type:"structure"`
+
+	ChangeInfo *route53ChangeInfo `type:"structure"`
+}
@@ -0,0 +1,194 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SRoute struct {
+	Type                 string
+	DestinationCidrBlock string
+	NextHopType          string
+	NextHop              string
+}
+
+func (route *SRoute) GetType() string {
+	return route.Type
+}
+
+func (route *SRoute) GetCidr() string {
+	return route.DestinationCidrBlock
+}
+
+func (route *SRoute) GetNextHopType() string {
+	return route.NextHopType
+}
+
+func (route *SRoute) GetNextHop() string {
+	return route.NextHop
+}
+
+type SRouteTable struct {
+	region *SRegion
+
+	RouteTableId string
+	VpcId        string
+	Main         bool
+	Routes       []SRoute
+	Tags         TagSpec
+}
+
+func (self *SRouteTable) GetId() string {
+	return self.RouteTableId
+}
+
+func (self *SRouteTable) GetName() string {
+	if name := self.Tags.GetNameTag(); len(name) > 0 {
+		return name
+	}
+	return self.RouteTableId
+}
+
+func (self *SRouteTable) GetGlobalId() string {
+	return self.RouteTableId
+}
+
+func (self *SRouteTable) GetStatus() string {
+	return ""
+}
+
+func (self *SRouteTable) Refresh() error {
+	return nil
+}
+
+func (self *SRouteTable) IsEmulated() bool {
+	return false
+}
+
+func (self *SRouteTable) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SRouteTable) GetManagerId() string {
+	return self.region.client.providerId
+}
+
+func (self *SRouteTable) GetDescription() string {
+	return self.Tags.GetDescTag()
+}
+
+func (self *SRouteTable) GetRegionId() string {
+	return self.region.RegionId
+}
+
+func (self *SRouteTable) GetVpcId() string {
+	return self.VpcId
+}
+
+// the main route table of a vpc is the counterpart of the system route
+// table of aliyun
+func (self *SRouteTable) GetType() string {
+	if self.Main {
+		return "System"
+	}
+	return "Custom"
+}
+
+func (self *SRouteTable) GetIRoutes() ([]cloudprovider.ICloudRoute, error) {
+	ret := make([]cloudprovider.ICloudRoute, len(self.Routes))
+	for i := range self.Routes {
+		ret[i] = &self.Routes[i]
+	}
+	return ret, nil
+}
+
+func newRouteFromEc2(route *ec2.Route) SRoute {
+	ret := SRoute{
+		Type:                 "Custom",
+		DestinationCidrBlock: StrVal(route.DestinationCidrBlock),
+	}
+	if StrVal(route.Origin) == ec2.RouteOriginCreateRouteTable {
+		ret.Type = "System"
+	}
+	gatewayId := StrVal(route.GatewayId)
+	switch {
+	case len(StrVal(route.VpcPeeringConnectionId)) > 0:
+		ret.NextHopType, ret.NextHop = "VpcPeering", StrVal(route.VpcPeeringConnectionId)
+	case len(StrVal(route.NatGatewayId)) > 0:
+		ret.NextHopType, ret.NextHop = "NatGateway", StrVal(route.NatGatewayId)
+	case len(StrVal(route.InstanceId)) > 0:
+		ret.NextHopType, ret.NextHop = "Instance", StrVal(route.InstanceId)
+	case len(StrVal(route.NetworkInterfaceId)) > 0:
+		ret.NextHopType, ret.NextHop = "NetworkInterface", StrVal(route.NetworkInterfaceId)
+	case len(StrVal(route.EgressOnlyInternetGatewayId)) > 0:
+		ret.NextHopType, ret.NextHop = "EgressOnlyInternetGateway", StrVal(route.EgressOnlyInternetGatewayId)
+	case gatewayId == "local":
+		ret.NextHopType = "local"
+	case strings.HasPrefix(gatewayId, "vgw-"):
+		ret.NextHopType, ret.NextHop = "VpnGateway", gatewayId
+	case strings.HasPrefix(gatewayId, "igw-"):
+		ret.NextHopType, ret.NextHop = "InternetGateway", gatewayId
+	default:
+		ret.NextHop = gatewayId
+	}
+	return ret
+}
+
+func (self *SRegion) GetRouteTables(vpcId string) ([]SRouteTable, error) {
+	params := &ec2.DescribeRouteTablesInput{}
+	if len(vpcId) > 0 {
+		params.SetFilters(AppendSingleValueFilter(nil, "vpc-id", vpcId))
+	}
+
+	tables := make([]SRouteTable, 0)
+	err := self.ec2Client.DescribeRouteTablesPages(params, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		for _, item := range page.RouteTables {
+			tagspec := TagSpec{ResourceType: "route-table"}
+			tagspec.LoadingEc2Tags(item.Tags)
+			table := SRouteTable{
+				region:       self,
+				RouteTableId: StrVal(item.RouteTableId),
+				VpcId:        StrVal(item.VpcId),
+				Tags:         tagspec,
+			}
+			for _, assoc := range item.Associations {
+				if assoc.Main != nil && *assoc.Main {
+					table.Main = true
+				}
+			}
+			for _, route := range item.Routes {
+				// ipv6 and prefix list destinations are not supported by the route table model
+				if len(StrVal(route.DestinationCidrBlock)) == 0 {
+					continue
+				}
+				table.Routes = append(table.Routes, newRouteFromEc2(route))
+			}
+			tables = append(tables, table)
+		}
+		return true
+	})
+	err = parseNotFoundError(err)
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
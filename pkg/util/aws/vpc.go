@@ -121,10 +121,30 @@ func (self *SVpc) GetISecurityGroups() ([]cloudprovider.ICloudSecurityGroup, err
 }
 
 func (self *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
-	rts := []cloudprovider.ICloudRouteTable{}
+	tables, err := self.region.GetRouteTables(self.VpcId)
+	if err != nil {
+		return nil, err
+	}
+	rts := make([]cloudprovider.ICloudRouteTable, len(tables))
+	for i := range tables {
+		rts[i] = &tables[i]
+	}
 	return rts, nil
 }
 
+func (self *SVpc) GetINatGateways() ([]cloudprovider.ICloudNatGateway, error) {
+	nats, err := self.region.GetNatGateways(self.VpcId, "")
+	if err != nil {
+		return nil, err
+	}
+	inats := make([]cloudprovider.ICloudNatGateway, len(nats))
+	for i := 0; i < len(nats); i++ {
+		nats[i].vpc = self
+		inats[i] = &nats[i]
+	}
+	return inats, nil
+}
+
 func (self *SVpc) GetManagerId() string {
 	return self.region.client.providerId
 }
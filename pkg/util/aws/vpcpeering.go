@@ -0,0 +1,160 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// SVpcPeering is a peering connection seen from the region of its requester
+// vpc, the connections accepted from other regions are listed by the accepter
+// region with the sides swapped
+type SVpcPeering struct {
+	region *SRegion
+
+	VpcPeeringConnectionId string
+	Status                 string
+	VpcId                  string
+	PeerVpcId              string
+	PeerRegionId           string
+	PeerOwnerId            string
+	Tags                   TagSpec
+}
+
+func (self *SVpcPeering) GetId() string {
+	return self.VpcPeeringConnectionId
+}
+
+func (self *SVpcPeering) GetName() string {
+	if name := self.Tags.GetNameTag(); len(name) > 0 {
+		return name
+	}
+	return self.VpcPeeringConnectionId
+}
+
+func (self *SVpcPeering) GetGlobalId() string {
+	return self.VpcPeeringConnectionId
+}
+
+func (self *SVpcPeering) GetStatus() string {
+	switch self.Status {
+	case ec2.VpcPeeringConnectionStateReasonCodeActive:
+		return api.VPC_PEERING_STATUS_ACTIVE
+	case ec2.VpcPeeringConnectionStateReasonCodeInitiatingRequest, ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance,
+		ec2.VpcPeeringConnectionStateReasonCodeProvisioning:
+		return api.VPC_PEERING_STATUS_PENDING
+	case ec2.VpcPeeringConnectionStateReasonCodeFailed, ec2.VpcPeeringConnectionStateReasonCodeRejected,
+		ec2.VpcPeeringConnectionStateReasonCodeExpired:
+		return api.VPC_PEERING_STATUS_FAILED
+	case ec2.VpcPeeringConnectionStateReasonCodeDeleting, ec2.VpcPeeringConnectionStateReasonCodeDeleted:
+		return api.VPC_PEERING_STATUS_DELETING
+	}
+	return api.VPC_PEERING_STATUS_UNKNOWN
+}
+
+func (self *SVpcPeering) Refresh() error {
+	peerings, err := self.region.GetVpcPeerings(self.VpcPeeringConnectionId)
+	if err != nil {
+		return err
+	}
+	if len(peerings) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(self, peerings[0])
+}
+
+func (self *SVpcPeering) IsEmulated() bool {
+	return false
+}
+
+func (self *SVpcPeering) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SVpcPeering) GetVpcId() string {
+	return self.VpcId
+}
+
+func (self *SVpcPeering) GetPeerVpcId() string {
+	return self.PeerVpcId
+}
+
+func (self *SVpcPeering) GetPeerRegionId() string {
+	return self.PeerRegionId
+}
+
+func (self *SVpcPeering) GetPeerAccountId() string {
+	return self.PeerOwnerId
+}
+
+func (self *SVpcPeering) GetBandwidth() int {
+	return 0
+}
+
+func (self *SRegion) GetVpcPeerings(peeringId string) ([]SVpcPeering, error) {
+	params := &ec2.DescribeVpcPeeringConnectionsInput{}
+	if len(peeringId) > 0 {
+		params.SetVpcPeeringConnectionIds([]*string{&peeringId})
+	}
+	ret, err := self.ec2Client.DescribeVpcPeeringConnections(params)
+	err = parseNotFoundError(err)
+	if err != nil {
+		return nil, err
+	}
+
+	peerings := make([]SVpcPeering, 0)
+	for _, item := range ret.VpcPeeringConnections {
+		if item.RequesterVpcInfo == nil || item.AccepterVpcInfo == nil {
+			continue
+		}
+		local, peer := item.RequesterVpcInfo, item.AccepterVpcInfo
+		if StrVal(local.Region) != self.RegionId {
+			local, peer = peer, local
+		}
+		tagspec := TagSpec{ResourceType: "vpc-peering-connection"}
+		tagspec.LoadingEc2Tags(item.Tags)
+		peering := SVpcPeering{
+			region:                 self,
+			VpcPeeringConnectionId: StrVal(item.VpcPeeringConnectionId),
+			VpcId:                  StrVal(local.VpcId),
+			PeerVpcId:              StrVal(peer.VpcId),
+			PeerRegionId:           StrVal(peer.Region),
+			PeerOwnerId:            StrVal(peer.OwnerId),
+			Tags:                   tagspec,
+		}
+		if item.Status != nil {
+			peering.Status = StrVal(item.Status.Code)
+		}
+		peerings = append(peerings, peering)
+	}
+	return peerings, nil
+}
+
+func (self *SRegion) GetIVpcPeerings() ([]cloudprovider.ICloudVpcPeering, error) {
+	peerings, err := self.GetVpcPeerings("")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudVpcPeering, len(peerings))
+	for i := range peerings {
+		ret[i] = &peerings[i]
+	}
+	return ret, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// an aws vpn connection carries the traffic of the whole vpc that the
+// virtual private gateway attaches to, the remote cidrs are its static routes
+type SVpnConnection struct {
+	region *SRegion
+
+	VpnConnectionId        string
+	State                  string
+	VpcId                  string
+	VpnGatewayId           string
+	CustomerGatewayId      string
+	CustomerGatewayAddress string
+	RemoteCidrs            []string
+	Tags                   TagSpec
+}
+
+func (self *SVpnConnection) GetId() string {
+	return self.VpnConnectionId
+}
+
+func (self *SVpnConnection) GetName() string {
+	if name := self.Tags.GetNameTag(); len(name) > 0 {
+		return name
+	}
+	return self.VpnConnectionId
+}
+
+func (self *SVpnConnection) GetGlobalId() string {
+	return self.VpnConnectionId
+}
+
+func (self *SVpnConnection) GetStatus() string {
+	switch self.State {
+	case ec2.VpnStateAvailable:
+		return api.VPN_CONNECTION_STATUS_CONNECTED
+	case ec2.VpnStatePending:
+		return api.VPN_CONNECTION_STATUS_PENDING
+	case ec2.VpnStateDeleting, ec2.VpnStateDeleted:
+		return api.VPN_CONNECTION_STATUS_DELETING
+	}
+	return api.VPN_CONNECTION_STATUS_UNKNOWN
+}
+
+func (self *SVpnConnection) Refresh() error {
+	conns, err := self.region.GetVpnConnections(self.VpnConnectionId)
+	if err != nil {
+		return err
+	}
+	if len(conns) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(self, conns[0])
+}
+
+func (self *SVpnConnection) IsEmulated() bool {
+	return false
+}
+
+func (self *SVpnConnection) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SVpnConnection) GetVpcId() string {
+	return self.VpcId
+}
+
+func (self *SVpnConnection) GetVpnGatewayId() string {
+	return self.VpnGatewayId
+}
+
+// the tunnel outside addresses of aws are only available in the customer
+// gateway configuration document, none of them is the address of the gateway
+func (self *SVpnConnection) GetVpnGatewayAddress() string {
+	return ""
+}
+
+func (self *SVpnConnection) GetCustomerGatewayId() string {
+	return self.CustomerGatewayId
+}
+
+func (self *SVpnConnection) GetCustomerGatewayAddress() string {
+	return self.CustomerGatewayAddress
+}
+
+func (self *SVpnConnection) GetLocalCidrs() []string {
+	return []string{}
+}
+
+func (self *SVpnConnection) GetRemoteCidrs() []string {
+	return self.RemoteCidrs
+}
+
+func (self *SRegion) GetVpnConnections(connId string) ([]SVpnConnection, error) {
+	params := &ec2.DescribeVpnConnectionsInput{}
+	if len(connId) > 0 {
+		params.SetVpnConnectionIds([]*string{&connId})
+	}
+	ret, err := self.ec2Client.DescribeVpnConnections(params)
+	err = parseNotFoundError(err)
+	if err != nil {
+		return nil, err
+	}
+	if len(ret.VpnConnections) == 0 {
+		return []SVpnConnection{}, nil
+	}
+
+	gateways, err := self.ec2Client.DescribeVpnGateways(&ec2.DescribeVpnGatewaysInput{})
+	err = parseNotFoundError(err)
+	if err != nil {
+		return nil, err
+	}
+	vpcIds := make(map[string]string)
+	for _, gw := range gateways.VpnGateways {
+		for _, attach := range gw.VpcAttachments {
+			if StrVal(attach.State) == ec2.AttachmentStatusAttached {
+				vpcIds[StrVal(gw.VpnGatewayId)] = StrVal(attach.VpcId)
+			}
+		}
+	}
+	customerGateways, err := self.ec2Client.DescribeCustomerGateways(&ec2.DescribeCustomerGatewaysInput{})
+	err = parseNotFoundError(err)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make(map[string]string)
+	for _, gw := range customerGateways.CustomerGateways {
+		addresses[StrVal(gw.CustomerGatewayId)] = StrVal(gw.IpAddress)
+	}
+
+	conns := make([]SVpnConnection, 0)
+	for _, item := range ret.VpnConnections {
+		tagspec := TagSpec{ResourceType: "vpn-connection"}
+		tagspec.LoadingEc2Tags(item.Tags)
+		conn := SVpnConnection{
+			region:                 self,
+			VpnConnectionId:        StrVal(item.VpnConnectionId),
+			State:                  StrVal(item.State),
+			VpnGatewayId:           StrVal(item.VpnGatewayId),
+			VpcId:                  vpcIds[StrVal(item.VpnGatewayId)],
+			CustomerGatewayId:      StrVal(item.CustomerGatewayId),
+			CustomerGatewayAddress: addresses[StrVal(item.CustomerGatewayId)],
+			RemoteCidrs:            []string{},
+			Tags:                   tagspec,
+		}
+		for _, route := range item.Routes {
+			conn.RemoteCidrs = append(conn.RemoteCidrs, StrVal(route.DestinationCidrBlock))
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+func (self *SRegion) GetIVpnConnections() ([]cloudprovider.ICloudVpnConnection, error) {
+	conns, err := self.GetVpnConnections("")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudVpnConnection, len(conns))
+	for i := range conns {
+		ret[i] = &conns[i]
+	}
+	return ret, nil
+}
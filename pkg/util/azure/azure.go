@@ -510,7 +510,50 @@ func recoverFromError(client *autorest.Client, domain, subscriptionId string, az
 	return false
 }
 
+var (
+	azureThrottledErrors = []string{
+		"TooManyRequests",
+		"SubscriptionRequestsThrottled",
+	}
+	azureServerErrors = []string{
+		"InternalServerError",
+		"ServerTimeout",
+		"ServiceUnavailable",
+		"EOF",
+	}
+)
+
+func classifyAzureError(err error) string {
+	return cloudprovider.ClassifyApiError(err, azureThrottledErrors, azureServerErrors)
+}
+
+// azureApiAction names the api after the resource type of the url,
+// e.g. GET Microsoft.Compute/virtualMachines
+func azureApiAction(method, baseUrl string) string {
+	segs := strings.Split(strings.Trim(strings.Split(baseUrl, "?")[0], "/"), "/")
+	resource := ""
+	for i := 0; i < len(segs); i += 2 {
+		if strings.ToLower(segs[i]) == "providers" && i+2 < len(segs) {
+			resource = segs[i+1] + "/" + segs[i+2]
+			break
+		}
+		resource = segs[i]
+	}
+	return method + " " + resource
+}
+
 func jsonRequest(client *autorest.Client, method, domain, baseUrl string, subscriptionId string, body string) (jsonutils.JSONObject, error) {
+	caller := cloudprovider.GetApiCaller(CLOUD_PROVIDER_AZURE, subscriptionId)
+	var result jsonutils.JSONObject
+	err := caller.Call(azureApiAction(method, baseUrl), classifyAzureError, func() error {
+		var err error
+		result, err = azureRequest(client, method, domain, baseUrl, subscriptionId, body)
+		return err
+	})
+	return result, err
+}
+
+func azureRequest(client *autorest.Client, method, domain, baseUrl string, subscriptionId string, body string) (jsonutils.JSONObject, error) {
 	result, err := _jsonRequest(client, method, domain, baseUrl, body)
 	if err != nil {
 		return nil, err
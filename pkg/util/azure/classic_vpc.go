@@ -155,6 +155,11 @@ func (self *SClassicVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, er
 	return rts, nil
 }
 
+func (self *SClassicVpc) GetINatGateways() ([]cloudprovider.ICloudNatGateway, error) {
+	nats := []cloudprovider.ICloudNatGateway{}
+	return nats, nil
+}
+
 func (self *SClassicVpc) fetchWires() error {
 	networks := make([]cloudprovider.ICloudNetwork, len(self.Properties.Subnets))
 	wire := SClassicWire{zone: self.region.izones[0].(*SZone), vpc: self}
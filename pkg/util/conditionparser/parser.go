@@ -53,6 +53,43 @@ func Eval(exprStr string, input interface{}) (bool, error) {
 		log.Errorf("parse expr %s error %s", exprStr, err)
 		return false, err
 	}
+	return evalBool(expr, input)
+}
+
+// EvalAttributes evaluates the expression like Eval, but the operand
+// identifiers are always looked up in the attributes of input, which
+// go/parser no longer tells apart from the selected fields
+func EvalAttributes(exprStr string, input *jsonutils.JSONDict) (bool, error) {
+	if len(exprStr) == 0 {
+		return true, nil
+	}
+	expr, err := parser.ParseExpr(exprStr)
+	if err != nil {
+		log.Errorf("parse expr %s error %s", exprStr, err)
+		return false, err
+	}
+	resolveOperands(expr)
+	return evalBool(expr, input)
+}
+
+// resolveOperands links the identifiers other than the selected fields to
+// objects, as go/parser did for the unresolved identifiers
+func resolveOperands(expr ast.Expr) {
+	sels := map[*ast.Ident]bool{}
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.SelectorExpr:
+			sels[n.Sel] = true
+		case *ast.Ident:
+			if n.Obj == nil && !sels[n] {
+				n.Obj = ast.NewObj(ast.Var, n.Name)
+			}
+		}
+		return true
+	})
+}
+
+func evalBool(expr ast.Expr, input interface{}) (bool, error) {
 	result, err := eval(expr, input)
 	if err != nil {
 		return false, err
@@ -177,3 +177,30 @@ func TestEval3(t *testing.T) {
 		}
 	}
 }
+
+func TestEvalAttributes(t *testing.T) {
+	input, err := jsonutils.ParseString(`{"object":{"metadata":{"env":"prod"},"status":"running"},"subject":{"project":"dev"}}`)
+	if err != nil {
+		t.Fatalf("fail to parse input %s", err)
+	}
+	cases := []struct {
+		in   string
+		want bool
+	}{
+		{`object.metadata.env == "prod"`, true},
+		{`object.metadata.env != "prod"`, false},
+		{`object.status.in("running", "ready") && subject.project == "dev"`, true},
+		{`object["status"] == "ready"`, false},
+		{``, true},
+	}
+	for _, c := range cases {
+		got, err := EvalAttributes(c.in, input.(*jsonutils.JSONDict))
+		if err != nil {
+			t.Errorf("eval expr %s error %s", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: expect %v got %v", c.in, c.want, got)
+		}
+	}
+}
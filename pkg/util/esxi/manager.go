@@ -27,6 +27,7 @@ import (
 	"github.com/vmware/govmomi/session"
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 
 	"yunion.io/x/jsonutils"
@@ -88,6 +89,10 @@ func (cli *SESXiClient) connect() error {
 	if err != nil {
 		return err
 	}
+	govmcli.Client.RoundTripper = &sApiRoundTripper{
+		roundTripper: govmcli.Client.RoundTripper,
+		caller:       cloudprovider.GetApiCaller(CLOUD_PROVIDER_VMWARE, fmt.Sprintf("%s@%s", cli.account, cli.host)),
+	}
 
 	userinfo := url.UserPassword(cli.account, cli.password)
 
@@ -101,6 +106,33 @@ func (cli *SESXiClient) connect() error {
 	return nil
 }
 
+// sApiRoundTripper passes the soap calls through the api caller of the account
+type sApiRoundTripper struct {
+	roundTripper soap.RoundTripper
+	caller       *cloudprovider.SApiCaller
+}
+
+func (self *sApiRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	return self.caller.Call(esxiApiAction(req), classifyEsxiError, func() error {
+		return self.roundTripper.RoundTrip(ctx, req, res)
+	})
+}
+
+// esxiApiAction names the api after the method of the request body,
+// e.g. RetrievePropertiesEx
+func esxiApiAction(req soap.HasFault) string {
+	return strings.TrimSuffix(reflect.Indirect(reflect.ValueOf(req)).Type().Name(), "Body")
+}
+
+// faults are raised by the vim api against the request itself, the other
+// errors come from the connection to the server
+func classifyEsxiError(err error) string {
+	if soap.IsSoapFault(err) || soap.IsVimFault(err) {
+		return cloudprovider.API_ERROR_CLIENT
+	}
+	return cloudprovider.API_ERROR_SERVER
+}
+
 func (cli *SESXiClient) disconnect() error {
 	if cli.client != nil {
 		return cli.client.Logout(cli.context)
@@ -14,7 +14,15 @@
 
 package esxi
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
 
 func TestNewClient(t *testing.T) {
 	cli, err := NewESXiClient("", "", "10.168.222.104", 443, "root", "123@Vmware")
@@ -34,3 +42,15 @@ func TestNewClient(t *testing.T) {
 		}
 	}
 }
+
+func TestEsxiApiAction(t *testing.T) {
+	if action := esxiApiAction(&methods.RetrievePropertiesExBody{}); action != "RetrievePropertiesEx" {
+		t.Errorf("want RetrievePropertiesEx got %s", action)
+	}
+	if errClass := classifyEsxiError(soap.WrapSoapFault(&soap.Fault{String: "not found"})); errClass != cloudprovider.API_ERROR_CLIENT {
+		t.Errorf("soap fault: want %s got %s", cloudprovider.API_ERROR_CLIENT, errClass)
+	}
+	if errClass := classifyEsxiError(errors.New("connection refused")); errClass != cloudprovider.API_ERROR_SERVER {
+		t.Errorf("connection error: want %s got %s", cloudprovider.API_ERROR_SERVER, errClass)
+	}
+}
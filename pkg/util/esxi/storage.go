@@ -669,6 +669,27 @@ func (self *SDatastore) getDatastoreObj() *object.Datastore {
 	return object.NewDatastore(self.manager.client.Client, self.getDatastore().Self)
 }
 
+// getPathTicket returns the url of a datastore file on a host the datastore
+// is attached to and the cookie of a one-time service ticket to GET it, so
+// that the file is downloadable without the credentials of the account
+func (self *SDatastore) getPathTicket(ctx context.Context, remotePath string) (string, *http.Cookie, error) {
+	ds := self.getDatastoreObj()
+	ds.InventoryPath = self.SManagedObject.GetName()
+	hosts, err := ds.AttachedHosts(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(hosts) == 0 {
+		return "", nil, fmt.Errorf("datastore %s is not attached to any host", self.GetName())
+	}
+	ctx = ds.HostContext(ctx, hosts[0])
+	u, cookie, err := ds.ServiceTicket(ctx, strings.TrimLeft(self.cleanPath(remotePath), "/"), "GET")
+	if err != nil {
+		return "", nil, err
+	}
+	return u.String(), cookie, nil
+}
+
 func (self *SDatastore) MakeDir(ctx context.Context, remotePath string) (string, error) {
 	dnm := object.NewDatastoreNamespaceManager(self.manager.client.Client)
 
@@ -132,6 +132,72 @@ func (disk *SVirtualDisk) GetIStorage() (cloudprovider.ICloudStorage, error) {
 	return istorage, nil
 }
 
+type sExportFile struct {
+	datastore *SDatastore
+	path      string
+}
+
+// exportFiles returns the export info of the disk and the datastore files
+// behind its urls
+func (disk *SVirtualDisk) exportFiles(ctx context.Context) (*cloudprovider.SDiskExportInfo, map[string]sExportFile, error) {
+	dc, err := disk.vm.GetDatacenter()
+	if err != nil {
+		return nil, nil, err
+	}
+	info := cloudprovider.SDiskExportInfo{Format: "vmdk"}
+	files := make(map[string]sExportFile)
+	for backing := disk.getBackingInfo(); backing != nil; backing = backing.Parent {
+		if backing.Datastore == nil {
+			return nil, nil, fmt.Errorf("no datastore for %s", backing.FileName)
+		}
+		istorage, err := dc.GetIStorageByMoId(moRefId(*backing.Datastore))
+		if err != nil {
+			return nil, nil, err
+		}
+		ds := istorage.(*SDatastore)
+		vmdkInfo, err := ds.GetVmdkInfo(ctx, backing.FileName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fail to read descriptor %s: %s", backing.FileName, err)
+		}
+		descPath := ds.cleanPath(backing.FileName)
+		extentPath := path.Join(path.Dir(descPath), vmdkInfo.ExtentFile)
+		layer := cloudprovider.SDiskExportLayer{
+			DescriptorName: path.Base(descPath),
+			DescriptorUrl:  ds.GetPathUrl(descPath),
+			ExtentName:     vmdkInfo.ExtentFile,
+			ExtentUrl:      ds.GetPathUrl(extentPath),
+		}
+		files[layer.DescriptorUrl] = sExportFile{datastore: ds, path: descPath}
+		files[layer.ExtentUrl] = sExportFile{datastore: ds, path: extentPath}
+		info.Layers = append(info.Layers, layer)
+	}
+	return &info, files, nil
+}
+
+func (disk *SVirtualDisk) GetExportInfo(ctx context.Context) (*cloudprovider.SDiskExportInfo, error) {
+	info, _, err := disk.exportFiles(ctx)
+	return info, err
+}
+
+func (disk *SVirtualDisk) GetExportTicket(ctx context.Context, url string) (*cloudprovider.SDiskExportTicket, error) {
+	_, files, err := disk.exportFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := files[url]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a file of disk %s", url, disk.GetName())
+	}
+	ticketUrl, cookie, err := file.datastore.getPathTicket(ctx, file.path)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudprovider.SDiskExportTicket{
+		Url:     ticketUrl,
+		Headers: map[string]string{"Cookie": cookie.String()},
+	}, nil
+}
+
 func (disk *SVirtualDisk) GetIsAutoDelete() bool {
 	return true
 }
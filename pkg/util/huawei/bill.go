@@ -0,0 +1,84 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package huawei
+
+import (
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/huawei/client"
+)
+
+type SFeeRecord struct {
+	ResourceId           string  `json:"resourceId"`
+	ResourceTypeCode     string  `json:"resourceTypeCode"`
+	CloudServiceTypeCode string  `json:"cloudServiceTypeCode"`
+	RegionCode           string  `json:"regionCode"`
+	EnterpriseProjectId  string  `json:"enterpriseProjectId"`
+	Amount               float64 `json:"amount"`
+}
+
+// huawei resource type codes of the resources managed locally
+var billResourceTypes = map[string]string{
+	"hws.resource.type.vm":         cloudprovider.CLOUD_BILL_RESOURCE_SERVER,
+	"hws.resource.type.volume":     cloudprovider.CLOUD_BILL_RESOURCE_DISK,
+	"hws.resource.type.ip":         cloudprovider.CLOUD_BILL_RESOURCE_EIP,
+	"hws.resource.type.elb":        cloudprovider.CLOUD_BILL_RESOURCE_LOADBALANCER,
+	"hws.resource.type.natgateway": cloudprovider.CLOUD_BILL_RESOURCE_NATGATEWAY,
+	"hws.resource.type.obs":        cloudprovider.CLOUD_BILL_RESOURCE_BUCKET,
+}
+
+// https://support.huaweicloud.com/api-oce/zh-cn_topic_0109685133.html
+func (self *SHuaweiClient) queryDomainFeeRecords(domainId string, day string) ([]SFeeRecord, error) {
+	huawei, _ := client.NewClientWithAccessKey("", "", self.accessKey, self.secret, self.debug)
+	huawei.Bills.SetDomainId(domainId)
+	queries := map[string]string{
+		"cycle":           day[:7],
+		"bill_date_begin": day,
+		"bill_date_end":   day,
+	}
+	records := make([]SFeeRecord, 0)
+	err := doListAllWithOffset(huawei.Bills.List, queries, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (self *SHuaweiClient) GetCloudBillItems(query cloudprovider.SCloudBillQuery) ([]cloudprovider.SCloudBillItem, error) {
+	day := cloudprovider.BillDate(query.Day)
+	domains, err := self.getEnabledDomains()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]cloudprovider.SCloudBillItem, 0)
+	for _, domain := range domains {
+		records, err := self.queryDomainFeeRecords(domain.ID, day.Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			items = append(items, cloudprovider.SCloudBillItem{
+				ResourceType: billResourceTypes[record.ResourceTypeCode],
+				ResourceId:   record.ResourceId,
+				ProjectId:    record.EnterpriseProjectId,
+				RegionId:     record.RegionCode,
+				Product:      record.CloudServiceTypeCode,
+				Amount:       record.Amount,
+				Currency:     "CNY",
+				UsageDate:    day,
+			})
+		}
+	}
+	return cloudprovider.MergeCloudBillItems(items), nil
+}
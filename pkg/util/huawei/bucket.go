@@ -0,0 +1,314 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package huawei
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/huawei/obs"
+)
+
+type SBucket struct {
+	region *SRegion
+
+	Name         string
+	Location     string
+	CreationDate time.Time
+}
+
+func isObsErrorStatus(err error, status int) bool {
+	if oerr, ok := err.(obs.ObsError); ok {
+		return oerr.StatusCode == status
+	}
+	return false
+}
+
+func (self *SRegion) GetBuckets() ([]SBucket, error) {
+	cli, err := self.getOBSClient()
+	if err != nil {
+		return nil, err
+	}
+	output, err := cli.ListBuckets(&obs.ListBucketsInput{QueryLocation: true})
+	if err != nil {
+		return nil, err
+	}
+	buckets := make([]SBucket, 0)
+	for _, b := range output.Buckets {
+		if b.Location != self.GetId() {
+			continue
+		}
+		buckets = append(buckets, SBucket{
+			region:       self,
+			Name:         b.Name,
+			Location:     b.Location,
+			CreationDate: b.CreationDate,
+		})
+	}
+	return buckets, nil
+}
+
+func (self *SRegion) GetIBuckets() ([]cloudprovider.ICloudBucket, error) {
+	buckets, err := self.GetBuckets()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudBucket, 0, len(buckets))
+	for i := range buckets {
+		ret = append(ret, &buckets[i])
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIBucketById(name string) (cloudprovider.ICloudBucket, error) {
+	buckets, err := self.GetBuckets()
+	if err != nil {
+		return nil, err
+	}
+	for i := range buckets {
+		if buckets[i].Name == name {
+			return &buckets[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SRegion) CreateIBucket(name string, storageClass string, acl string) (cloudprovider.ICloudBucket, error) {
+	cli, err := self.getOBSClient()
+	if err != nil {
+		return nil, err
+	}
+	input := &obs.CreateBucketInput{
+		Bucket:       name,
+		ACL:          obs.AclType(acl),
+		StorageClass: obs.StorageClassType(storageClass),
+	}
+	input.Location = self.GetId()
+	_, err = cli.CreateBucket(input)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetIBucketById(name)
+}
+
+func (self *SRegion) DeleteIBucket(name string) error {
+	cli, err := self.getOBSClient()
+	if err != nil {
+		return err
+	}
+	_, err = cli.DeleteBucket(name)
+	if err != nil && !isObsErrorStatus(err, http.StatusNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (b *SBucket) GetId() string {
+	return b.Name
+}
+
+func (b *SBucket) GetName() string {
+	return b.Name
+}
+
+func (b *SBucket) GetGlobalId() string {
+	return b.Name
+}
+
+func (b *SBucket) GetStatus() string {
+	return "available"
+}
+
+func (b *SBucket) Refresh() error {
+	bucket, err := b.region.GetIBucketById(b.Name)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(b, bucket)
+}
+
+func (b *SBucket) IsEmulated() bool {
+	return false
+}
+
+func (b *SBucket) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (b *SBucket) GetIRegion() cloudprovider.ICloudRegion {
+	return b.region
+}
+
+func (b *SBucket) GetLocation() string {
+	return b.Location
+}
+
+func (b *SBucket) GetStorageClass() string {
+	cli, err := b.region.getOBSClient()
+	if err != nil {
+		return ""
+	}
+	output, err := cli.GetBucketMetadata(&obs.GetBucketMetadataInput{Bucket: b.Name})
+	if err != nil {
+		return ""
+	}
+	return string(output.StorageClass)
+}
+
+func (b *SBucket) GetCreateAt() time.Time {
+	return b.CreationDate
+}
+
+func (b *SBucket) GetAccessUrl() string {
+	return fmt.Sprintf("https://%s.obs.%s.myhuaweicloud.com", b.Name, b.region.GetId())
+}
+
+func (b *SBucket) GetAcl() string {
+	cli, err := b.region.getOBSClient()
+	if err != nil {
+		return cloudprovider.ACL_UNKNOWN
+	}
+	output, err := cli.GetBucketAcl(b.Name)
+	if err != nil {
+		return cloudprovider.ACL_UNKNOWN
+	}
+	allRead, allWrite, authRead := false, false, false
+	for _, grant := range output.Grants {
+		uri := string(grant.Grantee.URI)
+		switch {
+		case strings.HasSuffix(uri, string(obs.GroupAllUsers)) || uri == "Everyone":
+			switch grant.Permission {
+			case obs.PermissionRead:
+				allRead = true
+			case obs.PermissionWrite:
+				allWrite = true
+			case obs.PermissionFullControl:
+				allRead, allWrite = true, true
+			}
+		case strings.HasSuffix(uri, string(obs.GroupAuthenticatedUsers)):
+			if grant.Permission == obs.PermissionRead || grant.Permission == obs.PermissionFullControl {
+				authRead = true
+			}
+		}
+	}
+	switch {
+	case allRead && allWrite:
+		return cloudprovider.ACL_PUBLIC_READ_WRITE
+	case allRead:
+		return cloudprovider.ACL_PUBLIC_READ
+	case authRead:
+		return cloudprovider.ACL_AUTHENTICATED_READ
+	}
+	return cloudprovider.ACL_PRIVATE
+}
+
+func (b *SBucket) SetAcl(acl string) error {
+	cli, err := b.region.getOBSClient()
+	if err != nil {
+		return err
+	}
+	_, err = cli.SetBucketAcl(&obs.SetBucketAclInput{Bucket: b.Name, ACL: obs.AclType(acl)})
+	return err
+}
+
+func (b *SBucket) GetStats() (cloudprovider.SBucketStats, error) {
+	stats := cloudprovider.SBucketStats{}
+	cli, err := b.region.getOBSClient()
+	if err != nil {
+		return stats, err
+	}
+	output, err := cli.GetBucketStorageInfo(b.Name)
+	if err != nil {
+		return stats, err
+	}
+	stats.SizeBytes = output.Size
+	stats.ObjectCount = output.ObjectNumber
+	return stats, nil
+}
+
+func (b *SBucket) GetLifecycle() ([]cloudprovider.SBucketLifecycleRule, error) {
+	cli, err := b.region.getOBSClient()
+	if err != nil {
+		return nil, err
+	}
+	output, err := cli.GetBucketLifecycleConfiguration(b.Name)
+	if err != nil {
+		if isObsErrorStatus(err, http.StatusNotFound) {
+			return []cloudprovider.SBucketLifecycleRule{}, nil
+		}
+		return nil, err
+	}
+	rules := make([]cloudprovider.SBucketLifecycleRule, 0, len(output.LifecycleRules))
+	for _, r := range output.LifecycleRules {
+		rules = append(rules, cloudprovider.SBucketLifecycleRule{
+			ID:             r.ID,
+			Prefix:         r.Prefix,
+			Enabled:        r.Status == obs.RuleStatusEnabled,
+			ExpirationDays: r.Expiration.Days,
+		})
+	}
+	return rules, nil
+}
+
+func (b *SBucket) SetLifecycle(rules []cloudprovider.SBucketLifecycleRule) error {
+	cli, err := b.region.getOBSClient()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		_, err = cli.DeleteBucketLifecycleConfiguration(b.Name)
+		return err
+	}
+	input := &obs.SetBucketLifecycleConfigurationInput{Bucket: b.Name}
+	for i, rule := range rules {
+		r := obs.LifecycleRule{
+			ID:     rule.ID,
+			Prefix: rule.Prefix,
+			Status: obs.RuleStatusDisabled,
+		}
+		if len(r.ID) == 0 {
+			r.ID = fmt.Sprintf("rule-%d", i)
+		}
+		if rule.Enabled {
+			r.Status = obs.RuleStatusEnabled
+		}
+		r.Expiration.Days = rule.ExpirationDays
+		input.LifecycleRules = append(input.LifecycleRules, r)
+	}
+	_, err = cli.SetBucketLifecycleConfiguration(input)
+	return err
+}
+
+func (b *SBucket) GetTempUrl(method string, key string, expire time.Duration) (string, error) {
+	cli, err := b.region.getOBSClient()
+	if err != nil {
+		return "", err
+	}
+	output, err := cli.CreateSignedUrl(&obs.CreateSignedUrlInput{
+		Method:  obs.HttpMethodType(strings.ToUpper(method)),
+		Bucket:  b.Name,
+		Key:     key,
+		Expires: int(expire / time.Second),
+	})
+	if err != nil {
+		return "", err
+	}
+	return output.SignedUrl, nil
+}
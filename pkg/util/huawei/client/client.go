@@ -33,6 +33,9 @@ type Client struct {
 
 	Balances           *modules.SBalanceManager
 	Bandwidths         *modules.SBandwidthManager
+	Bills              *modules.SBillManager
+	DBInstances        *modules.SDBInstanceManager
+	DBInstanceBackups  *modules.SDBInstanceBackupManager
 	Disks              *modules.SDiskManager
 	Domains            *modules.SDomainManager
 	Eips               *modules.SEipManager
@@ -42,6 +45,9 @@ type Client struct {
 	Interface          *modules.SInterfaceManager
 	Jobs               *modules.SJobManager
 	Keypairs           *modules.SKeypairManager
+	NatGateways        *modules.SNatGatewayManager
+	SNatRules          *modules.SNatSRuleManager
+	DNatRules          *modules.SNatDRuleManager
 	Orders             *modules.SOrderManager
 	Port               *modules.SPortManager
 	Projects           *modules.SProjectManager
@@ -116,8 +122,14 @@ func (self *Client) initManagers() {
 		self.Jobs = modules.NewJobManager(self.regionId, self.projectId, self.signer, self.debug)
 		self.Balances = modules.NewBalanceManager(self.signer, self.debug)
 		self.Bandwidths = modules.NewBandwidthManager(self.regionId, self.projectId, self.signer, self.debug)
+		self.Bills = modules.NewBillManager(self.signer, self.debug)
 		self.Port = modules.NewPortManager(self.regionId, self.projectId, self.signer, self.debug)
 		self.Flavors = modules.NewFlavorManager(self.regionId, self.projectId, self.signer, self.debug)
+		self.NatGateways = modules.NewNatGatewayManager(self.regionId, self.signer, self.debug)
+		self.SNatRules = modules.NewNatSRuleManager(self.regionId, self.signer, self.debug)
+		self.DNatRules = modules.NewNatDRuleManager(self.regionId, self.signer, self.debug)
+		self.DBInstances = modules.NewDBInstanceManager(self.regionId, self.projectId, self.signer, self.debug)
+		self.DBInstanceBackups = modules.NewDBInstanceBackupManager(self.regionId, self.projectId, self.signer, self.debug)
 	}
 
 	self.init = true
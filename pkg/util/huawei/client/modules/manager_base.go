@@ -20,11 +20,11 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/util/httputils"
 	"yunion.io/x/onecloud/pkg/util/huawei/client/auth"
@@ -153,30 +153,48 @@ func (self *SBaseManager) jsonRequest(request requests.IRequest) (http.Header, j
 	}
 
 	// 发送 request。todo: 支持debug
-	const MAX_RETRY = 3
-	retry := MAX_RETRY
-	for {
-		h, b, e := httputils.JSONRequest(self.httpClient, ctx, httputils.THttpMethod(request.GetMethod()), request.BuildUrl(), header, jsonBody, self.debug)
-		if e == nil {
-			if self.debug {
-				log.Debugf("response: %s body: %s", h, b)
-			}
-			return h, b, e
+	var h http.Header
+	var b jsonutils.JSONObject
+	action := request.GetProduct() + "." + request.GetMethod()
+	err = self.apiCaller().Call(action, classifyHuaweiError(request.GetMethod()), func() error {
+		var e error
+		h, b, e = httputils.JSONRequest(self.httpClient, ctx, httputils.THttpMethod(request.GetMethod()), request.BuildUrl(), header, jsonBody, self.debug)
+		return e
+	})
+	if err != nil {
+		if e, ok := err.(*httputils.JSONClientError); ok && (e.Code == 404 || strings.Index(e.Details, "could not be found") > 0) {
+			return h, b, cloudprovider.ErrNotFound
 		}
+		return h, b, err
+	}
+	if self.debug {
+		log.Debugf("response: %s body: %s", h, b)
+	}
+	return h, b, nil
+}
+
+func (self *SBaseManager) apiCaller() *cloudprovider.SApiCaller {
+	accessKey, _ := self.signer.GetAccessKeyId()
+	return cloudprovider.GetApiCaller(api.CLOUD_PROVIDER_HUAWEI, accessKey)
+}
 
-		switch err := e.(type) {
-		case *httputils.JSONClientError:
-			if err.Code == 499 && retry > 0 && request.GetMethod() == "GET" {
-				retry -= 1
-				time.Sleep(time.Second * time.Duration(MAX_RETRY-retry))
-			} else if err.Code == 404 || strings.Index(err.Details, "could not be found") > 0 {
-				return h, b, cloudprovider.ErrNotFound
-			} else {
-				return h, b, e
-			}
-		default:
-			return h, b, e
+func classifyHuaweiError(method string) cloudprovider.FApiErrorClassifier {
+	return func(err error) string {
+		e, ok := err.(*httputils.JSONClientError)
+		if !ok {
+			return cloudprovider.API_ERROR_SERVER
+		}
+		switch {
+		// APIGW.0308: the throttling threshold has been reached
+		case e.Code == 429 || strings.Contains(e.Details, "APIGW.0308"):
+			return cloudprovider.API_ERROR_THROTTLED
+		// 499 on GET are transient, retrying always succeeds
+		case e.Code == 499 && method == "GET":
+			return cloudprovider.API_ERROR_THROTTLED
+		case e.Code >= 500:
+			return cloudprovider.API_ERROR_SERVER
 		}
+		return cloudprovider.API_ERROR_CLIENT
 	}
 }
 
@@ -44,7 +44,9 @@ const (
 	ServiceNameOBS  ServiceNameType = "obs"  // 对象存储服务 OBS
 	ServiceNameVPC  ServiceNameType = "vpc"  // 虚拟私有云 VPC
 	ServiceNameELB  ServiceNameType = "elb"  // 弹性负载均衡 ELB
+	ServiceNameNAT  ServiceNameType = "nat"  // NAT网关 NAT
 	ServiceNameBSS  ServiceNameType = "bss"  // 合作伙伴运营能力
+	ServiceNameRDS  ServiceNameType = "rds"  // 关系型数据库 RDS
 
 )
 
@@ -0,0 +1,65 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/util/huawei/client/auth"
+	"yunion.io/x/onecloud/pkg/util/huawei/client/responses"
+)
+
+// 客户查询自身的资源消费记录，同余额查询一样只允许使用客户自身的AK/SK
+type SBillManager struct {
+	domainId string // 租户ID
+	SResourceManager
+}
+
+type billCtx struct {
+	domainId string
+}
+
+// https://support.huaweicloud.com/api-oce/zh-cn_topic_0109685133.html
+// url hardcode
+func (self *billCtx) GetPath() string {
+	return fmt.Sprintf("%s/customer/account-mgr/bill", self.domainId)
+}
+
+func NewBillManager(signer auth.Signer, debug bool) *SBillManager {
+	return &SBillManager{SResourceManager: SResourceManager{
+		SBaseManager:  NewBaseManager(signer, debug),
+		ServiceName:   ServiceNameBSS,
+		Region:        "cn-north-1",
+		ProjectId:     "",
+		version:       "v1.0",
+		Keyword:       "fee_record",
+		KeywordPlural: "feeRecords",
+
+		ResourceKeyword: "res-fee-records",
+	}}
+}
+
+func (self *SBillManager) List(querys map[string]string) (*responses.ListResult, error) {
+	if len(self.domainId) == 0 {
+		return nil, fmt.Errorf("domainId is emtpy.Use SetDomainId method to set.")
+	}
+
+	ctx := &billCtx{domainId: self.domainId}
+	return self.ListInContext(ctx, querys)
+}
+
+func (self *SBillManager) SetDomainId(domainId string) {
+	self.domainId = domainId
+}
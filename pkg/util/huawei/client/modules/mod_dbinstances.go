@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+import (
+	"yunion.io/x/onecloud/pkg/util/huawei/client/auth"
+)
+
+type SDBInstanceManager struct {
+	SResourceManager
+}
+
+type SDBInstanceBackupManager struct {
+	SResourceManager
+}
+
+// https://support.huaweicloud.com/api-rds/rds_01_0004.html
+func NewDBInstanceManager(regionId string, projectId string, signer auth.Signer, debug bool) *SDBInstanceManager {
+	return &SDBInstanceManager{SResourceManager: SResourceManager{
+		SBaseManager:  NewBaseManager(signer, debug),
+		ServiceName:   ServiceNameRDS,
+		Region:        regionId,
+		ProjectId:     projectId,
+		version:       "v3",
+		Keyword:       "instance",
+		KeywordPlural: "instances",
+
+		ResourceKeyword: "instances",
+	}}
+}
+
+// https://support.huaweicloud.com/api-rds/rds_09_0003.html
+func NewDBInstanceBackupManager(regionId string, projectId string, signer auth.Signer, debug bool) *SDBInstanceBackupManager {
+	return &SDBInstanceBackupManager{SResourceManager: SResourceManager{
+		SBaseManager:  NewBaseManager(signer, debug),
+		ServiceName:   ServiceNameRDS,
+		Region:        regionId,
+		ProjectId:     projectId,
+		version:       "v3",
+		Keyword:       "backup",
+		KeywordPlural: "backups",
+
+		ResourceKeyword: "backups",
+	}}
+}
@@ -0,0 +1,382 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package huawei
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/huawei/client/responses"
+)
+
+const (
+	// the page size of the rds apis should not exceed 100
+	rdsPageLimit = 100
+
+	rdsTimeFormat = "2006-01-02T15:04:05-0700"
+)
+
+type SDatastore struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+}
+
+type SDBInstanceVolume struct {
+	Type string `json:"type"`
+	Size int    `json:"size"`
+}
+
+type SDBInstanceNode struct {
+	ID               string `json:"id"`
+	Role             string `json:"role"`
+	Status           string `json:"status"`
+	AvailabilityZone string `json:"availability_zone"`
+}
+
+type SDBInstanceChargeInfo struct {
+	ChargeMode string `json:"charge_mode"`
+}
+
+// https://support.huaweicloud.com/api-rds/rds_01_0004.html
+type SDBInstance struct {
+	region *SRegion
+
+	ID         string                `json:"id"`
+	Name       string                `json:"name"`
+	Status     string                `json:"status"`
+	PrivateIps []string              `json:"private_ips"`
+	PublicIps  []string              `json:"public_ips"`
+	Port       int                   `json:"port"`
+	Type       string                `json:"type"`
+	Datastore  SDatastore            `json:"datastore"`
+	Created    string                `json:"created"`
+	DbUserName string                `json:"db_user_name"`
+	VpcId      string                `json:"vpc_id"`
+	SubnetId   string                `json:"subnet_id"`
+	FlavorRef  string                `json:"flavor_ref"`
+	Cpu        string                `json:"cpu"`
+	Mem        string                `json:"mem"`
+	Volume     SDBInstanceVolume     `json:"volume"`
+	Nodes      []SDBInstanceNode     `json:"nodes"`
+	ChargeInfo SDBInstanceChargeInfo `json:"charge_info"`
+}
+
+func (self *SRegion) GetDBInstances(instanceId string) ([]SDBInstance, error) {
+	_, err := self.getECSClient()
+	if err != nil {
+		return nil, err
+	}
+	if self.ecsClient == nil {
+		return []SDBInstance{}, nil
+	}
+	queries := map[string]string{"limit": fmt.Sprintf("%d", rdsPageLimit)}
+	if len(instanceId) > 0 {
+		queries["id"] = instanceId
+	}
+	instances := make([]SDBInstance, 0)
+	for {
+		queries["offset"] = fmt.Sprintf("%d", len(instances))
+		_, part, err := doListPart(self.ecsClient.DBInstances.List, queries, &instances)
+		if err != nil {
+			return nil, err
+		}
+		if part < rdsPageLimit {
+			break
+		}
+	}
+	for i := range instances {
+		instances[i].region = self
+	}
+	return instances, nil
+}
+
+func (self *SRegion) GetIDBInstances() ([]cloudprovider.ICloudDBInstance, error) {
+	instances, err := self.GetDBInstances("")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudDBInstance, len(instances))
+	for i := range instances {
+		ret[i] = &instances[i]
+	}
+	return ret, nil
+}
+
+func (self *SRegion) GetIDBInstanceById(id string) (cloudprovider.ICloudDBInstance, error) {
+	instances, err := self.GetDBInstances(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) != 1 {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return &instances[0], nil
+}
+
+func (self *SDBInstance) GetId() string {
+	return self.ID
+}
+
+func (self *SDBInstance) GetName() string {
+	return self.Name
+}
+
+func (self *SDBInstance) GetGlobalId() string {
+	return self.ID
+}
+
+func (self *SDBInstance) GetStatus() string {
+	switch self.Status {
+	case "BUILD":
+		return api.DBINSTANCE_STATUS_DEPLOYING
+	case "ACTIVE":
+		return api.DBINSTANCE_STATUS_RUNNING
+	case "SHUTDOWN":
+		return api.DBINSTANCE_STATUS_STOPPED
+	case "REBOOTING":
+		return api.DBINSTANCE_STATUS_REBOOTING
+	case "MODIFYING", "MODIFYING INSTANCE TYPE":
+		return api.DBINSTANCE_STATUS_CHANGE_CONFIG
+	case "RESTORING", "SWITCHOVER", "MIGRATING", "BACKING UP", "MODIFYING DATABASE PORT":
+		return api.DBINSTANCE_STATUS_MAINTENANCE
+	case "FAILED", "STORAGE FULL":
+		return api.DBINSTANCE_STATUS_FAILED
+	}
+	return api.DBINSTANCE_STATUS_UNKNOWN
+}
+
+func (self *SDBInstance) Refresh() error {
+	instances, err := self.region.GetDBInstances(self.ID)
+	if err != nil {
+		return err
+	}
+	if len(instances) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(self, instances[0])
+}
+
+func (self *SDBInstance) IsEmulated() bool {
+	return false
+}
+
+func (self *SDBInstance) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SDBInstance) GetProjectId() string {
+	return ""
+}
+
+func (self *SDBInstance) GetBillingType() string {
+	if self.ChargeInfo.ChargeMode == "prePaid" {
+		return models.BILLING_TYPE_PREPAID
+	}
+	return models.BILLING_TYPE_POSTPAID
+}
+
+func (self *SDBInstance) GetExpiredAt() time.Time {
+	return time.Time{}
+}
+
+func (self *SDBInstance) GetEngine() string {
+	return self.Datastore.Type
+}
+
+func (self *SDBInstance) GetEngineVersion() string {
+	return self.Datastore.Version
+}
+
+func (self *SDBInstance) GetInstanceType() string {
+	return self.FlavorRef
+}
+
+func (self *SDBInstance) GetVcpuCount() int {
+	cpu, _ := strconv.Atoi(self.Cpu)
+	return cpu
+}
+
+func (self *SDBInstance) GetVmemSizeMB() int {
+	mem, _ := strconv.Atoi(self.Mem)
+	return mem * 1024
+}
+
+func (self *SDBInstance) GetStorageType() string {
+	return self.Volume.Type
+}
+
+func (self *SDBInstance) GetStorageSizeGB() int {
+	return self.Volume.Size
+}
+
+// GetZoneId returns the zone of the master node
+func (self *SDBInstance) GetZoneId() string {
+	for _, node := range self.Nodes {
+		if node.Role == "master" {
+			return node.AvailabilityZone
+		}
+	}
+	if len(self.Nodes) > 0 {
+		return self.Nodes[0].AvailabilityZone
+	}
+	return ""
+}
+
+func (self *SDBInstance) GetVpcId() string {
+	return self.VpcId
+}
+
+func (self *SDBInstance) GetCreatedAt() time.Time {
+	created, _ := time.Parse(rdsTimeFormat, self.Created)
+	return created
+}
+
+func (self *SDBInstance) GetEndpoints() []cloudprovider.SDBInstanceEndpoint {
+	endpoints := make([]cloudprovider.SDBInstanceEndpoint, 0, len(self.PrivateIps)+len(self.PublicIps))
+	for _, ip := range self.PrivateIps {
+		endpoints = append(endpoints, cloudprovider.SDBInstanceEndpoint{Type: cloudprovider.DBINSTANCE_ENDPOINT_INTRANET, Address: ip, Port: self.Port})
+	}
+	for _, ip := range self.PublicIps {
+		endpoints = append(endpoints, cloudprovider.SDBInstanceEndpoint{Type: cloudprovider.DBINSTANCE_ENDPOINT_INTERNET, Address: ip, Port: self.Port})
+	}
+	return endpoints
+}
+
+// https://support.huaweicloud.com/api-rds/rds_09_0003.html
+type SDBInstanceBackup struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Size      int    `json:"size"`
+	Status    string `json:"status"`
+	BeginTime string `json:"begin_time"`
+	EndTime   string `json:"end_time"`
+}
+
+func (self *SDBInstance) GetBackups() ([]cloudprovider.SDBInstanceBackup, error) {
+	queries := map[string]string{
+		"instance_id": self.ID,
+		"limit":       fmt.Sprintf("%d", rdsPageLimit),
+	}
+	backups := make([]SDBInstanceBackup, 0)
+	for {
+		queries["offset"] = fmt.Sprintf("%d", len(backups))
+		_, part, err := doListPart(self.region.ecsClient.DBInstanceBackups.List, queries, &backups)
+		if err != nil {
+			return nil, err
+		}
+		if part < rdsPageLimit {
+			break
+		}
+	}
+	ret := make([]cloudprovider.SDBInstanceBackup, len(backups))
+	for i, backup := range backups {
+		ret[i] = cloudprovider.SDBInstanceBackup{
+			Id:     backup.ID,
+			Mode:   cloudprovider.DBINSTANCE_BACKUP_AUTOMATED,
+			Status: backup.Status,
+			// size in KB
+			SizeMB: backup.Size / 1024,
+		}
+		ret[i].StartTime, _ = time.Parse(rdsTimeFormat, backup.BeginTime)
+		ret[i].EndTime, _ = time.Parse(rdsTimeFormat, backup.EndTime)
+		if backup.Type == "manual" {
+			ret[i].Mode = cloudprovider.DBINSTANCE_BACKUP_MANUAL
+		}
+	}
+	return ret, nil
+}
+
+type SDBInstanceUser struct {
+	Name      string   `json:"name"`
+	Databases []string `json:"databases"`
+}
+
+// the accounts api is only available for mysql instances
+func (self *SDBInstance) GetAccounts() ([]cloudprovider.SDBInstanceAccount, error) {
+	if self.Datastore.Type != api.DBINSTANCE_ENGINE_MYSQL {
+		return nil, cloudprovider.ErrNotSupported
+	}
+	listUsers := func(queries map[string]string) (*responses.ListResult, error) {
+		return self.region.ecsClient.DBInstances.ListInContextWithSpec(nil, self.ID+"/db_user/detail", queries, "users")
+	}
+	users := make([]SDBInstanceUser, 0)
+	queries := map[string]string{"limit": fmt.Sprintf("%d", rdsPageLimit)}
+	for page := 1; ; page++ {
+		queries["page"] = fmt.Sprintf("%d", page)
+		_, part, err := doListPart(listUsers, queries, &users)
+		if err != nil {
+			return nil, err
+		}
+		if part < rdsPageLimit {
+			break
+		}
+	}
+	ret := make([]cloudprovider.SDBInstanceAccount, len(users))
+	for i, user := range users {
+		ret[i] = cloudprovider.SDBInstanceAccount{Name: user.Name, Status: "available", Databases: user.Databases}
+		if ret[i].Databases == nil {
+			ret[i].Databases = []string{}
+		}
+	}
+	return ret, nil
+}
+
+func (self *SDBInstance) doAction(action string, params jsonutils.JSONObject) error {
+	_, err := self.region.ecsClient.DBInstances.PerformAction2(action, self.ID, params, "")
+	return err
+}
+
+func (self *SDBInstance) Start() error {
+	return self.doAction("action/startup", nil)
+}
+
+func (self *SDBInstance) Stop() error {
+	return self.doAction("action/shutdown", nil)
+}
+
+func (self *SDBInstance) Reboot() error {
+	params := jsonutils.NewDict()
+	params.Set("restart", jsonutils.NewDict())
+	return self.doAction("action", params)
+}
+
+// ChangeConfig resizes the flavor and enlarges the volume separately,
+// the volume of an instance can not be shrunk
+func (self *SDBInstance) ChangeConfig(config *cloudprovider.SDBInstanceChangeConfig) error {
+	if len(config.InstanceType) > 0 && config.InstanceType != self.FlavorRef {
+		params := jsonutils.NewDict()
+		resize := jsonutils.NewDict()
+		resize.Set("spec_code", jsonutils.NewString(config.InstanceType))
+		params.Set("resize_flavor", resize)
+		err := self.doAction("action", params)
+		if err != nil {
+			return err
+		}
+	}
+	if config.StorageSizeGB > self.Volume.Size {
+		params := jsonutils.NewDict()
+		enlarge := jsonutils.NewDict()
+		enlarge.Set("size", jsonutils.NewInt(int64(config.StorageSizeGB)))
+		params.Set("enlarge_volume", enlarge)
+		return self.doAction("action", params)
+	}
+	return nil
+}
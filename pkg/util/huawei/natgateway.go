@@ -0,0 +1,368 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package huawei
+
+import (
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// https://support.huaweicloud.com/api-nat/nat_api_0003.html
+type SNatGateway struct {
+	vpc *SVpc
+
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	Spec              string `json:"spec"`
+	RouterId          string `json:"router_id"`
+	InternalNetworkId string `json:"internal_network_id"`
+	Status            string `json:"status"`
+	AdminStateUp      bool   `json:"admin_state_up"`
+	CreatedAt         string `json:"created_at"`
+}
+
+type SNatSEntry struct {
+	nat *SNatGateway
+
+	ID                string `json:"id"`
+	NatGatewayId      string `json:"nat_gateway_id"`
+	NetworkId         string `json:"network_id"`
+	Cidr              string `json:"cidr"`
+	SourceType        int    `json:"source_type"`
+	FloatingIpId      string `json:"floating_ip_id"`
+	FloatingIpAddress string `json:"floating_ip_address"`
+	Status            string `json:"status"`
+}
+
+type SNatDEntry struct {
+	nat *SNatGateway
+
+	ID                  string `json:"id"`
+	NatGatewayId        string `json:"nat_gateway_id"`
+	PortId              string `json:"port_id"`
+	PrivateIp           string `json:"private_ip"`
+	InternalServicePort int    `json:"internal_service_port"`
+	FloatingIpId        string `json:"floating_ip_id"`
+	FloatingIpAddress   string `json:"floating_ip_address"`
+	ExternalServicePort int    `json:"external_service_port"`
+	Protocol            string `json:"protocol"`
+	Status              string `json:"status"`
+}
+
+func natStatus(status string) string {
+	switch status {
+	case "ACTIVE":
+		return api.NAT_STATUS_AVAILABLE
+	case "PENDING_CREATE":
+		return api.NAT_STATUS_ALLOCATE
+	case "PENDING_UPDATE":
+		return api.NAT_STATUS_DEPLOYING
+	case "PENDING_DELETE":
+		return api.NAT_STATUS_DELETING
+	case "INACTIVE", "EIP_FREEZED":
+		return api.NAT_STATUS_FAILED
+	default:
+		return api.NAT_STATUS_UNKNOWN
+	}
+}
+
+func (self *SRegion) GetNatGateways(vpcId, natGatewayId string) ([]SNatGateway, error) {
+	queries := make(map[string]string)
+	if len(vpcId) > 0 {
+		queries["router_id"] = vpcId
+	}
+	if len(natGatewayId) > 0 {
+		queries["id"] = natGatewayId
+	}
+	natGateways := make([]SNatGateway, 0)
+	err := doListAllWithMarker(self.ecsClient.NatGateways.List, queries, &natGateways)
+	if err != nil {
+		return nil, err
+	}
+	return natGateways, nil
+}
+
+func (self *SRegion) GetNatSEntries(natGatewayId string) ([]SNatSEntry, error) {
+	queries := map[string]string{"nat_gateway_id": natGatewayId}
+	entries := make([]SNatSEntry, 0)
+	err := doListAllWithMarker(self.ecsClient.SNatRules.List, queries, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (self *SRegion) GetNatDEntries(natGatewayId string) ([]SNatDEntry, error) {
+	queries := map[string]string{"nat_gateway_id": natGatewayId}
+	entries := make([]SNatDEntry, 0)
+	err := doListAllWithMarker(self.ecsClient.DNatRules.List, queries, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (self *SRegion) CreateNatSEntry(natGatewayId string, rule cloudprovider.SNatSRule) (*SNatSEntry, error) {
+	if len(rule.ExternalIPID) == 0 {
+		return nil, fmt.Errorf("CreateNatSEntry: floating ip id should not be empty")
+	}
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(natGatewayId), "snat_rule", "nat_gateway_id")
+	params.Add(jsonutils.NewString(rule.ExternalIPID), "snat_rule", "floating_ip_id")
+	if len(rule.NetworkID) > 0 {
+		params.Add(jsonutils.NewString(rule.NetworkID), "snat_rule", "network_id")
+	} else {
+		params.Add(jsonutils.NewString(rule.SourceCIDR), "snat_rule", "cidr")
+		params.Add(jsonutils.NewInt(0), "snat_rule", "source_type")
+	}
+	entry := SNatSEntry{}
+	err := DoCreate(self.ecsClient.SNatRules.Create, params, &entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (self *SRegion) CreateNatDEntry(natGatewayId string, rule cloudprovider.SNatDRule) (*SNatDEntry, error) {
+	if len(rule.ExternalIPID) == 0 {
+		return nil, fmt.Errorf("CreateNatDEntry: floating ip id should not be empty")
+	}
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(natGatewayId), "dnat_rule", "nat_gateway_id")
+	params.Add(jsonutils.NewString(rule.ExternalIPID), "dnat_rule", "floating_ip_id")
+	params.Add(jsonutils.NewInt(int64(rule.ExternalPort)), "dnat_rule", "external_service_port")
+	params.Add(jsonutils.NewString(rule.InternalIP), "dnat_rule", "private_ip")
+	params.Add(jsonutils.NewInt(int64(rule.InternalPort)), "dnat_rule", "internal_service_port")
+	params.Add(jsonutils.NewString(rule.Protocol), "dnat_rule", "protocol")
+	entry := SNatDEntry{}
+	err := DoCreate(self.ecsClient.DNatRules.Create, params, &entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (nat *SNatGateway) GetId() string {
+	return nat.ID
+}
+
+func (nat *SNatGateway) GetName() string {
+	if len(nat.Name) > 0 {
+		return nat.Name
+	}
+	return nat.ID
+}
+
+func (nat *SNatGateway) GetGlobalId() string {
+	return nat.ID
+}
+
+func (nat *SNatGateway) GetStatus() string {
+	return natStatus(nat.Status)
+}
+
+func (nat *SNatGateway) Refresh() error {
+	nats, err := nat.vpc.region.GetNatGateways(nat.RouterId, nat.ID)
+	if err != nil {
+		return err
+	}
+	if len(nats) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(nat, nats[0])
+}
+
+func (nat *SNatGateway) IsEmulated() bool {
+	return false
+}
+
+func (nat *SNatGateway) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (nat *SNatGateway) GetNatSpec() string {
+	switch nat.Spec {
+	case "1":
+		return api.NAT_SPEC_SMALL
+	case "2":
+		return api.NAT_SPEC_MIDDLE
+	case "3":
+		return api.NAT_SPEC_LARGE
+	case "4":
+		return api.NAT_SPEC_XLARGE
+	}
+	return nat.Spec
+}
+
+func (nat *SNatGateway) GetDescription() string {
+	return nat.Description
+}
+
+func (nat *SNatGateway) GetINatSEntries() ([]cloudprovider.ICloudNatSEntry, error) {
+	entries, err := nat.vpc.region.GetNatSEntries(nat.ID)
+	if err != nil {
+		return nil, err
+	}
+	ientries := make([]cloudprovider.ICloudNatSEntry, len(entries))
+	for i := 0; i < len(entries); i++ {
+		entries[i].nat = nat
+		ientries[i] = &entries[i]
+	}
+	return ientries, nil
+}
+
+func (nat *SNatGateway) GetINatDEntries() ([]cloudprovider.ICloudNatDEntry, error) {
+	entries, err := nat.vpc.region.GetNatDEntries(nat.ID)
+	if err != nil {
+		return nil, err
+	}
+	ientries := make([]cloudprovider.ICloudNatDEntry, len(entries))
+	for i := 0; i < len(entries); i++ {
+		entries[i].nat = nat
+		ientries[i] = &entries[i]
+	}
+	return ientries, nil
+}
+
+func (nat *SNatGateway) CreateINatSEntry(rule cloudprovider.SNatSRule) (cloudprovider.ICloudNatSEntry, error) {
+	entry, err := nat.vpc.region.CreateNatSEntry(nat.ID, rule)
+	if err != nil {
+		return nil, err
+	}
+	entry.nat = nat
+	return entry, nil
+}
+
+func (nat *SNatGateway) CreateINatDEntry(rule cloudprovider.SNatDRule) (cloudprovider.ICloudNatDEntry, error) {
+	entry, err := nat.vpc.region.CreateNatDEntry(nat.ID, rule)
+	if err != nil {
+		return nil, err
+	}
+	entry.nat = nat
+	return entry, nil
+}
+
+func (entry *SNatSEntry) GetId() string {
+	return entry.ID
+}
+
+func (entry *SNatSEntry) GetName() string {
+	return entry.ID
+}
+
+func (entry *SNatSEntry) GetGlobalId() string {
+	return entry.ID
+}
+
+func (entry *SNatSEntry) GetStatus() string {
+	return natStatus(entry.Status)
+}
+
+func (entry *SNatSEntry) Refresh() error {
+	new := SNatSEntry{}
+	err := DoGet(entry.nat.vpc.region.ecsClient.SNatRules.Get, entry.ID, nil, &new)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(entry, new)
+}
+
+func (entry *SNatSEntry) IsEmulated() bool {
+	return false
+}
+
+func (entry *SNatSEntry) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (entry *SNatSEntry) GetIP() string {
+	return entry.FloatingIpAddress
+}
+
+func (entry *SNatSEntry) GetSourceCIDR() string {
+	return entry.Cidr
+}
+
+func (entry *SNatSEntry) GetNetworkId() string {
+	return entry.NetworkId
+}
+
+func (entry *SNatSEntry) Delete() error {
+	_, err := entry.nat.vpc.region.ecsClient.SNatRules.Delete(entry.ID, nil)
+	return err
+}
+
+func (entry *SNatDEntry) GetId() string {
+	return entry.ID
+}
+
+func (entry *SNatDEntry) GetName() string {
+	return entry.ID
+}
+
+func (entry *SNatDEntry) GetGlobalId() string {
+	return entry.ID
+}
+
+func (entry *SNatDEntry) GetStatus() string {
+	return natStatus(entry.Status)
+}
+
+func (entry *SNatDEntry) Refresh() error {
+	new := SNatDEntry{}
+	err := DoGet(entry.nat.vpc.region.ecsClient.DNatRules.Get, entry.ID, nil, &new)
+	if err != nil {
+		return err
+	}
+	return jsonutils.Update(entry, new)
+}
+
+func (entry *SNatDEntry) IsEmulated() bool {
+	return false
+}
+
+func (entry *SNatDEntry) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (entry *SNatDEntry) GetIpProtocol() string {
+	return entry.Protocol
+}
+
+func (entry *SNatDEntry) GetExternalIp() string {
+	return entry.FloatingIpAddress
+}
+
+func (entry *SNatDEntry) GetExternalPort() int {
+	return entry.ExternalServicePort
+}
+
+func (entry *SNatDEntry) GetInternalIp() string {
+	return entry.PrivateIp
+}
+
+func (entry *SNatDEntry) GetInternalPort() int {
+	return entry.InternalServicePort
+}
+
+func (entry *SNatDEntry) Delete() error {
+	_, err := entry.nat.vpc.region.ecsClient.DNatRules.Delete(entry.ID, nil)
+	return err
+}
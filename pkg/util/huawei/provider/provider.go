@@ -156,3 +156,7 @@ func (self *SHuaweiProvider) GetCloudRegionExternalIdPrefix() string {
 func (self *SHuaweiProvider) GetIProjects() ([]cloudprovider.ICloudProject, error) {
 	return self.client.GetIProjects()
 }
+
+func (self *SHuaweiProvider) GetCloudBillItems(query cloudprovider.SCloudBillQuery) ([]cloudprovider.SCloudBillItem, error) {
+	return self.client.GetCloudBillItems(query)
+}
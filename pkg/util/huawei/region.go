@@ -663,3 +663,11 @@ func (region *SRegion) CreateILoadBalancerAcl(acl *cloudprovider.SLoadbalancerAc
 func (region *SRegion) GetSkus(zoneId string) ([]cloudprovider.ICloudSku, error) {
 	return nil, cloudprovider.ErrNotImplemented
 }
+
+func (self *SRegion) GetIVpcPeerings() ([]cloudprovider.ICloudVpcPeering, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
+
+func (self *SRegion) GetIVpnConnections() ([]cloudprovider.ICloudVpnConnection, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
@@ -170,6 +170,19 @@ func (self *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
 	return rts, nil
 }
 
+func (self *SVpc) GetINatGateways() ([]cloudprovider.ICloudNatGateway, error) {
+	nats, err := self.region.GetNatGateways(self.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	inats := make([]cloudprovider.ICloudNatGateway, len(nats))
+	for i := 0; i < len(nats); i++ {
+		nats[i].vpc = self
+		inats[i] = &nats[i]
+	}
+	return inats, nil
+}
+
 func (self *SVpc) GetManagerId() string {
 	return self.region.client.providerId
 }
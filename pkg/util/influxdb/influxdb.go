@@ -42,13 +42,19 @@ func NewInfluxdb(accessUrl string) *SInfluxdb {
 	return &inst
 }
 
-type dbResult struct {
+type SDBResult struct {
 	Name    string
+	Tags    map[string]string
 	Columns []string
 	Values  [][]jsonutils.JSONObject
 }
 
-func (db *SInfluxdb) query(sql string) ([][]dbResult, error) {
+// Query runs a statement, the measurements must be qualified with the database
+func (db *SInfluxdb) Query(sql string) ([][]SDBResult, error) {
+	return db.query(sql)
+}
+
+func (db *SInfluxdb) query(sql string) ([][]SDBResult, error) {
 	nurl := fmt.Sprintf("%s/query?q=%s", db.accessUrl, url.QueryEscape(sql))
 	_, body, err := httputils.JSONRequest(db.client, context.Background(), "POST", nurl, nil, nil, false)
 	if err != nil {
@@ -59,11 +65,11 @@ func (db *SInfluxdb) query(sql string) ([][]dbResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	rets := make([][]dbResult, len(results))
+	rets := make([][]SDBResult, len(results))
 	for i := range results {
 		series, err := results[i].Get("series")
 		if err == nil {
-			ret := make([]dbResult, 0)
+			ret := make([]SDBResult, 0)
 			err = series.Unmarshal(&ret)
 			if err != nil {
 				return nil, err
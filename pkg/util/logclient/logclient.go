@@ -67,7 +67,7 @@ var WhiteList = []string{
 	ACT_SWITCH_TO_BACKUP, ACT_RENEW, ACT_MIGRATE,
 	ACT_IMAGE_SAVE, ACT_RECYCLE_PREPAID, ACT_UNDO_RECYCLE_PREPAID,
 	ACT_FETCH, ACT_VM_CHANGE_NIC, ACT_HOST_IMPORT_LIBVIRT_SERVERS,
-	ACT_GUEST_CREATE_FROM_IMPORT,
+	ACT_GUEST_CREATE_FROM_IMPORT, ACT_HOST_FAILOVER,
 }
 
 const (
@@ -139,6 +139,8 @@ const (
 
 	ACT_HOST_IMPORT_LIBVIRT_SERVERS = "libvirt托管虚拟机导入"
 	ACT_GUEST_CREATE_FROM_IMPORT    = "导入虚拟机创建"
+
+	ACT_HOST_FAILOVER = "宿主机故障迁移"
 )
 
 // golang 不支持 const 的string array, http://t.cn/EzAvbw8
@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+// SResourceBase is embedded by all the mock resources
+type SResourceBase struct {
+	client *SMockClient
+
+	Id        string
+	Name      string
+	Status    string
+	CreatedAt time.Time
+	Tags      map[string]string
+}
+
+func (self *SResourceBase) getBase() *SResourceBase {
+	return self
+}
+
+func (self *SResourceBase) GetId() string {
+	return self.Id
+}
+
+func (self *SResourceBase) GetName() string {
+	return self.Name
+}
+
+func (self *SResourceBase) GetGlobalId() string {
+	return self.Id
+}
+
+func (self *SResourceBase) GetStatus() string {
+	return self.Status
+}
+
+func (self *SResourceBase) IsEmulated() bool {
+	return false
+}
+
+func (self *SResourceBase) GetMetadata() *jsonutils.JSONDict {
+	if len(self.Tags) == 0 {
+		return nil
+	}
+	return jsonutils.Marshal(self.Tags).(*jsonutils.JSONDict)
+}
+
+func (self *SResourceBase) GetProjectId() string {
+	return ""
+}
+
+func (self *SResourceBase) GetBillingType() string {
+	return models.BILLING_TYPE_POSTPAID
+}
+
+func (self *SResourceBase) GetExpiredAt() time.Time {
+	return time.Time{}
+}
+
+func (self *SResourceBase) GetManagerId() string {
+	return self.client.providerId
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type SDisk struct {
+	SResourceBase
+
+	RegionId   string
+	StorageId  string
+	InstanceId string
+	SizeMB     int
+	DiskType   string
+	AutoDelete bool
+	TemplateId string
+}
+
+func (self *SDisk) Refresh() error {
+	return self.client.refresh(kindDisk, self)
+}
+
+func (self *SDisk) GetIStorage() (cloudprovider.ICloudStorage, error) {
+	res, err := self.client.get(kindStorage, self.StorageId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SStorage), nil
+}
+
+func (self *SDisk) GetDiskFormat() string {
+	return "qcow2"
+}
+
+func (self *SDisk) GetDiskSizeMB() int {
+	return self.SizeMB
+}
+
+func (self *SDisk) GetIsAutoDelete() bool {
+	return self.AutoDelete
+}
+
+func (self *SDisk) GetTemplateId() string {
+	return self.TemplateId
+}
+
+func (self *SDisk) GetDiskType() string {
+	return self.DiskType
+}
+
+func (self *SDisk) GetFsFormat() string {
+	return ""
+}
+
+func (self *SDisk) GetIsNonPersistent() bool {
+	return false
+}
+
+func (self *SDisk) GetDriver() string {
+	return "virtio"
+}
+
+func (self *SDisk) GetCacheMode() string {
+	return "none"
+}
+
+func (self *SDisk) GetMountpoint() string {
+	return ""
+}
+
+func (self *SDisk) GetAccessPath() string {
+	return ""
+}
+
+// Delete fails if the disk is attached
+func (self *SDisk) Delete(ctx context.Context) error {
+	if err := self.client.cloud.call("DeleteDisk"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	return cloud.transact(func() error {
+		res, err := cloud.record(kindDisk, self.Id)
+		if err != nil {
+			// already deleted
+			return nil
+		}
+		if instanceId := res.(*SDisk).InstanceId; len(instanceId) > 0 {
+			return fmt.Errorf("disk %s is attached to %s", self.Id, instanceId)
+		}
+		cloud.remove(kindDisk, self.Id)
+		return nil
+	})
+}
+
+func (self *SDisk) CreateISnapshot(ctx context.Context, name string, desc string) (cloudprovider.ICloudSnapshot, error) {
+	if err := self.client.cloud.call("CreateISnapshot"); err != nil {
+		return nil, err
+	}
+	snapshot := self.client.create(kindSnapshot, "snap", &SSnapshot{
+		SResourceBase: SResourceBase{Name: name, Status: models.SNAPSHOT_READY},
+		RegionId:      self.RegionId,
+		DiskId:        self.Id,
+		DiskType:      self.DiskType,
+		SizeGB:        int32(self.SizeMB / 1024),
+	})
+	return snapshot.(*SSnapshot), nil
+}
+
+func (self *SDisk) GetISnapshot(idStr string) (cloudprovider.ICloudSnapshot, error) {
+	res, err := self.client.get(kindSnapshot, idStr)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := res.(*SSnapshot)
+	if snapshot.DiskId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return snapshot, nil
+}
+
+func (self *SDisk) GetISnapshots() ([]cloudprovider.ICloudSnapshot, error) {
+	records := self.client.list(kindSnapshot, func(res iMockResource) bool {
+		return res.(*SSnapshot).DiskId == self.Id
+	})
+	isnapshots := make([]cloudprovider.ICloudSnapshot, len(records))
+	for i := range records {
+		isnapshots[i] = records[i].(*SSnapshot)
+	}
+	return isnapshots, nil
+}
+
+func (self *SDisk) Resize(ctx context.Context, newSizeMB int64) error {
+	if err := self.client.cloud.call("ResizeDisk"); err != nil {
+		return err
+	}
+	err := self.client.cloud.update(kindDisk, self.Id, func(res iMockResource) error {
+		disk := res.(*SDisk)
+		if int(newSizeMB) < disk.SizeMB {
+			return fmt.Errorf("cannot shrink disk %s from %dMB to %dMB", disk.Id, disk.SizeMB, newSizeMB)
+		}
+		disk.SizeMB = int(newSizeMB)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SDisk) Reset(ctx context.Context, snapshotId string) (string, error) {
+	if err := self.client.cloud.call("ResetDisk"); err != nil {
+		return "", err
+	}
+	if _, err := self.GetISnapshot(snapshotId); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+func (self *SDisk) Rebuild(ctx context.Context) error {
+	return cloudprovider.ErrNotSupported
+}
+
+type SSnapshot struct {
+	SResourceBase
+
+	RegionId string
+	DiskId   string
+	DiskType string
+	SizeGB   int32
+}
+
+func (self *SSnapshot) Refresh() error {
+	return self.client.refresh(kindSnapshot, self)
+}
+
+func (self *SSnapshot) GetSize() int32 {
+	return self.SizeGB
+}
+
+func (self *SSnapshot) GetDiskId() string {
+	return self.DiskId
+}
+
+func (self *SSnapshot) GetDiskType() string {
+	return self.DiskType
+}
+
+func (self *SSnapshot) Delete() error {
+	if err := self.client.cloud.call("DeleteSnapshot"); err != nil {
+		return err
+	}
+	return self.client.cloud.transact(func() error {
+		self.client.cloud.remove(kindSnapshot, self.Id)
+		return nil
+	})
+}
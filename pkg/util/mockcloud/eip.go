@@ -0,0 +1,172 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type SEip struct {
+	SResourceBase
+
+	RegionId   string
+	IpAddr     string
+	Bandwidth  int
+	ChargeType string
+	InstanceId string
+}
+
+// allocEipAddr allocates a public address from 100.64.0.0/10 for an eip or
+// an internet loadbalancer
+func (self *SMockCloud) allocEipAddr() string {
+	used := map[string]bool{}
+	for _, res := range self.records(kindEip, nil) {
+		used[res.(*SEip).IpAddr] = true
+	}
+	for _, res := range self.records(kindLoadbalancer, nil) {
+		used[res.(*SLoadbalancer).Address] = true
+	}
+	for i := 1; ; i++ {
+		addr := fmt.Sprintf("100.%d.%d.%d", 64+i/65536%64, i/256%256, i%256)
+		if i%256 != 0 && !used[addr] {
+			return addr
+		}
+	}
+}
+
+func (self *SEip) Refresh() error {
+	return self.client.refresh(kindEip, self)
+}
+
+func (self *SEip) GetIpAddr() string {
+	return self.IpAddr
+}
+
+func (self *SEip) GetMode() string {
+	return models.EIP_MODE_STANDALONE_EIP
+}
+
+func (self *SEip) GetAssociationType() string {
+	if len(self.InstanceId) > 0 {
+		return models.EIP_ASSOCIATE_TYPE_SERVER
+	}
+	return ""
+}
+
+func (self *SEip) GetAssociationExternalId() string {
+	return self.InstanceId
+}
+
+func (self *SEip) GetBandwidth() int {
+	return self.Bandwidth
+}
+
+func (self *SEip) GetInternetChargeType() string {
+	return self.ChargeType
+}
+
+// Delete fails if the eip is associated
+func (self *SEip) Delete() error {
+	if err := self.client.cloud.call("DeleteEip"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	return cloud.transact(func() error {
+		res, err := cloud.record(kindEip, self.Id)
+		if err != nil {
+			return nil
+		}
+		if instanceId := res.(*SEip).InstanceId; len(instanceId) > 0 {
+			return fmt.Errorf("eip %s is associated with %s", self.Id, instanceId)
+		}
+		cloud.remove(kindEip, self.Id)
+		return nil
+	})
+}
+
+func (self *SEip) Associate(instanceId string) error {
+	if err := self.client.cloud.call("AssociateEip"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	err := cloud.transact(func() error {
+		res, err := cloud.record(kindEip, self.Id)
+		if err != nil {
+			return err
+		}
+		eip := res.(*SEip)
+		if len(eip.InstanceId) > 0 && eip.InstanceId != instanceId {
+			return fmt.Errorf("eip %s is associated with %s", self.Id, eip.InstanceId)
+		}
+		res, err = cloud.record(kindInstance, instanceId)
+		if err != nil {
+			return err
+		}
+		instance := res.(*SInstance)
+		if len(instance.EipId) > 0 && instance.EipId != self.Id {
+			return fmt.Errorf("instance %s already has eip %s", instanceId, instance.EipId)
+		}
+		eip.InstanceId = instanceId
+		instance.EipId = self.Id
+		cloud.touch(kindEip, eip.Id)
+		cloud.touch(kindInstance, instance.Id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SEip) Dissociate() error {
+	if err := self.client.cloud.call("DissociateEip"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	err := cloud.transact(func() error {
+		res, err := cloud.record(kindEip, self.Id)
+		if err != nil {
+			return err
+		}
+		eip := res.(*SEip)
+		if res, err := cloud.record(kindInstance, eip.InstanceId); err == nil {
+			res.(*SInstance).EipId = ""
+			cloud.touch(kindInstance, eip.InstanceId)
+		}
+		eip.InstanceId = ""
+		cloud.touch(kindEip, eip.Id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SEip) ChangeBandwidth(bw int) error {
+	if err := self.client.cloud.call("ChangeBandwidth"); err != nil {
+		return err
+	}
+	err := self.client.cloud.update(kindEip, self.Id, func(res iMockResource) error {
+		res.(*SEip).Bandwidth = bw
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
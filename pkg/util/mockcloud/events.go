@@ -0,0 +1,108 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"reflect"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// eventResourceTypes maps the kinds reported by the change feed of the mock
+// cloud to the event resource types
+var eventResourceTypes = map[string]string{
+	kindInstance:     cloudprovider.CLOUD_EVENT_RESOURCE_SERVER,
+	kindDisk:         cloudprovider.CLOUD_EVENT_RESOURCE_DISK,
+	kindSnapshot:     cloudprovider.CLOUD_EVENT_RESOURCE_SNAPSHOT,
+	kindEip:          cloudprovider.CLOUD_EVENT_RESOURCE_EIP,
+	kindVpc:          cloudprovider.CLOUD_EVENT_RESOURCE_VPC,
+	kindSecgroup:     cloudprovider.CLOUD_EVENT_RESOURCE_SECGROUP,
+	kindLoadbalancer: cloudprovider.CLOUD_EVENT_RESOURCE_LOADBALANCER,
+}
+
+type sMockEvent struct {
+	cloudprovider.SCloudEvent
+
+	RegionId string
+}
+
+// regionIdOf returns the region of res, assume the lock is held
+func (self *SMockCloud) regionIdOf(res iMockResource) string {
+	if secgroup, ok := res.(*SSecurityGroup); ok {
+		vpc, err := self.record(kindVpc, secgroup.VpcId)
+		if err != nil {
+			return ""
+		}
+		res = vpc
+	}
+	field := reflect.ValueOf(res).Elem().FieldByName("RegionId")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// emit appends a change event of res to the feed, assume the lock is held
+func (self *SMockCloud) emit(kind string, res iMockResource, action string) {
+	resourceType, ok := eventResourceTypes[kind]
+	if !ok {
+		return
+	}
+	self.events = append(self.events, sMockEvent{
+		SCloudEvent: cloudprovider.SCloudEvent{
+			ResourceType: resourceType,
+			ResourceId:   res.getBase().Id,
+			Action:       action,
+			CreatedAt:    time.Now().UTC(),
+		},
+		RegionId: self.regionIdOf(res),
+	})
+	if len(self.events) > MOCK_MAX_EVENT_COUNT {
+		dropped := len(self.events) - MOCK_MAX_EVENT_COUNT
+		self.eventsSince = self.events[dropped-1].CreatedAt
+		self.events = append([]sMockEvent{}, self.events[dropped:]...)
+	}
+}
+
+// touch reports a record modified in a transaction, assume the lock is held
+func (self *SMockCloud) touch(kind string, id string) {
+	res, err := self.record(kind, id)
+	if err != nil {
+		return
+	}
+	self.emit(kind, res, cloudprovider.CLOUD_EVENT_ACTION_UPDATE)
+}
+
+func (self *SRegion) GetICloudEvents(start time.Time, end time.Time) ([]cloudprovider.SCloudEvent, error) {
+	cloud := self.client.cloud
+	if err := cloud.call("GetICloudEvents"); err != nil {
+		return nil, err
+	}
+	cloud.lock.Lock()
+	defer cloud.lock.Unlock()
+
+	if start.Before(cloud.eventsSince) {
+		return nil, cloudprovider.ErrEventExpired
+	}
+	events := []cloudprovider.SCloudEvent{}
+	for _, event := range cloud.events {
+		if event.RegionId != self.Id || event.CreatedAt.Before(start) || !event.CreatedAt.Before(end) {
+			continue
+		}
+		events = append(events, event.SCloudEvent)
+	}
+	return events, nil
+}
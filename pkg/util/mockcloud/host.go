@@ -0,0 +1,288 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	MOCK_HOST_CPU_COUNT   = 64
+	MOCK_HOST_MEM_SIZE_MB = 256 * 1024
+)
+
+// SHost is the emulated host of a zone which all the instances of the zone are on
+type SHost struct {
+	SResourceBase
+
+	ZoneId string
+}
+
+func (self *SHost) IsEmulated() bool {
+	return true
+}
+
+func (self *SHost) Refresh() error {
+	return self.client.refresh(kindHost, self)
+}
+
+func (self *SHost) getZone() (*SZone, error) {
+	res, err := self.client.get(kindZone, self.ZoneId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SZone), nil
+}
+
+func (self *SHost) GetIVMs() ([]cloudprovider.ICloudVM, error) {
+	if err := self.client.cloud.call("GetIVMs"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindInstance, func(res iMockResource) bool {
+		return res.(*SInstance).HostId == self.Id
+	})
+	ivms := make([]cloudprovider.ICloudVM, len(records))
+	for i := range records {
+		ivms[i] = records[i].(*SInstance)
+	}
+	return ivms, nil
+}
+
+func (self *SHost) GetIVMById(id string) (cloudprovider.ICloudVM, error) {
+	if err := self.client.cloud.call("GetIVMById"); err != nil {
+		return nil, err
+	}
+	res, err := self.client.get(kindInstance, id)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SInstance), nil
+}
+
+func (self *SHost) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, err
+	}
+	return zone.GetIWires()
+}
+
+func (self *SHost) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, err
+	}
+	return zone.GetIStorages()
+}
+
+func (self *SHost) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, err
+	}
+	return zone.GetIStorageById(id)
+}
+
+func (self *SHost) GetEnabled() bool {
+	return true
+}
+
+func (self *SHost) GetHostStatus() string {
+	return api.HOST_ONLINE
+}
+
+func (self *SHost) GetAccessIp() string {
+	return ""
+}
+
+func (self *SHost) GetAccessMac() string {
+	return ""
+}
+
+func (self *SHost) GetSysInfo() jsonutils.JSONObject {
+	info := jsonutils.NewDict()
+	info.Add(jsonutils.NewString(CLOUD_PROVIDER_MOCK), "manufacture")
+	return info
+}
+
+func (self *SHost) GetSN() string {
+	return ""
+}
+
+func (self *SHost) GetCpuCount() int8 {
+	return MOCK_HOST_CPU_COUNT
+}
+
+func (self *SHost) GetNodeCount() int8 {
+	return 1
+}
+
+func (self *SHost) GetCpuDesc() string {
+	return ""
+}
+
+func (self *SHost) GetCpuMhz() int {
+	return 0
+}
+
+func (self *SHost) GetMemSizeMB() int {
+	return MOCK_HOST_MEM_SIZE_MB
+}
+
+func (self *SHost) GetStorageSizeMB() int {
+	return 0
+}
+
+func (self *SHost) GetStorageType() string {
+	return api.DISK_TYPE_HYBRID
+}
+
+func (self *SHost) GetHostType() string {
+	return api.HOST_TYPE_MOCK
+}
+
+func (self *SHost) GetIsMaintenance() bool {
+	return false
+}
+
+func (self *SHost) GetVersion() string {
+	return MOCK_API_VERSION
+}
+
+func (self *SHost) GetIHostNics() ([]cloudprovider.ICloudHostNetInterface, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SHost) CreateVM(desc *cloudprovider.SManagedVMCreateConfig) (cloudprovider.ICloudVM, error) {
+	if err := self.client.cloud.call("CreateVM"); err != nil {
+		return nil, err
+	}
+	zone, err := self.getZone()
+	if err != nil {
+		return nil, err
+	}
+	region, err := zone.getRegion()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := self.client.get(kindImage, desc.ExternalImageId)
+	if err != nil {
+		return nil, fmt.Errorf("image %s not found", desc.ExternalImageId)
+	}
+	image := res.(*SImage)
+
+	instance := &SInstance{
+		SResourceBase: SResourceBase{Name: desc.Name, Status: api.VM_RUNNING},
+		RegionId:      region.Id,
+		ZoneId:        zone.Id,
+		HostId:        self.Id,
+		ImageId:       image.Id,
+		OsType:        image.OsType,
+		OsName:        image.Name,
+		Cpu:           desc.Cpu,
+		MemoryMB:      desc.MemoryMB,
+		UserData:      desc.UserData,
+	}
+	if len(desc.InstanceType) > 0 {
+		sku, err := region.getSkuByName(desc.InstanceType)
+		if err != nil {
+			return nil, fmt.Errorf("instance type %s not found", desc.InstanceType)
+		}
+		instance.InstanceType = sku.Name
+		instance.Cpu = sku.CpuCoreCount
+		instance.MemoryMB = sku.MemorySizeMB
+	}
+	if len(desc.ExternalSecgroupIds) > 0 {
+		instance.SecgroupIds = append([]string{}, desc.ExternalSecgroupIds...)
+	} else if len(desc.ExternalSecgroupId) > 0 {
+		instance.SecgroupIds = []string{desc.ExternalSecgroupId}
+	}
+
+	cloud := self.client.cloud
+	err = cloud.transact(func() error {
+		instance.Id = cloud.newId("i")
+		nic, err := cloud.allocNic(instance.Id, desc.ExternalNetworkId, desc.IpAddr)
+		if err != nil {
+			return err
+		}
+		instance.Nics = []SInstanceNic{*nic}
+
+		disks := []*SDisk{}
+		for i, info := range append([]cloudprovider.SDiskInfo{desc.SysDisk}, desc.DataDisks...) {
+			storage, err := cloud.findStorage(zone.Id, info.StorageType)
+			if err != nil {
+				return err
+			}
+			disk := &SDisk{
+				SResourceBase: SResourceBase{Id: cloud.newId("d"), Name: info.Name, Status: api.DISK_READY},
+				RegionId:      region.Id,
+				StorageId:     storage.Id,
+				SizeMB:        info.SizeGB * 1024,
+				DiskType:      api.DISK_TYPE_DATA,
+				InstanceId:    instance.Id,
+				AutoDelete:    true,
+			}
+			if i == 0 {
+				disk.DiskType = api.DISK_TYPE_SYS
+				disk.TemplateId = image.Id
+				if disk.SizeMB < image.SizeGB*1024 {
+					disk.SizeMB = image.SizeGB * 1024
+				}
+			}
+			if len(disk.Name) == 0 {
+				disk.Name = fmt.Sprintf("%s-%s-%d", desc.Name, disk.DiskType, i)
+			}
+			disks = append(disks, disk)
+		}
+		for _, disk := range disks {
+			cloud.add(kindDisk, disk)
+			instance.DiskIds = append(instance.DiskIds, disk.Id)
+		}
+
+		// the instance is created but fails to boot
+		if cloud.consumeFailure("BootVM") {
+			instance.Status = api.VM_START_FAILED
+			instance.ErrorMsg = fmt.Sprintf("BootVM: %s", ErrInjected)
+		}
+		cloud.add(kindInstance, instance)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err = self.client.get(kindInstance, instance.Id)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SInstance), nil
+}
+
+// findStorage finds the storage of storageType in zone, the first one is
+// chosen if storageType is empty
+func (self *SMockCloud) findStorage(zoneId string, storageType string) (*SStorage, error) {
+	for _, res := range self.records(kindStorage, nil) {
+		storage := res.(*SStorage)
+		if storage.ZoneId == zoneId && (len(storageType) == 0 || storage.StorageType == storageType) {
+			return storage, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s storage in zone %s", storageType, zoneId)
+}
@@ -0,0 +1,476 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/billing"
+)
+
+type SInstanceNic struct {
+	client *SMockClient
+
+	NetworkId string
+	IpAddr    string
+	MacAddr   string
+}
+
+func (self *SInstanceNic) GetIP() string {
+	return self.IpAddr
+}
+
+func (self *SInstanceNic) GetMAC() string {
+	return self.MacAddr
+}
+
+func (self *SInstanceNic) GetDriver() string {
+	return "virtio"
+}
+
+func (self *SInstanceNic) GetINetwork() cloudprovider.ICloudNetwork {
+	res, err := self.client.get(kindNetwork, self.NetworkId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SNetwork)
+}
+
+type SInstance struct {
+	SResourceBase
+
+	RegionId     string
+	ZoneId       string
+	HostId       string
+	ImageId      string
+	OsType       string
+	OsName       string
+	InstanceType string
+	Cpu          int
+	MemoryMB     int
+	UserData     string
+
+	Nics        []SInstanceNic
+	DiskIds     []string
+	SecgroupIds []string
+	EipId       string
+
+	// ErrorMsg is set when the instance fails in background
+	ErrorMsg string
+}
+
+func (self *SInstance) Refresh() error {
+	return self.client.refresh(kindInstance, self)
+}
+
+func (self *SInstance) GetCreateTime() time.Time {
+	return self.CreatedAt
+}
+
+func (self *SInstance) GetIHost() cloudprovider.ICloudHost {
+	res, err := self.client.get(kindHost, self.HostId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SHost)
+}
+
+func (self *SInstance) GetIDisks() ([]cloudprovider.ICloudDisk, error) {
+	if err := self.client.cloud.call("GetIDisks"); err != nil {
+		return nil, err
+	}
+	idisks := []cloudprovider.ICloudDisk{}
+	for _, diskId := range self.DiskIds {
+		res, err := self.client.get(kindDisk, diskId)
+		if err != nil {
+			return nil, err
+		}
+		idisks = append(idisks, res.(*SDisk))
+	}
+	return idisks, nil
+}
+
+func (self *SInstance) GetINics() ([]cloudprovider.ICloudNic, error) {
+	inics := make([]cloudprovider.ICloudNic, len(self.Nics))
+	for i := range self.Nics {
+		nic := self.Nics[i]
+		nic.client = self.client
+		inics[i] = &nic
+	}
+	return inics, nil
+}
+
+func (self *SInstance) GetIEIP() (cloudprovider.ICloudEIP, error) {
+	if len(self.EipId) == 0 {
+		return nil, nil
+	}
+	res, err := self.client.get(kindEip, self.EipId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SEip), nil
+}
+
+func (self *SInstance) GetVcpuCount() int8 {
+	return int8(self.Cpu)
+}
+
+func (self *SInstance) GetVmemSizeMB() int {
+	return self.MemoryMB
+}
+
+func (self *SInstance) GetBootOrder() string {
+	return "dcn"
+}
+
+func (self *SInstance) GetVga() string {
+	return "std"
+}
+
+func (self *SInstance) GetVdi() string {
+	return "vnc"
+}
+
+func (self *SInstance) GetOSType() string {
+	return self.OsType
+}
+
+func (self *SInstance) GetOSName() string {
+	return self.OsName
+}
+
+func (self *SInstance) GetBios() string {
+	return "BIOS"
+}
+
+func (self *SInstance) GetMachine() string {
+	return "pc"
+}
+
+func (self *SInstance) GetInstanceType() string {
+	return self.InstanceType
+}
+
+func (self *SInstance) GetHypervisor() string {
+	return api.HYPERVISOR_MOCK
+}
+
+func (self *SInstance) GetSecurityGroupIds() ([]string, error) {
+	return self.SecgroupIds, nil
+}
+
+func (self *SInstance) AssignSecurityGroup(secgroupId string) error {
+	if err := self.client.cloud.call("AssignSecurityGroup"); err != nil {
+		return err
+	}
+	return self.update(func(instance *SInstance) error {
+		if !utils.IsInStringArray(secgroupId, instance.SecgroupIds) {
+			instance.SecgroupIds = append(append([]string{}, instance.SecgroupIds...), secgroupId)
+		}
+		return nil
+	})
+}
+
+func (self *SInstance) SetSecurityGroups(secgroupIds []string) error {
+	if err := self.client.cloud.call("SetSecurityGroups"); err != nil {
+		return err
+	}
+	return self.update(func(instance *SInstance) error {
+		instance.SecgroupIds = append([]string{}, secgroupIds...)
+		return nil
+	})
+}
+
+// update modifies the record of the instance and refreshes self
+func (self *SInstance) update(fn func(instance *SInstance) error) error {
+	err := self.client.cloud.update(kindInstance, self.Id, func(res iMockResource) error {
+		return fn(res.(*SInstance))
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SInstance) setStatus(status string) error {
+	return self.update(func(instance *SInstance) error {
+		instance.Status = status
+		instance.ErrorMsg = ""
+		return nil
+	})
+}
+
+func (self *SInstance) StartVM(ctx context.Context) error {
+	if err := self.client.cloud.call("StartVM"); err != nil {
+		return err
+	}
+	return self.setStatus(api.VM_RUNNING)
+}
+
+func (self *SInstance) StopVM(ctx context.Context, isForce bool) error {
+	if err := self.client.cloud.call("StopVM"); err != nil {
+		return err
+	}
+	return self.setStatus(api.VM_READY)
+}
+
+// DeleteVM removes the instance with its auto delete disks, the other disks
+// and the eip are detached
+func (self *SInstance) DeleteVM(ctx context.Context) error {
+	if err := self.client.cloud.call("DeleteVM"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	return cloud.transact(func() error {
+		res, err := cloud.record(kindInstance, self.Id)
+		if err != nil {
+			return err
+		}
+		instance := res.(*SInstance)
+		for _, diskId := range instance.DiskIds {
+			res, err := cloud.record(kindDisk, diskId)
+			if err != nil {
+				continue
+			}
+			disk := res.(*SDisk)
+			if disk.AutoDelete {
+				cloud.remove(kindDisk, diskId)
+			} else {
+				disk.InstanceId = ""
+				cloud.touch(kindDisk, diskId)
+			}
+		}
+		if res, err := cloud.record(kindEip, instance.EipId); err == nil {
+			res.(*SEip).InstanceId = ""
+			cloud.touch(kindEip, instance.EipId)
+		}
+		cloud.remove(kindInstance, self.Id)
+		return nil
+	})
+}
+
+func (self *SInstance) UpdateVM(ctx context.Context, name string) error {
+	if err := self.client.cloud.call("UpdateVM"); err != nil {
+		return err
+	}
+	return self.update(func(instance *SInstance) error {
+		instance.Name = name
+		return nil
+	})
+}
+
+func (self *SInstance) UpdateUserData(userData string) error {
+	return self.update(func(instance *SInstance) error {
+		instance.UserData = userData
+		return nil
+	})
+}
+
+func (self *SInstance) DeployVM(ctx context.Context, name string, password string, publicKey string, deleteKeypair bool, description string) error {
+	if err := self.client.cloud.call("DeployVM"); err != nil {
+		return err
+	}
+	if len(name) == 0 {
+		return nil
+	}
+	return self.UpdateVM(ctx, name)
+}
+
+// RebuildRoot replaces the system disk with a new one made from imageId
+func (self *SInstance) RebuildRoot(ctx context.Context, imageId string, passwd string, publicKey string, sysSizeGB int) (string, error) {
+	if err := self.client.cloud.call("RebuildRoot"); err != nil {
+		return "", err
+	}
+	cloud := self.client.cloud
+	diskId := ""
+	err := cloud.transact(func() error {
+		res, err := cloud.record(kindInstance, self.Id)
+		if err != nil {
+			return err
+		}
+		instance := res.(*SInstance)
+		if len(imageId) == 0 {
+			imageId = instance.ImageId
+		}
+		res, err = cloud.record(kindImage, imageId)
+		if err != nil {
+			return fmt.Errorf("image %s not found", imageId)
+		}
+		image := res.(*SImage)
+		if len(instance.DiskIds) == 0 {
+			return fmt.Errorf("instance %s has no system disk", self.Id)
+		}
+		res, err = cloud.record(kindDisk, instance.DiskIds[0])
+		if err != nil {
+			return err
+		}
+		disk := *res.(*SDisk)
+		cloud.remove(kindDisk, disk.Id)
+
+		disk.Id = cloud.newId("d")
+		disk.CreatedAt = time.Time{}
+		disk.TemplateId = image.Id
+		if sysSizeGB > 0 {
+			disk.SizeMB = sysSizeGB * 1024
+		}
+		if disk.SizeMB < image.SizeGB*1024 {
+			disk.SizeMB = image.SizeGB * 1024
+		}
+		cloud.add(kindDisk, &disk)
+
+		instance.DiskIds = append([]string{disk.Id}, instance.DiskIds[1:]...)
+		instance.ImageId = image.Id
+		instance.OsType = image.OsType
+		instance.OsName = image.Name
+		diskId = disk.Id
+		cloud.touch(kindInstance, instance.Id)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return diskId, self.Refresh()
+}
+
+func (self *SInstance) ChangeConfig(ctx context.Context, ncpu int, vmem int) error {
+	if err := self.client.cloud.call("ChangeConfig"); err != nil {
+		return err
+	}
+	return self.update(func(instance *SInstance) error {
+		instance.Cpu = ncpu
+		instance.MemoryMB = vmem
+		instance.InstanceType = ""
+		return nil
+	})
+}
+
+func (self *SInstance) ChangeConfig2(ctx context.Context, instanceType string) error {
+	if err := self.client.cloud.call("ChangeConfig"); err != nil {
+		return err
+	}
+	res, err := self.client.get(kindRegion, self.RegionId)
+	if err != nil {
+		return err
+	}
+	sku, err := res.(*SRegion).getSkuByName(instanceType)
+	if err != nil {
+		return fmt.Errorf("instance type %s not found", instanceType)
+	}
+	return self.update(func(instance *SInstance) error {
+		instance.Cpu = sku.CpuCoreCount
+		instance.MemoryMB = sku.MemorySizeMB
+		instance.InstanceType = sku.Name
+		return nil
+	})
+}
+
+func (self *SInstance) GetVNCInfo() (jsonutils.JSONObject, error) {
+	ret := jsonutils.NewDict()
+	ret.Add(jsonutils.NewString(fmt.Sprintf("mock://vnc/%s", self.Id)), "url")
+	ret.Add(jsonutils.NewString("mock"), "protocol")
+	ret.Add(jsonutils.NewString(self.Id), "instance_id")
+	return ret, nil
+}
+
+func (self *SInstance) AttachDisk(ctx context.Context, diskId string) error {
+	if err := self.client.cloud.call("AttachDisk"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	err := cloud.transact(func() error {
+		res, err := cloud.record(kindInstance, self.Id)
+		if err != nil {
+			return err
+		}
+		instance := res.(*SInstance)
+		res, err = cloud.record(kindDisk, diskId)
+		if err != nil {
+			return err
+		}
+		disk := res.(*SDisk)
+		if len(disk.InstanceId) > 0 {
+			return fmt.Errorf("disk %s is attached to %s", diskId, disk.InstanceId)
+		}
+		storage, err := cloud.record(kindStorage, disk.StorageId)
+		if err != nil {
+			return err
+		}
+		if storage.(*SStorage).ZoneId != instance.ZoneId {
+			return fmt.Errorf("disk %s is not in zone %s", diskId, instance.ZoneId)
+		}
+		disk.InstanceId = instance.Id
+		instance.DiskIds = append(append([]string{}, instance.DiskIds...), diskId)
+		cloud.touch(kindDisk, diskId)
+		cloud.touch(kindInstance, instance.Id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SInstance) DetachDisk(ctx context.Context, diskId string) error {
+	if err := self.client.cloud.call("DetachDisk"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	err := cloud.transact(func() error {
+		res, err := cloud.record(kindInstance, self.Id)
+		if err != nil {
+			return err
+		}
+		instance := res.(*SInstance)
+		diskIds := []string{}
+		for _, id := range instance.DiskIds {
+			if id != diskId {
+				diskIds = append(diskIds, id)
+			}
+		}
+		instance.DiskIds = diskIds
+		if res, err := cloud.record(kindDisk, diskId); err == nil {
+			res.(*SDisk).InstanceId = ""
+			cloud.touch(kindDisk, diskId)
+		}
+		cloud.touch(kindInstance, instance.Id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SInstance) CreateDisk(ctx context.Context, sizeMb int, uuid string, driver string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) Renew(bc billing.SBillingCycle) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SInstance) GetError() error {
+	if len(self.ErrorMsg) > 0 {
+		return fmt.Errorf("%s", self.ErrorMsg)
+	}
+	return nil
+}
@@ -0,0 +1,650 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SLoadbalancer struct {
+	SResourceBase
+
+	RegionId    string
+	ZoneId      string
+	VpcId       string
+	NetworkId   string
+	Address     string
+	AddressType string
+	Spec        string
+	ChargeType  string
+}
+
+func (self *SRegion) GetILoadBalancers() ([]cloudprovider.ICloudLoadbalancer, error) {
+	if err := self.client.cloud.call("GetILoadBalancers"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindLoadbalancer, func(res iMockResource) bool {
+		return res.(*SLoadbalancer).RegionId == self.Id
+	})
+	ilbs := make([]cloudprovider.ICloudLoadbalancer, len(records))
+	for i := range records {
+		ilbs[i] = records[i].(*SLoadbalancer)
+	}
+	return ilbs, nil
+}
+
+func (self *SRegion) GetILoadBalancerById(loadbalancerId string) (cloudprovider.ICloudLoadbalancer, error) {
+	res, err := self.client.get(kindLoadbalancer, loadbalancerId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SLoadbalancer), nil
+}
+
+func (self *SRegion) CreateILoadBalancer(loadbalancer *cloudprovider.SLoadbalancer) (cloudprovider.ICloudLoadbalancer, error) {
+	if err := self.client.cloud.call("CreateILoadBalancer"); err != nil {
+		return nil, err
+	}
+	lb := &SLoadbalancer{
+		SResourceBase: SResourceBase{Name: loadbalancer.Name, Status: api.LB_STATUS_ENABLED},
+		RegionId:      self.Id,
+		ZoneId:        loadbalancer.ZoneID,
+		VpcId:         loadbalancer.VpcID,
+		NetworkId:     loadbalancer.NetworkID,
+		AddressType:   loadbalancer.AddressType,
+		Spec:          loadbalancer.LoadbalancerSpec,
+		ChargeType:    loadbalancer.ChargeType,
+	}
+	cloud := self.client.cloud
+	err := cloud.transact(func() error {
+		if lb.AddressType == api.LB_ADDR_TYPE_INTERNET {
+			lb.Address = cloud.allocEipAddr()
+			return nil
+		}
+		addr, err := cloud.allocAddr(lb.NetworkId, loadbalancer.Address)
+		if err != nil {
+			return err
+		}
+		lb.Address = addr
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return self.client.create(kindLoadbalancer, "lb", lb).(*SLoadbalancer), nil
+}
+
+func (self *SLoadbalancer) Refresh() error {
+	return self.client.refresh(kindLoadbalancer, self)
+}
+
+func (self *SLoadbalancer) GetAddress() string {
+	return self.Address
+}
+
+func (self *SLoadbalancer) GetAddressType() string {
+	return self.AddressType
+}
+
+func (self *SLoadbalancer) GetNetworkType() string {
+	return api.LB_NETWORK_TYPE_VPC
+}
+
+func (self *SLoadbalancer) GetNetworkId() string {
+	return self.NetworkId
+}
+
+func (self *SLoadbalancer) GetVpcId() string {
+	return self.VpcId
+}
+
+func (self *SLoadbalancer) GetZoneId() string {
+	return self.ZoneId
+}
+
+func (self *SLoadbalancer) GetLoadbalancerSpec() string {
+	return self.Spec
+}
+
+func (self *SLoadbalancer) GetChargeType() string {
+	return self.ChargeType
+}
+
+// Delete removes the loadbalancer with all its listeners and backendgroups
+func (self *SLoadbalancer) Delete() error {
+	if err := self.client.cloud.call("DeleteLoadbalancer"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	return cloud.transact(func() error {
+		for _, res := range cloud.records(kindListener, func(res iMockResource) bool {
+			return res.(*SLoadbalancerListener).LoadbalancerId == self.Id
+		}) {
+			cloud.removeListener(res.getBase().Id)
+		}
+		for _, res := range cloud.records(kindBackendGroup, func(res iMockResource) bool {
+			return res.(*SLoadbalancerBackendGroup).LoadbalancerId == self.Id
+		}) {
+			cloud.removeBackendGroup(res.getBase().Id)
+		}
+		cloud.remove(kindLoadbalancer, self.Id)
+		return nil
+	})
+}
+
+func (self *SLoadbalancer) setStatus(status string) error {
+	err := self.client.cloud.update(kindLoadbalancer, self.Id, func(res iMockResource) error {
+		res.getBase().Status = status
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SLoadbalancer) Start() error {
+	if err := self.client.cloud.call("StartLoadbalancer"); err != nil {
+		return err
+	}
+	return self.setStatus(api.LB_STATUS_ENABLED)
+}
+
+func (self *SLoadbalancer) Stop() error {
+	if err := self.client.cloud.call("StopLoadbalancer"); err != nil {
+		return err
+	}
+	return self.setStatus(api.LB_STATUS_DISABLED)
+}
+
+func (self *SLoadbalancer) GetILoadBalancerListeners() ([]cloudprovider.ICloudLoadbalancerListener, error) {
+	if err := self.client.cloud.call("GetILoadBalancerListeners"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindListener, func(res iMockResource) bool {
+		return res.(*SLoadbalancerListener).LoadbalancerId == self.Id
+	})
+	ilisteners := make([]cloudprovider.ICloudLoadbalancerListener, len(records))
+	for i := range records {
+		ilisteners[i] = records[i].(*SLoadbalancerListener)
+	}
+	return ilisteners, nil
+}
+
+func (self *SLoadbalancer) GetILoadBalancerListenerById(listenerId string) (cloudprovider.ICloudLoadbalancerListener, error) {
+	res, err := self.client.get(kindListener, listenerId)
+	if err != nil {
+		return nil, err
+	}
+	listener := res.(*SLoadbalancerListener)
+	if listener.LoadbalancerId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return listener, nil
+}
+
+func (self *SLoadbalancer) CreateILoadBalancerListener(listener *cloudprovider.SLoadbalancerListener) (cloudprovider.ICloudLoadbalancerListener, error) {
+	if err := self.client.cloud.call("CreateILoadBalancerListener"); err != nil {
+		return nil, err
+	}
+	conflicts := self.client.list(kindListener, func(res iMockResource) bool {
+		l := res.(*SLoadbalancerListener)
+		return l.LoadbalancerId == self.Id && l.Config.ListenerPort == listener.ListenerPort
+	})
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("port %d of loadbalancer %s is in use", listener.ListenerPort, self.Id)
+	}
+	ret := self.client.create(kindListener, "lsn", &SLoadbalancerListener{
+		SResourceBase:  SResourceBase{Name: listener.Name, Status: api.LB_STATUS_ENABLED},
+		LoadbalancerId: self.Id,
+		Config:         *listener,
+	})
+	return ret.(*SLoadbalancerListener), nil
+}
+
+func (self *SLoadbalancer) GetILoadBalancerBackendGroups() ([]cloudprovider.ICloudLoadbalancerBackendGroup, error) {
+	if err := self.client.cloud.call("GetILoadBalancerBackendGroups"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindBackendGroup, func(res iMockResource) bool {
+		return res.(*SLoadbalancerBackendGroup).LoadbalancerId == self.Id
+	})
+	igroups := make([]cloudprovider.ICloudLoadbalancerBackendGroup, len(records))
+	for i := range records {
+		igroups[i] = records[i].(*SLoadbalancerBackendGroup)
+	}
+	return igroups, nil
+}
+
+func (self *SLoadbalancer) GetILoadBalancerBackendGroupById(groupId string) (cloudprovider.ICloudLoadbalancerBackendGroup, error) {
+	res, err := self.client.get(kindBackendGroup, groupId)
+	if err != nil {
+		return nil, err
+	}
+	group := res.(*SLoadbalancerBackendGroup)
+	if group.LoadbalancerId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return group, nil
+}
+
+func (self *SLoadbalancer) CreateILoadBalancerBackendGroup(group *cloudprovider.SLoadbalancerBackendGroup) (cloudprovider.ICloudLoadbalancerBackendGroup, error) {
+	if err := self.client.cloud.call("CreateILoadBalancerBackendGroup"); err != nil {
+		return nil, err
+	}
+	ret := self.client.create(kindBackendGroup, "lbbg", &SLoadbalancerBackendGroup{
+		SResourceBase:  SResourceBase{Name: group.Name, Status: api.LB_STATUS_ENABLED},
+		LoadbalancerId: self.Id,
+		GroupType:      group.GroupType,
+	}).(*SLoadbalancerBackendGroup)
+	for _, backend := range group.Backends {
+		self.client.create(kindBackend, "lbb", &SLoadbalancerBackend{
+			SResourceBase:  SResourceBase{Name: backend.Name, Status: api.LB_STATUS_ENABLED},
+			BackendGroupId: ret.Id,
+			ServerId:       backend.ExternalID,
+			Weight:         backend.Weight,
+			Port:           backend.Port,
+			BackendType:    backend.BackendType,
+			BackendRole:    backend.BackendRole,
+		})
+	}
+	return ret, nil
+}
+
+type SLoadbalancerListener struct {
+	SResourceBase
+
+	LoadbalancerId string
+	Config         cloudprovider.SLoadbalancerListener
+}
+
+func (self *SMockCloud) removeListener(listenerId string) {
+	for _, res := range self.records(kindListenerRule, func(res iMockResource) bool {
+		return res.(*SLoadbalancerListenerRule).ListenerId == listenerId
+	}) {
+		self.remove(kindListenerRule, res.getBase().Id)
+	}
+	self.remove(kindListener, listenerId)
+}
+
+func (self *SLoadbalancerListener) Refresh() error {
+	return self.client.refresh(kindListener, self)
+}
+
+func (self *SLoadbalancerListener) GetListenerType() string {
+	return self.Config.ListenerType
+}
+
+func (self *SLoadbalancerListener) GetListenerPort() int {
+	return self.Config.ListenerPort
+}
+
+func (self *SLoadbalancerListener) GetScheduler() string {
+	return self.Config.Scheduler
+}
+
+func (self *SLoadbalancerListener) GetAclStatus() string {
+	return self.Config.AccessControlListStatus
+}
+
+func (self *SLoadbalancerListener) GetAclType() string {
+	return self.Config.AccessControlListType
+}
+
+func (self *SLoadbalancerListener) GetAclId() string {
+	return self.Config.AccessControlListID
+}
+
+func (self *SLoadbalancerListener) GetHealthCheck() string {
+	return self.Config.HealthCheck
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckType() string {
+	if self.Config.ListenerType == api.LB_LISTENER_TYPE_HTTPS {
+		return api.LB_HEALTH_CHECK_HTTP
+	}
+	return self.Config.ListenerType
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckTimeout() int {
+	return self.Config.HealthCheckTimeout
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckInterval() int {
+	return self.Config.HealthCheckInterval
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckRise() int {
+	return self.Config.HealthCheckRise
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckFail() int {
+	return self.Config.HealthCheckFail
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckReq() string {
+	return ""
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckExp() string {
+	return ""
+}
+
+func (self *SLoadbalancerListener) GetBackendGroupId() string {
+	return self.Config.BackendGroupID
+}
+
+func (self *SLoadbalancerListener) GetBackendServerPort() int {
+	return self.Config.BackendServerPort
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckDomain() string {
+	return self.Config.HealthCheckDomain
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckURI() string {
+	return self.Config.HealthCheckURI
+}
+
+func (self *SLoadbalancerListener) GetHealthCheckCode() string {
+	return self.Config.HealthCheckHttpCode
+}
+
+func (self *SLoadbalancerListener) GetStickySession() string {
+	return self.Config.StickySession
+}
+
+func (self *SLoadbalancerListener) GetStickySessionType() string {
+	return self.Config.StickySessionType
+}
+
+func (self *SLoadbalancerListener) GetStickySessionCookie() string {
+	return self.Config.StickySessionCookie
+}
+
+func (self *SLoadbalancerListener) GetStickySessionCookieTimeout() int {
+	return self.Config.StickySessionCookieTimeout
+}
+
+func (self *SLoadbalancerListener) XForwardedForEnabled() bool {
+	return self.Config.XForwardedFor
+}
+
+func (self *SLoadbalancerListener) GzipEnabled() bool {
+	return self.Config.Gzip
+}
+
+func (self *SLoadbalancerListener) GetCertificateId() string {
+	return self.Config.CertificateID
+}
+
+func (self *SLoadbalancerListener) GetTLSCipherPolicy() string {
+	return self.Config.TLSCipherPolicy
+}
+
+func (self *SLoadbalancerListener) HTTP2Enabled() bool {
+	return self.Config.EnableHTTP2
+}
+
+func (self *SLoadbalancerListener) update(fn func(listener *SLoadbalancerListener)) error {
+	err := self.client.cloud.update(kindListener, self.Id, func(res iMockResource) error {
+		fn(res.(*SLoadbalancerListener))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SLoadbalancerListener) Start() error {
+	if err := self.client.cloud.call("StartListener"); err != nil {
+		return err
+	}
+	return self.update(func(listener *SLoadbalancerListener) {
+		listener.Status = api.LB_STATUS_ENABLED
+	})
+}
+
+func (self *SLoadbalancerListener) Stop() error {
+	if err := self.client.cloud.call("StopListener"); err != nil {
+		return err
+	}
+	return self.update(func(listener *SLoadbalancerListener) {
+		listener.Status = api.LB_STATUS_DISABLED
+	})
+}
+
+func (self *SLoadbalancerListener) Sync(config *cloudprovider.SLoadbalancerListener) error {
+	if err := self.client.cloud.call("SyncListener"); err != nil {
+		return err
+	}
+	return self.update(func(listener *SLoadbalancerListener) {
+		listener.Name = config.Name
+		listener.Config = *config
+	})
+}
+
+func (self *SLoadbalancerListener) Delete() error {
+	if err := self.client.cloud.call("DeleteListener"); err != nil {
+		return err
+	}
+	return self.client.cloud.transact(func() error {
+		self.client.cloud.removeListener(self.Id)
+		return nil
+	})
+}
+
+func (self *SLoadbalancerListener) GetILoadbalancerListenerRules() ([]cloudprovider.ICloudLoadbalancerListenerRule, error) {
+	records := self.client.list(kindListenerRule, func(res iMockResource) bool {
+		return res.(*SLoadbalancerListenerRule).ListenerId == self.Id
+	})
+	irules := make([]cloudprovider.ICloudLoadbalancerListenerRule, len(records))
+	for i := range records {
+		irules[i] = records[i].(*SLoadbalancerListenerRule)
+	}
+	return irules, nil
+}
+
+func (self *SLoadbalancerListener) GetILoadBalancerListenerRuleById(ruleId string) (cloudprovider.ICloudLoadbalancerListenerRule, error) {
+	res, err := self.client.get(kindListenerRule, ruleId)
+	if err != nil {
+		return nil, err
+	}
+	rule := res.(*SLoadbalancerListenerRule)
+	if rule.ListenerId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return rule, nil
+}
+
+func (self *SLoadbalancerListener) CreateILoadBalancerListenerRule(rule *cloudprovider.SLoadbalancerListenerRule) (cloudprovider.ICloudLoadbalancerListenerRule, error) {
+	if err := self.client.cloud.call("CreateILoadBalancerListenerRule"); err != nil {
+		return nil, err
+	}
+	ret := self.client.create(kindListenerRule, "rule", &SLoadbalancerListenerRule{
+		SResourceBase:  SResourceBase{Name: rule.Name, Status: api.LB_STATUS_ENABLED},
+		ListenerId:     self.Id,
+		Domain:         rule.Domain,
+		Path:           rule.Path,
+		BackendGroupId: rule.BackendGroupID,
+	})
+	return ret.(*SLoadbalancerListenerRule), nil
+}
+
+type SLoadbalancerListenerRule struct {
+	SResourceBase
+
+	ListenerId     string
+	Domain         string
+	Path           string
+	BackendGroupId string
+}
+
+func (self *SLoadbalancerListenerRule) Refresh() error {
+	return self.client.refresh(kindListenerRule, self)
+}
+
+func (self *SLoadbalancerListenerRule) GetDomain() string {
+	return self.Domain
+}
+
+func (self *SLoadbalancerListenerRule) GetPath() string {
+	return self.Path
+}
+
+func (self *SLoadbalancerListenerRule) GetBackendGroupId() string {
+	return self.BackendGroupId
+}
+
+func (self *SLoadbalancerListenerRule) Delete() error {
+	if err := self.client.cloud.call("DeleteListenerRule"); err != nil {
+		return err
+	}
+	return self.client.cloud.transact(func() error {
+		self.client.cloud.remove(kindListenerRule, self.Id)
+		return nil
+	})
+}
+
+type SLoadbalancerBackendGroup struct {
+	SResourceBase
+
+	LoadbalancerId string
+	GroupType      string
+}
+
+func (self *SMockCloud) removeBackendGroup(groupId string) {
+	for _, res := range self.records(kindBackend, func(res iMockResource) bool {
+		return res.(*SLoadbalancerBackend).BackendGroupId == groupId
+	}) {
+		self.remove(kindBackend, res.getBase().Id)
+	}
+	self.remove(kindBackendGroup, groupId)
+}
+
+func (self *SLoadbalancerBackendGroup) Refresh() error {
+	return self.client.refresh(kindBackendGroup, self)
+}
+
+func (self *SLoadbalancerBackendGroup) IsDefault() bool {
+	return self.GroupType == api.LB_BACKENDGROUP_TYPE_DEFAULT
+}
+
+func (self *SLoadbalancerBackendGroup) GetType() string {
+	return self.GroupType
+}
+
+func (self *SLoadbalancerBackendGroup) GetILoadbalancerBackends() ([]cloudprovider.ICloudLoadbalancerBackend, error) {
+	records := self.client.list(kindBackend, func(res iMockResource) bool {
+		return res.(*SLoadbalancerBackend).BackendGroupId == self.Id
+	})
+	ibackends := make([]cloudprovider.ICloudLoadbalancerBackend, len(records))
+	for i := range records {
+		ibackends[i] = records[i].(*SLoadbalancerBackend)
+	}
+	return ibackends, nil
+}
+
+func (self *SLoadbalancerBackendGroup) AddBackendServer(serverId string, weight int, port int) (cloudprovider.ICloudLoadbalancerBackend, error) {
+	if err := self.client.cloud.call("AddBackendServer"); err != nil {
+		return nil, err
+	}
+	ret := self.client.create(kindBackend, "lbb", &SLoadbalancerBackend{
+		SResourceBase:  SResourceBase{Name: serverId, Status: api.LB_STATUS_ENABLED},
+		BackendGroupId: self.Id,
+		ServerId:       serverId,
+		Weight:         weight,
+		Port:           port,
+		BackendType:    api.LB_BACKEND_GUEST,
+		BackendRole:    api.LB_BACKEND_ROLE_DEFAULT,
+	})
+	return ret.(*SLoadbalancerBackend), nil
+}
+
+func (self *SLoadbalancerBackendGroup) RemoveBackendServer(serverId string, weight int, port int) error {
+	if err := self.client.cloud.call("RemoveBackendServer"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	return cloud.transact(func() error {
+		for _, res := range cloud.records(kindBackend, func(res iMockResource) bool {
+			backend := res.(*SLoadbalancerBackend)
+			return backend.BackendGroupId == self.Id && backend.ServerId == serverId && backend.Port == port
+		}) {
+			cloud.remove(kindBackend, res.getBase().Id)
+		}
+		return nil
+	})
+}
+
+func (self *SLoadbalancerBackendGroup) Delete() error {
+	if err := self.client.cloud.call("DeleteBackendGroup"); err != nil {
+		return err
+	}
+	return self.client.cloud.transact(func() error {
+		self.client.cloud.removeBackendGroup(self.Id)
+		return nil
+	})
+}
+
+func (self *SLoadbalancerBackendGroup) Sync(name string) error {
+	err := self.client.cloud.update(kindBackendGroup, self.Id, func(res iMockResource) error {
+		res.getBase().Name = name
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+type SLoadbalancerBackend struct {
+	SResourceBase
+
+	BackendGroupId string
+	ServerId       string
+	Weight         int
+	Port           int
+	BackendType    string
+	BackendRole    string
+}
+
+func (self *SLoadbalancerBackend) Refresh() error {
+	return self.client.refresh(kindBackend, self)
+}
+
+func (self *SLoadbalancerBackend) GetWeight() int {
+	return self.Weight
+}
+
+func (self *SLoadbalancerBackend) GetPort() int {
+	return self.Port
+}
+
+func (self *SLoadbalancerBackend) GetBackendType() string {
+	return self.BackendType
+}
+
+func (self *SLoadbalancerBackend) GetBackendRole() string {
+	return self.BackendRole
+}
+
+func (self *SLoadbalancerBackend) GetBackendId() string {
+	return self.ServerId
+}
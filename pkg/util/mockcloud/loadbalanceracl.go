@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SLoadbalancerAcl struct {
+	SResourceBase
+
+	RegionId string
+	Entries  []cloudprovider.SLoadbalancerAccessControlListEntry
+}
+
+func (self *SRegion) GetILoadBalancerAcls() ([]cloudprovider.ICloudLoadbalancerAcl, error) {
+	if err := self.client.cloud.call("GetILoadBalancerAcls"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindAcl, func(res iMockResource) bool {
+		return res.(*SLoadbalancerAcl).RegionId == self.Id
+	})
+	iacls := make([]cloudprovider.ICloudLoadbalancerAcl, len(records))
+	for i := range records {
+		iacls[i] = records[i].(*SLoadbalancerAcl)
+	}
+	return iacls, nil
+}
+
+func (self *SRegion) GetILoadBalancerAclById(aclId string) (cloudprovider.ICloudLoadbalancerAcl, error) {
+	res, err := self.client.get(kindAcl, aclId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SLoadbalancerAcl), nil
+}
+
+func (self *SRegion) CreateILoadBalancerAcl(acl *cloudprovider.SLoadbalancerAccessControlList) (cloudprovider.ICloudLoadbalancerAcl, error) {
+	if err := self.client.cloud.call("CreateILoadBalancerAcl"); err != nil {
+		return nil, err
+	}
+	ret := self.client.create(kindAcl, "acl", &SLoadbalancerAcl{
+		SResourceBase: SResourceBase{Name: acl.Name, Status: api.LB_STATUS_ENABLED},
+		RegionId:      self.Id,
+		Entries:       append([]cloudprovider.SLoadbalancerAccessControlListEntry{}, acl.Entrys...),
+	})
+	return ret.(*SLoadbalancerAcl), nil
+}
+
+func (self *SLoadbalancerAcl) Refresh() error {
+	return self.client.refresh(kindAcl, self)
+}
+
+func (self *SLoadbalancerAcl) GetAclEntries() []cloudprovider.SLoadbalancerAccessControlListEntry {
+	return append([]cloudprovider.SLoadbalancerAccessControlListEntry{}, self.Entries...)
+}
+
+func (self *SLoadbalancerAcl) Sync(acl *cloudprovider.SLoadbalancerAccessControlList) error {
+	if err := self.client.cloud.call("SyncAcl"); err != nil {
+		return err
+	}
+	err := self.client.cloud.update(kindAcl, self.Id, func(res iMockResource) error {
+		res.(*SLoadbalancerAcl).Entries = append([]cloudprovider.SLoadbalancerAccessControlListEntry{}, acl.Entrys...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SLoadbalancerAcl) Delete() error {
+	if err := self.client.cloud.call("DeleteAcl"); err != nil {
+		return err
+	}
+	return self.client.cloud.transact(func() error {
+		self.client.cloud.remove(kindAcl, self.Id)
+		return nil
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SLoadbalancerCertificate struct {
+	SResourceBase
+
+	RegionId                string
+	Certificate             string
+	PrivateKey              string
+	CommonName              string
+	SubjectAlternativeNames string
+	Fingerprint             string
+	ExpireTime              time.Time
+}
+
+func (self *SRegion) GetILoadBalancerCertificates() ([]cloudprovider.ICloudLoadbalancerCertificate, error) {
+	if err := self.client.cloud.call("GetILoadBalancerCertificates"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindCertificate, func(res iMockResource) bool {
+		return res.(*SLoadbalancerCertificate).RegionId == self.Id
+	})
+	icerts := make([]cloudprovider.ICloudLoadbalancerCertificate, len(records))
+	for i := range records {
+		icerts[i] = records[i].(*SLoadbalancerCertificate)
+	}
+	return icerts, nil
+}
+
+func (self *SRegion) GetILoadBalancerCertificateById(certId string) (cloudprovider.ICloudLoadbalancerCertificate, error) {
+	res, err := self.client.get(kindCertificate, certId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SLoadbalancerCertificate), nil
+}
+
+func (self *SRegion) CreateILoadBalancerCertificate(cert *cloudprovider.SLoadbalancerCertificate) (cloudprovider.ICloudLoadbalancerCertificate, error) {
+	if err := self.client.cloud.call("CreateILoadBalancerCertificate"); err != nil {
+		return nil, err
+	}
+	ret := &SLoadbalancerCertificate{
+		SResourceBase: SResourceBase{Name: cert.Name, Status: api.LB_STATUS_ENABLED},
+		RegionId:      self.Id,
+	}
+	ret.setCertificate(cert.Certificate, cert.PrivateKey)
+	return self.client.create(kindCertificate, "cert", ret).(*SLoadbalancerCertificate), nil
+}
+
+// setCertificate fills the attributes parsed from the pem encoded certificate,
+// the name is used as common name if the certificate is not parsable
+func (self *SLoadbalancerCertificate) setCertificate(certificate string, privateKey string) {
+	self.Certificate = certificate
+	self.PrivateKey = privateKey
+	self.Fingerprint = fmt.Sprintf("%s:%x", api.LB_TLS_CERT_FINGERPRINT_ALGO_SHA256, sha256.Sum256([]byte(certificate)))
+	self.CommonName = self.Name
+	self.SubjectAlternativeNames = ""
+	self.ExpireTime = time.Now().UTC().AddDate(1, 0, 0)
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil {
+		return
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return
+	}
+	self.Fingerprint = fmt.Sprintf("%s:%x", api.LB_TLS_CERT_FINGERPRINT_ALGO_SHA256, sha256.Sum256(cert.Raw))
+	self.CommonName = cert.Subject.CommonName
+	self.SubjectAlternativeNames = strings.Join(cert.DNSNames, ",")
+	self.ExpireTime = cert.NotAfter
+}
+
+func (self *SLoadbalancerCertificate) Refresh() error {
+	return self.client.refresh(kindCertificate, self)
+}
+
+func (self *SLoadbalancerCertificate) GetCommonName() string {
+	return self.CommonName
+}
+
+func (self *SLoadbalancerCertificate) GetSubjectAlternativeNames() string {
+	return self.SubjectAlternativeNames
+}
+
+func (self *SLoadbalancerCertificate) GetFingerprint() string {
+	return self.Fingerprint
+}
+
+func (self *SLoadbalancerCertificate) GetExpireTime() time.Time {
+	return self.ExpireTime
+}
+
+func (self *SLoadbalancerCertificate) Sync(name, privateKey, publickKey string) error {
+	if err := self.client.cloud.call("SyncCertificate"); err != nil {
+		return err
+	}
+	err := self.client.cloud.update(kindCertificate, self.Id, func(res iMockResource) error {
+		cert := res.(*SLoadbalancerCertificate)
+		cert.Name = name
+		if len(publickKey) > 0 {
+			cert.setCertificate(publickKey, privateKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.Refresh()
+}
+
+func (self *SLoadbalancerCertificate) Delete() error {
+	if err := self.client.cloud.call("DeleteCertificate"); err != nil {
+		return err
+	}
+	return self.client.cloud.transact(func() error {
+		self.client.cloud.remove(kindCertificate, self.Id)
+		return nil
+	})
+}
@@ -0,0 +1,462 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	CLOUD_PROVIDER_MOCK = api.CLOUD_PROVIDER_MOCK
+
+	MOCK_API_VERSION = "v1"
+
+	MOCK_DEFAULT_REGION_COUNT = 1
+	MOCK_DEFAULT_ZONE_COUNT   = 2
+
+	// MOCK_MAX_EVENT_COUNT is how many change events are retained
+	MOCK_MAX_EVENT_COUNT = 10000
+)
+
+var ErrInjected = errors.New("mock injected failure")
+
+func IsInjectedFailure(err error) bool {
+	return err != nil && strings.HasSuffix(err.Error(), ErrInjected.Error())
+}
+
+const (
+	kindRegion       = "region"
+	kindZone         = "zone"
+	kindVpc          = "vpc"
+	kindWire         = "wire"
+	kindNetwork      = "network"
+	kindHost         = "host"
+	kindStorage      = "storage"
+	kindStoragecache = "storagecache"
+	kindImage        = "image"
+	kindInstance     = "instance"
+	kindDisk         = "disk"
+	kindSnapshot     = "snapshot"
+	kindEip          = "eip"
+	kindSecgroup     = "secgroup"
+	kindSku          = "sku"
+	kindLoadbalancer = "lb"
+	kindListener     = "listener"
+	kindListenerRule = "rule"
+	kindBackendGroup = "backendgroup"
+	kindBackend      = "backend"
+	kindAcl          = "acl"
+	kindCertificate  = "cert"
+)
+
+// SMockConfig describes the initial topology and the behavior of a mock cloud,
+// it is parsed from the access url of the cloudaccount, e.g.
+//
+//	mock://local?regions=2&zones=3&latency=200ms&fail=CreateVM:1,DeleteDisk:-1
+//
+// a failure count less than 0 means the operation always fails
+type SMockConfig struct {
+	Regions  int
+	Zones    int
+	Latency  time.Duration
+	Failures map[string]int
+}
+
+func ParseConfig(accessUrl string) (*SMockConfig, error) {
+	config := &SMockConfig{
+		Regions:  MOCK_DEFAULT_REGION_COUNT,
+		Zones:    MOCK_DEFAULT_ZONE_COUNT,
+		Failures: map[string]int{},
+	}
+	if len(accessUrl) == 0 {
+		return config, nil
+	}
+	u, err := url.Parse(accessUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access url %s: %s", accessUrl, err)
+	}
+	query := u.Query()
+	if v := query.Get("regions"); len(v) > 0 {
+		config.Regions, err = strconv.Atoi(v)
+		if err != nil || config.Regions <= 0 {
+			return nil, fmt.Errorf("invalid regions %s", v)
+		}
+	}
+	if v := query.Get("zones"); len(v) > 0 {
+		config.Zones, err = strconv.Atoi(v)
+		if err != nil || config.Zones <= 0 || config.Zones > 26 {
+			return nil, fmt.Errorf("invalid zones %s", v)
+		}
+	}
+	if v := query.Get("latency"); len(v) > 0 {
+		config.Latency, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latency %s: %s", v, err)
+		}
+	}
+	if v := query.Get("fail"); len(v) > 0 {
+		for _, f := range strings.Split(v, ",") {
+			parts := strings.SplitN(f, ":", 2)
+			count := 1
+			if len(parts) == 2 {
+				count, err = strconv.Atoi(parts[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid failure count %s", f)
+				}
+			}
+			config.Failures[parts[0]] = count
+		}
+	}
+	return config, nil
+}
+
+var (
+	cloudsLock sync.Mutex
+	clouds     = map[string]*SMockCloud{}
+)
+
+// GetMockCloud returns the in-memory cloud of the account, the cloud is created
+// from config at the first time so that its state survives across providers
+func GetMockCloud(account string, config *SMockConfig) *SMockCloud {
+	cloudsLock.Lock()
+	defer cloudsLock.Unlock()
+
+	if cloud, ok := clouds[account]; ok {
+		return cloud
+	}
+	cloud := newMockCloud(account, config)
+	clouds[account] = cloud
+	return cloud
+}
+
+// ResetMockClouds drops the state of all mock clouds
+func ResetMockClouds() {
+	cloudsLock.Lock()
+	defer cloudsLock.Unlock()
+
+	clouds = map[string]*SMockCloud{}
+}
+
+type iMockResource interface {
+	getBase() *SResourceBase
+}
+
+// cloneResource makes a shallow copy of res
+func cloneResource(res iMockResource) iMockResource {
+	v := reflect.New(reflect.TypeOf(res).Elem())
+	v.Elem().Set(reflect.ValueOf(res).Elem())
+	return v.Interface().(iMockResource)
+}
+
+// SMockCloud holds the server side state of a mock cloud. Resources handed out
+// to the clients are copies of the records, the records must be updated by
+// replacing instead of modifying their slices and maps
+type SMockCloud struct {
+	lock sync.Mutex
+
+	account string
+
+	latency  time.Duration
+	failures map[string]int
+	calls    map[string]int
+
+	seq       int
+	resources map[string]map[string]iMockResource
+
+	events []sMockEvent
+	// eventsSince is the time since which the retained events are complete
+	eventsSince time.Time
+}
+
+func newMockCloud(account string, config *SMockConfig) *SMockCloud {
+	cloud := &SMockCloud{
+		account:     account,
+		latency:     config.Latency,
+		failures:    map[string]int{},
+		calls:       map[string]int{},
+		resources:   map[string]map[string]iMockResource{},
+		eventsSince: time.Now().UTC(),
+	}
+	for op, count := range config.Failures {
+		cloud.failures[op] = count
+	}
+	cloud.populate(config)
+	return cloud
+}
+
+func (self *SMockCloud) SetLatency(latency time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.latency = latency
+}
+
+// InjectFailure makes the next count calls of op fail with ErrInjected,
+// count less than 0 makes all the calls fail
+func (self *SMockCloud) InjectFailure(op string, count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.failures[op] = count
+}
+
+func (self *SMockCloud) ClearFailures() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.failures = map[string]int{}
+}
+
+// GetCallCount returns how many times op has been called
+func (self *SMockCloud) GetCallCount(op string) int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.calls[op]
+}
+
+func (self *SMockCloud) consumeFailure(op string) bool {
+	count, ok := self.failures[op]
+	if !ok || count == 0 {
+		return false
+	}
+	if count > 0 {
+		self.failures[op] = count - 1
+	}
+	return true
+}
+
+// call simulates a remote api call of op
+func (self *SMockCloud) call(op string) error {
+	self.lock.Lock()
+	self.calls[op] += 1
+	latency := self.latency
+	failed := self.consumeFailure(op)
+	self.lock.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if failed {
+		log.Debugf("mock cloud %s: injected failure of %s", self.account, op)
+		return fmt.Errorf("%s: %s", op, ErrInjected)
+	}
+	return nil
+}
+
+func (self *SMockCloud) newId(prefix string) string {
+	self.seq += 1
+	return fmt.Sprintf("%s-%06d", prefix, self.seq)
+}
+
+func (self *SMockCloud) add(kind string, res iMockResource) {
+	if _, ok := self.resources[kind]; !ok {
+		self.resources[kind] = map[string]iMockResource{}
+	}
+	base := res.getBase()
+	if base.CreatedAt.IsZero() {
+		base.CreatedAt = time.Now().UTC()
+	}
+	self.resources[kind][base.Id] = res
+	self.emit(kind, res, cloudprovider.CLOUD_EVENT_ACTION_CREATE)
+}
+
+func (self *SMockCloud) remove(kind string, id string) {
+	if res, ok := self.resources[kind][id]; ok {
+		self.emit(kind, res, cloudprovider.CLOUD_EVENT_ACTION_DELETE)
+		delete(self.resources[kind], id)
+	}
+}
+
+func (self *SMockCloud) record(kind string, id string) (iMockResource, error) {
+	res, ok := self.resources[kind][id]
+	if !ok {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return res, nil
+}
+
+func (self *SMockCloud) records(kind string, filter func(res iMockResource) bool) []iMockResource {
+	ret := []iMockResource{}
+	for _, res := range self.resources[kind] {
+		if filter == nil || filter(res) {
+			ret = append(ret, res)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].getBase().Id < ret[j].getBase().Id
+	})
+	return ret
+}
+
+// update modifies the record of kind with id under the lock
+func (self *SMockCloud) update(kind string, id string, fn func(res iMockResource) error) error {
+	return self.transact(func() error {
+		res, err := self.record(kind, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(res); err != nil {
+			return err
+		}
+		self.emit(kind, res, cloudprovider.CLOUD_EVENT_ACTION_UPDATE)
+		return nil
+	})
+}
+
+// transact runs fn which may touch several records under the lock
+func (self *SMockCloud) transact(fn func() error) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return fn()
+}
+
+// SMockClient is the view of a mock cloud from a cloudprovider
+type SMockClient struct {
+	providerId   string
+	providerName string
+
+	cloud *SMockCloud
+}
+
+func NewMockClient(providerId, providerName, accessUrl, account string) (*SMockClient, error) {
+	config, err := ParseConfig(accessUrl)
+	if err != nil {
+		return nil, err
+	}
+	client := &SMockClient{
+		providerId:   providerId,
+		providerName: providerName,
+		cloud:        GetMockCloud(account, config),
+	}
+	return client, nil
+}
+
+func (self *SMockClient) GetCloud() *SMockCloud {
+	return self.cloud
+}
+
+func (self *SMockClient) copy(res iMockResource) iMockResource {
+	ret := cloneResource(res)
+	ret.getBase().client = self
+	return ret
+}
+
+func (self *SMockClient) get(kind string, id string) (iMockResource, error) {
+	self.cloud.lock.Lock()
+	defer self.cloud.lock.Unlock()
+
+	res, err := self.cloud.record(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	return self.copy(res), nil
+}
+
+func (self *SMockClient) list(kind string, filter func(res iMockResource) bool) []iMockResource {
+	self.cloud.lock.Lock()
+	defer self.cloud.lock.Unlock()
+
+	records := self.cloud.records(kind, filter)
+	ret := make([]iMockResource, len(records))
+	for i := range records {
+		ret[i] = self.copy(records[i])
+	}
+	return ret
+}
+
+// refresh reloads res from the record of kind
+func (self *SMockClient) refresh(kind string, res iMockResource) error {
+	self.cloud.lock.Lock()
+	defer self.cloud.lock.Unlock()
+
+	rec, err := self.cloud.record(kind, res.getBase().Id)
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(res).Elem().Set(reflect.ValueOf(rec).Elem())
+	res.getBase().client = self
+	return nil
+}
+
+// create stores res as a new record of kind and returns a copy of it
+func (self *SMockClient) create(kind string, prefix string, res iMockResource) iMockResource {
+	self.cloud.lock.Lock()
+	defer self.cloud.lock.Unlock()
+
+	base := res.getBase()
+	if len(base.Id) == 0 {
+		base.Id = self.cloud.newId(prefix)
+	}
+	base.client = nil
+	self.cloud.add(kind, res)
+	return self.copy(res)
+}
+
+func (self *SMockClient) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
+	subAccount := cloudprovider.SSubAccount{
+		Name:         self.providerName,
+		State:        api.CLOUD_PROVIDER_CONNECTED,
+		Account:      self.cloud.account,
+		HealthStatus: api.CLOUD_PROVIDER_HEALTH_NORMAL,
+	}
+	return []cloudprovider.SSubAccount{subAccount}, nil
+}
+
+func (self *SMockClient) GetRegions() []SRegion {
+	records := self.list(kindRegion, nil)
+	regions := make([]SRegion, len(records))
+	for i := range records {
+		regions[i] = *records[i].(*SRegion)
+	}
+	return regions
+}
+
+func (self *SMockClient) GetIRegions() []cloudprovider.ICloudRegion {
+	regions := self.GetRegions()
+	iregions := make([]cloudprovider.ICloudRegion, len(regions))
+	for i := range regions {
+		iregions[i] = &regions[i]
+	}
+	return iregions
+}
+
+func (self *SMockClient) GetIRegionById(id string) (cloudprovider.ICloudRegion, error) {
+	regions := self.GetRegions()
+	for i := range regions {
+		if regions[i].GetGlobalId() == id {
+			return &regions[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SMockClient) GetIProjects() ([]cloudprovider.ICloudProject, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
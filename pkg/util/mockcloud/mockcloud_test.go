@@ -0,0 +1,352 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+func newTestClient(t *testing.T, accessUrl string) *SMockClient {
+	ResetMockClouds()
+	client, err := NewMockClient("provider-id", "mock", accessUrl, t.Name())
+	if err != nil {
+		t.Fatalf("NewMockClient: %s", err)
+	}
+	return client
+}
+
+func TestParseConfig(t *testing.T) {
+	cases := []struct {
+		url     string
+		want    SMockConfig
+		wantErr bool
+	}{
+		{
+			url:  "",
+			want: SMockConfig{Regions: MOCK_DEFAULT_REGION_COUNT, Zones: MOCK_DEFAULT_ZONE_COUNT},
+		},
+		{
+			url:  "mock://?regions=2&zones=3&latency=10ms&fail=CreateVM:2,BootVM",
+			want: SMockConfig{Regions: 2, Zones: 3, Latency: 10 * time.Millisecond, Failures: map[string]int{"CreateVM": 2, "BootVM": 1}},
+		},
+		{url: "mock://?zones=27", wantErr: true},
+		{url: "mock://?regions=0", wantErr: true},
+		{url: "mock://?latency=abc", wantErr: true},
+		{url: "mock://?fail=CreateVM:x", wantErr: true},
+	}
+	for _, c := range cases {
+		config, err := ParseConfig(c.url)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expect error", c.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", c.url, err)
+			continue
+		}
+		if config.Regions != c.want.Regions || config.Zones != c.want.Zones || config.Latency != c.want.Latency {
+			t.Errorf("%q: got %#v, want %#v", c.url, config, c.want)
+		}
+		for op, count := range c.want.Failures {
+			if config.Failures[op] != count {
+				t.Errorf("%q: failure %s got %d, want %d", c.url, op, config.Failures[op], count)
+			}
+		}
+	}
+}
+
+func TestInventory(t *testing.T) {
+	client := newTestClient(t, "mock://?regions=2&zones=3")
+	iregions := client.GetIRegions()
+	if len(iregions) != 2 {
+		t.Fatalf("expect 2 regions, got %d", len(iregions))
+	}
+	for _, iregion := range iregions {
+		izones, err := iregion.GetIZones()
+		if err != nil {
+			t.Fatalf("GetIZones: %s", err)
+		}
+		if len(izones) != 3 {
+			t.Errorf("region %s: expect 3 zones, got %d", iregion.GetId(), len(izones))
+		}
+		for _, izone := range izones {
+			ihosts, _ := izone.GetIHosts()
+			istorages, _ := izone.GetIStorages()
+			if len(ihosts) != 1 || len(istorages) != 1 {
+				t.Errorf("zone %s: got %d hosts %d storages", izone.GetId(), len(ihosts), len(istorages))
+			}
+		}
+		ivpcs, _ := iregion.GetIVpcs()
+		if len(ivpcs) != 1 {
+			t.Errorf("region %s: expect 1 vpc, got %d", iregion.GetId(), len(ivpcs))
+		}
+		iskus, _ := iregion.GetSkus("")
+		if len(iskus) == 0 {
+			t.Errorf("region %s: no skus", iregion.GetId())
+		}
+	}
+	iregion, err := client.GetIRegionById(iregions[1].GetGlobalId())
+	if err != nil || iregion.GetId() != iregions[1].GetId() {
+		t.Errorf("GetIRegionById: %v", err)
+	}
+}
+
+type testEnv struct {
+	region  cloudprovider.ICloudRegion
+	host    cloudprovider.ICloudHost
+	network cloudprovider.ICloudNetwork
+	image   cloudprovider.ICloudImage
+}
+
+func newTestEnv(t *testing.T, client *SMockClient) *testEnv {
+	env := &testEnv{region: client.GetIRegions()[0]}
+	ihosts, err := env.region.GetIHosts()
+	if err != nil || len(ihosts) == 0 {
+		t.Fatalf("GetIHosts: %v", err)
+	}
+	env.host = ihosts[0]
+	ivpcs, _ := env.region.GetIVpcs()
+	iwires, _ := ivpcs[0].GetIWires()
+	for _, iwire := range iwires {
+		inetworks, _ := iwire.GetINetworks()
+		if iwire.GetIZone().GetId() == env.host.(*SHost).ZoneId && len(inetworks) > 0 {
+			env.network = inetworks[0]
+		}
+	}
+	if env.network == nil {
+		t.Fatalf("no network in zone of host %s", env.host.GetId())
+	}
+	icaches, _ := env.region.GetIStoragecaches()
+	iimages, _ := icaches[0].GetIImages()
+	if len(iimages) == 0 {
+		t.Fatalf("no images")
+	}
+	env.image = iimages[0]
+	return env
+}
+
+func (env *testEnv) createVM(name string) (cloudprovider.ICloudVM, error) {
+	return env.host.CreateVM(&cloudprovider.SManagedVMCreateConfig{
+		Name:              name,
+		ExternalImageId:   env.image.GetId(),
+		Cpu:               2,
+		MemoryMB:          4096,
+		ExternalNetworkId: env.network.GetId(),
+		SysDisk:           cloudprovider.SDiskInfo{StorageType: api.STORAGE_MOCK_CLOUD, SizeGB: 1},
+		DataDisks:         []cloudprovider.SDiskInfo{{StorageType: api.STORAGE_MOCK_CLOUD, SizeGB: 20}},
+	})
+}
+
+func TestInstanceLifecycle(t *testing.T) {
+	client := newTestClient(t, "")
+	env := newTestEnv(t, client)
+
+	ivm, err := env.createVM("vm1")
+	if err != nil {
+		t.Fatalf("CreateVM: %s", err)
+	}
+	if ivm.GetStatus() != api.VM_RUNNING {
+		t.Errorf("expect status %s, got %s", api.VM_RUNNING, ivm.GetStatus())
+	}
+	idisks, err := ivm.GetIDisks()
+	if err != nil || len(idisks) != 2 {
+		t.Fatalf("expect 2 disks, got %d: %v", len(idisks), err)
+	}
+	if idisks[0].GetDiskType() != api.DISK_TYPE_SYS || int64(idisks[0].GetDiskSizeMB()) < env.image.GetSize()/1024/1024 {
+		t.Errorf("unexpected sys disk %s %dMB", idisks[0].GetDiskType(), idisks[0].GetDiskSizeMB())
+	}
+	inics, _ := ivm.GetINics()
+	if len(inics) != 1 || len(inics[0].GetIP()) == 0 {
+		t.Fatalf("expect 1 nic with address")
+	}
+
+	// the copy held by the caller is refreshed from the cloud state
+	if err := ivm.StopVM(context.Background(), true); err != nil {
+		t.Fatalf("StopVM: %s", err)
+	}
+	if ivm.GetStatus() != api.VM_READY {
+		t.Errorf("expect status %s after stop, got %s", api.VM_READY, ivm.GetStatus())
+	}
+
+	ieip, err := env.region.CreateEIP("eip1", 10, models.EIP_CHARGE_TYPE_BY_TRAFFIC, "")
+	if err != nil {
+		t.Fatalf("CreateEIP: %s", err)
+	}
+	if err := ieip.Associate(ivm.GetGlobalId()); err != nil {
+		t.Fatalf("Associate: %s", err)
+	}
+	if err := ieip.Delete(); err == nil {
+		t.Errorf("expect delete of associated eip to fail")
+	}
+
+	if err := ivm.DeleteVM(context.Background()); err != nil {
+		t.Fatalf("DeleteVM: %s", err)
+	}
+	if err := ivm.Refresh(); err != cloudprovider.ErrNotFound {
+		t.Errorf("expect ErrNotFound after delete, got %v", err)
+	}
+	for _, idisk := range idisks {
+		if err := idisk.Refresh(); err != cloudprovider.ErrNotFound {
+			t.Errorf("expect auto delete disk %s removed, got %v", idisk.GetId(), err)
+		}
+	}
+	if err := ieip.Refresh(); err != nil {
+		t.Fatalf("eip Refresh: %s", err)
+	}
+	if len(ieip.GetAssociationExternalId()) > 0 {
+		t.Errorf("expect eip dissociated after instance deleted")
+	}
+	if err := ieip.Delete(); err != nil {
+		t.Errorf("eip Delete: %s", err)
+	}
+}
+
+func TestFailureInjection(t *testing.T) {
+	client := newTestClient(t, "mock://?fail=CreateVM:1")
+	env := newTestEnv(t, client)
+	cloud := client.GetCloud()
+
+	_, err := env.createVM("vm1")
+	if !IsInjectedFailure(err) {
+		t.Fatalf("expect injected failure, got %v", err)
+	}
+	if _, err := env.createVM("vm1"); err != nil {
+		t.Fatalf("expect failure consumed, got %s", err)
+	}
+	if cloud.GetCallCount("CreateVM") != 2 {
+		t.Errorf("expect 2 CreateVM calls, got %d", cloud.GetCallCount("CreateVM"))
+	}
+
+	cloud.InjectFailure("BootVM", 1)
+	ivm, err := env.createVM("vm2")
+	if err != nil {
+		t.Fatalf("CreateVM: %s", err)
+	}
+	if ivm.GetStatus() != api.VM_START_FAILED || !IsInjectedFailure(ivm.GetError()) {
+		t.Errorf("expect boot failure, got status %s error %v", ivm.GetStatus(), ivm.GetError())
+	}
+
+	cloud.InjectFailure("GetIVpcs", -1)
+	for i := 0; i < 3; i++ {
+		if _, err := env.region.GetIVpcs(); !IsInjectedFailure(err) {
+			t.Fatalf("expect persistent failure, got %v", err)
+		}
+	}
+	cloud.ClearFailures()
+	if _, err := env.region.GetIVpcs(); err != nil {
+		t.Errorf("expect failures cleared, got %s", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	client := newTestClient(t, "mock://?latency=20ms")
+	iregion := client.GetIRegions()[0]
+	start := time.Now()
+	if _, err := iregion.GetIVpcs(); err != nil {
+		t.Fatalf("GetIVpcs: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expect latency of 20ms, got %s", elapsed)
+	}
+	client.GetCloud().SetLatency(0)
+	start = time.Now()
+	iregion.GetIVpcs()
+	if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+		t.Errorf("expect latency reset, got %s", elapsed)
+	}
+}
+
+func TestVpcDelete(t *testing.T) {
+	client := newTestClient(t, "")
+	iregion := client.GetIRegions()[0]
+	ivpc, err := iregion.CreateIVpc("vpc1", "", "10.0.0.0/16")
+	if err != nil {
+		t.Fatalf("CreateIVpc: %s", err)
+	}
+	iwires, _ := ivpc.GetIWires()
+	if len(iwires) == 0 {
+		t.Fatalf("expect wires created for zones")
+	}
+	inetwork, err := iwires[0].CreateINetwork("net1", "10.0.1.0/24", "")
+	if err != nil {
+		t.Fatalf("CreateINetwork: %s", err)
+	}
+	if err := ivpc.Delete(); err == nil {
+		t.Errorf("expect delete of vpc with networks to fail")
+	}
+	if err := inetwork.Delete(); err != nil {
+		t.Fatalf("network Delete: %s", err)
+	}
+	if err := ivpc.Delete(); err != nil {
+		t.Fatalf("vpc Delete: %s", err)
+	}
+	if _, err := iregion.GetIVpcById(ivpc.GetId()); err != cloudprovider.ErrNotFound {
+		t.Errorf("expect ErrNotFound, got %v", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	client := newTestClient(t, "mock://?regions=2")
+	env := newTestEnv(t, client)
+	source := env.region.(cloudprovider.ICloudEventSource)
+
+	start := time.Now().UTC()
+	ivm, err := env.createVM("vm1")
+	if err != nil {
+		t.Fatalf("CreateVM: %s", err)
+	}
+	if err := ivm.StopVM(context.Background(), true); err != nil {
+		t.Fatalf("StopVM: %s", err)
+	}
+	if err := ivm.DeleteVM(context.Background()); err != nil {
+		t.Fatalf("DeleteVM: %s", err)
+	}
+	end := time.Now().UTC().Add(time.Second)
+
+	events, err := source.GetICloudEvents(start, end)
+	if err != nil {
+		t.Fatalf("GetICloudEvents: %s", err)
+	}
+	actions := []string{}
+	for _, event := range events {
+		if event.ResourceType == cloudprovider.CLOUD_EVENT_RESOURCE_SERVER && event.ResourceId == ivm.GetGlobalId() {
+			actions = append(actions, event.Action)
+		}
+	}
+	want := []string{cloudprovider.CLOUD_EVENT_ACTION_CREATE, cloudprovider.CLOUD_EVENT_ACTION_UPDATE, cloudprovider.CLOUD_EVENT_ACTION_DELETE}
+	if strings.Join(actions, ",") != strings.Join(want, ",") {
+		t.Errorf("server events got %v, want %v", actions, want)
+	}
+
+	// events of the other regions are not reported
+	other := client.GetIRegions()[1].(cloudprovider.ICloudEventSource)
+	events, err = other.GetICloudEvents(start, end)
+	if err != nil || len(events) > 0 {
+		t.Errorf("expect no events of region 2, got %d: %v", len(events), err)
+	}
+
+	if _, err := source.GetICloudEvents(start.Add(-time.Hour), end); err != cloudprovider.ErrEventExpired {
+		t.Errorf("expect ErrEventExpired, got %v", err)
+	}
+}
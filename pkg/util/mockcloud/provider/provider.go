@@ -0,0 +1,137 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/mockcloud"
+)
+
+// SMockProviderFactory provides the in-memory mock cloud, the clouds are kept
+// by account so that the state survives across providers
+type SMockProviderFactory struct {
+}
+
+func (self *SMockProviderFactory) GetId() string {
+	return mockcloud.CLOUD_PROVIDER_MOCK
+}
+
+func (self *SMockProviderFactory) GetName() string {
+	return mockcloud.CLOUD_PROVIDER_MOCK
+}
+
+func (self *SMockProviderFactory) ValidateChangeBandwidth(instanceId string, bandwidth int64) error {
+	return nil
+}
+
+func (self *SMockProviderFactory) IsPublicCloud() bool {
+	return true
+}
+
+func (self *SMockProviderFactory) IsOnPremise() bool {
+	return false
+}
+
+func (self *SMockProviderFactory) IsSupportPrepaidResources() bool {
+	return false
+}
+
+func (self *SMockProviderFactory) NeedSyncSkuFromCloud() bool {
+	return true
+}
+
+func (self *SMockProviderFactory) ValidateCreateCloudaccountData(ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict) error {
+	account, _ := data.GetString("account")
+	if len(account) == 0 {
+		return httperrors.NewMissingParameterError("account")
+	}
+	secret, _ := data.GetString("secret")
+	if len(secret) == 0 {
+		return httperrors.NewMissingParameterError("secret")
+	}
+	accessUrl, _ := data.GetString("access_url")
+	if _, err := mockcloud.ParseConfig(accessUrl); err != nil {
+		return httperrors.NewInputParameterError("%s", err)
+	}
+	return nil
+}
+
+func (self *SMockProviderFactory) ValidateUpdateCloudaccountCredential(ctx context.Context, userCred mcclient.TokenCredential, data jsonutils.JSONObject, cloudaccount string) (*cloudprovider.SCloudaccount, error) {
+	secret, _ := data.GetString("secret")
+	if len(secret) == 0 {
+		return nil, httperrors.NewMissingParameterError("secret")
+	}
+	account := &cloudprovider.SCloudaccount{
+		Account: cloudaccount,
+		Secret:  secret,
+	}
+	return account, nil
+}
+
+func (self *SMockProviderFactory) GetProvider(providerId, providerName, url, account, secret string) (cloudprovider.ICloudProvider, error) {
+	client, err := mockcloud.NewMockClient(providerId, providerName, url, account)
+	if err != nil {
+		return nil, err
+	}
+	return &SMockProvider{
+		SBaseProvider: cloudprovider.NewBaseProvider(self),
+		client:        client,
+	}, nil
+}
+
+func init() {
+	factory := SMockProviderFactory{}
+	cloudprovider.RegisterFactory(&factory)
+}
+
+type SMockProvider struct {
+	cloudprovider.SBaseProvider
+	client *mockcloud.SMockClient
+}
+
+func (self *SMockProvider) GetVersion() string {
+	return mockcloud.MOCK_API_VERSION
+}
+
+func (self *SMockProvider) GetSysInfo() (jsonutils.JSONObject, error) {
+	return jsonutils.NewDict(), nil
+}
+
+func (self *SMockProvider) GetSubAccounts() ([]cloudprovider.SSubAccount, error) {
+	return self.client.GetSubAccounts()
+}
+
+func (self *SMockProvider) GetIRegions() []cloudprovider.ICloudRegion {
+	return self.client.GetIRegions()
+}
+
+func (self *SMockProvider) GetIRegionById(extId string) (cloudprovider.ICloudRegion, error) {
+	return self.client.GetIRegionById(extId)
+}
+
+func (self *SMockProvider) GetBalance() (float64, string, error) {
+	return 0.0, api.CLOUD_PROVIDER_HEALTH_UNKNOWN, cloudprovider.ErrNotSupported
+}
+
+func (self *SMockProvider) GetIProjects() ([]cloudprovider.ICloudProject, error) {
+	return self.client.GetIProjects()
+}
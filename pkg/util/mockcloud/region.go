@@ -0,0 +1,482 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/pkg/util/secrules"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type SRegion struct {
+	SResourceBase
+
+	GeographicInfo cloudprovider.SGeographicInfo
+}
+
+// populate builds the initial topology of the cloud: each region has a
+// storagecache with public images, a default vpc, a default secgroup and skus,
+// each zone has a host, a storage and a wire of the default vpc with a network
+func (self *SMockCloud) populate(config *SMockConfig) {
+	for i := 1; i <= config.Regions; i++ {
+		region := &SRegion{
+			SResourceBase: SResourceBase{
+				Id:     fmt.Sprintf("region-%d", i),
+				Name:   fmt.Sprintf("Mock Region %d", i),
+				Status: models.CLOUD_REGION_STATUS_INSERVER,
+			},
+		}
+		self.add(kindRegion, region)
+
+		cache := &SStoragecache{
+			SResourceBase: SResourceBase{Id: self.newId("cache"), Name: fmt.Sprintf("%s-cache", region.Id), Status: "available"},
+			RegionId:      region.Id,
+		}
+		self.add(kindStoragecache, cache)
+		for _, image := range []SImage{
+			{OsType: "Linux", OsDist: "CentOS", OsVersion: "7.6", SizeGB: 20},
+			{OsType: "Linux", OsDist: "Ubuntu", OsVersion: "18.04", SizeGB: 20},
+			{OsType: "Windows", OsDist: "Windows Server", OsVersion: "2016", SizeGB: 40},
+		} {
+			image := image
+			image.Id = self.newId("img")
+			image.Name = fmt.Sprintf("%s %s 64bit", image.OsDist, image.OsVersion)
+			image.Status = models.CACHED_IMAGE_STATUS_READY
+			image.CacheId = cache.Id
+			image.ImageType = cloudprovider.CachedImageTypeSystem
+			self.add(kindImage, &image)
+		}
+
+		vpc := &SVpc{
+			SResourceBase: SResourceBase{Id: self.newId("vpc"), Name: "default", Status: models.VPC_STATUS_AVAILABLE},
+			RegionId:      region.Id,
+			CidrBlock:     "192.168.0.0/16",
+			IsDefault:     true,
+		}
+		self.add(kindVpc, vpc)
+		self.add(kindSecgroup, &SSecurityGroup{
+			SResourceBase: SResourceBase{Id: self.newId("sg"), Name: "default", Status: "ready"},
+			VpcId:         vpc.Id,
+			Description:   "default security group",
+			Rules:         []secrules.SecurityRule{*secrules.MustParseSecurityRule("out:allow any")},
+		})
+
+		for j := 0; j < config.Zones; j++ {
+			zone := &SZone{
+				SResourceBase: SResourceBase{
+					Id:     fmt.Sprintf("%s%c", region.Id, 'a'+j),
+					Name:   fmt.Sprintf("Mock Region %d Zone %c", i, 'A'+j),
+					Status: models.ZONE_ENABLE,
+				},
+				RegionId: region.Id,
+			}
+			self.add(kindZone, zone)
+			self.add(kindHost, &SHost{
+				SResourceBase: SResourceBase{Id: fmt.Sprintf("host-%s", zone.Id), Name: fmt.Sprintf("%s-host", zone.Id), Status: api.HOST_STATUS_RUNNING},
+				ZoneId:        zone.Id,
+			})
+			self.add(kindStorage, &SStorage{
+				SResourceBase: SResourceBase{Id: self.newId("storage"), Name: fmt.Sprintf("%s-%s", zone.Id, api.STORAGE_MOCK_CLOUD), Status: api.STORAGE_ONLINE},
+				ZoneId:        zone.Id,
+				CacheId:       cache.Id,
+				StorageType:   api.STORAGE_MOCK_CLOUD,
+			})
+			wire := &SWire{
+				SResourceBase: SResourceBase{Id: self.newId("wire"), Name: fmt.Sprintf("%s-%s", vpc.Name, zone.Id), Status: "available"},
+				VpcId:         vpc.Id,
+				ZoneId:        zone.Id,
+			}
+			self.add(kindWire, wire)
+			network, _ := newNetwork(fmt.Sprintf("%s-net", zone.Id), fmt.Sprintf("192.168.%d.0/24", j), wire.Id)
+			network.Id = self.newId("net")
+			self.add(kindNetwork, network)
+		}
+
+		for _, sku := range []SSku{
+			{CpuCoreCount: 1, MemorySizeMB: 1024},
+			{CpuCoreCount: 2, MemorySizeMB: 4096},
+			{CpuCoreCount: 4, MemorySizeMB: 8192},
+			{CpuCoreCount: 8, MemorySizeMB: 16384},
+		} {
+			sku := sku
+			sku.Name = fmt.Sprintf("mock.c1.%dc%dg", sku.CpuCoreCount, sku.MemorySizeMB/1024)
+			sku.Id = fmt.Sprintf("%s/%s", region.Id, sku.Name)
+			sku.Status = models.SkuStatusAvailable
+			sku.RegionId = region.Id
+			self.add(kindSku, &sku)
+		}
+	}
+}
+
+func (self *SRegion) GetGlobalId() string {
+	return fmt.Sprintf("%s/%s", CLOUD_PROVIDER_MOCK, self.Id)
+}
+
+func (self *SRegion) Refresh() error {
+	return self.client.refresh(kindRegion, self)
+}
+
+func (self *SRegion) GetGeographicInfo() cloudprovider.SGeographicInfo {
+	return self.GeographicInfo
+}
+
+func (self *SRegion) GetProvider() string {
+	return CLOUD_PROVIDER_MOCK
+}
+
+func (self *SRegion) getZoneIds() []string {
+	zones := self.getZones()
+	ids := make([]string, len(zones))
+	for i := range zones {
+		ids[i] = zones[i].Id
+	}
+	return ids
+}
+
+func (self *SRegion) inRegion(zoneId string) bool {
+	for _, id := range self.getZoneIds() {
+		if id == zoneId {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *SRegion) getZones() []SZone {
+	records := self.client.list(kindZone, func(res iMockResource) bool {
+		return res.(*SZone).RegionId == self.Id
+	})
+	zones := make([]SZone, len(records))
+	for i := range records {
+		zones[i] = *records[i].(*SZone)
+	}
+	return zones
+}
+
+func (self *SRegion) GetIZones() ([]cloudprovider.ICloudZone, error) {
+	if err := self.client.cloud.call("GetIZones"); err != nil {
+		return nil, err
+	}
+	zones := self.getZones()
+	izones := make([]cloudprovider.ICloudZone, len(zones))
+	for i := range zones {
+		izones[i] = &zones[i]
+	}
+	return izones, nil
+}
+
+func (self *SRegion) GetIZoneById(id string) (cloudprovider.ICloudZone, error) {
+	zones := self.getZones()
+	for i := range zones {
+		if zones[i].GetGlobalId() == id {
+			return &zones[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SRegion) getVpcs() []SVpc {
+	records := self.client.list(kindVpc, func(res iMockResource) bool {
+		return res.(*SVpc).RegionId == self.Id
+	})
+	vpcs := make([]SVpc, len(records))
+	for i := range records {
+		vpcs[i] = *records[i].(*SVpc)
+	}
+	return vpcs
+}
+
+func (self *SRegion) GetIVpcs() ([]cloudprovider.ICloudVpc, error) {
+	if err := self.client.cloud.call("GetIVpcs"); err != nil {
+		return nil, err
+	}
+	vpcs := self.getVpcs()
+	ivpcs := make([]cloudprovider.ICloudVpc, len(vpcs))
+	for i := range vpcs {
+		ivpcs[i] = &vpcs[i]
+	}
+	return ivpcs, nil
+}
+
+func (self *SRegion) GetIVpcById(id string) (cloudprovider.ICloudVpc, error) {
+	res, err := self.client.get(kindVpc, id)
+	if err != nil {
+		return nil, err
+	}
+	vpc := res.(*SVpc)
+	if vpc.RegionId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return vpc, nil
+}
+
+func (self *SRegion) CreateIVpc(name string, desc string, cidr string) (cloudprovider.ICloudVpc, error) {
+	if err := self.client.cloud.call("CreateIVpc"); err != nil {
+		return nil, err
+	}
+	vpc := self.client.create(kindVpc, "vpc", &SVpc{
+		SResourceBase: SResourceBase{Name: name, Status: models.VPC_STATUS_AVAILABLE},
+		RegionId:      self.Id,
+		CidrBlock:     cidr,
+	}).(*SVpc)
+	for _, zoneId := range self.getZoneIds() {
+		self.client.create(kindWire, "wire", &SWire{
+			SResourceBase: SResourceBase{Name: fmt.Sprintf("%s-%s", name, zoneId), Status: "available"},
+			VpcId:         vpc.Id,
+			ZoneId:        zoneId,
+		})
+	}
+	return vpc, nil
+}
+
+func (self *SRegion) getEips() []SEip {
+	records := self.client.list(kindEip, func(res iMockResource) bool {
+		return res.(*SEip).RegionId == self.Id
+	})
+	eips := make([]SEip, len(records))
+	for i := range records {
+		eips[i] = *records[i].(*SEip)
+	}
+	return eips
+}
+
+func (self *SRegion) GetIEips() ([]cloudprovider.ICloudEIP, error) {
+	if err := self.client.cloud.call("GetIEips"); err != nil {
+		return nil, err
+	}
+	eips := self.getEips()
+	ieips := make([]cloudprovider.ICloudEIP, len(eips))
+	for i := range eips {
+		ieips[i] = &eips[i]
+	}
+	return ieips, nil
+}
+
+func (self *SRegion) GetIEipById(id string) (cloudprovider.ICloudEIP, error) {
+	res, err := self.client.get(kindEip, id)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SEip), nil
+}
+
+func (self *SRegion) CreateEIP(name string, bwMbps int, chargeType string, bgpType string) (cloudprovider.ICloudEIP, error) {
+	if err := self.client.cloud.call("CreateEIP"); err != nil {
+		return nil, err
+	}
+	if len(chargeType) == 0 {
+		chargeType = models.EIP_CHARGE_TYPE_BY_TRAFFIC
+	}
+	eip := &SEip{
+		SResourceBase: SResourceBase{Name: name, Status: models.EIP_STATUS_READY},
+		RegionId:      self.Id,
+		Bandwidth:     bwMbps,
+		ChargeType:    chargeType,
+	}
+	self.client.cloud.transact(func() error {
+		eip.IpAddr = self.client.cloud.allocEipAddr()
+		return nil
+	})
+	return self.client.create(kindEip, "eip", eip).(*SEip), nil
+}
+
+func (self *SRegion) DeleteSecurityGroup(vpcId, secgroupId string) error {
+	if err := self.client.cloud.call("DeleteSecurityGroup"); err != nil {
+		return err
+	}
+	return self.client.cloud.transact(func() error {
+		self.client.cloud.remove(kindSecgroup, secgroupId)
+		return nil
+	})
+}
+
+func (self *SRegion) SyncSecurityGroup(secgroupId string, vpcId string, name string, desc string, rules []secrules.SecurityRule) (string, error) {
+	if err := self.client.cloud.call("SyncSecurityGroup"); err != nil {
+		return "", err
+	}
+	if len(secgroupId) > 0 {
+		err := self.client.cloud.update(kindSecgroup, secgroupId, func(res iMockResource) error {
+			secgroup := res.(*SSecurityGroup)
+			secgroup.Description = desc
+			secgroup.Rules = append([]secrules.SecurityRule{}, rules...)
+			return nil
+		})
+		if err == nil {
+			return secgroupId, nil
+		}
+		if err != cloudprovider.ErrNotFound {
+			return "", err
+		}
+	}
+	secgroup := self.client.create(kindSecgroup, "sg", &SSecurityGroup{
+		SResourceBase: SResourceBase{Name: name, Status: "ready"},
+		VpcId:         vpcId,
+		Description:   desc,
+		Rules:         append([]secrules.SecurityRule{}, rules...),
+	})
+	return secgroup.getBase().Id, nil
+}
+
+func (self *SRegion) GetISnapshots() ([]cloudprovider.ICloudSnapshot, error) {
+	if err := self.client.cloud.call("GetISnapshots"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindSnapshot, func(res iMockResource) bool {
+		return res.(*SSnapshot).RegionId == self.Id
+	})
+	isnapshots := make([]cloudprovider.ICloudSnapshot, len(records))
+	for i := range records {
+		isnapshots[i] = records[i].(*SSnapshot)
+	}
+	return isnapshots, nil
+}
+
+func (self *SRegion) GetISnapshotById(snapshotId string) (cloudprovider.ICloudSnapshot, error) {
+	res, err := self.client.get(kindSnapshot, snapshotId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SSnapshot), nil
+}
+
+func (self *SRegion) GetIHosts() ([]cloudprovider.ICloudHost, error) {
+	ihosts := []cloudprovider.ICloudHost{}
+	zones := self.getZones()
+	for i := range zones {
+		hosts, err := zones[i].GetIHosts()
+		if err != nil {
+			return nil, err
+		}
+		ihosts = append(ihosts, hosts...)
+	}
+	return ihosts, nil
+}
+
+func (self *SRegion) GetIHostById(id string) (cloudprovider.ICloudHost, error) {
+	res, err := self.client.get(kindHost, id)
+	if err != nil {
+		return nil, err
+	}
+	host := res.(*SHost)
+	if !self.inRegion(host.ZoneId) {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return host, nil
+}
+
+func (self *SRegion) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	istorages := []cloudprovider.ICloudStorage{}
+	zones := self.getZones()
+	for i := range zones {
+		storages, err := zones[i].GetIStorages()
+		if err != nil {
+			return nil, err
+		}
+		istorages = append(istorages, storages...)
+	}
+	return istorages, nil
+}
+
+func (self *SRegion) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	res, err := self.client.get(kindStorage, id)
+	if err != nil {
+		return nil, err
+	}
+	storage := res.(*SStorage)
+	if !self.inRegion(storage.ZoneId) {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return storage, nil
+}
+
+func (self *SRegion) GetIStoragecaches() ([]cloudprovider.ICloudStoragecache, error) {
+	records := self.client.list(kindStoragecache, func(res iMockResource) bool {
+		return res.(*SStoragecache).RegionId == self.Id
+	})
+	icaches := make([]cloudprovider.ICloudStoragecache, len(records))
+	for i := range records {
+		icaches[i] = records[i].(*SStoragecache)
+	}
+	return icaches, nil
+}
+
+func (self *SRegion) GetIStoragecacheById(id string) (cloudprovider.ICloudStoragecache, error) {
+	res, err := self.client.get(kindStoragecache, id)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SStoragecache), nil
+}
+
+func (self *SRegion) GetSkus(zoneId string) ([]cloudprovider.ICloudSku, error) {
+	if err := self.client.cloud.call("GetSkus"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindSku, func(res iMockResource) bool {
+		return res.(*SSku).RegionId == self.Id
+	})
+	iskus := make([]cloudprovider.ICloudSku, len(records))
+	for i := range records {
+		iskus[i] = records[i].(*SSku)
+	}
+	return iskus, nil
+}
+
+func (self *SRegion) getSkuByName(name string) (*SSku, error) {
+	records := self.client.list(kindSku, func(res iMockResource) bool {
+		sku := res.(*SSku)
+		return sku.RegionId == self.Id && sku.Name == name
+	})
+	if len(records) == 0 {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return records[0].(*SSku), nil
+}
+
+func (self *SRegion) GetIBuckets() ([]cloudprovider.ICloudBucket, error) {
+	return []cloudprovider.ICloudBucket{}, nil
+}
+
+func (self *SRegion) GetIBucketById(name string) (cloudprovider.ICloudBucket, error) {
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (self *SRegion) CreateIBucket(name string, storageClass string, acl string) (cloudprovider.ICloudBucket, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) DeleteIBucket(name string) error {
+	return cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetIDBInstances() ([]cloudprovider.ICloudDBInstance, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetIDBInstanceById(id string) (cloudprovider.ICloudDBInstance, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetIVpcPeerings() ([]cloudprovider.ICloudVpcPeering, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+func (self *SRegion) GetIVpnConnections() ([]cloudprovider.ICloudVpnConnection, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
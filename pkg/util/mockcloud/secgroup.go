@@ -0,0 +1,43 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"yunion.io/x/pkg/util/secrules"
+)
+
+type SSecurityGroup struct {
+	SResourceBase
+
+	VpcId       string
+	Description string
+	Rules       []secrules.SecurityRule
+}
+
+func (self *SSecurityGroup) Refresh() error {
+	return self.client.refresh(kindSecgroup, self)
+}
+
+func (self *SSecurityGroup) GetDescription() string {
+	return self.Description
+}
+
+func (self *SSecurityGroup) GetRules() ([]secrules.SecurityRule, error) {
+	return append([]secrules.SecurityRule{}, self.Rules...), nil
+}
+
+func (self *SSecurityGroup) GetVpcId() string {
+	return self.VpcId
+}
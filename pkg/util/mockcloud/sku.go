@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type SSku struct {
+	SResourceBase
+
+	RegionId     string
+	CpuCoreCount int
+	MemorySizeMB int
+}
+
+func (self *SSku) Refresh() error {
+	return self.client.refresh(kindSku, self)
+}
+
+func (self *SSku) GetInstanceTypeFamily() string {
+	return "mock.c1"
+}
+
+func (self *SSku) GetInstanceTypeCategory() string {
+	return models.SkuCategoryGeneralPurpose
+}
+
+func (self *SSku) GetPrepaidStatus() string {
+	return models.SkuStatusSoldout
+}
+
+func (self *SSku) GetPostpaidStatus() string {
+	return models.SkuStatusAvailable
+}
+
+func (self *SSku) GetCpuCoreCount() int {
+	return self.CpuCoreCount
+}
+
+func (self *SSku) GetMemorySizeMB() int {
+	return self.MemorySizeMB
+}
+
+func (self *SSku) GetOsName() string {
+	return "Any"
+}
+
+func (self *SSku) GetSysDiskResizable() bool {
+	return true
+}
+
+func (self *SSku) GetSysDiskType() string {
+	return api.STORAGE_MOCK_CLOUD
+}
+
+func (self *SSku) GetSysDiskMinSizeGB() int {
+	return 0
+}
+
+func (self *SSku) GetSysDiskMaxSizeGB() int {
+	return 500
+}
+
+func (self *SSku) GetAttachedDiskType() string {
+	return ""
+}
+
+func (self *SSku) GetAttachedDiskSizeGB() int {
+	return 0
+}
+
+func (self *SSku) GetAttachedDiskCount() int {
+	return 0
+}
+
+func (self *SSku) GetDataDiskTypes() string {
+	return api.STORAGE_MOCK_CLOUD
+}
+
+func (self *SSku) GetDataDiskMaxCount() int {
+	return 16
+}
+
+func (self *SSku) GetNicType() string {
+	return "vpc"
+}
+
+func (self *SSku) GetNicMaxCount() int {
+	return 1
+}
+
+func (self *SSku) GetGpuAttachable() bool {
+	return false
+}
+
+func (self *SSku) GetGpuSpec() string {
+	return ""
+}
+
+func (self *SSku) GetGpuCount() int {
+	return 0
+}
+
+func (self *SSku) GetGpuMaxCount() int {
+	return 0
+}
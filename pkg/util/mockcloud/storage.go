@@ -0,0 +1,129 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const MOCK_STORAGE_CAPACITY_MB = 1024 * 1024 * 1024
+
+type SStorage struct {
+	SResourceBase
+
+	ZoneId      string
+	CacheId     string
+	StorageType string
+}
+
+func (self *SStorage) IsEmulated() bool {
+	return true
+}
+
+func (self *SStorage) Refresh() error {
+	return self.client.refresh(kindStorage, self)
+}
+
+func (self *SStorage) GetIStoragecache() cloudprovider.ICloudStoragecache {
+	res, err := self.client.get(kindStoragecache, self.CacheId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SStoragecache)
+}
+
+func (self *SStorage) GetIZone() cloudprovider.ICloudZone {
+	res, err := self.client.get(kindZone, self.ZoneId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SZone)
+}
+
+func (self *SStorage) GetIDisks() ([]cloudprovider.ICloudDisk, error) {
+	if err := self.client.cloud.call("GetIDisks"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindDisk, func(res iMockResource) bool {
+		return res.(*SDisk).StorageId == self.Id
+	})
+	idisks := make([]cloudprovider.ICloudDisk, len(records))
+	for i := range records {
+		idisks[i] = records[i].(*SDisk)
+	}
+	return idisks, nil
+}
+
+func (self *SStorage) GetIDiskById(id string) (cloudprovider.ICloudDisk, error) {
+	res, err := self.client.get(kindDisk, id)
+	if err != nil {
+		return nil, err
+	}
+	disk := res.(*SDisk)
+	if disk.StorageId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return disk, nil
+}
+
+func (self *SStorage) CreateIDisk(name string, sizeGb int, desc string) (cloudprovider.ICloudDisk, error) {
+	if err := self.client.cloud.call("CreateIDisk"); err != nil {
+		return nil, err
+	}
+	res, err := self.client.get(kindZone, self.ZoneId)
+	if err != nil {
+		return nil, err
+	}
+	zone := res.(*SZone)
+	disk := self.client.create(kindDisk, "d", &SDisk{
+		SResourceBase: SResourceBase{Name: name, Status: api.DISK_READY},
+		RegionId:      zone.RegionId,
+		StorageId:     self.Id,
+		SizeMB:        sizeGb * 1024,
+		DiskType:      api.DISK_TYPE_DATA,
+	})
+	return disk.(*SDisk), nil
+}
+
+func (self *SStorage) GetStorageType() string {
+	return self.StorageType
+}
+
+func (self *SStorage) GetMediumType() string {
+	return api.DISK_TYPE_SSD
+}
+
+func (self *SStorage) GetCapacityMB() int {
+	return MOCK_STORAGE_CAPACITY_MB
+}
+
+func (self *SStorage) GetStorageConf() jsonutils.JSONObject {
+	return jsonutils.NewDict()
+}
+
+func (self *SStorage) GetEnabled() bool {
+	return true
+}
+
+func (self *SStorage) GetMountPoint() string {
+	return ""
+}
+
+func (self *SStorage) IsSysDiskStore() bool {
+	return true
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SStoragecache struct {
+	SResourceBase
+
+	RegionId string
+}
+
+func (self *SStoragecache) Refresh() error {
+	return self.client.refresh(kindStoragecache, self)
+}
+
+func (self *SStoragecache) GetIImages() ([]cloudprovider.ICloudImage, error) {
+	if err := self.client.cloud.call("GetIImages"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindImage, func(res iMockResource) bool {
+		return res.(*SImage).CacheId == self.Id
+	})
+	iimages := make([]cloudprovider.ICloudImage, len(records))
+	for i := range records {
+		iimages[i] = records[i].(*SImage)
+	}
+	return iimages, nil
+}
+
+func (self *SStoragecache) GetIImageById(extId string) (cloudprovider.ICloudImage, error) {
+	res, err := self.client.get(kindImage, extId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SImage), nil
+}
+
+func (self *SStoragecache) GetPath() string {
+	return ""
+}
+
+func (self *SStoragecache) CreateIImage(snapshotId, imageName, osType, imageDesc string) (cloudprovider.ICloudImage, error) {
+	if err := self.client.cloud.call("CreateIImage"); err != nil {
+		return nil, err
+	}
+	res, err := self.client.get(kindSnapshot, snapshotId)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := res.(*SSnapshot)
+	image := self.client.create(kindImage, "img", &SImage{
+		SResourceBase: SResourceBase{Name: imageName, Status: models.CACHED_IMAGE_STATUS_READY},
+		CacheId:       self.Id,
+		ImageType:     cloudprovider.CachedImageTypeCustomized,
+		OsType:        osType,
+		SizeGB:        int(snapshot.SizeGB),
+	})
+	return image.(*SImage), nil
+}
+
+func (self *SStoragecache) DownloadImage(userCred mcclient.TokenCredential, imageId string, extId string, path string) (jsonutils.JSONObject, error) {
+	return nil, cloudprovider.ErrNotSupported
+}
+
+// UploadImage pretends to import the image, the content of the image is not transferred
+func (self *SStoragecache) UploadImage(ctx context.Context, userCred mcclient.TokenCredential, imageId string, osArch, osType, osDist, osVersion string, extId string, isForce bool) (string, error) {
+	if len(extId) > 0 && !isForce {
+		if _, err := self.client.get(kindImage, extId); err == nil {
+			return extId, nil
+		}
+	}
+	if err := self.client.cloud.call("UploadImage"); err != nil {
+		return "", err
+	}
+	image := self.client.create(kindImage, "img", &SImage{
+		SResourceBase: SResourceBase{Name: imageId, Status: models.CACHED_IMAGE_STATUS_READY},
+		CacheId:       self.Id,
+		ImageType:     cloudprovider.CachedImageTypeCustomized,
+		OsType:        osType,
+		OsDist:        osDist,
+		OsVersion:     osVersion,
+		OsArch:        osArch,
+		SizeGB:        10,
+	})
+	return image.getBase().Id, nil
+}
+
+type SImage struct {
+	SResourceBase
+
+	CacheId   string
+	ImageType string
+	OsType    string
+	OsDist    string
+	OsVersion string
+	OsArch    string
+	SizeGB    int
+}
+
+func (self *SImage) Refresh() error {
+	return self.client.refresh(kindImage, self)
+}
+
+func (self *SImage) Delete(ctx context.Context) error {
+	if err := self.client.cloud.call("DeleteImage"); err != nil {
+		return err
+	}
+	return self.client.cloud.transact(func() error {
+		self.client.cloud.remove(kindImage, self.Id)
+		return nil
+	})
+}
+
+func (self *SImage) GetIStoragecache() cloudprovider.ICloudStoragecache {
+	res, err := self.client.get(kindStoragecache, self.CacheId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SStoragecache)
+}
+
+func (self *SImage) GetSize() int64 {
+	return int64(self.SizeGB) * 1024 * 1024 * 1024
+}
+
+func (self *SImage) GetImageType() string {
+	return self.ImageType
+}
+
+func (self *SImage) GetImageStatus() string {
+	return cloudprovider.IMAGE_STATUS_ACTIVE
+}
+
+func (self *SImage) GetOsType() string {
+	return self.OsType
+}
+
+func (self *SImage) GetOsDist() string {
+	return self.OsDist
+}
+
+func (self *SImage) GetOsVersion() string {
+	return self.OsVersion
+}
+
+func (self *SImage) GetOsArch() string {
+	if len(self.OsArch) == 0 {
+		return "x86_64"
+	}
+	return self.OsArch
+}
+
+func (self *SImage) GetMinOsDiskSizeGb() int {
+	return self.SizeGB
+}
+
+func (self *SImage) GetMinRamSizeMb() int {
+	return 0
+}
+
+func (self *SImage) GetImageFormat() string {
+	return "qcow2"
+}
+
+func (self *SImage) GetCreateTime() time.Time {
+	return self.CreatedAt
+}
@@ -0,0 +1,350 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/pkg/util/netutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SVpc struct {
+	SResourceBase
+
+	RegionId  string
+	CidrBlock string
+	IsDefault bool
+}
+
+func (self *SVpc) Refresh() error {
+	return self.client.refresh(kindVpc, self)
+}
+
+func (self *SVpc) GetRegion() cloudprovider.ICloudRegion {
+	res, err := self.client.get(kindRegion, self.RegionId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SRegion)
+}
+
+func (self *SVpc) GetIsDefault() bool {
+	return self.IsDefault
+}
+
+func (self *SVpc) GetCidrBlock() string {
+	return self.CidrBlock
+}
+
+func (self *SVpc) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	if err := self.client.cloud.call("GetIWires"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindWire, func(res iMockResource) bool {
+		return res.(*SWire).VpcId == self.Id
+	})
+	iwires := make([]cloudprovider.ICloudWire, len(records))
+	for i := range records {
+		iwires[i] = records[i].(*SWire)
+	}
+	return iwires, nil
+}
+
+func (self *SVpc) GetIWireById(wireId string) (cloudprovider.ICloudWire, error) {
+	res, err := self.client.get(kindWire, wireId)
+	if err != nil {
+		return nil, err
+	}
+	wire := res.(*SWire)
+	if wire.VpcId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return wire, nil
+}
+
+func (self *SVpc) GetISecurityGroups() ([]cloudprovider.ICloudSecurityGroup, error) {
+	if err := self.client.cloud.call("GetISecurityGroups"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindSecgroup, func(res iMockResource) bool {
+		return res.(*SSecurityGroup).VpcId == self.Id
+	})
+	isecgroups := make([]cloudprovider.ICloudSecurityGroup, len(records))
+	for i := range records {
+		isecgroups[i] = records[i].(*SSecurityGroup)
+	}
+	return isecgroups, nil
+}
+
+func (self *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
+	return []cloudprovider.ICloudRouteTable{}, nil
+}
+
+func (self *SVpc) GetINatGateways() ([]cloudprovider.ICloudNatGateway, error) {
+	return []cloudprovider.ICloudNatGateway{}, nil
+}
+
+// Delete removes the vpc with its wires and secgroups, it fails if any
+// network is left in the vpc
+func (self *SVpc) Delete() error {
+	if err := self.client.cloud.call("DeleteVpc"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	return cloud.transact(func() error {
+		wires := cloud.records(kindWire, func(res iMockResource) bool {
+			return res.(*SWire).VpcId == self.Id
+		})
+		for _, wire := range wires {
+			networks := cloud.records(kindNetwork, func(res iMockResource) bool {
+				return res.(*SNetwork).WireId == wire.getBase().Id
+			})
+			if len(networks) > 0 {
+				return fmt.Errorf("vpc %s is not empty", self.Id)
+			}
+		}
+		for _, wire := range wires {
+			cloud.remove(kindWire, wire.getBase().Id)
+		}
+		for _, secgroup := range cloud.records(kindSecgroup, func(res iMockResource) bool {
+			return res.(*SSecurityGroup).VpcId == self.Id
+		}) {
+			cloud.remove(kindSecgroup, secgroup.getBase().Id)
+		}
+		cloud.remove(kindVpc, self.Id)
+		return nil
+	})
+}
+
+type SWire struct {
+	SResourceBase
+
+	VpcId  string
+	ZoneId string
+}
+
+func (self *SWire) IsEmulated() bool {
+	return true
+}
+
+func (self *SWire) Refresh() error {
+	return self.client.refresh(kindWire, self)
+}
+
+func (self *SWire) GetIVpc() cloudprovider.ICloudVpc {
+	res, err := self.client.get(kindVpc, self.VpcId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SVpc)
+}
+
+func (self *SWire) GetIZone() cloudprovider.ICloudZone {
+	res, err := self.client.get(kindZone, self.ZoneId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SZone)
+}
+
+func (self *SWire) GetBandwidth() int {
+	return 10000
+}
+
+func (self *SWire) GetINetworks() ([]cloudprovider.ICloudNetwork, error) {
+	if err := self.client.cloud.call("GetINetworks"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindNetwork, func(res iMockResource) bool {
+		return res.(*SNetwork).WireId == self.Id
+	})
+	inetworks := make([]cloudprovider.ICloudNetwork, len(records))
+	for i := range records {
+		inetworks[i] = records[i].(*SNetwork)
+	}
+	return inetworks, nil
+}
+
+func (self *SWire) GetINetworkById(netid string) (cloudprovider.ICloudNetwork, error) {
+	res, err := self.client.get(kindNetwork, netid)
+	if err != nil {
+		return nil, err
+	}
+	network := res.(*SNetwork)
+	if network.WireId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return network, nil
+}
+
+func (self *SWire) CreateINetwork(name string, cidr string, desc string) (cloudprovider.ICloudNetwork, error) {
+	if err := self.client.cloud.call("CreateINetwork"); err != nil {
+		return nil, err
+	}
+	network, err := newNetwork(name, cidr, self.Id)
+	if err != nil {
+		return nil, err
+	}
+	return self.client.create(kindNetwork, "net", network).(*SNetwork), nil
+}
+
+type SNetwork struct {
+	SResourceBase
+
+	WireId  string
+	IpStart string
+	IpEnd   string
+	IpMask  int8
+	Gateway string
+}
+
+// newNetwork makes a network of cidr whose first address is the gateway
+func newNetwork(name string, cidr string, wireId string) (*SNetwork, error) {
+	prefix, err := netutils.NewIPV4Prefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cidr %s: %s", cidr, err)
+	}
+	ipRange := prefix.ToIPRange()
+	network := &SNetwork{
+		SResourceBase: SResourceBase{Name: name, Status: api.NETWORK_STATUS_AVAILABLE},
+		WireId:        wireId,
+		IpStart:       ipRange.StartIp().StepUp().StepUp().String(),
+		IpEnd:         ipRange.EndIp().StepDown().String(),
+		IpMask:        prefix.MaskLen,
+		Gateway:       ipRange.StartIp().StepUp().String(),
+	}
+	return network, nil
+}
+
+func (self *SNetwork) Refresh() error {
+	return self.client.refresh(kindNetwork, self)
+}
+
+func (self *SNetwork) GetIWire() cloudprovider.ICloudWire {
+	res, err := self.client.get(kindWire, self.WireId)
+	if err != nil {
+		return nil
+	}
+	return res.(*SWire)
+}
+
+func (self *SNetwork) GetIpStart() string {
+	return self.IpStart
+}
+
+func (self *SNetwork) GetIpEnd() string {
+	return self.IpEnd
+}
+
+func (self *SNetwork) GetIpMask() int8 {
+	return self.IpMask
+}
+
+func (self *SNetwork) GetGateway() string {
+	return self.Gateway
+}
+
+func (self *SNetwork) GetServerType() string {
+	return api.NETWORK_TYPE_GUEST
+}
+
+func (self *SNetwork) GetIsPublic() bool {
+	return true
+}
+
+func (self *SNetwork) GetAllocTimeoutSeconds() int {
+	return 120
+}
+
+// Delete fails if any address of the network is in use
+func (self *SNetwork) Delete() error {
+	if err := self.client.cloud.call("DeleteNetwork"); err != nil {
+		return err
+	}
+	cloud := self.client.cloud
+	return cloud.transact(func() error {
+		if len(cloud.usedAddrs(self.Id)) > 0 {
+			return fmt.Errorf("network %s is in use", self.Id)
+		}
+		cloud.remove(kindNetwork, self.Id)
+		return nil
+	})
+}
+
+// usedAddrs returns the addresses of network used by instances and loadbalancers
+func (self *SMockCloud) usedAddrs(networkId string) map[string]bool {
+	used := map[string]bool{}
+	for _, res := range self.records(kindInstance, nil) {
+		for _, nic := range res.(*SInstance).Nics {
+			if nic.NetworkId == networkId {
+				used[nic.IpAddr] = true
+			}
+		}
+	}
+	for _, res := range self.records(kindLoadbalancer, nil) {
+		lb := res.(*SLoadbalancer)
+		if lb.NetworkId == networkId {
+			used[lb.Address] = true
+		}
+	}
+	return used
+}
+
+// allocAddr allocates ipAddr of network, the first free address is chosen
+// if ipAddr is empty
+func (self *SMockCloud) allocAddr(networkId string, ipAddr string) (string, error) {
+	res, err := self.record(kindNetwork, networkId)
+	if err != nil {
+		return "", fmt.Errorf("network %s not found", networkId)
+	}
+	network := res.(*SNetwork)
+	start, _ := netutils.NewIPV4Addr(network.IpStart)
+	end, _ := netutils.NewIPV4Addr(network.IpEnd)
+	ipRange := netutils.NewIPV4AddrRange(start, end)
+	used := self.usedAddrs(networkId)
+	if len(ipAddr) > 0 {
+		addr, err := netutils.NewIPV4Addr(ipAddr)
+		if err != nil || !ipRange.Contains(addr) {
+			return "", fmt.Errorf("address %s is out of network %s", ipAddr, networkId)
+		}
+		if used[ipAddr] {
+			return "", fmt.Errorf("address %s is in use", ipAddr)
+		}
+		return ipAddr, nil
+	}
+	for addr := start; ipRange.Contains(addr); addr = addr.StepUp() {
+		if !used[addr.String()] {
+			return addr.String(), nil
+		}
+	}
+	return "", fmt.Errorf("network %s is exhausted", networkId)
+}
+
+func (self *SMockCloud) allocNic(instanceId string, networkId string, ipAddr string) (*SInstanceNic, error) {
+	addr, err := self.allocAddr(networkId, ipAddr)
+	if err != nil {
+		return nil, err
+	}
+	seq := self.seq
+	nic := &SInstanceNic{
+		NetworkId: networkId,
+		IpAddr:    addr,
+		MacAddr:   fmt.Sprintf("00:16:3e:%02x:%02x:%02x", (seq>>16)&0xff, (seq>>8)&0xff, seq&0xff),
+	}
+	return nic, nil
+}
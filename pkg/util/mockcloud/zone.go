@@ -0,0 +1,127 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SZone struct {
+	SResourceBase
+
+	RegionId string
+}
+
+func (self *SZone) GetGlobalId() string {
+	return fmt.Sprintf("%s/%s/%s", CLOUD_PROVIDER_MOCK, self.RegionId, self.Id)
+}
+
+func (self *SZone) IsEmulated() bool {
+	return true
+}
+
+func (self *SZone) Refresh() error {
+	return self.client.refresh(kindZone, self)
+}
+
+func (self *SZone) getRegion() (*SRegion, error) {
+	res, err := self.client.get(kindRegion, self.RegionId)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SRegion), nil
+}
+
+func (self *SZone) GetIRegion() cloudprovider.ICloudRegion {
+	region, err := self.getRegion()
+	if err != nil {
+		return nil
+	}
+	return region
+}
+
+func (self *SZone) GetIHosts() ([]cloudprovider.ICloudHost, error) {
+	if err := self.client.cloud.call("GetIHosts"); err != nil {
+		return nil, err
+	}
+	records := self.client.list(kindHost, func(res iMockResource) bool {
+		return res.(*SHost).ZoneId == self.Id
+	})
+	ihosts := make([]cloudprovider.ICloudHost, len(records))
+	for i := range records {
+		ihosts[i] = records[i].(*SHost)
+	}
+	return ihosts, nil
+}
+
+func (self *SZone) GetIHostById(id string) (cloudprovider.ICloudHost, error) {
+	res, err := self.client.get(kindHost, id)
+	if err != nil {
+		return nil, err
+	}
+	host := res.(*SHost)
+	if host.ZoneId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return host, nil
+}
+
+func (self *SZone) getStorages() []SStorage {
+	records := self.client.list(kindStorage, func(res iMockResource) bool {
+		return res.(*SStorage).ZoneId == self.Id
+	})
+	storages := make([]SStorage, len(records))
+	for i := range records {
+		storages[i] = *records[i].(*SStorage)
+	}
+	return storages
+}
+
+func (self *SZone) GetIStorages() ([]cloudprovider.ICloudStorage, error) {
+	if err := self.client.cloud.call("GetIStorages"); err != nil {
+		return nil, err
+	}
+	storages := self.getStorages()
+	istorages := make([]cloudprovider.ICloudStorage, len(storages))
+	for i := range storages {
+		istorages[i] = &storages[i]
+	}
+	return istorages, nil
+}
+
+func (self *SZone) GetIStorageById(id string) (cloudprovider.ICloudStorage, error) {
+	res, err := self.client.get(kindStorage, id)
+	if err != nil {
+		return nil, err
+	}
+	storage := res.(*SStorage)
+	if storage.ZoneId != self.Id {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return storage, nil
+}
+
+func (self *SZone) GetIWires() ([]cloudprovider.ICloudWire, error) {
+	records := self.client.list(kindWire, func(res iMockResource) bool {
+		return res.(*SWire).ZoneId == self.Id
+	})
+	iwires := make([]cloudprovider.ICloudWire, len(records))
+	for i := range records {
+		iwires[i] = records[i].(*SWire)
+	}
+	return iwires, nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SBucket struct {
+	client *SObjectStoreClient
+	region cloudprovider.ICloudRegion
+
+	Name         string
+	Location     string
+	StorageClass string
+	CreatedAt    time.Time
+}
+
+func NewBucket(client *SObjectStoreClient, region cloudprovider.ICloudRegion, info SBucketInfo, location string) *SBucket {
+	return &SBucket{
+		client:       client,
+		region:       region,
+		Name:         info.Name,
+		Location:     location,
+		StorageClass: DEFAULT_STORAGE_CLASS,
+		CreatedAt:    info.CreatedAt,
+	}
+}
+
+// GetBuckets returns the buckets of the given location, all buckets are
+// returned when location is empty
+func GetBuckets(client *SObjectStoreClient, region cloudprovider.ICloudRegion, location string) ([]cloudprovider.ICloudBucket, error) {
+	infos, err := client.ListBuckets()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]cloudprovider.ICloudBucket, 0)
+	for i := range infos {
+		loc, err := client.GetBucketLocation(infos[i].Name)
+		if err != nil {
+			// bucket removed in the meantime
+			if err == cloudprovider.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if len(location) > 0 && loc != location {
+			continue
+		}
+		ret = append(ret, NewBucket(client, region, infos[i], loc))
+	}
+	return ret, nil
+}
+
+func GetBucket(client *SObjectStoreClient, region cloudprovider.ICloudRegion, name string) (cloudprovider.ICloudBucket, error) {
+	infos, err := client.ListBuckets()
+	if err != nil {
+		return nil, err
+	}
+	for i := range infos {
+		if infos[i].Name == name {
+			loc, err := client.GetBucketLocation(name)
+			if err != nil {
+				return nil, err
+			}
+			return NewBucket(client, region, infos[i], loc), nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (b *SBucket) GetId() string {
+	return b.Name
+}
+
+func (b *SBucket) GetName() string {
+	return b.Name
+}
+
+func (b *SBucket) GetGlobalId() string {
+	return b.Name
+}
+
+func (b *SBucket) GetStatus() string {
+	return "available"
+}
+
+func (b *SBucket) Refresh() error {
+	return b.client.HeadBucket(b.Name)
+}
+
+func (b *SBucket) IsEmulated() bool {
+	return false
+}
+
+func (b *SBucket) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (b *SBucket) GetIRegion() cloudprovider.ICloudRegion {
+	return b.region
+}
+
+func (b *SBucket) GetLocation() string {
+	return b.Location
+}
+
+func (b *SBucket) GetStorageClass() string {
+	return b.StorageClass
+}
+
+func (b *SBucket) GetCreateAt() time.Time {
+	return b.CreatedAt
+}
+
+func (b *SBucket) GetAccessUrl() string {
+	return b.client.BucketUrl(b.Name)
+}
+
+func (b *SBucket) GetAcl() string {
+	acl, err := b.client.GetBucketAcl(b.Name)
+	if err != nil {
+		return cloudprovider.ACL_UNKNOWN
+	}
+	return acl
+}
+
+func (b *SBucket) SetAcl(acl string) error {
+	return b.client.SetBucketAcl(b.Name, acl)
+}
+
+func (b *SBucket) GetStats() (cloudprovider.SBucketStats, error) {
+	return b.client.GetBucketStats(b.Name)
+}
+
+func (b *SBucket) GetLifecycle() ([]cloudprovider.SBucketLifecycleRule, error) {
+	return b.client.GetBucketLifecycle(b.Name)
+}
+
+func (b *SBucket) SetLifecycle(rules []cloudprovider.SBucketLifecycleRule) error {
+	return b.client.SetBucketLifecycle(b.Name, rules)
+}
+
+func (b *SBucket) GetTempUrl(method string, key string, expire time.Duration) (string, error) {
+	return b.client.PresignUrl(b.Name, method, key, expire)
+}
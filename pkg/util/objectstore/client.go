@@ -0,0 +1,429 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package objectstore implements cloudprovider.ICloudBucket on top of the
+// generic S3 protocol, which is spoken by AWS S3, Qcloud COS and most of
+// the S3-compatible storage servers.
+package objectstore
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+const (
+	DEFAULT_LOCATION = "us-east-1"
+
+	DEFAULT_STORAGE_CLASS = "STANDARD"
+
+	GROUP_ALL_USERS           = "/groups/global/AllUsers"
+	GROUP_AUTHENTICATED_USERS = "/groups/global/AuthenticatedUsers"
+)
+
+type SObjectStoreClient struct {
+	endpoint  string
+	region    string
+	pathStyle bool
+
+	client *s3.S3
+
+	locations *SBucketLocationCache
+}
+
+// SBucketLocationCache remembers the locations of the buckets. The buckets
+// of all the regions are listed by every region of a provider, whose
+// clients share one cache so the location of a bucket is queried once.
+type SBucketLocationCache struct {
+	lock      sync.Mutex
+	locations map[string]string
+}
+
+func NewBucketLocationCache() *SBucketLocationCache {
+	return &SBucketLocationCache{locations: make(map[string]string)}
+}
+
+func (c *SBucketLocationCache) get(name string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	loc, ok := c.locations[name]
+	return loc, ok
+}
+
+func (c *SBucketLocationCache) set(name string, location string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.locations[name] = location
+}
+
+func (c *SBucketLocationCache) remove(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.locations, name)
+}
+
+type SBucketInfo struct {
+	Name      string
+	CreatedAt time.Time
+}
+
+type SObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// NewObjectStoreClient creates a client of the S3 service at endpoint, an
+// empty endpoint means the AWS S3 endpoint of the region
+func NewObjectStoreClient(endpoint string, region string, accessKey string, secret string, pathStyle bool) (*SObjectStoreClient, error) {
+	if len(region) == 0 {
+		region = DEFAULT_LOCATION
+	}
+	cfg := &sdk.Config{
+		Region:           sdk.String(region),
+		Credentials:      credentials.NewStaticCredentials(accessKey, secret, ""),
+		S3ForcePathStyle: sdk.Bool(pathStyle),
+	}
+	if len(endpoint) > 0 {
+		cfg.Endpoint = sdk.String(endpoint)
+	}
+	s, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cli := SObjectStoreClient{
+		endpoint:  endpoint,
+		region:    region,
+		pathStyle: pathStyle,
+		client:    s3.New(s),
+		locations: NewBucketLocationCache(),
+	}
+	return &cli, nil
+}
+
+// SetLocationCache makes the client share the bucket locations with the
+// other clients of the provider
+func (cli *SObjectStoreClient) SetLocationCache(cache *SBucketLocationCache) {
+	if cache != nil {
+		cli.locations = cache
+	}
+}
+
+// NormalizeLocation converts the legacy location constraints of S3 to
+// the region names
+func NormalizeLocation(location string) string {
+	switch location {
+	case "":
+		return DEFAULT_LOCATION
+	case "EU":
+		return "eu-west-1"
+	}
+	return location
+}
+
+func isAwsErrorCode(err error, codes ...string) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		for _, code := range codes {
+			if aerr.Code() == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (cli *SObjectStoreClient) ListBuckets() ([]SBucketInfo, error) {
+	output, err := cli.client.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]SBucketInfo, 0, len(output.Buckets))
+	for _, bucket := range output.Buckets {
+		info := SBucketInfo{Name: sdk.StringValue(bucket.Name)}
+		if bucket.CreationDate != nil {
+			info.CreatedAt = *bucket.CreationDate
+		}
+		ret = append(ret, info)
+	}
+	return ret, nil
+}
+
+func (cli *SObjectStoreClient) GetBucketLocation(name string) (string, error) {
+	if loc, ok := cli.locations.get(name); ok {
+		return loc, nil
+	}
+	output, err := cli.client.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: sdk.String(name)})
+	if err != nil {
+		if isAwsErrorCode(err, s3.ErrCodeNoSuchBucket) {
+			return "", cloudprovider.ErrNotFound
+		}
+		return "", err
+	}
+	loc := NormalizeLocation(sdk.StringValue(output.LocationConstraint))
+	cli.locations.set(name, loc)
+	return loc, nil
+}
+
+func (cli *SObjectStoreClient) CreateBucket(name string, location string, acl string) error {
+	return cli.CreateBucketWithHeaders(name, location, acl, nil)
+}
+
+// CreateBucketWithHeaders creates the bucket with the vendor specific headers
+// not modeled by S3, e.g. the storage class header of COS
+func (cli *SObjectStoreClient) CreateBucketWithHeaders(name string, location string, acl string, headers http.Header) error {
+	input := &s3.CreateBucketInput{Bucket: sdk.String(name)}
+	if len(acl) > 0 {
+		input.ACL = sdk.String(acl)
+	}
+	if len(location) > 0 && location != DEFAULT_LOCATION {
+		input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{
+			LocationConstraint: sdk.String(location),
+		}
+	}
+	req, _ := cli.client.CreateBucketRequest(input)
+	for k, v := range headers {
+		for _, val := range v {
+			req.HTTPRequest.Header.Add(k, val)
+		}
+	}
+	return req.Send()
+}
+
+func (cli *SObjectStoreClient) DeleteBucket(name string) error {
+	cli.locations.remove(name)
+	_, err := cli.client.DeleteBucket(&s3.DeleteBucketInput{Bucket: sdk.String(name)})
+	if err != nil && isAwsErrorCode(err, s3.ErrCodeNoSuchBucket) {
+		return nil
+	}
+	return err
+}
+
+func (cli *SObjectStoreClient) HeadBucket(name string) error {
+	_, err := cli.client.HeadBucket(&s3.HeadBucketInput{Bucket: sdk.String(name)})
+	if err != nil {
+		if isAwsErrorCode(err, s3.ErrCodeNoSuchBucket, "NotFound") {
+			return cloudprovider.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetBucketAcl maps the grants of a bucket to one of the canned acls
+func (cli *SObjectStoreClient) GetBucketAcl(name string) (string, error) {
+	output, err := cli.client.GetBucketAcl(&s3.GetBucketAclInput{Bucket: sdk.String(name)})
+	if err != nil {
+		return "", err
+	}
+	return grantsToAcl(output.Grants), nil
+}
+
+func grantsToAcl(grants []*s3.Grant) string {
+	allRead, allWrite, authRead := false, false, false
+	for _, grant := range grants {
+		if grant.Grantee == nil {
+			continue
+		}
+		uri := sdk.StringValue(grant.Grantee.URI)
+		perm := sdk.StringValue(grant.Permission)
+		switch {
+		case strings.HasSuffix(uri, GROUP_ALL_USERS):
+			switch perm {
+			case s3.PermissionRead:
+				allRead = true
+			case s3.PermissionWrite:
+				allWrite = true
+			case s3.PermissionFullControl:
+				allRead, allWrite = true, true
+			}
+		case strings.HasSuffix(uri, GROUP_AUTHENTICATED_USERS):
+			if perm == s3.PermissionRead || perm == s3.PermissionFullControl {
+				authRead = true
+			}
+		}
+	}
+	switch {
+	case allRead && allWrite:
+		return cloudprovider.ACL_PUBLIC_READ_WRITE
+	case allRead:
+		return cloudprovider.ACL_PUBLIC_READ
+	case authRead:
+		return cloudprovider.ACL_AUTHENTICATED_READ
+	}
+	return cloudprovider.ACL_PRIVATE
+}
+
+func (cli *SObjectStoreClient) SetBucketAcl(name string, acl string) error {
+	_, err := cli.client.PutBucketAcl(&s3.PutBucketAclInput{
+		Bucket: sdk.String(name),
+		ACL:    sdk.String(acl),
+	})
+	return err
+}
+
+// GetBucketStats sums up the size of the objects, S3 has no api to query
+// the usage of a bucket directly. It lists the whole bucket, so it is only
+// called on demand.
+func (cli *SObjectStoreClient) GetBucketStats(name string) (cloudprovider.SBucketStats, error) {
+	stats := cloudprovider.SBucketStats{}
+	err := cli.client.ListObjectsPages(&s3.ListObjectsInput{Bucket: sdk.String(name)},
+		func(page *s3.ListObjectsOutput, lastPage bool) bool {
+			for _, obj := range page.Contents {
+				stats.SizeBytes += sdk.Int64Value(obj.Size)
+				stats.ObjectCount += 1
+			}
+			return true
+		})
+	if err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+func (cli *SObjectStoreClient) GetBucketLifecycle(name string) ([]cloudprovider.SBucketLifecycleRule, error) {
+	output, err := cli.client.GetBucketLifecycleConfiguration(&s3.GetBucketLifecycleConfigurationInput{Bucket: sdk.String(name)})
+	if err != nil {
+		if isAwsErrorCode(err, "NoSuchLifecycleConfiguration") {
+			return []cloudprovider.SBucketLifecycleRule{}, nil
+		}
+		return nil, err
+	}
+	rules := make([]cloudprovider.SBucketLifecycleRule, 0, len(output.Rules))
+	for _, r := range output.Rules {
+		rule := cloudprovider.SBucketLifecycleRule{
+			ID:      sdk.StringValue(r.ID),
+			Prefix:  sdk.StringValue(r.Prefix),
+			Enabled: sdk.StringValue(r.Status) == s3.ExpirationStatusEnabled,
+		}
+		if r.Filter != nil && r.Filter.Prefix != nil {
+			rule.Prefix = *r.Filter.Prefix
+		}
+		if r.Expiration != nil {
+			rule.ExpirationDays = int(sdk.Int64Value(r.Expiration.Days))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// SetBucketLifecycle replaces the lifecycle rules of the bucket, an empty
+// rule list removes the lifecycle configuration
+func (cli *SObjectStoreClient) SetBucketLifecycle(name string, rules []cloudprovider.SBucketLifecycleRule) error {
+	if len(rules) == 0 {
+		_, err := cli.client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{Bucket: sdk.String(name)})
+		return err
+	}
+	conf := &s3.BucketLifecycleConfiguration{}
+	for i, rule := range rules {
+		r := &s3.LifecycleRule{
+			ID:     sdk.String(rule.ID),
+			Filter: &s3.LifecycleRuleFilter{Prefix: sdk.String(rule.Prefix)},
+			Status: sdk.String(s3.ExpirationStatusDisabled),
+		}
+		if len(rule.ID) == 0 {
+			r.ID = sdk.String(fmt.Sprintf("rule-%d", i))
+		}
+		if rule.Enabled {
+			r.Status = sdk.String(s3.ExpirationStatusEnabled)
+		}
+		if rule.ExpirationDays > 0 {
+			r.Expiration = &s3.LifecycleExpiration{Days: sdk.Int64(int64(rule.ExpirationDays))}
+		}
+		conf.Rules = append(conf.Rules, r)
+	}
+	_, err := cli.client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 sdk.String(name),
+		LifecycleConfiguration: conf,
+	})
+	return err
+}
+
+// ListObjects returns all the objects of the bucket with the key prefix
+func (cli *SObjectStoreClient) ListObjects(name string, prefix string) ([]SObjectInfo, error) {
+	ret := make([]SObjectInfo, 0)
+	input := &s3.ListObjectsV2Input{Bucket: sdk.String(name), Prefix: sdk.String(prefix)}
+	err := cli.client.ListObjectsV2Pages(input, func(output *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range output.Contents {
+			ret = append(ret, SObjectInfo{
+				Key:          sdk.StringValue(obj.Key),
+				Size:         sdk.Int64Value(obj.Size),
+				LastModified: sdk.TimeValue(obj.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		if isAwsErrorCode(err, s3.ErrCodeNoSuchBucket) {
+			return nil, cloudprovider.ErrNotFound
+		}
+		return nil, err
+	}
+	return ret, nil
+}
+
+// GetObject returns the content of the object, the caller should close it
+func (cli *SObjectStoreClient) GetObject(name string, key string) (io.ReadCloser, error) {
+	output, err := cli.client.GetObject(&s3.GetObjectInput{Bucket: sdk.String(name), Key: sdk.String(key)})
+	if err != nil {
+		if isAwsErrorCode(err, s3.ErrCodeNoSuchBucket, s3.ErrCodeNoSuchKey) {
+			return nil, cloudprovider.ErrNotFound
+		}
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (cli *SObjectStoreClient) PresignUrl(name string, method string, key string, expire time.Duration) (string, error) {
+	var req *request.Request
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		req, _ = cli.client.GetObjectRequest(&s3.GetObjectInput{Bucket: sdk.String(name), Key: sdk.String(key)})
+	case http.MethodPut:
+		req, _ = cli.client.PutObjectRequest(&s3.PutObjectInput{Bucket: sdk.String(name), Key: sdk.String(key)})
+	case http.MethodHead:
+		req, _ = cli.client.HeadObjectRequest(&s3.HeadObjectInput{Bucket: sdk.String(name), Key: sdk.String(key)})
+	case http.MethodDelete:
+		req, _ = cli.client.DeleteObjectRequest(&s3.DeleteObjectInput{Bucket: sdk.String(name), Key: sdk.String(key)})
+	default:
+		return "", fmt.Errorf("unsupported method %s", method)
+	}
+	return req.Presign(expire)
+}
+
+// BucketUrl returns the url to access the bucket
+func (cli *SObjectStoreClient) BucketUrl(name string) string {
+	endpoint := cli.client.Endpoint
+	scheme := "https"
+	if idx := strings.Index(endpoint, "://"); idx >= 0 {
+		scheme = endpoint[:idx]
+		endpoint = endpoint[idx+3:]
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if cli.pathStyle {
+		return fmt.Sprintf("%s://%s/%s", scheme, endpoint, name)
+	}
+	return fmt.Sprintf("%s://%s.%s", scheme, name, endpoint)
+}
@@ -0,0 +1,335 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// fakeS3Server is a tiny in-memory server speaking the path style S3
+// protocol, just enough for the bucket operations of the client
+type fakeS3Server struct {
+	lock    sync.Mutex
+	buckets map[string]*fakeBucket
+}
+
+type fakeBucket struct {
+	location  string
+	acl       string
+	lifecycle []byte
+	objects   map[string]int
+	createdAt time.Time
+}
+
+func newFakeS3Server() *httptest.Server {
+	srv := &fakeS3Server{buckets: map[string]*fakeBucket{}}
+	return httptest.NewServer(srv)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func (srv *fakeS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	path := strings.Trim(r.URL.Path, "/")
+	if len(path) == 0 {
+		type bucket struct {
+			Name         string
+			CreationDate string
+		}
+		result := struct {
+			XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+			Buckets []bucket `xml:"Buckets>Bucket"`
+		}{}
+		for name, b := range srv.buckets {
+			result.Buckets = append(result.Buckets, bucket{Name: name, CreationDate: b.createdAt.Format(time.RFC3339)})
+		}
+		xml.NewEncoder(w).Encode(result)
+		return
+	}
+	parts := strings.SplitN(path, "/", 2)
+	name := parts[0]
+	body, _ := ioutil.ReadAll(r.Body)
+	query := r.URL.Query()
+	b, exist := srv.buckets[name]
+	if !exist && !(r.Method == http.MethodPut && len(parts) == 1 && len(query) == 0) {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if len(parts) == 2 {
+		// object operations
+		switch r.Method {
+		case http.MethodPut:
+			b.objects[parts[1]] = len(body)
+		case http.MethodDelete:
+			delete(b.objects, parts[1])
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	switch {
+	case r.Method == http.MethodPut && len(query) == 0:
+		if exist {
+			writeS3Error(w, http.StatusConflict, "BucketAlreadyExists")
+			return
+		}
+		conf := struct {
+			LocationConstraint string
+		}{}
+		xml.Unmarshal(body, &conf)
+		acl := r.Header.Get("x-amz-acl")
+		if len(acl) == 0 {
+			acl = cloudprovider.ACL_PRIVATE
+		}
+		srv.buckets[name] = &fakeBucket{location: conf.LocationConstraint, acl: acl, objects: map[string]int{}, createdAt: time.Now()}
+	case r.Method == http.MethodHead:
+	case r.Method == http.MethodDelete && len(query) == 0:
+		delete(srv.buckets, name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && hasQuery(query, "location"):
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint>%s</LocationConstraint>`, b.location)
+	case r.Method == http.MethodPut && hasQuery(query, "acl"):
+		b.acl = r.Header.Get("x-amz-acl")
+	case r.Method == http.MethodGet && hasQuery(query, "acl"):
+		grants := ""
+		if b.acl == cloudprovider.ACL_PUBLIC_READ || b.acl == cloudprovider.ACL_PUBLIC_READ_WRITE {
+			grants += `<Grant><Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group"><URI>http://acs.amazonaws.com/groups/global/AllUsers</URI></Grantee><Permission>READ</Permission></Grant>`
+		}
+		if b.acl == cloudprovider.ACL_PUBLIC_READ_WRITE {
+			grants += `<Grant><Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group"><URI>http://acs.amazonaws.com/groups/global/AllUsers</URI></Grantee><Permission>WRITE</Permission></Grant>`
+		}
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><AccessControlPolicy><Owner><ID>owner</ID></Owner><AccessControlList>%s</AccessControlList></AccessControlPolicy>`, grants)
+	case r.Method == http.MethodPut && hasQuery(query, "lifecycle"):
+		b.lifecycle = body
+	case r.Method == http.MethodGet && hasQuery(query, "lifecycle"):
+		if len(b.lifecycle) == 0 {
+			writeS3Error(w, http.StatusNotFound, "NoSuchLifecycleConfiguration")
+			return
+		}
+		w.Write(b.lifecycle)
+	case r.Method == http.MethodDelete && hasQuery(query, "lifecycle"):
+		b.lifecycle = nil
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet:
+		contents := ""
+		for key, size := range b.objects {
+			contents += fmt.Sprintf("<Contents><Key>%s</Key><Size>%d</Size></Contents>", key, size)
+		}
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>%s</Name><IsTruncated>false</IsTruncated>%s</ListBucketResult>`, name, contents)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func hasQuery(query map[string][]string, key string) bool {
+	_, ok := query[key]
+	return ok
+}
+
+// newTestClient returns a client of the S3-compatible server given by
+// OBJECTSTORE_TEST_ENDPOINT, e.g. a local minio, or of an in-memory fake
+func newTestClient(t *testing.T) (*SObjectStoreClient, func()) {
+	endpoint := os.Getenv("OBJECTSTORE_TEST_ENDPOINT")
+	if len(endpoint) > 0 {
+		cli, err := NewObjectStoreClient(endpoint, "", os.Getenv("OBJECTSTORE_TEST_ACCESS_KEY"), os.Getenv("OBJECTSTORE_TEST_SECRET"), true)
+		if err != nil {
+			t.Fatalf("NewObjectStoreClient: %s", err)
+		}
+		return cli, func() {}
+	}
+	srv := newFakeS3Server()
+	cli, err := NewObjectStoreClient(srv.URL, "", "access", "secret", true)
+	if err != nil {
+		t.Fatalf("NewObjectStoreClient: %s", err)
+	}
+	return cli, srv.Close
+}
+
+func TestBucketLifecycle(t *testing.T) {
+	cli, cleanup := newTestClient(t)
+	defer cleanup()
+
+	name := fmt.Sprintf("objectstore-test-%d", time.Now().UnixNano())
+	err := cli.CreateBucket(name, "", cloudprovider.ACL_PRIVATE)
+	if err != nil {
+		t.Fatalf("CreateBucket: %s", err)
+	}
+	defer cli.DeleteBucket(name)
+
+	buckets, err := GetBuckets(cli, nil, DEFAULT_LOCATION)
+	if err != nil {
+		t.Fatalf("GetBuckets: %s", err)
+	}
+	found := false
+	for _, b := range buckets {
+		if b.GetGlobalId() == name {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("bucket %s not listed", name)
+	}
+
+	bucket, err := GetBucket(cli, nil, name)
+	if err != nil {
+		t.Fatalf("GetBucket: %s", err)
+	}
+	if acl := bucket.GetAcl(); acl != cloudprovider.ACL_PRIVATE {
+		t.Errorf("acl want %s got %s", cloudprovider.ACL_PRIVATE, acl)
+	}
+	err = bucket.SetAcl(cloudprovider.ACL_PUBLIC_READ)
+	if err != nil {
+		t.Fatalf("SetAcl: %s", err)
+	}
+	if acl := bucket.GetAcl(); acl != cloudprovider.ACL_PUBLIC_READ {
+		t.Errorf("acl want %s got %s", cloudprovider.ACL_PUBLIC_READ, acl)
+	}
+
+	rules, err := bucket.GetLifecycle()
+	if err != nil {
+		t.Fatalf("GetLifecycle: %s", err)
+	}
+	if len(rules) != 0 {
+		t.Errorf("want no lifecycle rules, got %d", len(rules))
+	}
+	err = bucket.SetLifecycle([]cloudprovider.SBucketLifecycleRule{
+		{ID: "expire-logs", Prefix: "logs/", Enabled: true, ExpirationDays: 7},
+	})
+	if err != nil {
+		t.Fatalf("SetLifecycle: %s", err)
+	}
+	rules, err = bucket.GetLifecycle()
+	if err != nil {
+		t.Fatalf("GetLifecycle: %s", err)
+	}
+	if len(rules) != 1 || rules[0].Prefix != "logs/" || !rules[0].Enabled || rules[0].ExpirationDays != 7 {
+		t.Errorf("unexpected lifecycle rules %#v", rules)
+	}
+	err = bucket.SetLifecycle(nil)
+	if err != nil {
+		t.Fatalf("SetLifecycle: %s", err)
+	}
+
+	url, err := bucket.GetTempUrl(http.MethodPut, "data/obj", time.Minute)
+	if err != nil {
+		t.Fatalf("GetTempUrl: %s", err)
+	}
+	req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader("hello"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("put object with presigned url: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("put object with presigned url: status %d", resp.StatusCode)
+	}
+	defer func() {
+		url, _ := bucket.GetTempUrl(http.MethodDelete, "data/obj", time.Minute)
+		req, _ := http.NewRequest(http.MethodDelete, url, nil)
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	stats, err := bucket.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %s", err)
+	}
+	if stats.ObjectCount != 1 || stats.SizeBytes != 5 {
+		t.Errorf("unexpected stats %#v", stats)
+	}
+}
+
+func TestAclAndLocation(t *testing.T) {
+	if acl := grantsToAcl(nil); acl != cloudprovider.ACL_PRIVATE {
+		t.Errorf("want %s got %s", cloudprovider.ACL_PRIVATE, acl)
+	}
+	if loc := NormalizeLocation(""); loc != DEFAULT_LOCATION {
+		t.Errorf("want %s got %s", DEFAULT_LOCATION, loc)
+	}
+	if loc := NormalizeLocation("EU"); loc != "eu-west-1" {
+		t.Errorf("want eu-west-1 got %s", loc)
+	}
+}
+
+func TestCreateBucketWithHeaders(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer srv.Close()
+	cli, err := NewObjectStoreClient(srv.URL, "", "access", "secret", true)
+	if err != nil {
+		t.Fatalf("NewObjectStoreClient: %s", err)
+	}
+	err = cli.CreateBucketWithHeaders("bucket", "", "", http.Header{"X-Cos-Storage-Class": []string{"STANDARD_IA"}})
+	if err != nil {
+		t.Fatalf("CreateBucketWithHeaders: %s", err)
+	}
+	if class := header.Get("x-cos-storage-class"); class != "STANDARD_IA" {
+		t.Errorf("storage class header want STANDARD_IA got %s", class)
+	}
+	if !strings.Contains(header.Get("Authorization"), "x-cos-storage-class") {
+		t.Errorf("storage class header is not signed: %s", header.Get("Authorization"))
+	}
+}
+
+func TestBucketLocationCache(t *testing.T) {
+	queries := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["location"]; ok {
+			queries += 1
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint>eu-west-2</LocationConstraint>`)
+			return
+		}
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><ListAllMyBucketsResult><Buckets><Bucket><Name>bucket</Name></Bucket></Buckets></ListAllMyBucketsResult>`)
+	}))
+	defer srv.Close()
+	cache := NewBucketLocationCache()
+	for _, region := range []string{"eu-west-1", "eu-west-2"} {
+		cli, err := NewObjectStoreClient(srv.URL, region, "access", "secret", true)
+		if err != nil {
+			t.Fatalf("NewObjectStoreClient: %s", err)
+		}
+		cli.SetLocationCache(cache)
+		buckets, err := GetBuckets(cli, nil, region)
+		if err != nil {
+			t.Fatalf("GetBuckets: %s", err)
+		}
+		if want := region == "eu-west-2"; want != (len(buckets) == 1) {
+			t.Errorf("region %s got %d buckets", region, len(buckets))
+		}
+	}
+	if queries != 1 {
+		t.Errorf("location queried %d times, want 1", queries)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"net/url"
+	"time"
+
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// GetICloudEvents reports the servers changed during [start, end) with the
+// changes-since filter of nova, which includes the deleted servers as well
+func (region *SRegion) GetICloudEvents(start time.Time, end time.Time) ([]cloudprovider.SCloudEvent, error) {
+	params := url.Values{}
+	params.Set("all_tenants", "True")
+	params.Set("changes-since", start.UTC().Format(time.RFC3339))
+	_, maxVersion, _ := region.GetVersion("compute")
+	_, resp, err := region.List("compute", "/servers/detail?"+params.Encode(), maxVersion, nil)
+	if err != nil {
+		return nil, err
+	}
+	instances := []SInstance{}
+	if err := resp.Unmarshal(&instances, "servers"); err != nil {
+		return nil, err
+	}
+	events := []cloudprovider.SCloudEvent{}
+	for i := range instances {
+		if !instances[i].Updated.Before(end) {
+			continue
+		}
+		event := cloudprovider.SCloudEvent{
+			ResourceType: cloudprovider.CLOUD_EVENT_RESOURCE_SERVER,
+			ResourceId:   instances[i].GetGlobalId(),
+			Action:       cloudprovider.CLOUD_EVENT_ACTION_UPDATE,
+			CreatedAt:    instances[i].Updated,
+		}
+		if utils.IsInStringArray(instances[i].Status, []string{INSTANCE_STATUS_DELETED, INSTANCE_STATUS_SOFT_DELETED}) {
+			event.Action = cloudprovider.CLOUD_EVENT_ACTION_DELETE
+		} else if !instances[i].Created.Before(start) {
+			event.Action = cloudprovider.CLOUD_EVENT_ACTION_CREATE
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -18,8 +18,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -101,6 +103,45 @@ func (cli *SOpenStackClient) fetchRegions() error {
 	return fmt.Errorf("failed to find right endpoint type")
 }
 
+func (cli *SOpenStackClient) getApiCaller() *cloudprovider.SApiCaller {
+	return cloudprovider.GetApiCaller(CLOUD_PROVIDER_OPENSTACK, fmt.Sprintf("%s/%s@%s", cli.project, cli.username, cli.authURL))
+}
+
+// openstackApiAction names the api after the service and the resource of
+// the url, e.g. GET compute /servers
+func openstackApiAction(service, method, url string) string {
+	resource := strings.Split(strings.Trim(strings.Split(url, "?")[0], "/"), "/")[0]
+	return fmt.Sprintf("%s %s /%s", method, service, resource)
+}
+
+func classifyOpenStackStatus(statusCode int) string {
+	switch {
+	case statusCode == 429:
+		return cloudprovider.API_ERROR_THROTTLED
+	case statusCode >= 500:
+		return cloudprovider.API_ERROR_SERVER
+	case statusCode >= 400:
+		return cloudprovider.API_ERROR_CLIENT
+	}
+	return ""
+}
+
+func classifyOpenStackError(err error) string {
+	switch e := err.(type) {
+	case net.Error:
+		return cloudprovider.API_ERROR_SERVER
+	case *httputils.JSONClientError:
+		// 499 is set by httputils when the endpoint is not reachable
+		if e.Code == 499 {
+			return cloudprovider.API_ERROR_SERVER
+		}
+		if errClass := classifyOpenStackStatus(e.Code); len(errClass) > 0 {
+			return errClass
+		}
+	}
+	return cloudprovider.API_ERROR_CLIENT
+}
+
 func (cli *SOpenStackClient) Request(region, service, method string, url string, microversion string, body jsonutils.JSONObject) (http.Header, jsonutils.JSONObject, error) {
 	header := http.Header{}
 	if len(microversion) > 0 {
@@ -108,12 +149,39 @@ func (cli *SOpenStackClient) Request(region, service, method string, url string,
 	}
 	ctx := context.Background()
 	session := cli.client.NewSession(ctx, region, "", cli.endpointType, cli.tokenCredential, "")
-	header, resp, err := session.JSONRequest(service, "", httputils.THttpMethod(method), url, header, body)
+	var respHeader http.Header
+	var resp jsonutils.JSONObject
+	err := cli.getApiCaller().Call(openstackApiAction(service, method, url), classifyOpenStackError, func() error {
+		var err error
+		respHeader, resp, err = session.JSONRequest(service, "", httputils.THttpMethod(method), url, header, body)
+		return err
+	})
 	if err != nil && body != nil {
 		uri, _ := session.GetServiceURL(service, "")
 		log.Errorf("microversion %s url: %s, params: %s", microversion, uri+url, body.PrettyString())
 	}
-	return header, resp, err
+	return respHeader, resp, err
+}
+
+// rawRequest limits the raw requests without retrying them as the body
+// may not be replayed, the response is left to the caller
+func (cli *SOpenStackClient) rawRequest(region, service, method string, url string, header http.Header, body io.Reader) (*http.Response, error) {
+	ctx := context.Background()
+	session := cli.client.NewSession(ctx, region, "", cli.endpointType, cli.tokenCredential, "")
+	caller := cli.getApiCaller()
+	action := openstackApiAction(service, method, url)
+	err := caller.Begin(action)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := session.RawRequest(service, "", httputils.THttpMethod(method), url, header, body)
+	if err != nil {
+		caller.End(action, start, classifyOpenStackError(err))
+		return nil, err
+	}
+	caller.End(action, start, classifyOpenStackStatus(resp.StatusCode))
+	return resp, nil
 }
 
 func (cli *SOpenStackClient) RawRequest(region, service, method string, url string, microversion string, body jsonutils.JSONObject) (*http.Response, error) {
@@ -121,13 +189,11 @@ func (cli *SOpenStackClient) RawRequest(region, service, method string, url stri
 	if len(microversion) > 0 {
 		header.Set("X-Openstack-Nova-API-Version", microversion)
 	}
-	ctx := context.Background()
-	session := cli.client.NewSession(ctx, region, "", cli.endpointType, cli.tokenCredential, "")
 	data := strings.NewReader("")
 	if body != nil {
 		data = strings.NewReader(body.String())
 	}
-	return session.RawRequest(service, "", httputils.THttpMethod(method), url, header, data)
+	return cli.rawRequest(region, service, method, url, header, data)
 }
 
 func (cli *SOpenStackClient) StreamRequest(region, service, method string, url string, microversion string, body io.Reader) (*http.Response, error) {
@@ -136,9 +202,7 @@ func (cli *SOpenStackClient) StreamRequest(region, service, method string, url s
 		header.Set("X-Openstack-Nova-API-Version", microversion)
 	}
 	header.Set("Content-Type", "application/octet-stream")
-	ctx := context.Background()
-	session := cli.client.NewSession(ctx, region, "", cli.endpointType, cli.tokenCredential, "")
-	return session.RawRequest(service, "", httputils.THttpMethod(method), url, header, body)
+	return cli.rawRequest(region, service, method, url, header, body)
 }
 
 func (cli *SOpenStackClient) getVersion(region string, service string) (string, string, error) {
@@ -153,7 +217,12 @@ func (cli *SOpenStackClient) getVersion(region string, service string) (string,
 	if strings.Index(uri, telnetID) > 0 {
 		url = uri[0:strings.Index(uri, telnetID)]
 	}
-	_, resp, err := session.JSONRequest(url, "", "GET", "/", nil, nil)
+	var resp jsonutils.JSONObject
+	err = cli.getApiCaller().Call(openstackApiAction(service, "GET", "/"), classifyOpenStackError, func() error {
+		var err error
+		_, resp, err = session.JSONRequest(url, "", "GET", "/", nil, nil)
+		return err
+	})
 	if err != nil {
 		return "", "", err
 	}
@@ -133,6 +133,11 @@ func (vpc *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
 	return rts, nil
 }
 
+func (vpc *SVpc) GetINatGateways() ([]cloudprovider.ICloudNatGateway, error) {
+	nats := []cloudprovider.ICloudNatGateway{}
+	return nats, nil
+}
+
 func (vpc *SVpc) fetchWires() error {
 	if len(vpc.region.izones) == 0 {
 		if err := vpc.region.fetchZones(); err != nil {
@@ -0,0 +1,113 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qcloud
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SBillDetailComponent struct {
+	ComponentCodeName string
+	ItemCodeName      string
+	RealCost          string
+}
+
+type SBillDetail struct {
+	BusinessCode     string
+	BusinessCodeName string
+	ResourceId       string
+	RegionId         string
+	ProjectId        int
+	ProjectName      string
+	ComponentSet     []SBillDetailComponent
+}
+
+// qcloud business codes of the resources managed locally
+var billBusinessResourceTypes = map[string]string{
+	"p_cvm": cloudprovider.CLOUD_BILL_RESOURCE_SERVER,
+	"p_cbs": cloudprovider.CLOUD_BILL_RESOURCE_DISK,
+	"p_eip": cloudprovider.CLOUD_BILL_RESOURCE_EIP,
+	"p_clb": cloudprovider.CLOUD_BILL_RESOURCE_LOADBALANCER,
+	"p_nat": cloudprovider.CLOUD_BILL_RESOURCE_NATGATEWAY,
+	"p_cos": cloudprovider.CLOUD_BILL_RESOURCE_BUCKET,
+}
+
+func (detail *SBillDetail) getRealCost() float64 {
+	cost := 0.0
+	for _, component := range detail.ComponentSet {
+		amount, err := strconv.ParseFloat(component.RealCost, 64)
+		if err != nil {
+			log.Errorf("invalid real cost %s of %s", component.RealCost, detail.ResourceId)
+			continue
+		}
+		cost += amount
+	}
+	return cost
+}
+
+func (client *SQcloudClient) DescribeBillDetail(day time.Time, offset int, limit int) ([]SBillDetail, error) {
+	params := make(map[string]string)
+	params["Offset"] = fmt.Sprintf("%d", offset)
+	params["Limit"] = fmt.Sprintf("%d", limit)
+	params["PeriodType"] = "byUsedTime"
+	params["Month"] = day.Format("2006-01")
+	params["BeginTime"] = day.Format("2006-01-02") + " 00:00:00"
+	params["EndTime"] = day.Format("2006-01-02") + " 23:59:59"
+	body, err := client.billingRequest("DescribeBillDetail", params)
+	if err != nil {
+		log.Errorf("DescribeBillDetail fail %s", err)
+		return nil, err
+	}
+	details := make([]SBillDetail, 0)
+	err = body.Unmarshal(&details, "DetailSet")
+	if err != nil {
+		log.Errorf("Unmarshal fail %s", err)
+		return nil, err
+	}
+	return details, nil
+}
+
+func (client *SQcloudClient) GetCloudBillItems(query cloudprovider.SCloudBillQuery) ([]cloudprovider.SCloudBillItem, error) {
+	day := cloudprovider.BillDate(query.Day)
+	items := make([]cloudprovider.SCloudBillItem, 0)
+	for {
+		details, err := client.DescribeBillDetail(day, len(items), 100)
+		if err != nil {
+			return nil, err
+		}
+		for _, detail := range details {
+			items = append(items, cloudprovider.SCloudBillItem{
+				ResourceType: billBusinessResourceTypes[detail.BusinessCode],
+				ResourceId:   detail.ResourceId,
+				ProjectId:    fmt.Sprintf("%d", detail.ProjectId),
+				RegionId:     detail.RegionId,
+				Product:      detail.BusinessCode,
+				Amount:       detail.getRealCost(),
+				Currency:     "CNY",
+				UsageDate:    day,
+			})
+		}
+		if len(details) < 100 {
+			break
+		}
+	}
+	return cloudprovider.MergeCloudBillItems(items), nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qcloud
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/objectstore"
+)
+
+const (
+	// the storage class of a bucket is set by the header on creation,
+	// which is not part of the S3 protocol
+	COS_HEADER_STORAGE_CLASS = "x-cos-storage-class"
+)
+
+var (
+	COS_STORAGE_CLASSES = []string{
+		"STANDARD",
+		"STANDARD_IA",
+		"ARCHIVE",
+		"MAZ_STANDARD",
+		"MAZ_STANDARD_IA",
+	}
+)
+
+// COS speaks the S3 protocol at the regional endpoint
+// https://cloud.tencent.com/document/product/436/37421
+func (self *SRegion) getBucketClient() (*objectstore.SObjectStoreClient, error) {
+	if self.bucketClient == nil {
+		endpoint := fmt.Sprintf("https://cos.%s.myqcloud.com", self.Region)
+		cli, err := objectstore.NewObjectStoreClient(endpoint, self.Region, self.client.SecretID, self.client.SecretKey, false)
+		if err != nil {
+			return nil, err
+		}
+		cli.SetLocationCache(self.client.bucketLocations)
+		self.bucketClient = cli
+	}
+	return self.bucketClient, nil
+}
+
+// cosBucketName appends the appid to the bucket name as required by COS
+func (self *SRegion) cosBucketName(name string) string {
+	if len(self.client.AppID) == 0 || strings.HasSuffix(name, "-"+self.client.AppID) {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", name, self.client.AppID)
+}
+
+func (self *SRegion) GetIBuckets() ([]cloudprovider.ICloudBucket, error) {
+	cli, err := self.getBucketClient()
+	if err != nil {
+		return nil, err
+	}
+	return objectstore.GetBuckets(cli, self, self.Region)
+}
+
+func (self *SRegion) GetIBucketById(name string) (cloudprovider.ICloudBucket, error) {
+	cli, err := self.getBucketClient()
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := objectstore.GetBucket(cli, self, self.cosBucketName(name))
+	if err != nil {
+		return nil, err
+	}
+	if bucket.GetLocation() != self.Region {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return bucket, nil
+}
+
+func (self *SRegion) CreateIBucket(name string, storageClass string, acl string) (cloudprovider.ICloudBucket, error) {
+	cli, err := self.getBucketClient()
+	if err != nil {
+		return nil, err
+	}
+	headers := http.Header{}
+	if len(storageClass) > 0 {
+		if !utils.IsInStringArray(storageClass, COS_STORAGE_CLASSES) {
+			return nil, fmt.Errorf("unsupported storage class %s, should be one of %s", storageClass, strings.Join(COS_STORAGE_CLASSES, ","))
+		}
+		headers.Set(COS_HEADER_STORAGE_CLASS, storageClass)
+	}
+	name = self.cosBucketName(name)
+	err = cli.CreateBucketWithHeaders(name, "", acl, headers)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetIBucketById(name)
+}
+
+func (self *SRegion) DeleteIBucket(name string) error {
+	cli, err := self.getBucketClient()
+	if err != nil {
+		return err
+	}
+	return cli.DeleteBucket(self.cosBucketName(name))
+}
@@ -0,0 +1,226 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// DNSPod 的域名只有2017版本的云解析接口, 均为公网解析
+type SDnsRecord struct {
+	RecordId string
+	Name     string
+	Type     string
+	Value    string
+	Ttl      int
+	Line     string
+	Enabled  bool
+}
+
+func (self *SDnsRecord) GetGlobalId() string {
+	return self.RecordId
+}
+
+func (self *SDnsRecord) GetDnsName() string {
+	return self.Name
+}
+
+func (self *SDnsRecord) GetDnsType() string {
+	return self.Type
+}
+
+func (self *SDnsRecord) GetDnsValue() string {
+	return self.Value
+}
+
+func (self *SDnsRecord) GetTtl() int {
+	return self.Ttl
+}
+
+type SDnsZone struct {
+	client *SQcloudClient
+
+	DomainId    string
+	DomainName  string
+	RecordCount int
+}
+
+func (self *SDnsZone) GetId() string {
+	return self.DomainId
+}
+
+func (self *SDnsZone) GetName() string {
+	return self.DomainName
+}
+
+func (self *SDnsZone) GetGlobalId() string {
+	return self.DomainId
+}
+
+func (self *SDnsZone) GetStatus() string {
+	return api.DNS_ZONE_STATUS_AVAILABLE
+}
+
+func (self *SDnsZone) Refresh() error {
+	return nil
+}
+
+func (self *SDnsZone) IsEmulated() bool {
+	return false
+}
+
+func (self *SDnsZone) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SDnsZone) GetZoneType() string {
+	return cloudprovider.DNS_ZONE_TYPE_PUBLIC
+}
+
+func (self *SDnsZone) GetIDnsRecords() ([]cloudprovider.ICloudDnsRecord, error) {
+	records := make([]SDnsRecord, 0)
+	for {
+		parts, total, err := self.client.GetDnsRecords(self.DomainName, len(records), 100)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, parts...)
+		if len(records) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	ret := make([]cloudprovider.ICloudDnsRecord, 0, len(records))
+	for i := range records {
+		// 只同步默认线路上启用的记录, 其它线路的记录没有对应的本地记录
+		if records[i].Line != "默认" || !records[i].Enabled {
+			continue
+		}
+		ret = append(ret, &records[i])
+	}
+	return ret, nil
+}
+
+func (self *SDnsZone) AddDnsRecord(record cloudprovider.SDnsRecord) (string, error) {
+	params := make(map[string]string)
+	params["domain"] = self.DomainName
+	params["subDomain"] = record.Name
+	params["recordType"] = record.Type
+	params["recordLine"] = "默认"
+	params["value"] = record.Value
+	if record.Ttl > 0 {
+		params["ttl"] = fmt.Sprintf("%d", record.Ttl)
+	}
+	body, err := self.client.cnsRequest("RecordCreate", params)
+	if err != nil {
+		return "", err
+	}
+	return body.GetString("data", "record", "id")
+}
+
+func (self *SDnsZone) RemoveDnsRecord(recordId string) error {
+	params := make(map[string]string)
+	params["domain"] = self.DomainName
+	params["recordId"] = recordId
+	_, err := self.client.cnsRequest("RecordDelete", params)
+	return err
+}
+
+func (client *SQcloudClient) GetDnsZones(offset int, limit int) ([]SDnsZone, int, error) {
+	if limit > 100 || limit <= 0 {
+		limit = 100
+	}
+	params := make(map[string]string)
+	params["offset"] = fmt.Sprintf("%d", offset)
+	params["length"] = fmt.Sprintf("%d", limit)
+
+	body, err := client.cnsRequest("DomainList", params)
+	if err != nil {
+		log.Errorf("GetDnsZones fail %s", err)
+		return nil, 0, err
+	}
+	domains, err := body.GetArray("data", "domains")
+	if err != nil {
+		return nil, 0, err
+	}
+	zones := make([]SDnsZone, len(domains))
+	for i := range domains {
+		zones[i].client = client
+		zones[i].DomainId, _ = domains[i].GetString("id")
+		zones[i].DomainName, _ = domains[i].GetString("name")
+		count, _ := domains[i].Int("records")
+		zones[i].RecordCount = int(count)
+	}
+	total, _ := body.Int("data", "info", "domain_total")
+	return zones, int(total), nil
+}
+
+func (client *SQcloudClient) GetDnsRecords(domain string, offset int, limit int) ([]SDnsRecord, int, error) {
+	if limit > 100 || limit <= 0 {
+		limit = 100
+	}
+	params := make(map[string]string)
+	params["domain"] = domain
+	params["offset"] = fmt.Sprintf("%d", offset)
+	params["length"] = fmt.Sprintf("%d", limit)
+
+	body, err := client.cnsRequest("RecordList", params)
+	if err != nil {
+		log.Errorf("GetDnsRecords fail %s", err)
+		return nil, 0, err
+	}
+	items, err := body.GetArray("data", "records")
+	if err != nil {
+		return nil, 0, err
+	}
+	records := make([]SDnsRecord, len(items))
+	for i := range items {
+		records[i].RecordId, _ = items[i].GetString("id")
+		records[i].Name, _ = items[i].GetString("name")
+		records[i].Type, _ = items[i].GetString("type")
+		records[i].Value, _ = items[i].GetString("value")
+		records[i].Line, _ = items[i].GetString("line")
+		ttl, _ := items[i].Int("ttl")
+		records[i].Ttl = int(ttl)
+		enabled, _ := items[i].Int("enabled")
+		records[i].Enabled = enabled == 1
+	}
+	total, _ := body.Int("data", "info", "record_total")
+	return records, int(total), nil
+}
+
+func (client *SQcloudClient) GetIDnsZones() ([]cloudprovider.ICloudDnsZone, error) {
+	zones := make([]SDnsZone, 0)
+	for {
+		parts, total, err := client.GetDnsZones(len(zones), 100)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, parts...)
+		if len(zones) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	ret := make([]cloudprovider.ICloudDnsZone, len(zones))
+	for i := range zones {
+		ret[i] = &zones[i]
+	}
+	return ret, nil
+}
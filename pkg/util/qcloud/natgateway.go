@@ -0,0 +1,389 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qcloud
+
+import (
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+type SPublicIpAddress struct {
+	AddressId       string
+	PublicIpAddress string
+	IsBlocked       bool
+}
+
+type SDestinationIpPortTranslationNatRule struct {
+	nat *SNatGateway
+
+	IpProtocol       string
+	PublicIpAddress  string
+	PublicPort       int
+	PrivateIpAddress string
+	PrivatePort      int
+	Description      string
+}
+
+type SSourceIpTranslationNatRule struct {
+	nat *SNatGateway
+
+	NatGatewaySnatId  string
+	ResourceId        string
+	ResourceType      string
+	PrivateIpAddress  string
+	PublicIpAddresses []string
+	Description       string
+}
+
+type SNatGateway struct {
+	vpc *SVpc
+
+	NatGatewayId                           string
+	NatGatewayName                         string
+	CreatedTime                            time.Time
+	State                                  string
+	InternetMaxBandwidthOut                int
+	MaxConcurrentConnection                int
+	PublicIpAddressSet                     []SPublicIpAddress
+	NetworkState                           string
+	DestinationIpPortTranslationNatRuleSet []SDestinationIpPortTranslationNatRule
+	VpcId                                  string
+	Zone                                   string
+}
+
+func (self *SRegion) GetNatGateways(vpcId string, natGatewayId string, offset int, limit int) ([]SNatGateway, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["Limit"] = fmt.Sprintf("%d", limit)
+	params["Offset"] = fmt.Sprintf("%d", offset)
+	idx := 0
+	if len(vpcId) > 0 {
+		params[fmt.Sprintf("Filters.%d.Name", idx)] = "vpc-id"
+		params[fmt.Sprintf("Filters.%d.Values.0", idx)] = vpcId
+		idx++
+	}
+	if len(natGatewayId) > 0 {
+		params[fmt.Sprintf("Filters.%d.Name", idx)] = "nat-gateway-id"
+		params[fmt.Sprintf("Filters.%d.Values.0", idx)] = natGatewayId
+	}
+	body, err := self.vpcRequest("DescribeNatGateways", params)
+	if err != nil {
+		log.Errorf("DescribeNatGateways fail %s", err)
+		return nil, 0, err
+	}
+	nats := make([]SNatGateway, 0)
+	err = body.Unmarshal(&nats, "NatGatewaySet")
+	if err != nil {
+		return nil, 0, err
+	}
+	total, _ := body.Float("TotalCount")
+	return nats, int(total), nil
+}
+
+func (self *SRegion) GetNatSourceIpTranslationNatRules(natGatewayId string, offset int, limit int) ([]SSourceIpTranslationNatRule, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["Limit"] = fmt.Sprintf("%d", limit)
+	params["Offset"] = fmt.Sprintf("%d", offset)
+	params["NatGatewayId"] = natGatewayId
+	body, err := self.vpcRequest("DescribeNatGatewaySourceIpTranslationNatRules", params)
+	if err != nil {
+		log.Errorf("DescribeNatGatewaySourceIpTranslationNatRules fail %s", err)
+		return nil, 0, err
+	}
+	rules := make([]SSourceIpTranslationNatRule, 0)
+	err = body.Unmarshal(&rules, "SourceIpTranslationNatRuleSet")
+	if err != nil {
+		return nil, 0, err
+	}
+	total, _ := body.Float("TotalCount")
+	return rules, int(total), nil
+}
+
+func (nat *SNatGateway) GetId() string {
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGateway) GetName() string {
+	if len(nat.NatGatewayName) > 0 {
+		return nat.NatGatewayName
+	}
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGateway) GetGlobalId() string {
+	return nat.NatGatewayId
+}
+
+func (nat *SNatGateway) GetStatus() string {
+	switch nat.State {
+	case "PENDING":
+		return api.NAT_STATUS_ALLOCATE
+	case "AVAILABLE":
+		return api.NAT_STATUS_AVAILABLE
+	case "UPDATING":
+		return api.NAT_STATUS_DEPLOYING
+	case "DELETING":
+		return api.NAT_STATUS_DELETING
+	case "FAILED":
+		return api.NAT_STATUS_FAILED
+	default:
+		return api.NAT_STATUS_UNKNOWN
+	}
+}
+
+func (nat *SNatGateway) Refresh() error {
+	nats, total, err := nat.vpc.region.GetNatGateways(nat.VpcId, nat.NatGatewayId, 0, 1)
+	if err != nil {
+		return err
+	}
+	if total != 1 || len(nats) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(nat, nats[0])
+}
+
+func (nat *SNatGateway) IsEmulated() bool {
+	return false
+}
+
+func (nat *SNatGateway) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+// qcloud nat gateway spec is determined by the max concurrent connections
+func (nat *SNatGateway) GetNatSpec() string {
+	switch {
+	case nat.MaxConcurrentConnection <= 1000000:
+		return api.NAT_SPEC_SMALL
+	case nat.MaxConcurrentConnection <= 3000000:
+		return api.NAT_SPEC_MIDDLE
+	default:
+		return api.NAT_SPEC_LARGE
+	}
+}
+
+func (nat *SNatGateway) GetDescription() string {
+	return ""
+}
+
+func (nat *SNatGateway) GetINatSEntries() ([]cloudprovider.ICloudNatSEntry, error) {
+	rules := make([]SSourceIpTranslationNatRule, 0)
+	for {
+		parts, total, err := nat.vpc.region.GetNatSourceIpTranslationNatRules(nat.NatGatewayId, len(rules), 50)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, parts...)
+		if len(rules) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	ientries := make([]cloudprovider.ICloudNatSEntry, len(rules))
+	for i := 0; i < len(rules); i++ {
+		rules[i].nat = nat
+		ientries[i] = &rules[i]
+	}
+	return ientries, nil
+}
+
+func (nat *SNatGateway) GetINatDEntries() ([]cloudprovider.ICloudNatDEntry, error) {
+	ientries := make([]cloudprovider.ICloudNatDEntry, len(nat.DestinationIpPortTranslationNatRuleSet))
+	for i := 0; i < len(nat.DestinationIpPortTranslationNatRuleSet); i++ {
+		nat.DestinationIpPortTranslationNatRuleSet[i].nat = nat
+		ientries[i] = &nat.DestinationIpPortTranslationNatRuleSet[i]
+	}
+	return ientries, nil
+}
+
+func (nat *SNatGateway) CreateINatSEntry(rule cloudprovider.SNatSRule) (cloudprovider.ICloudNatSEntry, error) {
+	if len(rule.NetworkID) == 0 {
+		return nil, fmt.Errorf("qcloud snat rule requires a subnet")
+	}
+	params := make(map[string]string)
+	params["NatGatewayId"] = nat.NatGatewayId
+	params["SourceIpTranslationNatRules.0.ResourceId"] = rule.NetworkID
+	params["SourceIpTranslationNatRules.0.ResourceType"] = "SUBNET"
+	params["SourceIpTranslationNatRules.0.PublicIpAddresses.0"] = rule.ExternalIP
+	params["SourceIpTranslationNatRules.0.Description"] = rule.Name
+	_, err := nat.vpc.region.vpcRequest("CreateNatGatewaySourceIpTranslationNatRule", params)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := nat.GetINatSEntries()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].GetNetworkId() == rule.NetworkID {
+			return entries[i], nil
+		}
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (nat *SNatGateway) CreateINatDEntry(rule cloudprovider.SNatDRule) (cloudprovider.ICloudNatDEntry, error) {
+	entry := SDestinationIpPortTranslationNatRule{
+		nat:              nat,
+		IpProtocol:       rule.Protocol,
+		PublicIpAddress:  rule.ExternalIP,
+		PublicPort:       rule.ExternalPort,
+		PrivateIpAddress: rule.InternalIP,
+		PrivatePort:      rule.InternalPort,
+		Description:      rule.Name,
+	}
+	params := entry.params()
+	params["DestinationIpPortTranslationNatRules.0.Description"] = entry.Description
+	_, err := nat.vpc.region.vpcRequest("CreateNatGatewayDestinationIpPortTranslationNatRule", params)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (rule *SSourceIpTranslationNatRule) GetId() string {
+	return rule.NatGatewaySnatId
+}
+
+func (rule *SSourceIpTranslationNatRule) GetName() string {
+	if len(rule.Description) > 0 {
+		return rule.Description
+	}
+	return rule.NatGatewaySnatId
+}
+
+func (rule *SSourceIpTranslationNatRule) GetGlobalId() string {
+	return rule.NatGatewaySnatId
+}
+
+func (rule *SSourceIpTranslationNatRule) GetStatus() string {
+	return api.NAT_STATUS_AVAILABLE
+}
+
+func (rule *SSourceIpTranslationNatRule) Refresh() error {
+	return nil
+}
+
+func (rule *SSourceIpTranslationNatRule) IsEmulated() bool {
+	return false
+}
+
+func (rule *SSourceIpTranslationNatRule) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (rule *SSourceIpTranslationNatRule) GetIP() string {
+	if len(rule.PublicIpAddresses) > 0 {
+		return rule.PublicIpAddresses[0]
+	}
+	return ""
+}
+
+func (rule *SSourceIpTranslationNatRule) GetSourceCIDR() string {
+	return ""
+}
+
+func (rule *SSourceIpTranslationNatRule) GetNetworkId() string {
+	if rule.ResourceType == "SUBNET" {
+		return rule.ResourceId
+	}
+	return ""
+}
+
+func (rule *SSourceIpTranslationNatRule) Delete() error {
+	params := make(map[string]string)
+	params["NatGatewayId"] = rule.nat.NatGatewayId
+	params["NatGatewaySnatIds.0"] = rule.NatGatewaySnatId
+	_, err := rule.nat.vpc.region.vpcRequest("DeleteNatGatewaySourceIpTranslationNatRule", params)
+	return err
+}
+
+// qcloud dnat rules have no id, they are identified by the protocol and the public address
+func (rule *SDestinationIpPortTranslationNatRule) GetId() string {
+	return fmt.Sprintf("%s/%s/%s/%d", rule.nat.NatGatewayId, rule.IpProtocol, rule.PublicIpAddress, rule.PublicPort)
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetName() string {
+	if len(rule.Description) > 0 {
+		return rule.Description
+	}
+	return fmt.Sprintf("%s/%s/%d", rule.IpProtocol, rule.PublicIpAddress, rule.PublicPort)
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetGlobalId() string {
+	return rule.GetId()
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetStatus() string {
+	return api.NAT_STATUS_AVAILABLE
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) Refresh() error {
+	return nil
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) IsEmulated() bool {
+	return false
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetIpProtocol() string {
+	return rule.IpProtocol
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetExternalIp() string {
+	return rule.PublicIpAddress
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetExternalPort() int {
+	return rule.PublicPort
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetInternalIp() string {
+	return rule.PrivateIpAddress
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) GetInternalPort() int {
+	return rule.PrivatePort
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) params() map[string]string {
+	params := make(map[string]string)
+	params["NatGatewayId"] = rule.nat.NatGatewayId
+	params["DestinationIpPortTranslationNatRules.0.IpProtocol"] = rule.IpProtocol
+	params["DestinationIpPortTranslationNatRules.0.PublicIpAddress"] = rule.PublicIpAddress
+	params["DestinationIpPortTranslationNatRules.0.PublicPort"] = fmt.Sprintf("%d", rule.PublicPort)
+	params["DestinationIpPortTranslationNatRules.0.PrivateIpAddress"] = rule.PrivateIpAddress
+	params["DestinationIpPortTranslationNatRules.0.PrivatePort"] = fmt.Sprintf("%d", rule.PrivatePort)
+	return params
+}
+
+func (rule *SDestinationIpPortTranslationNatRule) Delete() error {
+	_, err := rule.nat.vpc.region.vpcRequest("DeleteNatGatewayDestinationIpPortTranslationNatRule", rule.params())
+	return err
+}
@@ -165,3 +165,11 @@ func (self *SQcloudProvider) GetBalance() (float64, string, error) {
 func (self *SQcloudProvider) GetIProjects() ([]cloudprovider.ICloudProject, error) {
 	return self.client.GetIProjects()
 }
+
+func (self *SQcloudProvider) GetCloudBillItems(query cloudprovider.SCloudBillQuery) ([]cloudprovider.SCloudBillItem, error) {
+	return self.client.GetCloudBillItems(query)
+}
+
+func (self *SQcloudProvider) GetIDnsZones() ([]cloudprovider.ICloudDnsZone, error) {
+	return self.client.GetIDnsZones()
+}
@@ -18,7 +18,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
@@ -30,6 +29,7 @@ import (
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/objectstore"
 )
 
 const (
@@ -51,11 +51,15 @@ type SQcloudClient struct {
 	SecretKey    string
 	iregions     []cloudprovider.ICloudRegion
 
+	// the buckets of all the regions are listed in every region
+	bucketLocations *objectstore.SBucketLocationCache
+
 	Debug bool
 }
 
 func NewQcloudClient(providerId string, providerName string, secretID string, secretKey string, isDebug bool) (*SQcloudClient, error) {
 	client := SQcloudClient{providerId: providerId, providerName: providerName, SecretID: secretID, SecretKey: secretKey, Debug: isDebug}
+	client.bucketLocations = objectstore.NewBucketLocationCache()
 	if account := strings.Split(secretID, "/"); len(account) == 2 {
 		client.SecretID = account[0]
 		client.AppID = account[1]
@@ -79,47 +83,59 @@ func apiDomain(product string, params map[string]string) string {
 	}
 }
 
-func jsonRequest(client *common.Client, apiName string, params map[string]string, debug bool, retry bool) (jsonutils.JSONObject, error) {
+func jsonRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool, retry bool) (jsonutils.JSONObject, error) {
 	domain := apiDomain("cvm", params)
-	return _jsonRequest(client, domain, QCLOUD_API_VERSION, apiName, params, debug, retry)
+	return _jsonRequest(caller, client, domain, QCLOUD_API_VERSION, apiName, params, debug, retry)
 }
 
-func vpcRequest(client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+func vpcRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	domain := apiDomain("vpc", params)
-	return _jsonRequest(client, domain, QCLOUD_API_VERSION, apiName, params, debug, true)
+	return _jsonRequest(caller, client, domain, QCLOUD_API_VERSION, apiName, params, debug, true)
 }
 
-func cbsRequest(client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+func cbsRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	domain := apiDomain("cbs", params)
-	return _jsonRequest(client, domain, QCLOUD_API_VERSION, apiName, params, debug, true)
+	return _jsonRequest(caller, client, domain, QCLOUD_API_VERSION, apiName, params, debug, true)
 }
 
-func accountRequest(client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+func accountRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	domain := "account.api.qcloud.com"
-	return _phpJsonRequest(client, &wssJsonResponse{}, domain, "/v2/index.php", "", apiName, params, debug)
+	return _phpJsonRequest(caller, client, &wssJsonResponse{}, domain, "/v2/index.php", "", apiName, params, debug)
 }
 
 // loadbalancer服务 api 3.0
-func clbRequest(client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+func clbRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	domain := apiDomain("clb", params)
-	return _jsonRequest(client, domain, QCLOUD_CLB_API_VERSION, apiName, params, debug, true)
+	return _jsonRequest(caller, client, domain, QCLOUD_CLB_API_VERSION, apiName, params, debug, true)
 }
 
 // loadbalancer服务 api 2017
-func lbRequest(client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+func lbRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	domain := "lb.api.qcloud.com"
-	return _phpJsonRequest(client, &lbJsonResponse{}, domain, "/v2/index.php", "", apiName, params, debug)
+	return _phpJsonRequest(caller, client, &lbJsonResponse{}, domain, "/v2/index.php", "", apiName, params, debug)
+}
+
+// vpc服务 api 2017, 对等连接没有3.0版本的接口
+func vpc2017Request(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+	domain := "vpc.api.qcloud.com"
+	return _phpJsonRequest(caller, client, &lbJsonResponse{}, domain, "/v2/index.php", "", apiName, params, debug)
+}
+
+// 云解析服务 api 2017, DNSPod 的域名没有3.0版本的接口
+func cnsRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+	domain := "cns.api.qcloud.com"
+	return _phpJsonRequest(caller, client, &lbJsonResponse{}, domain, "/v2/index.php", "", apiName, params, debug)
 }
 
 // ssl 证书服务
-func wssRequest(client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+func wssRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	domain := "wss.api.qcloud.com"
-	return _phpJsonRequest(client, &wssJsonResponse{}, domain, "/v2/index.php", "", apiName, params, debug)
+	return _phpJsonRequest(caller, client, &wssJsonResponse{}, domain, "/v2/index.php", "", apiName, params, debug)
 }
 
-func billingRequest(client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+func billingRequest(caller *cloudprovider.SApiCaller, client *common.Client, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	domain := "billing.tencentcloudapi.com"
-	return _jsonRequest(client, domain, QCLOUD_BILLING_API_VERSION, apiName, params, debug, true)
+	return _jsonRequest(caller, client, domain, QCLOUD_BILLING_API_VERSION, apiName, params, debug, true)
 }
 
 // ============phpJsonRequest============
@@ -219,7 +235,7 @@ func (r *QcloudResponse) GetResponse() *interface{} {
 	return r.Response
 }
 
-func _jsonRequest(client *common.Client, domain string, version string, apiName string, params map[string]string, debug bool, retry bool) (jsonutils.JSONObject, error) {
+func _jsonRequest(caller *cloudprovider.SApiCaller, client *common.Client, domain string, version string, apiName string, params map[string]string, debug bool, retry bool) (jsonutils.JSONObject, error) {
 	req := &tchttp.BaseRequest{}
 	if region, ok := params["Region"]; ok {
 		client = client.Init(region)
@@ -239,12 +255,12 @@ func _jsonRequest(client *common.Client, domain string, version string, apiName
 	resp := &QcloudResponse{
 		BaseResponse: &tchttp.BaseResponse{},
 	}
-	return _baseJsonRequest(client, req, resp, debug, retry)
+	return _baseJsonRequest(caller, client, req, resp, debug, retry)
 }
 
 // 老版本腾讯云api。 适用于类似 https://cvm.api.qcloud.com/v2/index.php 这样的带/v2/index.php路径的接口
 // todo: 添加自定义response参数
-func _phpJsonRequest(client *common.Client, resp qcloudResponse, domain string, path string, version string, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
+func _phpJsonRequest(caller *cloudprovider.SApiCaller, client *common.Client, resp qcloudResponse, domain string, path string, version string, apiName string, params map[string]string, debug bool) (jsonutils.JSONObject, error) {
 	req := &phpJsonRequest{Path: path}
 	if region, ok := params["Region"]; ok {
 		client = client.Init(region)
@@ -261,34 +277,46 @@ func _phpJsonRequest(client *common.Client, resp qcloudResponse, domain string,
 		req.GetParams()[k] = v
 	}
 
-	return _baseJsonRequest(client, req, resp, debug, true)
+	return _baseJsonRequest(caller, client, req, resp, debug, true)
 }
 
-func _baseJsonRequest(client *common.Client, req tchttp.Request, resp qcloudResponse, debug bool, retry bool) (jsonutils.JSONObject, error) {
-	tryMax := 1
-	if retry {
-		tryMax = 3
+var (
+	// errors that go away if called later, e.g. the instance is in operation
+	qcloudThrottledErrors = []string{
+		"Code=RequestLimitExceeded",
+		"retry later",
+		"Code=MutexOperation.TaskRunning",
+		// Code=InvalidInstance.NotSupported, Message=The request does not support the instances `ins-bg54517v` which are in operation or in a special state., RequestId=79d02048-a8c9-4b59-b442-3c6f01fb728e
+		// 重装系统后立即关机有可能会引发 Code=InvalidInstance.NotSupported 错误, 重试可以避免任务失败
+		"Code=InvalidInstance.NotSupported",
 	}
-	var err error
-	for i := 1; i <= tryMax; i++ {
-		err = client.Send(req, resp)
-		if err == nil {
-			break
-		}
-		needRetry := false
-		for _, msg := range []string{"EOF", "TLS handshake timeout", "Code=InternalError", "retry later", "Code=MutexOperation.TaskRunning", "Code=InvalidInstance.NotSupported"} {
-			// Code=InvalidInstance.NotSupported, Message=The request does not support the instances `ins-bg54517v` which are in operation or in a special state., RequestId=79d02048-a8c9-4b59-b442-3c6f01fb728e
-			// 重装系统后立即关机有可能会引发 Code=InvalidInstance.NotSupported 错误, 重试可以避免任务失败
-			if strings.Contains(err.Error(), msg) {
-				needRetry = true
-				break
-			}
+	qcloudServerErrors = []string{
+		"EOF",
+		"TLS handshake timeout",
+		"Code=InternalError",
+	}
+)
+
+func classifyQcloudError(retry bool) cloudprovider.FApiErrorClassifier {
+	return func(err error) string {
+		errClass := cloudprovider.ClassifyApiError(err, qcloudThrottledErrors, qcloudServerErrors)
+		if !retry && errClass == cloudprovider.API_ERROR_THROTTLED && !strings.Contains(err.Error(), "Code=RequestLimitExceeded") {
+			return cloudprovider.API_ERROR_CLIENT
 		}
-		if needRetry {
-			log.Errorf("request url %s\nparams: %s\nerror: %v\ntry after %d seconds", req.GetDomain(), jsonutils.Marshal(req.GetParams()).PrettyString(), err, i*10)
-			time.Sleep(time.Second * time.Duration(i*10))
-			continue
+		if retry && errClass == cloudprovider.API_ERROR_SERVER {
+			// transient network and server errors are worth a retry as well
+			return cloudprovider.API_ERROR_THROTTLED
 		}
+		return errClass
+	}
+}
+
+func _baseJsonRequest(caller *cloudprovider.SApiCaller, client *common.Client, req tchttp.Request, resp qcloudResponse, debug bool, retry bool) (jsonutils.JSONObject, error) {
+	action := req.GetService() + "." + req.GetAction()
+	err := caller.Call(action, classifyQcloudError(retry), func() error {
+		return client.Send(req, resp)
+	})
+	if err != nil {
 		log.Errorf("request url: %s\nparams: %s\nresponse: %v\nerror: %v", req.GetDomain(), jsonutils.Marshal(req.GetParams()).PrettyString(), resp.GetResponse(), err)
 		return nil, err
 	}
@@ -296,9 +324,6 @@ func _baseJsonRequest(client *common.Client, req tchttp.Request, resp qcloudResp
 		log.Debugf("request: %s", req.GetParams())
 		log.Debugf("response: %s", jsonutils.Marshal(resp.GetResponse()).PrettyString())
 	}
-	if err != nil {
-		return nil, err
-	}
 	return jsonutils.Marshal(resp.GetResponse()), nil
 }
 
@@ -311,6 +336,10 @@ func (client *SQcloudClient) GetRegions() []SRegion {
 	return regions
 }
 
+func (client *SQcloudClient) apiCaller() *cloudprovider.SApiCaller {
+	return cloudprovider.GetApiCaller(CLOUD_PROVIDER_QCLOUD, client.SecretID)
+}
+
 func (client *SQcloudClient) getDefaultClient() (*common.Client, error) {
 	return common.NewClientWithSecretId(client.SecretID, client.SecretKey, QCLOUD_DEFAULT_REGION)
 }
@@ -320,7 +349,7 @@ func (client *SQcloudClient) vpcRequest(apiName string, params map[string]string
 	if err != nil {
 		return nil, err
 	}
-	return vpcRequest(cli, apiName, params, client.Debug)
+	return vpcRequest(client.apiCaller(), cli, apiName, params, client.Debug)
 }
 
 func (client *SQcloudClient) cbsRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
@@ -328,7 +357,7 @@ func (client *SQcloudClient) cbsRequest(apiName string, params map[string]string
 	if err != nil {
 		return nil, err
 	}
-	return cbsRequest(cli, apiName, params, client.Debug)
+	return cbsRequest(client.apiCaller(), cli, apiName, params, client.Debug)
 }
 
 func (client *SQcloudClient) accountRequestRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
@@ -336,7 +365,7 @@ func (client *SQcloudClient) accountRequestRequest(apiName string, params map[st
 	if err != nil {
 		return nil, err
 	}
-	return accountRequest(cli, apiName, params, client.Debug)
+	return accountRequest(client.apiCaller(), cli, apiName, params, client.Debug)
 }
 
 func (client *SQcloudClient) clbRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
@@ -344,7 +373,7 @@ func (client *SQcloudClient) clbRequest(apiName string, params map[string]string
 	if err != nil {
 		return nil, err
 	}
-	return clbRequest(cli, apiName, params, client.Debug)
+	return clbRequest(client.apiCaller(), cli, apiName, params, client.Debug)
 }
 
 func (client *SQcloudClient) lbRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
@@ -352,7 +381,23 @@ func (client *SQcloudClient) lbRequest(apiName string, params map[string]string)
 	if err != nil {
 		return nil, err
 	}
-	return lbRequest(cli, apiName, params, client.Debug)
+	return lbRequest(client.apiCaller(), cli, apiName, params, client.Debug)
+}
+
+func (client *SQcloudClient) vpc2017Request(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
+	cli, err := client.getDefaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return vpc2017Request(client.apiCaller(), cli, apiName, params, client.Debug)
+}
+
+func (client *SQcloudClient) cnsRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
+	cli, err := client.getDefaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return cnsRequest(client.apiCaller(), cli, apiName, params, client.Debug)
 }
 
 func (client *SQcloudClient) wssRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
@@ -360,7 +405,7 @@ func (client *SQcloudClient) wssRequest(apiName string, params map[string]string
 	if err != nil {
 		return nil, err
 	}
-	return wssRequest(cli, apiName, params, client.Debug)
+	return wssRequest(client.apiCaller(), cli, apiName, params, client.Debug)
 }
 
 func (client *SQcloudClient) billingRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
@@ -368,7 +413,7 @@ func (client *SQcloudClient) billingRequest(apiName string, params map[string]st
 	if err != nil {
 		return nil, err
 	}
-	return billingRequest(cli, apiName, params, client.Debug)
+	return billingRequest(client.apiCaller(), cli, apiName, params, client.Debug)
 }
 
 func (client *SQcloudClient) jsonRequest(apiName string, params map[string]string, retry bool) (jsonutils.JSONObject, error) {
@@ -376,7 +421,7 @@ func (client *SQcloudClient) jsonRequest(apiName string, params map[string]strin
 	if err != nil {
 		return nil, err
 	}
-	return jsonRequest(cli, apiName, params, client.Debug, retry)
+	return jsonRequest(client.apiCaller(), cli, apiName, params, client.Debug, retry)
 }
 
 func (client *SQcloudClient) fetchRegions() error {
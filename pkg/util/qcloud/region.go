@@ -26,12 +26,15 @@ import (
 
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/objectstore"
 )
 
 type SRegion struct {
 	client    *SQcloudClient
 	cosClient *cos.Client
 
+	bucketClient *objectstore.SObjectStoreClient
+
 	izones []cloudprovider.ICloudZone
 	ivpcs  []cloudprovider.ICloudVpc
 
@@ -635,6 +638,11 @@ func (self *SRegion) lbRequest(apiName string, params map[string]string) (jsonut
 	return self.client.lbRequest(apiName, params)
 }
 
+func (self *SRegion) vpc2017Request(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
+	params["Region"] = self.Region
+	return self.client.vpc2017Request(apiName, params)
+}
+
 func (self *SRegion) wssRequest(apiName string, params map[string]string) (jsonutils.JSONObject, error) {
 	return self.client.wssRequest(apiName, params)
 }
@@ -791,3 +799,11 @@ func (self *SRegion) GetInstanceStatus(instanceId string) (string, error) {
 	}
 	return instance.InstanceState, nil
 }
+
+func (self *SRegion) GetIDBInstances() ([]cloudprovider.ICloudDBInstance, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
+
+func (self *SRegion) GetIDBInstanceById(id string) (cloudprovider.ICloudDBInstance, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qcloud
+
+import (
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// next hop types in the same terms as the other providers
+var qcloudRouteGatewayTypes = map[string]string{
+	"LOCAL":          "local",
+	"CVM":            "Instance",
+	"NORMAL_CVM":     "Instance",
+	"VPN":            "VpnGateway",
+	"PEERCONNECTION": "VpcPeering",
+	"NAT":            "NatGateway",
+	"HAVIP":          "HaVip",
+	"EIP":            "Eip",
+	"DIRECTCONNECT":  "DirectConnect",
+	"CCN":            "Ccn",
+}
+
+type SRouteSet struct {
+	RouteId              int
+	DestinationCidrBlock string
+	GatewayType          string
+	GatewayId            string
+	RouteDescription     string
+	RouteType            string
+	Enabled              bool
+}
+
+func (route *SRouteSet) GetType() string {
+	if route.RouteType == "USER" {
+		return "Custom"
+	}
+	return "System"
+}
+
+func (route *SRouteSet) GetCidr() string {
+	return route.DestinationCidrBlock
+}
+
+func (route *SRouteSet) GetNextHopType() string {
+	if hopType, ok := qcloudRouteGatewayTypes[route.GatewayType]; ok {
+		return hopType
+	}
+	return route.GatewayType
+}
+
+func (route *SRouteSet) GetNextHop() string {
+	return route.GatewayId
+}
+
+type SRouteTable struct {
+	vpc *SVpc
+
+	VpcId          string
+	RouteTableId   string
+	RouteTableName string
+	Main           bool
+	RouteSet       []SRouteSet
+	CreatedTime    time.Time
+}
+
+func (self *SRouteTable) GetId() string {
+	return self.RouteTableId
+}
+
+func (self *SRouteTable) GetName() string {
+	if len(self.RouteTableName) > 0 {
+		return self.RouteTableName
+	}
+	return self.RouteTableId
+}
+
+func (self *SRouteTable) GetGlobalId() string {
+	return self.RouteTableId
+}
+
+func (self *SRouteTable) GetStatus() string {
+	return ""
+}
+
+func (self *SRouteTable) Refresh() error {
+	return nil
+}
+
+func (self *SRouteTable) IsEmulated() bool {
+	return false
+}
+
+func (self *SRouteTable) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SRouteTable) GetManagerId() string {
+	return self.vpc.region.client.providerId
+}
+
+func (self *SRouteTable) GetDescription() string {
+	return ""
+}
+
+func (self *SRouteTable) GetRegionId() string {
+	return self.vpc.region.Region
+}
+
+func (self *SRouteTable) GetVpcId() string {
+	return self.VpcId
+}
+
+func (self *SRouteTable) GetType() string {
+	if self.Main {
+		return "System"
+	}
+	return "Custom"
+}
+
+func (self *SRouteTable) GetIRoutes() ([]cloudprovider.ICloudRoute, error) {
+	routes := make([]cloudprovider.ICloudRoute, 0, len(self.RouteSet))
+	for i := range self.RouteSet {
+		if !self.RouteSet[i].Enabled {
+			continue
+		}
+		routes = append(routes, &self.RouteSet[i])
+	}
+	return routes, nil
+}
+
+func (self *SRegion) GetRouteTables(vpcId string, offset int, limit int) ([]SRouteTable, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["Limit"] = fmt.Sprintf("%d", limit)
+	params["Offset"] = fmt.Sprintf("%d", offset)
+	if len(vpcId) > 0 {
+		params["Filters.0.Name"] = "vpc-id"
+		params["Filters.0.Values.0"] = vpcId
+	}
+	body, err := self.vpcRequest("DescribeRouteTables", params)
+	if err != nil {
+		log.Errorf("DescribeRouteTables fail %s", err)
+		return nil, 0, err
+	}
+	tables := make([]SRouteTable, 0)
+	err = body.Unmarshal(&tables, "RouteTableSet")
+	if err != nil {
+		return nil, 0, err
+	}
+	total, _ := body.Float("TotalCount")
+	return tables, int(total), nil
+}
@@ -101,10 +101,45 @@ func (self *SVpc) GetISecurityGroups() ([]cloudprovider.ICloudSecurityGroup, err
 }
 
 func (self *SVpc) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
-	rts := []cloudprovider.ICloudRouteTable{}
+	tables := make([]SRouteTable, 0)
+	for {
+		parts, total, err := self.region.GetRouteTables(self.VpcId, len(tables), 50)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, parts...)
+		if len(tables) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	rts := make([]cloudprovider.ICloudRouteTable, len(tables))
+	for i := 0; i < len(tables); i++ {
+		tables[i].vpc = self
+		rts[i] = &tables[i]
+	}
 	return rts, nil
 }
 
+func (self *SVpc) GetINatGateways() ([]cloudprovider.ICloudNatGateway, error) {
+	nats := make([]SNatGateway, 0)
+	for {
+		parts, total, err := self.region.GetNatGateways(self.VpcId, "", len(nats), 50)
+		if err != nil {
+			return nil, err
+		}
+		nats = append(nats, parts...)
+		if len(nats) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	inats := make([]cloudprovider.ICloudNatGateway, len(nats))
+	for i := 0; i < len(nats); i++ {
+		nats[i].vpc = self
+		inats[i] = &nats[i]
+	}
+	return inats, nil
+}
+
 func (self *SVpc) getWireByZoneId(zoneId string) *SWire {
 	for i := 0; i <= len(self.iwires); i++ {
 		wire := self.iwires[i].(*SWire)
@@ -0,0 +1,152 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qcloud
+
+import (
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+)
+
+// 对等连接只有2017版本的接口, 字段为小写驼峰, 新版本的vpc id在unVpcId中
+type SVpcPeering struct {
+	region *SRegion
+
+	PeeringConnectionId   string `json:"peeringConnectionId"`
+	PeeringConnectionName string `json:"peeringConnectionName"`
+	UnVpcId               string `json:"unVpcId"`
+	UnPeerVpcId           string `json:"unPeerVpcId"`
+	PeerRegion            string `json:"peerRegion"`
+	PeerUin               string `json:"peerUin"`
+	// 0: 申请中, 1: 已连接, 2: 已过期, 3: 已拒绝, 4: 已删除
+	State     int `json:"state"`
+	Bandwidth int `json:"bandwidth"`
+}
+
+func (self *SVpcPeering) GetId() string {
+	return self.PeeringConnectionId
+}
+
+func (self *SVpcPeering) GetName() string {
+	if len(self.PeeringConnectionName) > 0 {
+		return self.PeeringConnectionName
+	}
+	return self.PeeringConnectionId
+}
+
+func (self *SVpcPeering) GetGlobalId() string {
+	return self.PeeringConnectionId
+}
+
+func (self *SVpcPeering) GetStatus() string {
+	switch self.State {
+	case 0:
+		return api.VPC_PEERING_STATUS_PENDING
+	case 1:
+		return api.VPC_PEERING_STATUS_ACTIVE
+	case 2, 3:
+		return api.VPC_PEERING_STATUS_FAILED
+	case 4:
+		return api.VPC_PEERING_STATUS_DELETING
+	}
+	return api.VPC_PEERING_STATUS_UNKNOWN
+}
+
+func (self *SVpcPeering) Refresh() error {
+	peerings, _, err := self.region.GetVpcPeerings(self.PeeringConnectionId, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(peerings) != 1 {
+		return cloudprovider.ErrNotFound
+	}
+	return jsonutils.Update(self, peerings[0])
+}
+
+func (self *SVpcPeering) IsEmulated() bool {
+	return false
+}
+
+func (self *SVpcPeering) GetMetadata() *jsonutils.JSONDict {
+	return nil
+}
+
+func (self *SVpcPeering) GetVpcId() string {
+	return self.UnVpcId
+}
+
+func (self *SVpcPeering) GetPeerVpcId() string {
+	return self.UnPeerVpcId
+}
+
+func (self *SVpcPeering) GetPeerRegionId() string {
+	return self.PeerRegion
+}
+
+func (self *SVpcPeering) GetPeerAccountId() string {
+	return self.PeerUin
+}
+
+func (self *SVpcPeering) GetBandwidth() int {
+	return self.Bandwidth
+}
+
+func (self *SRegion) GetVpcPeerings(peeringId string, offset int, limit int) ([]SVpcPeering, int, error) {
+	if limit > 50 || limit <= 0 {
+		limit = 50
+	}
+	params := make(map[string]string)
+	params["limit"] = fmt.Sprintf("%d", limit)
+	params["offset"] = fmt.Sprintf("%d", offset)
+	if len(peeringId) > 0 {
+		params["peeringConnectionId"] = peeringId
+	}
+	body, err := self.vpc2017Request("DescribeVpcPeeringConnections", params)
+	if err != nil {
+		log.Errorf("DescribeVpcPeeringConnections fail %s", err)
+		return nil, 0, err
+	}
+	peerings := make([]SVpcPeering, 0)
+	err = body.Unmarshal(&peerings, "data")
+	if err != nil {
+		return nil, 0, err
+	}
+	total, _ := body.Float("totalCount")
+	return peerings, int(total), nil
+}
+
+func (self *SRegion) GetIVpcPeerings() ([]cloudprovider.ICloudVpcPeering, error) {
+	peerings := make([]SVpcPeering, 0)
+	for {
+		parts, total, err := self.GetVpcPeerings("", len(peerings), 50)
+		if err != nil {
+			return nil, err
+		}
+		peerings = append(peerings, parts...)
+		if len(peerings) >= total || len(parts) == 0 {
+			break
+		}
+	}
+	ret := make([]cloudprovider.ICloudVpcPeering, len(peerings))
+	for i := 0; i < len(peerings); i++ {
+		peerings[i].region = self
+		ret[i] = &peerings[i]
+	}
+	return ret, nil
+}
@@ -19,8 +19,10 @@ import (
 	"regexp"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 
 	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/conditionparser"
 )
 
 type TRbacResult string
@@ -69,13 +71,27 @@ type SRbacRule struct {
 	Action   string
 	Extra    []string
 	Result   TRbacResult
+
+	// Condition is an expression evaluated by conditionparser against the
+	// attributes of the request, e.g. `object.metadata.env != "prod"`,
+	// the rule takes effect only if the condition is satisfied
+	Condition string
 }
 
+const (
+	ruleKeyResult    = "result"
+	ruleKeyCondition = "condition"
+)
+
 func isWildMatch(str string) bool {
 	return len(str) == 0 || str == WILD_MATCH
 }
 
 func (rule *SRbacRule) contains(rule2 *SRbacRule) bool {
+	if len(rule.Condition) > 0 || len(rule2.Condition) > 0 {
+		// conditional rules are never merged
+		return false
+	}
 	if !isWildMatch(rule.Service) && rule.Service != rule2.Service {
 		return false
 	}
@@ -102,6 +118,43 @@ func (rule *SRbacRule) stricterThan(r2 *SRbacRule) bool {
 	return rule.Result.StricterThan(r2.Result)
 }
 
+func (rule *SRbacRule) IsConditional() bool {
+	return len(rule.Condition) > 0
+}
+
+func (rule *SRbacRule) isSamePath(r2 *SRbacRule) bool {
+	if rule.Service != r2.Service || rule.Resource != r2.Resource || rule.Action != r2.Action {
+		return false
+	}
+	if len(rule.Extra) != len(r2.Extra) {
+		return false
+	}
+	for i := range rule.Extra {
+		if rule.Extra[i] != r2.Extra[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// EvalCondition tests whether the condition of the rule is satisfied by input,
+// a rule without condition is always satisfied
+func (rule *SRbacRule) EvalCondition(input jsonutils.JSONObject) bool {
+	if len(rule.Condition) == 0 {
+		return true
+	}
+	attrs, _ := input.(*jsonutils.JSONDict)
+	if attrs == nil {
+		attrs = jsonutils.NewDict()
+	}
+	ok, err := conditionparser.EvalAttributes(rule.Condition, attrs)
+	if err != nil {
+		log.Debugf("eval rbac condition %s fail %s", rule.Condition, err)
+		return false
+	}
+	return ok
+}
+
 func (rule *SRbacRule) match(service string, resource string, action string, extra ...string) (bool, int, int) {
 	matched := 0
 	weight := 0
@@ -162,6 +215,15 @@ func (policy *SRbacPolicy) getMatchRule(req []string) *SRbacRule {
 }
 
 func (policy *SRbacPolicy) GetMatchRule(service string, resource string, action string, extra ...string) *SRbacRule {
+	return policy.GetMatchRuleWithInput(nil, service, resource, action, extra...)
+}
+
+// GetMatchRuleWithInput finds the most specific rule matching the request.
+// Conditional rules are evaluated against input in the order of declaration
+// and take precedence over unconditional rules of the same path; if all rules
+// of the most specific path are conditional and none is satisfied, a Deny
+// rule carrying the path is returned
+func (policy *SRbacPolicy) GetMatchRuleWithInput(input jsonutils.JSONObject, service string, resource string, action string, extra ...string) *SRbacRule {
 	maxMatchCnt := 0
 	minWeight := 1000000
 	var matchRule *SRbacRule
@@ -175,7 +237,46 @@ func (policy *SRbacPolicy) GetMatchRule(service string, resource string, action
 			matchRule = &policy.Rules[i]
 		}
 	}
-	return matchRule
+	if matchRule == nil || !policy.hasConditionalRules() {
+		return matchRule
+	}
+	var unconditional *SRbacRule
+	for i := 0; i < len(policy.Rules); i += 1 {
+		rule := &policy.Rules[i]
+		if !rule.isSamePath(matchRule) {
+			continue
+		}
+		if !rule.IsConditional() {
+			if unconditional == nil || unconditional.stricterThan(rule) {
+				unconditional = rule
+			}
+			continue
+		}
+		if rule.EvalCondition(input) {
+			return rule
+		}
+	}
+	if unconditional != nil {
+		return unconditional
+	}
+	denyRule := *matchRule
+	denyRule.Condition = ""
+	denyRule.Result = Deny
+	return &denyRule
+}
+
+func (policy *SRbacPolicy) hasConditionalRules() bool {
+	for i := range policy.Rules {
+		if policy.Rules[i].IsConditional() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConditionalRules returns true if any rule of the policy has a condition
+func (policy *SRbacPolicy) HasConditionalRules() bool {
+	return policy.hasConditionalRules()
 }
 
 func CompactRules(rules []SRbacRule) []SRbacRule {
@@ -273,31 +374,95 @@ const (
 	levelExtra    = 3
 )
 
+func isConditionalRuleJson(ruleJson jsonutils.JSONObject) bool {
+	ruleDict, ok := ruleJson.(*jsonutils.JSONDict)
+	if !ok {
+		return false
+	}
+	result, err := ruleDict.Get(ruleKeyResult)
+	if err != nil {
+		return false
+	}
+	if _, ok := result.(*jsonutils.JSONString); !ok {
+		return false
+	}
+	for _, key := range ruleDict.SortedKeys() {
+		if key != ruleKeyResult && key != ruleKeyCondition {
+			return false
+		}
+	}
+	return true
+}
+
+func isRuleLeafJson(ruleJson jsonutils.JSONObject) bool {
+	switch ruleJson.(type) {
+	case *jsonutils.JSONString, *jsonutils.JSONArray:
+		return true
+	default:
+		return isConditionalRuleJson(ruleJson)
+	}
+}
+
+func decodeResult(isAdmin bool, ruleStr string) (TRbacResult, error) {
+	switch ruleStr {
+	case string(Allow):
+		if isAdmin {
+			return AdminAllow, nil
+		} else {
+			return OwnerAllow, nil
+		}
+	case string(AdminAllow):
+		return AdminAllow, nil
+	case string(OwnerAllow):
+		return OwnerAllow, nil
+	case string(UserAllow):
+		return UserAllow, nil
+	case string(GuestAllow):
+		return GuestAllow, nil
+	case string(Deny):
+		return Deny, nil
+	default:
+		return Deny, fmt.Errorf("unsupported rule string %s", ruleStr)
+	}
+}
+
 func decode(isAdmin bool, rules jsonutils.JSONObject, decodeRule SRbacRule, level int) ([]SRbacRule, error) {
+	if isConditionalRuleJson(rules) {
+		ruleStr, _ := rules.GetString(ruleKeyResult)
+		result, err := decodeResult(isAdmin, ruleStr)
+		if err != nil {
+			return nil, err
+		}
+		decodeRule.Result = result
+		decodeRule.Condition, _ = rules.GetString(ruleKeyCondition)
+		if len(decodeRule.Condition) > 0 && !conditionparser.IsValid(decodeRule.Condition) {
+			return nil, fmt.Errorf("invalid rule condition %s", decodeRule.Condition)
+		}
+		return []SRbacRule{decodeRule}, nil
+	}
 	switch rules.(type) {
+	case *jsonutils.JSONArray:
+		ruleArray, _ := rules.GetArray()
+		rules := make([]SRbacRule, 0)
+		for i := range ruleArray {
+			if !isRuleLeafJson(ruleArray[i]) {
+				return nil, fmt.Errorf("unsupport rule data %s", ruleArray[i].String())
+			}
+			decoded, err := decode(isAdmin, ruleArray[i], decodeRule, level)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, decoded...)
+		}
+		return rules, nil
 	case *jsonutils.JSONString:
 		ruleJsonStr := rules.(*jsonutils.JSONString)
 		ruleStr, _ := ruleJsonStr.GetString()
-		switch ruleStr {
-		case string(Allow):
-			if isAdmin {
-				decodeRule.Result = AdminAllow
-			} else {
-				decodeRule.Result = OwnerAllow
-			}
-		case string(AdminAllow):
-			decodeRule.Result = AdminAllow
-		case string(OwnerAllow):
-			decodeRule.Result = OwnerAllow
-		case string(UserAllow):
-			decodeRule.Result = UserAllow
-		case string(GuestAllow):
-			decodeRule.Result = GuestAllow
-		case string(Deny):
-			decodeRule.Result = Deny
-		default:
-			return nil, fmt.Errorf("unsupported rule string %s", ruleStr)
+		result, err := decodeResult(isAdmin, ruleStr)
+		if err != nil {
+			return nil, err
 		}
+		decodeRule.Result = result
 		return []SRbacRule{decodeRule}, nil
 	case *jsonutils.JSONDict:
 		ruleJsonDict, err := rules.GetMap()
@@ -349,44 +514,67 @@ func (rule *SRbacRule) toStringArray() []string {
 	return strArr[0 : i+1]
 }
 
-func addRule2Json(nodeJson *jsonutils.JSONDict, keys []string, result TRbacResult) error {
+func (rule *SRbacRule) leafJson() jsonutils.JSONObject {
+	if len(rule.Condition) == 0 {
+		return jsonutils.NewString(string(rule.Result))
+	}
+	ret := jsonutils.NewDict()
+	ret.Add(jsonutils.NewString(string(rule.Result)), ruleKeyResult)
+	ret.Add(jsonutils.NewString(rule.Condition), ruleKeyCondition)
+	return ret
+}
+
+func addRule2Json(nodeJson *jsonutils.JSONDict, keys []string, rule *SRbacRule) error {
 	if len(keys) == 1 {
 		if nodeJson.Contains(keys[0]) {
 			nextJson, _ := nodeJson.Get(keys[0])
 			switch nextJson.(type) {
-			case *jsonutils.JSONString: // conflict??
-				return fmt.Errorf("conflict?")
+			case *jsonutils.JSONArray:
+				nextJsonArray := nextJson.(*jsonutils.JSONArray)
+				nextJsonArray.Add(rule.leafJson())
+				return nil
+			case *jsonutils.JSONString:
+				if !rule.IsConditional() { // conflict??
+					return fmt.Errorf("conflict?")
+				}
+				nodeJson.Set(keys[0], jsonutils.NewArray(nextJson, rule.leafJson()))
+				return nil
 			case *jsonutils.JSONDict:
+				if isConditionalRuleJson(nextJson) {
+					nodeJson.Set(keys[0], jsonutils.NewArray(nextJson, rule.leafJson()))
+					return nil
+				}
 				nextJsonDict := nextJson.(*jsonutils.JSONDict)
-				addRule2Json(nextJsonDict, []string{WILD_MATCH}, result)
+				addRule2Json(nextJsonDict, []string{WILD_MATCH}, rule)
 				return nil
 			default:
 				return fmt.Errorf("invalid rules")
 			}
 		} else {
-			nodeJson.Add(jsonutils.NewString(string(result)), keys[0])
+			nodeJson.Add(rule.leafJson(), keys[0])
 			return nil
 		}
 	}
 	// len(keys) > 1
 	exist, _ := nodeJson.Get(keys[0])
 	if exist != nil {
-		switch exist.(type) {
-		case *jsonutils.JSONString: // need restruct
+		if isRuleLeafJson(exist) { // need restruct
 			newDict := jsonutils.NewDict()
 			newDict.Add(exist, "*")
 			nodeJson.Set(keys[0], newDict)
-			return addRule2Json(newDict, keys[1:], result)
+			return addRule2Json(newDict, keys[1:], rule)
+		}
+		switch exist.(type) {
 		case *jsonutils.JSONDict:
 			existDict := exist.(*jsonutils.JSONDict)
-			return addRule2Json(existDict, keys[1:], result)
+			return addRule2Json(existDict, keys[1:], rule)
 		default:
 			return fmt.Errorf("invalid rules")
 		}
 	} else {
 		next := jsonutils.NewDict()
 		nodeJson.Add(next, keys[0])
-		return addRule2Json(next, keys[1:], result)
+		return addRule2Json(next, keys[1:], rule)
 	}
 }
 
@@ -394,7 +582,7 @@ func (policy *SRbacPolicy) Encode() (jsonutils.JSONObject, error) {
 	rules := jsonutils.NewDict()
 	for i := 0; i < len(policy.Rules); i += 1 {
 		keys := policy.Rules[i].toStringArray()
-		err := addRule2Json(rules, keys, policy.Rules[i].Result)
+		err := addRule2Json(rules, keys, &policy.Rules[i])
 		if err != nil {
 			return nil, err
 		}
@@ -334,3 +334,82 @@ func TestSRbacPolicyMatch(t *testing.T) {
 		}
 	}
 }
+
+func TestSRbacPolicy_Condition(t *testing.T) {
+	policyStr := `{
+        "is_admin": false,
+        "policy": {
+            "compute": {
+                "servers": {
+                    "*": "allow",
+                    "perform": {
+                        "*": "allow",
+                        "stop": {"result": "allow", "condition": "object.metadata.env != \"prod\""},
+                        "start": [
+                            {"result": "allow", "condition": "object.tags.owner == request.owner"},
+                            "deny"
+                        ]
+                    }
+                }
+            }
+        }
+    }`
+	policyJson, err := jsonutils.ParseString(policyStr)
+	if err != nil {
+		t.Fatalf("fail to parse json string %s", err)
+	}
+	policy := SRbacPolicy{}
+	err = policy.Decode(policyJson)
+	if err != nil {
+		t.Fatalf("decode error %s", err)
+	}
+
+	newInput := func(env, owner, reqOwner string) jsonutils.JSONObject {
+		input := jsonutils.NewDict()
+		input.Add(jsonutils.NewString(env), "object", "metadata", "env")
+		input.Add(jsonutils.NewString(owner), "object", "tags", "owner")
+		input.Add(jsonutils.NewString(reqOwner), "request", "owner")
+		return input
+	}
+
+	cases := []struct {
+		input     jsonutils.JSONObject
+		action    string
+		want      TRbacResult
+		condition string
+	}{
+		{newInput("dev", "", ""), "stop", OwnerAllow, `object.metadata.env != "prod"`},
+		{newInput("prod", "", ""), "stop", Deny, ""},
+		{nil, "stop", Deny, ""},
+		{newInput("prod", "alice", "alice"), "start", OwnerAllow, "object.tags.owner == request.owner"},
+		{newInput("prod", "alice", "bob"), "start", Deny, ""},
+		{newInput("prod", "", ""), "restart", OwnerAllow, ""},
+	}
+	for _, c := range cases {
+		rule := policy.GetMatchRuleWithInput(c.input, "compute", "servers", "perform", c.action)
+		if rule == nil {
+			t.Errorf("%s: no rule matched", c.action)
+			continue
+		}
+		if rule.Result != c.want || rule.Condition != c.condition {
+			t.Errorf("%s %s: want %s %q got %s %q", c.action, c.input, c.want, c.condition, rule.Result, rule.Condition)
+		}
+	}
+
+	encoded, err := policy.Encode()
+	if err != nil {
+		t.Fatalf("encode error %s", err)
+	}
+	policy2 := SRbacPolicy{}
+	err = policy2.Decode(encoded)
+	if err != nil {
+		t.Fatalf("decode encoded policy error %s", err)
+	}
+	if len(policy2.Rules) != len(policy.Rules) {
+		t.Errorf("rules mismatch after encode: %s", encoded)
+	}
+	rule := policy2.GetMatchRuleWithInput(newInput("prod", "alice", "bob"), "compute", "servers", "perform", "start")
+	if rule == nil || rule.Result != Deny {
+		t.Errorf("encoded policy %s should deny start", encoded)
+	}
+}
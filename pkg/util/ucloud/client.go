@@ -19,10 +19,11 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"strings"
-	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/util/httputils"
 )
 
@@ -151,13 +152,27 @@ func parseUcloudResponse(resp jsonutils.JSONObject) (jsonutils.JSONObject, error
 	return resp, nil
 }
 
+func classifyUcloudError(err error) string {
+	e, ok := err.(*httputils.JSONClientError)
+	if !ok {
+		return cloudprovider.API_ERROR_CLIENT
+	}
+	// server errors of ucloud are mostly transient, worth a retry
+	if e.Code == 429 || e.Code >= 500 {
+		return cloudprovider.API_ERROR_THROTTLED
+	}
+	return cloudprovider.API_ERROR_CLIENT
+}
+
 func jsonRequest(client *SUcloudClient, params SParams) (jsonutils.JSONObject, error) {
 	ctx := context.Background()
-	MAX_RETRY := 3
-	retry := 0
+	action, _ := params.data.GetString("Action")
+	caller := cloudprovider.GetApiCaller(CLOUD_PROVIDER_UCLOUD, client.accessKeyId)
 
-	for retry < MAX_RETRY {
-		_, resp, err := httputils.JSONRequest(
+	var resp jsonutils.JSONObject
+	err := caller.Call(action, classifyUcloudError, func() error {
+		var err error
+		_, resp, err = httputils.JSONRequest(
 			client.httpClient,
 			ctx,
 			httputils.POST,
@@ -165,24 +180,10 @@ func jsonRequest(client *SUcloudClient, params SParams) (jsonutils.JSONObject, e
 			nil,
 			BuildParams(params, client.accessKeySecret),
 			client.Debug)
-
-		if err == nil {
-			return parseUcloudResponse(resp)
-		}
-
-		switch e := err.(type) {
-		case *httputils.JSONClientError:
-			if e.Code >= 500 {
-				time.Sleep(3 * time.Second)
-				retry += 1
-				continue
-			} else {
-				return nil, err
-			}
-		default:
-			return nil, err
-		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, fmt.Errorf("timeout for request: %s", params)
+	return parseUcloudResponse(resp)
 }
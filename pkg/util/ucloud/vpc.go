@@ -125,6 +125,11 @@ func (self *SVPC) GetIRouteTables() ([]cloudprovider.ICloudRouteTable, error) {
 	return rts, nil
 }
 
+func (self *SVPC) GetINatGateways() ([]cloudprovider.ICloudNatGateway, error) {
+	nats := []cloudprovider.ICloudNatGateway{}
+	return nats, nil
+}
+
 func (self *SVPC) GetManagerId() string {
 	return self.region.client.providerId
 }
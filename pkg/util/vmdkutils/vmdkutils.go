@@ -35,6 +35,8 @@ type SVMDKInfo struct {
 	UUID             string
 	AdapterType      string
 	VirtualHWVersion string
+
+	ParentFileNameHint string
 }
 
 func (info SVMDKInfo) Size() int64 {
@@ -88,6 +90,8 @@ func ParseStream(stream io.Reader) (*SVMDKInfo, error) {
 					info.AdapterType = value
 				case "ddb.virtualHWVersion":
 					info.VirtualHWVersion = value
+				case "parentFileNameHint":
+					info.ParentFileNameHint = value
 				}
 			}
 		}
@@ -97,3 +101,17 @@ func ParseStream(stream io.Reader) (*SVMDKInfo, error) {
 	}
 	return &info, nil
 }
+
+// SetParentFileNameHint replaces the parent descriptor path of a delta disk
+// descriptor, so that the chain is able to be opened at another location
+func SetParentFileNameHint(content string, parent string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		key := strings.TrimSpace(line)
+		equalPos := strings.IndexByte(key, '=')
+		if equalPos > 0 && strings.TrimSpace(key[:equalPos]) == "parentFileNameHint" {
+			lines[i] = fmt.Sprintf("parentFileNameHint=%q", parent)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
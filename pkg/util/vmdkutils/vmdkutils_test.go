@@ -56,3 +56,23 @@ func TestParseStream(t *testing.T) {
 		t.Errorf("should parse error")
 	}
 }
+
+func TestSetParentFileNameHint(t *testing.T) {
+	content := `version=1
+CID=ab798ecb
+parentCID=9e3a5f2c
+parentFileNameHint="/vmfs/volumes/5b2b4b7e-image/image_cache/base.vmdk"
+
+RW 62914560 VMFSSPARSE "disk-delta.vmdk"
+`
+	info, err := Parse(SetParentFileNameHint(content, "../1/base.vmdk"))
+	if err != nil {
+		t.Fatalf("parse error %s", err)
+	}
+	if info.ParentFileNameHint != "../1/base.vmdk" {
+		t.Errorf("parent file name hint %s", info.ParentFileNameHint)
+	}
+	if info.ExtentFile != "disk-delta.vmdk" {
+		t.Errorf("extent file %s", info.ExtentFile)
+	}
+}
@@ -18,9 +18,15 @@ import (
 	"fmt"
 	"os/exec"
 
+	"yunion.io/x/jsonutils"
+
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
 )
 
+const (
+	DATA_TYPE_IPMITOOL_SOL = "ipmitool_sol"
+)
+
 type IpmiInfo struct {
 	IpAddr   string `json:"ip_addr"`
 	Username string `json:"username"`
@@ -63,3 +69,11 @@ func (c *IpmitoolSol) GetCommand() *exec.Cmd {
 func (c IpmitoolSol) GetProtocol() string {
 	return PROTOCOL_TTY
 }
+
+func (c IpmitoolSol) GetDataType() string {
+	return DATA_TYPE_IPMITOOL_SOL
+}
+
+func (c IpmitoolSol) Serialize() jsonutils.JSONObject {
+	return jsonutils.Marshal(c.Info)
+}
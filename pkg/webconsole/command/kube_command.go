@@ -15,7 +15,9 @@
 package command
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"time"
@@ -23,21 +25,52 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/k8s"
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
 )
 
+const (
+	DATA_TYPE_KUBECTL_EXEC = "kubectl_exec"
+	DATA_TYPE_KUBECTL_LOG  = "kubectl_log"
+)
+
 type K8sEnv struct {
-	Cluster    string
-	Namespace  string
-	Pod        string
-	Container  string
-	Kubeconfig string
+	Cluster   string
+	Namespace string
+	Pod       string
+	Container string
+	// Kubeconfig is a local temp file, it is fetched again by the replica
+	// restoring the session
+	Kubeconfig string `json:"-"`
 	Data       jsonutils.JSONObject
 }
 
+// FetchKubeconfig saves the kubeconfig of cluster into a temp file
+func FetchKubeconfig(ctx context.Context, cluster string) (string, error) {
+	adminSession := auth.GetAdminSession(ctx, o.Options.Region, "")
+	ret, err := k8s.KubeClusters.GetSpecific(adminSession, cluster, "kubeconfig", jsonutils.NewDict())
+	if err != nil {
+		return "", err
+	}
+	conf, err := ret.GetString("kubeconfig")
+	if err != nil {
+		return "", httperrors.NewNotFoundError("Not found cluster %q kubeconfig", cluster)
+	}
+	f, err := ioutil.TempFile("", "kubeconfig-")
+	if err != nil {
+		return "", fmt.Errorf("Save kubeconfig error: %v", err)
+	}
+	defer f.Close()
+	f.WriteString(conf)
+	return f.Name(), nil
+}
+
 type Kubectl struct {
 	*BaseCommand
 	kubeconfig string
+	env        *K8sEnv
 }
 
 func NewKubectlCommand(kubeconfig, namespace string) *Kubectl {
@@ -80,6 +113,14 @@ func (c *Kubectl) Exec() *KubectlExec {
 	return cmd
 }
 
+func (c *KubectlExec) GetDataType() string {
+	return DATA_TYPE_KUBECTL_EXEC
+}
+
+func (c *KubectlExec) Serialize() jsonutils.JSONObject {
+	return jsonutils.Marshal(c.env)
+}
+
 func (c *KubectlExec) Stdin() *KubectlExec {
 	// -i: Pass stdin to the container
 	c.AppendArgs("-i")
@@ -114,7 +155,9 @@ func (c *KubectlExec) Command(cmd string, args ...string) *KubectlExec {
 }
 
 func NewPodBashCommand(env *K8sEnv) ICommand {
-	return NewKubectlCommand(env.Kubeconfig, env.Namespace).Exec().
+	kubectl := NewKubectlCommand(env.Kubeconfig, env.Namespace)
+	kubectl.env = env
+	return kubectl.Exec().
 		Stdin().
 		TTY().
 		Pod(env.Pod).
@@ -135,6 +178,14 @@ func (c *Kubectl) Logs() *KubectlLog {
 	return cmd
 }
 
+func (c *KubectlLog) GetDataType() string {
+	return DATA_TYPE_KUBECTL_LOG
+}
+
+func (c *KubectlLog) Serialize() jsonutils.JSONObject {
+	return jsonutils.Marshal(c.env)
+}
+
 func (c *KubectlLog) Follow() *KubectlLog {
 	// -f: Specify if the logs should be streamed
 	c.AppendArgs("-f")
@@ -171,7 +222,9 @@ func (c *KubectlLog) Since(data jsonutils.JSONObject) *KubectlLog {
 }
 
 func NewPodLogCommand(env *K8sEnv) ICommand {
-	return NewKubectlCommand(env.Kubeconfig, env.Namespace).Logs().
+	kubectl := NewKubectlCommand(env.Kubeconfig, env.Namespace)
+	kubectl.env = env
+	return kubectl.Logs().
 		Follow().
 		Pod(env.Pod).
 		Since(env.Data).
@@ -18,6 +18,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"yunion.io/x/jsonutils"
 )
 
 func TestKubectlExec_Command(t *testing.T) {
@@ -63,3 +65,33 @@ func TestKubectlExec_Command(t *testing.T) {
 		})
 	}
 }
+
+func TestKubectl_Serialize(t *testing.T) {
+	env := &K8sEnv{
+		Cluster:    "default",
+		Namespace:  "system",
+		Pod:        "Pod1",
+		Container:  "Container1",
+		Kubeconfig: "/tmp/kubeconfig",
+		Data:       jsonutils.Marshal(map[string]string{"since": "5m"}),
+	}
+	for _, cmd := range []ICommand{NewPodBashCommand(env), NewPodLogCommand(env)} {
+		data := cmd.(interface {
+			Serialize() jsonutils.JSONObject
+		}).Serialize()
+		if data.Contains("kubeconfig") {
+			t.Errorf("local kubeconfig path should not be serialized: %s", data)
+		}
+		got := &K8sEnv{}
+		if err := data.Unmarshal(got); err != nil {
+			t.Fatalf("unmarshal %s: %v", data, err)
+		}
+		got.Kubeconfig = env.Kubeconfig
+		if got.Pod != env.Pod || got.Container != env.Container || got.Namespace != env.Namespace || got.Cluster != env.Cluster {
+			t.Errorf("restored env = %#v, want %#v", got, env)
+		}
+		if since, _ := got.Data.GetString("since"); since != "5m" {
+			t.Errorf("restored since = %q, want 5m", since)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/util/regutils"
+
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
+)
+
+const (
+	DATA_TYPE_SERIAL_CONSOLE = "serial_console"
+)
+
+var (
+	serialTokenReg = regexp.MustCompile(`^[0-9a-f]+$`)
+)
+
+type SerialInfo struct {
+	Id   string `json:"id"`
+	Host string `json:"host"`
+	Port int64  `json:"port"`
+	// one-time token presented in the first line to the serial forward
+	Token string `json:"token"`
+}
+
+// SerialConsole attaches to the tcp chardev of a KVM guest serial port
+type SerialConsole struct {
+	*BaseCommand
+	Info *SerialInfo
+}
+
+func NewSerialConsoleCommand(info *SerialInfo) (*SerialConsole, error) {
+	if info.Host == "" {
+		return nil, fmt.Errorf("Empty serial host")
+	}
+	if info.Port <= 0 {
+		return nil, fmt.Errorf("Invalid serial port: %d", info.Port)
+	}
+	if _, err := os.Stat(o.Options.SocatPath); err != nil {
+		return nil, fmt.Errorf("socat %s not available: %v", o.Options.SocatPath, err)
+	}
+	// both are interpolated into the shell command of socat
+	if !regutils.MatchIPAddr(info.Host) && !regutils.MatchDomainName(info.Host) {
+		return nil, fmt.Errorf("Invalid serial host: %s", info.Host)
+	}
+	if !serialTokenReg.MatchString(info.Token) {
+		return nil, fmt.Errorf("Invalid serial token")
+	}
+	// the token line is sent ahead of the terminal input
+	forward := fmt.Sprintf("{ echo %s; exec cat; } | exec %s - tcp:%s:%d", info.Token, o.Options.SocatPath, info.Host, info.Port)
+	cmd := NewBaseCommand(o.Options.SocatPath, "-,raw,echo=0", "SYSTEM:"+forward)
+	return &SerialConsole{
+		BaseCommand: cmd,
+		Info:        info,
+	}, nil
+}
+
+func (c *SerialConsole) GetCommand() *exec.Cmd {
+	cmd := c.BaseCommand.GetCommand()
+	cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+	return cmd
+}
+
+func (c SerialConsole) GetProtocol() string {
+	return PROTOCOL_TTY
+}
+
+func (c SerialConsole) GetDataType() string {
+	return DATA_TYPE_SERIAL_CONSOLE
+}
+
+func (c SerialConsole) Serialize() jsonutils.JSONObject {
+	return jsonutils.Marshal(c.Info)
+}
@@ -34,8 +34,19 @@ import (
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
 )
 
+const (
+	DATA_TYPE_SSHTOOL_SOL = "sshtool_sol"
+)
+
+type SSHInfo struct {
+	IP string `json:"ip"`
+	// ProjectId owns the keypair to log in automatically
+	ProjectId string `json:"project_id"`
+}
+
 type SSHtoolSol struct {
 	*BaseCommand
+	Info     *SSHInfo
 	IP       string
 	Username string
 	reTry    int
@@ -43,13 +54,13 @@ type SSHtoolSol struct {
 	keyFile  string
 }
 
-func getCommand(ctx context.Context, userCred mcclient.TokenCredential, ip string) (string, *BaseCommand, error) {
+func getCommand(ctx context.Context, projectId string, ip string) (string, *BaseCommand, error) {
 	cmd := NewBaseCommand(o.Options.SshToolPath)
 	if !o.Options.EnableAutoLogin {
 		return "", nil, nil
 	}
 	s := auth.GetAdminSession(ctx, o.Options.Region, "v2")
-	key, err := modules.Sshkeypairs.GetById(s, userCred.GetProjectId(), jsonutils.Marshal(map[string]bool{"admin": true}))
+	key, err := modules.Sshkeypairs.GetById(s, projectId, jsonutils.Marshal(map[string]bool{"admin": true}))
 	if err != nil {
 		return "", nil, err
 	}
@@ -81,18 +92,24 @@ func getCommand(ctx context.Context, userCred mcclient.TokenCredential, ip strin
 }
 
 func NewSSHtoolSolCommand(ctx context.Context, userCred mcclient.TokenCredential, ip string) (*SSHtoolSol, error) {
+	return NewSSHtoolSolCommandByInfo(ctx, &SSHInfo{IP: ip, ProjectId: userCred.GetProjectId()})
+}
+
+func NewSSHtoolSolCommandByInfo(ctx context.Context, info *SSHInfo) (*SSHtoolSol, error) {
+	ip := info.IP
 	if conn, err := net.DialTimeout("tcp", ip+":22", time.Second*2); err != nil {
 		return nil, fmt.Errorf("IPAddress %s not accessable", ip)
 	} else {
 		conn.Close()
 
-		keyFile, cmd, err := getCommand(ctx, userCred, ip)
+		keyFile, cmd, err := getCommand(ctx, info.ProjectId, ip)
 		if err != nil {
 			log.Errorf("getCommand error: %v", err)
 		}
 
 		return &SSHtoolSol{
 			BaseCommand: cmd,
+			Info:        info,
 			IP:          ip,
 			Username:    "",
 			reTry:       0,
@@ -123,6 +140,14 @@ func (c *SSHtoolSol) GetProtocol() string {
 	return PROTOCOL_TTY
 }
 
+func (c *SSHtoolSol) GetDataType() string {
+	return DATA_TYPE_SSHTOOL_SOL
+}
+
+func (c *SSHtoolSol) Serialize() jsonutils.JSONObject {
+	return jsonutils.Marshal(c.Info)
+}
+
 func (c *SSHtoolSol) Connect() error {
 	conn, err := net.DialTimeout("tcp", c.IP+":22", time.Second*2)
 	if err != nil {
@@ -17,12 +17,15 @@ package webconsole
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
+	"os"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 
+	"yunion.io/x/onecloud/pkg/appctx"
 	"yunion.io/x/onecloud/pkg/appsrv"
 	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
 	"yunion.io/x/onecloud/pkg/httperrors"
@@ -48,6 +51,10 @@ func InitHandlers(app *appsrv.Application) {
 	app.AddHandler("POST", ApiPathPrefix+"baremetal/<id>", auth.Authenticate(handleBaremetalShell))
 	app.AddHandler("POST", ApiPathPrefix+"ssh/<ip>", auth.Authenticate(handleSshShell))
 	app.AddHandler("POST", ApiPathPrefix+"server/<id>", auth.Authenticate(handleServerRemoteConsole))
+	app.AddHandler("POST", ApiPathPrefix+"server/<id>/serial", auth.Authenticate(handleServerSerialConsole))
+	app.AddHandler("GET", ApiPathPrefix+"consolesessions", auth.Authenticate(handleListConsoleSessions))
+	app.AddHandler("GET", ApiPathPrefix+"consolesessions/<id>", auth.Authenticate(handleGetConsoleSession))
+	app.AddHandler("GET", ApiPathPrefix+"consolesessions/<id>/recording", auth.Authenticate(handleGetConsoleSessionRecording))
 }
 
 func fetchK8sEnv(ctx context.Context, w http.ResponseWriter, r *http.Request) (*command.K8sEnv, error) {
@@ -75,28 +82,17 @@ func fetchK8sEnv(ctx context.Context, w http.ResponseWriter, r *http.Request) (*
 		return nil, httperrors.NewNotFoundError("Not found pod %q", podName)
 	}
 
-	data := jsonutils.NewDict()
-	ret, err := k8s.KubeClusters.GetSpecific(adminSession, cluster, "kubeconfig", data)
+	kubeconfig, err := command.FetchKubeconfig(ctx, cluster)
 	if err != nil {
 		return nil, err
 	}
-	conf, err := ret.GetString("kubeconfig")
-	if err != nil {
-		return nil, httperrors.NewNotFoundError("Not found cluster %q kubeconfig", cluster)
-	}
-	f, err := ioutil.TempFile("", "kubeconfig-")
-	if err != nil {
-		return nil, fmt.Errorf("Save kubeconfig error: %v", err)
-	}
-	f.WriteString(conf)
-	defer f.Close()
 
 	return &command.K8sEnv{
 		Cluster:    cluster,
 		Namespace:  namespace,
 		Pod:        podName,
 		Container:  container,
-		Kubeconfig: f.Name(),
+		Kubeconfig: kubeconfig,
 		Data:       body,
 	}, nil
 }
@@ -138,7 +134,7 @@ func handleK8sCommand(
 	}
 
 	cmd := cmdFactory(env)
-	handleCommandSession(cmd, w)
+	handleCommandSession(ctx, cmd, fmt.Sprintf("%s/%s/%s", env.Cluster, env.Namespace, env.Pod), w)
 }
 
 func handleK8sShell(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -161,7 +157,7 @@ func handleSshShell(ctx context.Context, w http.ResponseWriter, r *http.Request)
 		httperrors.GeneralServerError(w, err)
 		return
 	}
-	handleCommandSession(cmd, w)
+	handleCommandSession(ctx, cmd, env.Params["<ip>"], w)
 }
 
 func handleBaremetalShell(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -187,7 +183,7 @@ func handleBaremetalShell(ctx context.Context, w http.ResponseWriter, r *http.Re
 		httperrors.GeneralServerError(w, err)
 		return
 	}
-	handleCommandSession(cmd, w)
+	handleCommandSession(ctx, cmd, hostId, w)
 }
 
 func handleServerRemoteConsole(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -206,12 +202,38 @@ func handleServerRemoteConsole(ctx context.Context, w http.ResponseWriter, r *ht
 	case session.ALIYUN, session.QCLOUD, session.OPENSTACK, session.VMRC:
 		responsePublicCloudConsole(info, w)
 	case session.VNC, session.SPICE, session.WMKS:
-		handleDataSession(info, w, url.Values{"password": {info.GetPassword()}})
+		handleDataSession(ctx, info, srvId, w, url.Values{"password": {info.GetPassword()}})
 	default:
 		httperrors.NotAcceptableError(w, "Unspported remote console protocol: %s", info.Protocol)
 	}
 }
 
+func handleServerSerialConsole(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	env, err := fetchCloudEnv(ctx, w, r)
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return
+	}
+	srvId := env.Params["<id>"]
+	ret, err := modules.Servers.GetSpecific(env.ClientSessin, srvId, "serial", nil)
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return
+	}
+	info := command.SerialInfo{}
+	err = ret.Unmarshal(&info)
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return
+	}
+	cmd, err := command.NewSerialConsoleCommand(&info)
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return
+	}
+	handleCommandSession(ctx, cmd, srvId, w)
+}
+
 func responsePublicCloudConsole(info *session.RemoteConsoleInfo, w http.ResponseWriter) {
 	data := jsonutils.NewDict()
 	params, err := info.GetConnectParams()
@@ -223,8 +245,9 @@ func responsePublicCloudConsole(info *session.RemoteConsoleInfo, w http.Response
 	sendJSON(w, data)
 }
 
-func handleDataSession(sData session.ISessionData, w http.ResponseWriter, connParams url.Values) {
-	s, err := session.Manager.Save(sData)
+func handleDataSession(ctx context.Context, sData session.ISessionData, target string, w http.ResponseWriter, connParams url.Values) {
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+	s, err := session.Manager.Save(sData, userCred, target)
 	if err != nil {
 		httperrors.GeneralServerError(w, err)
 		return
@@ -240,8 +263,111 @@ func handleDataSession(sData session.ISessionData, w http.ResponseWriter, connPa
 	sendJSON(w, data)
 }
 
-func handleCommandSession(cmd command.ICommand, w http.ResponseWriter) {
-	handleDataSession(cmd, w, nil)
+func handleCommandSession(ctx context.Context, cmd command.ICommand, target string, w http.ResponseWriter) {
+	handleDataSession(ctx, cmd, target, w, nil)
+}
+
+func checkConsoleSessionAuditAllow(ctx context.Context, w http.ResponseWriter, action string) bool {
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+	if userCred == nil || !userCred.IsAdminAllow("webconsole", "consolesessions", action) {
+		httperrors.ForbiddenError(w, "not allow to %s consolesessions", action)
+		return false
+	}
+	return true
+}
+
+func handleListConsoleSessions(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !checkConsoleSessionAuditAllow(ctx, w, policy.PolicyActionList) {
+		return
+	}
+	_, query, _ := appsrv.FetchEnv(ctx, w, r)
+	recs, err := session.Manager.ListRecords()
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return
+	}
+	filters := map[string]string{}
+	for _, key := range []string{"user", "project", "target", "protocol"} {
+		if val, _ := query.GetString(key); len(val) > 0 {
+			filters[key] = val
+		}
+	}
+	ret := jsonutils.NewArray()
+	for _, rec := range recs {
+		info := rec.AuditInfo()
+		match := true
+		for key, val := range filters {
+			if v, _ := info.GetString(key); v != val {
+				if v, _ = info.GetString(key + "_id"); v != val {
+					match = false
+					break
+				}
+			}
+		}
+		if match {
+			ret.Add(info)
+		}
+	}
+	body := jsonutils.NewDict()
+	body.Add(ret, "consolesessions")
+	body.Add(jsonutils.NewInt(int64(ret.Length())), "total")
+	appsrv.SendJSON(w, body)
+}
+
+func fetchConsoleSessionRecord(ctx context.Context, w http.ResponseWriter) (*session.SSessionRecord, bool) {
+	params := appctx.AppContextParams(ctx)
+	rec, err := session.Manager.GetRecord(params["<id>"])
+	if err == session.ErrSessionRecordNotFound {
+		httperrors.NotFoundError(w, "consolesession %s not found", params["<id>"])
+		return nil, false
+	}
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return nil, false
+	}
+	return rec, true
+}
+
+func handleGetConsoleSession(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !checkConsoleSessionAuditAllow(ctx, w, policy.PolicyActionGet) {
+		return
+	}
+	rec, ok := fetchConsoleSessionRecord(ctx, w)
+	if !ok {
+		return
+	}
+	body := jsonutils.NewDict()
+	body.Add(rec.AuditInfo(), "consolesession")
+	appsrv.SendJSON(w, body)
+}
+
+func handleGetConsoleSessionRecording(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !checkConsoleSessionAuditAllow(ctx, w, policy.PolicyActionGet) {
+		return
+	}
+	rec, ok := fetchConsoleSessionRecord(ctx, w)
+	if !ok {
+		return
+	}
+	if len(rec.Recording) == 0 {
+		httperrors.NotFoundError(w, "consolesession %s has no recording", rec.Id)
+		return
+	}
+	f, err := os.Open(session.RecordingPath(rec.Recording))
+	if os.IsNotExist(err) {
+		httperrors.NotFoundError(w, "recording of consolesession %s not found on instance %s", rec.Id, rec.Instance)
+		return
+	}
+	if err != nil {
+		httperrors.GeneralServerError(w, err)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", rec.Recording))
+	if _, err := io.Copy(w, f); err != nil {
+		log.Errorf("send recording %s error: %v", rec.Recording, err)
+	}
 }
 
 func sendJSON(w http.ResponseWriter, body jsonutils.JSONObject) {
@@ -14,7 +14,10 @@
 
 package options
 
-import common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
+import (
+	"yunion.io/x/onecloud/pkg/cloudcommon/etcd"
+	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
+)
 
 var (
 	Options WebConsoleOptions
@@ -22,11 +25,20 @@ var (
 
 type WebConsoleOptions struct {
 	common_options.CommonOptions
+	etcd.SEtcdOptions
 
 	ApiServer       string `help:"API server url to handle websocket connection, usually with public access" default:"http://webconsole.yunion.io"`
 	KubectlPath     string `help:"kubectl binary path used to connect k8s cluster" default:"/usr/bin/kubectl"`
 	IpmitoolPath    string `help:"ipmitool binary path used to connect baremetal sol" default:"/usr/bin/ipmitool"`
 	SshToolPath     string `help:"sshtool binary path used to connect server sol" default:"/usr/bin/ssh"`
 	SshpassToolPath string `help:"sshpass tool binary path used to connect server sol" default:"/usr/bin/sshpass"`
+	SocatPath       string `help:"socat binary path used to connect guest serial console" default:"/usr/bin/socat"`
 	EnableAutoLogin bool   `help:"allow webconsole to log in directly with the cloudroot public key" default:"false"`
+
+	SessionStore       string `help:"backend to keep console sessions, local or etcd" default:"local" choices:"local|etcd"`
+	SessionSecret      string `help:"secret shared by all webconsole replicas to encrypt session access token and data, random if not set, required by etcd store"`
+	SessionTTLSeconds  int    `help:"seconds an unused console session is kept in store" default:"3600"`
+	EnableRecording    bool   `help:"record tty sessions in asciicast format and vnc sessions as frame logs" default:"false"`
+	RecordingDir       string `help:"directory to save session recordings, should be shared by all replicas" default:"/opt/cloud/workspace/webconsole/recordings"`
+	RecordRetentionDay int    `help:"days to keep closed console session records" default:"30"`
 }
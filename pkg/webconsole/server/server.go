@@ -51,8 +51,10 @@ func (s *ConnectionServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	var srv http.Handler
 	protocol := sessionObj.GetProtocol()
 	switch protocol {
-	case session.VNC, session.SPICE:
+	case session.VNC:
 		srv, err = NewWebsockifyServer(sessionObj)
+	case session.SPICE:
+		srv, err = NewSpiceProxyServer(sessionObj)
 	case session.WMKS:
 		srv, err = NewWebsocketProxyServer(sessionObj)
 	default:
@@ -0,0 +1,47 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/webconsole/session"
+)
+
+type SpiceProxyServer struct {
+	*WebsockifyServer
+}
+
+// NewSpiceProxyServer serves a channel of the spice client, the session is
+// kept until its last channel is closed
+func NewSpiceProxyServer(s *session.SSession) (*SpiceProxyServer, error) {
+	srv, err := NewWebsockifyServer(s)
+	if err != nil {
+		session.Manager.ReleaseSpiceChannel(s.Id)
+		return nil, err
+	}
+	once := &sync.Once{}
+	srv.onClose = func() {
+		once.Do(func() {
+			if session.Manager.ReleaseSpiceChannel(s.Id) {
+				log.Infof("All spice channels of session %s closed", s.Id)
+				s.Close()
+			}
+		})
+	}
+	return &SpiceProxyServer{srv}, nil
+}
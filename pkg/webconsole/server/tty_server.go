@@ -84,7 +84,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 				} else if n, err := p.Pty.Read(buf); err != nil {
 					p.IsOk = false
 				} else {
-					so.Emit(OUTPUT_EVENT, string(buf[0:n]))
+					emitOutput(so, p, string(buf[0:n]))
 				}
 				if !p.IsOk {
 					if err := p.Session.Connect(); err != nil {
@@ -94,7 +94,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 					}
 					p.Stop()
 					if info := p.Session.ShowInfo(); len(info) > 0 {
-						so.Emit(OUTPUT_EVENT, info)
+						emitOutput(so, p, info)
 					}
 				}
 			} else if p.Exit {
@@ -116,9 +116,9 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 			}
 			if data == "\r" {
 				p.Show, p.Output, p.Command = p.Session.GetData(p.Buffer)
-				so.Emit(OUTPUT_EVENT, "\r\n")
+				emitOutput(so, p, "\r\n")
 				if len(p.Output) > 0 {
-					so.Emit(OUTPUT_EVENT, p.Output)
+					emitOutput(so, p, p.Output)
 				}
 				if len(p.Command) > 0 {
 					log.Infof("exec: %s", p.Command)
@@ -126,7 +126,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 					cmd := exec.Command(args[0], args[1:]...)
 					cmd.Env = append(cmd.Env, "TERM=xterm-256color")
 					if _pty, err := pty.Start(cmd); err != nil {
-						so.Emit(OUTPUT_EVENT, err.Error()+"\r\n")
+						emitOutput(so, p, err.Error()+"\r\n")
 						log.Errorf("exec error: %v", err)
 					} else {
 						p.Pty, p.Cmd, p.IsOk = _pty, cmd, true
@@ -146,9 +146,12 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 				p.Buffer += data
 			}
 			if p.Show && len(data) > 0 {
-				so.Emit(OUTPUT_EVENT, data)
+				emitOutput(so, p, data)
 			}
 		} else {
+			if p.Recorder != nil {
+				p.Recorder.Input(data)
+			}
 			p.Pty.Write([]byte(data))
 		}
 	})
@@ -185,6 +188,13 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 	})
 }
 
+func emitOutput(so socketio.Socket, p *session.Pty, data string) {
+	if p.Recorder != nil {
+		p.Recorder.Output(data)
+	}
+	so.Emit(OUTPUT_EVENT, data)
+}
+
 func cleanUp(so socketio.Socket, p *session.Pty) {
 	so.Disconnect()
 	p.Stop()
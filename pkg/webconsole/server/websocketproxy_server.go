@@ -61,4 +61,5 @@ func NewWebsocketProxyServer(s *session.SSession) (*WebsocketProxyServer, error)
 
 func (s *WebsocketProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.proxy.ServeHTTP(w, r)
+	s.Session.Close()
 }
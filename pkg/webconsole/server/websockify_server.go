@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/websocket"
 
@@ -45,6 +46,10 @@ type WebsockifyServer struct {
 	Session    *session.SSession
 	TargetHost string
 	TargetPort int64
+	Recorder   *session.SFrameRecorder
+
+	onClose  func()
+	exitOnce *sync.Once
 }
 
 func NewWebsockifyServer(s *session.SSession) (*WebsockifyServer, error) {
@@ -60,6 +65,10 @@ func NewWebsockifyServer(s *session.SSession) (*WebsockifyServer, error) {
 		Session:    s,
 		TargetHost: info.Host,
 		TargetPort: info.Port,
+		exitOnce:   &sync.Once{},
+	}
+	server.onClose = func() {
+		s.Close()
 	}
 	return server, nil
 }
@@ -73,6 +82,7 @@ func (s *WebsockifyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	wsConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Errorf("New websocket connection error: %v", err)
+		s.onClose()
 		return
 	}
 	log.Debugf("Get coordinate subprotocol: %s", wsConn.Subprotocol())
@@ -82,6 +92,7 @@ func (s *WebsockifyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Errorf("Connection to target %s error: %v", targetConn, err)
 		wsConn.Close()
+		s.onClose()
 		return
 	}
 
@@ -89,6 +100,7 @@ func (s *WebsockifyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WebsockifyServer) doProxy(wsConn *websocket.Conn, tcpConn net.Conn) {
+	s.Recorder = s.Session.AcquireFrameRecorder()
 	go s.wsToTcp(wsConn, tcpConn)
 	s.tcpToWs(wsConn, tcpConn)
 }
@@ -118,6 +130,9 @@ func (s *WebsockifyServer) wsToTcp(wsConn *websocket.Conn, tcpConn net.Conn) {
 			return
 		}
 
+		if s.Recorder != nil {
+			s.Recorder.Frame(session.FRAME_CLIENT_TO_SERVER, data)
+		}
 		_, err = tcpConn.Write(data)
 		if err != nil {
 			log.Errorf("Write to tcp socket error: %v", err)
@@ -147,6 +162,9 @@ func (s *WebsockifyServer) tcpToWs(wsConn *websocket.Conn, tcpConn net.Conn) {
 			return
 		}
 
+		if s.Recorder != nil {
+			s.Recorder.Frame(session.FRAME_SERVER_TO_CLIENT, buffer[0:n])
+		}
 		err = s.WriteToWs(wsConn, buffer[0:n])
 		if err != nil {
 			log.Errorf("Write to websocket error: %v", err)
@@ -155,8 +173,14 @@ func (s *WebsockifyServer) tcpToWs(wsConn *websocket.Conn, tcpConn net.Conn) {
 	}
 }
 
+// onExit is called by both directions of the proxy, only the first one cleans up
 func (s *WebsockifyServer) onExit(wsConn *websocket.Conn, tcpConn net.Conn) {
-	wsConn.Close()
-	tcpConn.Close()
-	s.Session.Close()
+	s.exitOnce.Do(func() {
+		wsConn.Close()
+		tcpConn.Close()
+		if s.Recorder != nil {
+			s.Session.ReleaseFrameRecorder()
+		}
+		s.onClose()
+	})
 }
@@ -26,10 +26,12 @@ import (
 	"yunion.io/x/log"
 
 	app_common "yunion.io/x/onecloud/pkg/cloudcommon/app"
+	"yunion.io/x/onecloud/pkg/cloudcommon/etcd"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
 	"yunion.io/x/onecloud/pkg/webconsole"
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
 	"yunion.io/x/onecloud/pkg/webconsole/server"
+	"yunion.io/x/onecloud/pkg/webconsole/session"
 )
 
 func ensureBinExists(binPath string) {
@@ -56,6 +58,26 @@ func StartService() {
 		ensureBinExists(binPath)
 	}
 
+	if len(opts.SessionSecret) > 0 {
+		session.AES_KEY = opts.SessionSecret
+	}
+	if opts.SessionStore == session.SESSION_STORE_ETCD {
+		// the sessions shared by replicas are only readable with the same key
+		if len(opts.SessionSecret) == 0 {
+			log.Fatalf("--session-secret must be specified for session store %s", opts.SessionStore)
+		}
+		err := etcd.InitDefaultEtcdClient(&opts.SEtcdOptions)
+		if err != nil {
+			log.Fatalf("init etcd fail: %s", err)
+		}
+		defer etcd.CloseDefaultEtcdClient()
+	}
+	store, err := session.NewSessionStore(opts.SessionStore)
+	if err != nil {
+		log.Fatalf("init session store: %v", err)
+	}
+	session.Manager.SetStore(store)
+
 	app_common.InitAuth(commonOpts, func() {
 		log.Infof("Auth complete")
 	})
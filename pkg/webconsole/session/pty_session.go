@@ -38,6 +38,7 @@ type Pty struct {
 	Output     string
 	Command    string
 	Exit       bool
+	Recorder   *SAsciicastRecorder
 }
 
 func NewPty(session *SSession) (p *Pty, err error) {
@@ -50,6 +51,7 @@ func NewPty(session *SSession) (p *Pty, err error) {
 		Exit:    false,
 		Pty:     nil,
 	}
+	p.Recorder = session.NewTTYRecorder()
 	log.Debugf("[session %s] Start command: %#v", session.Id, cmd)
 	if cmd != nil {
 		p.Pty, err = pty.Start(p.Cmd)
@@ -82,6 +84,9 @@ func (p *Pty) startResizeMonitor() {
 func (p *Pty) Resize(size *pty.Winsize) {
 	p.size = size
 	p.sizeCh <- syscall.SIGWINCH
+	if p.Recorder != nil {
+		p.Recorder.Resize(size.Cols, size.Rows)
+	}
 }
 
 func (p *Pty) Stop() {
@@ -97,5 +102,10 @@ func (p *Pty) Stop() {
 			log.Errorf("Wait command error: %v", err)
 		}
 	}
+	if p.Recorder != nil {
+		if err := p.Recorder.Close(); err != nil {
+			log.Errorf("Close recorder error: %v", err)
+		}
+	}
 	p.Session.Close()
 }
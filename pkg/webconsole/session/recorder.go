@@ -0,0 +1,173 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
+)
+
+const (
+	ASCIICAST_EVENT_OUTPUT = "o"
+	ASCIICAST_EVENT_INPUT  = "i"
+	ASCIICAST_EVENT_RESIZE = "r"
+
+	FRAME_CLIENT_TO_SERVER = "c2s"
+	FRAME_SERVER_TO_CLIENT = "s2c"
+
+	RECORDING_TTY_SUFFIX   = ".cast"
+	RECORDING_FRAME_SUFFIX = ".frames"
+)
+
+type sRecordWriter struct {
+	lock    *sync.Mutex
+	file    *os.File
+	startAt time.Time
+}
+
+func newRecordWriter(path string) (*sRecordWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &sRecordWriter{
+		lock:    &sync.Mutex{},
+		file:    f,
+		startAt: time.Now(),
+	}, nil
+}
+
+func (w *sRecordWriter) writeLine(obj interface{}) {
+	line, err := json.Marshal(obj)
+	if err != nil {
+		log.Errorf("marshal record line error: %v", err)
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.file == nil {
+		return
+	}
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		log.Errorf("write record %s error: %v", w.file.Name(), err)
+	}
+}
+
+func (w *sRecordWriter) elapsed() float64 {
+	return time.Since(w.startAt).Seconds()
+}
+
+func (w *sRecordWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// SAsciicastRecorder writes tty sessions in asciicast v2 format, which can be
+// replayed by asciinema player
+type SAsciicastRecorder struct {
+	*sRecordWriter
+}
+
+type sAsciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env"`
+}
+
+func NewAsciicastRecorder(path string, title string) (*SAsciicastRecorder, error) {
+	w, err := newRecordWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := &SAsciicastRecorder{w}
+	rec.writeLine(sAsciicastHeader{
+		Version:   2,
+		Width:     80,
+		Height:    24,
+		Timestamp: w.startAt.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	})
+	return rec, nil
+}
+
+func (rec *SAsciicastRecorder) event(evType string, data string) {
+	rec.writeLine([]interface{}{rec.elapsed(), evType, data})
+}
+
+func (rec *SAsciicastRecorder) Output(data string) {
+	rec.event(ASCIICAST_EVENT_OUTPUT, data)
+}
+
+func (rec *SAsciicastRecorder) Input(data string) {
+	rec.event(ASCIICAST_EVENT_INPUT, data)
+}
+
+func (rec *SAsciicastRecorder) Resize(cols, rows uint16) {
+	rec.event(ASCIICAST_EVENT_RESIZE, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// SFrameRecorder logs raw frames of graphic console sessions, one json line
+// per frame with its direction and elapsed seconds
+type SFrameRecorder struct {
+	*sRecordWriter
+}
+
+type sFrame struct {
+	Time      float64 `json:"time"`
+	Direction string  `json:"direction"`
+	Data      string  `json:"data"`
+}
+
+func NewFrameRecorder(path string) (*SFrameRecorder, error) {
+	w, err := newRecordWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SFrameRecorder{w}, nil
+}
+
+func (rec *SFrameRecorder) Frame(direction string, data []byte) {
+	rec.writeLine(sFrame{
+		Time:      rec.elapsed(),
+		Direction: direction,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	})
+}
+
+func RecordingPath(name string) string {
+	return filepath.Join(o.Options.RecordingDir, filepath.Base(name))
+}
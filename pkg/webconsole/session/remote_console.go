@@ -15,12 +15,16 @@
 package session
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"os/exec"
 
+	"yunion.io/x/jsonutils"
+
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/webconsole/command"
 )
 
 const (
@@ -33,6 +37,68 @@ const (
 	VMRC      = "vmrc"
 )
 
+const (
+	DATA_TYPE_REMOTE_CONSOLE = "remote_console"
+)
+
+func init() {
+	RegisterSessionDataFactory(DATA_TYPE_REMOTE_CONSOLE, func(data jsonutils.JSONObject) (ISessionData, error) {
+		info := &RemoteConsoleInfo{}
+		if err := data.Unmarshal(info); err != nil {
+			return nil, err
+		}
+		return info, nil
+	})
+	RegisterSessionDataFactory(command.DATA_TYPE_IPMITOOL_SOL, func(data jsonutils.JSONObject) (ISessionData, error) {
+		info := &command.IpmiInfo{}
+		if err := data.Unmarshal(info); err != nil {
+			return nil, err
+		}
+		return command.NewIpmitoolSolCommand(info)
+	})
+	RegisterSessionDataFactory(command.DATA_TYPE_SERIAL_CONSOLE, func(data jsonutils.JSONObject) (ISessionData, error) {
+		info := &command.SerialInfo{}
+		if err := data.Unmarshal(info); err != nil {
+			return nil, err
+		}
+		return command.NewSerialConsoleCommand(info)
+	})
+	RegisterSessionDataFactory(command.DATA_TYPE_SSHTOOL_SOL, func(data jsonutils.JSONObject) (ISessionData, error) {
+		info := &command.SSHInfo{}
+		if err := data.Unmarshal(info); err != nil {
+			return nil, err
+		}
+		return command.NewSSHtoolSolCommandByInfo(context.Background(), info)
+	})
+	RegisterSessionDataFactory(command.DATA_TYPE_KUBECTL_EXEC, func(data jsonutils.JSONObject) (ISessionData, error) {
+		env, err := restoreK8sEnv(data)
+		if err != nil {
+			return nil, err
+		}
+		return command.NewPodBashCommand(env), nil
+	})
+	RegisterSessionDataFactory(command.DATA_TYPE_KUBECTL_LOG, func(data jsonutils.JSONObject) (ISessionData, error) {
+		env, err := restoreK8sEnv(data)
+		if err != nil {
+			return nil, err
+		}
+		return command.NewPodLogCommand(env), nil
+	})
+}
+
+func restoreK8sEnv(data jsonutils.JSONObject) (*command.K8sEnv, error) {
+	env := &command.K8sEnv{}
+	if err := data.Unmarshal(env); err != nil {
+		return nil, err
+	}
+	kubeconfig, err := command.FetchKubeconfig(context.Background(), env.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	env.Kubeconfig = kubeconfig
+	return env, nil
+}
+
 type RemoteConsoleInfo struct {
 	Host        string `json:"host"`
 	Port        int64  `json:"port"`
@@ -78,6 +144,14 @@ func (info *RemoteConsoleInfo) GetProtocol() string {
 }
 
 // GetCommand implements ISessionData interface
+func (info *RemoteConsoleInfo) GetDataType() string {
+	return DATA_TYPE_REMOTE_CONSOLE
+}
+
+func (info *RemoteConsoleInfo) Serialize() jsonutils.JSONObject {
+	return jsonutils.Marshal(info)
+}
+
 func (info *RemoteConsoleInfo) GetCommand() *exec.Cmd {
 	return nil
 }
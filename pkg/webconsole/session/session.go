@@ -18,14 +18,17 @@ import (
 	"fmt"
 	"math/rand"
 	"net/url"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/golang-plus/uuid"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/utils"
 
+	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/webconsole/command"
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
 )
@@ -41,18 +44,73 @@ func init() {
 	AES_KEY = fmt.Sprintf("webconsole-%f", rand.Float32())
 }
 
+type TSessionDataFactory func(data jsonutils.JSONObject) (ISessionData, error)
+
+var sessionDataFactories = map[string]TSessionDataFactory{}
+
+// RegisterSessionDataFactory registers how to rebuild session data saved by
+// another webconsole replica
+func RegisterSessionDataFactory(dataType string, factory TSessionDataFactory) {
+	sessionDataFactories[dataType] = factory
+}
+
 type SSessionManager struct {
 	*sync.Map
+	store    ISessionStore
+	instance string
+
+	// spice clients open one websocket per channel (main, display, inputs,
+	// cursor ...) with the same token, counted by session id
+	spiceLock     *sync.Mutex
+	spiceChannels map[string]int
+
+	// the records of the sessions being served by this instance are kept
+	// alive until the sessions are closed
+	aliveLock *sync.Mutex
+	alives    map[string]chan struct{}
+
+	recorderLock   *sync.Mutex
+	frameRecorders map[string]*sFrameRecorderRef
+}
+
+type sFrameRecorderRef struct {
+	recorder *SFrameRecorder
+	refs     int
 }
 
 func NewSessionManager() *SSessionManager {
+	instance, _ := os.Hostname()
 	s := &SSessionManager{
-		Map: &sync.Map{},
+		Map:           &sync.Map{},
+		store:         NewLocalSessionStore(),
+		instance:      instance,
+		spiceLock:     &sync.Mutex{},
+		spiceChannels: make(map[string]int),
+		aliveLock:     &sync.Mutex{},
+		alives:        make(map[string]chan struct{}),
+
+		recorderLock:   &sync.Mutex{},
+		frameRecorders: make(map[string]*sFrameRecorderRef),
 	}
 	return s
 }
 
-func (man *SSessionManager) Save(data ISessionData) (session *SSession, err error) {
+func (man *SSessionManager) SetStore(store ISessionStore) {
+	man.store = store
+}
+
+func sessionTTL() time.Duration {
+	if o.Options.SessionTTLSeconds > 0 {
+		return time.Duration(o.Options.SessionTTLSeconds) * time.Second
+	}
+	return time.Hour
+}
+
+func recordRetention() time.Duration {
+	return time.Duration(o.Options.RecordRetentionDay) * 24 * time.Hour
+}
+
+func (man *SSessionManager) Save(data ISessionData, userCred mcclient.TokenCredential, target string) (session *SSession, err error) {
 	key, err := uuid.NewV4()
 	if err != nil {
 		return
@@ -62,10 +120,44 @@ func (man *SSessionManager) Save(data ISessionData) (session *SSession, err erro
 	if err != nil {
 		return
 	}
+	rec := &SSessionRecord{
+		Id:          idStr,
+		AccessToken: token,
+		Protocol:    data.GetProtocol(),
+		Target:      target,
+		Instance:    man.instance,
+		CreatedAt:   time.Now(),
+	}
+	if sData, ok := data.(ISerializableSessionData); ok {
+		rec.DataType = sData.GetDataType()
+		// the data holds secrets like ipmi and vnc passwords
+		rec.Data, err = encryptSessionData(sData.Serialize())
+		if err != nil {
+			return nil, fmt.Errorf("encrypt session data: %v", err)
+		}
+	}
+	if userCred != nil {
+		rec.UserId = userCred.GetUserId()
+		rec.User = userCred.GetUserName()
+		rec.ProjectId = userCred.GetProjectId()
+		rec.Project = userCred.GetProjectName()
+	}
+	if o.Options.EnableRecording {
+		switch rec.Protocol {
+		case command.PROTOCOL_TTY:
+			rec.Recording = idStr + RECORDING_TTY_SUFFIX
+		case VNC, SPICE:
+			rec.Recording = idStr + RECORDING_FRAME_SUFFIX
+		}
+	}
+	if err = man.store.Put(rec, sessionTTL()); err != nil {
+		return nil, fmt.Errorf("save session record: %v", err)
+	}
 	session = &SSession{
 		Id:           idStr,
 		ISessionData: data,
 		AccessToken:  token,
+		record:       rec,
 	}
 	man.Store(idStr, session)
 	return
@@ -77,28 +169,236 @@ func (man *SSessionManager) Get(accessToken string) (*SSession, bool) {
 		log.Errorf("DescryptAESBase64Url error: %v", err)
 		return nil, false
 	}
-	obj, ok := man.Load(id)
-	if !ok {
+	rec, err := man.store.Get(id)
+	if err != nil {
+		log.Errorf("Get session %s record error: %v", id, err)
 		return nil, false
 	}
-	s := obj.(*SSession)
-	if time.Since(s.AccessedAt) < AccessInterval {
-		log.Warningf("Token: %s, Session: %s can't be accessed during %s, last accessed at: %s", accessToken, s.Id, AccessInterval, s.AccessedAt)
+	if rec.IsClosed() {
+		log.Warningf("Session %s already closed at %s", id, rec.ClosedAt)
 		return nil, false
 	}
-	s.AccessedAt = time.Now()
+	if rec.Protocol == SPICE {
+		man.spiceLock.Lock()
+		defer man.spiceLock.Unlock()
+		// the other channels join the connected first one as is
+		if man.spiceChannels[id] > 0 {
+			if obj, ok := man.Load(id); ok {
+				man.spiceChannels[id] += 1
+				return obj.(*SSession), true
+			}
+		}
+	}
+	if time.Since(rec.AccessedAt) < AccessInterval {
+		log.Warningf("Token: %s, Session: %s can't be accessed during %s, last accessed at: %s", accessToken, rec.Id, AccessInterval, rec.AccessedAt)
+		return nil, false
+	}
+	var s *SSession
+	if obj, ok := man.Load(id); ok {
+		s = obj.(*SSession)
+	} else {
+		s, err = man.restore(rec)
+		if err != nil {
+			log.Errorf("Restore session %s error: %v", id, err)
+			return nil, false
+		}
+	}
+	rec.AccessedAt = time.Now()
+	if err := man.store.Put(rec, sessionTTL()); err != nil {
+		log.Errorf("Update session %s record error: %v", id, err)
+	}
+	s.AccessedAt = rec.AccessedAt
+	s.record = rec
+	if rec.Protocol == SPICE {
+		man.spiceChannels[id] += 1
+	}
+	man.startKeepAlive(id)
 	return s, true
 }
 
+func keepAliveInterval() time.Duration {
+	return sessionTTL() / 2
+}
+
+// startKeepAlive refreshes the ttl of the session record periodically, so
+// that a console in use outlives SessionTTLSeconds
+func (man *SSessionManager) startKeepAlive(id string) {
+	man.aliveLock.Lock()
+	defer man.aliveLock.Unlock()
+	if _, ok := man.alives[id]; ok {
+		return
+	}
+	stop := make(chan struct{})
+	man.alives[id] = stop
+	interval := keepAliveInterval()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !man.refresh(id, stop) {
+					man.stopKeepAlive(id)
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (man *SSessionManager) stopKeepAlive(id string) {
+	man.aliveLock.Lock()
+	defer man.aliveLock.Unlock()
+	if stop, ok := man.alives[id]; ok {
+		close(stop)
+		delete(man.alives, id)
+	}
+}
+
+// refresh puts the record back with a new ttl, it returns false if the
+// session is gone. The lock keeps a close from being overwritten
+func (man *SSessionManager) refresh(id string, stop chan struct{}) bool {
+	man.aliveLock.Lock()
+	defer man.aliveLock.Unlock()
+	select {
+	case <-stop:
+		return true
+	default:
+	}
+	rec, err := man.store.Get(id)
+	if err == ErrSessionRecordNotFound {
+		return false
+	}
+	if err != nil {
+		log.Errorf("Get session %s record error: %v", id, err)
+		return true
+	}
+	if rec.IsClosed() {
+		return false
+	}
+	if err := man.store.Put(rec, sessionTTL()); err != nil {
+		log.Errorf("Refresh session %s record error: %v", id, err)
+	}
+	return true
+}
+
+// ReleaseSpiceChannel releases a channel got by Get, it returns true once the
+// last channel of the session is released
+func (man *SSessionManager) ReleaseSpiceChannel(id string) bool {
+	man.spiceLock.Lock()
+	defer man.spiceLock.Unlock()
+	man.spiceChannels[id] -= 1
+	if man.spiceChannels[id] > 0 {
+		return false
+	}
+	delete(man.spiceChannels, id)
+	return true
+}
+
+func (man *SSessionManager) restore(rec *SSessionRecord) (*SSession, error) {
+	factory, ok := sessionDataFactories[rec.DataType]
+	if !ok {
+		return nil, fmt.Errorf("session of protocol %s can only be served by instance %s", rec.Protocol, rec.Instance)
+	}
+	sData, err := decryptSessionData(rec.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt session data: %v", err)
+	}
+	data, err := factory(sData)
+	if err != nil {
+		return nil, err
+	}
+	s := &SSession{
+		ISessionData: data,
+		Id:           rec.Id,
+		AccessToken:  rec.AccessToken,
+		record:       rec,
+	}
+	man.Store(rec.Id, s)
+	return s, nil
+}
+
+func (man *SSessionManager) GetRecord(id string) (*SSessionRecord, error) {
+	return man.store.Get(id)
+}
+
+func (man *SSessionManager) ListRecords() ([]*SSessionRecord, error) {
+	return man.store.List()
+}
+
+func (man *SSessionManager) close(id string) {
+	man.stopKeepAlive(id)
+	man.Delete(id)
+	rec, err := man.store.Get(id)
+	if err != nil {
+		if err != ErrSessionRecordNotFound {
+			log.Errorf("Get session %s record error: %v", id, err)
+		}
+		return
+	}
+	if rec.IsClosed() {
+		return
+	}
+	retention := recordRetention()
+	if retention <= 0 {
+		if err := man.store.Delete(id); err != nil {
+			log.Errorf("Delete session %s record error: %v", id, err)
+		}
+		return
+	}
+	rec.ClosedAt = time.Now()
+	rec.AccessToken = ""
+	rec.Data = nil
+	if err := man.store.Put(rec, retention); err != nil {
+		log.Errorf("Save closed session %s record error: %v", id, err)
+	}
+}
+
+// encryptSessionData encrypts data with the secret shared by the replicas
+func encryptSessionData(data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	secret, err := utils.EncryptAESBase64(AES_KEY, data.String())
+	if err != nil {
+		return nil, err
+	}
+	return jsonutils.NewString(secret), nil
+}
+
+func decryptSessionData(data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if data == nil {
+		return nil, fmt.Errorf("empty session data")
+	}
+	secret, err := data.GetString()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := utils.DescryptAESBase64(AES_KEY, secret)
+	if err != nil {
+		return nil, err
+	}
+	return jsonutils.ParseString(plain)
+}
+
 type ISessionData interface {
 	command.ICommand
 }
 
+// ISerializableSessionData is implemented by session data which can be
+// rebuilt on any webconsole replica by the factory of its data type
+type ISerializableSessionData interface {
+	ISessionData
+	GetDataType() string
+	Serialize() jsonutils.JSONObject
+}
+
 type SSession struct {
 	ISessionData
 	Id          string
 	AccessToken string
 	AccessedAt  time.Time
+
+	record *SSessionRecord
 }
 
 func (s SSession) GetConnectParams(params url.Values) (string, error) {
@@ -111,10 +411,82 @@ func (s SSession) GetConnectParams(params url.Values) (string, error) {
 	return params.Encode(), nil
 }
 
+func (s *SSession) getRecordingPath(suffix string) string {
+	if s.record == nil || len(s.record.Recording) == 0 {
+		return ""
+	}
+	if ext := len(s.record.Recording) - len(suffix); ext < 0 || s.record.Recording[ext:] != suffix {
+		return ""
+	}
+	return RecordingPath(s.record.Recording)
+}
+
+// NewTTYRecorder returns nil if recording is not enabled for this session
+func (s *SSession) NewTTYRecorder() *SAsciicastRecorder {
+	path := s.getRecordingPath(RECORDING_TTY_SUFFIX)
+	if len(path) == 0 {
+		return nil
+	}
+	rec, err := NewAsciicastRecorder(path, fmt.Sprintf("%s@%s", s.record.User, s.record.Target))
+	if err != nil {
+		log.Errorf("Create asciicast recorder for session %s error: %v", s.Id, err)
+		return nil
+	}
+	return rec
+}
+
+// AcquireFrameRecorder returns nil if recording is not enabled for this
+// session, the recorder is shared by the channels of the session, e.g. the
+// spice channels, and closed once all of them release it
+func (s *SSession) AcquireFrameRecorder() *SFrameRecorder {
+	path := s.getRecordingPath(RECORDING_FRAME_SUFFIX)
+	if len(path) == 0 {
+		return nil
+	}
+	return Manager.acquireFrameRecorder(s.Id, path)
+}
+
+func (s *SSession) ReleaseFrameRecorder() {
+	Manager.releaseFrameRecorder(s.Id)
+}
+
+func (man *SSessionManager) acquireFrameRecorder(id string, path string) *SFrameRecorder {
+	man.recorderLock.Lock()
+	defer man.recorderLock.Unlock()
+	if ref, ok := man.frameRecorders[id]; ok {
+		ref.refs += 1
+		return ref.recorder
+	}
+	rec, err := NewFrameRecorder(path)
+	if err != nil {
+		log.Errorf("Create frame recorder for session %s error: %v", id, err)
+		return nil
+	}
+	man.frameRecorders[id] = &sFrameRecorderRef{recorder: rec, refs: 1}
+	return rec
+}
+
+func (man *SSessionManager) releaseFrameRecorder(id string) {
+	man.recorderLock.Lock()
+	defer man.recorderLock.Unlock()
+	ref, ok := man.frameRecorders[id]
+	if !ok {
+		return
+	}
+	ref.refs -= 1
+	if ref.refs > 0 {
+		return
+	}
+	delete(man.frameRecorders, id)
+	if err := ref.recorder.Close(); err != nil {
+		log.Errorf("Close frame recorder of session %s error: %v", id, err)
+	}
+}
+
 func (s *SSession) Close() error {
 	if err := s.ISessionData.Cleanup(); err != nil {
 		log.Errorf("Clean up command error: %v", err)
 	}
-	Manager.Delete(s.Id)
+	Manager.close(s.Id)
 	return nil
 }
@@ -0,0 +1,193 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/webconsole/command"
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
+)
+
+func TestSessionManager_Restore(t *testing.T) {
+	store := NewLocalSessionStore()
+	man := NewSessionManager()
+	man.SetStore(store)
+
+	info := &RemoteConsoleInfo{Host: "10.168.222.23", Port: 5901, Protocol: VNC}
+	s, err := man.Save(info, nil, "server1")
+	if err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	// another replica shares the store but not the live sessions
+	replica := NewSessionManager()
+	replica.SetStore(store)
+	got, ok := replica.Get(s.AccessToken)
+	if !ok {
+		t.Fatalf("session %s not restored on replica", s.Id)
+	}
+	rec, _ := store.Get(s.Id)
+	if strings.Contains(rec.Data.String(), info.Host) {
+		t.Errorf("session data is saved in plaintext: %s", rec.Data)
+	}
+	gotInfo, ok := got.ISessionData.(*RemoteConsoleInfo)
+	if !ok || gotInfo.Host != info.Host || gotInfo.Port != info.Port {
+		t.Errorf("restored data = %#v, want %#v", got.ISessionData, info)
+	}
+	if _, ok := man.Get(s.AccessToken); ok {
+		t.Errorf("session %s can be accessed again within %s", s.Id, AccessInterval)
+	}
+
+	replica.close(s.Id)
+	if _, err := store.Get(s.Id); err != ErrSessionRecordNotFound {
+		t.Errorf("closed session record without retention should be deleted, got %v", err)
+	}
+}
+
+type fakeTTYCommand struct {
+	*command.BaseCommand
+}
+
+func (c fakeTTYCommand) GetProtocol() string {
+	return command.PROTOCOL_TTY
+}
+
+func TestSessionManager_NotSerializable(t *testing.T) {
+	store := NewLocalSessionStore()
+	man := NewSessionManager()
+	man.SetStore(store)
+
+	s, err := man.Save(fakeTTYCommand{command.NewBaseCommand("/bin/true")}, nil, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+	replica := NewSessionManager()
+	replica.SetStore(store)
+	if _, ok := replica.Get(s.AccessToken); ok {
+		t.Errorf("not serializable session %s should only be served by its instance", s.Id)
+	}
+}
+
+func TestLocalSessionStore_Expire(t *testing.T) {
+	store := NewLocalSessionStore()
+	store.Put(&SSessionRecord{Id: "s1", CreatedAt: time.Now()}, time.Hour)
+	store.Put(&SSessionRecord{Id: "s2", CreatedAt: time.Now()}, -time.Second)
+	recs, err := store.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(recs) != 1 || recs[0].Id != "s1" {
+		t.Errorf("List() = %v, want only s1", recs)
+	}
+}
+
+func TestSessionManager_SpiceChannels(t *testing.T) {
+	man := NewSessionManager()
+	man.SetStore(NewLocalSessionStore())
+
+	info := &RemoteConsoleInfo{Host: "10.168.222.23", Port: 5921, Protocol: SPICE}
+	s, err := man.Save(info, nil, "server1")
+	if err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	const channels = 4
+	var wg sync.WaitGroup
+	for i := 0; i < channels; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := man.Get(s.AccessToken); !ok {
+				t.Errorf("spice channel of session %s rejected", s.Id)
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < channels; i++ {
+		last := man.ReleaseSpiceChannel(s.Id)
+		if last != (i == channels-1) {
+			t.Errorf("release channel %d: last = %v", i, last)
+		}
+	}
+	// the token is not reusable once all channels are closed
+	if _, ok := man.Get(s.AccessToken); ok {
+		t.Errorf("session %s can be accessed again within %s", s.Id, AccessInterval)
+	}
+}
+
+func TestSessionManager_KeepAlive(t *testing.T) {
+	ttl := o.Options.SessionTTLSeconds
+	o.Options.SessionTTLSeconds = 1
+	defer func() { o.Options.SessionTTLSeconds = ttl }()
+
+	store := NewLocalSessionStore()
+	man := NewSessionManager()
+	man.SetStore(store)
+
+	info := &RemoteConsoleInfo{Host: "10.168.222.23", Port: 5901, Protocol: VNC}
+	s, err := man.Save(info, nil, "server1")
+	if err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+	if _, ok := man.Get(s.AccessToken); !ok {
+		t.Fatalf("session %s not found", s.Id)
+	}
+	time.Sleep(2500 * time.Millisecond)
+	if _, err := store.Get(s.Id); err != nil {
+		t.Errorf("session %s in use expired: %v", s.Id, err)
+	}
+
+	man.close(s.Id)
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := store.Get(s.Id); err != ErrSessionRecordNotFound {
+		t.Errorf("closed session %s kept alive: %v", s.Id, err)
+	}
+}
+
+func TestSessionManager_SharedFrameRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webconsole-recording")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "s1"+RECORDING_FRAME_SUFFIX)
+
+	man := NewSessionManager()
+	// the spice channels of a session write the same recorder
+	recs := []*SFrameRecorder{}
+	for i := 0; i < 3; i++ {
+		recs = append(recs, man.acquireFrameRecorder("s1", path))
+	}
+	if recs[0] == nil || recs[1] != recs[0] || recs[2] != recs[0] {
+		t.Fatalf("frame recorder is not shared by channels: %v", recs)
+	}
+	for i := range recs {
+		man.releaseFrameRecorder("s1")
+		closed := recs[0].file == nil
+		if closed != (i == len(recs)-1) {
+			t.Errorf("release channel %d: closed = %v", i, closed)
+		}
+	}
+	if rec := man.acquireFrameRecorder("s1", path); rec == recs[0] {
+		t.Errorf("closed frame recorder is reused")
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/etcd"
+)
+
+const (
+	SESSION_STORE_LOCAL = "local"
+	SESSION_STORE_ETCD  = "etcd"
+
+	etcdSessionPrefix = "/webconsole/sessions/"
+)
+
+var (
+	ErrSessionRecordNotFound = fmt.Errorf("session record not found")
+)
+
+// SSessionRecord is the serializable part of a console session which is
+// shared by all webconsole replicas and kept for audit after close
+type SSessionRecord struct {
+	Id          string               `json:"id"`
+	AccessToken string               `json:"access_token"`
+	Protocol    string               `json:"protocol"`
+	DataType    string               `json:"data_type"`
+	Data        jsonutils.JSONObject `json:"data"`
+	Target      string               `json:"target"`
+	UserId      string               `json:"user_id"`
+	User        string               `json:"user"`
+	ProjectId   string               `json:"project_id"`
+	Project     string               `json:"project"`
+	Instance    string               `json:"instance"`
+	Recording   string               `json:"recording"`
+	CreatedAt   time.Time            `json:"created_at"`
+	AccessedAt  time.Time            `json:"accessed_at"`
+	ClosedAt    time.Time            `json:"closed_at"`
+}
+
+func (rec *SSessionRecord) IsClosed() bool {
+	return !rec.ClosedAt.IsZero()
+}
+
+// AuditInfo strips secrets from the record before it is sent to api callers
+func (rec *SSessionRecord) AuditInfo() jsonutils.JSONObject {
+	ret := jsonutils.Marshal(rec).(*jsonutils.JSONDict)
+	ret.Remove("access_token")
+	ret.Remove("data")
+	ret.Add(jsonutils.NewBool(rec.IsClosed()), "closed")
+	ret.Add(jsonutils.NewBool(len(rec.Recording) > 0), "has_recording")
+	return ret
+}
+
+type ISessionStore interface {
+	Put(rec *SSessionRecord, ttl time.Duration) error
+	Get(id string) (*SSessionRecord, error)
+	Delete(id string) error
+	List() ([]*SSessionRecord, error)
+}
+
+func NewSessionStore(backend string) (ISessionStore, error) {
+	switch backend {
+	case SESSION_STORE_ETCD:
+		cli := etcd.Default()
+		if cli == nil {
+			return nil, fmt.Errorf("etcd client not initialized")
+		}
+		return &SEtcdSessionStore{client: cli}, nil
+	case SESSION_STORE_LOCAL, "":
+		return NewLocalSessionStore(), nil
+	default:
+		return nil, fmt.Errorf("Unsupported session store %q", backend)
+	}
+}
+
+type sLocalSessionItem struct {
+	record   *SSessionRecord
+	expireAt time.Time
+}
+
+// SLocalSessionStore keeps records in process memory, it can only be used
+// when a single webconsole instance is deployed
+type SLocalSessionStore struct {
+	lock  *sync.Mutex
+	items map[string]*sLocalSessionItem
+}
+
+func NewLocalSessionStore() *SLocalSessionStore {
+	return &SLocalSessionStore{
+		lock:  &sync.Mutex{},
+		items: make(map[string]*sLocalSessionItem),
+	}
+}
+
+func (store *SLocalSessionStore) Put(rec *SSessionRecord, ttl time.Duration) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	copied := *rec
+	store.items[rec.Id] = &sLocalSessionItem{
+		record:   &copied,
+		expireAt: time.Now().Add(ttl),
+	}
+	return nil
+}
+
+func (store *SLocalSessionStore) Get(id string) (*SSessionRecord, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	item, ok := store.items[id]
+	if !ok {
+		return nil, ErrSessionRecordNotFound
+	}
+	if time.Now().After(item.expireAt) {
+		delete(store.items, id)
+		return nil, ErrSessionRecordNotFound
+	}
+	copied := *item.record
+	return &copied, nil
+}
+
+func (store *SLocalSessionStore) Delete(id string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	delete(store.items, id)
+	return nil
+}
+
+func (store *SLocalSessionStore) List() ([]*SSessionRecord, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	now := time.Now()
+	ret := make([]*SSessionRecord, 0, len(store.items))
+	for id, item := range store.items {
+		if now.After(item.expireAt) {
+			delete(store.items, id)
+			continue
+		}
+		copied := *item.record
+		ret = append(ret, &copied)
+	}
+	sortSessionRecords(ret)
+	return ret, nil
+}
+
+// SEtcdSessionStore saves records in etcd with a lease per record, so that
+// every webconsole replica can serve a session created by another one
+type SEtcdSessionStore struct {
+	client *etcd.SEtcdClient
+}
+
+func (store *SEtcdSessionStore) key(id string) string {
+	return etcdSessionPrefix + id
+}
+
+func (store *SEtcdSessionStore) Put(rec *SSessionRecord, ttl time.Duration) error {
+	seconds := int64(ttl.Seconds())
+	if seconds <= 0 {
+		seconds = 1
+	}
+	return store.client.PutWithTTL(context.Background(), store.key(rec.Id), jsonutils.Marshal(rec).String(), seconds)
+}
+
+func (store *SEtcdSessionStore) Get(id string) (*SSessionRecord, error) {
+	val, err := store.client.Get(context.Background(), store.key(id))
+	if err == etcd.ErrNoSuchKey {
+		return nil, ErrSessionRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseSessionRecord(val)
+}
+
+func (store *SEtcdSessionStore) Delete(id string) error {
+	_, err := store.client.Delete(context.Background(), store.key(id))
+	return err
+}
+
+func (store *SEtcdSessionStore) List() ([]*SSessionRecord, error) {
+	kvs, err := store.client.List(context.Background(), etcdSessionPrefix)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*SSessionRecord, 0, len(kvs))
+	for _, kv := range kvs {
+		rec, err := parseSessionRecord(kv.Value)
+		if err != nil {
+			return nil, fmt.Errorf("parse session record %s: %v", kv.Key, err)
+		}
+		ret = append(ret, rec)
+	}
+	sortSessionRecords(ret)
+	return ret, nil
+}
+
+func parseSessionRecord(val []byte) (*SSessionRecord, error) {
+	obj, err := jsonutils.Parse(val)
+	if err != nil {
+		return nil, err
+	}
+	rec := &SSessionRecord{}
+	if err := obj.Unmarshal(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func sortSessionRecords(recs []*SSessionRecord) {
+	sort.Slice(recs, func(i, j int) bool {
+		return recs[i].CreatedAt.After(recs[j].CreatedAt)
+	})
+}